@@ -0,0 +1,1508 @@
+// PortFly gRPC API.
+//
+// Mirrors the read side of the REST API for hosts, ports and tunnel sessions,
+// and streams session events. Field names and string values match the JSON
+// of the REST API, so both describe the same schema. Changes go through the
+// REST API, which applies maintenance windows, quotas and grants.
+//
+// Regenerate the Go code with `make proto`.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: portfly/v1/portfly.proto
+
+package portflyv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// A host; passwords and private keys are never returned
+type Host struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Hostname      string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Port          int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Username      string                 `protobuf:"bytes,5,opt,name=username,proto3" json:"username,omitempty"`
+	Description   string                 `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
+	AuthMethod    string                 `protobuf:"bytes,7,opt,name=auth_method,json=authMethod,proto3" json:"auth_method,omitempty"` // password, key, agent, gssapi
+	Status        string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`                           // connected, disconnected, connecting, error, unknown
+	LastConnected *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=last_connected,json=lastConnected,proto3" json:"last_connected,omitempty"`
+	GroupId       uint32                 `protobuf:"varint,10,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Tags          []string               `protobuf:"bytes,11,rep,name=tags,proto3" json:"tags,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Host) Reset() {
+	*x = Host{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Host) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Host) ProtoMessage() {}
+
+func (x *Host) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Host.ProtoReflect.Descriptor instead.
+func (*Host) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Host) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Host) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Host) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Host) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Host) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *Host) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Host) GetAuthMethod() string {
+	if x != nil {
+		return x.AuthMethod
+	}
+	return ""
+}
+
+func (x *Host) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Host) GetLastConnected() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastConnected
+	}
+	return nil
+}
+
+func (x *Host) GetGroupId() uint32 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *Host) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Host) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Host) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListHostsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       uint32                 `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"` // 0 lists all groups
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListHostsRequest) Reset() {
+	*x = ListHostsRequest{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListHostsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListHostsRequest) ProtoMessage() {}
+
+func (x *ListHostsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListHostsRequest.ProtoReflect.Descriptor instead.
+func (*ListHostsRequest) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListHostsRequest) GetGroupId() uint32 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+type ListHostsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hosts         []*Host                `protobuf:"bytes,1,rep,name=hosts,proto3" json:"hosts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListHostsResponse) Reset() {
+	*x = ListHostsResponse{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListHostsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListHostsResponse) ProtoMessage() {}
+
+func (x *ListHostsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListHostsResponse.ProtoReflect.Descriptor instead.
+func (*ListHostsResponse) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListHostsResponse) GetHosts() []*Host {
+	if x != nil {
+		return x.Hosts
+	}
+	return nil
+}
+
+type GetHostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetHostRequest) Reset() {
+	*x = GetHostRequest{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetHostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetHostRequest) ProtoMessage() {}
+
+func (x *GetHostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetHostRequest.ProtoReflect.Descriptor instead.
+func (*GetHostRequest) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetHostRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+// A port's most recent failure
+type PortError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Retryable     bool                   `protobuf:"varint,4,opt,name=retryable,proto3" json:"retryable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortError) Reset() {
+	*x = PortError{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortError) ProtoMessage() {}
+
+func (x *PortError) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortError.ProtoReflect.Descriptor instead.
+func (*PortError) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PortError) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *PortError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *PortError) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *PortError) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
+}
+
+// A forwarded port
+type Port struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"` // remote_port, local_port
+	Port          int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	PortEnd       int32                  `protobuf:"varint,5,opt,name=port_end,json=portEnd,proto3" json:"port_end,omitempty"` // last port of a range, 0 for a single port
+	BindAddress   string                 `protobuf:"bytes,6,opt,name=bind_address,json=bindAddress,proto3" json:"bind_address,omitempty"`
+	Description   string                 `protobuf:"bytes,7,opt,name=description,proto3" json:"description,omitempty"`
+	ServiceType   string                 `protobuf:"bytes,8,opt,name=service_type,json=serviceType,proto3" json:"service_type,omitempty"` // as configured, or inferred from the port number
+	Status        string                 `protobuf:"bytes,9,opt,name=status,proto3" json:"status,omitempty"`
+	StatusMessage string                 `protobuf:"bytes,10,opt,name=status_message,json=statusMessage,proto3" json:"status_message,omitempty"`
+	LastError     *PortError             `protobuf:"bytes,11,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	LastActive    *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=last_active,json=lastActive,proto3" json:"last_active,omitempty"`
+	AutoStart     bool                   `protobuf:"varint,13,opt,name=auto_start,json=autoStart,proto3" json:"auto_start,omitempty"`
+	GroupId       uint32                 `protobuf:"varint,14,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	HostId        *uint32                `protobuf:"varint,15,opt,name=host_id,json=hostId,proto3,oneof" json:"host_id,omitempty"`
+	TargetPortId  *uint32                `protobuf:"varint,16,opt,name=target_port_id,json=targetPortId,proto3,oneof" json:"target_port_id,omitempty"`
+	ActiveHostId  *uint32                `protobuf:"varint,17,opt,name=active_host_id,json=activeHostId,proto3,oneof" json:"active_host_id,omitempty"`
+	Tags          []string               `protobuf:"bytes,18,rep,name=tags,proto3" json:"tags,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,19,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,20,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Port) Reset() {
+	*x = Port{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Port) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Port) ProtoMessage() {}
+
+func (x *Port) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Port.ProtoReflect.Descriptor instead.
+func (*Port) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Port) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Port) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Port) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Port) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Port) GetPortEnd() int32 {
+	if x != nil {
+		return x.PortEnd
+	}
+	return 0
+}
+
+func (x *Port) GetBindAddress() string {
+	if x != nil {
+		return x.BindAddress
+	}
+	return ""
+}
+
+func (x *Port) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Port) GetServiceType() string {
+	if x != nil {
+		return x.ServiceType
+	}
+	return ""
+}
+
+func (x *Port) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Port) GetStatusMessage() string {
+	if x != nil {
+		return x.StatusMessage
+	}
+	return ""
+}
+
+func (x *Port) GetLastError() *PortError {
+	if x != nil {
+		return x.LastError
+	}
+	return nil
+}
+
+func (x *Port) GetLastActive() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastActive
+	}
+	return nil
+}
+
+func (x *Port) GetAutoStart() bool {
+	if x != nil {
+		return x.AutoStart
+	}
+	return false
+}
+
+func (x *Port) GetGroupId() uint32 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *Port) GetHostId() uint32 {
+	if x != nil && x.HostId != nil {
+		return *x.HostId
+	}
+	return 0
+}
+
+func (x *Port) GetTargetPortId() uint32 {
+	if x != nil && x.TargetPortId != nil {
+		return *x.TargetPortId
+	}
+	return 0
+}
+
+func (x *Port) GetActiveHostId() uint32 {
+	if x != nil && x.ActiveHostId != nil {
+		return *x.ActiveHostId
+	}
+	return 0
+}
+
+func (x *Port) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Port) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Port) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListPortsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       uint32                 `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"` // 0 lists all groups
+	HostId        uint32                 `protobuf:"varint,2,opt,name=host_id,json=hostId,proto3" json:"host_id,omitempty"`    // 0 lists all hosts; cannot be combined with group_id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPortsRequest) Reset() {
+	*x = ListPortsRequest{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPortsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPortsRequest) ProtoMessage() {}
+
+func (x *ListPortsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPortsRequest.ProtoReflect.Descriptor instead.
+func (*ListPortsRequest) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListPortsRequest) GetGroupId() uint32 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *ListPortsRequest) GetHostId() uint32 {
+	if x != nil {
+		return x.HostId
+	}
+	return 0
+}
+
+type ListPortsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ports         []*Port                `protobuf:"bytes,1,rep,name=ports,proto3" json:"ports,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPortsResponse) Reset() {
+	*x = ListPortsResponse{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPortsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPortsResponse) ProtoMessage() {}
+
+func (x *ListPortsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPortsResponse.ProtoReflect.Descriptor instead.
+func (*ListPortsResponse) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListPortsResponse) GetPorts() []*Port {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+// GetPortRequest references a port by ID or by name
+type GetPortRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Port:
+	//
+	//	*GetPortRequest_Id
+	//	*GetPortRequest_Name
+	Port          isGetPortRequest_Port `protobuf_oneof:"port"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPortRequest) Reset() {
+	*x = GetPortRequest{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPortRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPortRequest) ProtoMessage() {}
+
+func (x *GetPortRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPortRequest.ProtoReflect.Descriptor instead.
+func (*GetPortRequest) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetPortRequest) GetPort() isGetPortRequest_Port {
+	if x != nil {
+		return x.Port
+	}
+	return nil
+}
+
+func (x *GetPortRequest) GetId() uint32 {
+	if x != nil {
+		if x, ok := x.Port.(*GetPortRequest_Id); ok {
+			return x.Id
+		}
+	}
+	return 0
+}
+
+func (x *GetPortRequest) GetName() string {
+	if x != nil {
+		if x, ok := x.Port.(*GetPortRequest_Name); ok {
+			return x.Name
+		}
+	}
+	return ""
+}
+
+type isGetPortRequest_Port interface {
+	isGetPortRequest_Port()
+}
+
+type GetPortRequest_Id struct {
+	Id uint32 `protobuf:"varint,1,opt,name=id,proto3,oneof"`
+}
+
+type GetPortRequest_Name struct {
+	Name string `protobuf:"bytes,2,opt,name=name,proto3,oneof"`
+}
+
+func (*GetPortRequest_Id) isGetPortRequest_Port() {}
+
+func (*GetPortRequest_Name) isGetPortRequest_Port() {}
+
+// A tunnel session record
+type Session struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name            string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Status          string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	StartTime       *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	EndTime         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	ErrorMessage    string                 `protobuf:"bytes,6,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	DataTransferred int64                  `protobuf:"varint,7,opt,name=data_transferred,json=dataTransferred,proto3" json:"data_transferred,omitempty"`
+	LocalAddress    string                 `protobuf:"bytes,8,opt,name=local_address,json=localAddress,proto3" json:"local_address,omitempty"`
+	RemoteAddress   string                 `protobuf:"bytes,9,opt,name=remote_address,json=remoteAddress,proto3" json:"remote_address,omitempty"`
+	HostId          uint32                 `protobuf:"varint,10,opt,name=host_id,json=hostId,proto3" json:"host_id,omitempty"`
+	PortId          *uint32                `protobuf:"varint,11,opt,name=port_id,json=portId,proto3,oneof" json:"port_id,omitempty"`
+	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt       *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Session) Reset() {
+	*x = Session{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Session) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Session) ProtoMessage() {}
+
+func (x *Session) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Session.ProtoReflect.Descriptor instead.
+func (*Session) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Session) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Session) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Session) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Session) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *Session) GetEndTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndTime
+	}
+	return nil
+}
+
+func (x *Session) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *Session) GetDataTransferred() int64 {
+	if x != nil {
+		return x.DataTransferred
+	}
+	return 0
+}
+
+func (x *Session) GetLocalAddress() string {
+	if x != nil {
+		return x.LocalAddress
+	}
+	return ""
+}
+
+func (x *Session) GetRemoteAddress() string {
+	if x != nil {
+		return x.RemoteAddress
+	}
+	return ""
+}
+
+func (x *Session) GetHostId() uint32 {
+	if x != nil {
+		return x.HostId
+	}
+	return 0
+}
+
+func (x *Session) GetPortId() uint32 {
+	if x != nil && x.PortId != nil {
+		return *x.PortId
+	}
+	return 0
+}
+
+func (x *Session) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Session) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListSessionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ActiveOnly    bool                   `protobuf:"varint,1,opt,name=active_only,json=activeOnly,proto3" json:"active_only,omitempty"` // only sessions that are running
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSessionsRequest) Reset() {
+	*x = ListSessionsRequest{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSessionsRequest) ProtoMessage() {}
+
+func (x *ListSessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSessionsRequest.ProtoReflect.Descriptor instead.
+func (*ListSessionsRequest) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListSessionsRequest) GetActiveOnly() bool {
+	if x != nil {
+		return x.ActiveOnly
+	}
+	return false
+}
+
+type ListSessionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sessions      []*Session             `protobuf:"bytes,1,rep,name=sessions,proto3" json:"sessions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSessionsResponse) Reset() {
+	*x = ListSessionsResponse{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSessionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSessionsResponse) ProtoMessage() {}
+
+func (x *ListSessionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSessionsResponse.ProtoReflect.Descriptor instead.
+func (*ListSessionsResponse) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListSessionsResponse) GetSessions() []*Session {
+	if x != nil {
+		return x.Sessions
+	}
+	return nil
+}
+
+type GetSessionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSessionRequest) Reset() {
+	*x = GetSessionRequest{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSessionRequest) ProtoMessage() {}
+
+func (x *GetSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSessionRequest.ProtoReflect.Descriptor instead.
+func (*GetSessionRequest) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetSessionRequest) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type WatchEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Types         []string               `protobuf:"bytes,1,rep,name=types,proto3" json:"types,omitempty"` // event types to receive, e.g. session.stats; empty receives all
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *WatchEventsRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+// Transfer statistics since the previous session.stats event
+type StatsUpdate struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Interval          *durationpb.Duration   `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	BytesSent         int64                  `protobuf:"varint,2,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
+	BytesReceived     int64                  `protobuf:"varint,3,opt,name=bytes_received,json=bytesReceived,proto3" json:"bytes_received,omitempty"`
+	SendRate          float64                `protobuf:"fixed64,4,opt,name=send_rate,json=sendRate,proto3" json:"send_rate,omitempty"`          // bytes per second
+	ReceiveRate       float64                `protobuf:"fixed64,5,opt,name=receive_rate,json=receiveRate,proto3" json:"receive_rate,omitempty"` // bytes per second
+	NewConnections    int64                  `protobuf:"varint,6,opt,name=new_connections,json=newConnections,proto3" json:"new_connections,omitempty"`
+	ActiveConnections int64                  `protobuf:"varint,7,opt,name=active_connections,json=activeConnections,proto3" json:"active_connections,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *StatsUpdate) Reset() {
+	*x = StatsUpdate{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsUpdate) ProtoMessage() {}
+
+func (x *StatsUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsUpdate.ProtoReflect.Descriptor instead.
+func (*StatsUpdate) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *StatsUpdate) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+func (x *StatsUpdate) GetBytesSent() int64 {
+	if x != nil {
+		return x.BytesSent
+	}
+	return 0
+}
+
+func (x *StatsUpdate) GetBytesReceived() int64 {
+	if x != nil {
+		return x.BytesReceived
+	}
+	return 0
+}
+
+func (x *StatsUpdate) GetSendRate() float64 {
+	if x != nil {
+		return x.SendRate
+	}
+	return 0
+}
+
+func (x *StatsUpdate) GetReceiveRate() float64 {
+	if x != nil {
+		return x.ReceiveRate
+	}
+	return 0
+}
+
+func (x *StatsUpdate) GetNewConnections() int64 {
+	if x != nil {
+		return x.NewConnections
+	}
+	return 0
+}
+
+func (x *StatsUpdate) GetActiveConnections() int64 {
+	if x != nil {
+		return x.ActiveConnections
+	}
+	return 0
+}
+
+// Something that happened to a running session
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	SessionId     string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	Attempt       int32                  `protobuf:"varint,6,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	RetryIn       *durationpb.Duration   `protobuf:"bytes,7,opt,name=retry_in,json=retryIn,proto3" json:"retry_in,omitempty"`
+	Stats         *StatsUpdate           `protobuf:"bytes,8,opt,name=stats,proto3" json:"stats,omitempty"` // session.stats only
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_portfly_v1_portfly_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_portfly_v1_portfly_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_portfly_v1_portfly_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *Event) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Event) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Event) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Event) GetAttempt() int32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *Event) GetRetryIn() *durationpb.Duration {
+	if x != nil {
+		return x.RetryIn
+	}
+	return nil
+}
+
+func (x *Event) GetStats() *StatsUpdate {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *Event) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+var File_portfly_v1_portfly_proto protoreflect.FileDescriptor
+
+const file_portfly_v1_portfly_proto_rawDesc = "" +
+	"\n" +
+	"\x18portfly/v1/portfly.proto\x12\n" +
+	"portfly.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb9\x03\n" +
+	"\x04Host\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
+	"\bhostname\x18\x03 \x01(\tR\bhostname\x12\x12\n" +
+	"\x04port\x18\x04 \x01(\x05R\x04port\x12\x1a\n" +
+	"\busername\x18\x05 \x01(\tR\busername\x12 \n" +
+	"\vdescription\x18\x06 \x01(\tR\vdescription\x12\x1f\n" +
+	"\vauth_method\x18\a \x01(\tR\n" +
+	"authMethod\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status\x12A\n" +
+	"\x0elast_connected\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\rlastConnected\x12\x19\n" +
+	"\bgroup_id\x18\n" +
+	" \x01(\rR\agroupId\x12\x12\n" +
+	"\x04tags\x18\v \x03(\tR\x04tags\x129\n" +
+	"\n" +
+	"created_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"-\n" +
+	"\x10ListHostsRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\rR\agroupId\";\n" +
+	"\x11ListHostsResponse\x12&\n" +
+	"\x05hosts\x18\x01 \x03(\v2\x10.portfly.v1.HostR\x05hosts\" \n" +
+	"\x0eGetHostRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"\x91\x01\n" +
+	"\tPortError\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x128\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1c\n" +
+	"\tretryable\x18\x04 \x01(\bR\tretryable\"\xf1\x05\n" +
+	"\x04Port\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x12\n" +
+	"\x04port\x18\x04 \x01(\x05R\x04port\x12\x19\n" +
+	"\bport_end\x18\x05 \x01(\x05R\aportEnd\x12!\n" +
+	"\fbind_address\x18\x06 \x01(\tR\vbindAddress\x12 \n" +
+	"\vdescription\x18\a \x01(\tR\vdescription\x12!\n" +
+	"\fservice_type\x18\b \x01(\tR\vserviceType\x12\x16\n" +
+	"\x06status\x18\t \x01(\tR\x06status\x12%\n" +
+	"\x0estatus_message\x18\n" +
+	" \x01(\tR\rstatusMessage\x124\n" +
+	"\n" +
+	"last_error\x18\v \x01(\v2\x15.portfly.v1.PortErrorR\tlastError\x12;\n" +
+	"\vlast_active\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"lastActive\x12\x1d\n" +
+	"\n" +
+	"auto_start\x18\r \x01(\bR\tautoStart\x12\x19\n" +
+	"\bgroup_id\x18\x0e \x01(\rR\agroupId\x12\x1c\n" +
+	"\ahost_id\x18\x0f \x01(\rH\x00R\x06hostId\x88\x01\x01\x12)\n" +
+	"\x0etarget_port_id\x18\x10 \x01(\rH\x01R\ftargetPortId\x88\x01\x01\x12)\n" +
+	"\x0eactive_host_id\x18\x11 \x01(\rH\x02R\factiveHostId\x88\x01\x01\x12\x12\n" +
+	"\x04tags\x18\x12 \x03(\tR\x04tags\x129\n" +
+	"\n" +
+	"created_at\x18\x13 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x14 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\n" +
+	"\n" +
+	"\b_host_idB\x11\n" +
+	"\x0f_target_port_idB\x11\n" +
+	"\x0f_active_host_id\"F\n" +
+	"\x10ListPortsRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\rR\agroupId\x12\x17\n" +
+	"\ahost_id\x18\x02 \x01(\rR\x06hostId\";\n" +
+	"\x11ListPortsResponse\x12&\n" +
+	"\x05ports\x18\x01 \x03(\v2\x10.portfly.v1.PortR\x05ports\"@\n" +
+	"\x0eGetPortRequest\x12\x10\n" +
+	"\x02id\x18\x01 \x01(\rH\x00R\x02id\x12\x14\n" +
+	"\x04name\x18\x02 \x01(\tH\x00R\x04nameB\x06\n" +
+	"\x04port\"\x8c\x04\n" +
+	"\aSession\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"start_time\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x125\n" +
+	"\bend_time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\aendTime\x12#\n" +
+	"\rerror_message\x18\x06 \x01(\tR\ferrorMessage\x12)\n" +
+	"\x10data_transferred\x18\a \x01(\x03R\x0fdataTransferred\x12#\n" +
+	"\rlocal_address\x18\b \x01(\tR\flocalAddress\x12%\n" +
+	"\x0eremote_address\x18\t \x01(\tR\rremoteAddress\x12\x17\n" +
+	"\ahost_id\x18\n" +
+	" \x01(\rR\x06hostId\x12\x1c\n" +
+	"\aport_id\x18\v \x01(\rH\x00R\x06portId\x88\x01\x01\x129\n" +
+	"\n" +
+	"created_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\n" +
+	"\n" +
+	"\b_port_id\"6\n" +
+	"\x13ListSessionsRequest\x12\x1f\n" +
+	"\vactive_only\x18\x01 \x01(\bR\n" +
+	"activeOnly\"G\n" +
+	"\x14ListSessionsResponse\x12/\n" +
+	"\bsessions\x18\x01 \x03(\v2\x13.portfly.v1.SessionR\bsessions\"#\n" +
+	"\x11GetSessionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"*\n" +
+	"\x12WatchEventsRequest\x12\x14\n" +
+	"\x05types\x18\x01 \x03(\tR\x05types\"\xa2\x02\n" +
+	"\vStatsUpdate\x125\n" +
+	"\binterval\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\binterval\x12\x1d\n" +
+	"\n" +
+	"bytes_sent\x18\x02 \x01(\x03R\tbytesSent\x12%\n" +
+	"\x0ebytes_received\x18\x03 \x01(\x03R\rbytesReceived\x12\x1b\n" +
+	"\tsend_rate\x18\x04 \x01(\x01R\bsendRate\x12!\n" +
+	"\freceive_rate\x18\x05 \x01(\x01R\vreceiveRate\x12'\n" +
+	"\x0fnew_connections\x18\x06 \x01(\x03R\x0enewConnections\x12-\n" +
+	"\x12active_connections\x18\a \x01(\x03R\x11activeConnections\"\xbb\x02\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x02 \x01(\tR\tsessionId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x18\n" +
+	"\aattempt\x18\x06 \x01(\x05R\aattempt\x124\n" +
+	"\bretry_in\x18\a \x01(\v2\x19.google.protobuf.DurationR\aretryIn\x12-\n" +
+	"\x05stats\x18\b \x01(\v2\x17.portfly.v1.StatsUpdateR\x05stats\x128\n" +
+	"\ttimestamp\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp2\xef\x03\n" +
+	"\x0ePortFlyService\x12H\n" +
+	"\tListHosts\x12\x1c.portfly.v1.ListHostsRequest\x1a\x1d.portfly.v1.ListHostsResponse\x127\n" +
+	"\aGetHost\x12\x1a.portfly.v1.GetHostRequest\x1a\x10.portfly.v1.Host\x12H\n" +
+	"\tListPorts\x12\x1c.portfly.v1.ListPortsRequest\x1a\x1d.portfly.v1.ListPortsResponse\x127\n" +
+	"\aGetPort\x12\x1a.portfly.v1.GetPortRequest\x1a\x10.portfly.v1.Port\x12Q\n" +
+	"\fListSessions\x12\x1f.portfly.v1.ListSessionsRequest\x1a .portfly.v1.ListSessionsResponse\x12@\n" +
+	"\n" +
+	"GetSession\x12\x1d.portfly.v1.GetSessionRequest\x1a\x13.portfly.v1.Session\x12B\n" +
+	"\vWatchEvents\x12\x1e.portfly.v1.WatchEventsRequest\x1a\x11.portfly.v1.Event0\x01B5Z3github.com/aqz236/port-fly/api/portfly/v1;portflyv1b\x06proto3"
+
+var (
+	file_portfly_v1_portfly_proto_rawDescOnce sync.Once
+	file_portfly_v1_portfly_proto_rawDescData []byte
+)
+
+func file_portfly_v1_portfly_proto_rawDescGZIP() []byte {
+	file_portfly_v1_portfly_proto_rawDescOnce.Do(func() {
+		file_portfly_v1_portfly_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_portfly_v1_portfly_proto_rawDesc), len(file_portfly_v1_portfly_proto_rawDesc)))
+	})
+	return file_portfly_v1_portfly_proto_rawDescData
+}
+
+var file_portfly_v1_portfly_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_portfly_v1_portfly_proto_goTypes = []any{
+	(*Host)(nil),                  // 0: portfly.v1.Host
+	(*ListHostsRequest)(nil),      // 1: portfly.v1.ListHostsRequest
+	(*ListHostsResponse)(nil),     // 2: portfly.v1.ListHostsResponse
+	(*GetHostRequest)(nil),        // 3: portfly.v1.GetHostRequest
+	(*PortError)(nil),             // 4: portfly.v1.PortError
+	(*Port)(nil),                  // 5: portfly.v1.Port
+	(*ListPortsRequest)(nil),      // 6: portfly.v1.ListPortsRequest
+	(*ListPortsResponse)(nil),     // 7: portfly.v1.ListPortsResponse
+	(*GetPortRequest)(nil),        // 8: portfly.v1.GetPortRequest
+	(*Session)(nil),               // 9: portfly.v1.Session
+	(*ListSessionsRequest)(nil),   // 10: portfly.v1.ListSessionsRequest
+	(*ListSessionsResponse)(nil),  // 11: portfly.v1.ListSessionsResponse
+	(*GetSessionRequest)(nil),     // 12: portfly.v1.GetSessionRequest
+	(*WatchEventsRequest)(nil),    // 13: portfly.v1.WatchEventsRequest
+	(*StatsUpdate)(nil),           // 14: portfly.v1.StatsUpdate
+	(*Event)(nil),                 // 15: portfly.v1.Event
+	(*timestamppb.Timestamp)(nil), // 16: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 17: google.protobuf.Duration
+}
+var file_portfly_v1_portfly_proto_depIdxs = []int32{
+	16, // 0: portfly.v1.Host.last_connected:type_name -> google.protobuf.Timestamp
+	16, // 1: portfly.v1.Host.created_at:type_name -> google.protobuf.Timestamp
+	16, // 2: portfly.v1.Host.updated_at:type_name -> google.protobuf.Timestamp
+	0,  // 3: portfly.v1.ListHostsResponse.hosts:type_name -> portfly.v1.Host
+	16, // 4: portfly.v1.PortError.timestamp:type_name -> google.protobuf.Timestamp
+	4,  // 5: portfly.v1.Port.last_error:type_name -> portfly.v1.PortError
+	16, // 6: portfly.v1.Port.last_active:type_name -> google.protobuf.Timestamp
+	16, // 7: portfly.v1.Port.created_at:type_name -> google.protobuf.Timestamp
+	16, // 8: portfly.v1.Port.updated_at:type_name -> google.protobuf.Timestamp
+	5,  // 9: portfly.v1.ListPortsResponse.ports:type_name -> portfly.v1.Port
+	16, // 10: portfly.v1.Session.start_time:type_name -> google.protobuf.Timestamp
+	16, // 11: portfly.v1.Session.end_time:type_name -> google.protobuf.Timestamp
+	16, // 12: portfly.v1.Session.created_at:type_name -> google.protobuf.Timestamp
+	16, // 13: portfly.v1.Session.updated_at:type_name -> google.protobuf.Timestamp
+	9,  // 14: portfly.v1.ListSessionsResponse.sessions:type_name -> portfly.v1.Session
+	17, // 15: portfly.v1.StatsUpdate.interval:type_name -> google.protobuf.Duration
+	17, // 16: portfly.v1.Event.retry_in:type_name -> google.protobuf.Duration
+	14, // 17: portfly.v1.Event.stats:type_name -> portfly.v1.StatsUpdate
+	16, // 18: portfly.v1.Event.timestamp:type_name -> google.protobuf.Timestamp
+	1,  // 19: portfly.v1.PortFlyService.ListHosts:input_type -> portfly.v1.ListHostsRequest
+	3,  // 20: portfly.v1.PortFlyService.GetHost:input_type -> portfly.v1.GetHostRequest
+	6,  // 21: portfly.v1.PortFlyService.ListPorts:input_type -> portfly.v1.ListPortsRequest
+	8,  // 22: portfly.v1.PortFlyService.GetPort:input_type -> portfly.v1.GetPortRequest
+	10, // 23: portfly.v1.PortFlyService.ListSessions:input_type -> portfly.v1.ListSessionsRequest
+	12, // 24: portfly.v1.PortFlyService.GetSession:input_type -> portfly.v1.GetSessionRequest
+	13, // 25: portfly.v1.PortFlyService.WatchEvents:input_type -> portfly.v1.WatchEventsRequest
+	2,  // 26: portfly.v1.PortFlyService.ListHosts:output_type -> portfly.v1.ListHostsResponse
+	0,  // 27: portfly.v1.PortFlyService.GetHost:output_type -> portfly.v1.Host
+	7,  // 28: portfly.v1.PortFlyService.ListPorts:output_type -> portfly.v1.ListPortsResponse
+	5,  // 29: portfly.v1.PortFlyService.GetPort:output_type -> portfly.v1.Port
+	11, // 30: portfly.v1.PortFlyService.ListSessions:output_type -> portfly.v1.ListSessionsResponse
+	9,  // 31: portfly.v1.PortFlyService.GetSession:output_type -> portfly.v1.Session
+	15, // 32: portfly.v1.PortFlyService.WatchEvents:output_type -> portfly.v1.Event
+	26, // [26:33] is the sub-list for method output_type
+	19, // [19:26] is the sub-list for method input_type
+	19, // [19:19] is the sub-list for extension type_name
+	19, // [19:19] is the sub-list for extension extendee
+	0,  // [0:19] is the sub-list for field type_name
+}
+
+func init() { file_portfly_v1_portfly_proto_init() }
+func file_portfly_v1_portfly_proto_init() {
+	if File_portfly_v1_portfly_proto != nil {
+		return
+	}
+	file_portfly_v1_portfly_proto_msgTypes[5].OneofWrappers = []any{}
+	file_portfly_v1_portfly_proto_msgTypes[8].OneofWrappers = []any{
+		(*GetPortRequest_Id)(nil),
+		(*GetPortRequest_Name)(nil),
+	}
+	file_portfly_v1_portfly_proto_msgTypes[9].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_portfly_v1_portfly_proto_rawDesc), len(file_portfly_v1_portfly_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_portfly_v1_portfly_proto_goTypes,
+		DependencyIndexes: file_portfly_v1_portfly_proto_depIdxs,
+		MessageInfos:      file_portfly_v1_portfly_proto_msgTypes,
+	}.Build()
+	File_portfly_v1_portfly_proto = out.File
+	file_portfly_v1_portfly_proto_goTypes = nil
+	file_portfly_v1_portfly_proto_depIdxs = nil
+}
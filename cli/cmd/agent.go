@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/core/agent"
+	"github.com/aqz236/port-fly/core/manager"
+)
+
+// agentCmd runs a remote agent connected to the server
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run tunnels on this machine on behalf of a PortFly server",
+	Long: `Connect to a PortFly server over an outbound WebSocket and open tunnels on
+this machine when the server asks. Use it to put a forward's listener on a
+laptop or a jump box that the server cannot reach directly.
+
+An agent behind NAT can also expose services on its network through the
+server with --expose name=host:port[@server-port]; the server listens on
+127.0.0.1 (or the given port) and relays each client over the agent's
+connection, so no inbound firewall rule is needed.
+
+The server must be started with PORTFLY_AGENT_TOKEN (shared) or
+PORTFLY_AGENT_TOKENS (name=token,...) set; pass the matching token with
+--token or the PORTFLY_AGENT_TOKEN environment variable. The agent
+reconnects automatically and keeps its tunnels running while disconnected.
+
+Examples:
+  portfly agent --server https://portfly.internal:8080 --token s3cret
+  PORTFLY_AGENT_TOKEN=s3cret portfly agent --name laptop
+  portfly agent --name onprem --expose db=10.0.0.5:5432@15432 --expose wiki=localhost:8080`,
+	Args: cobra.NoArgs,
+	RunE: runAgent,
+}
+
+var (
+	agentName   string
+	agentToken  string
+	agentExpose []string
+)
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+
+	agentCmd.Flags().StringVar(&agentName, "name", "", "Agent name shown on the server (default: hostname)")
+	agentCmd.Flags().StringVar(&agentToken, "token", "", "Agent token configured on the server (default: $PORTFLY_AGENT_TOKEN)")
+	agentCmd.Flags().StringArrayVar(&agentExpose, "expose", nil, "Expose a local service through the server: name=host:port[@server-port] (repeatable)")
+}
+
+func runAgent(cmd *cobra.Command, args []string) error {
+	token := agentToken
+	if token == "" {
+		token = os.Getenv("PORTFLY_AGENT_TOKEN")
+	}
+	if token == "" {
+		return fmt.Errorf("an agent token is required (--token or PORTFLY_AGENT_TOKEN)")
+	}
+
+	var services []agent.ExposePayload
+	for _, spec := range agentExpose {
+		service, err := parseExposeSpec(spec)
+		if err != nil {
+			return err
+		}
+		services = append(services, service)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sessionMgr := manager.NewSessionManager(config.SSH, logger)
+	a := agent.New(agent.Config{
+		ServerURL: agentWebSocketURL(resolveServerURL()),
+		Name:      agentName,
+		Token:     token,
+		Version:   version,
+		Expose:    services,
+	}, sessionMgr, logger)
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Agent connecting to %s. Press Ctrl+C to stop.\n", resolveServerURL())
+	return a.Run(ctx)
+}
+
+// agentWebSocketURL converts the server's HTTP URL to the agent endpoint
+func agentWebSocketURL(serverURL string) string {
+	url := strings.TrimSuffix(serverURL, "/")
+	switch {
+	case strings.HasPrefix(url, "https://"):
+		url = "wss://" + strings.TrimPrefix(url, "https://")
+	case strings.HasPrefix(url, "http://"):
+		url = "ws://" + strings.TrimPrefix(url, "http://")
+	}
+	return url + agent.WebSocketPath
+}
+
+// parseExposeSpec parses name=host:port[@server-port]
+func parseExposeSpec(spec string) (agent.ExposePayload, error) {
+	name, target, found := strings.Cut(spec, "=")
+	if !found || name == "" || target == "" {
+		return agent.ExposePayload{}, fmt.Errorf("invalid --expose %q: expected name=host:port[@server-port]", spec)
+	}
+
+	service := agent.ExposePayload{Name: name, Target: target}
+	if target, port, found := strings.Cut(target, "@"); found {
+		remotePort, err := strconv.Atoi(port)
+		if err != nil || remotePort < 1 || remotePort > 65535 {
+			return agent.ExposePayload{}, fmt.Errorf("invalid --expose %q: bad server port %q", spec, port)
+		}
+		service.Target = target
+		service.RemotePort = remotePort
+	}
+	if _, _, err := net.SplitHostPort(service.Target); err != nil {
+		return agent.ExposePayload{}, fmt.Errorf("invalid --expose %q: %w", spec, err)
+	}
+	return service, nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// ansibleInventoryCmd prints the managed hosts as an Ansible dynamic inventory
+var ansibleInventoryCmd = &cobra.Command{
+	Use:   "ansible-inventory",
+	Short: "Print managed hosts as an Ansible dynamic inventory",
+	Long: `Print the projects, groups and hosts managed by the PortFly server as an
+Ansible dynamic inventory. Projects and groups become nested Ansible groups
+and each host carries ansible_host, ansible_port, ansible_user, its private
+key path and proxy settings, so playbooks run against the same inventory as
+PortFly.
+
+The command speaks the inventory script protocol (--list, --host <name>), so
+a two-line wrapper can be passed to ansible -i. Passwords and private keys
+are never exported; key-authenticated hosts point at <identity-dir>/<name>,
+the same files as portfly ssh-config.
+
+Examples:
+  portfly ansible-inventory --list
+  portfly ansible-inventory --project 2 --prefix pf-
+
+  printf '#!/bin/sh\nexec portfly ansible-inventory --group 3 "$@"\n' > portfly.sh
+  chmod +x portfly.sh && ansible -i portfly.sh all -m ping`,
+	Args: cobra.NoArgs,
+	RunE: runAnsibleInventory,
+}
+
+var (
+	ansibleInventoryList        bool
+	ansibleInventoryHost        string
+	ansibleInventoryGroup       uint
+	ansibleInventoryProject     uint
+	ansibleInventoryPrefix      string
+	ansibleInventoryIdentityDir string
+)
+
+func init() {
+	rootCmd.AddCommand(ansibleInventoryCmd)
+
+	ansibleInventoryCmd.Flags().BoolVar(&ansibleInventoryList, "list", false, "Print the whole inventory (the default)")
+	ansibleInventoryCmd.Flags().StringVar(&ansibleInventoryHost, "host", "", "Print the variables of this host only")
+	ansibleInventoryCmd.Flags().UintVar(&ansibleInventoryGroup, "group", 0, "Only export this group and its subgroups")
+	ansibleInventoryCmd.Flags().UintVar(&ansibleInventoryProject, "project", 0, "Only export this project and its subprojects")
+	ansibleInventoryCmd.Flags().StringVar(&ansibleInventoryPrefix, "prefix", "", "Prefix for host names")
+	ansibleInventoryCmd.Flags().StringVar(&ansibleInventoryIdentityDir, "identity-dir", "", "Directory of private key files (default ~/.ssh/portfly)")
+}
+
+func runAnsibleInventory(cmd *cobra.Command, args []string) error {
+	query := url.Values{}
+	if ansibleInventoryGroup != 0 {
+		query.Set("group_id", strconv.FormatUint(uint64(ansibleInventoryGroup), 10))
+	}
+	if ansibleInventoryProject != 0 {
+		query.Set("project_id", strconv.FormatUint(uint64(ansibleInventoryProject), 10))
+	}
+	if ansibleInventoryPrefix != "" {
+		query.Set("prefix", ansibleInventoryPrefix)
+	}
+	if ansibleInventoryIdentityDir != "" {
+		query.Set("identity_dir", ansibleInventoryIdentityDir)
+	}
+	if cmd.Flags().Changed("host") && !ansibleInventoryList {
+		query.Set("host", ansibleInventoryHost)
+	}
+
+	path := "/export/ansible"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return newAPIClient().download(path, cmd.OutOrStdout())
+}
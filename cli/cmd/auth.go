@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/aqz236/port-fly/core/keychain"
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// authCmd groups the commands managing credentials in the OS keychain
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Manage credentials stored in the OS keychain",
+	Long: `Store SSH passwords, private key passphrases and PortFly server API tokens
+in the OS keychain (macOS Keychain, the Secret Service on Linux and BSD, DPAPI
+on Windows) instead of prompting every time.
+
+Passwords and passphrases entered at a prompt are also stored once the
+connection succeeds. Use --no-keychain or PORTFLY_NO_KEYCHAIN=1 on headless
+machines or wherever nothing should be stored.`,
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login [[user@]hostname[:port]]",
+	Short: "Store a credential in the OS keychain",
+	Long: `Store a credential in the OS keychain: the SSH password for [user@]hostname,
+the passphrase of the key given with -i, or, without a host, the API token of
+the PortFly server.
+
+Examples:
+  portfly auth login deploy@bastion.example.com
+  portfly auth login -i ~/.ssh/id_ed25519
+  portfly auth login --server https://portfly.internal
+  echo "$TOKEN" | portfly auth login --stdin`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAuthLogin,
+
+	ValidArgsFunction: completeHostTargets,
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout [[user@]hostname[:port]]",
+	Short: "Remove a credential from the OS keychain",
+	Long: `Remove a credential from the OS keychain: the SSH password for
+[user@]hostname, the passphrase of the key given with -i, or, without a host,
+the API token of the PortFly server.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAuthLogout,
+
+	ValidArgsFunction: completeHostTargets,
+}
+
+var (
+	noKeychain     bool
+	authIdentity   string
+	authPort       int
+	authFromStdin  bool
+	pendingSecrets []pendingSecret
+)
+
+// pendingSecret is a credential entered at a prompt, stored once it worked
+type pendingSecret struct {
+	account string
+	secret  string
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+	authCmd.AddCommand(authLoginCmd, authLogoutCmd)
+
+	rootCmd.PersistentFlags().BoolVar(&noKeychain, "no-keychain", false,
+		"Neither read nor store credentials in the OS keychain (default $PORTFLY_NO_KEYCHAIN)")
+
+	for _, cmd := range []*cobra.Command{authLoginCmd, authLogoutCmd} {
+		cmd.Flags().StringVarP(&authIdentity, "identity", "i", "", "Private key whose passphrase is stored or removed")
+		cmd.Flags().IntVarP(&authPort, "port", "p", 22, "SSH port")
+	}
+	authLoginCmd.Flags().BoolVar(&authFromStdin, "stdin", false, "Read the credential from stdin instead of prompting")
+}
+
+// keychainEnabled reports whether credentials may be read from and stored
+// in the OS keychain
+func keychainEnabled() bool {
+	if noKeychain {
+		return false
+	}
+	disabled, _ := strconv.ParseBool(os.Getenv("PORTFLY_NO_KEYCHAIN"))
+	return !disabled
+}
+
+// sshPasswordAccount names the keychain entry of an SSH password
+func sshPasswordAccount(config models.SSHConnectionConfig) string {
+	return fmt.Sprintf("ssh:%s@%s", config.Username, net.JoinHostPort(config.Host, strconv.Itoa(config.Port)))
+}
+
+// keyPassphraseAccount names the keychain entry of a private key's passphrase
+func keyPassphraseAccount(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return "key:" + path
+}
+
+// apiTokenAccount names the keychain entry of a server's API token
+func apiTokenAccount(server string) string {
+	return "api:" + strings.TrimRight(server, "/")
+}
+
+// lookupSecret returns the stored credential for account, if any
+func lookupSecret(account string) (string, bool) {
+	if !keychainEnabled() {
+		return "", false
+	}
+	secret, err := keychain.Get(account)
+	if err != nil {
+		if !errors.Is(err, keychain.ErrNotFound) && logger != nil {
+			logger.Debug("keychain unavailable", "account", account, "error", err)
+		}
+		return "", false
+	}
+	return secret, true
+}
+
+// promptSecret returns the stored credential for account, or prompts for it
+// and keeps it to be stored by saveSecrets once the connection succeeds
+func promptSecret(account, prompt string) (string, error) {
+	if secret, ok := lookupSecret(account); ok {
+		return secret, nil
+	}
+	secret, err := readPassword(prompt)
+	if err != nil {
+		return "", err
+	}
+	if keychainEnabled() {
+		pendingSecrets = append(pendingSecrets, pendingSecret{account: account, secret: secret})
+	}
+	return secret, nil
+}
+
+// saveSecrets stores the credentials entered at prompts; called after they
+// were used successfully
+func saveSecrets() {
+	for _, pending := range pendingSecrets {
+		if err := keychain.Set(pending.account, pending.secret); err != nil {
+			if errors.Is(err, keychain.ErrUnsupported) {
+				logger.Debug("not storing credential", "account", pending.account, "error", err)
+				continue
+			}
+			logger.Warn("failed to store credential", "account", pending.account, "error", err)
+			continue
+		}
+		logger.Info("credential stored in keychain", "account", pending.account)
+	}
+	pendingSecrets = nil
+}
+
+// keyPassphrase returns the passphrase of an encrypted private key, from the
+// keychain or a prompt, and "" for keys without one
+func keyPassphrase(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Reported when the key is loaded
+		return "", nil
+	}
+	var missing *ssh.PassphraseMissingError
+	if _, err := ssh.ParseRawPrivateKey(data); !errors.As(err, &missing) {
+		return "", nil
+	}
+	return promptSecret(keyPassphraseAccount(path), fmt.Sprintf("Enter passphrase for key %s: ", path))
+}
+
+// authAccount returns the keychain entry the login and logout commands act on
+func authAccount(args []string) (account, prompt string, err error) {
+	switch {
+	case authIdentity != "":
+		return keyPassphraseAccount(authIdentity), fmt.Sprintf("Enter passphrase for key %s: ", authIdentity), nil
+	case len(args) == 1:
+		config, err := parseSSHTarget(args[0])
+		if err != nil {
+			return "", "", fmt.Errorf("invalid SSH target: %w", err)
+		}
+		if authPort != 22 {
+			config.Port = authPort
+		}
+		return sshPasswordAccount(config), fmt.Sprintf("Enter SSH password for %s@%s: ", config.Username, config.Host), nil
+	default:
+		server := resolveServerURL()
+		return apiTokenAccount(server), fmt.Sprintf("Enter API token for %s: ", server), nil
+	}
+}
+
+func runAuthLogin(cmd *cobra.Command, args []string) error {
+	account, prompt, err := authAccount(args)
+	if err != nil {
+		return err
+	}
+
+	var secret string
+	if authFromStdin {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read credential: %w", err)
+		}
+		secret = strings.TrimRight(string(data), "\r\n")
+	} else if secret, err = readPassword(prompt); err != nil {
+		return err
+	}
+	if secret == "" {
+		return fmt.Errorf("empty credential")
+	}
+
+	if err := keychain.Set(account, secret); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Stored %s in the OS keychain\n", account)
+	return nil
+}
+
+func runAuthLogout(cmd *cobra.Command, args []string) error {
+	account, _, err := authAccount(args)
+	if err != nil {
+		return err
+	}
+
+	if err := keychain.Delete(account); err != nil {
+		if errors.Is(err, keychain.ErrNotFound) {
+			return fmt.Errorf("no credential stored for %s", account)
+		}
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Removed %s from the OS keychain\n", account)
+	return nil
+}
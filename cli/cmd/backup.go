@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/server/backup"
+)
+
+// backupCmd groups commands that create and restore database backups
+var backupCmd = &cobra.Command{
+	Use:     "backup",
+	Aliases: []string{"backups"},
+	Short:   "Create, list and restore encrypted backups of the server database",
+	Long: `Manage encrypted snapshots of the PortFly server database.
+
+The server must be started with PORTFLY_BACKUP_PASSPHRASE set; snapshots are
+encrypted with it and written to its backup directory, where the newest ones
+are kept. Restoring replaces the server's data with the snapshot.
+
+Examples:
+  portfly backup create
+  portfly backup list
+  portfly backup download portfly-20260101-030000.db.enc -f offsite.db.enc
+  portfly backup restore portfly-20260101-030000.db.enc
+  portfly backup restore --file offsite.db.enc`,
+}
+
+var backupFile string
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+
+	backupCmd.AddCommand(&cobra.Command{
+		Use:   "create",
+		Short: "Take a backup now",
+		Args:  cobra.NoArgs,
+		RunE:  runBackupCreate,
+	})
+	backupCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List backups on the server, newest first",
+		Args:  cobra.NoArgs,
+		RunE:  runBackupList,
+	})
+
+	downloadCmd := &cobra.Command{
+		Use:   "download <name>",
+		Short: "Download an encrypted backup",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runBackupDownload,
+	}
+	downloadCmd.Flags().StringVarP(&backupFile, "file", "f", "", "File to write (default: the backup name)")
+	backupCmd.AddCommand(downloadCmd)
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore [name]",
+		Short: "Restore the server database from a backup",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runBackupRestore,
+	}
+	restoreCmd.Flags().StringVarP(&backupFile, "file", "f", "", "Upload and restore a local backup file instead")
+	backupCmd.AddCommand(restoreCmd)
+}
+
+func runBackupCreate(cmd *cobra.Command, args []string) error {
+	var info backup.Info
+	if err := newAdminClient().post("/admin/backup", nil, &info); err != nil {
+		return err
+	}
+
+	return printResult(info, func(w io.Writer) error {
+		fmt.Fprintf(w, "Created:\t%s\t%s\n", info.Name, formatBytes(info.Size))
+		return nil
+	})
+}
+
+func runBackupList(cmd *cobra.Command, args []string) error {
+	var backups []backup.Info
+	if err := newAdminClient().get("/admin/backups", &backups); err != nil {
+		return err
+	}
+
+	return printResult(backups, func(w io.Writer) error {
+		if len(backups) == 0 {
+			fmt.Fprintln(w, "No backups.")
+			return nil
+		}
+		fmt.Fprintln(w, "NAME\tSIZE\tCREATED")
+		for _, info := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", info.Name, formatBytes(info.Size), formatTime(&info.CreatedAt))
+		}
+		return nil
+	})
+}
+
+func runBackupDownload(cmd *cobra.Command, args []string) error {
+	path := backupFile
+	if path == "" {
+		path = args[0]
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	err = newAdminClient().download("/admin/backups/"+url.PathEscape(args[0]), file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Downloaded %s to %s\n", args[0], path)
+	return nil
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) error {
+	if (len(args) == 0) == (backupFile == "") {
+		return fmt.Errorf("specify either a backup name or --file")
+	}
+
+	client := newAdminClient()
+	source := backupFile
+	if backupFile != "" {
+		file, err := os.Open(backupFile)
+		if err != nil {
+			return fmt.Errorf("failed to open backup: %w", err)
+		}
+		defer file.Close()
+		if err := client.upload("/admin/restore", "application/octet-stream", file, nil); err != nil {
+			return err
+		}
+	} else {
+		source = args[0]
+		if err := client.post("/admin/restore", map[string]string{"name": args[0]}, nil); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Restored server database from %s\n", source)
+	return nil
+}
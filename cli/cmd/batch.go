@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// batchCmd runs several API operations in one request
+var batchCmd = &cobra.Command{
+	Use:   "batch <file>",
+	Short: "Run a list of operations (create hosts, start ports, ...) in one request",
+	Long: `Send a list of operations to the server, which runs them with bounded
+concurrency and reports each one's outcome. Operations are independent: one
+failing does not stop the others, and the command exits with status 2 when
+only some of them failed.
+
+The file (or - for stdin) is JSON or YAML, either a list of operations or an
+object with "operations" and "concurrency". Each operation has an op, an id
+for the ones acting on an existing host, port or forward, and the data sent
+as the request body:
+
+  - op: create_host
+    data: {name: web-1, hostname: 10.0.0.11, username: deploy, group_id: 3}
+  - op: start_port
+    id: "12"
+  - op: delete_forward
+    id: "4"
+
+Ops: create_host, update_host, delete_host, create_port, update_port,
+delete_port, start_port, stop_port, create_forward, delete_forward.
+Operations run in no particular order; use --concurrency 1 to run them in
+the order of the file.
+
+Examples:
+  portfly batch provision.yaml
+  portfly batch --concurrency 1 hosts.json -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBatch,
+}
+
+var batchConcurrency int
+
+// batchReport mirrors the server's batch response
+type batchReport struct {
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Duration  int64             `json:"duration"`
+	Results   []batchItemResult `json:"results"`
+}
+
+type batchItemResult struct {
+	Index   int             `json:"index"`
+	Op      string          `json:"op"`
+	ID      string          `json:"id,omitempty"`
+	Status  int             `json:"status"`
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+
+	batchCmd.Flags().IntVar(&batchConcurrency, "concurrency", 0, "Operations run at the same time (default 4, at most 16)")
+}
+
+func runBatch(cmd *cobra.Command, args []string) error {
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	// YAML is a superset of JSON, so one decoder reads both
+	var parsed any
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", args[0], err)
+	}
+	request, ok := parsed.(map[string]any)
+	if !ok {
+		request = map[string]any{"operations": parsed}
+	}
+	// IDs are strings on the wire, YAML reads bare numbers as ints
+	if operations, ok := request["operations"].([]any); ok {
+		for _, operation := range operations {
+			if fields, ok := operation.(map[string]any); ok && fields["id"] != nil {
+				fields["id"] = fmt.Sprint(fields["id"])
+			}
+		}
+	}
+	if batchConcurrency != 0 {
+		request["concurrency"] = batchConcurrency
+	}
+
+	var report batchReport
+	if err := newAPIClient().post("/batch", request, &report); err != nil {
+		return err
+	}
+
+	if err := printResult(report, func(w io.Writer) error {
+		fmt.Fprintln(w, "#\tOP\tID\tSTATUS\tERROR")
+		for _, result := range report.Results {
+			status := "-"
+			if result.Status != 0 {
+				status = strconv.Itoa(result.Status)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", result.Index, result.Op, valueOrDash(result.ID), status, valueOrDash(result.Error))
+		}
+		fmt.Fprintf(w, "\n%d of %d operations succeeded.\n", report.Succeeded, report.Total)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	batch := BatchResult{Succeeded: report.Succeeded, Failed: report.Failed}
+	return batch.Err()
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// benchCmd measures a running port tunnel on the server
+var benchCmd = &cobra.Command{
+	Use:   "bench <port id|name>",
+	Short: "Measure latency and throughput of a running port tunnel",
+	Long: `Run a short latency and throughput test through a port's running tunnel
+and compare it with the direct path.
+
+The echo mode (default) measures the SSH connection against cat on the host
+and compares its round trips with opening a TCP connection to the SSH server.
+The target mode connects through the tunnel to the target and compares with
+connecting to the target directly: HTTP targets are timed by request and
+downloaded from --path for throughput, other targets only by connection setup.
+
+Examples:
+  portfly bench 12
+  portfly bench web --mode target --path /static/large.bin
+  portfly bench staging-db --mode target --samples 100 -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBench,
+
+	ValidArgsFunction: completePortRefs,
+}
+
+var (
+	benchMode     string
+	benchSamples  int
+	benchBytes    int64
+	benchDuration time.Duration
+	benchTimeout  time.Duration
+	benchHTTP     bool
+	benchPath     string
+)
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().StringVar(&benchMode, "mode", models.BenchmarkEcho, "What to measure: echo (the SSH connection) or target")
+	benchCmd.Flags().IntVar(&benchSamples, "samples", models.DefaultBenchmarkSamples, "Number of round trips")
+	benchCmd.Flags().Int64Var(&benchBytes, "bytes", models.DefaultBenchmarkBytes, "Most bytes moved by the throughput test")
+	benchCmd.Flags().DurationVar(&benchDuration, "duration", models.DefaultBenchmarkDuration, "Longest the throughput test runs")
+	benchCmd.Flags().DurationVar(&benchTimeout, "timeout", models.DefaultBenchmarkTimeout, "Limit of each round trip")
+	benchCmd.Flags().BoolVar(&benchHTTP, "http", false, "Treat the target as HTTP (default: from the target port's service type)")
+	benchCmd.Flags().StringVar(&benchPath, "path", "/", "Path requested from HTTP targets")
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	req := map[string]any{
+		"mode":     benchMode,
+		"samples":  benchSamples,
+		"bytes":    benchBytes,
+		"duration": benchDuration.Milliseconds(),
+		"timeout":  benchTimeout.Milliseconds(),
+		"path":     benchPath,
+	}
+	if cmd.Flags().Changed("http") {
+		req["http"] = benchHTTP
+	}
+
+	// The server bounds the benchmark
+	client := newAPIClient()
+	client.httpClient.Timeout = 0
+	var result models.BenchmarkResult
+	if err := client.post("/ports/"+url.PathEscape(args[0])+"/benchmark", req, &result); err != nil {
+		return err
+	}
+
+	return printResult(result, func(w io.Writer) error {
+		fmt.Fprintf(w, "Mode:\t%s (%s)\n\n", result.Mode, time.Duration(result.Duration)*time.Millisecond)
+		fmt.Fprintln(w, "PATH\tADDRESS\tMIN\tAVG\tP50\tP90\tP99\tMAX\tFAILED\tTHROUGHPUT")
+		printBenchPath(w, "tunnel", result.Tunnel)
+		if result.Direct != nil {
+			printBenchPath(w, "direct", *result.Direct)
+		}
+		return nil
+	})
+}
+
+// printBenchPath writes a table row for one measured path
+func printBenchPath(w io.Writer, name string, path models.BenchmarkPath) {
+	if path.RTT == nil && path.Throughput == nil {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, valueOrDash(path.Address), "error: "+path.Error)
+		return
+	}
+
+	latency := "-\t-\t-\t-\t-\t-\t-"
+	if rtt := path.RTT; rtt != nil && rtt.Samples > 0 {
+		latency = fmt.Sprintf("%.2fms\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%d/%d",
+			rtt.Min, rtt.Avg, rtt.P50, rtt.P90, rtt.P99, rtt.Max, rtt.Failed, rtt.Samples+rtt.Failed)
+	}
+	throughput := "-"
+	if path.Throughput != nil {
+		throughput = fmt.Sprintf("%.2f MB/s", path.Throughput.MBps)
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, path.Address, latency, throughput)
+	if path.Error != "" {
+		fmt.Fprintf(w, "\t\terror: %s\n", path.Error)
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// authChallenge is a keyboard-interactive question, such as a one-time
+// password, a host asked while the server connected to it
+type authChallenge struct {
+	ID          string   `json:"id"`
+	HostName    string   `json:"host_name"`
+	Instruction string   `json:"instruction,omitempty"`
+	Questions   []string `json:"questions"`
+	Echos       []bool   `json:"echos"`
+}
+
+// answerChallenge prompts for the answers to a challenge event and sends
+// them back to the server. Failing to prompt only costs the host, so it is
+// reported without ending the stream.
+func answerChallenge(client *apiClient, data []byte) error {
+	var challenge authChallenge
+	if err := json.Unmarshal(data, &challenge); err != nil {
+		return fmt.Errorf("failed to decode challenge: %w", err)
+	}
+
+	if challenge.Instruction != "" {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", challenge.HostName, strings.TrimSpace(challenge.Instruction))
+	}
+	answers := make([]string, len(challenge.Questions))
+	for i, question := range challenge.Questions {
+		prompt := challenge.HostName + ": " + question
+		var err error
+		if i < len(challenge.Echos) && challenge.Echos[i] {
+			answers[i], err = readLine(prompt)
+		} else {
+			answers[i], err = readPassword(prompt)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: cannot answer authentication challenge: %v\n", challenge.HostName, err)
+			return nil
+		}
+	}
+
+	if err := client.post("/auth/challenges/"+url.PathEscape(challenge.ID), map[string]any{"answers": answers}, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: failed to answer authentication challenge: %v\n", challenge.HostName, err)
+	}
+	return nil
+}
+
+// readLine prompts on stderr and reads a visible answer from the terminal
+func readLine(prompt string) (string, error) {
+	in := os.Stdin
+	if !term.IsTerminal(int(in.Fd())) {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			return "", fmt.Errorf("no terminal: %w", err)
+		}
+		defer tty.Close()
+		in = tty
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	compat "github.com/aqz236/port-fly/core/version"
+)
+
+// serverURL is the PortFly server the CLI talks to
+var serverURL string
+
+// adminSocket is the unix socket of the server's admin API, when it has one
+var adminSocket string
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&serverURL, "server", "",
+		"PortFly server URL (default $PORTFLY_SERVER or the configured server address)")
+	rootCmd.PersistentFlags().StringVar(&adminSocket, "admin-socket", "",
+		"admin API unix socket of a local server (default $PORTFLY_ADMIN_SOCKET)")
+}
+
+// apiResponse mirrors the server's response envelope
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// apiError is an error response from the server
+type apiError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	return "server error: " + e.Message
+}
+
+// isNotFound reports whether err is a 404 response from the server
+func isNotFound(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// apiClient is a minimal client for the PortFly REST API
+type apiClient struct {
+	baseURL    string
+	httpClient *http.Client
+	token      string // API token sent as a bearer token, from the OS keychain
+
+	// Admin credentials for a server serving the admin API on the network
+	adminUser     string
+	adminPassword string
+}
+
+// newAPIClient creates a client for the server selected by flags, env or config
+func newAPIClient() *apiClient {
+	server := resolveServerURL()
+	token, _ := lookupSecret(apiTokenAccount(server))
+	return &apiClient{
+		baseURL:    strings.TrimRight(server, "/") + "/api/v1",
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		token:      token,
+	}
+}
+
+// newAdminClient creates a client for the admin API: on the admin socket
+// when there is one, otherwise on the server URL with the admin credentials
+// from PORTFLY_ADMIN_USER and PORTFLY_ADMIN_PASSWORD
+func newAdminClient() *apiClient {
+	socket := adminSocket
+	if socket == "" {
+		socket = os.Getenv("PORTFLY_ADMIN_SOCKET")
+	}
+	if socket == "" {
+		client := newAPIClient()
+		client.adminUser = os.Getenv("PORTFLY_ADMIN_USER")
+		client.adminPassword = os.Getenv("PORTFLY_ADMIN_PASSWORD")
+		return client
+	}
+
+	dialer := &net.Dialer{}
+	return &apiClient{
+		baseURL: "http://portfly-admin/api/v1",
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			},
+		},
+	}
+}
+
+// resolveServerURL picks the server URL from --server, PORTFLY_SERVER or the config file
+func resolveServerURL() string {
+	if serverURL != "" {
+		return serverURL
+	}
+	if env := os.Getenv("PORTFLY_SERVER"); env != "" {
+		return env
+	}
+
+	host := config.Server.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	scheme := "http"
+	if config.Server.TLS.Enabled {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(config.Server.Port)))
+}
+
+// get performs a GET request and decodes the response data into out
+func (c *apiClient) get(path string, out any) error {
+	return c.do(http.MethodGet, path, nil, out)
+}
+
+// post performs a POST request and decodes the response data into out
+func (c *apiClient) post(path string, body, out any) error {
+	return c.do(http.MethodPost, path, body, out)
+}
+
+// upload POSTs a raw request body, e.g. a file, and decodes the response data into out
+func (c *apiClient) upload(path, contentType string, body io.Reader, out any) error {
+	return c.send(http.MethodPost, path, contentType, body, out)
+}
+
+// download GETs a raw response body and copies it to w
+func (c *apiClient) download(path string, w io.Writer) error {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach server at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+	if err := checkServerVersion(resp); err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 400 {
+		// Errors still come wrapped in the response envelope
+		var envelope apiResponse
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error == "" {
+			envelope.Error = resp.Status
+		}
+		return &apiError{StatusCode: resp.StatusCode, Message: envelope.Error}
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	return nil
+}
+
+// stream POSTs body asking for server-sent events and calls event with the
+// name and data of each one until the server ends the stream
+func (c *apiClient) stream(path, contentType string, body io.Reader, event func(name string, data []byte) error) error {
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "text/event-stream")
+	c.authorize(req)
+
+	// Streams last as long as the server keeps sending
+	client := *c.httpClient
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach server at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+	if err := checkServerVersion(resp); err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 400 {
+		var envelope apiResponse
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || envelope.Error == "" {
+			envelope.Error = resp.Status
+		}
+		return &apiError{StatusCode: resp.StatusCode, Message: envelope.Error}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	var name string
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				if err := event(name, data.Bytes()); err != nil {
+					return err
+				}
+			}
+			name = ""
+			data.Reset()
+		case strings.HasPrefix(line, "event:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event stream: %w", err)
+	}
+	return nil
+}
+
+// authorize adds the API token, if any, to a request, along with the actor
+// the server records in the change history
+func (c *apiClient) authorize(req *http.Request) {
+	if c.adminUser != "" {
+		req.SetBasicAuth(c.adminUser, c.adminPassword)
+	} else if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if actor := currentActor(); actor != "" {
+		req.Header.Set("X-PortFly-Actor", actor)
+	}
+	req.Header.Set(compat.HeaderVersion, version)
+	req.Header.Set(compat.HeaderAPIRevision, strconv.Itoa(compat.APIRevision))
+}
+
+// currentActor identifies the local user as user@hostname
+func currentActor() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if name == "" {
+		return ""
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return name + "@" + hostname
+	}
+	return name
+}
+
+// do sends a request and unwraps the response envelope
+func (c *apiClient) do(method, path string, body, out any) error {
+	if body == nil {
+		return c.send(method, path, "", nil, out)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	return c.send(method, path, "application/json", bytes.NewReader(payload), out)
+}
+
+// send performs a request with an encoded body and unwraps the response envelope
+func (c *apiClient) send(method, path, contentType string, body io.Reader, out any) error {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.authorize(req)
+
+	started := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logger.Debug("API request failed", "method", method, "url", req.URL.String(), "error", err)
+		return fmt.Errorf("failed to reach server at %s: %w", c.baseURL, err)
+	}
+	defer resp.Body.Close()
+	logger.Debug("API request", "method", method, "url", req.URL.String(), "status", resp.StatusCode, "duration", time.Since(started))
+	if err := checkServerVersion(resp); err != nil {
+		return err
+	}
+
+	var envelope apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("unexpected response from server (%s): %w", resp.Status, err)
+	}
+
+	if !envelope.Success || resp.StatusCode >= 400 {
+		if envelope.Error == "" {
+			envelope.Error = resp.Status
+		}
+		return &apiError{StatusCode: resp.StatusCode, Message: envelope.Error}
+	}
+
+	if out != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
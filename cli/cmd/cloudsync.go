@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/server/cloudsync"
+)
+
+// cloudSyncCmd shows and runs the server's cloud host sync
+var cloudSyncCmd = &cobra.Command{
+	Use:   "cloud-sync",
+	Short: "Show and run the sync of hosts from AWS, GCP and Azure instances",
+	Long: `The server keeps the hosts of a group in step with the instances of a cloud
+account when sources are configured in the file named by
+PORTFLY_CLOUD_SYNC_FILE. New instances become hosts, changed addresses,
+names and tags are updated, and the hosts of terminated instances are
+archived; they can be restored from the host's history.
+
+Examples:
+  portfly cloud-sync status
+  portfly cloud-sync run prod-ec2`,
+}
+
+func init() {
+	rootCmd.AddCommand(cloudSyncCmd)
+
+	cloudSyncCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "List the sync sources and their last run",
+		Args:  cobra.NoArgs,
+		RunE:  runCloudSyncStatus,
+	})
+	cloudSyncCmd.AddCommand(&cobra.Command{
+		Use:   "run <source>",
+		Short: "Sync a source now",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runCloudSyncRun,
+	})
+}
+
+func runCloudSyncStatus(cmd *cobra.Command, args []string) error {
+	var statuses []cloudsync.Status
+	if err := newAPIClient().get("/cloud-sync", &statuses); err != nil {
+		return err
+	}
+
+	return printResult(statuses, func(w io.Writer) error {
+		fmt.Fprintln(w, "SOURCE\tPROVIDER\tGROUP\tINTERVAL\tLAST SYNC\tCREATED\tUPDATED\tARCHIVED\tERROR")
+		for _, status := range statuses {
+			if status.Last == nil {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%s\t-\t-\t-\t-\t-\n", status.Name, status.Provider, status.GroupID, status.Interval)
+				continue
+			}
+			last := status.Last
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%d\t%d\t%d\t%s\n",
+				status.Name, status.Provider, status.GroupID, status.Interval, formatTime(&last.StartedAt),
+				last.Created, last.Updated, last.Archived, valueOrDash(last.Error))
+		}
+		return nil
+	})
+}
+
+func runCloudSyncRun(cmd *cobra.Command, args []string) error {
+	var report cloudsync.Report
+	if err := newAPIClient().post("/cloud-sync/"+url.PathEscape(args[0])+"/run", nil, &report); err != nil {
+		return err
+	}
+
+	return printResult(report, func(w io.Writer) error {
+		fmt.Fprintf(w, "Source:\t%s\n", report.Source)
+		fmt.Fprintf(w, "Instances:\t%d\n", report.Instances)
+		fmt.Fprintf(w, "Created:\t%d\n", report.Created)
+		fmt.Fprintf(w, "Updated:\t%d\n", report.Updated)
+		fmt.Fprintf(w, "Archived:\t%d\n", report.Archived)
+		fmt.Fprintf(w, "Unchanged:\t%d\n", report.Unchanged)
+		fmt.Fprintf(w, "Duration:\t%.0fms\n", report.DurationMs)
+		if len(report.Skipped) > 0 {
+			fmt.Fprintf(w, "Skipped:\t%s\n", strings.Join(report.Skipped, "\n\t"))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// completionTimeout bounds server lookups so <TAB> never hangs
+const completionTimeout = 2 * time.Second
+
+// completionCmd generates shell completion scripts
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate shell completion scripts",
+	Long: `Generate a completion script for your shell.
+
+Completions include live resources from the PortFly server (host targets for
+start, session IDs and port names for sessions stop/stats), so the server
+selected by --server, $PORTFLY_SERVER or the config file must be reachable.
+
+Bash:
+  source <(portfly completion bash)
+  # or permanently:
+  portfly completion bash > /etc/bash_completion.d/portfly
+
+Zsh:
+  portfly completion zsh > "${fpath[1]}/_portfly"
+
+Fish:
+  portfly completion fish > ~/.config/fish/completions/portfly.fish
+
+PowerShell:
+  portfly completion powershell | Out-String | Invoke-Expression`,
+	DisableFlagsInUseLine: true,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			return rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(completionCmd)
+}
+
+// completionClient returns an API client for completion functions, which run
+// without the usual config initialization
+func completionClient() (*apiClient, bool) {
+	if config == nil {
+		initConfig()
+		cfg, err := loadConfig()
+		if err != nil {
+			return nil, false
+		}
+		config = cfg
+	}
+
+	client := newAPIClient()
+	client.httpClient = &http.Client{Timeout: completionTimeout}
+	return client, true
+}
+
+// completeHostTargets completes [user@]hostname targets from the server's hosts
+func completeHostTargets(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	client, ok := completionClient()
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var hosts []models.Host
+	if err := client.get("/hosts", &hosts); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for _, host := range hosts {
+		target := host.Hostname
+		if host.Username != "" {
+			target = host.Username + "@" + target
+		}
+		if host.Port != 0 && host.Port != 22 {
+			target = fmt.Sprintf("%s:%d", target, host.Port)
+		}
+		if strings.HasPrefix(target, toComplete) {
+			completions = append(completions, target+"\t"+host.Name)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSessionIDs completes tunnel session IDs from the server
+func completeSessionIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	client, ok := completionClient()
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return sessionIDCompletions(client, "/sessions", toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeSessionRefs completes active session IDs and port names for sessions stop
+func completeSessionRefs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	client, ok := completionClient()
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	completions := sessionIDCompletions(client, "/sessions/active", toComplete)
+	completions = append(completions, portNameCompletions(client, toComplete)...)
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completePortRefs completes port names for share and bench
+func completePortRefs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	client, ok := completionClient()
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return portNameCompletions(client, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeGroupNames completes group names for the --group flag
+func completeGroupNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	client, ok := completionClient()
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var groups []models.Group
+	if err := client.get("/groups", &groups); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for _, group := range groups {
+		if strings.HasPrefix(group.Name, toComplete) {
+			completions = append(completions, fmt.Sprintf("%s\t%d hosts", group.Name, len(group.Hosts)))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// portNameCompletions lists port names that start with toComplete
+func portNameCompletions(client *apiClient, toComplete string) []string {
+	var ports []models.Port
+	if err := client.get("/ports", &ports); err != nil {
+		return nil
+	}
+
+	var completions []string
+	for _, port := range ports {
+		if port.Name != "" && strings.HasPrefix(port.Name, toComplete) {
+			completions = append(completions, port.Name+"\tport "+port.GetFullAddress())
+		}
+	}
+	return completions
+}
+
+// sessionIDCompletions lists session IDs and names from path that start with toComplete
+func sessionIDCompletions(client *apiClient, path, toComplete string) []string {
+	var sessions []models.TunnelSession
+	if err := client.get(path, &sessions); err != nil {
+		return nil
+	}
+
+	var completions []string
+	for _, session := range sessions {
+		description := string(session.Status)
+		if session.LocalAddress != "" {
+			description += " " + session.LocalAddress
+		}
+
+		id := strconv.FormatUint(uint64(session.ID), 10)
+		if strings.HasPrefix(id, toComplete) {
+			completions = append(completions, id+"\t"+description)
+		}
+		if session.Name != "" && strings.HasPrefix(session.Name, toComplete) {
+			completions = append(completions, session.Name+"\t"+description)
+		}
+	}
+	return completions
+}
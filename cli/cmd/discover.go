@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/core/discovery"
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// discoverCmd finds SSH hosts on the local network
+var discoverCmd = &cobra.Command{
+	Use:   "discover [cidr...]",
+	Short: "Find SSH hosts on the local network",
+	Long: `Find SSH servers on the local network and propose them as hosts.
+
+Hosts announcing _ssh._tcp over mDNS are found without arguments; CIDR
+arguments additionally sweep the given ranges for an SSH banner on --port.
+Each result shows the hostname, the OS guessed from the SSH banner and the
+connect latency. Hosts already configured on the server are marked.
+
+The scan runs from this machine; --via-server scans from the server's network
+instead. --add creates the new hosts in a group.
+
+Examples:
+  portfly discover
+  portfly discover 192.168.1.0/24
+  portfly discover 10.0.0.0/24 --port 2222 --no-mdns
+  portfly discover 192.168.1.0/24 --add --group 3 --user pi`,
+	RunE: runDiscover,
+}
+
+var (
+	discoverPort      int
+	discoverTimeout   time.Duration
+	discoverNoMDNS    bool
+	discoverViaServer bool
+	discoverAdd       bool
+	discoverGroupID   uint
+	discoverUser      string
+)
+
+func init() {
+	rootCmd.AddCommand(discoverCmd)
+
+	discoverCmd.Flags().IntVar(&discoverPort, "port", discovery.DefaultPort, "Port to sweep in CIDR ranges")
+	discoverCmd.Flags().DurationVar(&discoverTimeout, "timeout", discovery.DefaultTimeout, "mDNS listen window and per-address connect timeout")
+	discoverCmd.Flags().BoolVar(&discoverNoMDNS, "no-mdns", false, "Skip mDNS browsing")
+	discoverCmd.Flags().BoolVar(&discoverViaServer, "via-server", false, "Scan from the server's network instead of this machine")
+	discoverCmd.Flags().BoolVar(&discoverAdd, "add", false, "Add discovered hosts that are not configured yet")
+	discoverCmd.Flags().UintVar(&discoverGroupID, "group", 0, "Group to add hosts to (with --add)")
+	discoverCmd.Flags().StringVar(&discoverUser, "user", "", "SSH username for added hosts (default: current user)")
+}
+
+func runDiscover(cmd *cobra.Command, args []string) error {
+	if discoverAdd && discoverGroupID == 0 {
+		return fmt.Errorf("--add requires --group")
+	}
+
+	client := newAPIClient()
+	var candidates []discovery.Candidate
+
+	if discoverViaServer {
+		req := map[string]any{
+			"mdns":    !discoverNoMDNS,
+			"cidrs":   args,
+			"port":    discoverPort,
+			"timeout": discoverTimeout.Milliseconds(),
+		}
+		if err := client.post("/hosts/discover", req, &candidates); err != nil {
+			return err
+		}
+	} else {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		var err error
+		candidates, err = discovery.Discover(ctx, discovery.Options{
+			MDNS:    !discoverNoMDNS,
+			CIDRs:   args,
+			Port:    discoverPort,
+			Timeout: discoverTimeout,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Marking is best effort unless hosts are about to be added
+		var hosts []models.Host
+		if err := client.get("/hosts", &hosts); err != nil {
+			if discoverAdd {
+				return err
+			}
+			logger.Debug("could not fetch hosts to mark existing ones", "error", err)
+		}
+		discovery.MarkExisting(candidates, hosts)
+	}
+
+	if discoverAdd {
+		return addDiscoveredHosts(client, candidates)
+	}
+
+	return printResult(candidates, func(w io.Writer) error {
+		if len(candidates) == 0 {
+			fmt.Fprintln(w, "No SSH hosts found.")
+			return nil
+		}
+		fmt.Fprintln(w, "ADDRESS\tPORT\tHOSTNAME\tOS\tLATENCY\tFOUND BY\tSTATUS")
+		for _, c := range candidates {
+			status := "new"
+			if c.Existing {
+				status = "configured"
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%.1fms\t%s\t%s\n",
+				c.Address, c.Port, valueOrDash(c.Hostname), valueOrDash(c.OS), c.Latency, joinDiscoverySources(c.Sources), status)
+		}
+		return nil
+	})
+}
+
+// addDiscoveredHosts creates hosts for candidates not configured yet
+func addDiscoveredHosts(client *apiClient, candidates []discovery.Candidate) error {
+	username := discoverUser
+	if username == "" {
+		username = os.Getenv("USER")
+	}
+
+	var result BatchResult
+	for _, c := range candidates {
+		if c.Existing {
+			continue
+		}
+		host := c.Host(username, discoverGroupID)
+		var created models.Host
+		err := client.post("/hosts", host, &created)
+		result.Add(ItemResult{ID: fmt.Sprint(created.ID), Name: host.Name, Status: "added"}, err)
+	}
+
+	if err := printResult(result, func(w io.Writer) error {
+		if len(result.Items) == 0 {
+			fmt.Fprintln(w, "No new hosts to add.")
+			return nil
+		}
+		for _, item := range result.Items {
+			if item.Error != "" {
+				fmt.Fprintf(w, "  Failed:\t%s\t%s\n", item.Name, item.Error)
+				continue
+			}
+			fmt.Fprintf(w, "  Added:\t%s\t%s\n", item.ID, item.Name)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return result.Err()
+}
+
+// joinDiscoverySources formats how a candidate was found
+func joinDiscoverySources(sources []discovery.Source) string {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = string(source)
+	}
+	return strings.Join(names, ",")
+}
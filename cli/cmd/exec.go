@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// execCmd runs a command on every host of a group through the server
+var execCmd = &cobra.Command{
+	Use:   "exec --group <group> <command>",
+	Short: "Run a command on every host of a group",
+	Long: `Run a shell command on all hosts of a group at once. The PortFly server
+connects to the hosts, at most --concurrency at a time, and reports each
+host's output and exit code as soon as it finishes, followed by a summary.
+
+Output lines are prefixed with the host name. The exit status is 0 when the
+command succeeded everywhere, 2 when it failed on some hosts and 1 when it
+failed on all of them.
+
+With --sudo the command runs through sudo. When sudo prompts, the server
+types the host's sudo password, or its login password, over stdin and hides
+it from the output; hosts with neither ask here. --ask-sudo-password asks
+once up front and uses that password on every host.
+
+Examples:
+  portfly exec --group db "uptime"
+  portfly exec -g db ls -la /var/lib/postgresql
+  portfly exec -g web -c 50 --timeout 2m --sudo systemctl restart nginx
+  portfly exec -g db --ask-sudo-password "cat /etc/shadow | wc -l"
+  portfly exec -g db -o json "df -h /" > report.json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runExec,
+}
+
+var (
+	execGroup       string
+	execConcurrency int
+	execTimeout     time.Duration
+	execSudo        bool
+	execAskSudo     bool
+)
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	execCmd.Flags().StringVarP(&execGroup, "group", "g", "", "Group whose hosts run the command, by name or ID")
+	execCmd.Flags().IntVarP(&execConcurrency, "concurrency", "c", 10, "Maximum number of hosts running the command at once")
+	execCmd.Flags().DurationVar(&execTimeout, "timeout", 30*time.Second, "Time limit for each host, including connecting")
+	execCmd.Flags().BoolVar(&execSudo, "sudo", false, "Run the command through sudo")
+	execCmd.Flags().BoolVar(&execAskSudo, "ask-sudo-password", false, "Prompt for the sudo password once and use it on every host (implies --sudo)")
+	// Flags after the command belong to it, e.g. portfly exec -g db ls -la
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.MarkFlagRequired("group")
+	execCmd.RegisterFlagCompletionFunc("group", completeGroupNames)
+}
+
+// execResult is one host's outcome, as streamed by the server
+type execResult struct {
+	HostID    uint   `json:"host_id"`
+	HostName  string `json:"host_name"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	ExitCode  int    `json:"exit_code"`
+	Error     string `json:"error,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Duration  int64  `json:"duration"`
+}
+
+// execReport summarizes the run over all hosts
+type execReport struct {
+	GroupID   uint         `json:"group_id"`
+	Command   string       `json:"command"`
+	Hosts     int          `json:"hosts"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Errors    int          `json:"errors"`
+	Duration  int64        `json:"duration"`
+	Results   []execResult `json:"results"`
+}
+
+func runExec(cmd *cobra.Command, args []string) error {
+	if execConcurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if execTimeout <= 0 {
+		return fmt.Errorf("--timeout must be positive")
+	}
+
+	body := map[string]any{
+		"command":     strings.Join(args, " "),
+		"timeout":     execTimeout.Milliseconds(),
+		"concurrency": execConcurrency,
+		"sudo":        execSudo || execAskSudo,
+	}
+	if execAskSudo {
+		password, err := readPassword("[sudo] password: ")
+		if err != nil {
+			return err
+		}
+		body["sudo_password"] = password
+	}
+	request, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	var report execReport
+	client := newAPIClient()
+	err = client.stream("/groups/"+url.PathEscape(execGroup)+"/execute", "application/json", bytes.NewReader(request), func(name string, data []byte) error {
+		switch name {
+		case "result":
+			// Machine-readable output only carries the final report
+			if outputFormat != OutputTable {
+				return nil
+			}
+			var result execResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				return fmt.Errorf("failed to decode result: %w", err)
+			}
+			printExecResult(result)
+		case "challenge":
+			// A host asks for e.g. a one-time password while connecting
+			return answerChallenge(client, data)
+		case "report":
+			if err := json.Unmarshal(data, &report); err != nil {
+				return fmt.Errorf("failed to decode report: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if report.Hosts == 0 {
+		return fmt.Errorf("server ended the stream without a report")
+	}
+
+	if err := printResult(report, func(w io.Writer) error {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "HOST\tEXIT\tDURATION\tERROR")
+		for _, result := range report.Results {
+			exitCode := "-"
+			if result.Error == "" {
+				exitCode = fmt.Sprint(result.ExitCode)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				result.HostName,
+				exitCode,
+				(time.Duration(result.Duration) * time.Millisecond).String(),
+				valueOrDash(result.Error))
+		}
+		fmt.Fprintf(w, "\n%d hosts: %d succeeded, %d failed, %d errors in %s\n",
+			report.Hosts, report.Succeeded, report.Failed, report.Errors,
+			(time.Duration(report.Duration) * time.Millisecond).String())
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	switch failed := report.Failed + report.Errors; {
+	case failed == 0:
+		return nil
+	case report.Succeeded == 0:
+		return &ExitError{Code: ExitFailure, Err: fmt.Errorf("command failed on all %d hosts", failed)}
+	default:
+		return &ExitError{Code: ExitPartialFailure, Err: fmt.Errorf("command failed on %d of %d hosts", failed, report.Hosts)}
+	}
+}
+
+// printExecResult writes a host's output with each line prefixed by the host
+// name, stderr to stderr
+func printExecResult(result execResult) {
+	printPrefixed(os.Stdout, result.HostName, result.Stdout)
+	printPrefixed(os.Stderr, result.HostName, result.Stderr)
+	if result.Truncated {
+		fmt.Fprintf(os.Stderr, "%s: (output truncated)\n", result.HostName)
+	}
+	if result.Error != "" {
+		fmt.Fprintf(os.Stderr, "%s: error: %s\n", result.HostName, result.Error)
+	}
+}
+
+// printPrefixed writes every line of text to w as "prefix: line"
+func printPrefixed(w io.Writer, prefix, text string) {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), len(text)+1)
+	for scanner.Scan() {
+		fmt.Fprintf(w, "%s: %s\n", prefix, scanner.Text())
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/core/importer"
+)
+
+// importCmd imports hosts from another SSH client's export
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import hosts from PuTTY, Termius, SecureCRT or OpenSSH configs",
+	Long: `Import the saved sessions of another SSH client as hosts in a group.
+
+Supported exports:
+  putty      registry export: reg export HKCU\Software\SimonTatham\PuTTY\Sessions putty.reg
+  termius    CSV export of the host list
+  securecrt  XML export (File > Export Settings)
+  openssh    client config such as ~/.ssh/config
+
+The format is detected from the file unless --format is given. Host name,
+port, user, authentication method, proxy and folder (as a tag) are carried
+over. Private keys are referenced by path only and must be added to the
+hosts afterwards; plain-text passwords in Termius exports are imported,
+SecureCRT's encrypted ones are not. Hosts that already exist are skipped.
+
+Examples:
+  portfly import putty.reg --group 3 --dry-run
+  portfly import termius.csv --group 3
+  portfly import sessions.xml --format securecrt --group 5 -o json
+  portfly import ~/.ssh/config --group 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImport,
+}
+
+var (
+	importGroupID uint
+	importFormat  string
+	importDryRun  bool
+)
+
+// importResult mirrors the server's import response
+type importResult struct {
+	Format  importer.Format    `json:"format"`
+	DryRun  bool               `json:"dry_run"`
+	Hosts   []importedHost     `json:"hosts"`
+	Skipped []importer.Skipped `json:"skipped"`
+	Created int                `json:"created"`
+}
+
+type importedHost struct {
+	importer.Entry
+	ID     uint   `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().UintVar(&importGroupID, "group", 0, "Group to add the hosts to")
+	importCmd.Flags().StringVar(&importFormat, "format", "", "Export format: putty, termius, securecrt or openssh (default: detect)")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Show what would be imported without creating hosts")
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	if importGroupID == 0 && !importDryRun {
+		return fmt.Errorf("--group is required unless --dry-run is set")
+	}
+	if importFormat != "" {
+		if _, err := importer.ParseFormat(importFormat); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	query := url.Values{}
+	query.Set("name", filepath.Base(args[0]))
+	if importGroupID != 0 {
+		query.Set("group_id", strconv.FormatUint(uint64(importGroupID), 10))
+	}
+	if importFormat != "" {
+		query.Set("format", importFormat)
+	}
+	if importDryRun {
+		query.Set("dry_run", "true")
+	}
+
+	var result importResult
+	if err := newAPIClient().upload("/hosts/import?"+query.Encode(), "application/octet-stream", file, &result); err != nil {
+		return err
+	}
+
+	if err := printResult(result, func(w io.Writer) error {
+		fmt.Fprintln(w, "STATUS\tNAME\tADDRESS\tUSER\tAUTH\tNOTES")
+		for _, host := range result.Hosts {
+			notes := strings.Join(host.Warnings, "; ")
+			if host.Error != "" {
+				notes = host.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				host.Status, host.Name, fmt.Sprintf("%s:%d", host.Hostname, host.Port),
+				valueOrDash(host.Username), host.AuthMethod, valueOrDash(notes))
+		}
+		for _, skipped := range result.Skipped {
+			fmt.Fprintf(w, "skipped\t%s\t-\t-\t-\t%s\n", skipped.Name, skipped.Reason)
+		}
+		if result.DryRun {
+			fmt.Fprintf(w, "\nDry run: %d %s session(s), nothing created.\n", len(result.Hosts), result.Format)
+		} else {
+			fmt.Fprintf(w, "\nCreated %d of %d %s session(s).\n", result.Created, len(result.Hosts), result.Format)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	batch := BatchResult{Succeeded: result.Created}
+	for _, host := range result.Hosts {
+		if host.Status == "failed" {
+			batch.Failed++
+		}
+	}
+	return batch.Err()
+}
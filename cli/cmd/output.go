@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by --output
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// Process exit codes
+const (
+	ExitOK             = 0
+	ExitFailure        = 1 // the command failed
+	ExitPartialFailure = 2 // some items of a batch failed, the rest succeeded
+)
+
+// outputFormat is the global --output value
+var outputFormat string
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", OutputTable, "Output format: table, json or yaml")
+}
+
+// validateOutputFormat checks the --output flag
+func validateOutputFormat() error {
+	switch outputFormat {
+	case OutputTable, OutputJSON, OutputYAML:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q (expected table, json or yaml)", outputFormat)
+	}
+}
+
+// printResult writes v as JSON or YAML, or calls table for table output
+func printResult(v any, table func(w io.Writer) error) error {
+	switch outputFormat {
+	case OutputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case OutputYAML:
+		// Round-trip through JSON so YAML keys match the JSON field names
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var generic any
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(generic); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if err := table(w); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+}
+
+// ItemResult is the outcome of one item of a batch operation
+type ItemResult struct {
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResult is the machine-readable result of a batch operation
+type BatchResult struct {
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Items     []ItemResult `json:"items"`
+}
+
+// Add records the outcome of one item
+func (r *BatchResult) Add(item ItemResult, err error) {
+	if err != nil {
+		item.Status = "failed"
+		item.Error = err.Error()
+		r.Failed++
+	} else {
+		r.Succeeded++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// Err returns an ExitError reflecting partial or total failure, or nil
+func (r *BatchResult) Err() error {
+	switch {
+	case r.Failed == 0:
+		return nil
+	case r.Succeeded == 0:
+		return &ExitError{Code: ExitFailure, Err: fmt.Errorf("all %d operations failed", r.Failed)}
+	default:
+		return &ExitError{Code: ExitPartialFailure, Err: fmt.Errorf("%d of %d operations failed", r.Failed, r.Failed+r.Succeeded)}
+	}
+}
+
+// ExitError carries the process exit code for a command error
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the process exit code for an error returned by Execute
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitFailure
+}
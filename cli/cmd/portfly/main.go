@@ -18,6 +18,6 @@ func main() {
 	
 	// Execute root command
 	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
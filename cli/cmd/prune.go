@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/server/retention"
+)
+
+// pruneCmd removes old session history on the server
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old tunnel sessions and session logs on the server",
+	Long: `Delete finished tunnel sessions and persisted session logs older than the
+server's retention age (90 days unless PORTFLY_RETENTION_DAYS is set), and
+report how much was removed. The server also prunes in the background; this
+runs it now, even when background pruning is disabled.
+
+Examples:
+  portfly prune
+  portfly prune --older-than-days 30`,
+	Args: cobra.NoArgs,
+	RunE: runPrune,
+}
+
+var pruneOlderThanDays int
+
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+
+	pruneCmd.Flags().IntVar(&pruneOlderThanDays, "older-than-days", 0, "Override the server's retention age")
+}
+
+func runPrune(cmd *cobra.Command, args []string) error {
+	var body any
+	if pruneOlderThanDays > 0 {
+		body = map[string]int{"older_than_days": pruneOlderThanDays}
+	}
+
+	var report retention.Report
+	if err := newAdminClient().post("/admin/prune", body, &report); err != nil {
+		return err
+	}
+
+	return printResult(report, func(w io.Writer) error {
+		fmt.Fprintf(w, "Cutoff:\t%s\n", formatTime(&report.Cutoff))
+		fmt.Fprintf(w, "Sessions removed:\t%d\n", report.SessionsRemoved)
+		fmt.Fprintf(w, "Log files removed:\t%d\n", report.LogFilesRemoved)
+		fmt.Fprintf(w, "Duration:\t%.1fms\n", report.DurationMs)
+		return nil
+	})
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// pushCmd copies local files to every host of a group through the server
+var pushCmd = &cobra.Command{
+	Use:   "push --group <group> <local path> <remote path>",
+	Short: "Copy a file or directory to every host of a group",
+	Long: `Copy a local file or directory to all hosts of a group over SFTP. The
+PortFly server receives the files once, then writes them to the hosts, at
+most --concurrency at a time. Every file is written to a temporary name,
+read back and checked against its SHA-256 before it replaces the target, so
+hosts never see a partial file.
+
+A file is copied to the remote path, or into it when the remote path ends
+with a slash. A directory's contents are copied into the remote path, which
+is created if needed. Symbolic links and special files are skipped.
+
+The exit status is 0 when every host received the files, 2 when some hosts
+failed and 1 when all of them did.
+
+Examples:
+  portfly push --group web ./nginx.conf /etc/nginx/nginx.conf
+  portfly push -g web ./dist /var/www/app
+  portfly push -g db -c 5 --timeout 30m ./backup.tar.gz /srv/restore/`,
+	Args: cobra.ExactArgs(2),
+	RunE: runPush,
+}
+
+var (
+	pushGroup       string
+	pushConcurrency int
+	pushTimeout     time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(pushCmd)
+
+	pushCmd.Flags().StringVarP(&pushGroup, "group", "g", "", "Group whose hosts receive the files, by name or ID")
+	pushCmd.Flags().IntVarP(&pushConcurrency, "concurrency", "c", 10, "Maximum number of hosts written to at once")
+	pushCmd.Flags().DurationVar(&pushTimeout, "timeout", 5*time.Minute, "Time limit for each host, including connecting")
+	pushCmd.MarkFlagRequired("group")
+	pushCmd.RegisterFlagCompletionFunc("group", completeGroupNames)
+}
+
+// pushResult is one host's outcome, as streamed by the server
+type pushResult struct {
+	HostID   uint   `json:"host_id"`
+	HostName string `json:"host_name"`
+	Files    int    `json:"files"`
+	Bytes    int64  `json:"bytes"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration"`
+}
+
+// pushedFile is a file or directory written to every host
+type pushedFile struct {
+	Path   string      `json:"path"`
+	Dir    bool        `json:"dir,omitempty"`
+	Mode   fs.FileMode `json:"mode"`
+	Size   int64       `json:"size,omitempty"`
+	SHA256 string      `json:"sha256,omitempty"`
+}
+
+// pushReport summarizes the copy over all hosts
+type pushReport struct {
+	GroupID   uint         `json:"group_id"`
+	Path      string       `json:"path"`
+	Files     []pushedFile `json:"files"`
+	Bytes     int64        `json:"bytes"`
+	Hosts     int          `json:"hosts"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Duration  int64        `json:"duration"`
+	Results   []pushResult `json:"results"`
+}
+
+func runPush(cmd *cobra.Command, args []string) error {
+	if pushConcurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if pushTimeout <= 0 {
+		return fmt.Errorf("--timeout must be positive")
+	}
+
+	local, remote := args[0], args[1]
+	info, err := os.Stat(local)
+	if err != nil {
+		return err
+	}
+	// The archive holds paths relative to the remote root, which for a
+	// single file is its parent directory
+	root, archiveName := remote, ""
+	if !info.IsDir() {
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file or directory", local)
+		}
+		if strings.HasSuffix(remote, "/") {
+			archiveName = filepath.Base(local)
+		} else {
+			root, archiveName = path.Split(remote)
+		}
+		if root == "" {
+			root = "."
+		}
+	}
+
+	archive, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeArchive(writer, local, info, archiveName))
+	}()
+	defer archive.Close()
+
+	query := url.Values{
+		"path":        {root},
+		"concurrency": {fmt.Sprint(pushConcurrency)},
+		"timeout":     {fmt.Sprint(pushTimeout.Milliseconds())},
+	}
+	var report pushReport
+	client := newAPIClient()
+	err = client.stream("/groups/"+url.PathEscape(pushGroup)+"/files?"+query.Encode(), "application/x-tar", archive, func(name string, data []byte) error {
+		switch name {
+		case "result":
+			// Machine-readable output only carries the final report
+			if outputFormat != OutputTable {
+				return nil
+			}
+			var result pushResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				return fmt.Errorf("failed to decode result: %w", err)
+			}
+			if result.Error != "" {
+				fmt.Fprintf(os.Stderr, "%s: error: %s\n", result.HostName, result.Error)
+			} else {
+				fmt.Printf("%s: %d files, %s\n", result.HostName, result.Files, formatBytes(result.Bytes))
+			}
+		case "challenge":
+			// A host asks for e.g. a one-time password while connecting
+			return answerChallenge(client, data)
+		case "report":
+			if err := json.Unmarshal(data, &report); err != nil {
+				return fmt.Errorf("failed to decode report: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if report.Hosts == 0 {
+		return fmt.Errorf("server ended the stream without a report")
+	}
+
+	files := 0
+	for _, file := range report.Files {
+		if !file.Dir {
+			files++
+		}
+	}
+	if err := printResult(report, func(w io.Writer) error {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "HOST\tFILES\tBYTES\tDURATION\tERROR")
+		for _, result := range report.Results {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n",
+				result.HostName,
+				result.Files,
+				formatBytes(result.Bytes),
+				(time.Duration(result.Duration) * time.Millisecond).String(),
+				valueOrDash(result.Error))
+		}
+		fmt.Fprintf(w, "\n%d files (%s) to %s on %d hosts: %d succeeded, %d failed in %s\n",
+			files, formatBytes(report.Bytes), report.Path,
+			report.Hosts, report.Succeeded, report.Failed,
+			(time.Duration(report.Duration) * time.Millisecond).String())
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	switch {
+	case report.Failed == 0:
+		return nil
+	case report.Succeeded == 0:
+		return &ExitError{Code: ExitFailure, Err: fmt.Errorf("push failed on all %d hosts", report.Failed)}
+	default:
+		return &ExitError{Code: ExitPartialFailure, Err: fmt.Errorf("push failed on %d of %d hosts", report.Failed, report.Hosts)}
+	}
+}
+
+// writeArchive writes local as a tar archive: a file under name, or the
+// contents of a directory
+func writeArchive(w io.Writer, local string, info fs.FileInfo, name string) error {
+	archive := tar.NewWriter(w)
+	if !info.IsDir() {
+		if err := addArchiveFile(archive, local, name, info); err != nil {
+			return err
+		}
+		return archive.Close()
+	}
+
+	err := filepath.WalkDir(local, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(local, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			fmt.Fprintf(os.Stderr, "skipping %s: not a regular file\n", file)
+			return nil
+		}
+		return addArchiveFile(archive, file, filepath.ToSlash(rel), info)
+	})
+	if err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// addArchiveFile writes one file or directory entry
+func addArchiveFile(archive *tar.Writer, file, name string, info fs.FileInfo) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		ModTime: info.ModTime(),
+	}
+	if info.IsDir() {
+		header.Typeflag = tar.TypeDir
+		header.Name += "/"
+		return archive.WriteHeader(header)
+	}
+
+	header.Typeflag = tar.TypeReg
+	header.Size = info.Size()
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := io.Copy(archive, f); err != nil {
+		return fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return nil
+}
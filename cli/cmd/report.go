@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// reportCmd groups reports about the resources configured on the server
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report on ports, hosts and groups configured on the PortFly server",
+}
+
+var reportUnusedDays int
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	unusedCmd := &cobra.Command{
+		Use:   "unused",
+		Short: "List ports, hosts and groups left unused",
+		Long: `List the ports not used, the hosts not connected and the groups without
+traffic in the last days, to find configuration that can be cleaned up.
+Resources created within the period are not reported; a group counts the
+traffic of its subgroups.
+
+Examples:
+  portfly report unused
+  portfly report unused --days 90
+  portfly report unused -o json`,
+		Args: cobra.NoArgs,
+		RunE: runReportUnused,
+	}
+	unusedCmd.Flags().IntVar(&reportUnusedDays, "days", models.DefaultUnusedDays, "Length of the period in days")
+
+	reportCmd.AddCommand(unusedCmd)
+}
+
+func runReportUnused(cmd *cobra.Command, args []string) error {
+	var report models.UsageReport
+	if err := newAPIClient().get(fmt.Sprintf("/reports/unused?days=%d", reportUnusedDays), &report); err != nil {
+		return err
+	}
+
+	return printResult(report, func(w io.Writer) error {
+		fmt.Fprintf(w, "Unused since %s (%d days)\n\n", formatTime(&report.Since), report.Days)
+
+		fmt.Fprintf(w, "PORTS (%d)\n", len(report.UnusedPorts))
+		if len(report.UnusedPorts) > 0 {
+			fmt.Fprintln(w, "ID\tNAME\tTYPE\tPORT\tGROUP\tCREATED\tLAST USED")
+		}
+		for _, port := range report.UnusedPorts {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				port.ID, port.Name, port.Type, port.Port, valueOrDash(port.GroupName),
+				formatTime(&port.CreatedAt), formatTime(port.LastUsed))
+		}
+
+		fmt.Fprintf(w, "\nHOSTS (%d)\n", len(report.UnusedHosts))
+		if len(report.UnusedHosts) > 0 {
+			fmt.Fprintln(w, "ID\tNAME\tHOSTNAME\tGROUP\tCREATED\tLAST CONNECTED")
+		}
+		for _, host := range report.UnusedHosts {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+				host.ID, host.Name, host.Hostname, valueOrDash(host.GroupName),
+				formatTime(&host.CreatedAt), formatTime(host.LastConnected))
+		}
+
+		fmt.Fprintf(w, "\nGROUPS WITHOUT TRAFFIC (%d)\n", len(report.IdleGroups))
+		if len(report.IdleGroups) > 0 {
+			fmt.Fprintln(w, "ID\tNAME\tPROJECT\tPORTS\tHOSTS\tCREATED\tLAST USED")
+		}
+		for _, group := range report.IdleGroups {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%s\t%s\n",
+				group.ID, group.Name, group.ProjectID, group.Ports, group.Hosts,
+				formatTime(&group.CreatedAt), formatTime(group.LastUsed))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// reservationsCmd manages the server's local port reservations
+var reservationsCmd = &cobra.Command{
+	Use:   "reservations",
+	Short: "List, make and release local port reservations",
+	Long: `Local ports are reserved for the project of their group and the user who
+saved them, so two projects or two users cannot forward to the same local
+port. Local ports created without a port number get one from the ranges in
+PORTFLY_PORT_POOLS (20000-29999 by default).
+
+Ports used by forwards PortFly does not manage can be reserved by hand.
+The user is the one sent by the CLI (user@hostname); reservations with the
+same project and user may overlap.
+
+Examples:
+  portfly reservations list --project 3
+  portfly reservations reserve --project 3 8080
+  portfly reservations reserve --project 3 --size 5
+  portfly reservations plan --project 3 --count 4
+  portfly reservations release 12`,
+}
+
+var (
+	reservationsProject uint
+	reservationsSize    int
+	reservationsCount   int
+	reservationsNote    string
+)
+
+func init() {
+	rootCmd.AddCommand(reservationsCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the reservations",
+		Args:  cobra.NoArgs,
+		RunE:  runReservationsList,
+	}
+	listCmd.Flags().UintVar(&reservationsProject, "project", 0, "Only list this project's reservations")
+
+	reserveCmd := &cobra.Command{
+		Use:   "reserve [port[-last]]",
+		Short: "Reserve a port or range, or the next free ports of the project's range",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runReservationsReserve,
+	}
+	reserveCmd.Flags().UintVar(&reservationsProject, "project", 0, "Project the ports are reserved for (required)")
+	reserveCmd.Flags().IntVar(&reservationsSize, "size", 1, "Number of consecutive ports to allocate without a port argument")
+	reserveCmd.Flags().StringVar(&reservationsNote, "note", "", "What the ports are used for")
+	reserveCmd.MarkFlagRequired("project")
+
+	planCmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show the ports the next allocations would get, without reserving them",
+		Args:  cobra.NoArgs,
+		RunE:  runReservationsPlan,
+	}
+	planCmd.Flags().UintVar(&reservationsProject, "project", 0, "Project to plan for")
+	planCmd.Flags().IntVar(&reservationsSize, "size", 1, "Number of consecutive ports per allocation")
+	planCmd.Flags().IntVar(&reservationsCount, "count", 1, "Number of allocations to plan")
+
+	reservationsCmd.AddCommand(listCmd, reserveCmd, planCmd, &cobra.Command{
+		Use:   "release <reservation id>",
+		Short: "Delete a reservation",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runReservationsRelease,
+	})
+}
+
+func runReservationsList(cmd *cobra.Command, args []string) error {
+	path := "/port-reservations"
+	if reservationsProject != 0 {
+		path += "?project_id=" + strconv.FormatUint(uint64(reservationsProject), 10)
+	}
+	var reservations []models.PortReservation
+	if err := newAPIClient().get(path, &reservations); err != nil {
+		return err
+	}
+
+	return printResult(reservations, func(w io.Writer) error {
+		fmt.Fprintln(w, "ID\tPORTS\tPROJECT\tUSER\tPORT ID\tNOTE")
+		for _, reservation := range reservations {
+			portID := "-"
+			if reservation.PortID != nil {
+				portID = strconv.FormatUint(uint64(*reservation.PortID), 10)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\n", reservation.ID, reservation.String(), reservation.ProjectID,
+				valueOrDash(reservation.Owner), portID, valueOrDash(reservation.Note))
+		}
+		return nil
+	})
+}
+
+func runReservationsReserve(cmd *cobra.Command, args []string) error {
+	request := map[string]any{
+		"project_id": reservationsProject,
+		"size":       reservationsSize,
+		"note":       reservationsNote,
+	}
+	if len(args) == 1 {
+		first, last, isRange := strings.Cut(args[0], "-")
+		port, err := strconv.Atoi(first)
+		if err != nil {
+			return fmt.Errorf("invalid port %q", args[0])
+		}
+		request["port"] = port
+		if isRange {
+			portEnd, err := strconv.Atoi(last)
+			if err != nil {
+				return fmt.Errorf("invalid port range %q", args[0])
+			}
+			request["port_end"] = portEnd
+		}
+	}
+
+	var reservation models.PortReservation
+	if err := newAPIClient().post("/port-reservations", request, &reservation); err != nil {
+		return err
+	}
+
+	return printResult(reservation, func(w io.Writer) error {
+		fmt.Fprintf(w, "Reserved:\t%s\n", reservation.String())
+		fmt.Fprintf(w, "Reservation:\t%d\n", reservation.ID)
+		fmt.Fprintf(w, "Project:\t%d\n", reservation.ProjectID)
+		fmt.Fprintf(w, "User:\t%s\n", valueOrDash(reservation.Owner))
+		return nil
+	})
+}
+
+func runReservationsPlan(cmd *cobra.Command, args []string) error {
+	query := url.Values{}
+	if reservationsProject != 0 {
+		query.Set("project_id", strconv.FormatUint(uint64(reservationsProject), 10))
+	}
+	query.Set("size", strconv.Itoa(reservationsSize))
+	query.Set("count", strconv.Itoa(reservationsCount))
+
+	var plan models.PortPlan
+	if err := newAPIClient().get("/port-reservations/plan?"+query.Encode(), &plan); err != nil {
+		return err
+	}
+
+	return printResult(plan, func(w io.Writer) error {
+		fmt.Fprintf(w, "Range:\t%s\n", plan.Pool.String())
+		if len(plan.Ports) == 0 {
+			fmt.Fprintln(w, "Ports:\tnone free")
+			return nil
+		}
+		for i, port := range plan.Ports {
+			label := ""
+			if i == 0 {
+				label = "Ports:"
+			}
+			if plan.Size > 1 {
+				fmt.Fprintf(w, "%s\t%d-%d\n", label, port, port+plan.Size-1)
+			} else {
+				fmt.Fprintf(w, "%s\t%d\n", label, port)
+			}
+		}
+		return nil
+	})
+}
+
+func runReservationsRelease(cmd *cobra.Command, args []string) error {
+	if _, err := strconv.ParseUint(args[0], 10, 32); err != nil {
+		return fmt.Errorf("invalid reservation ID %q", args[0])
+	}
+	if err := newAPIClient().do(http.MethodDelete, "/port-reservations/"+args[0], nil, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Released reservation %s\n", args[0])
+	return nil
+}
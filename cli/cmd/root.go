@@ -3,9 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 
 	"github.com/aqz236/port-fly/core/models"
 	"github.com/aqz236/port-fly/core/utils"
@@ -17,10 +20,13 @@ var (
 	buildTime = "unknown"
 
 	// Global flags
-	cfgFile string
-	verbose bool
-	config  *models.Config
-	logger  utils.Logger
+	cfgFile   string
+	verbosity int
+	quiet     bool
+	logFile   string
+	config    *models.Config
+	// Completion functions run without initializeConfig, so logging starts discarded
+	logger utils.Logger = utils.DiscardLogger()
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -40,8 +46,9 @@ Examples:
   portfly start -L 8080:192.168.1.100:80 user@example.com
   portfly start -R 8080:localhost:3000 user@example.com
   portfly start -D 1080 user@example.com
-  portfly list
-  portfly stop session-123`,
+  portfly sessions list
+  portfly sessions stop 42
+  portfly share staging-db`,
 	PersistentPreRunE: initializeConfig,
 }
 
@@ -61,18 +68,11 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./configs/default.yaml)")
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose logging")
-
-	// Version command
-	rootCmd.AddCommand(&cobra.Command{
-		Use:   "version",
-		Short: "Show version information",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("PortFly SSH Tunnel Manager\n")
-			fmt.Printf("Version: %s\n", version)
-			fmt.Printf("Built: %s\n", buildTime)
-		},
-	})
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "log more: -v for progress, -vv for debug details")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "log errors only, for scripts")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "",
+		"also write full debug logs as JSON to this file, e.g. for bug reports (default $PORTFLY_LOG_FILE)")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
 }
 
 // initConfig reads in config file and ENV variables
@@ -105,33 +105,69 @@ func initConfig() {
 
 // initializeConfig initializes configuration and logger
 func initializeConfig(cmd *cobra.Command, args []string) error {
-	// Load configuration
-	config = models.DefaultConfig()
-	if err := viper.Unmarshal(config); err != nil {
-		return fmt.Errorf("failed to unmarshal config: %w", err)
-	}
+	// Arguments are valid at this point; don't print usage for runtime errors
+	cmd.SilenceUsage = true
 
-	// Override log level if verbose flag is set
-	if verbose {
-		config.Logging.Level = "debug"
+	if err := validateOutputFormat(); err != nil {
+		return err
 	}
 
-	// Initialize logger
-	loggerConfig := utils.LoggerConfig{
-		Level:      config.Logging.Level,
-		Format:     config.Logging.Format,
-		Output:     config.Logging.Output,
-		MaxSize:    config.Logging.MaxSize,
-		MaxBackups: config.Logging.MaxBackups,
-		MaxAge:     config.Logging.MaxAge,
-		Compress:   config.Logging.Compress,
+	// Load configuration
+	var err error
+	config, err = loadConfig()
+	if err != nil {
+		return err
 	}
 
-	var err error
-	logger, err = utils.NewLogger(loggerConfig)
+	logger, err = utils.NewLogger(cliLoggerConfig(config.Logging))
 	if err != nil {
 		return fmt.Errorf("failed to initialize logger: %w", err)
 	}
 
 	return nil
 }
+
+// cliLoggerConfig sets up logging for a person at a terminal: leveled,
+// colored lines on stderr, which keeps stdout for results and for the
+// forwarded data in stdio mode. Warnings and errors show by default.
+func cliLoggerConfig(logging models.LoggingConfig) utils.LoggerConfig {
+	level := "warn"
+	switch {
+	case quiet:
+		level = "error"
+	case verbosity == 1:
+		level = "info"
+	case verbosity >= 2:
+		level = "debug"
+	}
+
+	file := logFile
+	if file == "" {
+		file = os.Getenv("PORTFLY_LOG_FILE")
+	}
+	// A log file in the configuration keeps receiving logs
+	if file == "" && !slices.Contains([]string{"", "stdout", "stderr"}, strings.ToLower(logging.Output)) {
+		file = logging.Output
+	}
+
+	return utils.LoggerConfig{
+		Level:      level,
+		Format:     "console",
+		Output:     "stderr",
+		Color:      os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stderr.Fd())),
+		File:       file,
+		MaxSize:    logging.MaxSize,
+		MaxBackups: logging.MaxBackups,
+		MaxAge:     logging.MaxAge,
+		Compress:   logging.Compress,
+	}
+}
+
+// loadConfig merges the config file and environment over the defaults
+func loadConfig() (*models.Config, error) {
+	cfg := models.DefaultConfig()
+	if err := viper.Unmarshal(cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return cfg, nil
+}
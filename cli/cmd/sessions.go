@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// sessionsCmd groups commands that inspect and control server-side tunnel sessions
+var sessionsCmd = &cobra.Command{
+	Use:     "sessions",
+	Aliases: []string{"session"},
+	Short:   "List, stop and inspect tunnel sessions on the PortFly server",
+	Long: `Inspect and control tunnel sessions managed by a PortFly server.
+
+Examples:
+  portfly sessions list
+  portfly sessions list --active -o json
+  portfly sessions stop 42
+  portfly sessions stop staging-db       # a session name, or a port name to stop its active sessions
+  portfly sessions stats 42 --server http://tunnels.internal:8080`,
+}
+
+var sessionsActive bool
+
+func init() {
+	rootCmd.AddCommand(sessionsCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List tunnel sessions",
+		Args:  cobra.NoArgs,
+		RunE:  runSessionsList,
+	}
+	listCmd.Flags().BoolVar(&sessionsActive, "active", false, "Only list active sessions")
+
+	sessionsCmd.AddCommand(listCmd)
+	sessionsCmd.AddCommand(newSessionsStopCmd("stop <id|name>"))
+	rootCmd.AddCommand(newSessionsStopCmd("stop <session|port>"))
+	sessionsCmd.AddCommand(&cobra.Command{
+		Use:   "stats <id|name>",
+		Short: "Show statistics for a tunnel session",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runSessionsStats,
+
+		ValidArgsFunction: completeSessionIDs,
+	})
+}
+
+// newSessionsStopCmd builds the stop command, registered as both
+// "portfly sessions stop" and the top-level "portfly stop"
+func newSessionsStopCmd(use string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: "Stop a tunnel session by ID or name, or the active sessions of a port",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runSessionsStop,
+
+		ValidArgsFunction: completeSessionRefs,
+	}
+}
+
+// sessionStats is the stats view of a single tunnel session
+type sessionStats struct {
+	ID              uint                 `json:"id"`
+	Name            string               `json:"name,omitempty"`
+	Status          models.SessionStatus `json:"status"`
+	LocalAddress    string               `json:"local_address,omitempty"`
+	RemoteAddress   string               `json:"remote_address,omitempty"`
+	DataTransferred int64                `json:"data_transferred"`
+	StartTime       *time.Time           `json:"start_time,omitempty"`
+	EndTime         *time.Time           `json:"end_time,omitempty"`
+	Uptime          string               `json:"uptime,omitempty"`
+	Error           string               `json:"error,omitempty"`
+}
+
+func runSessionsList(cmd *cobra.Command, args []string) error {
+	path := "/sessions"
+	if sessionsActive {
+		path = "/sessions/active"
+	}
+
+	var sessions []models.TunnelSession
+	if err := newAPIClient().get(path, &sessions); err != nil {
+		return err
+	}
+
+	return printResult(sessions, func(w io.Writer) error {
+		fmt.Fprintln(w, "ID\tNAME\tSTATUS\tLOCAL\tREMOTE\tTRANSFERRED\tSTARTED")
+		for _, session := range sessions {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				session.ID,
+				valueOrDash(session.Name),
+				session.Status,
+				valueOrDash(session.LocalAddress),
+				valueOrDash(session.RemoteAddress),
+				formatBytes(session.DataTransferred),
+				formatTime(session.StartTime))
+		}
+		return nil
+	})
+}
+
+func runSessionsStop(cmd *cobra.Command, args []string) error {
+	client := newAPIClient()
+
+	ids, err := resolveSessionIDs(client, args[0])
+	if err != nil {
+		return err
+	}
+
+	var result BatchResult
+	for _, id := range ids {
+		var session models.TunnelSession
+		err := client.post(fmt.Sprintf("/sessions/%d/stop", id), nil, &session)
+		result.Add(ItemResult{ID: strconv.FormatUint(uint64(id), 10), Status: string(models.StatusStopped)}, err)
+	}
+
+	if err := printResult(result, func(w io.Writer) error {
+		fmt.Fprintln(w, "ID\tSTATUS\tERROR")
+		for _, item := range result.Items {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", item.ID, item.Status, valueOrDash(item.Error))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return result.Err()
+}
+
+func runSessionsStats(cmd *cobra.Command, args []string) error {
+	var session models.TunnelSession
+	if err := newAPIClient().get("/sessions/"+url.PathEscape(args[0]), &session); err != nil {
+		return err
+	}
+
+	stats := sessionStats{
+		ID:              session.ID,
+		Name:            session.Name,
+		Status:          session.Status,
+		LocalAddress:    session.LocalAddress,
+		RemoteAddress:   session.RemoteAddress,
+		DataTransferred: session.DataTransferred,
+		StartTime:       session.StartTime,
+		EndTime:         session.EndTime,
+		Error:           session.ErrorMessage,
+	}
+	if session.StartTime != nil {
+		end := time.Now()
+		if session.EndTime != nil {
+			end = *session.EndTime
+		}
+		stats.Uptime = end.Sub(*session.StartTime).Truncate(time.Second).String()
+	}
+
+	return printResult(stats, func(w io.Writer) error {
+		fmt.Fprintf(w, "Session:\t%d\n", stats.ID)
+		fmt.Fprintf(w, "Name:\t%s\n", valueOrDash(stats.Name))
+		fmt.Fprintf(w, "Status:\t%s\n", stats.Status)
+		fmt.Fprintf(w, "Local:\t%s\n", valueOrDash(stats.LocalAddress))
+		fmt.Fprintf(w, "Remote:\t%s\n", valueOrDash(stats.RemoteAddress))
+		fmt.Fprintf(w, "Transferred:\t%s\n", formatBytes(stats.DataTransferred))
+		fmt.Fprintf(w, "Started:\t%s\n", formatTime(stats.StartTime))
+		fmt.Fprintf(w, "Uptime:\t%s\n", valueOrDash(stats.Uptime))
+		if stats.Error != "" {
+			fmt.Fprintf(w, "Error:\t%s\n", stats.Error)
+		}
+		return nil
+	})
+}
+
+// resolveSessionIDs resolves a session ID or name, or a port ID or name to
+// the port's active sessions; the server resolves names
+func resolveSessionIDs(client *apiClient, ref string) ([]uint, error) {
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		return []uint{uint(id)}, nil
+	}
+
+	var session models.TunnelSession
+	err := client.get("/sessions/"+url.PathEscape(ref), &session)
+	if err == nil {
+		return []uint{session.ID}, nil
+	}
+	if !isNotFound(err) {
+		return nil, err
+	}
+
+	var port models.Port
+	if err := client.get("/ports/"+url.PathEscape(ref), &port); err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("no session or port named %q", ref)
+		}
+		return nil, err
+	}
+
+	var sessions []models.TunnelSession
+	if err := client.get("/sessions/active", &sessions); err != nil {
+		return nil, err
+	}
+
+	var ids []uint
+	for _, session := range sessions {
+		if session.PortID != nil && *session.PortID == port.ID {
+			ids = append(ids, session.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("port %q has no active sessions", ref)
+	}
+	return ids, nil
+}
+
+// valueOrDash returns "-" for empty table cells
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// formatTime formats an optional timestamp for table output
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// formatBytes formats a byte count with binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// shareCmd prints copy/paste connection snippets for a port
+var shareCmd = &cobra.Command{
+	Use:   "share <port id|name>",
+	Short: "Print a connection URL and client command for a port",
+	Long: `Print ready-to-paste connection snippets for a port managed by the PortFly
+server: a URL, a client command (psql, mysql, redis-cli, curl, ...) and an
+example, chosen from the port's service type. For HTTP ports where the server
+recognized the application (Grafana, Jenkins, ...), the example calls its
+health or version endpoint.
+
+Examples:
+  portfly share 12
+  portfly share staging-db --service postgres
+  portfly share web --host tunnels.internal -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runShare,
+
+	ValidArgsFunction: completePortRefs,
+}
+
+var (
+	shareHost    string
+	shareService string
+)
+
+func init() {
+	rootCmd.AddCommand(shareCmd)
+
+	shareCmd.Flags().StringVar(&shareHost, "host", "", "Host clients connect to (default: derived from the port's bind address)")
+	shareCmd.Flags().StringVar(&shareService, "service", "", "Service type: tcp, http, https, postgres, mysql, redis, mongodb, ssh (default: inferred)")
+}
+
+func runShare(cmd *cobra.Command, args []string) error {
+	query := url.Values{}
+	if shareHost != "" {
+		query.Set("host", shareHost)
+	}
+	if shareService != "" {
+		query.Set("service", shareService)
+	}
+	path := "/ports/" + url.PathEscape(args[0]) + "/share"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var link models.ShareLink
+	if err := newAPIClient().get(path, &link); err != nil {
+		return err
+	}
+
+	return printResult(link, func(w io.Writer) error {
+		fmt.Fprintf(w, "Port:\t%s (%s)\n", link.Name, link.Service)
+		if link.App != "" {
+			fmt.Fprintf(w, "App:\t%s\n", link.App.Name())
+		}
+		if link.Title != "" {
+			fmt.Fprintf(w, "Title:\t%s\n", link.Title)
+		}
+		fmt.Fprintf(w, "URL:\t%s\n", link.URL)
+		fmt.Fprintf(w, "Command:\t%s\n", link.Command)
+		if link.Example != "" {
+			fmt.Fprintf(w, "Example:\t%s\n", link.Example)
+		}
+		return nil
+	})
+}
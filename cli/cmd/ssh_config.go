@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// sshConfigCmd prints the managed hosts as an OpenSSH client config
+var sshConfigCmd = &cobra.Command{
+	Use:   "ssh-config",
+	Short: "Export managed hosts as an OpenSSH config",
+	Long: `Print the hosts managed by the PortFly server as OpenSSH config blocks
+(Host alias, HostName, User, Port, IdentityFile, ProxyJump/ProxyCommand) so the
+same inventory can be used with plain ssh, scp and rsync.
+
+Private keys are never exported. Hosts using key authentication point
+IdentityFile at <identity-dir>/<alias>; save the matching key there.
+
+Examples:
+  portfly ssh-config >> ~/.ssh/config
+  portfly ssh-config --group 3 --prefix pf- -f ~/.ssh/config.d/portfly
+  ssh pf-staging-db`,
+	Args: cobra.NoArgs,
+	RunE: runSSHConfig,
+}
+
+var (
+	sshConfigGroup       uint
+	sshConfigPrefix      string
+	sshConfigIdentityDir string
+	sshConfigFile        string
+)
+
+func init() {
+	rootCmd.AddCommand(sshConfigCmd)
+
+	sshConfigCmd.Flags().UintVar(&sshConfigGroup, "group", 0, "Only export hosts in this group")
+	sshConfigCmd.Flags().StringVar(&sshConfigPrefix, "prefix", "", "Prefix for Host aliases")
+	sshConfigCmd.Flags().StringVar(&sshConfigIdentityDir, "identity-dir", "", "Directory of IdentityFile paths (default ~/.ssh/portfly)")
+	sshConfigCmd.Flags().StringVarP(&sshConfigFile, "file", "f", "", "Write to this file instead of stdout")
+}
+
+func runSSHConfig(cmd *cobra.Command, args []string) error {
+	query := url.Values{}
+	if sshConfigGroup != 0 {
+		query.Set("group_id", strconv.FormatUint(uint64(sshConfigGroup), 10))
+	}
+	if sshConfigPrefix != "" {
+		query.Set("prefix", sshConfigPrefix)
+	}
+	if sshConfigIdentityDir != "" {
+		query.Set("identity_dir", sshConfigIdentityDir)
+	}
+	path := "/hosts/export/ssh-config"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	if sshConfigFile == "" {
+		return newAPIClient().download(path, cmd.OutOrStdout())
+	}
+
+	file, err := os.OpenFile(sshConfigFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", sshConfigFile, err)
+	}
+	err = newAPIClient().download(path, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %s\n", sshConfigFile)
+	return nil
+}
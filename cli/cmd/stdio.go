@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	sshpkg "github.com/aqz236/port-fly/core/ssh"
+)
+
+// stdioCmd bridges stdin/stdout to a TCP endpoint behind an SSH server
+var stdioCmd = &cobra.Command{
+	Use:   "stdio [flags] [user@]hostname host:port",
+	Short: "Connect stdin and stdout to host:port through an SSH server",
+	Long: `Connect stdin and stdout to host:port, reached through the SSH server like
+ssh -W. PortFly can then be used as an OpenSSH ProxyCommand, or by any tool
+that talks to a server over a command's stdio (git, rsync, ...), with
+PortFly's authentication, proxy and failover options.
+
+Nothing but the forwarded data is written to stdout; logs and password
+prompts go to stderr and the terminal.
+
+Examples:
+  # ~/.ssh/config: reach internal hosts through a bastion
+  Host *.internal
+      ProxyCommand portfly stdio -i ~/.ssh/bastion user@bastion.example.com %h:%p
+
+  # One-off connection through a bastion behind a corporate proxy
+  ssh -o ProxyCommand="portfly stdio --proxy http://proxy.corp:3128 bastion.example.com %h:%p" app.internal
+
+  # git and rsync over the bastion
+  GIT_SSH_COMMAND="ssh -o ProxyCommand='portfly stdio bastion.example.com %h:%p'" git clone git@git.internal:team/app.git
+  rsync -e "ssh -o ProxyCommand='portfly stdio bastion.example.com %h:%p'" -a src/ app.internal:/srv/app/`,
+	Args: cobra.ExactArgs(2),
+	RunE: runStdio,
+
+	ValidArgsFunction: completeHostTargets,
+}
+
+func init() {
+	rootCmd.AddCommand(stdioCmd)
+
+	addSSHFlags(stdioCmd)
+}
+
+func runStdio(cmd *cobra.Command, args []string) error {
+	target := args[1]
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return fmt.Errorf("invalid target %q, expected host:port: %w", target, err)
+	}
+
+	sshConfig, err := sshConnectionConfig(args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := sshpkg.NewSSHClient(sshConfig, logger)
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", args[0], err)
+	}
+	defer client.Disconnect()
+	saveSecrets()
+
+	server, _ := client.ActiveEndpoint()
+	logger.Debug("forwarding stdio", "server", server, "target", target)
+	return client.ForwardStdio(ctx, "tcp", target, os.Stdin, os.Stdout)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/aqz236/port-fly/core/firewall"
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// configureTransparent attaches the --transparent settings to the first
+// dynamic forward. The SSH server and outbound proxy are excluded from the
+// redirect, or the tunnel's own connection would be sent into it.
+func configureTransparent(configs []models.TunnelConfig, sshConfig models.SSHConnectionConfig) error {
+	if len(transparentCIDRs) == 0 {
+		return nil
+	}
+
+	index := -1
+	for i := range configs {
+		if configs[i].Type == models.TunnelTypeDynamic {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("--transparent requires a dynamic forward (-D)")
+	}
+
+	port := transparentPort
+	if port == 0 {
+		port = configs[index].SOCKSPort + 1
+	}
+
+	exclude := append([]string{}, transparentExclude...)
+	hosts := []string{sshConfig.Host}
+	if sshConfig.ProxyURL != "" {
+		if proxy, err := url.Parse(sshConfig.ProxyURL); err == nil {
+			hosts = append(hosts, proxy.Hostname())
+		}
+	}
+	if sshConfig.ProxyCommand != "" {
+		logger.Warn("the proxy command's destination is not excluded from the redirect; add it with --transparent-exclude")
+	}
+	for _, host := range hosts {
+		ips, err := net.LookupIP(host)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s to exclude it from the redirect: %w", host, err)
+		}
+		for _, ip := range ips {
+			exclude = append(exclude, models.HostPrefix(ip))
+		}
+	}
+
+	configs[index].Transparent = &models.TransparentProxy{
+		Port:         port,
+		Destinations: transparentCIDRs,
+		Exclude:      exclude,
+		Firewall:     firewallName,
+	}
+	return configs[index].Validate()
+}
+
+// setupRedirect installs the redirect rules of a started transparent proxy
+// with --apply-redirect, or prints them for running by hand. It returns the
+// installed rules, to be removed on exit.
+func setupRedirect(proxy models.TransparentProxy) (*firewall.Rules, error) {
+	rules, err := firewall.Generate(proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	if !applyRedirect || background {
+		fmt.Fprintf(os.Stderr, "Run as root to redirect connections through the tunnel (%s):\n%s\n\nTo remove the rules:\n%s\n",
+			rules.Firewall, rules.Script(false), rules.Script(true))
+		return nil, nil
+	}
+
+	if err := rules.Install(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to install redirect rules: %w", err)
+	}
+	logger.Info("redirect rules installed",
+		"firewall", rules.Firewall,
+		"destinations", proxy.Destinations,
+		"port", proxy.Port)
+	return rules, nil
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	compat "github.com/aqz236/port-fly/core/version"
+)
+
+// defaultReleaseFeed is the latest-release endpoint checked by version --check,
+// in the GitHub releases API format
+const defaultReleaseFeed = "https://api.github.com/repos/aqz236/port-fly/releases/latest"
+
+// releaseFeedTimeout bounds the release feed request
+const releaseFeedTimeout = 10 * time.Second
+
+var versionCheck bool
+
+// versionCmd shows the CLI version and, with --check, how it relates to the
+// server and the latest release
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show version information",
+	Long: `Show the version of this CLI and the API revision it speaks.
+
+With --check, also ask the server for its version and report whether the two
+work together, and compare this CLI with the latest release. The release feed
+is $PORTFLY_RELEASE_FEED, for mirrors, or the project's GitHub releases. The
+command fails when the server is incompatible.
+
+Examples:
+  portfly version
+  portfly version --check
+  portfly version --check -o json`,
+	Args: cobra.NoArgs,
+	RunE: runVersion,
+}
+
+func init() {
+	rootCmd.AddCommand(versionCmd)
+
+	versionCmd.Flags().BoolVar(&versionCheck, "check", false, "Compare with the server and the latest release")
+}
+
+// versionReport is the output of portfly version
+type versionReport struct {
+	compat.Info
+	Server *serverVersionReport `json:"server,omitempty"`
+	Latest *releaseReport       `json:"latest,omitempty"`
+}
+
+// serverVersionReport is the server's version and its compatibility with the CLI
+type serverVersionReport struct {
+	URL        string `json:"url"`
+	Version    string `json:"version,omitempty"`
+	Revision   int    `json:"api_revision,omitempty"`
+	Compatible bool   `json:"compatible"`
+	Warning    string `json:"warning,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// releaseReport is the latest release and whether it is newer than the CLI
+type releaseReport struct {
+	Version         string    `json:"version,omitempty"`
+	URL             string    `json:"url,omitempty"`
+	PublishedAt     time.Time `json:"published_at,omitempty"`
+	UpdateAvailable bool      `json:"update_available"`
+	Error           string    `json:"error,omitempty"`
+}
+
+func runVersion(cmd *cobra.Command, args []string) error {
+	report := versionReport{Info: cliBuild()}
+	var incompatible error
+	if versionCheck {
+		report.Server, incompatible = checkServer()
+		report.Latest = checkLatestRelease(cmd.Context())
+	}
+
+	err := printResult(report, func(w io.Writer) error {
+		fmt.Fprintf(w, "PortFly SSH Tunnel Manager\n")
+		fmt.Fprintf(w, "Version:\t%s\n", report.Version)
+		fmt.Fprintf(w, "Built:\t%s\n", report.BuildTime)
+		fmt.Fprintf(w, "API revision:\t%d\n", report.APIRevision)
+		if server := report.Server; server != nil {
+			switch {
+			case server.Error != "" && server.Version == "":
+				fmt.Fprintf(w, "Server:\t%s unreachable: %s\n", server.URL, server.Error)
+			case !server.Compatible:
+				fmt.Fprintf(w, "Server:\t%s (API revision %d) INCOMPATIBLE: %s\n", server.Version, server.Revision, server.Error)
+			case server.Warning != "":
+				fmt.Fprintf(w, "Server:\t%s (API revision %d) compatible, %s\n", server.Version, server.Revision, server.Warning)
+			default:
+				fmt.Fprintf(w, "Server:\t%s (API revision %d) compatible\n", server.Version, server.Revision)
+			}
+		}
+		if latest := report.Latest; latest != nil {
+			switch {
+			case latest.Error != "":
+				fmt.Fprintf(w, "Latest:\tunknown: %s\n", latest.Error)
+			case latest.UpdateAvailable:
+				fmt.Fprintf(w, "Latest:\t%s, update available: %s\n", latest.Version, latest.URL)
+			default:
+				fmt.Fprintf(w, "Latest:\t%s, up to date\n", latest.Version)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if incompatible != nil {
+		return &ExitError{Code: ExitFailure, Err: incompatible}
+	}
+	return nil
+}
+
+// cliBuild describes this CLI
+func cliBuild() compat.Info {
+	return compat.Current(version, buildTime)
+}
+
+// checkServer asks the server for its version. The returned error is set
+// only when the server is reachable and incompatible.
+func checkServer() (*serverVersionReport, error) {
+	client := newAPIClient()
+	report := &serverVersionReport{URL: resolveServerURL()}
+
+	var server compat.Info
+	if err := client.get("/version", &server); err != nil {
+		report.Error = err.Error()
+		return report, nil
+	}
+	report.Version = server.Version
+	report.Revision = server.APIRevision
+	if err := compat.CheckAPI("CLI", cliBuild(), "server", server); err != nil {
+		report.Error = err.Error()
+		return report, err
+	}
+	report.Compatible = true
+	report.Warning = compat.Skew("CLI", version, "server", server.Version)
+	return report, nil
+}
+
+// checkLatestRelease reads the latest release from the release feed
+func checkLatestRelease(ctx context.Context) *releaseReport {
+	feed := os.Getenv("PORTFLY_RELEASE_FEED")
+	if feed == "" {
+		feed = defaultReleaseFeed
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, releaseFeedTimeout)
+	defer cancel()
+
+	report := &releaseReport{}
+	fail := func(err error) *releaseReport {
+		report.Error = err.Error()
+		return report
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feed, nil)
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "portfly/"+version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fail(fmt.Errorf("release feed answered %s", resp.Status))
+	}
+
+	var release struct {
+		TagName     string    `json:"tag_name"`
+		HTMLURL     string    `json:"html_url"`
+		PublishedAt time.Time `json:"published_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return fail(fmt.Errorf("unexpected release feed response: %w", err))
+	}
+	if release.TagName == "" {
+		return fail(errors.New("release feed has no tag_name"))
+	}
+	report.Version = release.TagName
+	report.URL = release.HTMLURL
+	report.PublishedAt = release.PublishedAt
+	// Development builds are never up to date
+	report.UpdateAvailable = compat.Compare(version, release.TagName) < 0
+	return report
+}
+
+// serverSkewWarning warns about a server from another release once per run
+var serverSkewWarning sync.Once
+
+// checkServerVersion compares the server build announced in a response's
+// headers with this CLI, refusing servers it cannot work with. Servers from
+// before API revisions announce none and are not checked; neither is the
+// version endpoint, which version --check reports on itself.
+func checkServerVersion(resp *http.Response) error {
+	if strings.HasSuffix(resp.Request.URL.Path, "/version") {
+		return nil
+	}
+	revision, err := strconv.Atoi(resp.Header.Get(compat.HeaderAPIRevision))
+	if err != nil {
+		return nil
+	}
+	server := compat.Info{Version: resp.Header.Get(compat.HeaderVersion), APIRevision: revision}
+	if err := compat.CheckAPI("CLI", cliBuild(), "server", server); err != nil {
+		return err
+	}
+	serverSkewWarning.Do(func() {
+		if skew := compat.Skew("CLI", version, "server", server.Version); skew != "" {
+			logger.Warn(skew)
+		}
+	})
+	return nil
+}
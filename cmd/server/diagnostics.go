@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/aqz236/port-fly/server"
+	"github.com/aqz236/port-fly/server/diagnostics"
+)
+
+// diagnosticsTimeout bounds the request to the running server
+const diagnosticsTimeout = time.Minute
+
+// runDiagnostics asks the running server for a diagnostic bundle and prints
+// where it was written. When no server is running it writes an offline bundle
+// with the configuration and environment itself.
+func runDiagnostics(config *server.Config, args []string) int {
+	flags := flag.NewFlagSet("diagnostics", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: portfly-server diagnostics [-o file] [--offline]")
+		fmt.Fprintln(flags.Output(), "\nWrite a diagnostic bundle for support: stack traces, recent logs, the configuration with secrets redacted and version information.")
+		flags.PrintDefaults()
+	}
+	output := flags.String("o", "", "also copy the bundle to this file")
+	offline := flags.Bool("offline", false, "do not ask the running server; write a bundle without its logs and state")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	client, baseURL := adminClient(config)
+	var info diagnostics.Info
+	var err error
+	if !*offline {
+		info, err = requestDiagnostics(client, baseURL)
+		if isNotRunning(err) {
+			fmt.Fprintf(os.Stderr, "No server is running (%v), writing an offline bundle\n", err)
+			*offline = true
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write diagnostic bundle: %v\n", err)
+			return 1
+		}
+	}
+	if *offline {
+		if info, err = server.WriteDiagnostics(config); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write diagnostic bundle: %v\n", err)
+			return 1
+		}
+	}
+	fmt.Println(info.Path)
+
+	if *output != "" {
+		if *offline {
+			err = copyBundle(info.Path, *output)
+		} else {
+			err = downloadBundle(client, baseURL, info.Name, *output)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to copy diagnostic bundle: %v\n", err)
+			return 1
+		}
+		fmt.Println(*output)
+	}
+	return 0
+}
+
+// adminClient returns a client for the server's admin API and its base URL:
+// the admin socket when one is configured, the network listener otherwise
+func adminClient(config *server.Config) (*http.Client, string) {
+	client := &http.Client{Timeout: diagnosticsTimeout}
+	if config.AdminSocket == "" {
+		host := config.Host
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			host = "localhost"
+		}
+		return client, "http://" + net.JoinHostPort(host, strconv.Itoa(config.Port)) + "/api/v1/admin"
+	}
+
+	socket := config.AdminSocket
+	client.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+	}
+	return client, "http://portfly/api/v1/admin"
+}
+
+// requestDiagnostics has the running server write a bundle
+func requestDiagnostics(client *http.Client, baseURL string) (diagnostics.Info, error) {
+	resp, err := client.Post(baseURL+"/diagnostics", "application/json", nil)
+	if err != nil {
+		return diagnostics.Info{}, err
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Success bool             `json:"success"`
+		Data    diagnostics.Info `json:"data"`
+		Error   string           `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return diagnostics.Info{}, fmt.Errorf("unexpected response from server: %s", resp.Status)
+	}
+	if !response.Success {
+		return diagnostics.Info{}, fmt.Errorf("server error: %s", response.Error)
+	}
+	return response.Data, nil
+}
+
+// downloadBundle saves the named bundle from the running server to path
+func downloadBundle(client *http.Client, baseURL, name, path string) error {
+	resp, err := client.Get(baseURL + "/diagnostics/" + url.PathEscape(name))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server answered %s", resp.Status)
+	}
+	return writeBundle(resp.Body, path)
+}
+
+// copyBundle copies a bundle on disk to path
+func copyBundle(source, path string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeBundle(file, path)
+}
+
+// writeBundle writes r to path, readable only by the user: bundles hold logs
+func writeBundle(r io.Reader, path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// isNotRunning reports whether err means nothing listens for the admin API
+func isNotRunning(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT)
+}
@@ -2,14 +2,29 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/aqz236/port-fly/server"
 	_ "github.com/aqz236/port-fly/server/storage/sqlite" // Register SQLite storage
 )
 
+var (
+	// Build information set by ldflags
+	Version   = "dev"
+	BuildTime = "unknown"
+)
+
 func main() {
+	// Set build information
+	server.SetBuildInfo(Version, BuildTime)
+
 	// Create default configuration
 	config := server.DefaultConfig()
+
+	// portfly-server diagnostics writes a support bundle and exits
+	if len(os.Args) > 1 && os.Args[1] == "diagnostics" {
+		os.Exit(runDiagnostics(config, os.Args[2:]))
+	}
 	
 	// Create and start server
 	srv, err := server.NewServer(config)
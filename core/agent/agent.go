@@ -0,0 +1,441 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/aqz236/port-fly/core/manager"
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/core/version"
+)
+
+// ErrIncompatibleServer is returned by Run when the server refuses the
+// agent's protocol version; reconnecting cannot succeed until one is upgraded
+var ErrIncompatibleServer = errors.New("server does not support this agent version")
+
+// Reconnect backoff bounds
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Config configures an agent
+type Config struct {
+	ServerURL string // ws:// or wss:// URL of the agent endpoint
+	Name      string // unique agent name, defaults to the hostname
+	Token     string // shared or per-agent token configured on the server
+	Version   string // reported to the server
+
+	// Services on this side published through the server, re-announced
+	// after every reconnect
+	Expose []ExposePayload
+}
+
+// Agent runs tunnels on this machine on behalf of the server. Tunnels keep
+// running across reconnects and their state is re-reported after each one.
+type Agent struct {
+	config   Config
+	sessions *manager.SessionManager
+	logger   utils.Logger
+
+	mu       sync.Mutex
+	forwards map[string]string // forward ID -> session ID
+
+	connMu sync.Mutex
+	conn   *websocket.Conn
+}
+
+// New creates an agent that runs tunnels with sessions
+func New(config Config, sessions *manager.SessionManager, logger utils.Logger) *Agent {
+	if config.Name == "" {
+		config.Name, _ = os.Hostname()
+	}
+
+	return &Agent{
+		config:   config,
+		sessions: sessions,
+		logger:   logger.With("agent", config.Name),
+		forwards: make(map[string]string),
+	}
+}
+
+// Run keeps the agent connected until ctx is done, then stops all tunnels.
+// It gives up with ErrIncompatibleServer when the server refuses the
+// agent's protocol version.
+func (a *Agent) Run(ctx context.Context) error {
+	events, unsubscribe := a.sessions.Subscribe()
+	defer unsubscribe()
+	go a.relayEvents(ctx, events)
+
+	delay := minReconnectDelay
+	for {
+		connectedAt := time.Now()
+		err := a.serve(ctx)
+		if ctx.Err() != nil {
+			a.closeAll()
+			return nil
+		}
+		if errors.Is(err, ErrIncompatibleServer) {
+			a.closeAll()
+			return err
+		}
+
+		// A connection that stayed up for a while resets the backoff
+		if time.Since(connectedAt) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		a.logger.Warn("disconnected from server, reconnecting", "error", err, "retry_in", delay)
+
+		select {
+		case <-ctx.Done():
+			a.closeAll()
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// serve runs one connection: register, report tunnel state, handle commands
+func (a *Agent) serve(ctx context.Context) error {
+	header := http.Header{}
+	if a.config.Token != "" {
+		header.Set("Authorization", "Bearer "+a.config.Token)
+	}
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, a.config.ServerURL, header)
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("failed to connect to %s: %s", a.config.ServerURL, resp.Status)
+		}
+		return fmt.Errorf("failed to connect to %s: %w", a.config.ServerURL, err)
+	}
+	defer conn.Close()
+
+	// Unblock the read loop on shutdown
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := a.register(conn); err != nil {
+		return err
+	}
+
+	a.connMu.Lock()
+	a.conn = conn
+	a.connMu.Unlock()
+	defer func() {
+		a.connMu.Lock()
+		a.conn = nil
+		a.connMu.Unlock()
+	}()
+
+	a.reportAll()
+	for _, service := range a.config.Expose {
+		a.send(MsgExpose, service.Name, service)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go a.heartbeat(done)
+
+	for {
+		// The server heartbeats too; silence means the connection is dead
+		conn.SetReadDeadline(time.Now().Add(3 * HeartbeatInterval))
+		var msg Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		a.handle(ctx, msg)
+	}
+}
+
+// heartbeat keeps the connection marked alive on the server until done
+func (a *Agent) heartbeat(done <-chan struct{}) {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			a.send(MsgHeartbeat, "", nil)
+		}
+	}
+}
+
+// register performs the registration handshake
+func (a *Agent) register(conn *websocket.Conn) error {
+	hostname, _ := os.Hostname()
+	msg, err := NewMessage(MsgRegister, "", RegisterPayload{
+		Name:            a.config.Name,
+		Hostname:        hostname,
+		OS:              runtime.GOOS + "/" + runtime.GOARCH,
+		Version:         a.config.Version,
+		ProtocolVersion: ProtocolVersion,
+	})
+	if err != nil {
+		return err
+	}
+	if err := conn.WriteJSON(msg); err != nil {
+		return fmt.Errorf("failed to register: %w", err)
+	}
+
+	var reply Message
+	if err := conn.ReadJSON(&reply); err != nil {
+		return fmt.Errorf("failed to read registration reply: %w", err)
+	}
+	switch reply.Type {
+	case MsgRegistered:
+		var registered RegisteredPayload
+		if err := reply.Decode(&registered); err != nil {
+			return err
+		}
+		a.logger.Info("registered with server", "agent_id", registered.AgentID, "server", a.config.ServerURL, "server_version", registered.ServerVersion)
+		if skew := version.Skew("agent", a.config.Version, "server", registered.ServerVersion); skew != "" {
+			a.logger.Warn(skew)
+		}
+		return nil
+	case MsgError:
+		var failure ErrorPayload
+		reply.Decode(&failure)
+		if failure.Code == ErrorIncompatible {
+			return fmt.Errorf("%w: %s", ErrIncompatibleServer, failure.Error)
+		}
+		return fmt.Errorf("registration rejected: %s", failure.Error)
+	}
+	return fmt.Errorf("unexpected registration reply: %s", reply.Type)
+}
+
+// handle dispatches a server command; ctx bounds the work it starts
+func (a *Agent) handle(ctx context.Context, msg Message) {
+	var err error
+	switch msg.Type {
+	case MsgOpenForward:
+		var payload OpenForwardPayload
+		if err = msg.Decode(&payload); err == nil {
+			err = a.openForward(ctx, msg.RequestID, payload)
+		}
+	case MsgCloseForward:
+		var payload CloseForwardPayload
+		if err = msg.Decode(&payload); err == nil {
+			err = a.closeForward(payload.ForwardID)
+		}
+	case MsgOpenStream:
+		var payload OpenStreamPayload
+		if err = msg.Decode(&payload); err == nil {
+			go a.openStream(payload)
+		}
+	case MsgExposed:
+		var payload ExposedPayload
+		if err = msg.Decode(&payload); err == nil {
+			a.logger.Info("service exposed on server", "service", payload.Name, "address", payload.Address)
+		}
+	case MsgError:
+		var payload ErrorPayload
+		msg.Decode(&payload)
+		a.logger.Warn("server reported an error", "request_id", msg.RequestID, "error", payload.Error)
+		return
+	case MsgHeartbeat:
+		return
+	default:
+		err = fmt.Errorf("unsupported message type: %s", msg.Type)
+	}
+
+	if err != nil {
+		a.logger.Warn("server request failed", "type", msg.Type, "error", err)
+		a.send(MsgError, msg.RequestID, ErrorPayload{Error: err.Error()})
+	}
+}
+
+// openForward starts a tunnel; a forward ID that already runs is restarted
+// with the new configuration. The tunnel comes up in the background so the
+// connection keeps serving commands during a slow handshake; failures are
+// reported against requestID.
+func (a *Agent) openForward(ctx context.Context, requestID string, payload OpenForwardPayload) error {
+	if payload.ForwardID == "" {
+		return errors.New("forward_id is required")
+	}
+	a.closeForward(payload.ForwardID)
+
+	session, err := a.sessions.CreateSession(ctx, payload.SSH, payload.Tunnel)
+	if err != nil {
+		a.reportStatus(payload.ForwardID, models.StatusError, "", err.Error())
+		return err
+	}
+	session.Name = payload.ForwardID
+
+	a.mu.Lock()
+	a.forwards[payload.ForwardID] = session.ID
+	a.mu.Unlock()
+
+	go func() {
+		if err := a.sessions.StartSession(ctx, session.ID); err != nil {
+			a.reportStatus(payload.ForwardID, models.StatusError, "", err.Error())
+			a.logger.Warn("server request failed", "type", MsgOpenForward, "error", err)
+			a.send(MsgError, requestID, ErrorPayload{Error: err.Error()})
+			return
+		}
+		a.logger.Info("forward opened", "forward_id", payload.ForwardID, "tunnel", payload.Tunnel.GetTunnelDescription())
+	}()
+	return nil
+}
+
+// openStream connects a client of an exposed service: it dials the local
+// target and a stream WebSocket back to the server and pipes between them
+func (a *Agent) openStream(payload OpenStreamPayload) {
+	var target string
+	for _, service := range a.config.Expose {
+		if service.Name == payload.Name {
+			target = service.Target
+			break
+		}
+	}
+	if target == "" {
+		a.send(MsgError, payload.StreamID, ErrorPayload{Error: fmt.Sprintf("unknown service: %s", payload.Name)})
+		return
+	}
+
+	local, err := net.DialTimeout("tcp", target, 10*time.Second)
+	if err != nil {
+		a.logger.Warn("failed to reach exposed service", "service", payload.Name, "target", target, "error", err)
+		a.send(MsgError, payload.StreamID, ErrorPayload{Error: err.Error()})
+		return
+	}
+
+	header := http.Header{}
+	if a.config.Token != "" {
+		header.Set("Authorization", "Bearer "+a.config.Token)
+	}
+	streamURL := strings.TrimSuffix(a.config.ServerURL, WebSocketPath) + StreamPath + payload.StreamID
+	ws, _, err := websocket.DefaultDialer.Dial(streamURL, header)
+	if err != nil {
+		local.Close()
+		a.logger.Warn("failed to open stream", "service", payload.Name, "error", err)
+		return
+	}
+
+	a.logger.Debug("stream opened", "service", payload.Name, "stream_id", payload.StreamID)
+	Pipe(ws, local)
+}
+
+// closeForward stops and forgets a tunnel
+func (a *Agent) closeForward(forwardID string) error {
+	a.mu.Lock()
+	sessionID, exists := a.forwards[forwardID]
+	delete(a.forwards, forwardID)
+	a.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("unknown forward: %s", forwardID)
+	}
+
+	err := a.sessions.DeleteSession(sessionID)
+	a.reportStatus(forwardID, models.StatusStopped, "forward closed", "")
+	a.logger.Info("forward closed", "forward_id", forwardID)
+	return err
+}
+
+// closeAll stops every tunnel on shutdown
+func (a *Agent) closeAll() {
+	a.mu.Lock()
+	ids := make([]string, 0, len(a.forwards))
+	for forwardID := range a.forwards {
+		ids = append(ids, forwardID)
+	}
+	a.mu.Unlock()
+
+	for _, forwardID := range ids {
+		a.closeForward(forwardID)
+	}
+}
+
+// relayEvents forwards session lifecycle events to the server
+func (a *Agent) relayEvents(ctx context.Context, events <-chan models.SessionEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Type == models.EventSessionStats {
+				continue
+			}
+			if forwardID, found := a.forwardFor(event.SessionID); found {
+				a.reportStatus(forwardID, event.Status, event.Message, event.Error)
+			}
+		}
+	}
+}
+
+// reportAll re-sends the state of every tunnel after reconnecting
+func (a *Agent) reportAll() {
+	a.mu.Lock()
+	forwards := make(map[string]string, len(a.forwards))
+	for forwardID, sessionID := range a.forwards {
+		forwards[forwardID] = sessionID
+	}
+	a.mu.Unlock()
+
+	for forwardID, sessionID := range forwards {
+		session, err := a.sessions.GetSession(sessionID)
+		if err != nil {
+			continue
+		}
+		a.reportStatus(forwardID, session.Status, session.StatusMessage, session.LastError)
+	}
+}
+
+// forwardFor returns the forward ID running as sessionID
+func (a *Agent) forwardFor(sessionID string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for forwardID, id := range a.forwards {
+		if id == sessionID {
+			return forwardID, true
+		}
+	}
+	return "", false
+}
+
+// reportStatus sends a forward status update
+func (a *Agent) reportStatus(forwardID string, status models.SessionStatus, message, errMessage string) {
+	a.send(MsgForwardStatus, "", ForwardStatusPayload{
+		ForwardID: forwardID,
+		Status:    status,
+		Message:   message,
+		Error:     errMessage,
+		Timestamp: time.Now(),
+	})
+}
+
+// send writes a message if connected; updates while disconnected are
+// dropped and replaced by reportAll on reconnect
+func (a *Agent) send(msgType MessageType, requestID string, payload any) {
+	msg, err := NewMessage(msgType, requestID, payload)
+	if err != nil {
+		a.logger.Error("failed to encode message", "type", msgType, "error", err)
+		return
+	}
+
+	a.connMu.Lock()
+	defer a.connMu.Unlock()
+	if a.conn == nil {
+		return
+	}
+	if err := a.conn.WriteJSON(msg); err != nil {
+		a.logger.Debug("failed to send message", "type", msgType, "error", err)
+	}
+}
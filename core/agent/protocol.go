@@ -0,0 +1,144 @@
+// Package agent implements the remote agent: a process on another machine
+// that keeps an outbound WebSocket to the PortFly server and opens tunnels
+// locally when the server asks, so forwards can terminate on a laptop or a
+// jump box instead of the server.
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ProtocolVersion is bumped on incompatible message changes.
+// MinProtocolVersion is the oldest version servers still accept from agents.
+const (
+	ProtocolVersion    = 1
+	MinProtocolVersion = 1
+)
+
+// WebSocketPath is the server endpoint agents connect to
+const WebSocketPath = "/ws/agent"
+
+// StreamPath is the server endpoint an agent dials to carry one connection to
+// an exposed service; the stream ID is appended
+const StreamPath = WebSocketPath + "/streams/"
+
+// HeartbeatInterval is how often each side sends a heartbeat. A peer that is
+// silent for three intervals is considered gone.
+const HeartbeatInterval = 15 * time.Second
+
+// MessageType identifies an agent protocol message
+type MessageType string
+
+const (
+	MsgRegister      MessageType = "register"       // agent -> server, first message
+	MsgRegistered    MessageType = "registered"     // server -> agent, registration accepted
+	MsgOpenForward   MessageType = "open_forward"   // server -> agent, start a tunnel
+	MsgCloseForward  MessageType = "close_forward"  // server -> agent, stop a tunnel
+	MsgForwardStatus MessageType = "forward_status" // agent -> server, tunnel state changed
+	MsgError         MessageType = "error"          // either direction, request failed
+	MsgHeartbeat     MessageType = "heartbeat"      // either direction, liveness
+	MsgExpose        MessageType = "expose"         // agent -> server, publish a local service
+	MsgExposed       MessageType = "exposed"        // server -> agent, service listening on the server
+	MsgOpenStream    MessageType = "open_stream"    // server -> agent, a client connected to an exposed service
+)
+
+// Message is the envelope of every frame on the agent WebSocket
+type Message struct {
+	Type      MessageType     `json:"type"`
+	RequestID string          `json:"request_id,omitempty"` // echoed in replies
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// RegisterPayload identifies the agent to the server
+type RegisterPayload struct {
+	Name            string `json:"name"`
+	Hostname        string `json:"hostname"`
+	OS              string `json:"os"`
+	Version         string `json:"version"`
+	ProtocolVersion int    `json:"protocol_version"`
+}
+
+// RegisteredPayload confirms registration
+type RegisteredPayload struct {
+	AgentID         string `json:"agent_id"`
+	ServerVersion   string `json:"server_version,omitempty"`
+	ProtocolVersion int    `json:"protocol_version,omitempty"` // the server's
+}
+
+// OpenForwardPayload asks the agent to run a tunnel through an SSH server
+type OpenForwardPayload struct {
+	ForwardID string                     `json:"forward_id"`
+	SSH       models.SSHConnectionConfig `json:"ssh"`
+	Tunnel    models.TunnelConfig        `json:"tunnel"`
+}
+
+// CloseForwardPayload asks the agent to stop a tunnel
+type CloseForwardPayload struct {
+	ForwardID string `json:"forward_id"`
+}
+
+// ForwardStatusPayload reports the state of a tunnel on the agent
+type ForwardStatusPayload struct {
+	ForwardID string               `json:"forward_id"`
+	Status    models.SessionStatus `json:"status"`
+	Message   string               `json:"message,omitempty"`
+	Error     string               `json:"error,omitempty"`
+	Timestamp time.Time            `json:"timestamp"`
+}
+
+// ExposePayload asks the server to listen for a service reachable from the
+// agent, so clients of the server reach it without inbound firewall rules
+type ExposePayload struct {
+	Name        string `json:"name"`
+	Target      string `json:"target"`                 // host:port dialed by the agent
+	BindAddress string `json:"bind_address,omitempty"` // server-side listen address, default 127.0.0.1
+	RemotePort  int    `json:"remote_port,omitempty"`  // server-side port, 0 picks a free one
+}
+
+// ExposedPayload reports where the server listens for an exposed service
+type ExposedPayload struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// OpenStreamPayload asks the agent to connect a new client to a service
+type OpenStreamPayload struct {
+	StreamID string `json:"stream_id"`
+	Name     string `json:"name"`
+}
+
+// Error codes agents act on
+const (
+	ErrorIncompatible = "incompatible" // the agent's protocol version is not supported; retrying will not help
+)
+
+// ErrorPayload describes a failed request
+type ErrorPayload struct {
+	Error string `json:"error"`
+	Code  string `json:"code,omitempty"`
+}
+
+// NewMessage builds a message with an encoded payload
+func NewMessage(msgType MessageType, requestID string, payload any) (Message, error) {
+	msg := Message{Type: msgType, RequestID: requestID}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return msg, fmt.Errorf("failed to encode %s payload: %w", msgType, err)
+		}
+		msg.Payload = data
+	}
+	return msg, nil
+}
+
+// Decode unmarshals the payload into v
+func (m Message) Decode(v any) error {
+	if err := json.Unmarshal(m.Payload, v); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", m.Type, err)
+	}
+	return nil
+}
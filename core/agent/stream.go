@@ -0,0 +1,55 @@
+package agent
+
+import (
+	"io"
+	"net"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// streamBufferSize is the largest chunk sent in one WebSocket message
+const streamBufferSize = 32 * 1024
+
+// Pipe copies bytes between a TCP connection and a stream WebSocket until
+// either side closes, then closes both. Data travels as binary messages.
+func Pipe(ws *websocket.Conn, conn net.Conn) {
+	var once sync.Once
+	closeBoth := func() {
+		once.Do(func() {
+			ws.Close()
+			conn.Close()
+		})
+	}
+	defer closeBoth()
+
+	go func() {
+		defer closeBoth()
+		buf := make([]byte, streamBufferSize)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				if werr := ws.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+		}
+	}()
+
+	for {
+		msgType, reader, err := ws.NextReader()
+		if err != nil {
+			return
+		}
+		if msgType != websocket.BinaryMessage {
+			continue
+		}
+		if _, err := io.Copy(conn, reader); err != nil {
+			return
+		}
+	}
+}
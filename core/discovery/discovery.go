@@ -0,0 +1,252 @@
+// Package discovery finds SSH hosts on the local network, through mDNS
+// (_ssh._tcp) announcements and optional TCP sweeps of CIDR ranges, so they
+// can be proposed as hosts instead of being entered by hand.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// Defaults applied to zero Options fields
+const (
+	DefaultPort        = 22
+	DefaultTimeout     = 2 * time.Second
+	DefaultConcurrency = 64
+
+	// MaxSweepAddresses caps the addresses probed per scan so a mistyped
+	// prefix does not sweep a /8
+	MaxSweepAddresses = 4096
+)
+
+var (
+	// ErrSweepTooLarge is returned when the CIDRs exceed MaxSweepAddresses
+	ErrSweepTooLarge = errors.New("sweep range too large")
+	// ErrInvalidCIDR is returned for ranges that do not parse
+	ErrInvalidCIDR = errors.New("invalid CIDR")
+)
+
+// Source tells how a candidate was found
+type Source string
+
+const (
+	SourceMDNS  Source = "mdns"
+	SourceSweep Source = "sweep"
+)
+
+// Options configures a discovery scan
+type Options struct {
+	MDNS        bool          // browse _ssh._tcp.local
+	CIDRs       []string      // ranges to sweep, e.g. 192.168.1.0/24
+	Port        int           // port swept in CIDRs
+	Timeout     time.Duration // mDNS listen window and per-address dial timeout
+	Concurrency int           // parallel dials during a sweep
+}
+
+// Candidate is an SSH server that could be added as a host
+type Candidate struct {
+	Hostname string   `json:"hostname"` // mDNS or reverse DNS name, empty if unknown
+	Address  string   `json:"address"`
+	Port     int      `json:"port"`
+	Sources  []Source `json:"sources"`
+	Instance string   `json:"instance,omitempty"` // mDNS service instance name
+	Banner   string   `json:"banner,omitempty"`   // SSH identification string
+	OS       string   `json:"os,omitempty"`       // guessed from the banner
+	Latency  float64  `json:"latency_ms"`         // TCP connect time
+	Existing bool     `json:"existing"`           // already configured as a host
+}
+
+// Discover runs the scans selected in opts and returns the SSH servers that
+// answered, with banner and latency, sorted by address
+func Discover(ctx context.Context, opts Options) ([]Candidate, error) {
+	if opts.Port <= 0 {
+		opts.Port = DefaultPort
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+
+	targets, err := sweepTargets(opts.CIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]*Candidate)
+	add := func(c Candidate, source Source) {
+		key := net.JoinHostPort(c.Address, strconv.Itoa(c.Port))
+		existing, exists := found[key]
+		if !exists {
+			c.Sources = []Source{source}
+			found[key] = &c
+			return
+		}
+		existing.Sources = append(existing.Sources, source)
+		if existing.Hostname == "" {
+			existing.Hostname = c.Hostname
+		}
+		if existing.Instance == "" {
+			existing.Instance = c.Instance
+		}
+	}
+
+	if opts.MDNS {
+		services, err := browseSSH(ctx, opts.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("mDNS browse failed: %w", err)
+		}
+		for _, service := range services {
+			add(service, SourceMDNS)
+		}
+	}
+
+	// Sweep responders already carry a banner; mDNS results are probed below
+	for _, c := range sweep(ctx, targets, opts) {
+		add(c, SourceSweep)
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Concurrency)
+	for _, c := range found {
+		if c.Banner != "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(c *Candidate) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			probeCandidate(ctx, c, opts.Timeout)
+		}(c)
+	}
+	wg.Wait()
+
+	candidates := make([]Candidate, 0, len(found))
+	for _, c := range found {
+		if c.Hostname == "" {
+			c.Hostname = reverseLookup(ctx, c.Address)
+		}
+		candidates = append(candidates, *c)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return compareAddresses(candidates[i].Address, candidates[j].Address) < 0
+	})
+	return candidates, nil
+}
+
+// Host builds a host for the candidate; credentials are left to the caller
+func (c Candidate) Host(username string, groupID uint) models.Host {
+	name := strings.TrimSuffix(c.Hostname, ".")
+	name = strings.TrimSuffix(name, ".local")
+	if name == "" {
+		name = c.Address
+	}
+
+	hostname := c.Address
+	if c.Hostname != "" {
+		hostname = strings.TrimSuffix(c.Hostname, ".")
+	}
+
+	description := "Discovered via " + joinSources(c.Sources)
+	if c.Banner != "" {
+		description += " (" + c.Banner + ")"
+	}
+
+	return models.Host{
+		Name:        name,
+		Hostname:    hostname,
+		Port:        c.Port,
+		Username:    username,
+		Description: description,
+		AuthMethod:  "key",
+		GroupID:     groupID,
+	}
+}
+
+// Matches reports whether host already points at the candidate
+func (c Candidate) Matches(host models.Host) bool {
+	port := host.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	if port != c.Port {
+		return false
+	}
+	hostname := strings.TrimSuffix(host.Hostname, ".")
+	return hostname == c.Address || (c.Hostname != "" && strings.EqualFold(hostname, strings.TrimSuffix(c.Hostname, ".")))
+}
+
+// MarkExisting flags candidates that match one of hosts
+func MarkExisting(candidates []Candidate, hosts []models.Host) {
+	for i := range candidates {
+		for _, host := range hosts {
+			if candidates[i].Matches(host) {
+				candidates[i].Existing = true
+				break
+			}
+		}
+	}
+}
+
+// probeCandidate fills banner, OS and latency of an mDNS result
+func probeCandidate(ctx context.Context, c *Candidate, timeout time.Duration) {
+	banner, latency, err := probe(ctx, c.Address, c.Port, timeout)
+	if err != nil {
+		return
+	}
+	c.Banner = banner
+	c.OS = GuessOS(banner)
+	c.Latency = milliseconds(latency)
+}
+
+// reverseLookup returns the first PTR name of address, or empty
+func reverseLookup(ctx context.Context, address string) string {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	names, err := net.DefaultResolver.LookupAddr(ctx, address)
+	if err != nil || len(names) == 0 {
+		return ""
+	}
+	return strings.TrimSuffix(names[0], ".")
+}
+
+// compareAddresses orders IPs numerically and anything else lexically
+func compareAddresses(a, b string) int {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	if ipA != nil && ipB != nil {
+		if ip4A, ip4B := ipA.To4(), ipB.To4(); (ip4A == nil) != (ip4B == nil) {
+			// IPv4 first
+			if ip4A != nil {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(string(ipA.To16()), string(ipB.To16()))
+	}
+	return strings.Compare(a, b)
+}
+
+// joinSources formats sources for descriptions
+func joinSources(sources []Source) string {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = string(source)
+	}
+	return strings.Join(names, "+")
+}
+
+// milliseconds converts d to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,164 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// sshService is the DNS-SD service type announced by SSH servers
+const sshService = "_ssh._tcp.local."
+
+// mdnsGroup is the IPv4 mDNS multicast address
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// unicastResponse is the QU bit: responders answer the querying port
+// directly, so the browser does not need to bind 5353
+const unicastResponse = 1 << 15
+
+// mdnsRecords accumulates records from every response
+type mdnsRecords struct {
+	instances map[string]bool      // PTR targets of the service
+	srv       map[string]srvTarget // instance -> target and port
+	addresses map[string][]net.IP  // host name -> A/AAAA addresses
+}
+
+// srvTarget is the host and port an instance runs on
+type srvTarget struct {
+	host string
+	port int
+	from net.IP // responder, used when no address record was sent
+}
+
+// browseSSH queries _ssh._tcp.local and collects answers for timeout
+func browseSSH(ctx context.Context, timeout time.Duration) ([]Candidate, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	query, err := buildQuery(sshService, dnsmessage.TypePTR)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteToUDP(query, mdnsGroup); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	records := mdnsRecords{
+		instances: make(map[string]bool),
+		srv:       make(map[string]srvTarget),
+		addresses: make(map[string][]net.IP),
+	}
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// Read deadline reached: the browse window is over
+			break
+		}
+		records.parse(buf[:n], from.IP)
+	}
+
+	return records.candidates(), nil
+}
+
+// buildQuery encodes a one-question mDNS query with the QU bit set
+func buildQuery(name string, qtype dnsmessage.Type) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  qtype,
+		Class: dnsmessage.ClassINET | unicastResponse,
+	}); err != nil {
+		return nil, err
+	}
+	return builder.Finish()
+}
+
+// parse records the PTR, SRV and address records of a response; answers and
+// additional records are treated alike since responders use both
+func (r *mdnsRecords) parse(packet []byte, from net.IP) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(packet)
+	if err != nil || !header.Response {
+		return
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return
+	}
+
+	var resources []dnsmessage.Resource
+	if answers, err := parser.AllAnswers(); err == nil {
+		resources = append(resources, answers...)
+	}
+	if err := parser.SkipAllAuthorities(); err == nil {
+		if additionals, err := parser.AllAdditionals(); err == nil {
+			resources = append(resources, additionals...)
+		}
+	}
+
+	for _, resource := range resources {
+		name := strings.ToLower(resource.Header.Name.String())
+		switch body := resource.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if name == sshService {
+				r.instances[body.PTR.String()] = true
+			}
+		case *dnsmessage.SRVResource:
+			r.srv[resource.Header.Name.String()] = srvTarget{host: body.Target.String(), port: int(body.Port), from: from}
+		case *dnsmessage.AResource:
+			r.addresses[name] = append(r.addresses[name], net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			r.addresses[name] = append(r.addresses[name], net.IP(body.AAAA[:]))
+		}
+	}
+}
+
+// candidates resolves instances to addresses; a host without address
+// records falls back to the responder's address
+func (r *mdnsRecords) candidates() []Candidate {
+	var candidates []Candidate
+	for instance := range r.instances {
+		target, exists := r.srv[instance]
+		if !exists {
+			continue
+		}
+
+		host := strings.ToLower(target.host)
+		address := ""
+		for _, ip := range r.addresses[host] {
+			if ip.To4() != nil {
+				address = ip.String()
+				break
+			}
+			if address == "" {
+				address = ip.String()
+			}
+		}
+		if address == "" {
+			address = target.from.String()
+		}
+
+		candidates = append(candidates, Candidate{
+			Hostname: strings.TrimSuffix(target.host, "."),
+			Address:  address,
+			Port:     target.port,
+			Instance: strings.TrimSuffix(strings.TrimSuffix(instance, "."), "."+sshService[:len(sshService)-1]),
+		})
+	}
+	return candidates
+}
@@ -0,0 +1,144 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// osBanners maps banner fragments to operating systems, most specific first
+var osBanners = []struct {
+	fragment string
+	os       string
+}{
+	{"OpenSSH_for_Windows", "windows"},
+	{"Raspbian", "raspbian"},
+	{"Ubuntu", "ubuntu"},
+	{"Debian", "debian"},
+	{"FreeBSD", "freebsd"},
+	{"NetBSD", "netbsd"},
+	{"OpenBSD", "openbsd"},
+	{"ROSSSH", "routeros"},
+	{"Cisco", "cisco"},
+	{"dropbear", "linux (dropbear)"},
+	{"Synology", "synology"},
+	{"Apple", "macos"},
+}
+
+// GuessOS guesses the operating system from an SSH identification string
+func GuessOS(banner string) string {
+	lower := strings.ToLower(banner)
+	for _, entry := range osBanners {
+		if strings.Contains(lower, strings.ToLower(entry.fragment)) {
+			return entry.os
+		}
+	}
+	return ""
+}
+
+// sweepTargets expands CIDRs into addresses, skipping network and broadcast
+// addresses of IPv4 ranges wider than /31
+func sweepTargets(cidrs []string) ([]netip.Addr, error) {
+	var targets []netip.Addr
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			// A bare address sweeps just that host
+			addr, addrErr := netip.ParseAddr(strings.TrimSpace(cidr))
+			if addrErr != nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidCIDR, cidr)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefix = prefix.Masked()
+
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits > 20 || 1<<hostBits > MaxSweepAddresses+2 {
+			return nil, fmt.Errorf("%w: %s exceeds %d addresses", ErrSweepTooLarge, cidr, MaxSweepAddresses)
+		}
+
+		start := len(targets)
+		for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+			targets = append(targets, addr)
+		}
+		if prefix.Addr().Is4() && hostBits > 1 {
+			targets = append(targets[:start], targets[start+1:len(targets)-1]...)
+		}
+	}
+	if len(targets) > MaxSweepAddresses {
+		return nil, fmt.Errorf("%w: %d addresses exceed %d", ErrSweepTooLarge, len(targets), MaxSweepAddresses)
+	}
+	return targets, nil
+}
+
+// sweep dials port on every target and returns the SSH servers that answered
+func sweep(ctx context.Context, targets []netip.Addr, opts Options) []Candidate {
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		candidates []Candidate
+	)
+	sem := make(chan struct{}, opts.Concurrency)
+
+	for _, target := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(address string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			banner, latency, err := probe(ctx, address, opts.Port, opts.Timeout)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			candidates = append(candidates, Candidate{
+				Address: address,
+				Port:    opts.Port,
+				Banner:  banner,
+				OS:      GuessOS(banner),
+				Latency: milliseconds(latency),
+			})
+			mu.Unlock()
+		}(target.String())
+	}
+	wg.Wait()
+	return candidates
+}
+
+// probe connects to address:port, measures the connect time and reads the
+// SSH identification line. Servers that accept but are not SSH are rejected.
+func probe(ctx context.Context, address string, port int, timeout time.Duration) (string, time.Duration, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return "", 0, err
+	}
+	latency := time.Since(start)
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	reader := bufio.NewReaderSize(conn, 256)
+	// RFC 4253 allows other lines before the identification string
+	for range 5 {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "SSH-") {
+			return line, latency, nil
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("no SSH banner from %s: %w", address, err)
+		}
+	}
+	return "", 0, fmt.Errorf("no SSH banner from %s", address)
+}
@@ -0,0 +1,221 @@
+// Package firewall generates the redirect rules that send connections for
+// selected networks to a tunnel's transparent proxy, and applies or removes
+// them with the privileges they need.
+package firewall
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ErrUnsupported is returned on platforms without a supported firewall
+var ErrUnsupported = errors.New("no supported firewall on this platform")
+
+// Command is one firewall invocation
+type Command struct {
+	Args  []string
+	Stdin string // ruleset piped to the command, e.g. for pfctl -f -
+}
+
+// String returns the command as a shell line
+func (c Command) String() string {
+	line := strings.Join(c.Args, " ")
+	if c.Stdin != "" {
+		line = fmt.Sprintf("printf '%%s' '%s' | %s", c.Stdin, line)
+	}
+	return line
+}
+
+// Rules are the commands installing and removing the redirect of one
+// transparent proxy
+type Rules struct {
+	Firewall string
+	Apply    []Command
+	Remove   []Command
+}
+
+// DefaultFirewall returns the firewall used on this platform
+func DefaultFirewall() (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return models.FirewallIPTables, nil
+	case "openbsd":
+		return models.FirewallPF, nil
+	default:
+		return "", fmt.Errorf("%w (%s)", ErrUnsupported, runtime.GOOS)
+	}
+}
+
+// Generate returns the rules redirecting TCP connections to the proxy's
+// destinations, except its excluded networks and loopback, to the proxy port
+func Generate(proxy models.TransparentProxy) (*Rules, error) {
+	if err := proxy.Validate(); err != nil {
+		return nil, err
+	}
+
+	firewall := proxy.Firewall
+	if firewall == "" {
+		var err error
+		if firewall, err = DefaultFirewall(); err != nil {
+			return nil, err
+		}
+	}
+
+	destinations, exclude := proxy.Prefixes()
+	switch firewall {
+	case models.FirewallIPTables:
+		return iptablesRules(proxy.Port, destinations, exclude), nil
+	case models.FirewallPF:
+		return pfRules(proxy.Port, destinations, exclude), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", models.ErrTransparentFirewall, firewall)
+	}
+}
+
+// iptablesRules keeps the rules in a chain of their own, jumped to from the
+// nat OUTPUT chain, so removing them leaves other rules untouched
+func iptablesRules(port int, destinations, exclude []netip.Prefix) *Rules {
+	rules := &Rules{Firewall: models.FirewallIPTables}
+	chain := "PORTFLY-" + strconv.Itoa(port)
+
+	for _, family := range []struct {
+		tool     string
+		loopback string
+		is6      bool
+	}{
+		{"iptables", "127.0.0.0/8", false},
+		{"ip6tables", "::1/128", true},
+	} {
+		var redirect []netip.Prefix
+		for _, prefix := range destinations {
+			if prefix.Addr().Is6() == family.is6 {
+				redirect = append(redirect, prefix)
+			}
+		}
+		if len(redirect) == 0 {
+			continue
+		}
+
+		nat := func(args ...string) Command {
+			return Command{Args: append([]string{family.tool, "-w", "-t", "nat"}, args...)}
+		}
+
+		rules.Apply = append(rules.Apply,
+			nat("-N", chain),
+			nat("-A", chain, "-d", family.loopback, "-j", "RETURN"))
+		for _, prefix := range exclude {
+			if prefix.Addr().Is6() == family.is6 {
+				rules.Apply = append(rules.Apply, nat("-A", chain, "-d", prefix.String(), "-j", "RETURN"))
+			}
+		}
+		for _, prefix := range redirect {
+			rules.Apply = append(rules.Apply,
+				nat("-A", chain, "-p", "tcp", "-d", prefix.String(), "-j", "REDIRECT", "--to-ports", strconv.Itoa(port)))
+		}
+		rules.Apply = append(rules.Apply, nat("-A", "OUTPUT", "-p", "tcp", "-j", chain))
+
+		rules.Remove = append(rules.Remove,
+			nat("-D", "OUTPUT", "-p", "tcp", "-j", chain),
+			nat("-F", chain),
+			nat("-X", chain))
+	}
+	return rules
+}
+
+// pfRules load into the anchor portfly/<port>, which pf.conf must reference
+// with anchor "portfly/*". Outgoing connections are routed to lo0 and
+// diverted there, keeping their destination as the local address.
+func pfRules(port int, destinations, exclude []netip.Prefix) *Rules {
+	anchor := "portfly/" + strconv.Itoa(port)
+
+	var ruleset strings.Builder
+	fmt.Fprintf(&ruleset, "table <portfly_dest> { %s }\n", joinPrefixes(destinations))
+	if len(exclude) > 0 {
+		fmt.Fprintf(&ruleset, "table <portfly_exclude> { %s }\n", joinPrefixes(exclude))
+		ruleset.WriteString("pass out quick proto tcp to <portfly_exclude>\n")
+	}
+	ruleset.WriteString("pass out route-to lo0 proto tcp to <portfly_dest> keep state\n")
+	fmt.Fprintf(&ruleset, "pass in quick on lo0 inet proto tcp to <portfly_dest> divert-to 127.0.0.1 port %d\n", port)
+	fmt.Fprintf(&ruleset, "pass in quick on lo0 inet6 proto tcp to <portfly_dest> divert-to ::1 port %d\n", port)
+
+	return &Rules{
+		Firewall: models.FirewallPF,
+		Apply:    []Command{{Args: []string{"pfctl", "-a", anchor, "-f", "-"}, Stdin: ruleset.String()}},
+		Remove:   []Command{{Args: []string{"pfctl", "-a", anchor, "-F", "all"}}},
+	}
+}
+
+func joinPrefixes(prefixes []netip.Prefix) string {
+	names := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		names[i] = prefix.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// Script returns the apply or remove commands as shell lines, for running by hand
+func (r *Rules) Script(remove bool) string {
+	commands := r.Apply
+	if remove {
+		commands = r.Remove
+	}
+	lines := make([]string, len(commands))
+	for i, command := range commands {
+		lines[i] = command.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Install applies the rules, through sudo unless running as root. Rules left
+// behind by an earlier run that did not clean up are removed first, and a
+// failure part way removes what was applied.
+func (r *Rules) Install(ctx context.Context) error {
+	r.Uninstall(ctx)
+
+	for _, command := range r.Apply {
+		if err := run(ctx, command); err != nil {
+			r.Uninstall(ctx)
+			return err
+		}
+	}
+	return nil
+}
+
+// Uninstall removes the rules, continuing past commands that fail because
+// the rules are already gone
+func (r *Rules) Uninstall(ctx context.Context) error {
+	var errs []error
+	for _, command := range r.Remove {
+		errs = append(errs, run(ctx, command))
+	}
+	return errors.Join(errs...)
+}
+
+// run executes a firewall command, with sudo when not root
+func run(ctx context.Context, command Command) error {
+	args := command.Args
+	if os.Geteuid() != 0 {
+		args = append([]string{"sudo", "--"}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	if command.Stdin != "" {
+		cmd.Stdin = strings.NewReader(command.Stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", strings.Join(command.Args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+// Package importer reads the session lists other SSH clients export — PuTTY
+// registry exports, Termius CSV, SecureCRT XML and OpenSSH client configs —
+// as hosts, so an existing inventory can be brought over in one step instead
+// of entered by hand.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// Format is an export format of another SSH client
+type Format string
+
+const (
+	FormatPuTTY     Format = "putty"     // .reg export of HKCU\Software\SimonTatham\PuTTY\Sessions
+	FormatTermius   Format = "termius"   // CSV export of the host list
+	FormatSecureCRT Format = "securecrt" // XML export of the session settings
+	FormatOpenSSH   Format = "openssh"   // ~/.ssh/config
+)
+
+// DefaultPort is used when an export leaves the port out
+const DefaultPort = 22
+
+var (
+	// ErrUnknownFormat is returned for a format name or file that is not
+	// one of the supported exports
+	ErrUnknownFormat = errors.New("unknown import format, expected putty, termius, securecrt or openssh")
+	// ErrInvalidExport is returned when a file does not parse as its format
+	ErrInvalidExport = errors.New("invalid export file")
+)
+
+// Entry is a session of the export mapped to host settings. Credentials the
+// export holds in plain text are carried over; others are left as hints.
+type Entry struct {
+	Name         string   `json:"name"`
+	Hostname     string   `json:"hostname"`
+	Port         int      `json:"port"`
+	Username     string   `json:"username,omitempty"`
+	AuthMethod   string   `json:"auth_method"`        // password, key or gssapi, guessed from the session's settings
+	KeyFile      string   `json:"key_file,omitempty"` // private key path on the machine that exported, not imported
+	ProxyURL     string   `json:"proxy_url,omitempty"`
+	ProxyCommand string   `json:"proxy_command,omitempty"`
+	Folder       string   `json:"folder,omitempty"` // folder or group of the session, e.g. "prod/db"
+	Tags         []string `json:"tags,omitempty"`
+	Warnings     []string `json:"warnings,omitempty"` // settings that could not be carried over
+
+	Password   string `json:"-"`
+	PrivateKey string `json:"-"`
+
+	format Format
+}
+
+// Skipped is a session of the export that does not map to a host, such as a
+// telnet or serial session
+type Skipped struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Result is the content of an export
+type Result struct {
+	Format  Format    `json:"format"`
+	Entries []Entry   `json:"entries"`
+	Skipped []Skipped `json:"skipped"`
+}
+
+// ParseFormat normalizes a format name
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case FormatPuTTY, FormatTermius, FormatSecureCRT, FormatOpenSSH:
+		return format, nil
+	case "crt":
+		return FormatSecureCRT, nil
+	case "ssh", "ssh-config", "ssh_config":
+		return FormatOpenSSH, nil
+	default:
+		return "", fmt.Errorf("%w, got %q", ErrUnknownFormat, name)
+	}
+}
+
+// DetectFormat guesses the format of an export from its content, falling
+// back to the file name's extension
+func DetectFormat(filename string, data []byte) (Format, error) {
+	text := strings.TrimSpace(decodeText(data))
+	switch {
+	case strings.HasPrefix(text, "Windows Registry Editor"), strings.HasPrefix(text, "REGEDIT4"):
+		return FormatPuTTY, nil
+	case strings.HasPrefix(text, "<?xml") && strings.Contains(text, "<VanDyke"), strings.HasPrefix(text, "<VanDyke"):
+		return FormatSecureCRT, nil
+	case sshConfigStart.MatchString(text):
+		return FormatOpenSSH, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".reg":
+		return FormatPuTTY, nil
+	case ".xml":
+		return FormatSecureCRT, nil
+	case ".csv":
+		return FormatTermius, nil
+	case ".conf":
+		return FormatOpenSSH, nil
+	}
+	if base := filepath.Base(filename); base == "config" || base == "ssh_config" {
+		return FormatOpenSSH, nil
+	}
+	if firstLine, _, _ := strings.Cut(text, "\n"); strings.Contains(firstLine, ",") {
+		return FormatTermius, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Parse reads an export of the given format
+func Parse(format Format, data []byte) (*Result, error) {
+	text := decodeText(data)
+
+	var result *Result
+	var err error
+	switch format {
+	case FormatPuTTY:
+		result, err = parsePuTTY(text)
+	case FormatTermius:
+		result, err = parseTermius(text)
+	case FormatSecureCRT:
+		result, err = parseSecureCRT(text)
+	case FormatOpenSSH:
+		result, err = parseOpenSSH(text)
+	default:
+		return nil, fmt.Errorf("%w, got %q", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Format = format
+	if result.Entries == nil {
+		result.Entries = []Entry{}
+	}
+	if result.Skipped == nil {
+		result.Skipped = []Skipped{}
+	}
+	return result, nil
+}
+
+// Host builds the host for an entry in the given group
+func (e Entry) Host(groupID uint) models.Host {
+	description := "Imported from " + e.format.String()
+	if e.Folder != "" {
+		description += " (" + e.Folder + ")"
+	}
+	if e.KeyFile != "" {
+		description += "; key file " + e.KeyFile
+	}
+
+	return models.Host{
+		Name:         e.Name,
+		Hostname:     e.Hostname,
+		Port:         e.Port,
+		Username:     e.Username,
+		Description:  truncate(description, 500),
+		AuthMethod:   e.AuthMethod,
+		Password:     e.Password,
+		PrivateKey:   e.PrivateKey,
+		ProxyURL:     e.ProxyURL,
+		ProxyCommand: e.ProxyCommand,
+		Tags:         e.Tags,
+		GroupID:      groupID,
+	}
+}
+
+// Matches reports whether host already points at the entry's server and user
+func (e Entry) Matches(host models.Host) bool {
+	port := host.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	return port == e.Port &&
+		strings.EqualFold(strings.TrimSuffix(host.Hostname, "."), strings.TrimSuffix(e.Hostname, ".")) &&
+		(e.Username == "" || host.Username == e.Username)
+}
+
+// String returns the name of the client a format is exported from
+func (f Format) String() string {
+	switch f {
+	case FormatPuTTY:
+		return "PuTTY"
+	case FormatTermius:
+		return "Termius"
+	case FormatSecureCRT:
+		return "SecureCRT"
+	case FormatOpenSSH:
+		return "OpenSSH"
+	}
+	return string(f)
+}
+
+// newEntry starts an entry with the defaults; the folder becomes a tag
+func newEntry(format Format, name, folder string) Entry {
+	entry := Entry{
+		Name:       truncate(name, 100),
+		Port:       DefaultPort,
+		AuthMethod: "password",
+		Folder:     folder,
+		format:     format,
+	}
+	if folder != "" {
+		entry.Tags = []string{folder}
+	}
+	return entry
+}
+
+// splitUserHost splits "user@host" as some clients store it in the hostname
+func splitUserHost(hostname string) (string, string) {
+	if i := strings.LastIndex(hostname, "@"); i >= 0 {
+		return hostname[:i], hostname[i+1:]
+	}
+	return "", hostname
+}
+
+// decodeText returns an export as text; Windows tools often write UTF-16
+// with a byte order mark
+func decodeText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return decodeUTF16(data[2:], false)
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return decodeUTF16(data[2:], true)
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		return string(data[3:])
+	}
+	return string(data)
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// truncate cuts s to at most n runes to fit a column
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
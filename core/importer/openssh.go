@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sshConfigStart matches the Host line of an OpenSSH client config
+var sshConfigStart = regexp.MustCompile(`(?im)^[ \t]*host[ \t]+\S`)
+
+// sshConfigBlock is a Host block of an OpenSSH client config, or the
+// settings before the first block, which apply to every host
+type sshConfigBlock struct {
+	patterns []string
+	options  [][2]string // keyword (lower case) and argument, in file order
+}
+
+// matches reports whether the block's patterns select alias the way
+// OpenSSH does: some pattern matches and no negated pattern does
+func (b *sshConfigBlock) matches(alias string) bool {
+	if b.patterns == nil {
+		return true
+	}
+	matched := false
+	for _, pattern := range b.patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		ok, _ := path.Match(strings.ToLower(strings.TrimPrefix(pattern, "!")), strings.ToLower(alias))
+		if ok && negated {
+			return false
+		}
+		matched = matched || ok
+	}
+	return matched
+}
+
+// parseOpenSSH reads an OpenSSH client config such as ~/.ssh/config. Each
+// alias of a Host line without wildcards becomes a host; settings of
+// wildcard blocks apply to the aliases they match, the first value of a
+// setting winning as in ssh itself.
+func parseOpenSSH(text string) (*Result, error) {
+	result := &Result{}
+	blocks := []*sshConfigBlock{{}}
+	var aliases []string
+	seen := make(map[string]bool)
+	inMatch := false
+
+	for n, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keyword, argument, ok := splitSSHConfigLine(line)
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: %q has no argument", ErrInvalidExport, n+1, line)
+		}
+
+		switch keyword {
+		case "host":
+			inMatch = false
+			block := &sshConfigBlock{patterns: sshConfigFields(argument)}
+			blocks = append(blocks, block)
+			for _, pattern := range block.patterns {
+				if strings.ContainsAny(pattern, "*?![") || seen[strings.ToLower(pattern)] {
+					continue
+				}
+				seen[strings.ToLower(pattern)] = true
+				aliases = append(aliases, pattern)
+			}
+		case "match":
+			inMatch = true
+			result.Skipped = append(result.Skipped, Skipped{
+				Name:   "Match " + argument,
+				Reason: "Match blocks are not imported",
+			})
+		case "include":
+			result.Skipped = append(result.Skipped, Skipped{
+				Name:   "Include " + argument,
+				Reason: "included files are not read, import them separately",
+			})
+		default:
+			if !inMatch {
+				block := blocks[len(blocks)-1]
+				block.options = append(block.options, [2]string{keyword, strings.Trim(argument, `"`)})
+			}
+		}
+	}
+
+	for _, alias := range aliases {
+		options := make(map[string]string)
+		for _, block := range blocks {
+			if !block.matches(alias) {
+				continue
+			}
+			for _, option := range block.options {
+				if _, set := options[option[0]]; !set {
+					options[option[0]] = option[1]
+				}
+			}
+		}
+		entry, err := sshConfigEntry(alias, options)
+		if err != nil {
+			result.Skipped = append(result.Skipped, Skipped{Name: alias, Reason: err.Error()})
+			continue
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
+
+// sshConfigEntry maps the settings that apply to an alias to an entry
+func sshConfigEntry(alias string, options map[string]string) (Entry, error) {
+	entry := newEntry(FormatOpenSSH, alias, "")
+	entry.Hostname = alias
+	if hostname := options["hostname"]; hostname != "" {
+		entry.Hostname = strings.ReplaceAll(hostname, "%h", alias)
+	}
+	entry.Username = options["user"]
+	if port := options["port"]; port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n <= 0 || n > 65535 {
+			return entry, fmt.Errorf("invalid port %q", port)
+		}
+		entry.Port = n
+	}
+
+	if keyFile := options["identityfile"]; keyFile != "" && !strings.EqualFold(keyFile, "none") {
+		entry.AuthMethod = "key"
+		entry.KeyFile = keyFile
+		entry.Warnings = append(entry.Warnings, "private key not included in the config, add it to the host")
+	} else if strings.EqualFold(options["passwordauthentication"], "yes") && strings.EqualFold(options["pubkeyauthentication"], "no") {
+		entry.Warnings = append(entry.Warnings, "no password saved")
+	} else {
+		entry.AuthMethod = "agent"
+	}
+
+	if jump := options["proxyjump"]; jump != "" && !strings.EqualFold(jump, "none") {
+		entry.ProxyCommand = sshJumpCommand(jump)
+	} else if command := options["proxycommand"]; command != "" && !strings.EqualFold(command, "none") {
+		entry.ProxyCommand = truncate(command, 500)
+	}
+
+	for _, keyword := range []string{"localforward", "remoteforward", "dynamicforward"} {
+		if options[keyword] != "" {
+			entry.Warnings = append(entry.Warnings, "forwards are not imported, add them as ports")
+			break
+		}
+	}
+	if entry.Username == "" {
+		entry.Warnings = append(entry.Warnings, "no user name set")
+	}
+	return entry, nil
+}
+
+// sshJumpCommand turns a ProxyJump list such as "bastion" or
+// "admin@gw:2222,bastion" into the equivalent ProxyCommand
+func sshJumpCommand(jump string) string {
+	hops := strings.Split(jump, ",")
+	last := hops[len(hops)-1]
+	// ssh takes a destination port on the command line only in URI form
+	if _, port, found := strings.Cut(last[strings.LastIndex(last, "@")+1:], ":"); found && port != "" {
+		last = "ssh://" + last
+	}
+	if len(hops) == 1 {
+		return "ssh -W %h:%p " + last
+	}
+	return "ssh -J " + strings.Join(hops[:len(hops)-1], ",") + " -W %h:%p " + last
+}
+
+// splitSSHConfigLine splits a config line into its lower-case keyword and
+// argument, which may be separated by whitespace or an equals sign
+func splitSSHConfigLine(line string) (string, string, bool) {
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return "", "", false
+	}
+	keyword := strings.ToLower(line[:i])
+	argument := strings.TrimSpace(line[i:])
+	argument = strings.TrimSpace(strings.TrimPrefix(argument, "="))
+	return keyword, argument, argument != ""
+}
+
+// sshConfigFields splits the patterns of a Host line, honoring quotes
+func sshConfigFields(argument string) []string {
+	var fields []string
+	for i, part := range strings.Split(argument, `"`) {
+		if i%2 == 1 {
+			fields = append(fields, part)
+			continue
+		}
+		fields = append(fields, strings.Fields(part)...)
+	}
+	return fields
+}
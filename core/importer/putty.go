@@ -0,0 +1,199 @@
+package importer
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// puttySessionsKey is the registry key PuTTY keeps its saved sessions under
+const puttySessionsKey = `\software\simontatham\putty\sessions\`
+
+// PuTTY proxy types, from the ProxyMethod setting
+const (
+	puttyProxyNone    = 0
+	puttyProxySOCKS4  = 1
+	puttyProxySOCKS5  = 2
+	puttyProxyHTTP    = 3
+	puttyProxyTelnet  = 4
+	puttyProxyCommand = 5
+	puttyProxySSH     = 6
+)
+
+// puttySession is the values of one session key
+type puttySession struct {
+	name    string
+	strings map[string]string
+	dwords  map[string]int
+}
+
+// parsePuTTY reads a registry export of PuTTY's sessions, as written by
+// "reg export HKCU\Software\SimonTatham\PuTTY\Sessions putty.reg"
+func parsePuTTY(text string) (*Result, error) {
+	var sessions []*puttySession
+	var current *puttySession
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		// Long hex values continue on the next line
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, `\`) + strings.TrimSpace(lines[i])
+		}
+
+		switch {
+		case line == "" || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "["):
+			current = nil
+			key := strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+			at := strings.Index(strings.ToLower(key), puttySessionsKey)
+			if at < 0 || strings.HasPrefix(key, "-") {
+				continue
+			}
+			name := key[at+len(puttySessionsKey):]
+			if unescaped, err := url.PathUnescape(name); err == nil {
+				name = unescaped
+			}
+			current = &puttySession{name: name, strings: map[string]string{}, dwords: map[string]int{}}
+			sessions = append(sessions, current)
+		case current != nil && strings.HasPrefix(line, `"`):
+			name, value, ok := parseRegValue(line)
+			if !ok {
+				continue
+			}
+			if dword, isDword := strings.CutPrefix(value, "dword:"); isDword {
+				if n, err := strconv.ParseUint(dword, 16, 32); err == nil {
+					current.dwords[name] = int(n)
+				}
+			} else if s, isString := unquoteRegString(value); isString {
+				current.strings[name] = s
+			}
+		}
+	}
+	if sessions == nil && !strings.Contains(strings.ToLower(text), strings.TrimSuffix(puttySessionsKey, `\`)) {
+		return nil, fmt.Errorf("%w: no PuTTY sessions key found", ErrInvalidExport)
+	}
+
+	result := &Result{}
+	for _, session := range sessions {
+		if session.name == "Default Settings" {
+			continue
+		}
+		entry, reason := session.entry()
+		if reason != "" {
+			result.Skipped = append(result.Skipped, Skipped{Name: session.name, Reason: reason})
+			continue
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
+
+// entry maps the session to a host, or returns why it cannot be
+func (s *puttySession) entry() (Entry, string) {
+	if protocol := s.strings["Protocol"]; protocol != "" && protocol != "ssh" {
+		return Entry{}, protocol + " session"
+	}
+	username, hostname := splitUserHost(s.strings["HostName"])
+	if hostname == "" {
+		return Entry{}, "no host name"
+	}
+
+	entry := newEntry(FormatPuTTY, s.name, "")
+	entry.Hostname = hostname
+	entry.Username = s.strings["UserName"]
+	if entry.Username == "" {
+		entry.Username = username
+	}
+	if port, ok := s.dwords["PortNumber"]; ok && port > 0 {
+		entry.Port = port
+	}
+
+	if keyFile := s.strings["PublicKeyFile"]; keyFile != "" {
+		entry.AuthMethod = "key"
+		entry.KeyFile = keyFile
+		entry.Warnings = append(entry.Warnings, "private key not included in the export, add it to the host (convert .ppk keys to OpenSSH format)")
+	}
+	if entry.Username == "" {
+		entry.Warnings = append(entry.Warnings, "no user name saved")
+	}
+
+	s.proxy(&entry)
+	return entry, ""
+}
+
+// proxy carries over the session's proxy where PortFly supports its type
+func (s *puttySession) proxy(entry *Entry) {
+	host := s.strings["ProxyHost"]
+	port := s.dwords["ProxyPort"]
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	user := s.strings["ProxyUsername"]
+
+	switch s.dwords["ProxyMethod"] {
+	case puttyProxyNone:
+	case puttyProxySOCKS5, puttyProxyHTTP:
+		scheme := "socks5"
+		if s.dwords["ProxyMethod"] == puttyProxyHTTP {
+			scheme = "http"
+		}
+		proxyURL := url.URL{Scheme: scheme, Host: address}
+		if user != "" {
+			proxyURL.User = url.User(user)
+			entry.Warnings = append(entry.Warnings, "proxy password not imported")
+		}
+		entry.ProxyURL = proxyURL.String()
+	case puttyProxySSH:
+		jump := host
+		if user != "" {
+			jump = user + "@" + host
+		}
+		if port != 0 && port != DefaultPort {
+			jump = "-p " + strconv.Itoa(port) + " " + jump
+		}
+		entry.ProxyCommand = "ssh -W %h:%p " + jump
+	case puttyProxyCommand:
+		command := s.strings["ProxyTelnetCommand"]
+		command = strings.NewReplacer("%host", "%h", "%port", "%p", "%user", "%r").Replace(command)
+		entry.ProxyCommand = command
+	case puttyProxySOCKS4:
+		entry.Warnings = append(entry.Warnings, "SOCKS4 proxy "+address+" not supported, not imported")
+	case puttyProxyTelnet:
+		entry.Warnings = append(entry.Warnings, "telnet proxy "+address+" not supported, not imported")
+	}
+}
+
+// parseRegValue splits a `"name"=value` line of a registry export
+func parseRegValue(line string) (string, string, bool) {
+	end := 1
+	for end < len(line) && line[end] != '"' {
+		if line[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(line) || !strings.HasPrefix(line[end+1:], "=") {
+		return "", "", false
+	}
+	name, _ := unquoteRegString(line[:end+1])
+	return name, line[end+2:], true
+}
+
+// unquoteRegString decodes a quoted registry string, where only backslash
+// and quote are escaped
+func unquoteRegString(value string) (string, bool) {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", false
+	}
+	var b strings.Builder
+	inner := value[1 : len(value)-1]
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == '\\' && i+1 < len(inner) {
+			i++
+		}
+		b.WriteByte(inner[i])
+	}
+	return b.String(), true
+}
@@ -0,0 +1,160 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// crtKey is a key of a SecureCRT XML export: a folder of keys, or a session
+// when it holds a Hostname value
+type crtKey struct {
+	Name    string     `xml:"name,attr"`
+	Keys    []crtKey   `xml:"key"`
+	Strings []crtValue `xml:"string"`
+	Dwords  []crtValue `xml:"dword"`
+}
+
+type crtValue struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// crtAuthMethods maps SecureCRT authentication methods to host methods
+var crtAuthMethods = map[string]string{
+	"publickey":            "key",
+	"gssapi":               "gssapi",
+	"password":             "password",
+	"keyboard-interactive": "password",
+}
+
+// parseSecureCRT reads the sessions of a SecureCRT XML export (File > Export
+// Settings). Passwords are stored encrypted and are not imported.
+func parseSecureCRT(text string) (*Result, error) {
+	var root crtKey
+	if err := xml.Unmarshal([]byte(text), &root); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+
+	var sessions *crtKey
+	for i := range root.Keys {
+		if strings.EqualFold(root.Keys[i].Name, "Sessions") {
+			sessions = &root.Keys[i]
+		}
+	}
+	if sessions == nil {
+		return nil, fmt.Errorf("%w: no Sessions key found", ErrInvalidExport)
+	}
+
+	result := &Result{}
+	var walk func(key *crtKey, folder string)
+	walk = func(key *crtKey, folder string) {
+		for i := range key.Keys {
+			child := &key.Keys[i]
+			if _, isSession := child.value("Hostname"); !isSession {
+				walk(child, strings.TrimPrefix(folder+"/"+child.Name, "/"))
+				continue
+			}
+			if folder == "" && child.Name == "Default" {
+				continue
+			}
+			entry, reason := child.entry(folder)
+			if reason != "" {
+				result.Skipped = append(result.Skipped, Skipped{Name: child.Name, Reason: reason})
+				continue
+			}
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+	walk(sessions, "")
+	return result, nil
+}
+
+// entry maps the session to a host, or returns why it cannot be
+func (k *crtKey) entry(folder string) (Entry, string) {
+	protocol, _ := k.value("Protocol Name")
+	if protocol != "" && !strings.HasPrefix(strings.ToUpper(protocol), "SSH") {
+		return Entry{}, protocol + " session"
+	}
+	hostname, _ := k.value("Hostname")
+	username, hostname := splitUserHost(hostname)
+	if hostname == "" {
+		return Entry{}, "no host name"
+	}
+
+	entry := newEntry(FormatSecureCRT, k.Name, folder)
+	entry.Hostname = hostname
+	if entry.Username, _ = k.value("Username"); entry.Username == "" {
+		entry.Username = username
+	}
+	portKey := "[SSH2] Port"
+	if strings.EqualFold(protocol, "SSH1") {
+		portKey = "[SSH1] Port"
+	}
+	if port := k.dword(portKey); port > 0 {
+		entry.Port = port
+	}
+
+	keyFile, _ := k.value("Identity Filename V2")
+	if keyFile == "" {
+		keyFile, _ = k.value("Identity Filename")
+	}
+	keyFile, _, _ = strings.Cut(keyFile, "::") // V2 appends the key type, e.g. "::rawkey"
+
+	// The first method SecureCRT tries decides the host's method
+	methods, _ := k.value("SSH2 Authentications V2")
+	for _, method := range strings.Split(methods, ",") {
+		if authMethod, ok := crtAuthMethods[strings.ToLower(strings.TrimSpace(method))]; ok {
+			entry.AuthMethod = authMethod
+			break
+		}
+	}
+	if entry.AuthMethod == "key" {
+		entry.KeyFile = keyFile
+		if keyFile == "" {
+			entry.Warnings = append(entry.Warnings, "uses SecureCRT's global public key, add the private key to the host")
+		} else {
+			entry.Warnings = append(entry.Warnings, "private key not included in the export, add it to the host")
+		}
+	}
+	if saved, _ := k.value("Password V2"); saved != "" && entry.AuthMethod == "password" {
+		entry.Warnings = append(entry.Warnings, "saved password is encrypted and was not imported")
+	}
+	if entry.Username == "" {
+		entry.Warnings = append(entry.Warnings, "no user name saved")
+	}
+	if firewall, _ := k.value("Firewall Name"); firewall != "" && !strings.EqualFold(firewall, "None") {
+		entry.Warnings = append(entry.Warnings, "firewall "+firewall+" not imported, set the host's proxy")
+	}
+	return entry, ""
+}
+
+// value returns a string value of the key and whether it is set
+func (k *crtKey) value(name string) (string, bool) {
+	for _, v := range k.Strings {
+		if v.Name == name {
+			return strings.TrimSpace(v.Value), true
+		}
+	}
+	return "", false
+}
+
+// dword returns a numeric value of the key, written in decimal or with a 0x
+// prefix, or 0 when unset
+func (k *crtKey) dword(name string) int {
+	for _, v := range k.Dwords {
+		if v.Name == name {
+			value, base := strings.TrimSpace(v.Value), 10
+			if hex, ok := strings.CutPrefix(strings.ToLower(value), "0x"); ok {
+				value, base = hex, 16
+			}
+			n, err := strconv.ParseInt(value, base, 64)
+			if err != nil {
+				return 0
+			}
+			return int(n)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,134 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// termiusColumns maps the header names seen in Termius exports and import
+// templates to the fields they hold; headers are compared case-insensitively
+// without spaces, underscores and dashes
+var termiusColumns = map[string]string{
+	"label":      "name",
+	"alias":      "name",
+	"name":       "name",
+	"hostname":   "hostname",
+	"hostnameip": "hostname",
+	"host":       "hostname",
+	"address":    "hostname",
+	"ip":         "hostname",
+	"port":       "port",
+	"sshport":    "port",
+	"username":   "username",
+	"user":       "username",
+	"password":   "password",
+	"sshkey":     "key",
+	"key":        "key",
+	"identity":   "key",
+	"group":      "group",
+	"groups":     "group",
+	"folder":     "group",
+	"tags":       "tags",
+	"protocol":   "protocol",
+}
+
+// parseTermius reads a CSV host list as exported by Termius. A plain-text
+// password is carried over; a key column holding a private key is imported,
+// a key name is left as a hint.
+func parseTermius(text string) (*Result, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		key := strings.NewReplacer(" ", "", "_", "", "-", "", "/", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+		if field, ok := termiusColumns[key]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["hostname"]; !ok {
+		return nil, fmt.Errorf("%w: no hostname column in CSV header", ErrInvalidExport)
+	}
+
+	result := &Result{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		username, hostname := splitUserHost(field("hostname"))
+		name := field("name")
+		if name == "" {
+			name = hostname
+		}
+		if hostname == "" {
+			if name != "" {
+				result.Skipped = append(result.Skipped, Skipped{Name: name, Reason: "no host name"})
+			}
+			continue
+		}
+		if protocol := strings.ToLower(field("protocol")); protocol != "" && protocol != "ssh" && protocol != "mosh" {
+			result.Skipped = append(result.Skipped, Skipped{Name: name, Reason: protocol + " host"})
+			continue
+		}
+
+		entry := newEntry(FormatTermius, name, strings.Trim(field("group"), "/"))
+		entry.Hostname = hostname
+		entry.Username = field("username")
+		if entry.Username == "" {
+			entry.Username = username
+		}
+		if value := field("port"); value != "" {
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 1 || port > 65535 {
+				result.Skipped = append(result.Skipped, Skipped{Name: name, Reason: fmt.Sprintf("invalid port %q", value)})
+				continue
+			}
+			entry.Port = port
+		}
+		for _, tag := range strings.FieldsFunc(field("tags"), func(r rune) bool { return r == ',' || r == ';' }) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				entry.Tags = append(entry.Tags, tag)
+			}
+		}
+
+		entry.Password = field("password")
+		switch key := field("key"); {
+		case strings.Contains(key, "PRIVATE KEY-----"):
+			entry.AuthMethod = "key"
+			entry.PrivateKey = key
+		case key != "":
+			entry.AuthMethod = "key"
+			entry.KeyFile = key
+			entry.Warnings = append(entry.Warnings, "private key not included in the export, add it to the host")
+		case entry.Password == "":
+			entry.Warnings = append(entry.Warnings, "no password or key saved")
+		}
+		if entry.Username == "" {
+			entry.Warnings = append(entry.Warnings, "no user name saved")
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	return result, nil
+}
@@ -0,0 +1,60 @@
+// Package keychain stores credentials in the operating system's keychain:
+// the macOS Keychain, the Secret Service (GNOME Keyring, KWallet) through
+// secret-tool, or files encrypted with DPAPI on Windows.
+package keychain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Service is the name PortFly's entries are stored under
+const Service = "portfly"
+
+// Common errors
+var (
+	ErrNotFound    = errors.New("credential not found in keychain")
+	ErrUnsupported = errors.New("no OS keychain available")
+)
+
+// commandTimeout bounds each call to the keychain tools, which may wait on a
+// locked keyring
+const commandTimeout = 30 * time.Second
+
+// Get returns the secret stored for account
+func Get(account string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	secret, err := get(ctx, account)
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrUnsupported) {
+		return "", fmt.Errorf("failed to read %s from keychain: %w", account, err)
+	}
+	return secret, err
+}
+
+// Set stores secret for account, replacing any previous one
+func Set(account, secret string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	err := set(ctx, account, secret)
+	if err != nil && !errors.Is(err, ErrUnsupported) {
+		return fmt.Errorf("failed to store %s in keychain: %w", account, err)
+	}
+	return err
+}
+
+// Delete removes the secret stored for account
+func Delete(account string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	err := remove(ctx, account)
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrUnsupported) {
+		return fmt.Errorf("failed to remove %s from keychain: %w", account, err)
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+//go:build darwin
+
+package keychain
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// errSecItemNotFound is the exit status of security(1) for a missing item
+const errSecItemNotFound = 44
+
+// get reads a generic password from the login keychain
+func get(ctx context.Context, account string) (string, error) {
+	out, err := security(ctx, "", "find-generic-password", "-s", Service, "-a", account, "-w")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+// set adds or updates a generic password. The command is passed on stdin to
+// security's interactive mode, with the secret hex-encoded, so the secret
+// never appears in a process's arguments.
+func set(ctx context.Context, account, secret string) error {
+	line := fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n",
+		quote(Service), quote(account), hex.EncodeToString([]byte(secret)))
+	_, err := security(ctx, line, "-i")
+	return err
+}
+
+// remove deletes a generic password
+func remove(ctx context.Context, account string) error {
+	_, err := security(ctx, "", "delete-generic-password", "-s", Service, "-a", account)
+	return err
+}
+
+// security runs security(1), mapping a missing item to ErrNotFound
+func security(ctx context.Context, stdin string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "security", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == errSecItemNotFound {
+			return "", ErrNotFound
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", ErrUnsupported
+		}
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	// Interactive mode reports failures on stderr without an exit status
+	if len(args) > 0 && args[0] == "-i" && strings.TrimSpace(stderr.String()) != "" {
+		return "", errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// quote quotes an argument for security's interactive mode
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,17 @@
+//go:build !darwin && !windows && !linux && !freebsd && !openbsd && !netbsd && !dragonfly
+
+package keychain
+
+import "context"
+
+func get(ctx context.Context, account string) (string, error) {
+	return "", ErrUnsupported
+}
+
+func set(ctx context.Context, account, secret string) error {
+	return ErrUnsupported
+}
+
+func remove(ctx context.Context, account string) error {
+	return ErrUnsupported
+}
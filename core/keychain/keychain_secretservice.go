@@ -0,0 +1,67 @@
+//go:build linux || freebsd || openbsd || netbsd || dragonfly
+
+package keychain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// get looks the secret up in the Secret Service
+func get(ctx context.Context, account string) (string, error) {
+	out, err := secretTool(ctx, "", "lookup", "service", Service, "account", account)
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		return "", ErrNotFound
+	}
+	return out, nil
+}
+
+// set stores the secret, which secret-tool reads from stdin
+func set(ctx context.Context, account, secret string) error {
+	_, err := secretTool(ctx, secret, "store", "--label", "PortFly "+account, "service", Service, "account", account)
+	return err
+}
+
+// remove clears the secret; secret-tool does not report whether it existed
+func remove(ctx context.Context, account string) error {
+	if _, err := get(ctx, account); err != nil {
+		return err
+	}
+	_, err := secretTool(ctx, "", "clear", "service", Service, "account", account)
+	return err
+}
+
+// secretTool runs secret-tool(1) from libsecret. Lookups of missing items
+// exit with status 1 and no output.
+func secretTool(ctx context.Context, stdin string, args ...string) (string, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return "", fmt.Errorf("%w: secret-tool not found (install libsecret-tools)", ErrUnsupported)
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if args[0] == "lookup" && errors.As(err, &exitErr) && stderr.Len() == 0 {
+			return "", ErrNotFound
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			// No session bus or keyring daemon, e.g. over SSH or in a container
+			return "", fmt.Errorf("%w: %s", ErrUnsupported, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
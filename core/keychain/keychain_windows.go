@@ -0,0 +1,93 @@
+//go:build windows
+
+package keychain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Secrets are encrypted with DPAPI for the current user and kept one file
+// per account under the user's configuration directory
+
+// get decrypts the account's file
+func get(ctx context.Context, account string) (string, error) {
+	path, err := credentialFile(account)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	in := newBlob(data)
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return "", fmt.Errorf("DPAPI decrypt: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return string(unsafe.Slice(out.Data, out.Size)), nil
+}
+
+// set encrypts the secret into the account's file
+func set(ctx context.Context, account, secret string) error {
+	path, err := credentialFile(account)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	in := newBlob([]byte(secret))
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return fmt.Errorf("DPAPI encrypt: %w", err)
+	}
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data)))
+	return os.WriteFile(path, unsafe.Slice(out.Data, out.Size), 0o600)
+}
+
+// remove deletes the account's file
+func remove(ctx context.Context, account string) error {
+	path, err := credentialFile(account)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// credentialFile returns the file holding account's secret; the name is a
+// hash so accounts with path separators or URLs map to plain file names
+func credentialFile(account string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	sum := sha256.Sum256([]byte(Service + "\x00" + account))
+	return filepath.Join(dir, Service, "credentials", hex.EncodeToString(sum[:])+".dpapi"), nil
+}
+
+// newBlob wraps data for the DPAPI calls
+func newBlob(data []byte) *windows.DataBlob {
+	if len(data) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+}
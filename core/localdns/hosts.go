@@ -0,0 +1,78 @@
+package localdns
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Markers delimiting the block managed by PortFly in the hosts file
+const (
+	hostsBlockBegin = "# BEGIN portfly (managed automatically, do not edit)"
+	hostsBlockEnd   = "# END portfly"
+)
+
+// hostsFile rewrites a managed block of the hosts file, leaving other entries untouched
+type hostsFile struct {
+	path string
+	mu   sync.Mutex
+}
+
+// write replaces the managed block with lines; an empty list removes the block
+func (h *hostsFile) write(lines []string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, err := os.ReadFile(h.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	content := stripManagedBlock(string(data))
+	if len(lines) > 0 {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += hostsBlockBegin + "\n" + strings.Join(lines, "\n") + "\n" + hostsBlockEnd + "\n"
+	}
+
+	if content == string(data) {
+		return nil
+	}
+
+	info, err := os.Stat(h.path)
+	mode := os.FileMode(0644)
+	if err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	// Write in place: the hosts file is often a bind mount or has special ownership
+	return os.WriteFile(h.path, []byte(content), mode)
+}
+
+// stripManagedBlock removes the PortFly block from hosts file content
+func stripManagedBlock(content string) string {
+	begin := strings.Index(content, hostsBlockBegin)
+	if begin < 0 {
+		return content
+	}
+	end := strings.Index(content[begin:], hostsBlockEnd)
+	if end < 0 {
+		return content[:begin]
+	}
+	end += begin + len(hostsBlockEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	return content[:begin] + content[end:]
+}
+
+// defaultHostsFile returns the platform hosts file path
+func defaultHostsFile() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("SystemRoot"), "System32", "drivers", "etc", "hosts")
+	}
+	return "/etc/hosts"
+}
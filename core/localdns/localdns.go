@@ -0,0 +1,228 @@
+package localdns
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// Modes for publishing names
+const (
+	ModeHosts    = "hosts"    // managed block in the hosts file
+	ModeResolver = "resolver" // embedded DNS server for the domain
+)
+
+// DefaultDomain is the top-level domain used for tunnel names
+const DefaultDomain = "portfly"
+
+// Config configures friendly hostnames for active tunnels
+type Config = models.LocalDNSConfig
+
+// Manager keeps the name -> address records of active tunnels and publishes
+// them through the configured backend
+type Manager struct {
+	config  Config
+	logger  utils.Logger
+	records map[string]net.IP
+	mu      sync.RWMutex
+
+	hosts    *hostsFile
+	resolver *resolver
+}
+
+// NewManager creates a manager and starts its backend
+func NewManager(config Config, logger utils.Logger) (*Manager, error) {
+	return NewManagerWithListener(config, net.ListenPacket, logger)
+}
+
+// NewManagerWithListener is NewManager with the function opening the
+// resolver's UDP socket, e.g. to reuse a socket inherited from another process
+func NewManagerWithListener(config Config, listen func(network, address string) (net.PacketConn, error), logger utils.Logger) (*Manager, error) {
+	if config.Domain == "" {
+		config.Domain = DefaultDomain
+	}
+	config.Domain = strings.Trim(strings.ToLower(config.Domain), ".")
+	if config.Mode == "" {
+		config.Mode = ModeHosts
+	}
+
+	m := &Manager{
+		config:  config,
+		logger:  logger,
+		records: make(map[string]net.IP),
+	}
+
+	switch config.Mode {
+	case ModeHosts:
+		path := config.HostsFile
+		if path == "" {
+			path = defaultHostsFile()
+		}
+		m.hosts = &hostsFile{path: path}
+	case ModeResolver:
+		addr := config.ListenAddr
+		if addr == "" {
+			addr = "127.0.0.1:5353"
+		}
+		r, err := startResolver(addr, listen, m, logger)
+		if err != nil {
+			return nil, err
+		}
+		m.resolver = r
+	default:
+		return nil, fmt.Errorf("invalid local DNS mode: %s (expected hosts or resolver)", config.Mode)
+	}
+
+	logger.Info("local DNS enabled", "mode", config.Mode, "domain", config.Domain)
+	return m, nil
+}
+
+// Name builds a fully qualified name from labels, e.g. Name("db", "staging")
+// returns "db.staging.portfly"
+func (m *Manager) Name(labels ...string) string {
+	var parts []string
+	for _, label := range labels {
+		if label = sanitizeLabel(label); label != "" {
+			parts = append(parts, label)
+		}
+	}
+	return strings.Join(append(parts, m.config.Domain), ".")
+}
+
+// Register points name at the tunnel's bind address
+func (m *Manager) Register(name, bindAddress string) error {
+	if m == nil {
+		return nil
+	}
+
+	ip := reachableIP(bindAddress)
+	if ip == nil {
+		return fmt.Errorf("cannot publish %s: bind address %q is not an IP address", name, bindAddress)
+	}
+
+	m.mu.Lock()
+	if current, exists := m.records[name]; exists && current.Equal(ip) {
+		m.mu.Unlock()
+		return nil
+	}
+	m.records[name] = ip
+	m.mu.Unlock()
+
+	m.logger.Info("local DNS name registered", "name", name, "address", ip.String())
+	return m.publish()
+}
+
+// Unregister removes the record for name
+func (m *Manager) Unregister(name string) error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	_, exists := m.records[name]
+	delete(m.records, name)
+	m.mu.Unlock()
+
+	if !exists {
+		return nil
+	}
+
+	m.logger.Info("local DNS name removed", "name", name)
+	return m.publish()
+}
+
+// Records returns a copy of the current name -> address records
+func (m *Manager) Records() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	records := make(map[string]string, len(m.records))
+	for name, ip := range m.records {
+		records[name] = ip.String()
+	}
+	return records
+}
+
+// Close removes all published names and stops the resolver
+func (m *Manager) Close() error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	m.records = make(map[string]net.IP)
+	m.mu.Unlock()
+
+	if m.resolver != nil {
+		return m.resolver.Close()
+	}
+	return m.publish()
+}
+
+// lookup returns the record for a fully qualified name
+func (m *Manager) lookup(name string) (net.IP, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ip, exists := m.records[strings.TrimSuffix(strings.ToLower(name), ".")]
+	return ip, exists
+}
+
+// inDomain reports whether name belongs to the managed domain
+func (m *Manager) inDomain(name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	return name == m.config.Domain || strings.HasSuffix(name, "."+m.config.Domain)
+}
+
+// publish writes the records to the hosts file; the resolver reads them live
+func (m *Manager) publish() error {
+	if m.hosts == nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	names := make([]string, 0, len(m.records))
+	for name := range m.records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, m.records[name].String()+"\t"+name)
+	}
+	m.mu.RUnlock()
+
+	if err := m.hosts.write(lines); err != nil {
+		return fmt.Errorf("failed to update %s: %w", m.hosts.path, err)
+	}
+	return nil
+}
+
+// reachableIP maps a bind address to the address clients should connect to
+func reachableIP(bindAddress string) net.IP {
+	switch bindAddress {
+	case "", "*", "localhost", "0.0.0.0":
+		return net.IPv4(127, 0, 0, 1)
+	case "::":
+		return net.IPv6loopback
+	}
+	return net.ParseIP(strings.Trim(bindAddress, "[]"))
+}
+
+// invalidLabelChars matches characters not allowed in a DNS label
+var invalidLabelChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// sanitizeLabel lowercases a name and replaces characters not valid in DNS
+func sanitizeLabel(label string) string {
+	label = invalidLabelChars.ReplaceAllString(strings.ToLower(label), "-")
+	label = strings.Trim(label, "-")
+	if len(label) > 63 {
+		label = strings.TrimRight(label[:63], "-")
+	}
+	return label
+}
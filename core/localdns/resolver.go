@@ -0,0 +1,132 @@
+package localdns
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// recordTTL is kept short so stopped tunnels disappear quickly from caches
+const recordTTL = 5
+
+// resolver is a minimal authoritative DNS server for the managed domain.
+// Point the system resolver at it for the domain only, e.g. a
+// /etc/resolver/portfly file on macOS or a systemd-resolved/dnsmasq
+// forwarding rule on Linux.
+type resolver struct {
+	conn    net.PacketConn
+	manager *Manager
+	logger  utils.Logger
+}
+
+// startResolver listens on addr (UDP) and serves queries in the background
+func startResolver(addr string, listen func(network, address string) (net.PacketConn, error), manager *Manager, logger utils.Logger) (*resolver, error) {
+	conn, err := listen("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start local DNS resolver on %s: %w", addr, err)
+	}
+
+	r := &resolver{conn: conn, manager: manager, logger: logger}
+	go r.serve()
+	return r, nil
+}
+
+// Close stops the resolver
+func (r *resolver) Close() error {
+	return r.conn.Close()
+}
+
+// serve answers queries until the connection is closed
+func (r *resolver) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			r.logger.Warn("local DNS read failed", "error", err)
+			continue
+		}
+
+		response, err := r.answer(buf[:n])
+		if err != nil {
+			r.logger.Debug("dropping malformed DNS query", "error", err)
+			continue
+		}
+		if _, err := r.conn.WriteTo(response, addr); err != nil {
+			r.logger.Debug("local DNS write failed", "error", err)
+		}
+	}
+}
+
+// answer builds the response for a single query packet
+func (r *resolver) answer(query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	responseHeader := dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		OpCode:             header.OpCode,
+		Authoritative:      true,
+		RecursionDesired:   header.RecursionDesired,
+		RCode:              dnsmessage.RCodeSuccess,
+		RecursionAvailable: false,
+	}
+
+	name := question.Name.String()
+	ip, found := r.manager.lookup(name)
+	switch {
+	case !r.manager.inDomain(name):
+		responseHeader.RCode = dnsmessage.RCodeRefused
+	case !found:
+		responseHeader.RCode = dnsmessage.RCodeNameError
+	}
+
+	builder := dnsmessage.NewBuilder(nil, responseHeader)
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	if found && question.Class == dnsmessage.ClassINET {
+		resourceHeader := dnsmessage.ResourceHeader{
+			Name:  question.Name,
+			Class: dnsmessage.ClassINET,
+			TTL:   recordTTL,
+		}
+		if ip4 := ip.To4(); ip4 != nil && question.Type == dnsmessage.TypeA {
+			var a dnsmessage.AResource
+			copy(a.A[:], ip4)
+			if err := builder.AResource(resourceHeader, a); err != nil {
+				return nil, err
+			}
+		} else if ip.To4() == nil && question.Type == dnsmessage.TypeAAAA {
+			var aaaa dnsmessage.AAAAResource
+			copy(aaaa.AAAA[:], ip.To16())
+			if err := builder.AAAAResource(resourceHeader, aaaa); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return builder.Finish()
+}
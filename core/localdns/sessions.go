@@ -0,0 +1,83 @@
+package localdns
+
+import (
+	"context"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// SessionSource is the part of the session manager the watcher needs
+type SessionSource interface {
+	Subscribe() (<-chan models.SessionEvent, func())
+	GetSession(sessionID string) (*models.Session, error)
+}
+
+// WatchSessions keeps records in sync with session lifecycle events until ctx
+// is done. Each active local or dynamic session is published as
+// <session name>.<domain>; remote forwards listen on the SSH server and are skipped.
+func WatchSessions(ctx context.Context, source SessionSource, m *Manager) {
+	if m == nil {
+		return
+	}
+
+	events, unsubscribe := source.Subscribe()
+	defer unsubscribe()
+
+	names := make(map[string]string) // session ID -> published name
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			m.handleSessionEvent(source, names, event)
+		}
+	}
+}
+
+// handleSessionEvent registers or removes the name of the session in event
+func (m *Manager) handleSessionEvent(source SessionSource, names map[string]string, event models.SessionEvent) {
+	if event.Type == models.EventSessionStats {
+		return // statistics do not change the name
+	}
+
+	remove := func() {
+		if name, exists := names[event.SessionID]; exists {
+			delete(names, event.SessionID)
+			if err := m.Unregister(name); err != nil {
+				m.logger.Warn("failed to remove local DNS name", "name", name, "error", err)
+			}
+		}
+	}
+
+	switch {
+	case event.Type == models.EventSessionGaveUp,
+		event.Status == models.StatusStopped,
+		event.Status == models.StatusError:
+		remove()
+		return
+	case event.Status != models.StatusActive:
+		return
+	}
+
+	session, err := source.GetSession(event.SessionID)
+	if err != nil || session.TunnelConfig.Type == models.TunnelTypeRemote {
+		return
+	}
+
+	name := m.Name(session.Name)
+	if session.Name == "" {
+		name = m.Name(session.ID)
+	}
+	if current, exists := names[event.SessionID]; exists && current != name {
+		remove()
+	}
+
+	if err := m.Register(name, session.TunnelConfig.Binds()[0].Address); err != nil {
+		m.logger.Warn("failed to publish local DNS name", "name", name, "error", err)
+		return
+	}
+	names[event.SessionID] = name
+}
@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// eventBufferSize is the per-subscriber channel capacity; slow subscribers drop events
+const eventBufferSize = 64
+
+// EventBus fans out session events to subscribers
+type EventBus struct {
+	subscribers map[int]chan models.SessionEvent
+	nextID      int
+	mu          sync.RWMutex
+
+	// Events dropped because a subscriber's buffer was full
+	dropped atomic.Uint64
+}
+
+// EventBusStats describes subscriber queues, for health reporting
+type EventBusStats struct {
+	Subscribers int    `json:"subscribers"`
+	Queued      int    `json:"queued"`     // events buffered across all subscribers
+	MaxQueued   int    `json:"max_queued"` // fullest subscriber buffer
+	Capacity    int    `json:"capacity"`   // buffer size per subscriber
+	Dropped     uint64 `json:"dropped_total"`
+}
+
+// NewEventBus creates a new event bus
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[int]chan models.SessionEvent),
+	}
+}
+
+// Subscribe registers a new subscriber and returns its channel and an unsubscribe function
+func (eb *EventBus) Subscribe() (<-chan models.SessionEvent, func()) {
+	ch := make(chan models.SessionEvent, eventBufferSize)
+	if eb == nil {
+		close(ch)
+		return ch, func() {}
+	}
+
+	eb.mu.Lock()
+	id := eb.nextID
+	eb.nextID++
+	eb.subscribers[id] = ch
+	eb.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			eb.mu.Lock()
+			delete(eb.subscribers, id)
+			eb.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers an event to all subscribers without blocking
+func (eb *EventBus) Publish(event models.SessionEvent) {
+	if eb == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	for _, ch := range eb.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Subscriber is not keeping up, drop the event
+			eb.dropped.Add(1)
+		}
+	}
+}
+
+// Stats returns the current queue depth of the subscribers
+func (eb *EventBus) Stats() EventBusStats {
+	stats := EventBusStats{Capacity: eventBufferSize}
+	if eb == nil {
+		return stats
+	}
+
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	stats.Subscribers = len(eb.subscribers)
+	for _, ch := range eb.subscribers {
+		stats.Queued += len(ch)
+		stats.MaxQueued = max(stats.MaxQueued, len(ch))
+	}
+	stats.Dropped = eb.dropped.Load()
+	return stats
+}
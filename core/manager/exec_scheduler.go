@@ -0,0 +1,432 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Defaults applied to zero ExecSchedulerConfig fields
+const (
+	// DefaultSessionsPerConnection matches the MaxSessions default of OpenSSH
+	DefaultSessionsPerConnection = 10
+	DefaultConnectionsPerHost    = 2
+	DefaultExecIdleTimeout       = 30 * time.Second
+)
+
+// maxSessionAttempts bounds how often opening a session is retried after the
+// server refused it or the connection turned out to be dead
+const maxSessionAttempts = 3
+
+// ExecSchedulerConfig configures how commands share SSH connections
+type ExecSchedulerConfig struct {
+	SessionsPerConnection int           `json:"sessions_per_connection"` // sessions open at once on one connection
+	ConnectionsPerHost    int           `json:"connections_per_host"`    // connections opened to one host before commands queue
+	IdleTimeout           time.Duration `json:"idle_timeout"`            // idle connections are closed after this
+}
+
+// ExecDialer opens an SSH connection; close is called once the scheduler
+// stops using it
+type ExecDialer func(ctx context.Context) (client *ssh.Client, close func(), err error)
+
+// ExecTarget identifies where a command runs. Commands with the same key share
+// connections, so the key must change whenever the connection would differ,
+// e.g. with the host's credentials or the user answering its challenges.
+type ExecTarget struct {
+	HostID uint
+	Key    string
+	Limit  HostLimit // the host's concurrency limit, enforced per session
+	Dial   ExecDialer
+}
+
+// ExecHostStats reports the scheduler state of one host
+type ExecHostStats struct {
+	HostID            uint    `json:"host_id"`
+	Connections       int     `json:"connections"`        // open connections
+	Sessions          int     `json:"sessions"`           // commands running
+	Queued            int     `json:"queued"`             // commands waiting for a session
+	SessionLimit      int     `json:"session_limit"`      // lowest session limit of its connections
+	Commands          int64   `json:"commands_total"`     // sessions opened
+	ConnectionsOpened int64   `json:"connections_total"`  // connections dialed
+	SessionsRefused   int64   `json:"sessions_refused"`   // sessions the server refused, lowering the limit
+	QueueWaitSeconds  float64 `json:"queue_wait_seconds"` // total time commands waited for a session
+}
+
+// ExecStats reports the scheduler state of all hosts with connections or
+// waiting commands, and totals since the start
+type ExecStats struct {
+	Connections       int             `json:"connections"`
+	Sessions          int             `json:"sessions"`
+	Queued            int             `json:"queued"`
+	Commands          int64           `json:"commands_total"`
+	ConnectionsOpened int64           `json:"connections_total"`
+	Reused            int64           `json:"reused_total"` // sessions opened on an existing connection
+	SessionsRefused   int64           `json:"sessions_refused_total"`
+	QueueWaitSeconds  float64         `json:"queue_wait_seconds_total"`
+	Hosts             []ExecHostStats `json:"hosts"`
+}
+
+// ExecScheduler runs commands over shared SSH connections. A burst of
+// commands to one host is spread over a few connections, each carrying up to
+// the per-connection session limit, and further commands queue until a
+// session ends. Servers refusing sessions below the limit lower it for that
+// connection. Every session also holds a slot of the host broker, so the
+// host's MaxSessions applies as without multiplexing.
+type ExecScheduler struct {
+	broker *HostBroker
+	config ExecSchedulerConfig
+
+	mu    sync.Mutex
+	pools map[string]*execPool
+	hosts map[uint]*execCounters
+
+	reused int64
+}
+
+// execPool holds the connections shared by commands with the same key
+type execPool struct {
+	key     string
+	hostID  uint
+	conns   []*execConn
+	queued  int
+	changed chan struct{} // closed and replaced when a session may be free
+}
+
+// execConn is one connection of a pool
+type execConn struct {
+	pool     *execPool
+	client   *ssh.Client
+	close    func()
+	ready    chan struct{} // closed once dialing finished
+	err      error
+	sessions int
+	limit    int
+	broken   bool
+	idle     *time.Timer
+}
+
+// execCounters are the running totals of one host
+type execCounters struct {
+	commands, connections, refused int64
+	queueWait                      time.Duration
+}
+
+// NewExecScheduler creates a scheduler taking session slots from broker
+func NewExecScheduler(broker *HostBroker, config ExecSchedulerConfig) *ExecScheduler {
+	if config.SessionsPerConnection <= 0 {
+		config.SessionsPerConnection = DefaultSessionsPerConnection
+	}
+	if config.ConnectionsPerHost <= 0 {
+		config.ConnectionsPerHost = DefaultConnectionsPerHost
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = DefaultExecIdleTimeout
+	}
+	return &ExecScheduler{
+		broker: broker,
+		config: config,
+		pools:  make(map[string]*execPool),
+		hosts:  make(map[uint]*execCounters),
+	}
+}
+
+// Session opens an SSH session on a shared connection to the target, waiting
+// for a free session when the target's connections are full. The returned
+// release function closes the session and must be called once the command
+// is done.
+func (s *ExecScheduler) Session(ctx context.Context, target ExecTarget) (*ssh.Session, func(), error) {
+	releaseSlot, err := s.broker.Acquire(ctx, target.HostID, target.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxSessionAttempts; attempt++ {
+		conn, err := s.reserve(ctx, target)
+		if err != nil {
+			releaseSlot()
+			return nil, nil, err
+		}
+
+		session, err := conn.client.NewSession()
+		if err == nil {
+			s.mu.Lock()
+			s.counters(target.HostID).commands++
+			s.mu.Unlock()
+			return session, s.releaser(conn, session, releaseSlot), nil
+		}
+		lastErr = err
+		s.refused(conn, err)
+	}
+	releaseSlot()
+	return nil, nil, fmt.Errorf("failed to create SSH session: %w", lastErr)
+}
+
+// Stats returns the scheduler state and totals
+func (s *ExecScheduler) Stats() ExecStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := ExecStats{Reused: s.reused, Hosts: []ExecHostStats{}}
+	byHost := make(map[uint]*ExecHostStats)
+	for _, pool := range s.pools {
+		host, ok := byHost[pool.hostID]
+		if !ok {
+			host = &ExecHostStats{HostID: pool.hostID}
+			byHost[pool.hostID] = host
+		}
+		host.Queued += pool.queued
+		for _, conn := range pool.conns {
+			host.Connections++
+			host.Sessions += conn.sessions
+			if host.SessionLimit == 0 || conn.limit < host.SessionLimit {
+				host.SessionLimit = conn.limit
+			}
+		}
+	}
+	for hostID, counters := range s.hosts {
+		stats.Commands += counters.commands
+		stats.ConnectionsOpened += counters.connections
+		stats.SessionsRefused += counters.refused
+		stats.QueueWaitSeconds += counters.queueWait.Seconds()
+		if host, ok := byHost[hostID]; ok {
+			host.Commands = counters.commands
+			host.ConnectionsOpened = counters.connections
+			host.SessionsRefused = counters.refused
+			host.QueueWaitSeconds = counters.queueWait.Seconds()
+		}
+	}
+	for _, host := range byHost {
+		stats.Connections += host.Connections
+		stats.Sessions += host.Sessions
+		stats.Queued += host.Queued
+		stats.Hosts = append(stats.Hosts, *host)
+	}
+	slices.SortFunc(stats.Hosts, func(a, b ExecHostStats) int { return int(a.HostID) - int(b.HostID) })
+	return stats
+}
+
+// Close closes all idle connections; connections in use close when their
+// last session ends
+func (s *ExecScheduler) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pool := range s.pools {
+		for _, conn := range slices.Clone(pool.conns) {
+			conn.broken = true
+			if conn.sessions == 0 {
+				s.closeConn(conn)
+			}
+		}
+	}
+}
+
+// reserve counts a session on a connection of the target's pool, dialing a
+// new connection while the pool has room and waiting for a free session
+// otherwise
+func (s *ExecScheduler) reserve(ctx context.Context, target ExecTarget) (*execConn, error) {
+	queuedAt := time.Now()
+	queued := false
+
+	s.mu.Lock()
+	pool := s.pool(target)
+	counters := s.counters(target.HostID)
+	for {
+		var conn *execConn
+		for _, candidate := range pool.conns {
+			if !candidate.broken && candidate.sessions < candidate.limit {
+				conn = candidate
+				break
+			}
+		}
+
+		if conn != nil || len(pool.conns) < s.config.ConnectionsPerHost {
+			if queued {
+				pool.queued--
+				counters.queueWait += time.Since(queuedAt)
+			}
+			dial := conn == nil
+			if dial {
+				conn = &execConn{pool: pool, ready: make(chan struct{}), limit: s.config.SessionsPerConnection}
+				pool.conns = append(pool.conns, conn)
+				counters.connections++
+			} else {
+				s.reused++
+			}
+			conn.sessions++
+			if conn.idle != nil {
+				conn.idle.Stop()
+				conn.idle = nil
+			}
+			s.mu.Unlock()
+
+			if dial {
+				s.dial(ctx, conn, target.Dial)
+			}
+			select {
+			case <-conn.ready:
+			case <-ctx.Done():
+				s.release(conn)
+				return nil, ctx.Err()
+			}
+			if conn.err != nil {
+				s.release(conn)
+				// A connection dialed for a command that went away is no
+				// reason to fail the others waiting for it
+				if !dial && ctx.Err() == nil && (errors.Is(conn.err, context.Canceled) || errors.Is(conn.err, context.DeadlineExceeded)) {
+					s.mu.Lock()
+					continue
+				}
+				return nil, conn.err
+			}
+			return conn, nil
+		}
+
+		if !queued {
+			queued = true
+			pool.queued++
+		}
+		changed := pool.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+			s.mu.Lock()
+		case <-ctx.Done():
+			s.mu.Lock()
+			pool.queued--
+			counters.queueWait += time.Since(queuedAt)
+			s.prune(pool)
+			s.mu.Unlock()
+			return nil, fmt.Errorf("%w: no SSH session freed: %v", ErrHostBusy, ctx.Err())
+		}
+	}
+}
+
+// dial connects a new pool connection, failing the sessions reserved on it
+// when the connection cannot be made
+func (s *ExecScheduler) dial(ctx context.Context, conn *execConn, dial ExecDialer) {
+	client, closeClient, err := dial(ctx)
+
+	s.mu.Lock()
+	conn.client, conn.close, conn.err = client, closeClient, err
+	if err != nil {
+		conn.broken = true
+		s.detach(conn)
+	}
+	s.mu.Unlock()
+	close(conn.ready)
+}
+
+// refused handles a session the connection could not open. A server
+// refusing the channel caps its sessions below ours, so the connection
+// takes fewer from now on; any other failure means the connection is gone.
+func (s *ExecScheduler) refused(conn *execConn, err error) {
+	s.mu.Lock()
+	var openErr *ssh.OpenChannelError
+	if errors.As(err, &openErr) && (openErr.Reason == ssh.Prohibited || openErr.Reason == ssh.ResourceShortage) {
+		conn.limit = max(conn.sessions-1, 1)
+		s.counters(conn.pool.hostID).refused++
+	} else {
+		conn.broken = true
+	}
+	s.mu.Unlock()
+	s.release(conn)
+}
+
+// releaser returns the function ending a command's session
+func (s *ExecScheduler) releaser(conn *execConn, session *ssh.Session, releaseSlot func()) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			session.Close()
+			s.release(conn)
+			releaseSlot()
+		})
+	}
+}
+
+// release gives back a session reserved on conn and wakes waiting commands.
+// Idle connections close after the idle timeout, broken ones right away.
+func (s *ExecScheduler) release(conn *execConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conn.sessions--
+	pool := conn.pool
+	close(pool.changed)
+	pool.changed = make(chan struct{})
+
+	if conn.sessions > 0 || conn.client == nil {
+		return
+	}
+	if conn.broken {
+		s.closeConn(conn)
+		return
+	}
+	conn.idle = time.AfterFunc(s.config.IdleTimeout, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if conn.sessions == 0 {
+			s.closeConn(conn)
+		}
+	})
+}
+
+// closeConn closes a connection and removes it from its pool; the caller
+// holds s.mu
+func (s *ExecScheduler) closeConn(conn *execConn) {
+	if conn.idle != nil {
+		conn.idle.Stop()
+		conn.idle = nil
+	}
+	if conn.close != nil {
+		conn.close()
+		conn.close = nil
+	}
+	s.detach(conn)
+}
+
+// detach removes a connection from its pool, dropping the pool once it is
+// unused; the caller holds s.mu
+func (s *ExecScheduler) detach(conn *execConn) {
+	pool := conn.pool
+	pool.conns = slices.DeleteFunc(pool.conns, func(c *execConn) bool { return c == conn })
+	close(pool.changed)
+	pool.changed = make(chan struct{})
+	s.prune(pool)
+}
+
+// prune drops a pool without connections or waiting commands; the caller
+// holds s.mu
+func (s *ExecScheduler) prune(pool *execPool) {
+	if len(pool.conns) == 0 && pool.queued == 0 && s.pools[pool.key] == pool {
+		delete(s.pools, pool.key)
+	}
+}
+
+// pool returns the pool of a target; the caller holds s.mu
+func (s *ExecScheduler) pool(target ExecTarget) *execPool {
+	key := fmt.Sprintf("%d/%s", target.HostID, target.Key)
+	pool, ok := s.pools[key]
+	if !ok {
+		pool = &execPool{key: key, hostID: target.HostID, changed: make(chan struct{})}
+		s.pools[key] = pool
+	}
+	return pool
+}
+
+// counters returns the totals of a host; the caller holds s.mu
+func (s *ExecScheduler) counters(hostID uint) *execCounters {
+	counters, ok := s.hosts[hostID]
+	if !ok {
+		counters = &execCounters{}
+		s.hosts[hostID] = counters
+	}
+	return counters
+}
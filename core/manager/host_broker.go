@@ -0,0 +1,170 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ErrHostBusy is returned when a host has no free connection slots
+var ErrHostBusy = errors.New("host busy")
+
+// HostLimit describes the concurrency limit for a single host
+type HostLimit struct {
+	MaxConcurrent int           // 0 means unlimited
+	Queue         bool          // wait for a free slot instead of failing
+	QueueTimeout  time.Duration // 0 waits until the context is done
+}
+
+// HostLimitFor returns the concurrency limit configured on a host
+func HostLimitFor(host *models.Host) HostLimit {
+	return HostLimit{
+		MaxConcurrent: host.MaxSessions,
+		Queue:         host.QueueWhenBusy,
+		QueueTimeout:  time.Duration(host.QueueTimeout) * time.Second,
+	}
+}
+
+// HostUsage reports the slot usage of a host
+type HostUsage struct {
+	Active int `json:"active"`
+	Queued int `json:"queued"`
+}
+
+// HostBroker hands out per-host connection slots for SSH sessions, exec and terminals
+type HostBroker struct {
+	hosts map[uint]*hostSlots
+	mu    sync.Mutex
+}
+
+// hostSlots tracks active connections and FIFO waiters for one host
+type hostSlots struct {
+	active  int
+	waiters []chan struct{}
+}
+
+// NewHostBroker creates a new host connection broker
+func NewHostBroker() *HostBroker {
+	return &HostBroker{
+		hosts: make(map[uint]*hostSlots),
+	}
+}
+
+// Acquire reserves a connection slot on the host. The returned release
+// function must be called once the connection is closed.
+func (b *HostBroker) Acquire(ctx context.Context, hostID uint, limit HostLimit) (func(), error) {
+	b.mu.Lock()
+	slots := b.slots(hostID)
+
+	if limit.MaxConcurrent <= 0 || slots.active < limit.MaxConcurrent {
+		slots.active++
+		b.mu.Unlock()
+		return b.releaser(hostID), nil
+	}
+
+	if !limit.Queue {
+		active := slots.active
+		b.mu.Unlock()
+		return nil, fmt.Errorf("%w: %d of %d connections in use", ErrHostBusy, active, limit.MaxConcurrent)
+	}
+
+	ready := make(chan struct{})
+	slots.waiters = append(slots.waiters, ready)
+	b.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if limit.QueueTimeout > 0 {
+		timer := time.NewTimer(limit.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ready:
+		return b.releaser(hostID), nil
+	case <-timeout:
+		if b.abandon(hostID, ready) {
+			return nil, fmt.Errorf("%w: no connection slot freed within %s", ErrHostBusy, limit.QueueTimeout)
+		}
+	case <-ctx.Done():
+		if b.abandon(hostID, ready) {
+			return nil, fmt.Errorf("%w: %v", ErrHostBusy, ctx.Err())
+		}
+	}
+
+	// The slot was handed over while we were giving up; take it
+	return b.releaser(hostID), nil
+}
+
+// Usage returns the current slot usage for a host
+func (b *HostBroker) Usage(hostID uint) HostUsage {
+	if b == nil {
+		return HostUsage{}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	slots, exists := b.hosts[hostID]
+	if !exists {
+		return HostUsage{}
+	}
+	return HostUsage{Active: slots.active, Queued: len(slots.waiters)}
+}
+
+// slots returns the slot tracker for a host; callers must hold b.mu
+func (b *HostBroker) slots(hostID uint) *hostSlots {
+	slots, exists := b.hosts[hostID]
+	if !exists {
+		slots = &hostSlots{}
+		b.hosts[hostID] = slots
+	}
+	return slots
+}
+
+// releaser returns a release function that frees the slot exactly once
+func (b *HostBroker) releaser(hostID uint) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.release(hostID) })
+	}
+}
+
+// release hands the slot to the next waiter or frees it
+func (b *HostBroker) release(hostID uint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	slots := b.slots(hostID)
+	if len(slots.waiters) > 0 {
+		next := slots.waiters[0]
+		slots.waiters = slots.waiters[1:]
+		close(next)
+		return
+	}
+
+	slots.active--
+	if slots.active <= 0 {
+		delete(b.hosts, hostID)
+	}
+}
+
+// abandon removes a waiter from the queue. It returns false if the waiter
+// was already granted a slot.
+func (b *HostBroker) abandon(hostID uint, ready chan struct{}) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	slots := b.slots(hostID)
+	for i, waiter := range slots.waiters {
+		if waiter == ready {
+			slots.waiters = append(slots.waiters[:i], slots.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -3,16 +3,24 @@ package manager
 import (
 	"context"
 	"fmt"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/aqz236/port-fly/core/models"
 	"github.com/aqz236/port-fly/core/ssh"
+	"github.com/aqz236/port-fly/core/telemetry"
 	"github.com/aqz236/port-fly/core/utils"
 )
 
+// tracer traces session lifecycle operations
+var tracer = telemetry.Tracer("core/manager")
+
 // SessionManager manages SSH tunnel sessions
 type SessionManager struct {
 	sessions    map[string]*ManagedSession
@@ -20,6 +28,14 @@ type SessionManager struct {
 	logger      utils.Logger
 	connPool    *ssh.ConnectionPool
 	config      models.SSHConfig
+	events      *EventBus
+
+	// Bandwidth limiters keyed by bandwidth group, or by session when ungrouped
+	limiters   map[string]*ssh.BandwidthLimiter
+	limitersMu sync.Mutex
+
+	// Directory capture files are kept in; empty takes capture files as paths
+	captureDir string
 }
 
 // ManagedSession wraps a session with management functionality
@@ -27,6 +43,8 @@ type ManagedSession struct {
 	session      *models.Session
 	sshClient    *ssh.SSHClient
 	tunnelMgr    *ssh.TunnelManager
+	backoff      *ssh.Backoff
+	wireguard    *ssh.WireGuardLink // set when forwards use the wireguard transport
 	ctx          context.Context
 	cancel       context.CancelFunc
 	mu           sync.RWMutex
@@ -34,9 +52,10 @@ type ManagedSession struct {
 
 // SessionManagerInterface defines the contract for session management
 type SessionManagerInterface interface {
-	CreateSession(config models.SSHConnectionConfig, tunnelConfig models.TunnelConfig) (*models.Session, error)
-	StartSession(sessionID string) error
+	CreateSession(ctx context.Context, config models.SSHConnectionConfig, tunnelConfig models.TunnelConfig) (*models.Session, error)
+	StartSession(ctx context.Context, sessionID string) error
 	StopSession(sessionID string) error
+	UpdateSession(sessionID string, tunnelConfig models.TunnelConfig) (*models.Session, error)
 	GetSession(sessionID string) (*models.Session, error)
 	ListSessions() ([]*models.Session, error)
 	DeleteSession(sessionID string) error
@@ -57,16 +76,59 @@ func NewSessionManager(config models.SSHConfig, logger utils.Logger) *SessionMan
 		logger:   logger.WithGroup("session_manager"),
 		connPool: connPool,
 		config:   config,
+		events:   NewEventBus(),
+		limiters: make(map[string]*ssh.BandwidthLimiter),
+	}
+}
+
+// Subscribe returns a channel of session events and a function to stop receiving them
+func (sm *SessionManager) Subscribe() (<-chan models.SessionEvent, func()) {
+	return sm.events.Subscribe()
+}
+
+// EventStats returns the queue depth of the session event bus
+func (sm *SessionManager) EventStats() EventBusStats {
+	return sm.events.Stats()
+}
+
+// PoolStats returns the SSH connection pool statistics, or nil when the
+// manager was created without a pool
+func (sm *SessionManager) PoolStats() map[string]interface{} {
+	if sm.connPool == nil {
+		return nil
 	}
+	return sm.connPool.Stats()
+}
+
+// publish emits an event for the given session
+func (sm *SessionManager) publish(ms *ManagedSession, eventType models.EventType, message string) {
+	ms.mu.RLock()
+	event := models.SessionEvent{
+		Type:      eventType,
+		SessionID: ms.session.ID,
+		Status:    ms.session.Status,
+		Message:   message,
+		Error:     ms.session.LastError,
+		Timestamp: time.Now(),
+	}
+	ms.mu.RUnlock()
+
+	sm.events.Publish(event)
 }
 
-// CreateSession creates a new SSH tunnel session
-func (sm *SessionManager) CreateSession(sshConfig models.SSHConnectionConfig, tunnelConfig models.TunnelConfig) (*models.Session, error) {
+// CreateSession creates a new SSH tunnel session. The session keeps the values
+// of ctx (such as the trace) but outlives it until stopped or deleted.
+func (sm *SessionManager) CreateSession(ctx context.Context, sshConfig models.SSHConnectionConfig, tunnelConfig models.TunnelConfig) (*models.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	
 	// Generate unique session ID
 	sessionID := uuid.New().String()
 	
 	// Apply default SSH configuration
 	sm.applyDefaultSSHConfig(&sshConfig)
+	sm.applyDefaultTunnelConfig(&tunnelConfig)
 	
 	// Validate configurations
 	if err := sm.validateConfigs(sshConfig, tunnelConfig); err != nil {
@@ -100,16 +162,27 @@ func (sm *SessionManager) CreateSession(sshConfig models.SSHConnectionConfig, tu
 		tunnelConfig,
 		sm.logger.With("session_id", sessionID),
 	)
+	tunnelMgr.SetBandwidthLimiter(sm.bandwidthLimiter(sessionID, tunnelConfig))
+	if err := sm.applyCapture(tunnelMgr, tunnelConfig); err != nil {
+		return nil, err
+	}
+	var wireguard *ssh.WireGuardLink
+	if sshConfig.WireGuard != nil {
+		wireguard = ssh.NewWireGuardLink(sshConfig)
+		tunnelMgr.SetWireGuard(wireguard)
+	}
 	
 	// Create context for session lifecycle
-	ctx, cancel := context.WithCancel(context.Background())
+	sessionCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 	
 	// Create managed session
 	managedSession := &ManagedSession{
 		session:   session,
 		sshClient: sshClient,
 		tunnelMgr: tunnelMgr,
-		ctx:       ctx,
+		backoff:   ssh.NewBackoff(sshConfig.GetRetryPolicy()),
+		wireguard: wireguard,
+		ctx:       sessionCtx,
 		cancel:    cancel,
 	}
 	
@@ -126,8 +199,11 @@ func (sm *SessionManager) CreateSession(sshConfig models.SSHConnectionConfig, tu
 	return session, nil
 }
 
-// StartSession starts a session
-func (sm *SessionManager) StartSession(sessionID string) error {
+// StartSession starts a session and waits until its SSH connection and tunnel
+// listeners are up. Cancelling ctx aborts an in-flight dial, handshake or bind
+// and leaves the session stopped; once started, the session runs until it is
+// stopped regardless of ctx.
+func (sm *SessionManager) StartSession(ctx context.Context, sessionID string) error {
 	sm.mu.RLock()
 	managedSession, exists := sm.sessions[sessionID]
 	sm.mu.RUnlock()
@@ -137,40 +213,58 @@ func (sm *SessionManager) StartSession(sessionID string) error {
 	}
 	
 	managedSession.mu.Lock()
-	defer managedSession.mu.Unlock()
-	
 	if managedSession.session.Status != models.StatusCreated && 
 	   managedSession.session.Status != models.StatusStopped {
+		managedSession.mu.Unlock()
 		return fmt.Errorf("session cannot be started in current status: %s", managedSession.session.Status)
 	}
 	
 	// Update status
 	managedSession.session.Status = models.StatusConnecting
 	managedSession.session.UpdatedAt = time.Now()
+	managedSession.mu.Unlock()
 	
-	// Start the session in a goroutine
-	go sm.runSession(managedSession)
+	// Run the session in a goroutine and wait for the startup phase
+	started := make(chan error, 1)
+	go sm.runSession(ctx, managedSession, started)
 	
 	sm.logger.Info("session start initiated", "session_id", sessionID)
-	return nil
+	return <-started
 }
 
-// runSession runs the session lifecycle
-func (sm *SessionManager) runSession(ms *ManagedSession) {
+// runSession runs the session lifecycle. The startup phase is bounded by both
+// ctx and the session context; its outcome is sent on started.
+func (sm *SessionManager) runSession(ctx context.Context, ms *ManagedSession, started chan<- error) {
 	sessionID := ms.session.ID
 	logger := sm.logger.With("session_id", sessionID)
 	
 	defer func() {
 		if r := recover(); r != nil {
 			logger.Error("session panic recovered", "panic", r)
+			utils.ReportPanic("session "+sessionID, r)
 			ms.mu.Lock()
 			ms.session.Status = models.StatusError
 			ms.session.LastError = fmt.Sprintf("panic: %v", r)
 			ms.session.UpdatedAt = time.Now()
 			ms.mu.Unlock()
+			select {
+			case started <- fmt.Errorf("session panic: %v", r):
+			default:
+			}
 		}
 	}()
 	
+	// Stopping or deleting the session also aborts the startup
+	ctx, cancelStart := context.WithCancel(ctx)
+	defer cancelStart()
+	stopWatch := context.AfterFunc(ms.ctx, cancelStart)
+	defer stopWatch()
+	
+	// Trace the startup phase; monitoring runs outside the span
+	ctx, span := telemetry.StartSpan(ctx, tracer, "session.start",
+		attribute.String("session.id", sessionID),
+		attribute.String("ssh.host", ms.session.SSHConfig.Host))
+	
 	// Establish SSH connection
 	logger.Info("establishing SSH connection")
 	ms.mu.Lock()
@@ -178,15 +272,22 @@ func (sm *SessionManager) runSession(ms *ManagedSession) {
 	ms.session.UpdatedAt = time.Now()
 	ms.mu.Unlock()
 	
-	if err := ms.sshClient.Connect(ms.ctx); err != nil {
-		logger.Error("failed to establish SSH connection", "error", err)
-		ms.mu.Lock()
-		ms.session.Status = models.StatusError
-		ms.session.LastError = err.Error()
-		ms.session.UpdatedAt = time.Now()
-		ms.mu.Unlock()
+	if ms.wireguard != nil {
+		// Forwards go over the WireGuard interface; no SSH connection is opened
+		if err := ms.wireguard.Check(); err != nil {
+			telemetry.EndSpan(span, err)
+			sm.failStart(ctx, ms, err, "WireGuard interface is not up")
+			started <- err
+			return
+		}
+	} else if err := ms.sshClient.Connect(ctx); err != nil {
+		telemetry.EndSpan(span, err)
+		sm.failStart(ctx, ms, err, "failed to establish SSH connection")
+		started <- err
 		return
 	}
+	ms.backoff.Succeeded()
+	sm.recordEndpoint(ms)
 	
 	// Update status to connected
 	now := time.Now()
@@ -200,39 +301,74 @@ func (sm *SessionManager) runSession(ms *ManagedSession) {
 	
 	// Start tunnel
 	logger.Info("starting tunnel")
-	if err := ms.tunnelMgr.Start(ms.ctx); err != nil {
-		logger.Error("failed to start tunnel", "error", err)
-		ms.mu.Lock()
-		ms.session.Status = models.StatusError
-		ms.session.LastError = err.Error()
-		ms.session.UpdatedAt = time.Now()
-		ms.mu.Unlock()
+	if err := ms.tunnelMgr.Start(ctx); err != nil {
+		telemetry.EndSpan(span, err)
 		
 		// Disconnect SSH client
 		ms.sshClient.Disconnect()
+		
+		sm.failStart(ctx, ms, err, "failed to start tunnel")
+		started <- err
 		return
 	}
 	
+	ssh.ReportProgress(ctx, ssh.PhaseListenerBound)
+	
 	// Update status to active
 	ms.mu.Lock()
 	ms.session.Status = models.StatusActive
 	ms.session.UpdatedAt = time.Now()
 	ms.mu.Unlock()
 	
+	telemetry.EndSpan(span, nil)
 	logger.Info("session is now active")
+	sm.publish(ms, models.EventSessionStatus, "session is now active")
+	started <- nil
+	
+	// Release the startup context; monitoring runs on the session context
+	stopWatch()
+	cancelStart()
 	
 	// Monitor session
 	sm.monitorSession(ms)
 }
 
+// failStart records a failed startup. A startup aborted through its context
+// leaves the session stopped so it can be started again; any other failure
+// is an error.
+func (sm *SessionManager) failStart(ctx context.Context, ms *ManagedSession, err error, message string) {
+	logger := sm.logger.With("session_id", ms.session.ID)
+	
+	status := models.StatusError
+	if ctx.Err() != nil {
+		status = models.StatusStopped
+		message = "session start aborted"
+		logger.Info(message, "error", err)
+	} else {
+		logger.Error(message, "error", err)
+	}
+	
+	ms.mu.Lock()
+	ms.session.Status = status
+	ms.session.LastError = err.Error()
+	ms.session.UpdatedAt = time.Now()
+	ms.mu.Unlock()
+	sm.publish(ms, models.EventSessionStatus, message)
+}
+
 // monitorSession monitors a running session
 func (sm *SessionManager) monitorSession(ms *ManagedSession) {
 	sessionID := ms.session.ID
 	logger := sm.logger.With("session_id", sessionID)
 	
-	ticker := time.NewTicker(30 * time.Second) // Update stats every 30 seconds
+	ticker := time.NewTicker(30 * time.Second) // Check the SSH connection every 30 seconds
 	defer ticker.Stop()
 	
+	statsTicker := time.NewTicker(sm.config.StatsPushInterval())
+	defer statsTicker.Stop()
+	pushed := ms.tunnelMgr.GetStats()
+	pushedAt := time.Now()
+	
 	for {
 		select {
 		case <-ms.ctx.Done():
@@ -244,44 +380,167 @@ func (sm *SessionManager) monitorSession(ms *ManagedSession) {
 			sm.stopSession(ms)
 			return
 			
-		case <-ticker.C:
-			// Update session statistics
+		case now := <-statsTicker.C:
+			// Ticks missed while busy are coalesced into one larger delta
 			sm.updateSessionStats(ms)
+			pushed = sm.publishStats(ms, pushed, now.Sub(pushedAt))
+			pushedAt = now
 			
+		case <-ticker.C:
 			// Check SSH connection health
-			if !ms.sshClient.IsConnected() {
-				logger.Warn("SSH connection lost, attempting reconnection")
-				ms.mu.Lock()
-				ms.session.Status = models.StatusConnecting
-				ms.session.UpdatedAt = time.Now()
-				reconnectCount := ms.session.Stats.ReconnectCount + 1
-				ms.session.Stats.ReconnectCount = reconnectCount
-				now := time.Now()
-				ms.session.Stats.LastReconnectAt = &now
-				ms.mu.Unlock()
-				
-				// Attempt reconnection
-				if err := ms.sshClient.Reconnect(ms.ctx); err != nil {
-					logger.Error("reconnection failed", "error", err)
-					ms.mu.Lock()
-					ms.session.Status = models.StatusError
-					ms.session.LastError = err.Error()
-					ms.session.UpdatedAt = time.Now()
-					ms.mu.Unlock()
-					sm.stopSession(ms)
+			if ms.wireguard != nil {
+				sm.checkWireGuard(ms)
+			} else if !ms.sshClient.IsConnected() {
+				if !sm.reconnectSession(ms) {
 					return
 				}
-				
-				logger.Info("SSH reconnection successful")
-				ms.mu.Lock()
-				ms.session.Status = models.StatusActive
-				ms.session.UpdatedAt = time.Now()
-				ms.mu.Unlock()
 			}
 		}
 	}
 }
 
+// reconnectSession re-establishes the SSH connection following the session's
+// retry policy. It returns false if the policy gave up and the session was stopped.
+func (sm *SessionManager) reconnectSession(ms *ManagedSession) bool {
+	logger := sm.logger.With("session_id", ms.session.ID)
+	logger.Warn("SSH connection lost, attempting reconnection")
+
+	ms.mu.Lock()
+	ms.session.Status = models.StatusConnecting
+	ms.session.UpdatedAt = time.Now()
+	ms.session.Stats.ReconnectCount++
+	now := time.Now()
+	ms.session.Stats.LastReconnectAt = &now
+	ms.mu.Unlock()
+
+	ctx, span := telemetry.StartSpan(ms.ctx, tracer, "session.reconnect",
+		attribute.String("session.id", ms.session.ID))
+
+	err := ms.sshClient.ReconnectWithBackoff(ctx, ms.backoff, func(attempt int, delay time.Duration, err error) {
+		message := models.FormatBackoffMessage(attempt, ms.backoff.Policy().MaxAttempts, delay)
+		nextRetry := time.Now().Add(delay)
+		span.AddEvent("backoff", trace.WithAttributes(
+			attribute.Int("reconnect.attempt", attempt),
+			attribute.String("reconnect.delay", delay.String())))
+
+		ms.mu.Lock()
+		ms.session.Status = models.StatusBackingOff
+		ms.session.StatusMessage = message
+		ms.session.LastError = err.Error()
+		ms.session.NextRetryAt = &nextRetry
+		ms.session.UpdatedAt = time.Now()
+		ms.mu.Unlock()
+
+		logger.Info(message)
+		sm.events.Publish(models.SessionEvent{
+			Type:      models.EventSessionBackoff,
+			SessionID: ms.session.ID,
+			Status:    models.StatusBackingOff,
+			Message:   message,
+			Error:     err.Error(),
+			Attempt:   attempt,
+			RetryIn:   delay,
+			Timestamp: time.Now(),
+		})
+	})
+
+	span.SetAttributes(attribute.Int("reconnect.attempts", ms.backoff.Attempt()))
+	telemetry.EndSpan(span, err)
+
+	if err != nil {
+		if ms.ctx.Err() != nil {
+			return false
+		}
+
+		action := ms.backoff.Policy().GiveUpAction
+		logger.Error("reconnection failed, giving up", "error", err, "action", action)
+
+		sm.stopSession(ms)
+
+		ms.mu.Lock()
+		if action == models.GiveUpActionError {
+			ms.session.Status = models.StatusError
+		}
+		message := fmt.Sprintf("gave up after %d attempts", ms.backoff.Attempt())
+		ms.session.LastError = err.Error()
+		ms.session.StatusMessage = message
+		ms.session.NextRetryAt = nil
+		ms.session.UpdatedAt = time.Now()
+		ms.mu.Unlock()
+
+		sm.publish(ms, models.EventSessionGaveUp, message)
+		return false
+	}
+
+	message := "SSH reconnection successful"
+	if active, changed := sm.recordEndpoint(ms); changed {
+		message = "failed over to " + active
+	}
+	logger.Info(message)
+	ms.mu.Lock()
+	ms.session.Status = models.StatusActive
+	ms.session.StatusMessage = ""
+	ms.session.NextRetryAt = nil
+	ms.session.UpdatedAt = time.Now()
+	ms.mu.Unlock()
+
+	sm.publish(ms, models.EventSessionReconnected, message)
+	return true
+}
+
+// checkWireGuard follows the WireGuard interface of a session. The listeners
+// stay up while it is down, but connections fail, so the session is shown
+// connecting until the interface is back.
+func (sm *SessionManager) checkWireGuard(ms *ManagedSession) {
+	err := ms.wireguard.Check()
+
+	ms.mu.Lock()
+	down := ms.session.Status == models.StatusConnecting
+	var message string
+	switch {
+	case err != nil && !down:
+		message = "waiting for WireGuard interface " + ms.wireguard.Interface()
+		ms.session.Status = models.StatusConnecting
+		ms.session.StatusMessage = message
+		ms.session.LastError = err.Error()
+	case err == nil && down:
+		message = "WireGuard interface " + ms.wireguard.Interface() + " is up again"
+		ms.session.Status = models.StatusActive
+		ms.session.StatusMessage = ""
+	default:
+		ms.mu.Unlock()
+		return
+	}
+	ms.session.UpdatedAt = time.Now()
+	ms.mu.Unlock()
+
+	logger := sm.logger.With("session_id", ms.session.ID)
+	if err != nil {
+		logger.Warn(message, "error", err)
+		sm.publish(ms, models.EventSessionStatus, message)
+		return
+	}
+	logger.Info(message)
+	sm.publish(ms, models.EventSessionReconnected, message)
+}
+
+// recordEndpoint stores the server the session is connected to and the
+// latest probe of its candidates. It returns the server and whether the
+// session moved to it from another one.
+func (sm *SessionManager) recordEndpoint(ms *ManagedSession) (string, bool) {
+	endpoint, ok := ms.sshClient.ActiveEndpoint()
+	if !ok {
+		return "", false
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	previous := ms.session.ActiveHost
+	ms.session.ActiveHost = endpoint.String()
+	ms.session.Candidates = ms.sshClient.Candidates()
+	return ms.session.ActiveHost, previous != "" && previous != ms.session.ActiveHost
+}
+
 // stopSession stops a managed session
 func (sm *SessionManager) stopSession(ms *ManagedSession) {
 	logger := sm.logger.With("session_id", ms.session.ID)
@@ -294,7 +553,7 @@ func (sm *SessionManager) stopSession(ms *ManagedSession) {
 	// Stop tunnel
 	if ms.tunnelMgr.IsRunning() {
 		logger.Info("stopping tunnel")
-		if err := ms.tunnelMgr.Stop(); err != nil {
+		if err := ms.tunnelMgr.Stop(ms.ctx); err != nil {
 			logger.Error("error stopping tunnel", "error", err)
 		}
 	}
@@ -314,6 +573,7 @@ func (sm *SessionManager) stopSession(ms *ManagedSession) {
 	ms.mu.Unlock()
 	
 	logger.Info("session stopped")
+	sm.publish(ms, models.EventSessionStatus, "session stopped")
 }
 
 // StopSession stops a session
@@ -346,6 +606,52 @@ func (sm *SessionManager) StopSession(sessionID string) error {
 	return nil
 }
 
+// UpdateSession applies a new tunnel configuration to a session. Running
+// tunnels are updated in place; the listener is only replaced when the bind
+// address or port changes, and existing connections are left to drain.
+func (sm *SessionManager) UpdateSession(sessionID string, tunnelConfig models.TunnelConfig) (*models.Session, error) {
+	sm.mu.RLock()
+	managedSession, exists := sm.sessions[sessionID]
+	sm.mu.RUnlock()
+	
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	
+	sm.applyDefaultTunnelConfig(&tunnelConfig)
+	if err := tunnelConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid tunnel config: %w", err)
+	}
+	
+	managedSession.mu.Lock()
+	if err := managedSession.session.SSHConfig.ValidateTransport(tunnelConfig); err != nil {
+		managedSession.mu.Unlock()
+		return nil, fmt.Errorf("invalid transport: %w", err)
+	}
+	previous := managedSession.session.TunnelConfig
+	if err := managedSession.tunnelMgr.UpdateConfig(managedSession.ctx, tunnelConfig); err != nil {
+		managedSession.mu.Unlock()
+		return nil, fmt.Errorf("failed to update tunnel: %w", err)
+	}
+	managedSession.tunnelMgr.SetBandwidthLimiter(sm.bandwidthLimiter(sessionID, tunnelConfig))
+	if err := sm.applyCapture(managedSession.tunnelMgr, tunnelConfig); err != nil {
+		// The capture is a debugging aid; keep the new configuration without it
+		sm.logger.Warn("failed to update capture", "session_id", sessionID, "error", err)
+	}
+	managedSession.session.TunnelConfig = tunnelConfig
+	managedSession.session.Description = tunnelConfig.GetTunnelDescription()
+	managedSession.session.UpdatedAt = time.Now()
+	sessionCopy := *managedSession.session
+	managedSession.mu.Unlock()
+	
+	sm.logger.Info("session updated",
+		"session_id", sessionID,
+		"listener_changed", previous.ListenerChanged(tunnelConfig))
+	sm.publish(managedSession, models.EventSessionUpdated, "tunnel configuration updated")
+	
+	return &sessionCopy, nil
+}
+
 // GetSession retrieves a session by ID
 func (sm *SessionManager) GetSession(sessionID string) (*models.Session, error) {
 	sm.mu.RLock()
@@ -405,6 +711,11 @@ func (sm *SessionManager) DeleteSession(sessionID string) error {
 	
 	// Cancel context
 	managedSession.cancel()
+	sm.applyCapture(managedSession.tunnelMgr, models.TunnelConfig{})
+	
+	sm.limitersMu.Lock()
+	delete(sm.limiters, sessionLimiterKey(sessionID))
+	sm.limitersMu.Unlock()
 	
 	sm.logger.Info("session deleted", "session_id", sessionID)
 	return nil
@@ -429,6 +740,132 @@ func (sm *SessionManager) GetSessionStats(sessionID string) (models.SessionStats
 	return managedSession.session.Stats, nil
 }
 
+// SubscribeCapture returns the live debug capture of a session's forwarded
+// bytes and a function to stop receiving them
+func (sm *SessionManager) SubscribeCapture(sessionID string) (<-chan models.CaptureChunk, func(), error) {
+	sm.mu.RLock()
+	managedSession, exists := sm.sessions[sessionID]
+	sm.mu.RUnlock()
+	
+	if !exists {
+		return nil, nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	
+	capture := managedSession.tunnelMgr.Capture()
+	if capture == nil {
+		return nil, nil, fmt.Errorf("capture is not enabled for session %s", sessionID)
+	}
+	chunks, unsubscribe := capture.Subscribe()
+	return chunks, unsubscribe, nil
+}
+
+// ListHTTPRequests returns summaries of the recent requests seen by a
+// session that inspects HTTP, newest first
+func (sm *SessionManager) ListHTTPRequests(sessionID string) ([]models.HTTPRecord, error) {
+	managedSession, err := sm.inspectingSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return managedSession.tunnelMgr.HTTPLog().List(), nil
+}
+
+// GetHTTPRequest returns a recent request of a session with its headers and bodies
+func (sm *SessionManager) GetHTTPRequest(sessionID string, id uint64) (models.HTTPRecord, error) {
+	managedSession, err := sm.inspectingSession(sessionID)
+	if err != nil {
+		return models.HTTPRecord{}, err
+	}
+	record, ok := managedSession.tunnelMgr.HTTPLog().Get(id)
+	if !ok {
+		return models.HTTPRecord{}, fmt.Errorf("%w: %d", models.ErrHTTPRecordNotFound, id)
+	}
+	return record, nil
+}
+
+// ReplayHTTPRequest sends a recent request of a session through its tunnel again
+func (sm *SessionManager) ReplayHTTPRequest(ctx context.Context, sessionID string, id uint64) (models.HTTPRecord, error) {
+	managedSession, err := sm.inspectingSession(sessionID)
+	if err != nil {
+		return models.HTTPRecord{}, err
+	}
+	return managedSession.tunnelMgr.ReplayHTTP(ctx, id)
+}
+
+// Benchmark measures a running session's tunnel and, where it can be
+// reached, the same test without the tunnel
+func (sm *SessionManager) Benchmark(ctx context.Context, sessionID string, options models.BenchmarkOptions) (*models.BenchmarkResult, error) {
+	if err := options.Normalize(); err != nil {
+		return nil, err
+	}
+
+	sm.mu.RLock()
+	managedSession, exists := sm.sessions[sessionID]
+	sm.mu.RUnlock()
+	
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	if !managedSession.tunnelMgr.IsRunning() {
+		return nil, fmt.Errorf("session %s is not running", sessionID)
+	}
+	
+	result := &models.BenchmarkResult{Mode: options.Mode, StartedAt: time.Now()}
+	client := managedSession.sshClient
+	config := managedSession.tunnelMgr.Config()
+	if options.Mode == models.BenchmarkEcho {
+		result.Tunnel = client.BenchmarkEcho(ctx, options)
+		if result.Tunnel.Address != "" {
+			// Connecting to the SSH server is the round trip without SSH on top
+			direct := ssh.BenchmarkConnections(ctx, result.Tunnel.Address, ssh.DirectDialer(result.Tunnel.Address),
+				models.BenchmarkOptions{Samples: options.Samples, Timeout: options.Timeout})
+			result.Direct = &direct
+		}
+	} else {
+		var tunnel ssh.BenchmarkDialer
+		var address string
+		switch config.Type {
+		case models.TunnelTypeLocal:
+			address = benchmarkListenAddress(config.LocalBindAddress, config.LocalPort)
+			tunnel = ssh.DirectDialer(address)
+		case models.TunnelTypeRemote:
+			address = benchmarkListenAddress(config.RemoteBindAddress, config.LocalPort)
+			tunnel = client.ForwardDialer(address)
+		default:
+			return nil, fmt.Errorf("%w: %s tunnels have no single target", models.ErrInvalidBenchmark, config.Type)
+		}
+		result.Tunnel = ssh.BenchmarkConnections(ctx, address, tunnel, options)
+		target := config.TargetAddress()
+		direct := ssh.BenchmarkConnections(ctx, target, ssh.DirectDialer(target), options)
+		result.Direct = &direct
+	}
+	result.Duration = time.Since(result.StartedAt).Milliseconds()
+	return result, nil
+}
+
+// benchmarkListenAddress is where a listener bound to address is connected
+// to, the loopback address for the wildcard ones
+func benchmarkListenAddress(address string, port int) string {
+	if ip := net.ParseIP(address); address == "" || ip != nil && ip.IsUnspecified() {
+		address = "127.0.0.1"
+	}
+	return net.JoinHostPort(address, strconv.Itoa(port))
+}
+
+// inspectingSession looks up a session whose tunnel inspects HTTP
+func (sm *SessionManager) inspectingSession(sessionID string) (*ManagedSession, error) {
+	sm.mu.RLock()
+	managedSession, exists := sm.sessions[sessionID]
+	sm.mu.RUnlock()
+	
+	if !exists {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	if !managedSession.tunnelMgr.Config().InspectHTTP {
+		return nil, fmt.Errorf("HTTP inspection is not enabled for session %s", sessionID)
+	}
+	return managedSession, nil
+}
+
 // updateSessionStats updates session statistics
 func (sm *SessionManager) updateSessionStats(ms *ManagedSession) {
 	if ms.tunnelMgr.IsRunning() {
@@ -441,11 +878,40 @@ func (sm *SessionManager) updateSessionStats(ms *ManagedSession) {
 	}
 }
 
+// publishStats pushes the statistics of a running session gathered since
+// previous and returns the statistics to compare the next push against
+func (sm *SessionManager) publishStats(ms *ManagedSession, previous models.SessionStats, interval time.Duration) models.SessionStats {
+	if !ms.tunnelMgr.IsRunning() {
+		return previous
+	}
+	current := ms.tunnelMgr.GetStats()
+	update := models.NewStatsUpdate(previous, current, interval)
+	if update == nil {
+		return current
+	}
+
+	ms.mu.RLock()
+	event := models.SessionEvent{
+		Type:      models.EventSessionStats,
+		SessionID: ms.session.ID,
+		Status:    ms.session.Status,
+		Stats:     update,
+		Timestamp: time.Now(),
+	}
+	ms.mu.RUnlock()
+
+	sm.events.Publish(event)
+	return current
+}
+
 // applyDefaultSSHConfig applies default SSH configuration
 func (sm *SessionManager) applyDefaultSSHConfig(config *models.SSHConnectionConfig) {
 	if config.ConnectTimeout == 0 {
 		config.ConnectTimeout = sm.config.ConnectTimeout
 	}
+	if config.HandshakeTimeout == 0 {
+		config.HandshakeTimeout = sm.config.HandshakeTimeout
+	}
 	if config.KeepAliveTimeout == 0 {
 		config.KeepAliveTimeout = sm.config.KeepAliveTimeout
 	}
@@ -458,14 +924,98 @@ func (sm *SessionManager) applyDefaultSSHConfig(config *models.SSHConnectionConf
 	if config.HostKeyCallback == "" {
 		config.HostKeyCallback = sm.config.HostKeyCallback
 	}
+	if config.ProxyURL == "" && config.ProxyCommand == "" {
+		config.ProxyURL = sm.config.Proxy
+	}
+}
+
+// applyDefaultTunnelConfig applies default transfer settings to tunnel configuration
+func (sm *SessionManager) applyDefaultTunnelConfig(config *models.TunnelConfig) {
+	if config.BufferSize == 0 {
+		config.BufferSize = sm.config.TransferBufferSize
+	}
+	if config.BindTimeout == 0 {
+		config.BindTimeout = sm.config.BindTimeout
+	}
+	if sm.config.DisableZeroCopy {
+		config.DisableZeroCopy = true
+	}
+}
+
+// bandwidthLimiter returns the limiter for a tunnel configuration, or nil
+// without a limit. Tunnels in the same bandwidth group share one limiter;
+// the most recently applied limit wins for the whole group.
+func (sm *SessionManager) bandwidthLimiter(sessionID string, config models.TunnelConfig) *ssh.BandwidthLimiter {
+	if config.BandwidthLimit <= 0 {
+		return nil
+	}
+	key := config.BandwidthGroup
+	if key == "" {
+		key = sessionLimiterKey(sessionID)
+	}
+
+	sm.limitersMu.Lock()
+	defer sm.limitersMu.Unlock()
+	limiter, exists := sm.limiters[key]
+	if !exists {
+		limiter = ssh.NewBandwidthLimiter(config.BandwidthLimit)
+		sm.limiters[key] = limiter
+	} else if limiter.Rate() != config.BandwidthLimit {
+		limiter.SetRate(config.BandwidthLimit)
+	}
+	return limiter
+}
+
+// SetCaptureDir confines capture files to dir: tunnel configurations then
+// name a file in it rather than a path. Servers set it since capture
+// settings come from API clients.
+func (sm *SessionManager) SetCaptureDir(dir string) {
+	sm.captureDir = dir
+}
+
+// applyCapture starts, replaces or stops the debug capture of a tunnel to
+// match config. Connections in flight keep recording into the previous
+// capture until they close, but its file and viewers are closed.
+func (sm *SessionManager) applyCapture(tunnelMgr *ssh.TunnelManager, config models.TunnelConfig) error {
+	current := tunnelMgr.Capture()
+	if config.Capture == nil || !config.Capture.Enabled {
+		if current != nil {
+			tunnelMgr.SetCapture(nil)
+			current.Close()
+		}
+		return nil
+	}
+	if current != nil && current.Config().Equal(config.Capture.WithDefaults()) {
+		return nil
+	}
+
+	capture, err := ssh.NewCapture(*config.Capture, sm.captureDir)
+	if err != nil {
+		return fmt.Errorf("failed to start capture: %w", err)
+	}
+	tunnelMgr.SetCapture(capture)
+	if current != nil {
+		current.Close()
+	}
+	return nil
+}
+
+// sessionLimiterKey keys the limiter of a tunnel outside any bandwidth group
+func sessionLimiterKey(sessionID string) string {
+	return "session:" + sessionID
 }
 
 // validateConfigs validates SSH and tunnel configurations
 func (sm *SessionManager) validateConfigs(sshConfig models.SSHConnectionConfig, tunnelConfig models.TunnelConfig) error {
-	// Validate SSH configuration
-	authManager := ssh.NewAuthManager()
-	if err := authManager.ValidateConfig(sshConfig); err != nil {
-		return fmt.Errorf("invalid SSH configuration: %w", err)
+	// Validate SSH configuration; forwards over WireGuard never authenticate
+	if sshConfig.WireGuard == nil {
+		authManager := ssh.NewAuthManager()
+		if err := authManager.ValidateConfig(sshConfig); err != nil {
+			return fmt.Errorf("invalid SSH configuration: %w", err)
+		}
+	}
+	if err := sshConfig.ValidateTransport(tunnelConfig); err != nil {
+		return fmt.Errorf("invalid transport: %w", err)
 	}
 	
 	// Validate tunnel configuration
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// AnsibleInventoryOptions Ansible 清单导出选项
+type AnsibleInventoryOptions struct {
+	AliasPrefix string // 主机名前缀，与 OpenSSH 配置导出一致
+	IdentityDir string // 私钥认证主机的 ansible_ssh_private_key_file 目录，密钥本身不会导出
+}
+
+// AnsibleGroup Ansible 清单中的组，对应 PortFly 项目或分组
+type AnsibleGroup struct {
+	Hosts    []string       `json:"hosts,omitempty"`
+	Children []string       `json:"children,omitempty"`
+	Vars     map[string]any `json:"vars,omitempty"`
+}
+
+// AnsibleInventory Ansible 动态清单（inventory 脚本 --list 的输出）
+// 项目与分组按层级成为嵌套的组，主机的连接参数在 _meta.hostvars 中
+type AnsibleInventory struct {
+	Groups   map[string]*AnsibleGroup
+	HostVars map[string]map[string]any
+	Skipped  []string // 字段模板无法解析而跳过的主机及原因
+}
+
+// ansibleNameUnsafe 匹配 Ansible 组名中不允许的字符
+var ansibleNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// MarshalJSON 输出 Ansible 要求的格式：组名为顶层键，另有 _meta.hostvars
+func (inv *AnsibleInventory) MarshalJSON() ([]byte, error) {
+	out := make(map[string]any, len(inv.Groups)+1)
+	for name, group := range inv.Groups {
+		out[name] = group
+	}
+	out["_meta"] = map[string]any{"hostvars": inv.HostVars}
+	return json.Marshal(out)
+}
+
+// BuildAnsibleInventory 由项目、分组和主机生成 Ansible 清单
+// 父项目或父分组不在列表中的项目和分组成为 all 的直接子组；组名取自名称，
+// 重名时追加 ID。主机名与 OpenSSH 配置导出的别名相同，字段模板按当前环境解析
+func BuildAnsibleInventory(projects []Project, groups []Group, hosts []Host, opts AnsibleInventoryOptions) *AnsibleInventory {
+	if opts.IdentityDir == "" {
+		opts.IdentityDir = DefaultIdentityDir
+	}
+	inv := &AnsibleInventory{
+		Groups:   make(map[string]*AnsibleGroup),
+		HostVars: make(map[string]map[string]any),
+	}
+	inv.Groups["all"] = &AnsibleGroup{}
+	taken := map[string]bool{"all": true, "ungrouped": true, "_meta": true}
+	name := func(base string, id uint) string {
+		n := strings.Trim(ansibleNameUnsafe.ReplaceAllString(base, "_"), "_")
+		if n == "" || (n[0] >= '0' && n[0] <= '9') {
+			n = "group_" + n
+		}
+		if taken[n] {
+			n = fmt.Sprintf("%s_%d", n, id)
+		}
+		taken[n] = true
+		return n
+	}
+
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+
+	projectNames := make(map[uint]string, len(projects))
+	for _, project := range projects {
+		projectNames[project.ID] = name(project.Name, project.ID)
+		inv.Groups[projectNames[project.ID]] = &AnsibleGroup{Vars: map[string]any{"portfly_project_id": project.ID}}
+	}
+	groupNames := make(map[uint]string, len(groups))
+	for _, group := range groups {
+		groupNames[group.ID] = name(group.Name, group.ID)
+		inv.Groups[groupNames[group.ID]] = &AnsibleGroup{Vars: map[string]any{"portfly_group_id": group.ID}}
+	}
+
+	for _, project := range projects {
+		parent, ok := parentName(project.ParentID, projectNames)
+		if !ok {
+			parent = "all"
+		}
+		inv.Groups[parent].Children = append(inv.Groups[parent].Children, projectNames[project.ID])
+	}
+	for _, group := range groups {
+		parent, ok := parentName(group.ParentID, groupNames)
+		if !ok {
+			if parent, ok = projectNames[group.ProjectID]; !ok {
+				parent = "all"
+			}
+		}
+		inv.Groups[parent].Children = append(inv.Groups[parent].Children, groupNames[group.ID])
+	}
+
+	seen := make(map[string]bool)
+	for i := range hosts {
+		host, err := hosts[i].Resolve()
+		if err != nil {
+			inv.Skipped = append(inv.Skipped, fmt.Sprintf("%s: %v", hosts[i].Name, err))
+			continue
+		}
+		alias := host.SSHConfigAlias(opts.AliasPrefix)
+		if seen[alias] {
+			alias = fmt.Sprintf("%s-%d", alias, host.ID)
+		}
+		seen[alias] = true
+
+		inv.HostVars[alias] = host.ansibleHostVars(alias, opts)
+		if group, ok := groupNames[host.GroupID]; ok {
+			inv.Groups[group].Hosts = append(inv.Groups[group].Hosts, alias)
+		} else {
+			inv.Groups["all"].Hosts = append(inv.Groups["all"].Hosts, alias)
+		}
+	}
+	return inv
+}
+
+// parentName 父项目或父分组在清单中的组名，父级不在清单中时返回 false
+func parentName(parentID *uint, names map[uint]string) (string, bool) {
+	if parentID == nil {
+		return "", false
+	}
+	n, ok := names[*parentID]
+	return n, ok
+}
+
+// ansibleHostVars 主机的 Ansible 连接变量；密码和私钥不会导出
+func (h *Host) ansibleHostVars(alias string, opts AnsibleInventoryOptions) map[string]any {
+	vars := map[string]any{
+		"ansible_host":    h.Hostname,
+		"portfly_host_id": h.ID,
+	}
+	if h.Port != 0 {
+		vars["ansible_port"] = h.Port
+	}
+	if h.Username != "" {
+		vars["ansible_user"] = h.Username
+	}
+	if len(h.Tags) > 0 {
+		vars["portfly_tags"] = h.Tags
+	}
+
+	switch AuthMethod(h.AuthMethod) {
+	case AuthMethodPrivateKey, "key":
+		vars["ansible_ssh_private_key_file"] = path.Join(opts.IdentityDir, alias)
+	}
+
+	// Ansible 按 shell 规则拆分 ansible_ssh_common_args
+	if directive := h.sshProxyDirective(); directive != "" {
+		option, value, _ := strings.Cut(directive, " ")
+		vars["ansible_ssh_common_args"] = "-o '" + option + "=" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+	}
+	return vars
+}
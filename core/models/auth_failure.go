@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// 认证失败相关事件
+const (
+	EventAuthFailures  EventType = "host.auth_failures"  // 主机连续认证失败达到阈值
+	EventAuthRecovered EventType = "host.auth_recovered" // 达到阈值的主机重新认证成功
+)
+
+// 发起 SSH 连接的功能，记录在认证结果中
+const (
+	AuthSourceConnect      = "connect"      // 连接测试 POST /hosts/:id/connect
+	AuthSourceTest         = "test"         // 连接测试 POST /hosts/:id/test
+	AuthSourceCommand      = "command"      // 单主机命令 POST /hosts/:id/execute
+	AuthSourceValidate     = "validate"     // 主机配置校验
+	AuthSourceTerminal     = "terminal"     // 网页终端
+	AuthSourceExec         = "exec"         // 分组批量命令
+	AuthSourcePush         = "push"         // 分组文件推送
+	AuthSourceReachability = "reachability" // 可达性探测
+	AuthSourcePort         = "port"         // 端口隧道
+)
+
+// AuthAttempt 一次 SSH 认证的结果
+type AuthAttempt struct {
+	HostID  uint
+	Success bool
+	Error   string
+	Source  string // 发起连接的功能，见 AuthSource*
+	Actor   string // 发起连接的用户或客户端地址
+	At      time.Time
+}
+
+// HostAuthStatus 主机的 SSH 认证失败记录，每个主机一条。连续失败次数在认证成功后清零，
+// 用于及早发现过期的密钥或被修改的密码
+type HostAuthStatus struct {
+	HostID    uint      `gorm:"primarykey;autoIncrement:false" json:"host_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ConsecutiveFailures int        `gorm:"not null;default:0;index" json:"consecutive_failures"` // 自上次成功以来的连续失败次数
+	TotalFailures       int        `gorm:"not null;default:0" json:"total_failures"`
+	LastError           string     `gorm:"size:500" json:"last_error,omitempty"` // 最近一次失败的错误
+	LastSource          string     `gorm:"size:50" json:"last_source,omitempty"` // 最近一次尝试的来源功能
+	LastActor           string     `gorm:"size:255" json:"last_actor,omitempty"` // 最近一次尝试的用户或客户端地址
+	LastAttemptAt       *time.Time `json:"last_attempt_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+
+	// 列表查询时附带的主机信息
+	HostName string `gorm:"->;-:migration" json:"host_name,omitempty"`
+	GroupID  uint   `gorm:"->;-:migration" json:"group_id,omitempty"`
+}
+
+// Apply 记录一次认证结果
+func (s *HostAuthStatus) Apply(attempt AuthAttempt) {
+	at := attempt.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	s.LastAttemptAt = &at
+	s.LastSource = attempt.Source
+	s.LastActor = attempt.Actor
+	if attempt.Success {
+		s.ConsecutiveFailures = 0
+		s.LastSuccessAt = &at
+		return
+	}
+	s.ConsecutiveFailures++
+	s.TotalFailures++
+	s.LastFailureAt = &at
+	s.LastError = attempt.Error
+	if len(s.LastError) > 500 {
+		s.LastError = s.LastError[:500]
+	}
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Policies for spreading new connections over a tunnel's targets
+const (
+	LoadBalanceRoundRobin       = "round_robin"       // each new connection goes to the next target in turn
+	LoadBalanceLeastConnections = "least_connections" // each new connection goes to the target with the fewest open connections
+)
+
+// TargetSpec is an additional host:port a local or remote forward sends
+// connections to, besides RemoteHost:RemotePort
+type TargetSpec struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// String returns the target as host:port
+func (t TargetSpec) String() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+// TargetStats contains statistics for a single target of a balanced tunnel
+type TargetStats struct {
+	Address           string `json:"address"`
+	TotalConnections  int64  `json:"total_connections"`
+	ActiveConnections int64  `json:"active_connections"`
+	FailedConnections int64  `json:"failed_connections"`
+}
+
+// TargetAddresses returns every target of the tunnel, the primary first
+func (tc TunnelConfig) TargetAddresses() []string {
+	addresses := []string{tc.TargetAddress()}
+	seen := map[string]bool{addresses[0]: true}
+	for _, target := range tc.Targets {
+		if addr := target.String(); !seen[addr] {
+			seen[addr] = true
+			addresses = append(addresses, addr)
+		}
+	}
+	return addresses
+}
+
+// Balanced reports whether connections are spread over several targets
+func (tc TunnelConfig) Balanced() bool {
+	return len(tc.TargetAddresses()) > 1
+}
+
+// BalancePolicy returns the configured policy, round robin by default
+func (tc TunnelConfig) BalancePolicy() string {
+	if tc.LoadBalance == "" {
+		return LoadBalanceRoundRobin
+	}
+	return tc.LoadBalance
+}
+
+// targetsDescription lists the targets (port ranges for ranged tunnels), with
+// the policy when there are several
+func (tc TunnelConfig) targetsDescription() string {
+	targets := []string{net.JoinHostPort(tc.RemoteHost, tc.portSpan(tc.RemotePort))}
+	for _, target := range tc.Targets {
+		if addr := net.JoinHostPort(target.Host, tc.portSpan(target.Port)); !slices.Contains(targets, addr) {
+			targets = append(targets, addr)
+		}
+	}
+	if len(targets) == 1 {
+		return targets[0]
+	}
+	return fmt.Sprintf("%s (%s)", strings.Join(targets, ", "), tc.BalancePolicy())
+}
+
+// validateTargets checks the additional targets and the balance policy
+func (tc TunnelConfig) validateTargets() error {
+	if len(tc.Targets) > 0 && tc.Type == TunnelTypeDynamic {
+		return fmt.Errorf("additional targets only apply to local and remote forwarding")
+	}
+	for _, target := range tc.Targets {
+		if target.Host == "" {
+			return fmt.Errorf("target host is required")
+		}
+		if target.Port <= 0 || target.Port > 65535 {
+			return fmt.Errorf("invalid target port: %d", target.Port)
+		}
+	}
+	switch tc.LoadBalance {
+	case "", LoadBalanceRoundRobin, LoadBalanceLeastConnections:
+	default:
+		return fmt.Errorf("invalid load balance policy: %s (expected round_robin or least_connections)", tc.LoadBalance)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Benchmark modes
+const (
+	// BenchmarkEcho measures the tunnel's SSH connection against an echo
+	// running on the host, which needs nothing from the target
+	BenchmarkEcho = "echo"
+	// BenchmarkTarget opens connections through the tunnel to its target
+	BenchmarkTarget = "target"
+)
+
+// Benchmark defaults and limits
+const (
+	DefaultBenchmarkSamples  = 20
+	MaxBenchmarkSamples      = 1000
+	DefaultBenchmarkBytes    = 16 << 20
+	MaxBenchmarkBytes        = 1 << 30
+	DefaultBenchmarkDuration = 5 * time.Second
+	MaxBenchmarkDuration     = time.Minute
+	DefaultBenchmarkTimeout  = 5 * time.Second
+)
+
+// ErrInvalidBenchmark is returned for benchmark options out of range
+var ErrInvalidBenchmark = errors.New("invalid benchmark options")
+
+// BenchmarkOptions configures a tunnel benchmark
+type BenchmarkOptions struct {
+	Mode     string        // echo (default) or target
+	Samples  int           // round trips measured
+	Bytes    int64         // most bytes sent in the throughput test
+	Duration time.Duration // longest the throughput test runs
+	Timeout  time.Duration // limit of each round trip
+	HTTP     bool          // the target speaks HTTP, so target mode can send requests
+	Path     string        // path requested from HTTP targets
+}
+
+// Normalize fills in defaults and checks the limits
+func (o *BenchmarkOptions) Normalize() error {
+	if o.Mode == "" {
+		o.Mode = BenchmarkEcho
+	}
+	if o.Mode != BenchmarkEcho && o.Mode != BenchmarkTarget {
+		return fmt.Errorf("%w: mode must be %s or %s", ErrInvalidBenchmark, BenchmarkEcho, BenchmarkTarget)
+	}
+	if o.Samples == 0 {
+		o.Samples = DefaultBenchmarkSamples
+	}
+	if o.Bytes == 0 {
+		o.Bytes = DefaultBenchmarkBytes
+	}
+	if o.Duration == 0 {
+		o.Duration = DefaultBenchmarkDuration
+	}
+	if o.Timeout == 0 {
+		o.Timeout = DefaultBenchmarkTimeout
+	}
+	if o.Path == "" {
+		o.Path = "/"
+	}
+	switch {
+	case o.Samples < 1 || o.Samples > MaxBenchmarkSamples:
+		return fmt.Errorf("%w: samples must be between 1 and %d", ErrInvalidBenchmark, MaxBenchmarkSamples)
+	case o.Bytes < 1 || o.Bytes > MaxBenchmarkBytes:
+		return fmt.Errorf("%w: bytes must be between 1 and %d", ErrInvalidBenchmark, MaxBenchmarkBytes)
+	case o.Duration < 0 || o.Duration > MaxBenchmarkDuration:
+		return fmt.Errorf("%w: duration must not exceed %s", ErrInvalidBenchmark, MaxBenchmarkDuration)
+	case o.Timeout < 0:
+		return fmt.Errorf("%w: timeout must not be negative", ErrInvalidBenchmark)
+	}
+	return nil
+}
+
+// BenchmarkResult compares a tunnel with the direct path
+type BenchmarkResult struct {
+	Mode      string         `json:"mode"`
+	Tunnel    BenchmarkPath  `json:"tunnel"`
+	Direct    *BenchmarkPath `json:"direct,omitempty"` // the same test without the tunnel, when it can be reached
+	StartedAt time.Time      `json:"started_at"`
+	Duration  int64          `json:"duration"` // milliseconds
+}
+
+// BenchmarkPath is what one path measured
+type BenchmarkPath struct {
+	Address    string           `json:"address"`
+	RTT        *LatencyStats    `json:"rtt,omitempty"`
+	Throughput *ThroughputStats `json:"throughput,omitempty"`
+	Error      string           `json:"error,omitempty"` // why the path could not be measured
+}
+
+// LatencyStats summarizes round trips in milliseconds
+type LatencyStats struct {
+	Samples int     `json:"samples"`
+	Failed  int     `json:"failed,omitempty"`
+	Min     float64 `json:"min_ms"`
+	Avg     float64 `json:"avg_ms"`
+	P50     float64 `json:"p50_ms"`
+	P90     float64 `json:"p90_ms"`
+	P99     float64 `json:"p99_ms"`
+	Max     float64 `json:"max_ms"`
+}
+
+// NewLatencyStats summarizes the successful round trips; failed counts the rest
+func NewLatencyStats(samples []time.Duration, failed int) *LatencyStats {
+	stats := &LatencyStats{Samples: len(samples), Failed: failed}
+	if len(samples) == 0 {
+		return stats
+	}
+
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, sample := range sorted {
+		total += sample
+	}
+	// Nearest-rank percentile
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+		return milliseconds(sorted[max(rank, 0)])
+	}
+
+	stats.Min = milliseconds(sorted[0])
+	stats.Avg = milliseconds(total / time.Duration(len(sorted)))
+	stats.P50 = percentile(50)
+	stats.P90 = percentile(90)
+	stats.P99 = percentile(99)
+	stats.Max = milliseconds(sorted[len(sorted)-1])
+	return stats
+}
+
+// ThroughputStats is the data moved in a throughput test
+type ThroughputStats struct {
+	Bytes   int64   `json:"bytes"`
+	Seconds float64 `json:"seconds"`
+	MBps    float64 `json:"mb_per_second"` // 10^6 bytes per second
+}
+
+// NewThroughputStats computes the rate of moving bytes in elapsed
+func NewThroughputStats(bytes int64, elapsed time.Duration) *ThroughputStats {
+	stats := &ThroughputStats{Bytes: bytes, Seconds: math.Round(elapsed.Seconds()*1000) / 1000}
+	if elapsed > 0 {
+		stats.MBps = math.Round(float64(bytes)/elapsed.Seconds()/1e6*100) / 100
+	}
+	return stats
+}
+
+// milliseconds rounds d to microseconds, in milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// SSHEndpoint is the address of an SSH server
+type SSHEndpoint struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// String returns the endpoint as host:port, bracketing IPv6 literals
+func (e SSHEndpoint) String() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(e.Port))
+}
+
+// CandidateStatus is the result of probing one of several redundant servers
+type CandidateStatus struct {
+	Endpoint  SSHEndpoint   `json:"endpoint"`
+	Healthy   bool          `json:"healthy"`
+	Latency   time.Duration `json:"latency,omitempty"` // TCP connect time, 0 when not probed
+	Error     string        `json:"error,omitempty"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// Endpoints returns the servers a connection may use: the configured host
+// followed by its candidates, without duplicates
+func (c SSHConnectionConfig) Endpoints() []SSHEndpoint {
+	endpoints := []SSHEndpoint{{Host: c.Host, Port: c.Port}}
+	seen := map[SSHEndpoint]bool{endpoints[0]: true}
+	for _, candidate := range c.Candidates {
+		if candidate.Port == 0 {
+			candidate.Port = c.Port
+		}
+		if !seen[candidate] {
+			seen[candidate] = true
+			endpoints = append(endpoints, candidate)
+		}
+	}
+	return endpoints
+}
+
+// WithEndpoint returns the configuration for connecting to one of its endpoints
+func (c SSHConnectionConfig) WithEndpoint(endpoint SSHEndpoint) SSHConnectionConfig {
+	c.Host = endpoint.Host
+	c.Port = endpoint.Port
+	return c
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Capture validation errors
+var (
+	ErrInvalidCaptureLimit  = errors.New("capture limits cannot be negative")
+	ErrInvalidCaptureRedact = errors.New("invalid capture redaction pattern")
+	ErrInvalidCaptureFile   = errors.New("capture file must be a file name without directories")
+)
+
+// Capture defaults
+const (
+	DefaultCaptureMaxBytes    = 64 * 1024        // 每个连接每个方向
+	DefaultCaptureMaxFileSize = 10 * 1024 * 1024 // 单个抓包文件
+	DefaultCaptureMaxFiles    = 3
+	DefaultCaptureDir         = "./data/captures" // 服务器保存抓包文件的目录
+)
+
+// DefaultCaptureRedactions 默认屏蔽的凭据（HTTP 认证头、Cookie 及常见的密码/令牌参数）
+var DefaultCaptureRedactions = []string{
+	`(?i)(authorization|proxy-authorization|cookie|set-cookie|x-api-key):[^\r\n]*`,
+	`(?i)(password|passwd|pwd|token|access_token|api_key|secret)=[^&\s]*`,
+}
+
+// CaptureRedacted replaces redacted content in captured data
+const CaptureRedacted = "[REDACTED]"
+
+// CaptureConfig 调试抓包：将转发的数据复制到轮转文件或实时 WebSocket 视图，
+// 用于在无法使用 tcpdump 时诊断经过隧道的协议问题
+type CaptureConfig struct {
+	Enabled     bool     `gorm:"default:false" json:"enabled"`
+	File        string   `gorm:"size:500" json:"file,omitempty"`                    // 抓包文件名，服务器上保存在抓包目录中；为空时仅实时查看
+	MaxBytes    int64    `gorm:"default:0" json:"max_bytes,omitempty"`              // 每个连接每个方向最多记录的字节数，0 使用默认值
+	MaxFileSize int64    `gorm:"default:0" json:"max_file_size,omitempty"`          // 超过后轮转文件，0 使用默认值
+	MaxFiles    int      `gorm:"default:0" json:"max_files,omitempty"`              // 保留的轮转文件数，0 使用默认值
+	Redact      []string `gorm:"type:text;serializer:json" json:"redact,omitempty"` // 额外屏蔽的正则表达式
+	KeepSecrets bool     `gorm:"default:false" json:"keep_secrets,omitempty"`       // 不应用默认的凭据屏蔽
+}
+
+// WithDefaults fills unset limits
+func (c CaptureConfig) WithDefaults() CaptureConfig {
+	if c.MaxBytes == 0 {
+		c.MaxBytes = DefaultCaptureMaxBytes
+	}
+	if c.MaxFileSize == 0 {
+		c.MaxFileSize = DefaultCaptureMaxFileSize
+	}
+	if c.MaxFiles == 0 {
+		c.MaxFiles = DefaultCaptureMaxFiles
+	}
+	return c
+}
+
+// Redactions returns the patterns to apply, defaults first
+func (c CaptureConfig) Redactions() []string {
+	if c.KeepSecrets {
+		return c.Redact
+	}
+	return append(slices.Clone(DefaultCaptureRedactions), c.Redact...)
+}
+
+// Validate 验证抓包配置
+func (c CaptureConfig) Validate() error {
+	if c.MaxBytes < 0 || c.MaxFileSize < 0 || c.MaxFiles < 0 {
+		return ErrInvalidCaptureLimit
+	}
+	for _, pattern := range c.Redact {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrInvalidCaptureRedact, pattern, err)
+		}
+	}
+	return nil
+}
+
+// ValidateFileName 验证抓包文件只是文件名：服务器将其放在抓包目录中，
+// 不允许包含目录或指向目录之外
+func (c CaptureConfig) ValidateFileName() error {
+	if c.File == "" {
+		return nil
+	}
+	if strings.ContainsAny(c.File, `/\`) || c.File == "." || c.File == ".." ||
+		filepath.Base(c.File) != c.File || filepath.IsAbs(c.File) {
+		return fmt.Errorf("%w: %q", ErrInvalidCaptureFile, c.File)
+	}
+	return nil
+}
+
+// Equal reports whether two capture configurations are the same
+func (c CaptureConfig) Equal(other CaptureConfig) bool {
+	return c.Enabled == other.Enabled && c.File == other.File &&
+		c.MaxBytes == other.MaxBytes && c.MaxFileSize == other.MaxFileSize &&
+		c.MaxFiles == other.MaxFiles && c.KeepSecrets == other.KeepSecrets &&
+		slices.Equal(c.Redact, other.Redact)
+}
+
+// TunnelCapture returns the configuration for a tunnel, or nil when disabled
+func (c CaptureConfig) TunnelCapture() *CaptureConfig {
+	if !c.Enabled {
+		return nil
+	}
+	return &c
+}
+
+// CaptureDirection is the direction of captured data
+type CaptureDirection string
+
+const (
+	CaptureSent     CaptureDirection = "sent"     // client to forwarding target
+	CaptureReceived CaptureDirection = "received" // forwarding target to client
+)
+
+// CaptureChunk is data read from one side of a forwarded connection
+type CaptureChunk struct {
+	Connection uint64           `json:"connection"` // per-capture connection number
+	Listener   string           `json:"listener"`
+	Client     string           `json:"client,omitempty"`
+	Direction  CaptureDirection `json:"direction"`
+	Offset     int64            `json:"offset"` // bytes captured before this chunk in this direction
+	Data       []byte           `json:"data"`
+	Dump       string           `json:"dump"`                // hex/ASCII view of the redacted data
+	Truncated  bool             `json:"truncated,omitempty"` // the capture limit was reached; later data is not recorded
+	Timestamp  time.Time        `json:"timestamp"`
+}
@@ -17,6 +17,18 @@ type Config struct {
 	
 	// Storage configuration
 	Storage StorageConfig `json:"storage" yaml:"storage"`
+	
+	// Friendly hostnames for active tunnels
+	LocalDNS LocalDNSConfig `json:"local_dns" yaml:"local_dns"`
+}
+
+// LocalDNSConfig publishes names like db.staging.portfly for active tunnels
+type LocalDNSConfig struct {
+	Enabled    bool   `json:"enabled" yaml:"enabled"`
+	Domain     string `json:"domain" yaml:"domain"`           // defaults to "portfly"
+	Mode       string `json:"mode" yaml:"mode"`               // "hosts" (default) or "resolver"
+	HostsFile  string `json:"hosts_file" yaml:"hosts_file"`   // defaults to /etc/hosts (or the Windows equivalent)
+	ListenAddr string `json:"listen_addr" yaml:"listen_addr"` // resolver address, defaults to 127.0.0.1:5353
 }
 
 // ServerConfig contains HTTP server configuration
@@ -48,6 +60,8 @@ type CORSConfig struct {
 type SSHConfig struct {
 	// Connection settings
 	ConnectTimeout   time.Duration `json:"connect_timeout" yaml:"connect_timeout"`
+	HandshakeTimeout time.Duration `json:"handshake_timeout" yaml:"handshake_timeout"` // SSH handshake after the transport is up
+	BindTimeout      time.Duration `json:"bind_timeout" yaml:"bind_timeout"`           // tunnel listener bind, incl. remote forward requests
 	KeepAliveTimeout time.Duration `json:"keepalive_timeout" yaml:"keepalive_timeout"`
 	MaxRetries       int           `json:"max_retries" yaml:"max_retries"`
 	RetryInterval    time.Duration `json:"retry_interval" yaml:"retry_interval"`
@@ -62,6 +76,32 @@ type SSHConfig struct {
 	Ciphers         []string `json:"ciphers" yaml:"ciphers"`
 	MACs            []string `json:"macs" yaml:"macs"`
 	KeyExchanges    []string `json:"key_exchanges" yaml:"key_exchanges"`
+	
+	// Outbound proxy used for all SSH connections unless a host sets its own
+	// (http://, https://, socks5:// URLs, credentials allowed in userinfo)
+	Proxy string `json:"proxy" yaml:"proxy"`
+	
+	// Tunnel transfer tuning, used unless a tunnel sets its own
+	TransferBufferSize int  `json:"transfer_buffer_size" yaml:"transfer_buffer_size"` // bytes, 0 uses 32 KiB
+	DisableZeroCopy    bool `json:"disable_zero_copy" yaml:"disable_zero_copy"`       // never use splice/ReadFrom
+	
+	// How often transfer statistics are pushed to event subscribers (1s-10s, 0 uses 5s)
+	StatsInterval time.Duration `json:"stats_interval" yaml:"stats_interval"`
+}
+
+// Bounds of the statistics push interval
+const (
+	MinStatsInterval     = time.Second
+	MaxStatsInterval     = 10 * time.Second
+	DefaultStatsInterval = 5 * time.Second
+)
+
+// StatsPushInterval returns the statistics push interval, clamped to its bounds
+func (c SSHConfig) StatsPushInterval() time.Duration {
+	if c.StatsInterval == 0 {
+		return DefaultStatsInterval
+	}
+	return min(max(c.StatsInterval, MinStatsInterval), MaxStatsInterval)
 }
 
 // LoggingConfig contains logging configuration
@@ -89,6 +129,9 @@ type BackupConfig struct {
 	Interval time.Duration `json:"interval" yaml:"interval"`
 	Path     string        `json:"path" yaml:"path"`
 	MaxFiles int           `json:"max_files" yaml:"max_files"`
+
+	// Passphrase snapshots are encrypted with (AES-256-GCM, scrypt-derived key)
+	Passphrase string `json:"-" yaml:"passphrase"`
 }
 
 // DefaultConfig returns a default configuration
@@ -111,6 +154,8 @@ func DefaultConfig() *Config {
 		},
 		SSH: SSHConfig{
 			ConnectTimeout:    30 * time.Second,
+			HandshakeTimeout:  30 * time.Second,
+			BindTimeout:       15 * time.Second,
 			KeepAliveTimeout:  30 * time.Second,
 			MaxRetries:        3,
 			RetryInterval:     5 * time.Second,
@@ -118,6 +163,7 @@ func DefaultConfig() *Config {
 			ConnectionTimeout: 60 * time.Second,
 			IdleTimeout:       300 * time.Second,
 			HostKeyCallback:   "ask", // ask, accept, strict
+			StatsInterval:     DefaultStatsInterval,
 		},
 		Logging: LoggingConfig{
 			Level:      "info",
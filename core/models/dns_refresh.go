@@ -0,0 +1,45 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MinDNSRefreshInterval bounds how often a tunnel re-resolves its targets
+const MinDNSRefreshInterval = 5 * time.Second
+
+// DNSRefresh validation errors
+var (
+	ErrInvalidDNSRefresh       = fmt.Errorf("dns refresh interval must be 0 or at least %s", MinDNSRefreshInterval)
+	ErrDNSRefreshOnLocalPort   = errors.New("dns_refresh only applies to remote_port, whose target is dialed by this machine")
+	ErrDNSRefreshOnLocalTunnel = errors.New("dns refresh only applies to remote forwarding; other targets are resolved by the SSH server")
+)
+
+// DNSRefresh 目标域名的定期重新解析。远程转发由本机连接目标，开启后新连接使用缓存的解析结果，
+// 按间隔重新解析；目标 IP 变化时（如云负载均衡更换地址）可关闭仍连在旧 IP 上的连接，客户端重连后使用新地址
+type DNSRefresh struct {
+	Interval         time.Duration `gorm:"default:0" json:"interval,omitempty"`              // 重新解析间隔，0 表示不启用
+	CycleConnections bool          `gorm:"default:false" json:"cycle_connections,omitempty"` // 关闭连到已不在解析结果中的 IP 的连接
+}
+
+// Enabled reports whether targets are re-resolved
+func (r DNSRefresh) Enabled() bool {
+	return r.Interval > 0
+}
+
+// Validate 验证重新解析配置
+func (r DNSRefresh) Validate() error {
+	if r.Interval < 0 || (r.Interval > 0 && r.Interval < MinDNSRefreshInterval) {
+		return ErrInvalidDNSRefresh
+	}
+	return nil
+}
+
+// TunnelDNSRefresh returns the configuration for a tunnel, or nil when disabled
+func (r DNSRefresh) TunnelDNSRefresh() *DNSRefresh {
+	if !r.Enabled() {
+		return nil
+	}
+	return &r
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// EventType represents the type of a session lifecycle event
+type EventType string
+
+const (
+	EventSessionStatus      EventType = "session.status"      // Session status changed
+	EventSessionBackoff     EventType = "session.backoff"     // Reconnect attempt failed, waiting before retry
+	EventSessionReconnected EventType = "session.reconnected" // SSH connection re-established
+	EventSessionGaveUp      EventType = "session.gave_up"     // Retry policy exhausted
+	EventSessionUpdated     EventType = "session.updated"     // Tunnel configuration changed
+	EventSessionStats       EventType = "session.stats"       // Transfer statistics since the previous push
+)
+
+// SessionEvent describes something that happened to a managed session
+type SessionEvent struct {
+	Type      EventType     `json:"type"`
+	SessionID string        `json:"session_id"`
+	Status    SessionStatus `json:"status"`
+	Message   string        `json:"message,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Attempt   int           `json:"attempt,omitempty"`
+	RetryIn   time.Duration `json:"retry_in,omitempty"`
+	Stats     *StatsUpdate  `json:"stats,omitempty"` // session.stats only
+	Timestamp time.Time     `json:"timestamp"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Grant validation errors
+var (
+	ErrGrantGranteeRequired = errors.New("grantee is required")
+	ErrGrantInvalidWindow   = errors.New("grant must expire after it starts")
+)
+
+// PortGrant 端口的临时访问授权（如外包人员或事故处理），持有令牌者可在有效期内启动和使用端口；
+// 过期或撤销后，通过该授权启动的端口会被自动停止，授权随之归档
+type PortGrant struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	PortID    uint   `gorm:"not null;index" json:"port_id"`
+	Grantee   string `gorm:"not null;size:100" json:"grantee"`     // 被授权的人或令牌名称
+	Reason    string `gorm:"size:500" json:"reason,omitempty"`     // 授权原因，如事故编号
+	TokenHash string `gorm:"size:64;uniqueIndex" json:"-"`         // 访问令牌的 SHA-256，令牌本身只在创建时返回一次
+	CreatedBy string `gorm:"size:100" json:"created_by,omitempty"` // 创建者，记录在审计日志中
+
+	StartsAt   time.Time  `gorm:"not null" json:"starts_at"`
+	ExpiresAt  time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt     *time.Time `json:"used_at,omitempty"`                  // 最近一次用该授权启动端口的时间
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`               // 提前撤销的时间
+	ArchivedAt *time.Time `gorm:"index" json:"archived_at,omitempty"` // 过期或撤销后完成清理的时间
+}
+
+// ActiveAt 授权在给定时间是否有效
+func (g *PortGrant) ActiveAt(now time.Time) bool {
+	return g.RevokedAt == nil && g.ArchivedAt == nil && !now.Before(g.StartsAt) && now.Before(g.ExpiresAt)
+}
+
+// Ended 授权是否已过期或被撤销，等待归档
+func (g *PortGrant) Ended(now time.Time) bool {
+	return g.ArchivedAt == nil && (g.RevokedAt != nil || !now.Before(g.ExpiresAt))
+}
+
+// Validate 验证授权
+func (g *PortGrant) Validate() error {
+	if g.Grantee == "" {
+		return ErrGrantGranteeRequired
+	}
+	if !g.ExpiresAt.After(g.StartsAt) {
+		return ErrGrantInvalidWindow
+	}
+	return nil
+}
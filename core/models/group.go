@@ -1,11 +1,29 @@
 package models
 
 import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Group hierarchy errors
+var (
+	ErrGroupCycle         = errors.New("cannot move a group under itself or its subgroups")
+	ErrGroupParentProject = errors.New("parent group must belong to the same project")
+	ErrGroupHasChildren   = errors.New("group has subgroups; move or delete them first")
+	ErrNestedGroupProject = errors.New("a nested group cannot change project; move it to the top level and its subgroups out first")
+)
+
+// 分组的默认颜色和图标，与数据库默认值一致；取默认值的分组继承祖先的设置
+const (
+	DefaultGroupColor = "#10b981"
+	DefaultGroupIcon  = "folder"
+)
+
 // Group Reactflow画布 - 可以包含主机和端口 node
 type Group struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -15,15 +33,30 @@ type Group struct {
 
 	Name        string   `gorm:"not null;size:100" json:"name"`
 	Description string   `gorm:"size:500" json:"description"`
-	Color       string   `gorm:"size:20;default:#10b981" json:"color"`
-	Icon        string   `gorm:"size:50;default:folder" json:"icon"`
+	Color       string   `gorm:"size:20;default:#10b981" json:"color"` // 为默认值时继承祖先分组的颜色
+	Icon        string   `gorm:"size:50;default:folder" json:"icon"`   // 为默认值时继承祖先分组的图标
 	Tags        []string `gorm:"type:text;serializer:json" json:"tags,omitempty"`
-	Metadata    string   `gorm:"type:text" json:"metadata,omitempty"` // JSON string
+	Metadata    Metadata `json:"metadata,omitempty"` // 自定义元数据，可按 metadata.<键> 查询
+
+	// 维护窗口（对分组内所有主机和端口生效）
+	MaintenanceWindows []MaintenanceWindow `gorm:"type:text;serializer:json" json:"maintenance_windows,omitempty"`
+
+	// 分组内主机网页终端的空闲超时和最长时长，未设置的项沿用上级分组和全局配置
+	TerminalPolicy *TerminalPolicy `gorm:"type:text;serializer:json" json:"terminal_policy,omitempty"`
 
 	// 外键
 	ProjectID uint    `gorm:"not null;index" json:"project_id"`
 	Project   Project `gorm:"constraint:OnDelete:CASCADE" json:"project,omitempty"`
 
+	// 树状结构支持，与项目相同；父分组必须属于同一项目，位置只能通过移动修改
+	ParentID *uint  `gorm:"index" json:"parent_id,omitempty"` // 父分组ID，为空表示顶层分组
+	Level    int    `gorm:"default:0" json:"level"`           // 层级深度，0为顶层分组
+	Path     string `gorm:"size:500" json:"path,omitempty"`   // 层级路径，如 "/1/2/3"
+	Sort     int    `gorm:"default:0" json:"sort"`            // 同级排序
+
+	Parent   *Group  `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE" json:"parent,omitempty"`
+	Children []Group `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE" json:"children,omitempty"`
+
 	// 关联关系
 	Hosts        []Host        `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE" json:"hosts,omitempty"`
 	PortForwards []PortForward `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE" json:"port_forwards,omitempty"`
@@ -35,4 +68,87 @@ type GroupStats struct {
 	ConnectedHosts int        `json:"connected_hosts"`
 	ActiveTunnels  int        `json:"active_tunnels"`
 	LastUsed       *time.Time `json:"last_used,omitempty"`
+
+	AuthFailingHosts int              `json:"auth_failing_hosts"`      // 最近认证失败的主机数
+	AuthFailures     []HostAuthStatus `json:"auth_failures,omitempty"` // 这些主机的失败记录，连续失败多的在前
+}
+
+// 分组树节点，用于前端展示
+type GroupTreeNode struct {
+	*Group
+	Children    []*GroupTreeNode `json:"children,omitempty"`
+	HasChildren bool             `json:"has_children"`
+}
+
+// 分组移动参数
+type MoveGroupParams struct {
+	GroupID  uint  `json:"group_id"`
+	ParentID *uint `json:"parent_id,omitempty"` // null表示移动到顶层
+	Position int   `json:"position"`            // 在目标位置的排序
+}
+
+// BuildPath 构建数字路径，parentPath 为父分组的路径，顶层分组为空
+func (g *Group) BuildPath(parentPath string) string {
+	return parentPath + "/" + strconv.FormatUint(uint64(g.ID), 10)
+}
+
+// AncestorIDs 按路径返回祖先分组的 ID，最近的在前
+func (g *Group) AncestorIDs() []uint {
+	var ids []uint
+	for _, part := range strings.Split(strings.Trim(g.Path, "/"), "/") {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint(id) == g.ID {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	slices.Reverse(ids)
+	return ids
+}
+
+// IsAncestorOf 是否为 other 的祖先分组
+func (g *Group) IsAncestorOf(other *Group) bool {
+	return g.Path != "" && strings.HasPrefix(other.Path, g.Path+"/")
+}
+
+// Inherit 返回继承祖先默认值后的分组副本，ancestors 最近的在前：颜色和图标为默认值时
+// 取最近的自定义过的祖先，标签和维护窗口累加所有祖先（祖先的在前，重复的标签只保留一个）
+func (g *Group) Inherit(ancestors []Group) *Group {
+	resolved := *g
+	resolved.Tags = nil
+	resolved.MaintenanceWindows = nil
+
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		ancestor := &ancestors[i]
+		for _, tag := range ancestor.Tags {
+			if !slices.Contains(resolved.Tags, tag) {
+				resolved.Tags = append(resolved.Tags, tag)
+			}
+		}
+		resolved.MaintenanceWindows = append(resolved.MaintenanceWindows, ancestor.MaintenanceWindows...)
+	}
+	for _, tag := range g.Tags {
+		if !slices.Contains(resolved.Tags, tag) {
+			resolved.Tags = append(resolved.Tags, tag)
+		}
+	}
+	resolved.MaintenanceWindows = append(resolved.MaintenanceWindows, g.MaintenanceWindows...)
+
+	for _, ancestor := range ancestors {
+		if resolved.Color != "" && resolved.Color != DefaultGroupColor {
+			break
+		}
+		if ancestor.Color != "" && ancestor.Color != DefaultGroupColor {
+			resolved.Color = ancestor.Color
+		}
+	}
+	for _, ancestor := range ancestors {
+		if resolved.Icon != "" && resolved.Icon != DefaultGroupIcon {
+			break
+		}
+		if ancestor.Icon != "" && ancestor.Icon != DefaultGroupIcon {
+			resolved.Icon = ancestor.Icon
+		}
+	}
+	return &resolved
 }
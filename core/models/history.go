@@ -0,0 +1,181 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 记录变更历史的实体类型
+const (
+	EntityHost  = "host"
+	EntityPort  = "port"
+	EntityGroup = "group"
+)
+
+// 变更动作
+const (
+	ChangeCreate  = "create"
+	ChangeUpdate  = "update"
+	ChangeDelete  = "delete"
+	ChangeRestore = "restore" // 恢复到历史版本
+)
+
+// FieldChange 字段的旧值和新值；敏感字段只记录发生了变化
+type FieldChange struct {
+	From     any  `json:"from"`
+	To       any  `json:"to"`
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// ChangeRecord 实体的一个版本：谁在什么时候做了什么修改
+type ChangeRecord struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	EntityType string `gorm:"size:20;not null;uniqueIndex:idx_change_entity_version" json:"entity_type"`
+	EntityID   uint   `gorm:"not null;uniqueIndex:idx_change_entity_version" json:"entity_id"`
+	Version    int    `gorm:"not null;uniqueIndex:idx_change_entity_version" json:"version"` // 每个实体从 1 开始递增
+
+	Action       string `gorm:"size:20;not null" json:"action"`
+	Actor        string `gorm:"size:255" json:"actor,omitempty"` // X-PortFly-Actor 请求头，缺省为客户端地址
+	RequestID    string `gorm:"size:64" json:"request_id,omitempty"`
+	RestoredFrom *int   `json:"restored_from,omitempty"` // 恢复操作的来源版本
+
+	Changes  map[string]FieldChange `gorm:"type:text;serializer:json" json:"changes,omitempty"`
+	Snapshot map[string]any         `gorm:"type:text;serializer:json" json:"snapshot"` // 变更后的字段（删除时为删除前），不含敏感字段
+}
+
+// historyIgnored 不计入历史的字段：标识、时间戳、关联对象和运行状态
+var historyIgnored = map[string][]string{
+	EntityHost: {"group", "port_forwards", "tunnel_sessions", "status", "last_connected", "connection_count"},
+	EntityPort: {"group", "host", "target_port", "source_ports", "tunnel_sessions", "active_host_id",
+		"status", "status_message", "last_error", "last_tested", "last_active", "connection_test", "web"},
+	EntityGroup: {"project", "hosts", "port_forwards", "parent", "children", "parent_id", "level", "path", "sort"},
+}
+
+// historySecrets 只记录是否变化、不保存取值的字段
+var historySecrets = map[string][]string{
+	EntityHost: {"password", "private_key", "sudo_password"},
+}
+
+// IsHistoryEntity 是否为记录变更历史的实体类型
+func IsHistoryEntity(entityType string) bool {
+	_, ok := historyIgnored[entityType]
+	return ok
+}
+
+// HistoryFields 返回实体计入历史的字段，按 JSON 名称索引。省略空值的字段也会出现，
+// 以便恢复时清空它们
+func HistoryFields(entityType string, entity any) (map[string]any, error) {
+	fields := make(map[string]any)
+	if err := collectFields(reflect.Indirect(reflect.ValueOf(entity)), fields); err != nil {
+		return nil, err
+	}
+	for _, name := range append([]string{"id", "created_at", "updated_at"}, historyIgnored[entityType]...) {
+		delete(fields, name)
+	}
+	return fields, nil
+}
+
+// collectFields 以 JSON 形式读取结构体的字段
+func collectFields(v reflect.Value, fields map[string]any) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			if err := collectFields(v.Field(i), fields); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		data, err := json.Marshal(v.Field(i).Interface())
+		if err != nil {
+			return err
+		}
+		var value any
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		fields[name] = value
+	}
+	return nil
+}
+
+// DiffFields 比较两组字段，返回发生变化的字段；敏感字段的取值不会出现在结果中
+func DiffFields(entityType string, before, after map[string]any) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	for name, to := range after {
+		if from, ok := before[name]; !ok || !reflect.DeepEqual(from, to) {
+			changes[name] = FieldChange{From: from, To: to}
+		}
+	}
+	for name, from := range before {
+		if _, ok := after[name]; !ok {
+			changes[name] = FieldChange{From: from}
+		}
+	}
+	for _, name := range historySecrets[entityType] {
+		if _, changed := changes[name]; changed {
+			changes[name] = FieldChange{Redacted: true}
+		}
+	}
+	return changes
+}
+
+// RedactFields 去掉敏感字段，得到可保存的快照
+func RedactFields(entityType string, fields map[string]any) map[string]any {
+	snapshot := make(map[string]any, len(fields))
+	for name, value := range fields {
+		snapshot[name] = value
+	}
+	for _, name := range historySecrets[entityType] {
+		delete(snapshot, name)
+	}
+	return snapshot
+}
+
+// ApplySnapshot 将快照中的字段写回实体（entity 为指针），快照中没有的字段（如敏感字段）保持不变
+func ApplySnapshot(entity any, snapshot map[string]any) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	// 先清空这些字段，JSON 中省略的嵌套空值才不会保留当前取值
+	resetFields(reflect.ValueOf(entity).Elem(), snapshot)
+	return json.Unmarshal(data, entity)
+}
+
+// resetFields 将 JSON 名称出现在 names 中的字段置为零值
+func resetFields(v reflect.Value, names map[string]any) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			resetFields(v.Field(i), names)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := names[name]; ok {
+			v.Field(i).SetZero()
+		}
+	}
+}
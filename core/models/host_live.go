@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// 主机实时可达状态
+const (
+	HostReachable   = "reachable"
+	HostUnreachable = "unreachable"
+	HostUnprobed    = "unknown" // 经代理连接的主机无法直接探测
+)
+
+// HostLiveState 主机的实时状态，不持久化。由主机监控探测 SSH 端口得到，
+// 列表接口将其附加到主机上，客户端无需再逐个查询统计
+type HostLiveState struct {
+	Reachability  string     `json:"reachability"`         // reachable, unreachable, unknown
+	LatencyMs     *float64   `json:"latency_ms,omitempty"` // SSH 端口的 TCP 连接耗时
+	Error         string     `json:"error,omitempty"`      // 不可达的原因
+	CheckedAt     *time.Time `json:"checked_at,omitempty"` // 最近一次探测时间
+	ActiveTunnels int        `json:"active_tunnels"`       // 经该主机运行中的隧道数
+}
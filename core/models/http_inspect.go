@@ -0,0 +1,96 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTP inspector errors
+var (
+	ErrHTTPRecordNotFound  = errors.New("http request not found")
+	ErrHTTPReplayTruncated = errors.New("request body was truncated when captured and cannot be replayed")
+)
+
+// HTTP inspector limits
+const (
+	HTTPLogSize   = 100       // requests kept per tunnel
+	HTTPBodyLimit = 64 * 1024 // bytes kept per request or response body
+)
+
+// HTTPExchange is a request seen on a tunnel carrying HTTP and the status of
+// its response
+type HTTPExchange struct {
+	Listener      string        `json:"listener"`
+	Client        string        `json:"client,omitempty"`
+	Method        string        `json:"method"`
+	Host          string        `json:"host,omitempty"`
+	Path          string        `json:"path"`
+	Proto         string        `json:"proto"`
+	Status        int           `json:"status"`
+	Duration      time.Duration `json:"duration"`                 // request headers to response headers
+	RequestBytes  int64         `json:"request_bytes,omitempty"`  // declared body size, -1 when unknown
+	ResponseBytes int64         `json:"response_bytes,omitempty"` // declared body size, -1 when unknown
+	StartedAt     time.Time     `json:"started_at"`
+}
+
+// StatusClass returns the response status class, e.g. "2xx"
+func (e HTTPExchange) StatusClass() string {
+	if e.Status < 100 || e.Status > 599 {
+		return "other"
+	}
+	return fmt.Sprintf("%dxx", e.Status/100)
+}
+
+// HTTPStats counts the HTTP requests seen on a tunnel
+type HTTPStats struct {
+	Requests      int64            `json:"requests"`
+	Responses     map[string]int64 `json:"responses"` // by status class
+	TotalDuration time.Duration    `json:"total_duration"`
+	MaxDuration   time.Duration    `json:"max_duration"`
+}
+
+// Record adds an exchange to the statistics
+func (s *HTTPStats) Record(exchange HTTPExchange) {
+	if s.Responses == nil {
+		s.Responses = make(map[string]int64)
+	}
+	s.Requests++
+	s.Responses[exchange.StatusClass()]++
+	s.TotalDuration += exchange.Duration
+	s.MaxDuration = max(s.MaxDuration, exchange.Duration)
+}
+
+// Clone returns a copy that does not share the response counters
+func (s *HTTPStats) Clone() *HTTPStats {
+	if s == nil {
+		return nil
+	}
+	clone := *s
+	clone.Responses = make(map[string]int64, len(s.Responses))
+	for class, count := range s.Responses {
+		clone.Responses[class] = count
+	}
+	return &clone
+}
+
+// HTTPRecord is a request and response kept by the HTTP inspector for
+// inspection and replay
+type HTTPRecord struct {
+	ID       uint64 `json:"id"`
+	ReplayOf uint64 `json:"replay_of,omitempty"` // ID of the replayed request
+	HTTPExchange
+
+	RequestHeader         http.Header `json:"request_header,omitempty"`
+	RequestBody           []byte      `json:"request_body,omitempty"`
+	RequestBodyTruncated  bool        `json:"request_body_truncated,omitempty"`
+	ResponseHeader        http.Header `json:"response_header,omitempty"`
+	ResponseBody          []byte      `json:"response_body,omitempty"`
+	ResponseBodyTruncated bool        `json:"response_body_truncated,omitempty"`
+}
+
+// Summary returns the record without headers and bodies, for listings
+func (r HTTPRecord) Summary() HTTPRecord {
+	return HTTPRecord{ID: r.ID, ReplayOf: r.ReplayOf, HTTPExchange: r.HTTPExchange}
+}
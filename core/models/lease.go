@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Lease 多实例部署下的资源租约，持有者负责该资源（如自动启动的端口隧道）
+// 持有者需在过期前续约，过期后其他实例可接管
+type Lease struct {
+	Name       string    `gorm:"primaryKey;size:191" json:"name"`      // 资源名称，如 port:12
+	Owner      string    `gorm:"size:191;not null;index" json:"owner"` // 持有者实例 ID
+	ExpiresAt  time.Time `gorm:"not null;index" json:"expires_at"`
+	AcquiredAt time.Time `json:"acquired_at"` // 当前持有者首次获得租约的时间
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// IsExpired 租约是否已过期
+func (l *Lease) IsExpired(now time.Time) bool {
+	return now.After(l.ExpiresAt)
+}
@@ -0,0 +1,276 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maintenance window validation errors
+var (
+	ErrInvalidSchedule          = errors.New("invalid maintenance schedule")
+	ErrInvalidMaintenanceLength = errors.New("maintenance duration must be between 1 minute and 7 days")
+	ErrInvalidMaintenanceAction = errors.New("invalid maintenance action")
+	ErrInvalidTimezone          = errors.New("invalid maintenance timezone")
+)
+
+// MaxMaintenanceDuration 单个维护窗口的最长时长（分钟）
+const MaxMaintenanceDuration = 7 * 24 * 60
+
+// MaintenanceAction 维护窗口内对隧道的处理方式
+type MaintenanceAction string
+
+const (
+	MaintenanceActionBlock MaintenanceAction = "block" // 阻止启动新隧道（默认）
+	MaintenanceActionStop  MaintenanceAction = "stop"  // 阻止启动，并停止运行中的隧道
+)
+
+// MaintenanceWindow 主机/分组的维护窗口：按 cron 表达式开始，持续 Duration 分钟
+// 窗口内隧道启动被拒绝，监控告警被静默
+type MaintenanceWindow struct {
+	Name     string            `json:"name,omitempty"`
+	Schedule string            `json:"schedule"`           // cron 表达式（分 时 日 月 周），如 "0 2 * * 6"；支持 @daily 等
+	Duration int               `json:"duration"`           // 窗口时长（分钟）
+	Timezone string            `json:"timezone,omitempty"` // IANA 时区，默认 UTC
+	Action   MaintenanceAction `json:"action,omitempty"`   // block 或 stop，默认 block
+}
+
+// MaintenanceStatus 主机/分组当前的维护状态，随拒绝的启动请求一起返回
+type MaintenanceStatus struct {
+	Active      bool              `json:"active"`
+	Scope       string            `json:"scope,omitempty"` // host 或 group，表示窗口定义在哪一层
+	ScopeID     uint              `json:"scope_id,omitempty"`
+	ScopeName   string            `json:"scope_name,omitempty"`
+	Window      string            `json:"window,omitempty"`
+	Schedule    string            `json:"schedule,omitempty"`
+	Action      MaintenanceAction `json:"action,omitempty"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+	Until       *time.Time        `json:"until,omitempty"`
+	AlertsMuted bool              `json:"alerts_muted"`
+}
+
+// Validate checks the schedule, duration, timezone and action
+func (w MaintenanceWindow) Validate() error {
+	if _, err := ParseCronSchedule(w.Schedule); err != nil {
+		return err
+	}
+	if w.Duration < 1 || w.Duration > MaxMaintenanceDuration {
+		return ErrInvalidMaintenanceLength
+	}
+	if _, err := w.location(); err != nil {
+		return err
+	}
+	switch w.Action {
+	case "", MaintenanceActionBlock, MaintenanceActionStop:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidMaintenanceAction, w.Action)
+	}
+}
+
+// EffectiveAction returns the action, defaulting to block
+func (w MaintenanceWindow) EffectiveAction() MaintenanceAction {
+	if w.Action == "" {
+		return MaintenanceActionBlock
+	}
+	return w.Action
+}
+
+// ActiveAt returns the start of the occurrence covering t, if any
+func (w MaintenanceWindow) ActiveAt(t time.Time) (time.Time, bool) {
+	schedule, err := ParseCronSchedule(w.Schedule)
+	if err != nil || w.Duration < 1 {
+		return time.Time{}, false
+	}
+	loc, err := w.location()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// An occurrence started in the last Duration minutes covers t
+	minute := t.In(loc).Truncate(time.Minute)
+	for i := 0; i < w.Duration && i < MaxMaintenanceDuration; i++ {
+		start := minute.Add(-time.Duration(i) * time.Minute)
+		if schedule.Matches(start) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// location resolves the window's timezone
+func (w MaintenanceWindow) location() (*time.Location, error) {
+	if w.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTimezone, w.Timezone)
+	}
+	return loc, nil
+}
+
+// ValidateMaintenanceWindows validates every window in the list
+func ValidateMaintenanceWindows(windows []MaintenanceWindow) error {
+	for i, window := range windows {
+		if err := window.Validate(); err != nil {
+			return fmt.Errorf("maintenance window %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// MaintenanceStatusAt evaluates windows at t. When several windows are open,
+// a stop window wins over a block window, then the one ending last.
+func MaintenanceStatusAt(windows []MaintenanceWindow, t time.Time) MaintenanceStatus {
+	var status MaintenanceStatus
+	for _, window := range windows {
+		start, ok := window.ActiveAt(t)
+		if !ok {
+			continue
+		}
+		until := start.Add(time.Duration(window.Duration) * time.Minute)
+		action := window.EffectiveAction()
+		if status.Active {
+			if status.Action == MaintenanceActionStop && action != MaintenanceActionStop {
+				continue
+			}
+			if status.Action == action && !until.After(*status.Until) {
+				continue
+			}
+		}
+		status = MaintenanceStatus{
+			Active:      true,
+			Window:      window.Name,
+			Schedule:    window.Schedule,
+			Action:      action,
+			StartedAt:   &start,
+			Until:       &until,
+			AlertsMuted: true,
+		}
+	}
+	return status
+}
+
+// CronSchedule is a parsed five-field cron expression
+type CronSchedule struct {
+	minutes, hours, days, months, weekdays uint64
+	anyDay, anyWeekday                     bool
+}
+
+// cronMacros are the supported shorthand schedules
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCronSchedule parses "minute hour day-of-month month day-of-week" with
+// *, lists, ranges and steps; day-of-week accepts 0-7 with 0 and 7 as Sunday
+func ParseCronSchedule(expr string) (*CronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q: expected 5 fields", ErrInvalidSchedule, expr)
+	}
+
+	var schedule CronSchedule
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("%w: minute: %v", ErrInvalidSchedule, err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("%w: hour: %v", ErrInvalidSchedule, err)
+	}
+	if schedule.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("%w: day of month: %v", ErrInvalidSchedule, err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("%w: month: %v", ErrInvalidSchedule, err)
+	}
+	if schedule.weekdays, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("%w: day of week: %v", ErrInvalidSchedule, err)
+	}
+	// 7 is Sunday as well
+	if schedule.weekdays&(1<<7) != 0 {
+		schedule.weekdays |= 1
+	}
+	schedule.anyDay = fields[2] == "*"
+	schedule.anyWeekday = fields[4] == "*"
+	return &schedule, nil
+}
+
+// Matches reports whether the schedule fires at t's minute
+func (s *CronSchedule) Matches(t time.Time) bool {
+	if s.minutes&(1<<t.Minute()) == 0 || s.hours&(1<<t.Hour()) == 0 || s.months&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dayMatch := s.days&(1<<t.Day()) != 0
+	weekdayMatch := s.weekdays&(1<<int(t.Weekday())) != 0
+	// As in cron, a restricted day-of-month and day-of-week match either
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekdayMatch
+	case s.anyWeekday:
+		return dayMatch
+	default:
+		return dayMatch || weekdayMatch
+	}
+}
+
+// parseCronField parses one comma-separated field into a bit set
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+			if high, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			low = value
+			if step == 1 {
+				high = value
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,329 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Metadata errors
+var (
+	ErrInvalidMetadata       = errors.New("invalid metadata")
+	ErrInvalidMetadataSchema = errors.New("invalid metadata schema")
+	ErrInvalidMetadataFilter = errors.New("invalid metadata filter")
+)
+
+// EntityProject 项目，与 EntityHost 等一起作为元数据模式的实体类型（项目不记录变更历史）
+const EntityProject = "project"
+
+// MetadataQueryPrefix 列表接口中按元数据过滤的查询参数前缀，如 metadata.env=prod
+const MetadataQueryPrefix = "metadata."
+
+// legacyMetadataKey 保存无法解析为 JSON 对象的旧版元数据文本
+const legacyMetadataKey = "_legacy"
+
+// metadataKeyPattern 元数据过滤路径中允许的键名
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Metadata 实体的自定义元数据，以 JSON 对象存储，可按键查询
+type Metadata map[string]any
+
+// UnmarshalJSON 接受 JSON 对象或 null；为兼容旧版接口，也接受内容为 JSON 对象的字符串
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		if strings.TrimSpace(text) == "" {
+			*m = nil
+			return nil
+		}
+		data = []byte(text)
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("%w: must be a JSON object", ErrInvalidMetadata)
+	}
+	*m = object
+	return nil
+}
+
+// Value 写入数据库的 JSON 文本，空元数据为 NULL
+func (m Metadata) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]any(m))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 读取数据库中的 JSON 文本；旧版写入的非 JSON 对象文本保留在 _legacy 键中
+func (m *Metadata) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("%w: unsupported column value %T", ErrInvalidMetadata, value)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		*m = nil
+		return nil
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		*m = Metadata{legacyMetadataKey: string(data)}
+		return nil
+	}
+	*m = object
+	return nil
+}
+
+// GormDataType 通用的列类型
+func (Metadata) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType 各数据库的 JSON 列类型；SQLite 以文本保存，由 JSON 函数查询
+func (Metadata) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "JSONB"
+	case "mysql":
+		return "JSON"
+	default:
+		return "TEXT"
+	}
+}
+
+// MetadataField 元数据模式中的一个键
+type MetadataField struct {
+	Type        string   `json:"type,omitempty" yaml:"type"` // string, number, integer, boolean, array, object；为空时不限类型
+	Required    bool     `json:"required,omitempty" yaml:"required"`
+	Enum        []string `json:"enum,omitempty" yaml:"enum"`       // 字符串的可选值
+	Pattern     string   `json:"pattern,omitempty" yaml:"pattern"` // 字符串须匹配的正则表达式
+	Description string   `json:"description,omitempty" yaml:"description"`
+
+	pattern *regexp.Regexp
+}
+
+// MetadataSchema 一种实体的元数据模式
+type MetadataSchema struct {
+	Fields map[string]*MetadataField `json:"fields" yaml:"fields"`
+	Strict bool                      `json:"strict,omitempty" yaml:"strict"` // 拒绝 fields 以外的键
+}
+
+// MetadataSchemas 按实体类型（project、group、host、port）索引的元数据模式
+type MetadataSchemas map[string]*MetadataSchema
+
+// LoadMetadataSchemas 读取 JSON 或 YAML 格式的元数据模式文件
+func LoadMetadataSchemas(path string) (MetadataSchemas, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata schemas: %w", err)
+	}
+	var schemas MetadataSchemas
+	// YAML is a superset of JSON
+	if err := yaml.Unmarshal(data, &schemas); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	if err := schemas.Compile(); err != nil {
+		return nil, err
+	}
+	return schemas, nil
+}
+
+// Compile 检查模式并编译其中的正则表达式
+func (s MetadataSchemas) Compile() error {
+	for entity, entitySchema := range s {
+		switch entity {
+		case EntityProject, EntityGroup, EntityHost, EntityPort:
+		default:
+			return fmt.Errorf("%w: unknown entity type %q", ErrInvalidMetadataSchema, entity)
+		}
+		if entitySchema == nil {
+			continue
+		}
+		for key, field := range entitySchema.Fields {
+			if field == nil {
+				return fmt.Errorf("%w: %s.%s has no definition", ErrInvalidMetadataSchema, entity, key)
+			}
+			switch field.Type {
+			case "", "string", "number", "integer", "boolean", "array", "object":
+			default:
+				return fmt.Errorf("%w: %s.%s has unknown type %q", ErrInvalidMetadataSchema, entity, key, field.Type)
+			}
+			if (len(field.Enum) > 0 || field.Pattern != "") && field.Type != "string" {
+				return fmt.Errorf("%w: %s.%s: enum and pattern need type string", ErrInvalidMetadataSchema, entity, key)
+			}
+			if field.Pattern != "" {
+				pattern, err := regexp.Compile(field.Pattern)
+				if err != nil {
+					return fmt.Errorf("%w: %s.%s: %v", ErrInvalidMetadataSchema, entity, key, err)
+				}
+				field.pattern = pattern
+			}
+		}
+	}
+	return nil
+}
+
+// Validate 按实体类型的模式检查元数据；没有模式的实体接受任意 JSON 对象
+func (s MetadataSchemas) Validate(entity string, metadata Metadata) error {
+	entitySchema := s[entity]
+	if entitySchema == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(entitySchema.Fields))
+	for key := range entitySchema.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		field := entitySchema.Fields[key]
+		value, ok := metadata[key]
+		if !ok || value == nil {
+			if field.Required {
+				return fmt.Errorf("%w: %s is required", ErrInvalidMetadata, key)
+			}
+			continue
+		}
+		if err := field.check(value); err != nil {
+			return fmt.Errorf("%w: %s %v", ErrInvalidMetadata, key, err)
+		}
+	}
+
+	if entitySchema.Strict {
+		for key := range metadata {
+			if _, ok := entitySchema.Fields[key]; !ok {
+				return fmt.Errorf("%w: unknown key %s", ErrInvalidMetadata, key)
+			}
+		}
+	}
+	return nil
+}
+
+// check validates one value against its field definition
+func (f *MetadataField) check(value any) error {
+	switch f.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		if len(f.Enum) > 0 && !containsString(f.Enum, s) {
+			return fmt.Errorf("must be one of %s", strings.Join(f.Enum, ", "))
+		}
+		if f.pattern != nil && !f.pattern.MatchString(s) {
+			return fmt.Errorf("must match %s", f.Pattern)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return errors.New("must be a number")
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return errors.New("must be an integer")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return errors.New("must be a boolean")
+		}
+	case "array":
+		if _, ok := value.([]any); !ok {
+			return errors.New("must be an array")
+		}
+	case "object":
+		if _, ok := value.(map[string]any); !ok {
+			return errors.New("must be an object")
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// MetadataFilter 按元数据键过滤：Path 处的值等于 Values 之一
+type MetadataFilter struct {
+	Path   []string // 嵌套的键，如 metadata.team.name 为 [team name]
+	Values []string
+}
+
+// ParseMetadataFilters 从查询参数中取出 metadata.<键>=<值> 过滤条件，按键排序；
+// 同一个键给出多个值时匹配其中任意一个
+func ParseMetadataFilters(query url.Values) ([]MetadataFilter, error) {
+	var filters []MetadataFilter
+	for name, values := range query {
+		if !strings.HasPrefix(name, MetadataQueryPrefix) {
+			continue
+		}
+		path := strings.Split(strings.TrimPrefix(name, MetadataQueryPrefix), ".")
+		for _, key := range path {
+			if !metadataKeyPattern.MatchString(key) {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidMetadataFilter, name)
+			}
+		}
+		filters = append(filters, MetadataFilter{Path: path, Values: values})
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return strings.Join(filters[i].Path, ".") < strings.Join(filters[j].Path, ".")
+	})
+	return filters, nil
+}
+
+// JSONPath 过滤键的 JSON 路径，如 $."team"."name"
+func (f MetadataFilter) JSONPath() string {
+	var path strings.Builder
+	path.WriteString("$")
+	for _, key := range f.Path {
+		path.WriteString(`."` + key + `"`)
+	}
+	return path.String()
+}
+
+// JSONValues 过滤值可能的 JSON 文本：字符串形式，以及值本身是数字、true、false 或 null 时的原样形式，
+// 因此 metadata.replicas=3 既匹配 3 也匹配 "3"
+func (f MetadataFilter) JSONValues() []string {
+	var candidates []string
+	for _, value := range f.Values {
+		quoted, _ := json.Marshal(value)
+		candidates = append(candidates, string(quoted))
+		switch value {
+		case "true", "false", "null":
+			candidates = append(candidates, value)
+			continue
+		}
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			candidates = append(candidates, strconv.FormatFloat(n, 'f', -1, 64))
+		}
+	}
+	return candidates
+}
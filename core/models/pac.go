@@ -0,0 +1,86 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ErrInvalidPACEntry is returned for domains or networks a PAC file cannot express
+var ErrInvalidPACEntry = errors.New("invalid PAC entry")
+
+// PACPath is where a dynamic tunnel serves its proxy auto-config file
+const PACPath = "/proxy.pac"
+
+// PACContentType is the MIME type browsers expect for PAC files
+const PACContentType = "application/x-ns-proxy-autoconfig"
+
+// PACConfig 代理自动配置（PAC）：动态隧道在 SOCKS 端口的 /proxy.pac 提供 PAC 文件，
+// 浏览器只需设置该地址，匹配的域名和网段经隧道访问，其余直连；两者都为空时全部经隧道
+type PACConfig struct {
+	Domains  []string `json:"domains,omitempty"`  // 如 corp.example.com（含子域名）或 *.internal
+	Networks []string `json:"networks,omitempty"` // CIDR，如 10.0.0.0/8
+}
+
+// Validate 验证 PAC 配置
+func (c PACConfig) Validate() error {
+	for _, domain := range c.Domains {
+		if domain == "" || strings.ContainsAny(domain, "\"'\\ \t\r\n") {
+			return fmt.Errorf("%w: domain %q", ErrInvalidPACEntry, domain)
+		}
+	}
+	for _, network := range c.Networks {
+		if _, err := netip.ParsePrefix(network); err != nil {
+			return fmt.Errorf("%w: network %q: %v", ErrInvalidPACEntry, network, err)
+		}
+	}
+	return nil
+}
+
+// File returns the PAC script sending matching hosts to the SOCKS proxy at
+// proxyAddr (host:port) and everything else direct
+func (c PACConfig) File(proxyAddr string, socksVersion int) (string, error) {
+	proxy := "SOCKS " + proxyAddr
+	if socksVersion == 5 {
+		// SOCKS is kept as a fallback for clients that do not know SOCKS5
+		proxy = fmt.Sprintf("SOCKS5 %s; SOCKS %s", proxyAddr, proxyAddr)
+	}
+
+	var b strings.Builder
+	b.WriteString("// Generated by PortFly\n")
+	b.WriteString("function FindProxyForURL(url, host) {\n")
+	fmt.Fprintf(&b, "  var proxy = %q;\n", proxy)
+
+	if len(c.Domains) == 0 && len(c.Networks) == 0 {
+		b.WriteString("  return proxy;\n}\n")
+		return b.String(), nil
+	}
+
+	for _, domain := range c.Domains {
+		if strings.ContainsAny(domain, "*?") {
+			fmt.Fprintf(&b, "  if (shExpMatch(host, %q)) return proxy;\n", domain)
+			continue
+		}
+		domain = strings.TrimPrefix(domain, ".")
+		fmt.Fprintf(&b, "  if (host == %q || dnsDomainIs(host, %q)) return proxy;\n", domain, "."+domain)
+	}
+	for _, network := range c.Networks {
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return "", fmt.Errorf("%w: network %q: %v", ErrInvalidPACEntry, network, err)
+		}
+		prefix = prefix.Masked()
+		if prefix.Addr().Is4() {
+			mask := net.IP(net.CIDRMask(prefix.Bits(), 32)).String()
+			fmt.Fprintf(&b, "  if (isInNet(host, %q, %q)) return proxy;\n", prefix.Addr().String(), mask)
+			continue
+		}
+		// IPv6 needs the isInNetEx extension (Chromium, Internet Explorer)
+		fmt.Fprintf(&b, "  if (typeof isInNetEx == \"function\" && isInNetEx(host, %q)) return proxy;\n", prefix.String())
+	}
+
+	b.WriteString("  return \"DIRECT\";\n}\n")
+	return b.String(), nil
+}
@@ -26,7 +26,7 @@ type PortForward struct {
 
 		// 元数据
 	Tags     []string `gorm:"type:text;serializer:json" json:"tags,omitempty"`
-	Metadata string   `gorm:"type:text" json:"metadata,omitempty"` // JSON string
+	Metadata Metadata   `json:"metadata,omitempty"` // 自定义元数据，可按 metadata.<键> 查询
 
 	// 外键
 	GroupID uint  `gorm:"not null;index" json:"group_id"`
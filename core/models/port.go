@@ -3,6 +3,9 @@ package models
 import (
 	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
@@ -14,6 +17,14 @@ var (
 	ErrInvalidPort     = errors.New("port number must be between 1 and 65535")
 	ErrInvalidPortType = errors.New("invalid port type")
 	ErrGroupRequired   = errors.New("group ID is required")
+	ErrNumericName     = errors.New("name cannot be a number, it would be ambiguous with an ID")
+
+	ErrRemoteBindOnLocalPort = errors.New("remote_bind_address only applies to remote_port")
+	ErrRemoteBindNotAllowed  = errors.New("non-loopback remote_bind_address requires allow_remote_connections")
+	ErrReusePortOnRemotePort = errors.New("reuse_port does not apply to remote_port, which listens on the SSH server")
+
+	ErrCandidatesWithoutHost = errors.New("candidate_host_ids requires host_id")
+	ErrDuplicateCandidate    = errors.New("candidate_host_ids lists a host twice or repeats host_id")
 )
 
 // PortType 端口类型
@@ -33,6 +44,7 @@ const (
 	PortStatusActive      PortStatus = "active"      // 活跃（正在转发）
 	PortStatusError       PortStatus = "error"       // 错误
 	PortStatusConnecting  PortStatus = "connecting"  // 连接中
+	PortStatusBackoff     PortStatus = "backoff"     // 退避等待重连
 )
 
 // Port 端口配置 - 独立模型
@@ -43,14 +55,23 @@ type Port struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	// 基本信息
-	Name        string     `gorm:"not null;size:100" json:"name"`
-	Type        PortType   `gorm:"not null;size:20" json:"type"`
-	Port        int        `gorm:"not null" json:"port"`
-	BindAddress string     `gorm:"size:255;default:127.0.0.1" json:"bind_address"`
-	Description string     `gorm:"size:500" json:"description"`
+	Name        string      `gorm:"not null;size:100" json:"name"`
+	Type        PortType    `gorm:"not null;size:20" json:"type"`
+	Port        int         `gorm:"not null" json:"port"`
+	PortEnd     int         `gorm:"default:0" json:"port_end,omitempty"` // 端口范围的最后一个端口，如 9000–9010 一次转发 11 个端口；为 0 时只有 Port
+	BindAddress string      `gorm:"size:255;default:127.0.0.1" json:"bind_address"`
+	ExtraBinds  []BindSpec  `gorm:"type:text;serializer:json" json:"extra_binds,omitempty"` // 额外监听地址，转发到同一目标
+	Description string      `gorm:"size:500" json:"description"`
+	ServiceType ServiceType `gorm:"size:20" json:"service_type,omitempty"` // 目标服务类型，为空时按端口号推断
+
+	// 远程转发（remote_port）监听配置，对应 OpenSSH 服务端 GatewayPorts
+	RemoteBindAddress      string `gorm:"size:255" json:"remote_bind_address,omitempty"`   // 服务端监听地址，为空时由 allow_remote_connections 决定
+	AllowRemoteConnections bool   `gorm:"default:false" json:"allow_remote_connections"` // 允许非本机连接（需服务端 GatewayPorts yes/clientspecified）
 
 	// 状态信息
 	Status         PortStatus `gorm:"size:20;default:unavailable" json:"status"`
+	StatusMessage  string     `gorm:"size:255" json:"status_message,omitempty"` // 状态说明，如 "backing off, retry in 30s"
+	LastError      PortError  `gorm:"embedded;embeddedPrefix:last_error_" json:"last_error"` // 最近一次错误（分类、说明、时间、是否可重试）
 	LastTested     *time.Time `json:"last_tested,omitempty"`
 	LastActive     *time.Time `json:"last_active,omitempty"`
 	ConnectionTest bool       `gorm:"default:false" json:"connection_test"` // Host连线测试结果
@@ -61,11 +82,34 @@ type Port struct {
 	IsVisible bool   `gorm:"default:true" json:"is_visible"`
 
 	// 配置选项
-	AutoStart bool `gorm:"default:false" json:"auto_start"`
+	AutoStart    bool `gorm:"default:false" json:"auto_start"`
+	RequireGrant bool `gorm:"default:false" json:"require_grant"` // 仅持有有效临时授权（PortGrant）时才能启动
+
+	// 公开状态页（无需认证，只显示名称、状态、延迟和可用率）
+	Published  bool   `gorm:"default:false" json:"published"`        // 在状态页上显示
+	PublicName string `gorm:"size:100" json:"public_name,omitempty"` // 状态页上的名称，为空时使用 name
+
+	// 重连策略
+	RetryPolicy RetryPolicy `gorm:"embedded;embeddedPrefix:retry_" json:"retry_policy"`
+
+	// TCP 套接字选项
+	SocketOptions SocketOptions `gorm:"embedded;embeddedPrefix:socket_" json:"socket_options"`
+
+	// 目标域名的定期重新解析（仅远程端口）
+	DNSRefresh DNSRefresh `gorm:"embedded;embeddedPrefix:dns_refresh_" json:"dns_refresh"`
+
+	// 调试抓包
+	Capture CaptureConfig `gorm:"embedded;embeddedPrefix:capture_" json:"capture"`
+
+	// 启动、停止时执行的命令或 Webhook（仅远程端口）
+	Hooks []PortHook `gorm:"type:text;serializer:json" json:"hooks,omitempty"`
+
+	// 探测到的 Web 服务信息（仅本地 HTTP/HTTPS 端口）
+	Web WebInfo `gorm:"embedded;embeddedPrefix:web_" json:"web"`
 
 	// 元数据
 	Tags     []string `gorm:"type:text;serializer:json" json:"tags,omitempty"`
-	Metadata string   `gorm:"type:text" json:"metadata,omitempty"` // JSON string
+	Metadata Metadata   `json:"metadata,omitempty"` // 自定义元数据，可按 metadata.<键> 查询
 
 	// 外键关联
 	GroupID uint  `gorm:"not null;index" json:"group_id"`
@@ -75,6 +119,11 @@ type Port struct {
 	HostID *uint `gorm:"index" json:"host_id,omitempty"`
 	Host   *Host `gorm:"constraint:OnDelete:SET NULL" json:"host,omitempty"`
 
+	// 冗余主机：同一服务部署在多台主机上时，启动时选择延迟最低的可达主机，
+	// 正在使用的主机不可达时自动切换到其他候选主机
+	CandidateHostIDs []uint `gorm:"type:text;serializer:json" json:"candidate_host_ids,omitempty"`
+	ActiveHostID     *uint  `json:"active_host_id,omitempty"` // 当前使用的主机，未启用冗余时为空
+
 	// 端口转发关联（Remote_Port -> Local_Port）
 	// 如果当前是Remote_Port，则指向目标Local_Port
 	TargetPortID *uint `gorm:"index" json:"target_port_id,omitempty"`
@@ -122,6 +171,10 @@ type PortConnection struct {
 	TunnelSessionID *uint            `gorm:"index" json:"tunnel_session_id,omitempty"`
 	TunnelSession   *TunnelSession   `gorm:"constraint:OnDelete:SET NULL" json:"tunnel_session,omitempty"`
 
+	// 运行该转发的服务实例与进程，重启后据此识别孤儿转发
+	Owner    string `gorm:"size:191;index" json:"owner,omitempty"`
+	OwnerPID int    `json:"owner_pid,omitempty"`
+
 	// 统计信息
 	Stats PortStats `gorm:"embedded;embeddedPrefix:stats_" json:"stats"`
 }
@@ -132,6 +185,10 @@ func (p *Port) Validate() error {
 		return ErrInvalidName
 	}
 
+	if IsNumericName(p.Name) {
+		return ErrNumericName
+	}
+
 	if p.Port <= 0 || p.Port > 65535 {
 		return ErrInvalidPort
 	}
@@ -140,13 +197,95 @@ func (p *Port) Validate() error {
 		return ErrInvalidPortType
 	}
 
+	if p.RemoteBindAddress != "" && !p.IsRemotePort() {
+		return ErrRemoteBindOnLocalPort
+	}
+
+	// 模板地址在启动解析时再检查
+	if p.IsRemotePort() && !p.AllowRemoteConnections && !IsTemplate(p.RemoteBindAddress) && !IsLoopbackAddress(p.GetRemoteBindAddress()) {
+		return fmt.Errorf("%w: %s", ErrRemoteBindNotAllowed, p.RemoteBindAddress)
+	}
+
+	if p.PortEnd != 0 && (p.PortEnd < p.Port || p.PortEnd > 65535 || p.PortCount() > MaxPortRange) {
+		return fmt.Errorf("%w: %d-%d (at most %d ports)", ErrInvalidPortRange, p.Port, p.PortEnd, MaxPortRange)
+	}
+
+	for _, bind := range p.ExtraBinds {
+		if bind.Port <= 0 || bind.Port+p.PortCount()-1 > 65535 {
+			return ErrInvalidPort
+		}
+	}
+
+	if err := p.ValidateTemplates(); err != nil {
+		return err
+	}
+
+	if p.ServiceType != "" && !p.ServiceType.IsValid() {
+		return fmt.Errorf("%w: %s", ErrInvalidServiceType, p.ServiceType)
+	}
+
 	if p.GroupID == 0 {
 		return ErrGroupRequired
 	}
 
+	if err := p.RetryPolicy.Validate(); err != nil {
+		return err
+	}
+
+	if err := p.SocketOptions.Validate(); err != nil {
+		return err
+	}
+	if p.IsRemotePort() && p.SocketOptions.ReusePort {
+		return ErrReusePortOnRemotePort
+	}
+
+	if err := p.Capture.Validate(); err != nil {
+		return err
+	}
+	if err := p.Capture.ValidateFileName(); err != nil {
+		return err
+	}
+
+	if err := p.DNSRefresh.Validate(); err != nil {
+		return err
+	}
+	if p.DNSRefresh.Enabled() && !p.IsRemotePort() {
+		return ErrDNSRefreshOnLocalPort
+	}
+
+	if err := ValidatePortHooks(p.Hooks); err != nil {
+		return err
+	}
+	if len(p.Hooks) > 0 && !p.IsRemotePort() {
+		return ErrHooksOnLocalPort
+	}
+
+	if len(p.CandidateHostIDs) > 0 {
+		if p.HostID == nil {
+			return ErrCandidatesWithoutHost
+		}
+		if len(p.HostIDs()) != len(p.CandidateHostIDs)+1 {
+			return ErrDuplicateCandidate
+		}
+	}
+
 	return nil
 }
 
+// HostIDs 返回端口可使用的主机：关联主机在前，其后为候选主机
+func (p *Port) HostIDs() []uint {
+	if p.HostID == nil {
+		return nil
+	}
+	ids := []uint{*p.HostID}
+	for _, id := range p.CandidateHostIDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 // IsRemotePort 检查是否为远程端口
 func (p *Port) IsRemotePort() bool {
 	return p.Type == PortTypeRemote
@@ -175,6 +314,19 @@ func (p *Port) GetDisplayName() string {
 	return fmt.Sprintf("%s:%d", p.Type, p.Port)
 }
 
+// IsNumericName 名称是否全为数字（与 ID 无法区分）
+func IsNumericName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // GetBindAddress 获取绑定地址
 func (p *Port) GetBindAddress() string {
 	if p.BindAddress != "" {
@@ -183,14 +335,95 @@ func (p *Port) GetBindAddress() string {
 	return "127.0.0.1"
 }
 
-// GetFullAddress 获取完整地址
+// GetRemoteBindAddress 获取远程转发在服务端的监听地址
+func (p *Port) GetRemoteBindAddress() string {
+	if p.RemoteBindAddress != "" {
+		return p.RemoteBindAddress
+	}
+	if p.AllowRemoteConnections {
+		return "0.0.0.0"
+	}
+	return "127.0.0.1"
+}
+
+// RemoteTunnelConfig 将远程端口映射为转发到目标本地端口的隧道配置
+func (p *Port) RemoteTunnelConfig(target *Port) (TunnelConfig, error) {
+	if !p.IsRemotePort() {
+		return TunnelConfig{}, ErrInvalidPortType
+	}
+
+	if target.PortEnd != 0 && target.PortCount() != p.PortCount() {
+		return TunnelConfig{}, fmt.Errorf("%w: %s forwards %d ports, %s listens on %d",
+			ErrPortRangeMismatch, p.GetDisplayName(), p.PortCount(), target.GetDisplayName(), target.PortCount())
+	}
+
+	config := TunnelConfig{
+		Type:                   TunnelTypeRemote,
+		RemoteBindAddress:      p.GetRemoteBindAddress(),
+		LocalPort:              p.Port,
+		LocalPortEnd:           p.PortEnd,
+		RemoteHost:             target.GetBindAddress(),
+		RemotePort:             target.Port,
+		AllowRemoteConnections: p.AllowRemoteConnections,
+		AdditionalBinds:        p.ExtraBinds,
+		Capture:                p.Capture.TunnelCapture(),
+		InspectHTTP:            target.ServiceType == ServiceHTTP,
+		SocketOptions:          p.SocketOptions,
+		DNSRefresh:             p.DNSRefresh.TunnelDNSRefresh(),
+	}
+	if err := config.Validate(); err != nil {
+		return TunnelConfig{}, err
+	}
+	return config, nil
+}
+
+// GetFullAddress 获取完整地址，端口范围显示为 host:first-last
 func (p *Port) GetFullAddress() string {
-	return fmt.Sprintf("%s:%d", p.GetBindAddress(), p.Port)
+	if p.PortEnd != 0 {
+		return net.JoinHostPort(p.GetBindAddress(), fmt.Sprintf("%d-%d", p.Port, p.PortEnd))
+	}
+	return net.JoinHostPort(p.GetBindAddress(), strconv.Itoa(p.Port))
+}
+
+// PortCount 端口范围包含的端口数，单个端口为 1
+func (p *Port) PortCount() int {
+	if p.PortEnd == 0 {
+		return 1
+	}
+	return p.PortEnd - p.Port + 1
+}
+
+// GetBinds 获取全部监听地址（主地址在前），端口范围按每个端口展开
+func (p *Port) GetBinds() []BindSpec {
+	binds := []BindSpec{{Address: p.GetBindAddress(), Port: p.Port}}
+	for _, bind := range p.ExtraBinds {
+		if bind.Address == "" {
+			bind.Address = "127.0.0.1"
+		}
+		binds = append(binds, bind)
+	}
+	if p.PortCount() <= 1 {
+		return binds
+	}
+
+	var expanded []BindSpec
+	for _, bind := range binds {
+		for offset := range p.PortCount() {
+			expanded = append(expanded, BindSpec{Address: bind.Address, Port: bind.Port + offset})
+		}
+	}
+	return expanded
+}
+
+// GetRetryPolicy 获取生效的重连策略
+func (p *Port) GetRetryPolicy() RetryPolicy {
+	return p.RetryPolicy.WithDefaults()
 }
 
 // UpdateStatus 更新端口状态
 func (p *Port) UpdateStatus(status PortStatus) {
 	p.Status = status
+	p.StatusMessage = ""
 	now := time.Now()
 	
 	switch status {
@@ -213,3 +446,9 @@ func (p *Port) SetConnectionTestResult(success bool) {
 		p.Status = PortStatusUnavailable
 	}
 }
+
+// SetBackoffStatus 设置退避等待状态
+func (p *Port) SetBackoffStatus(attempt int, delay time.Duration) {
+	p.Status = PortStatusBackoff
+	p.StatusMessage = FormatBackoffMessage(attempt, p.GetRetryPolicy().MaxAttempts, delay)
+}
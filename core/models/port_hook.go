@@ -0,0 +1,177 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Port hook events
+const (
+	HookEventStart = "start" // the tunnel is up; runs before the port is marked active
+	HookEventStop  = "stop"  // the tunnel was stopped
+)
+
+// Port hook failure policies
+const (
+	HookFailureIgnore = "ignore" // log the failure and carry on (default)
+	HookFailureBlock  = "block"  // a failed start hook stops the tunnel and fails the start
+)
+
+// Port hook limits
+const (
+	DefaultHookTimeout = 30 * time.Second
+	MaxHookTimeout     = 10 * time.Minute
+	MaxPortHooks       = 10
+)
+
+// Port hook validation errors
+var (
+	ErrInvalidHook      = errors.New("invalid port hook")
+	ErrHooksOnLocalPort = errors.New("hooks only apply to remote_port, which runs the tunnel")
+	// ErrLocalHooksDisabled 服务器未启用本机命令钩子
+	ErrLocalHooksDisabled = errors.New("local hook commands are disabled on this server")
+)
+
+// PortHook 端口启动或停止时执行的命令或调用的 Webhook。命令在服务器本机或经 SSH 在端口的主机上执行，
+// 事件以 PORTFLY_* 环境变量传入；Webhook 以 POST 发送 JSON 格式的事件。用于在服务网格中注册转发、更新 hosts 文件等
+type PortHook struct {
+	Name      string `json:"name,omitempty"`
+	Event     string `json:"event"`                // start 或 stop
+	Command   string `json:"command,omitempty"`    // 由 sh -c 执行，与 url 二选一
+	Remote    bool   `json:"remote,omitempty"`     // 命令在端口的主机上执行，而不是服务器本机
+	URL       string `json:"url,omitempty"`        // http 或 https 地址
+	Timeout   int    `json:"timeout,omitempty"`    // 秒，默认 30，最多 600
+	OnFailure string `json:"on_failure,omitempty"` // ignore（默认）或 block，block 只适用于 start
+}
+
+// DisplayName names the hook in logs and errors
+func (h PortHook) DisplayName() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	if h.URL != "" {
+		return h.Event + " webhook"
+	}
+	return h.Event + " command"
+}
+
+// GetTimeout returns how long the hook may run
+func (h PortHook) GetTimeout() time.Duration {
+	if h.Timeout == 0 {
+		return DefaultHookTimeout
+	}
+	return time.Duration(h.Timeout) * time.Second
+}
+
+// Local reports whether the hook runs a command on the server itself
+func (h PortHook) Local() bool {
+	return h.Command != "" && !h.Remote
+}
+
+// Blocks reports whether the hook failing fails the start
+func (h PortHook) Blocks() bool {
+	return h.OnFailure == HookFailureBlock
+}
+
+// Validate 验证钩子配置
+func (h PortHook) Validate() error {
+	name := h.DisplayName()
+	switch {
+	case h.Event != HookEventStart && h.Event != HookEventStop:
+		return fmt.Errorf("%w: %s: event must be %s or %s", ErrInvalidHook, name, HookEventStart, HookEventStop)
+	case (h.Command == "") == (h.URL == ""):
+		return fmt.Errorf("%w: %s: set exactly one of command and url", ErrInvalidHook, name)
+	case h.Remote && h.Command == "":
+		return fmt.Errorf("%w: %s: remote only applies to commands", ErrInvalidHook, name)
+	case h.Timeout < 0 || h.GetTimeout() > MaxHookTimeout:
+		return fmt.Errorf("%w: %s: timeout must be between 1 and %d seconds", ErrInvalidHook, name, int(MaxHookTimeout/time.Second))
+	case h.OnFailure != "" && h.OnFailure != HookFailureIgnore && h.OnFailure != HookFailureBlock:
+		return fmt.Errorf("%w: %s: on_failure must be %s or %s", ErrInvalidHook, name, HookFailureIgnore, HookFailureBlock)
+	case h.Blocks() && h.Event != HookEventStart:
+		return fmt.Errorf("%w: %s: only start hooks can block", ErrInvalidHook, name)
+	}
+	if h.URL != "" {
+		parsed, err := url.Parse(h.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("%w: %s: url must be an http or https URL", ErrInvalidHook, name)
+		}
+	}
+	return nil
+}
+
+// ValidatePortHooks 验证端口的所有钩子
+func ValidatePortHooks(hooks []PortHook) error {
+	if len(hooks) > MaxPortHooks {
+		return fmt.Errorf("%w: at most %d hooks per port", ErrInvalidHook, MaxPortHooks)
+	}
+	for _, hook := range hooks {
+		if err := hook.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PortHookEvent 传给钩子的事件：命令的环境变量，Webhook 的请求体
+type PortHookEvent struct {
+	Event    string    `json:"event"`
+	PortID   uint      `json:"port_id"`
+	PortName string    `json:"port_name"`
+	Address  string    `json:"address"`           // 隧道在主机上的监听地址
+	Target   string    `json:"target"`            // 转发到的本地地址
+	HostID   uint      `json:"host_id,omitempty"` // 运行隧道的主机
+	Host     string    `json:"host,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// Env returns the event as PORTFLY_* environment variables
+func (e PortHookEvent) Env() []string {
+	env := []string{
+		"PORTFLY_EVENT=" + e.Event,
+		"PORTFLY_PORT_ID=" + strconv.FormatUint(uint64(e.PortID), 10),
+		"PORTFLY_PORT_NAME=" + e.PortName,
+		"PORTFLY_ADDRESS=" + e.Address,
+		"PORTFLY_TARGET=" + e.Target,
+	}
+	if e.HostID != 0 {
+		env = append(env,
+			"PORTFLY_HOST_ID="+strconv.FormatUint(uint64(e.HostID), 10),
+			"PORTFLY_HOST="+e.Host)
+	}
+	return env
+}
+
+// PortHookResult 一个钩子的执行结果
+type PortHookResult struct {
+	Hook     string `json:"hook"`
+	Event    string `json:"event"`
+	Success  bool   `json:"success"`
+	ExitCode int    `json:"exit_code,omitempty"` // 命令的退出码
+	Status   int    `json:"status,omitempty"`    // Webhook 的 HTTP 状态码
+	Output   string `json:"output,omitempty"`    // 命令输出或响应正文的开头
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration"` // 毫秒
+}
+
+// Summary describes a failed result in one line
+func (r PortHookResult) Summary() string {
+	message := r.Error
+	if message == "" {
+		if r.Status != 0 {
+			message = fmt.Sprintf("HTTP %d", r.Status)
+		} else {
+			message = fmt.Sprintf("exit status %d", r.ExitCode)
+		}
+	}
+	if output := strings.TrimSpace(r.Output); output != "" {
+		if i := strings.LastIndexByte(output, '\n'); i >= 0 {
+			output = output[i+1:]
+		}
+		message += ": " + output
+	}
+	return r.Hook + ": " + message
+}
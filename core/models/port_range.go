@@ -0,0 +1,121 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+)
+
+// MaxPortRange is the largest number of ports one forward may cover
+const MaxPortRange = 1024
+
+// Port range validation errors
+var (
+	ErrInvalidPortRange  = errors.New("invalid port range")
+	ErrPortRangeMismatch = errors.New("port range sizes differ")
+)
+
+// BindRange is a range of consecutive ports a tunnel listens on
+type BindRange struct {
+	Address   string `json:"address"`
+	FirstPort int    `json:"first_port"`
+	LastPort  int    `json:"last_port"`
+}
+
+// String returns the range as host:first-last, bracketing IPv6 literals
+func (r BindRange) String() string {
+	return net.JoinHostPort(r.Address, fmt.Sprintf("%d-%d", r.FirstPort, r.LastPort))
+}
+
+// Contains reports whether a listener address (host:port) belongs to the
+// range. Remote forwards request "*" as 0.0.0.0.
+func (r BindRange) Contains(listenerAddr string) bool {
+	host, portStr, err := net.SplitHostPort(listenerAddr)
+	if err != nil {
+		return false
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < r.FirstPort || port > r.LastPort {
+		return false
+	}
+	return host == r.Address || (r.Address == "*" && host == "0.0.0.0")
+}
+
+// PortCount returns how many consecutive ports each bind covers
+func (tc TunnelConfig) PortCount() int {
+	if tc.LocalPortEnd == 0 {
+		return 1
+	}
+	return tc.LocalPortEnd - tc.LocalPort + 1
+}
+
+// PortRanges returns the port range of each bind, the primary first; nil
+// when the tunnel forwards single ports
+func (tc TunnelConfig) PortRanges() []BindRange {
+	count := tc.PortCount()
+	if count == 1 {
+		return nil
+	}
+	var ranges []BindRange
+	for _, bind := range tc.baseBinds() {
+		ranges = append(ranges, BindRange{Address: bind.Address, FirstPort: bind.Port, LastPort: bind.Port + count - 1})
+	}
+	return ranges
+}
+
+// PortOffset returns the position of bind within its port range, 0 for
+// tunnels forwarding single ports
+func (tc TunnelConfig) PortOffset(bind BindSpec) int {
+	count := tc.PortCount()
+	for _, base := range tc.baseBinds() {
+		if base.Address == bind.Address && bind.Port >= base.Port && bind.Port < base.Port+count {
+			return bind.Port - base.Port
+		}
+	}
+	return 0
+}
+
+// AtOffset returns the configuration for the listener at offset within the
+// range: its targets are shifted by the same offset
+func (tc TunnelConfig) AtOffset(offset int) TunnelConfig {
+	if offset == 0 {
+		return tc
+	}
+	tc.RemotePort += offset
+	tc.Targets = slices.Clone(tc.Targets)
+	for i := range tc.Targets {
+		tc.Targets[i].Port += offset
+	}
+	return tc
+}
+
+// portSpan formats port, or the range starting at port for ranged tunnels
+func (tc TunnelConfig) portSpan(port int) string {
+	if count := tc.PortCount(); count > 1 {
+		return fmt.Sprintf("%d-%d", port, port+count-1)
+	}
+	return strconv.Itoa(port)
+}
+
+// validatePortRange checks that the listener and target ranges fit
+func (tc TunnelConfig) validatePortRange() error {
+	if tc.LocalPortEnd == 0 {
+		return nil
+	}
+	if tc.Type == TunnelTypeDynamic {
+		return fmt.Errorf("%w: port ranges only apply to local and remote forwarding", ErrInvalidPortRange)
+	}
+	count := tc.PortCount()
+	if count < 1 || count > MaxPortRange {
+		return fmt.Errorf("%w: %d-%d (at most %d ports, first port first)", ErrInvalidPortRange, tc.LocalPort, tc.LocalPortEnd, MaxPortRange)
+	}
+	targets := append([]TargetSpec{{Host: tc.RemoteHost, Port: tc.RemotePort}}, tc.Targets...)
+	for _, target := range targets {
+		if target.Port+count-1 > 65535 {
+			return fmt.Errorf("%w: target %s-%d exceeds 65535", ErrInvalidPortRange, target, target.Port+count-1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Port reservation errors
+var (
+	ErrPortReserved       = errors.New("local port is reserved by another project or user")
+	ErrNoFreePort         = errors.New("no free local port left in the allocation range")
+	ErrInvalidPortPool    = errors.New("invalid port allocation range")
+	ErrInvalidReservation = errors.New("invalid port reservation")
+)
+
+// DefaultPortPool 未配置分配范围时自动分配本地端口使用的端口段
+var DefaultPortPool = PortPool{First: 20000, Last: 29999}
+
+// PortReservation 本地端口预留，避免不同项目或用户的端口相互冲突
+// 本地端口创建时自动预留其端口号；也可手动预留，供不在 PortFly 中配置的转发使用
+type PortReservation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Port      int    `gorm:"not null;index" json:"port"`
+	PortEnd   int    `gorm:"default:0" json:"port_end,omitempty"` // 端口段的最后一个端口，为 0 时只有 Port
+	ProjectID uint   `gorm:"not null;index" json:"project_id"`
+	Owner     string `gorm:"size:255;index" json:"owner,omitempty"` // 预留的用户（X-PortFly-Actor），为空时由项目共用
+	PortID    *uint  `gorm:"uniqueIndex" json:"port_id,omitempty"`  // 持有预留的端口，手动预留时为空
+	Note      string `gorm:"size:500" json:"note,omitempty"`
+}
+
+// LastPort 预留的最后一个端口
+func (r *PortReservation) LastPort() int {
+	if r.PortEnd == 0 {
+		return r.Port
+	}
+	return r.PortEnd
+}
+
+// Size 预留的端口数
+func (r *PortReservation) Size() int {
+	return r.LastPort() - r.Port + 1
+}
+
+// Overlaps 预留是否与端口段 first–last 重叠
+func (r *PortReservation) Overlaps(first, last int) bool {
+	return r.Port <= last && first <= r.LastPort()
+}
+
+// ConflictsWith 预留是否属于其他项目或其他用户；同一项目中未指定用户的预留由项目共用
+func (r *PortReservation) ConflictsWith(projectID uint, owner string) bool {
+	if r.ProjectID != projectID {
+		return true
+	}
+	return r.Owner != "" && owner != "" && r.Owner != owner
+}
+
+// Validate 验证预留
+func (r *PortReservation) Validate() error {
+	if r.ProjectID == 0 {
+		return fmt.Errorf("%w: project_id is required", ErrInvalidReservation)
+	}
+	if r.Port <= 0 || r.Port > 65535 {
+		return fmt.Errorf("%w: %w", ErrInvalidReservation, ErrInvalidPort)
+	}
+	if r.PortEnd != 0 && (r.PortEnd < r.Port || r.PortEnd > 65535 || r.Size() > MaxPortRange) {
+		return fmt.Errorf("%w: %d-%d", ErrInvalidPortRange, r.Port, r.PortEnd)
+	}
+	return nil
+}
+
+// String 预留的端口，端口段显示为 first-last
+func (r *PortReservation) String() string {
+	if r.PortEnd == 0 {
+		return strconv.Itoa(r.Port)
+	}
+	return fmt.Sprintf("%d-%d", r.Port, r.PortEnd)
+}
+
+// PortPool 自动分配本地端口的端口段，可限定项目和用户
+type PortPool struct {
+	ProjectID uint   `json:"project_id,omitempty"` // 为 0 时适用于所有项目
+	Owner     string `json:"owner,omitempty"`      // 为空时适用于所有用户
+	First     int    `json:"first"`
+	Last      int    `json:"last"`
+}
+
+// Validate 验证端口段
+func (p PortPool) Validate() error {
+	if p.First <= 0 || p.Last > 65535 || p.Last < p.First {
+		return fmt.Errorf("%w: %d-%d", ErrInvalidPortPool, p.First, p.Last)
+	}
+	return nil
+}
+
+// String 端口段的配置形式，如 project:3+user:alice=20000-20999
+func (p PortPool) String() string {
+	var scope []string
+	if p.ProjectID != 0 {
+		scope = append(scope, fmt.Sprintf("project:%d", p.ProjectID))
+	}
+	if p.Owner != "" {
+		scope = append(scope, "user:"+p.Owner)
+	}
+	if len(scope) == 0 {
+		scope = append(scope, "default")
+	}
+	return fmt.Sprintf("%s=%d-%d", strings.Join(scope, "+"), p.First, p.Last)
+}
+
+// PortPlan 自动分配的预演结果，不会预留端口
+type PortPlan struct {
+	Pool  PortPool `json:"pool"`
+	Size  int      `json:"size"`
+	Ports []int    `json:"ports"` // 接下来依次分配的端口块的第一个端口
+}
+
+// ParsePortPools 解析逗号分隔的端口段，如
+// "project:3=20000-20999,user:alice=21000-21099,project:3+user:bob=21100-21199,default=30000-39999"
+func ParsePortPools(value string) ([]PortPool, error) {
+	var pools []PortPool
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		scope, span, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("%w: %q, expected <scope>=<first>-<last>", ErrInvalidPortPool, entry)
+		}
+
+		var pool PortPool
+		for _, part := range strings.Split(scope, "+") {
+			kind, name, _ := strings.Cut(strings.TrimSpace(part), ":")
+			switch {
+			case kind == "default" && name == "":
+			case kind == "project":
+				id, err := strconv.ParseUint(name, 10, 32)
+				if err != nil || id == 0 {
+					return nil, fmt.Errorf("%w: invalid project in %q", ErrInvalidPortPool, entry)
+				}
+				pool.ProjectID = uint(id)
+			case kind == "user" && name != "":
+				pool.Owner = name
+			default:
+				return nil, fmt.Errorf("%w: unknown scope %q, expected project:<id>, user:<name> or default", ErrInvalidPortPool, part)
+			}
+		}
+
+		first, last, _ := strings.Cut(strings.TrimSpace(span), "-")
+		var err error
+		if pool.First, err = strconv.Atoi(first); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPortPool, entry)
+		}
+		if pool.Last, err = strconv.Atoi(last); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPortPool, entry)
+		}
+		if err := pool.Validate(); err != nil {
+			return nil, err
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+// SelectPortPool 选择项目和用户使用的端口段：同时匹配项目和用户的最优先，
+// 其次是用户、项目，最后是默认端口段；都没有时使用 DefaultPortPool
+func SelectPortPool(pools []PortPool, projectID uint, owner string) PortPool {
+	best, bestScore := DefaultPortPool, -1
+	for _, pool := range pools {
+		if (pool.ProjectID != 0 && pool.ProjectID != projectID) || (pool.Owner != "" && pool.Owner != owner) {
+			continue
+		}
+		score := 0
+		if pool.Owner != "" {
+			score += 2
+		}
+		if pool.ProjectID != 0 {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = pool, score
+		}
+	}
+	return best
+}
+
+// FreePortBlocks 在端口段中找出前 count 个未被占用的连续端口块（每块 size 个端口），
+// 返回每块的第一个端口；used 为已占用的端口段 [first, last]
+func FreePortBlocks(pool PortPool, used [][2]int, size, count int) []int {
+	sort.Slice(used, func(i, j int) bool { return used[i][0] < used[j][0] })
+
+	var blocks []int
+	next := pool.First
+	for _, span := range used {
+		for next+size-1 < span[0] && next+size-1 <= pool.Last && len(blocks) < count {
+			blocks = append(blocks, next)
+			next += size
+		}
+		if span[1] >= next {
+			next = span[1] + 1
+		}
+	}
+	for next+size-1 <= pool.Last && len(blocks) < count {
+		blocks = append(blocks, next)
+		next += size
+	}
+	return blocks
+}
+
+// ReservationConflict 返回与本地端口冲突的其他项目或用户的预留，没有冲突时返回 nil
+// 端口自己持有的预留决定其用户；远程端口不占用本地端口，不会冲突
+func ReservationConflict(reservations []PortReservation, port *Port, projectID uint) *PortReservation {
+	if port.Type != PortTypeLocal {
+		return nil
+	}
+	owner := ""
+	for i := range reservations {
+		if reservations[i].PortID != nil && *reservations[i].PortID == port.ID {
+			owner = reservations[i].Owner
+		}
+	}
+	last := port.Port
+	if port.PortEnd != 0 {
+		last = port.PortEnd
+	}
+	for i := range reservations {
+		r := &reservations[i]
+		if r.PortID != nil && *r.PortID == port.ID {
+			continue
+		}
+		if r.Overlaps(port.Port, last) && r.ConflictsWith(projectID, owner) {
+			return r
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Port status errors
+var (
+	ErrInvalidPortStatus     = errors.New("invalid port status")
+	ErrInvalidPortTransition = errors.New("invalid port status transition")
+	ErrPortStatusChanged     = errors.New("port status changed concurrently")
+)
+
+// portTransitions 允许的状态迁移；同一状态的重复设置始终允许
+var portTransitions = map[PortStatus][]PortStatus{
+	PortStatusAvailable:   {PortStatusConnecting, PortStatusActive, PortStatusUnavailable, PortStatusError},
+	PortStatusUnavailable: {PortStatusAvailable, PortStatusConnecting, PortStatusActive, PortStatusError},
+	PortStatusConnecting:  {PortStatusActive, PortStatusBackoff, PortStatusError, PortStatusAvailable, PortStatusUnavailable},
+	PortStatusActive:      {PortStatusConnecting, PortStatusBackoff, PortStatusError, PortStatusAvailable, PortStatusUnavailable},
+	PortStatusBackoff:     {PortStatusConnecting, PortStatusActive, PortStatusError, PortStatusAvailable},
+	PortStatusError:       {PortStatusConnecting, PortStatusActive, PortStatusAvailable, PortStatusUnavailable},
+}
+
+// IsValid 是否为已定义的端口状态
+func (s PortStatus) IsValid() bool {
+	_, ok := portTransitions[s]
+	return ok
+}
+
+// CanTransitionTo 是否允许从当前状态迁移到 next；空状态（新端口）可迁移到任意状态
+func (s PortStatus) CanTransitionTo(next PortStatus) bool {
+	if !next.IsValid() {
+		return false
+	}
+	if s == "" || s == next {
+		return true
+	}
+	for _, allowed := range portTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// PortErrorCode 端口错误原因分类
+type PortErrorCode string
+
+const (
+	PortErrorBindFailed    PortErrorCode = "bind_failed"    // 本地监听失败，如端口被占用
+	PortErrorAuthFailed    PortErrorCode = "auth_failed"    // SSH 认证失败
+	PortErrorConnectFailed PortErrorCode = "connect_failed" // 无法连接主机或目标
+	PortErrorRemoteRefused PortErrorCode = "remote_refused" // 服务端拒绝远程转发
+	PortErrorDNSFailed     PortErrorCode = "dns_failed"     // 主机名解析失败
+	PortErrorTimeout       PortErrorCode = "timeout"        // 连接或握手超时
+	PortErrorHostKey       PortErrorCode = "host_key"       // 主机密钥校验失败
+	PortErrorUnknown       PortErrorCode = "unknown"
+)
+
+// IsValid 是否为已定义的错误分类
+func (c PortErrorCode) IsValid() bool {
+	switch c {
+	case PortErrorBindFailed, PortErrorAuthFailed, PortErrorConnectFailed, PortErrorRemoteRefused,
+		PortErrorDNSFailed, PortErrorTimeout, PortErrorHostKey, PortErrorUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// Retryable 该类错误重试后是否可能恢复
+func (c PortErrorCode) Retryable() bool {
+	switch c {
+	case PortErrorConnectFailed, PortErrorDNSFailed, PortErrorTimeout, PortErrorUnknown:
+		return true
+	default:
+		// Bind, auth, refusal and host key errors need a configuration change
+		return false
+	}
+}
+
+// PortError 端口最近一次错误
+type PortError struct {
+	Code      PortErrorCode `gorm:"size:40" json:"code,omitempty"`
+	Message   string        `gorm:"size:500" json:"message,omitempty"`
+	Timestamp *time.Time    `json:"timestamp,omitempty"`
+	Retryable bool          `gorm:"default:false" json:"retryable"`
+}
+
+// ClassifyPortError 根据错误类型与内容归类
+func ClassifyPortError(err error) PortError {
+	now := time.Now()
+	code := classifyPortErrorCode(err)
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+	return PortError{Code: code, Message: message, Timestamp: &now, Retryable: code.Retryable()}
+}
+
+// classifyPortErrorCode maps typed errors first, then well-known messages
+// from the SSH library, which does not export typed errors for these
+func classifyPortErrorCode(err error) PortErrorCode {
+	if err == nil {
+		return PortErrorUnknown
+	}
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &dnsErr):
+		return PortErrorDNSFailed
+	case errors.Is(err, syscall.EADDRINUSE):
+		return PortErrorBindFailed
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return PortErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.ECONNRESET):
+		return PortErrorConnectFailed
+	}
+	if errors.As(err, &opErr) && opErr.Op == "listen" {
+		return PortErrorBindFailed
+	}
+
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "unable to authenticate"), strings.Contains(message, "no supported methods remain"):
+		return PortErrorAuthFailed
+	case strings.Contains(message, "host key"), strings.Contains(message, "knownhosts"):
+		return PortErrorHostKey
+	case strings.Contains(message, "refused remote forward"), strings.Contains(message, "tcpip-forward request denied"):
+		return PortErrorRemoteRefused
+	case strings.Contains(message, "timeout"), strings.Contains(message, "timed out"):
+		return PortErrorTimeout
+	case strings.Contains(message, "address already in use"):
+		return PortErrorBindFailed
+	case strings.Contains(message, "connection refused"), strings.Contains(message, "no route to host"):
+		return PortErrorConnectFailed
+	}
+	return PortErrorUnknown
+}
+
+// TransitionTo 按迁移规则更新状态；进入 error 状态时记录 cause 的分类，
+// cause 为 nil 时保留已有的错误信息
+func (p *Port) TransitionTo(status PortStatus, cause *PortError) error {
+	if !status.IsValid() {
+		return fmt.Errorf("%w: %s", ErrInvalidPortStatus, status)
+	}
+	if !p.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidPortTransition, p.Status, status)
+	}
+
+	p.UpdateStatus(status)
+	if cause != nil {
+		p.LastError = cause.normalized()
+	}
+	return nil
+}
+
+// maxPortErrorMessage matches the column size of PortError.Message
+const maxPortErrorMessage = 500
+
+// normalized fills in the code, timestamp and retryable flag of a reported error
+func (e PortError) normalized() PortError {
+	if e.Code == "" {
+		e.Code = PortErrorUnknown
+	}
+	if e.Timestamp == nil {
+		now := time.Now()
+		e.Timestamp = &now
+	}
+	e.Retryable = e.Retryable || e.Code.Retryable()
+	if len(e.Message) > maxPortErrorMessage {
+		e.Message = e.Message[:maxPortErrorMessage]
+	}
+	return e
+}
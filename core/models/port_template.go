@@ -0,0 +1,93 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Port template and favorite validation errors
+var (
+	ErrTemplateNameRequired  = errors.New("template name cannot be empty")
+	ErrFavoriteOwnerRequired = errors.New("favorite owner is required")
+)
+
+// PortTemplate 可复用的端口模板（名称、类型、默认端口、标签、健康检查），一次调用即可在主机上创建端口
+type PortTemplate struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name        string `gorm:"not null;size:100;uniqueIndex" json:"name"`
+	Description string `gorm:"size:500" json:"description"`
+
+	// 创建的端口使用的默认值，实例化时可覆盖端口号、监听地址和名称
+	Type                   PortType    `gorm:"not null;size:20" json:"type"`
+	Port                   int         `gorm:"not null" json:"port"`
+	PortEnd                int         `gorm:"default:0" json:"port_end,omitempty"`
+	BindAddress            string      `gorm:"size:255;default:127.0.0.1" json:"bind_address"`
+	RemoteBindAddress      string      `gorm:"size:255" json:"remote_bind_address,omitempty"`
+	AllowRemoteConnections bool        `gorm:"default:false" json:"allow_remote_connections"`
+	ServiceType            ServiceType `gorm:"size:20" json:"service_type,omitempty"`
+	AutoStart              bool        `gorm:"default:false" json:"auto_start"`
+	Tags                   []string    `gorm:"type:text;serializer:json" json:"tags,omitempty"`
+
+	// 健康检查：创建的端口发布到状态页，由状态页定期检查（http/https 服务发送请求，其他服务建立连接）
+	HealthCheck bool `gorm:"default:false" json:"health_check"`
+}
+
+// NewPort 按模板为主机生成端口，分组默认为主机所在分组，名称默认为"模板名-主机名"
+func (t *PortTemplate) NewPort(host *Host) *Port {
+	port := &Port{
+		Name:                   t.Name + "-" + host.Name,
+		Type:                   t.Type,
+		Port:                   t.Port,
+		PortEnd:                t.PortEnd,
+		BindAddress:            t.BindAddress,
+		RemoteBindAddress:      t.RemoteBindAddress,
+		AllowRemoteConnections: t.AllowRemoteConnections,
+		Description:            t.Description,
+		ServiceType:            t.ServiceType,
+		AutoStart:              t.AutoStart,
+		Published:              t.HealthCheck,
+		Tags:                   append([]string(nil), t.Tags...),
+		GroupID:                host.GroupID,
+		HostID:                 &host.ID,
+	}
+	if port.BindAddress == "" {
+		port.BindAddress = "127.0.0.1"
+	}
+	return port
+}
+
+// Validate 验证模板，默认值须能生成有效的端口
+func (t *PortTemplate) Validate() error {
+	if t.Name == "" {
+		return ErrTemplateNameRequired
+	}
+	// Hosts supply the group when the template is instantiated
+	port := t.NewPort(&Host{GroupID: 1})
+	return port.Validate()
+}
+
+// PortFavorite 用户收藏的端口，用于快速访问面板；用户由 X-PortFly-Actor 请求头标识
+type PortFavorite struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Owner    string `gorm:"not null;size:255;uniqueIndex:idx_port_favorite_owner,priority:1" json:"owner"`
+	PortID   uint   `gorm:"not null;index;uniqueIndex:idx_port_favorite_owner,priority:2" json:"port_id"`
+	Position int    `gorm:"not null;default:0" json:"position"` // 在面板中的顺序，从 0 开始
+
+	Port *Port `gorm:"constraint:OnDelete:CASCADE" json:"port,omitempty"`
+}
+
+// Validate 验证收藏
+func (f *PortFavorite) Validate() error {
+	if f.Owner == "" {
+		return ErrFavoriteOwnerRequired
+	}
+	if f.PortID == 0 {
+		return ErrInvalidPort
+	}
+	return nil
+}
@@ -1,12 +1,19 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Quota errors
+var (
+	ErrInvalidQuota  = errors.New("quota limits cannot be negative")
+	ErrQuotaExceeded = errors.New("project quota exceeded")
+)
+
 // Project 项目/工作空间 - 支持树状结构的容器
 type Project struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -14,12 +21,15 @@ type Project struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	Name        string `gorm:"not null;size:100" json:"name"`
-	Description string `gorm:"size:500" json:"description"`
-	Color       string `gorm:"size:20;default:#6366f1" json:"color"`
-	Icon        string `gorm:"size:50;default:folder" json:"icon"`
-	IsDefault   bool   `gorm:"default:false" json:"is_default"`
-	Metadata    string `gorm:"type:text" json:"metadata,omitempty"` // JSON string
+	Name        string   `gorm:"not null;size:100" json:"name"`
+	Description string   `gorm:"size:500" json:"description"`
+	Color       string   `gorm:"size:20;default:#6366f1" json:"color"`
+	Icon        string   `gorm:"size:50;default:folder" json:"icon"`
+	IsDefault   bool     `gorm:"default:false" json:"is_default"`
+	Metadata    Metadata `json:"metadata,omitempty"` // 自定义元数据，可按 metadata.<键> 查询
+
+	// 配额（多租户部署时限制单个项目的资源占用）
+	Quota ProjectQuota `gorm:"embedded;embeddedPrefix:quota_" json:"quota"`
 
 	// 树状结构支持
 	ParentID *uint  `gorm:"index" json:"parent_id,omitempty"` // 父项目ID，为空表示根项目
@@ -41,6 +51,65 @@ type ProjectStats struct {
 	TotalPorts    int        `json:"total_ports"`
 	ActiveTunnels int        `json:"active_tunnels"`
 	LastUsed      *time.Time `json:"last_used,omitempty"`
+	Quota         QuotaUsage `json:"quota"`
+
+	AuthFailingHosts int `json:"auth_failing_hosts"` // 最近认证失败的主机数
+}
+
+// 项目仪表盘的数据范围
+const (
+	DashboardRecentLimit    = 10        // 最近活动条数
+	DashboardTrafficBuckets = 24        // 流量走势的时间段数
+	DashboardTrafficBucket  = time.Hour // 每个时间段的长度
+)
+
+// ProjectDashboard 项目仪表盘：一次返回统计、活跃端口、最近活动、异常主机和流量走势
+type ProjectDashboard struct {
+	Stats          ProjectStats     `json:"stats"`
+	ActivePorts    []Port           `json:"active_ports"`    // 正在转发、连接中或退避中的端口
+	RecentActivity []TunnelSession  `json:"recent_activity"` // 最近更新的隧道会话
+	UnhealthyHosts []Host           `json:"unhealthy_hosts"` // 状态为 error 的主机
+	Traffic        []TrafficPoint   `json:"traffic"`         // 按时间段汇总的流量，从早到晚
+	AuthFailures   []HostAuthStatus `json:"auth_failures"`   // 最近认证失败的主机，连续失败多的在前
+}
+
+// TrafficPoint 流量走势中的一个时间段
+type TrafficPoint struct {
+	Start    time.Time `json:"start"`
+	Bytes    int64     `json:"bytes"`    // 该时间段内结束或最后更新的会话传输的字节数
+	Sessions int       `json:"sessions"` // 计入的会话数
+}
+
+// ProjectQuota 项目配额，0 表示不限制
+type ProjectQuota struct {
+	MaxHosts         int   `gorm:"default:0" json:"max_hosts"`          // 主机数上限
+	MaxActiveTunnels int   `gorm:"default:0" json:"max_active_tunnels"` // 同时运行的隧道（会话和端口）上限
+	MaxBandwidth     int64 `gorm:"default:0" json:"max_bandwidth"`      // 项目内所有隧道的总带宽（字节/秒）
+}
+
+// Validate 检查配额不为负数
+func (q ProjectQuota) Validate() error {
+	if q.MaxHosts < 0 || q.MaxActiveTunnels < 0 || q.MaxBandwidth < 0 {
+		return ErrInvalidQuota
+	}
+	return nil
+}
+
+// QuotaUsage 项目配额及当前用量
+type QuotaUsage struct {
+	ProjectQuota
+	Hosts         int `json:"hosts"`
+	ActiveTunnels int `json:"active_tunnels"` // 活跃的隧道会话与活跃端口之和
+}
+
+// HostsExceeded 再添加 n 台主机是否超出配额
+func (u QuotaUsage) HostsExceeded(n int) bool {
+	return u.MaxHosts > 0 && u.Hosts+n > u.MaxHosts
+}
+
+// TunnelsExceeded 再启动 n 条隧道是否超出配额
+func (u QuotaUsage) TunnelsExceeded(n int) bool {
+	return u.MaxActiveTunnels > 0 && u.ActiveTunnels+n > u.MaxActiveTunnels
 }
 
 // 项目树节点，用于前端展示
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// 可达性探测方式
+const (
+	ReachabilityMethodForward = "forward" // 经 SSH 的 direct-tcpip 通道由源主机连接目标
+	ReachabilityMethodCommand = "command" // 源主机禁止端口转发时，在其上执行 nc 或 bash 的 /dev/tcp
+)
+
+// Reachability 从源主机连接目标地址的最近一次探测结果。同一分组中每个源主机、
+// 目标地址和端口只保留一条，组成分组的可达性矩阵
+type Reachability struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	GroupID      uint   `gorm:"not null;uniqueIndex:idx_reachability_pair,priority:1" json:"group_id"`
+	SourceHostID uint   `gorm:"not null;uniqueIndex:idx_reachability_pair,priority:2" json:"source_host_id"`
+	TargetHostID *uint  `gorm:"index" json:"target_host_id,omitempty"` // 目标为已保存的主机时
+	TargetHost   string `gorm:"not null;size:255;uniqueIndex:idx_reachability_pair,priority:3" json:"target_host"`
+	TargetPort   int    `gorm:"not null;uniqueIndex:idx_reachability_pair,priority:4" json:"target_port"`
+
+	Reachable bool      `json:"reachable"`
+	Method    string    `gorm:"size:20" json:"method,omitempty"` // forward 或 command，未能探测时为空
+	LatencyMs *float64  `json:"latency_ms,omitempty"`            // 建立连接的耗时
+	Error     string    `gorm:"size:500" json:"error,omitempty"` // 不可达的原因，或探测失败的原因
+	CheckedAt time.Time `gorm:"not null;index" json:"checked_at"`
+}
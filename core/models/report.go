@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Idle-resource report bounds
+const (
+	DefaultUnusedDays = 30
+	MaxUnusedDays     = 3650
+)
+
+// ErrInvalidUnusedDays is returned for a report period out of range
+var ErrInvalidUnusedDays = errors.New("days must be between 1 and 3650")
+
+// UnusedPort 统计期内未使用的端口：没有转发会话、未处于活跃状态，也没有经它的端口连接
+type UnusedPort struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Type      PortType   `json:"type"`
+	Port      int        `json:"port"`
+	GroupID   uint       `json:"group_id"`
+	GroupName string     `json:"group_name,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastUsed  *time.Time `json:"last_used,omitempty"` // 最近一次使用，从未使用时为空
+}
+
+// UnusedHost 统计期内未连接过的主机
+type UnusedHost struct {
+	ID            uint       `json:"id"`
+	Name          string     `json:"name"`
+	Hostname      string     `json:"hostname"`
+	GroupID       uint       `json:"group_id"`
+	GroupName     string     `json:"group_name,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastConnected *time.Time `json:"last_connected,omitempty"` // 最近一次连接，从未连接时为空
+}
+
+// IdleGroup 统计期内没有流量的分组（含子分组的端口和主机）
+type IdleGroup struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	ProjectID uint       `json:"project_id"`
+	ParentID  *uint      `json:"parent_id,omitempty"`
+	Ports     int        `json:"ports"` // 分组自身的端口数
+	Hosts     int        `json:"hosts"` // 分组自身的主机数
+	CreatedAt time.Time  `json:"created_at"`
+	LastUsed  *time.Time `json:"last_used,omitempty"` // 分组及子分组的端口或主机最近一次使用
+}
+
+// UsageReport 闲置资源报告，列出 Days 天内未使用的端口、未连接的主机和没有流量的分组，
+// 统计期开始后才创建的资源不计入
+type UsageReport struct {
+	Days        int       `json:"days"`
+	Since       time.Time `json:"since"`
+	GeneratedAt time.Time `json:"generated_at"`
+
+	UnusedPorts []UnusedPort `json:"unused_ports"`
+	UnusedHosts []UnusedHost `json:"unused_hosts"`
+	IdleGroups  []IdleGroup  `json:"idle_groups"`
+}
+
+// UnusedSince returns the start of a report period of days ending at now
+func UnusedSince(now time.Time, days int) (time.Time, error) {
+	if days < 1 || days > MaxUnusedDays {
+		return time.Time{}, ErrInvalidUnusedDays
+	}
+	return now.AddDate(0, 0, -days), nil
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Retry policy validation errors
+var (
+	ErrInvalidRetryInterval   = errors.New("retry intervals cannot be negative")
+	ErrInvalidRetryMultiplier = errors.New("retry multiplier must be >= 1")
+	ErrInvalidRetryJitter     = errors.New("retry jitter must be between 0 and 1")
+	ErrInvalidMaxAttempts     = errors.New("max attempts cannot be negative")
+	ErrInvalidGiveUpAction    = errors.New("invalid give-up action")
+)
+
+// GiveUpAction 重试耗尽后的处理方式
+type GiveUpAction string
+
+const (
+	GiveUpActionStop  GiveUpAction = "stop"  // 停止会话，状态为 stopped
+	GiveUpActionError GiveUpAction = "error" // 停止会话，状态为 error
+)
+
+// RetryPolicy 隧道失败后的重连策略（指数退避 + 抖动）
+type RetryPolicy struct {
+	InitialInterval time.Duration `gorm:"default:0" json:"initial_interval"`       // 首次重试等待时间
+	MaxInterval     time.Duration `gorm:"default:0" json:"max_interval"`           // 单次等待上限
+	Multiplier      float64       `gorm:"default:0" json:"multiplier"`             // 每次失败后的放大系数
+	Jitter          float64       `gorm:"default:0" json:"jitter"`                 // 随机抖动比例 (0-1)
+	MaxAttempts     int           `gorm:"default:0" json:"max_attempts"`           // 最多重试次数，0 表示不限次数
+	ResetWindow     time.Duration `gorm:"default:0" json:"reset_window"`           // 连接稳定超过该时长后重置计数
+	GiveUpAction    GiveUpAction  `gorm:"size:20" json:"give_up_action,omitempty"` // 重试耗尽后的动作
+}
+
+// DefaultRetryPolicy returns the policy used when a port does not configure one
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		InitialInterval: 1 * time.Second,
+		MaxInterval:     60 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxAttempts:     10,
+		ResetWindow:     5 * time.Minute,
+		GiveUpAction:    GiveUpActionError,
+	}
+}
+
+// FixedRetryPolicy returns a policy that retries at a constant interval,
+// matching the legacy MaxRetries/RetryInterval behaviour
+func FixedRetryPolicy(maxAttempts int, interval time.Duration) RetryPolicy {
+	return RetryPolicy{
+		InitialInterval: interval,
+		MaxInterval:     interval,
+		Multiplier:      1,
+		MaxAttempts:     maxAttempts,
+		GiveUpAction:    GiveUpActionError,
+	}
+}
+
+// IsZero reports whether the policy has not been configured
+func (p RetryPolicy) IsZero() bool {
+	return p == RetryPolicy{}
+}
+
+// WithDefaults fills unset fields from DefaultRetryPolicy
+func (p RetryPolicy) WithDefaults() RetryPolicy {
+	if p.IsZero() {
+		return DefaultRetryPolicy()
+	}
+
+	defaults := DefaultRetryPolicy()
+	if p.InitialInterval == 0 {
+		p.InitialInterval = defaults.InitialInterval
+	}
+	if p.MaxInterval == 0 {
+		p.MaxInterval = defaults.MaxInterval
+	}
+	if p.MaxInterval < p.InitialInterval {
+		p.MaxInterval = p.InitialInterval
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = defaults.Multiplier
+	}
+	if p.GiveUpAction == "" {
+		p.GiveUpAction = defaults.GiveUpAction
+	}
+	return p
+}
+
+// Validate 验证重试策略
+func (p RetryPolicy) Validate() error {
+	if p.InitialInterval < 0 || p.MaxInterval < 0 || p.ResetWindow < 0 {
+		return ErrInvalidRetryInterval
+	}
+
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return ErrInvalidRetryMultiplier
+	}
+
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return ErrInvalidRetryJitter
+	}
+
+	if p.MaxAttempts < 0 {
+		return ErrInvalidMaxAttempts
+	}
+
+	switch p.GiveUpAction {
+	case "", GiveUpActionStop, GiveUpActionError:
+	default:
+		return ErrInvalidGiveUpAction
+	}
+
+	return nil
+}
+
+// Exhausted reports whether the given number of failed attempts uses up the
+// policy. MaxAttempts counts retries, so the attempt failing after the last
+// retry exhausts it, as with the legacy MaxRetries.
+func (p RetryPolicy) Exhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts > p.MaxAttempts
+}
+
+// Delay returns the wait before the given retry attempt (1-based).
+// random must be in [0, 1) and is used to spread the jitter.
+func (p RetryPolicy) Delay(attempt int, random float64) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(p.InitialInterval) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxInterval > 0 && delay > float64(p.MaxInterval) {
+		delay = float64(p.MaxInterval)
+	}
+
+	if p.Jitter > 0 {
+		// Spread uniformly over [delay*(1-jitter), delay*(1+jitter)]
+		delay = delay * (1 - p.Jitter + 2*p.Jitter*random)
+	}
+
+	return time.Duration(delay)
+}
+
+// FormatBackoffMessage returns a human-readable backoff status, e.g. "backing off, retry in 30s (attempt 2/5)"
+func FormatBackoffMessage(attempt, maxAttempts int, delay time.Duration) string {
+	retryIn := delay.Round(time.Second)
+	if delay < time.Second {
+		retryIn = delay.Round(time.Millisecond)
+	}
+
+	if maxAttempts > 0 {
+		return fmt.Sprintf("backing off, retry in %s (attempt %d/%d)", retryIn, attempt, maxAttempts)
+	}
+	return fmt.Sprintf("backing off, retry in %s (attempt %d)", retryIn, attempt)
+}
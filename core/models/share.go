@@ -0,0 +1,246 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"text/template"
+)
+
+// ErrInvalidServiceType is returned for unknown service types
+var ErrInvalidServiceType = errors.New("invalid service type")
+
+// ServiceType 端口背后的服务类型，用于生成分享片段
+type ServiceType string
+
+const (
+	ServiceTCP      ServiceType = "tcp"      // 通用 TCP
+	ServiceHTTP     ServiceType = "http"     // HTTP
+	ServiceHTTPS    ServiceType = "https"    // HTTPS
+	ServicePostgres ServiceType = "postgres" // PostgreSQL
+	ServiceMySQL    ServiceType = "mysql"    // MySQL / MariaDB
+	ServiceRedis    ServiceType = "redis"    // Redis
+	ServiceMongoDB  ServiceType = "mongodb"  // MongoDB
+	ServiceSSH      ServiceType = "ssh"      // SSH
+)
+
+// wellKnownServices 未指定服务类型时按端口号推断
+var wellKnownServices = map[int]ServiceType{
+	22:    ServiceSSH,
+	80:    ServiceHTTP,
+	443:   ServiceHTTPS,
+	3000:  ServiceHTTP,
+	3306:  ServiceMySQL,
+	5173:  ServiceHTTP,
+	5432:  ServicePostgres,
+	6379:  ServiceRedis,
+	8000:  ServiceHTTP,
+	8080:  ServiceHTTP,
+	8443:  ServiceHTTPS,
+	27017: ServiceMongoDB,
+}
+
+// shareTemplate 每种服务的分享片段模板
+type shareTemplate struct {
+	URL     string
+	Command string
+	Example string
+}
+
+// shareTemplates 模板变量见 shareData
+var shareTemplates = map[ServiceType]shareTemplate{
+	ServiceTCP: {
+		URL:     "tcp://{{.Address}}",
+		Command: "nc -v {{.Host}} {{.Port}}",
+	},
+	ServiceHTTP: {
+		URL:     "http://{{.Address}}/",
+		Command: "curl -i http://{{.Address}}/",
+		Example: "curl -s -X POST -H 'Content-Type: application/json' -d '{}' http://{{.Address}}/",
+	},
+	ServiceHTTPS: {
+		URL:     "https://{{.Address}}/",
+		Command: "curl -i https://{{.Address}}/",
+		Example: "curl -sk -X POST -H 'Content-Type: application/json' -d '{}' https://{{.Address}}/",
+	},
+	ServicePostgres: {
+		URL:     "postgresql://<user>@{{.Address}}/<database>",
+		Command: "psql -h {{.Host}} -p {{.Port}} -U <user> <database>",
+		Example: "psql \"postgresql://<user>@{{.Address}}/<database>\" -c 'select 1'",
+	},
+	ServiceMySQL: {
+		URL:     "mysql://<user>@{{.Address}}/<database>",
+		Command: "mysql -h {{.Host}} -P {{.Port}} -u <user> -p <database>",
+		Example: "mysql -h {{.Host}} -P {{.Port}} -u <user> -p -e 'select 1'",
+	},
+	ServiceRedis: {
+		URL:     "redis://{{.Address}}/0",
+		Command: "redis-cli -h {{.Host}} -p {{.Port}}",
+		Example: "redis-cli -h {{.Host}} -p {{.Port}} ping",
+	},
+	ServiceMongoDB: {
+		URL:     "mongodb://{{.Address}}/",
+		Command: "mongosh \"mongodb://{{.Address}}/\"",
+	},
+	ServiceSSH: {
+		URL:     "ssh://<user>@{{.Address}}",
+		Command: "ssh -p {{.Port}} <user>@{{.Host}}",
+	},
+}
+
+// ShareLink 可直接复制的连接片段
+type ShareLink struct {
+	PortID  uint        `json:"port_id"`
+	Name    string      `json:"name"`
+	Service ServiceType `json:"service"`
+	Host    string      `json:"host"`
+	Port    int         `json:"port"`
+	Address string      `json:"address"` // host:port，IPv6 带方括号
+	URL     string      `json:"url"`
+	Command string      `json:"command"`
+	Example string      `json:"example,omitempty"`
+	App     WebApp      `json:"app,omitempty"`   // 探测到的 Web 应用，示例改为该应用的健康检查或版本接口
+	Title   string      `json:"title,omitempty"` // 探测到的首页标题
+}
+
+// webAppExamples 识别出应用时示例请求的路径
+var webAppExamples = map[WebApp]string{
+	WebAppGrafana:       "/api/health",
+	WebAppJenkins:       "/api/json?pretty=true",
+	WebAppPgAdmin:       "/misc/ping",
+	WebAppPrometheus:    "/api/v1/query?query=up",
+	WebAppAlertmanager:  "/api/v2/status",
+	WebAppKibana:        "/api/status",
+	WebAppElasticsearch: "/_cluster/health?pretty",
+	WebAppGitLab:        "/-/health",
+	WebAppGitea:         "/api/v1/version",
+	WebAppRabbitMQ:      "/api/overview",
+	WebAppPortainer:     "/api/status",
+	WebAppArgoCD:        "/api/version",
+	WebAppMinIO:         "/minio/health/live",
+	WebAppAirflow:       "/health",
+	WebAppSonarQube:     "/api/system/status",
+	WebAppConsul:        "/v1/status/leader",
+	WebAppVault:         "/v1/sys/health",
+	WebAppTraefik:       "/api/overview",
+}
+
+// shareData 模板变量
+type shareData struct {
+	Host    string // 主机名，IPv6 不带方括号
+	Port    int
+	Address string // host:port
+	Name    string
+}
+
+// IsValid 检查服务类型是否受支持
+func (s ServiceType) IsValid() bool {
+	_, exists := shareTemplates[s]
+	return exists
+}
+
+// GetServiceType 获取服务类型，未设置时按端口号推断
+func (p *Port) GetServiceType() ServiceType {
+	if p.ServiceType != "" {
+		return p.ServiceType
+	}
+	if service, exists := wellKnownServices[p.Port]; exists {
+		return service
+	}
+	return ServiceTCP
+}
+
+// GetShareHost 获取客户端应连接的主机
+// 本地端口：监听地址（通配地址替换为回环地址）
+// 远程端口：允许远程连接时为 SSH 主机名，否则为服务端监听地址
+func (p *Port) GetShareHost() string {
+	if p.IsRemotePort() {
+		if p.AllowRemoteConnections && p.Host != nil && p.Host.Hostname != "" {
+			return p.Host.Hostname
+		}
+		return shareableAddress(p.GetRemoteBindAddress())
+	}
+	return shareableAddress(p.GetBindAddress())
+}
+
+// ShareLink 生成分享片段；host、service 为空时使用默认值
+func (p *Port) ShareLink(host string, service ServiceType) (*ShareLink, error) {
+	if host == "" {
+		host = p.GetShareHost()
+	}
+	if service == "" {
+		service = p.GetServiceType()
+	}
+	tmpl, exists := shareTemplates[service]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidServiceType, service)
+	}
+
+	data := shareData{
+		Host:    host,
+		Port:    p.Port,
+		Address: net.JoinHostPort(host, strconv.Itoa(p.Port)),
+		Name:    p.GetDisplayName(),
+	}
+
+	link := &ShareLink{
+		PortID:  p.ID,
+		Name:    data.Name,
+		Service: service,
+		Host:    host,
+		Port:    p.Port,
+		Address: data.Address,
+	}
+
+	var err error
+	if link.URL, err = renderShareTemplate(tmpl.URL, data); err != nil {
+		return nil, err
+	}
+	if link.Command, err = renderShareTemplate(tmpl.Command, data); err != nil {
+		return nil, err
+	}
+	if link.Example, err = renderShareTemplate(tmpl.Example, data); err != nil {
+		return nil, err
+	}
+
+	if service == ServiceHTTP || service == ServiceHTTPS {
+		link.App, link.Title = p.Web.App, p.Web.Title
+		if path, exists := webAppExamples[p.Web.App]; exists {
+			flags := "-s"
+			if service == ServiceHTTPS {
+				flags = "-sk"
+			}
+			link.Example = fmt.Sprintf("curl %s '%s://%s%s'", flags, service, data.Address, path)
+		}
+	}
+	return link, nil
+}
+
+// renderShareTemplate 渲染单个模板，空模板返回空字符串
+func renderShareTemplate(text string, data shareData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := template.New("share").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid share template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render share template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// shareableAddress 将通配监听地址替换为回环地址
+func shareableAddress(address string) string {
+	switch address {
+	case "", "*", "0.0.0.0":
+		return "127.0.0.1"
+	case "::":
+		return "::1"
+	}
+	return address
+}
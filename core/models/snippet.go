@@ -0,0 +1,269 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 操作系统系列，命令片段按系列提供不同的命令
+const (
+	OSFamilyDebian  = "debian"  // Debian、Ubuntu 及其衍生版
+	OSFamilyRHEL    = "rhel"    // RHEL、CentOS、Fedora、Rocky、AlmaLinux、Amazon Linux
+	OSFamilyAlpine  = "alpine"  // Alpine Linux
+	OSFamilyArch    = "arch"    // Arch Linux 及其衍生版
+	OSFamilySUSE    = "suse"    // openSUSE、SLES
+	OSFamilyLinux   = "linux"   // 其他 Linux；也是各发行版共用的命令
+	OSFamilyFreeBSD = "freebsd" // FreeBSD
+	OSFamilyDarwin  = "darwin"  // macOS
+	OSFamilyWindows = "windows" // Windows（OpenSSH 服务器，命令由 cmd.exe 执行）
+
+	// SnippetDefaultCommand 命令片段中没有对应系列的命令时使用的键
+	SnippetDefaultCommand = "default"
+)
+
+// OSFamilies 所有操作系统系列
+var OSFamilies = []string{
+	OSFamilyDebian, OSFamilyRHEL, OSFamilyAlpine, OSFamilyArch, OSFamilySUSE,
+	OSFamilyLinux, OSFamilyFreeBSD, OSFamilyDarwin, OSFamilyWindows,
+}
+
+// linuxFamilies 属于 Linux 的系列，没有专门的命令时使用 linux 的命令
+var linuxFamilies = []string{OSFamilyDebian, OSFamilyRHEL, OSFamilyAlpine, OSFamilyArch, OSFamilySUSE}
+
+// OSFamilyProbe 检测主机操作系统系列的命令，输出由 DetectOSFamily 解析
+const OSFamilyProbe = "uname -s; cat /etc/os-release"
+
+// Snippet validation errors
+var (
+	ErrInvalidSnippet      = errors.New("invalid snippet")
+	ErrInvalidOSFamily     = errors.New("invalid os family")
+	ErrInvalidSnippetParam = errors.New("invalid snippet parameter")
+	ErrNoSnippetCommand    = errors.New("snippet has no command for this os family")
+)
+
+// snippetParamName 参数名，在命令中以 {{name}} 引用
+var snippetParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// snippetPlaceholder 命令中的参数占位符，允许花括号内有空格
+var snippetPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// SnippetParam 命令片段的参数
+type SnippetParam struct {
+	Name        string `json:"name"` // 命令中以 {{name}} 引用
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"` // 没有默认值时必须提供
+	Pattern     string `json:"pattern,omitempty"`  // 值须完整匹配的正则表达式，如 ^[a-z0-9-]+$
+}
+
+// Snippet 可复用的命名命令（如重启 nginx、查看 syslog），按操作系统系列提供不同的命令，
+// 可在主机或分组上带参数执行
+type Snippet struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name        string   `gorm:"not null;size:100;uniqueIndex" json:"name"`
+	Description string   `gorm:"size:500" json:"description"`
+	Tags        []string `gorm:"type:text;serializer:json" json:"tags,omitempty"`
+
+	// 按操作系统系列的命令，键为系列名（debian、rhel 等）或 default
+	Commands map[string]string `gorm:"type:text;serializer:json" json:"commands"`
+	Params   []SnippetParam    `gorm:"type:text;serializer:json" json:"params,omitempty"`
+	Sudo     bool              `gorm:"default:false" json:"sudo"` // 默认通过 sudo 执行，执行时可覆盖
+
+	// 权限，用户由 X-PortFly-Actor 请求头标识
+	CreatedBy string   `gorm:"size:255" json:"created_by"`
+	Editors   []string `gorm:"type:text;serializer:json" json:"editors,omitempty"` // 除创建者外可修改和删除的用户
+	Runners   []string `gorm:"type:text;serializer:json" json:"runners,omitempty"` // 可执行的用户，为空时所有人可执行
+}
+
+// Validate 验证命令片段：名称、每个命令的系列、参数定义，且命令只引用已定义的参数
+func (s *Snippet) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSnippet)
+	}
+	if len(s.Commands) == 0 {
+		return fmt.Errorf("%w: at least one command is required", ErrInvalidSnippet)
+	}
+
+	params := make(map[string]bool, len(s.Params))
+	for _, param := range s.Params {
+		if !snippetParamName.MatchString(param.Name) {
+			return fmt.Errorf("%w: parameter name %q must be a letter or underscore followed by letters, digits or underscores", ErrInvalidSnippet, param.Name)
+		}
+		if params[param.Name] {
+			return fmt.Errorf("%w: duplicate parameter %q", ErrInvalidSnippet, param.Name)
+		}
+		params[param.Name] = true
+		if param.Pattern != "" {
+			pattern, err := compileFullPattern(param.Pattern)
+			if err != nil {
+				return fmt.Errorf("%w: parameter %q pattern: %v", ErrInvalidSnippet, param.Name, err)
+			}
+			if param.Default != "" && !pattern.MatchString(param.Default) {
+				return fmt.Errorf("%w: default of parameter %q does not match its pattern", ErrInvalidSnippet, param.Name)
+			}
+		}
+	}
+
+	for family, command := range s.Commands {
+		if family != SnippetDefaultCommand && !slices.Contains(OSFamilies, family) {
+			return fmt.Errorf("%w: unknown os family %q, expected one of %s or %s",
+				ErrInvalidSnippet, family, strings.Join(OSFamilies, ", "), SnippetDefaultCommand)
+		}
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("%w: command for %s is empty", ErrInvalidSnippet, family)
+		}
+		for _, match := range snippetPlaceholder.FindAllStringSubmatch(command, -1) {
+			if !params[match[1]] {
+				return fmt.Errorf("%w: command for %s uses undefined parameter %q", ErrInvalidSnippet, family, match[1])
+			}
+		}
+	}
+	return nil
+}
+
+// CommandFor 返回操作系统系列使用的命令：该系列的命令，Linux 发行版其次使用 linux 的命令，
+// 最后使用 default 的命令
+func (s *Snippet) CommandFor(family string) (string, bool) {
+	keys := []string{family}
+	if slices.Contains(linuxFamilies, family) {
+		keys = append(keys, OSFamilyLinux)
+	}
+	keys = append(keys, SnippetDefaultCommand)
+	for _, key := range keys {
+		if command, ok := s.Commands[key]; ok {
+			return command, true
+		}
+	}
+	return "", false
+}
+
+// ResolveParams 合并请求的参数值与默认值，检查必填参数和正则，不接受未定义的参数
+func (s *Snippet) ResolveParams(values map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(s.Params))
+	for name := range values {
+		if !slices.ContainsFunc(s.Params, func(param SnippetParam) bool { return param.Name == name }) {
+			return nil, fmt.Errorf("%w: snippet has no parameter %q", ErrInvalidSnippetParam, name)
+		}
+	}
+	for _, param := range s.Params {
+		value, ok := values[param.Name]
+		if !ok {
+			value = param.Default
+		}
+		if value == "" && param.Required {
+			return nil, fmt.Errorf("%w: %q is required", ErrInvalidSnippetParam, param.Name)
+		}
+		if param.Pattern != "" && value != "" {
+			// Patterns were checked when the snippet was saved
+			pattern, err := compileFullPattern(param.Pattern)
+			if err != nil || !pattern.MatchString(value) {
+				return nil, fmt.Errorf("%w: %q must match %s", ErrInvalidSnippetParam, param.Name, param.Pattern)
+			}
+		}
+		resolved[param.Name] = value
+	}
+	return resolved, nil
+}
+
+// Render 生成操作系统系列上执行的命令，参数值按该系列的 shell 加引号后替换占位符，
+// 不会被解释为命令的一部分
+func (s *Snippet) Render(family string, params map[string]string) (string, error) {
+	command, ok := s.CommandFor(family)
+	if !ok {
+		if family == "" {
+			family = "unknown"
+		}
+		return "", fmt.Errorf("%w: %s", ErrNoSnippetCommand, family)
+	}
+	return snippetPlaceholder.ReplaceAllStringFunc(command, func(placeholder string) string {
+		name := snippetPlaceholder.FindStringSubmatch(placeholder)[1]
+		return quoteForFamily(family, params[name])
+	}), nil
+}
+
+// CanRun 用户是否可以执行命令片段
+func (s *Snippet) CanRun(actor string) bool {
+	return len(s.Runners) == 0 || slices.Contains(s.Runners, actor) || s.CanEdit(actor)
+}
+
+// CanEdit 用户是否可以修改和删除命令片段；没有记录创建者的片段所有人可修改
+func (s *Snippet) CanEdit(actor string) bool {
+	return s.CreatedBy == "" || s.CreatedBy == actor || slices.Contains(s.Editors, actor)
+}
+
+// DetectOSFamily 解析 OSFamilyProbe 的输出，无法识别时返回空字符串
+func DetectOSFamily(output string) string {
+	var kernel string
+	release := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			if kernel == "" && line != "" {
+				kernel = line
+			}
+			continue
+		}
+		release[key] = strings.ToLower(strings.Trim(value, `"'`))
+	}
+
+	switch {
+	case kernel == "Darwin":
+		return OSFamilyDarwin
+	case kernel == "FreeBSD":
+		return OSFamilyFreeBSD
+	case strings.Contains(strings.ToLower(output), "is not recognized as an internal or external command"):
+		return OSFamilyWindows
+	case kernel != "Linux" && len(release) == 0:
+		return ""
+	}
+
+	ids := append([]string{release["ID"]}, strings.Fields(release["ID_LIKE"])...)
+	for _, id := range ids {
+		switch id {
+		case "debian", "ubuntu":
+			return OSFamilyDebian
+		case "rhel", "centos", "fedora", "rocky", "almalinux", "amzn":
+			return OSFamilyRHEL
+		case "alpine":
+			return OSFamilyAlpine
+		case "arch", "manjaro":
+			return OSFamilyArch
+		case "suse", "opensuse", "sles":
+			return OSFamilySUSE
+		}
+	}
+	return OSFamilyLinux
+}
+
+// ValidateOSFamily 验证主机设置的操作系统系列，空值表示自动检测
+func ValidateOSFamily(family string) error {
+	if family != "" && !slices.Contains(OSFamilies, family) {
+		return fmt.Errorf("%w: %q, expected one of %s", ErrInvalidOSFamily, family, strings.Join(OSFamilies, ", "))
+	}
+	return nil
+}
+
+// quoteForFamily quotes a parameter value as one word of the family's shell:
+// cmd.exe on Windows, a POSIX shell elsewhere
+func quoteForFamily(family, value string) string {
+	if family == OSFamilyWindows {
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// compileFullPattern compiles a parameter pattern anchored to match whole values
+func compileFullPattern(pattern string) (*regexp.Regexp, error) {
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidSocketOption is returned for negative keepalive settings
+var ErrInvalidSocketOption = errors.New("socket keepalive settings cannot be negative")
+
+// SocketOptions TCP 套接字选项，作用于转发接受和拨出的 TCP 连接。
+// SO_REUSEADDR 在 Unix 上由 Go 默认开启；Windows 上它允许其他进程抢占端口，因此不提供该选项。
+type SocketOptions struct {
+	KeepAliveIdle     time.Duration `gorm:"default:0" json:"keep_alive_idle,omitempty"`     // 空闲多久后开始探测，0 使用默认值 (15s)
+	KeepAliveInterval time.Duration `gorm:"default:0" json:"keep_alive_interval,omitempty"` // 探测间隔，0 使用默认值 (15s)
+	KeepAliveCount    int           `gorm:"default:0" json:"keep_alive_count,omitempty"`    // 判定断开前的探测次数，0 使用默认值 (9)
+	DisableKeepAlive  bool          `gorm:"default:false" json:"disable_keep_alive,omitempty"`
+	DisableNoDelay    bool          `gorm:"default:false" json:"disable_nodelay,omitempty"` // 关闭 TCP_NODELAY（启用 Nagle 算法合并小包）
+	ReusePort         bool          `gorm:"default:false" json:"reuse_port,omitempty"`      // SO_REUSEPORT，允许多个进程监听同一端口（仅 Linux/macOS/BSD）
+}
+
+// IsZero reports whether no option is set, leaving the defaults in place
+func (o SocketOptions) IsZero() bool {
+	return o == SocketOptions{}
+}
+
+// CustomKeepAlive reports whether keepalive probing is tuned
+func (o SocketOptions) CustomKeepAlive() bool {
+	return o.KeepAliveIdle != 0 || o.KeepAliveInterval != 0 || o.KeepAliveCount != 0
+}
+
+// Validate 验证套接字选项
+func (o SocketOptions) Validate() error {
+	if o.KeepAliveIdle < 0 || o.KeepAliveInterval < 0 || o.KeepAliveCount < 0 {
+		return ErrInvalidSocketOption
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultIdentityDir 导出 OpenSSH 配置时私钥文件的默认目录
+const DefaultIdentityDir = "~/.ssh/portfly"
+
+// SSHConfigOptions OpenSSH 配置导出选项
+type SSHConfigOptions struct {
+	AliasPrefix string // 别名前缀，如 "pf-"
+	IdentityDir string // 私钥认证主机的 IdentityFile 目录，密钥本身不会导出
+}
+
+// aliasUnsafe 匹配 OpenSSH Host 别名中不应出现的字符（空白、通配符、引号等）
+var aliasUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// jumpCommand 匹配等价于 ProxyJump 的 ProxyCommand，如 "ssh -W %h:%p bastion"
+var jumpCommand = regexp.MustCompile(`^ssh\s+(?:-q\s+)?-W\s+%h:%p\s+(\S+)$`)
+
+// SSHConfigAlias 主机在 OpenSSH 配置中的别名
+func (h *Host) SSHConfigAlias(prefix string) string {
+	alias := strings.Trim(aliasUnsafe.ReplaceAllString(h.Name, "-"), "-")
+	if alias == "" {
+		alias = "host-" + strconv.FormatUint(uint64(h.ID), 10)
+	}
+	return prefix + alias
+}
+
+// WriteSSHConfig 将主机写为 OpenSSH 配置块，别名重复时追加主机 ID
+// 字段模板按当前环境解析；无法解析的主机以注释形式跳过
+func WriteSSHConfig(w io.Writer, hosts []Host, opts SSHConfigOptions) error {
+	if opts.IdentityDir == "" {
+		opts.IdentityDir = DefaultIdentityDir
+	}
+
+	seen := make(map[string]bool)
+	for i := range hosts {
+		host, err := hosts[i].Resolve()
+		if err != nil {
+			if _, err := fmt.Fprintf(w, "# %s: skipped, %v\n\n", hosts[i].Name, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		alias := host.SSHConfigAlias(opts.AliasPrefix)
+		if seen[alias] {
+			alias = fmt.Sprintf("%s-%d", alias, host.ID)
+		}
+		seen[alias] = true
+
+		if _, err := io.WriteString(w, host.sshConfigBlock(alias, opts)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sshConfigBlock 生成单个主机的配置块
+func (h *Host) sshConfigBlock(alias string, opts SSHConfigOptions) string {
+	var b strings.Builder
+	if h.Description != "" {
+		fmt.Fprintf(&b, "# %s\n", strings.ReplaceAll(h.Description, "\n", " "))
+	}
+	fmt.Fprintf(&b, "Host %s\n", alias)
+	fmt.Fprintf(&b, "    HostName %s\n", h.Hostname)
+	if h.Username != "" {
+		fmt.Fprintf(&b, "    User %s\n", h.Username)
+	}
+	if h.Port != 0 && h.Port != 22 {
+		fmt.Fprintf(&b, "    Port %d\n", h.Port)
+	}
+
+	switch AuthMethod(h.AuthMethod) {
+	case AuthMethodPrivateKey, "key":
+		fmt.Fprintf(&b, "    IdentityFile %s\n", path.Join(opts.IdentityDir, alias))
+		b.WriteString("    IdentitiesOnly yes\n")
+	case AuthMethodPassword:
+		b.WriteString("    PreferredAuthentications password,keyboard-interactive\n")
+	}
+
+	if directive := h.sshProxyDirective(); directive != "" {
+		fmt.Fprintf(&b, "    %s\n", directive)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// sshProxyDirective 将主机的代理设置转换为 ProxyJump 或 ProxyCommand
+// ProxyURL 通过 OpenBSD nc 的 -X/-x 代理参数实现，凭据不会导出
+func (h *Host) sshProxyDirective() string {
+	if h.ProxyCommand != "" {
+		if match := jumpCommand.FindStringSubmatch(strings.TrimSpace(h.ProxyCommand)); match != nil {
+			return "ProxyJump " + match[1]
+		}
+		return "ProxyCommand " + h.ProxyCommand
+	}
+	if h.ProxyURL == "" {
+		return ""
+	}
+
+	proxy, err := url.Parse(h.ProxyURL)
+	if err != nil || proxy.Host == "" {
+		return ""
+	}
+	switch proxy.Scheme {
+	case "socks5", "socks5h":
+		return "ProxyCommand nc -X 5 -x " + hostWithDefaultPort(proxy, "1080") + " %h %p"
+	case "http", "https":
+		return "ProxyCommand nc -X connect -x " + hostWithDefaultPort(proxy, "3128") + " %h %p"
+	default:
+		return ""
+	}
+}
+
+// hostWithDefaultPort 代理地址，未指定端口时使用默认端口
+func hostWithDefaultPort(u *url.URL, port string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
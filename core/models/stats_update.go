@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+)
+
+// StatsUpdate 统计推送：自上次推送以来的流量增量与速率，供仪表盘实时显示
+type StatsUpdate struct {
+	Interval          time.Duration         `json:"interval"`            // 距上次推送的时间
+	BytesSent         int64                 `json:"bytes_sent"`          // 增量
+	BytesReceived     int64                 `json:"bytes_received"`      // 增量
+	SendRate          float64               `json:"send_rate"`           // 字节/秒
+	ReceiveRate       float64               `json:"receive_rate"`        // 字节/秒
+	NewConnections    int64                 `json:"new_connections"`     // 增量
+	ActiveConnections int64                 `json:"active_connections"`  // 当前值
+	Totals            SessionStats          `json:"totals"`              // 累计统计
+	Listeners         []ListenerStatsUpdate `json:"listeners,omitempty"` // 每个监听端口的增量
+}
+
+// ListenerStatsUpdate 单个监听端口自上次推送以来的增量
+type ListenerStatsUpdate struct {
+	Address           string  `json:"address"`
+	BytesSent         int64   `json:"bytes_sent"`
+	BytesReceived     int64   `json:"bytes_received"`
+	SendRate          float64 `json:"send_rate"`
+	ReceiveRate       float64 `json:"receive_rate"`
+	NewConnections    int64   `json:"new_connections"`
+	ActiveConnections int64   `json:"active_connections"`
+}
+
+// NewStatsUpdate returns the change from previous to current over interval,
+// or nil when nothing changed so idle sessions are not pushed
+func NewStatsUpdate(previous, current SessionStats, interval time.Duration) *StatsUpdate {
+	update := &StatsUpdate{
+		Interval:          interval,
+		BytesSent:         nonNegative(current.BytesSent - previous.BytesSent),
+		BytesReceived:     nonNegative(current.BytesReceived - previous.BytesReceived),
+		NewConnections:    nonNegative(current.TotalConnections - previous.TotalConnections),
+		ActiveConnections: current.ActiveConnections,
+		Totals:            current,
+	}
+	update.SendRate = perSecond(update.BytesSent, interval)
+	update.ReceiveRate = perSecond(update.BytesReceived, interval)
+
+	changed := update.BytesSent != 0 || update.BytesReceived != 0 || update.NewConnections != 0 ||
+		current.ActiveConnections != previous.ActiveConnections
+
+	previousListeners := make(map[string]ListenerStats, len(previous.Listeners))
+	for _, listener := range previous.Listeners {
+		previousListeners[listener.Address] = listener
+	}
+	for _, listener := range current.Listeners {
+		before := previousListeners[listener.Address]
+		delta := ListenerStatsUpdate{
+			Address:           listener.Address,
+			BytesSent:         nonNegative(listener.BytesSent - before.BytesSent),
+			BytesReceived:     nonNegative(listener.BytesReceived - before.BytesReceived),
+			NewConnections:    nonNegative(listener.TotalConnections - before.TotalConnections),
+			ActiveConnections: listener.ActiveConnections,
+		}
+		delta.SendRate = perSecond(delta.BytesSent, interval)
+		delta.ReceiveRate = perSecond(delta.BytesReceived, interval)
+		update.Listeners = append(update.Listeners, delta)
+	}
+
+	if !changed {
+		return nil
+	}
+	return update
+}
+
+// nonNegative treats counters that went backwards (a restarted tunnel) as no change
+func nonNegative(delta int64) int64 {
+	return max(delta, 0)
+}
+
+// perSecond converts a byte count over interval into a rate
+func perSecond(bytes int64, interval time.Duration) float64 {
+	if interval <= 0 {
+		return 0
+	}
+	return float64(bytes) / interval.Seconds()
+}
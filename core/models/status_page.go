@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// StatusCheck 公开状态页对已发布端口的一次检查
+type StatusCheck struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	PortID    uint      `gorm:"not null;index:idx_status_check_port,priority:1" json:"port_id"`
+	CheckedAt time.Time `gorm:"not null;index;index:idx_status_check_port,priority:2" json:"checked_at"`
+
+	Up        bool     `json:"up"`
+	LatencyMs *float64 `json:"latency_ms,omitempty"`            // 无法测量时为空，如监听在 SSH 服务端的远程端口
+	Error     string   `gorm:"size:255" json:"error,omitempty"` // 失败原因，可能包含内部地址，不在公开页面上显示
+}
+
+// StatusUptime 一段时间内的检查统计
+type StatusUptime struct {
+	Checks       int64    `json:"checks"`
+	Up           int64    `json:"up"`
+	AvgLatencyMs *float64 `json:"avg_latency_ms,omitempty"`
+}
+
+// Percent 可用率（百分比），没有检查时返回 nil
+func (u *StatusUptime) Percent() *float64 {
+	if u.Checks == 0 {
+		return nil
+	}
+	percent := float64(u.Up) * 100 / float64(u.Checks)
+	return &percent
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// ErrInvalidTemplate 模板语法错误或渲染失败
+var ErrInvalidTemplate = errors.New("invalid template")
+
+// TemplateData 端口/主机字段模板可引用的变量，如 {{ .Host.Hostname }}
+type TemplateData struct {
+	Port  *Port
+	Host  *Host
+	Group *Group
+}
+
+// templateFuncs 模板函数：{{ env "STAGE" }} 读取服务端环境变量，
+// {{ env "STAGE" | default "dev" }} 在值为空时使用默认值
+var templateFuncs = template.FuncMap{
+	"env": os.Getenv,
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// IsTemplate 字段是否包含模板占位符
+func IsTemplate(text string) bool {
+	return strings.Contains(text, "{{")
+}
+
+// parseFieldTemplate parses a templated field; unknown fields are errors so
+// typos fail at save time rather than resolving to "<no value>"
+func parseFieldTemplate(field, text string) (*template.Template, error) {
+	tmpl, err := template.New(field).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidTemplate, field, err)
+	}
+	return tmpl, nil
+}
+
+// renderField 渲染单个字段，不含占位符时原样返回
+func renderField(field, text string, data TemplateData) (string, error) {
+	if !IsTemplate(text) {
+		return text, nil
+	}
+	tmpl, err := parseFieldTemplate(field, text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidTemplate, field, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// validateFields 检查模板语法，保存时调用
+func validateFields(fields map[string]string) error {
+	for field, text := range fields {
+		if !IsTemplate(text) {
+			continue
+		}
+		if _, err := parseFieldTemplate(field, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateFields 端口中支持模板的字段
+func (p *Port) templateFields() map[string]string {
+	fields := map[string]string{
+		"bind_address":        p.BindAddress,
+		"remote_bind_address": p.RemoteBindAddress,
+		"description":         p.Description,
+	}
+	for i, bind := range p.ExtraBinds {
+		fields[fmt.Sprintf("extra_binds[%d].address", i)] = bind.Address
+	}
+	return fields
+}
+
+// ValidateTemplates 检查端口字段的模板语法
+func (p *Port) ValidateTemplates() error {
+	return validateFields(p.templateFields())
+}
+
+// Resolve 在启动时渲染端口字段模板，返回解析后的副本，原定义保持不变
+// 关联主机的字段先行解析，因此 {{ .Host.Hostname }} 得到的是最终主机名
+func (p *Port) Resolve() (*Port, error) {
+	resolved := *p
+	if p.Host != nil {
+		host, err := p.Host.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		resolved.Host = host
+	}
+	data := TemplateData{Port: p, Host: resolved.Host, Group: &p.Group}
+
+	var err error
+	if resolved.BindAddress, err = renderField("bind_address", p.BindAddress, data); err != nil {
+		return nil, err
+	}
+	if resolved.RemoteBindAddress, err = renderField("remote_bind_address", p.RemoteBindAddress, data); err != nil {
+		return nil, err
+	}
+	if resolved.Description, err = renderField("description", p.Description, data); err != nil {
+		return nil, err
+	}
+	resolved.ExtraBinds = make([]BindSpec, len(p.ExtraBinds))
+	for i, bind := range p.ExtraBinds {
+		if bind.Address, err = renderField(fmt.Sprintf("extra_binds[%d].address", i), bind.Address, data); err != nil {
+			return nil, err
+		}
+		resolved.ExtraBinds[i] = bind
+	}
+
+	// Templated remote bind addresses skip the loopback check when saved
+	if resolved.IsRemotePort() && !resolved.AllowRemoteConnections && !IsLoopbackAddress(resolved.GetRemoteBindAddress()) {
+		return nil, fmt.Errorf("%w: %s", ErrRemoteBindNotAllowed, resolved.RemoteBindAddress)
+	}
+	return &resolved, nil
+}
+
+// templateFields 主机中支持模板的字段
+func (h *Host) templateFields() map[string]string {
+	return map[string]string{
+		"hostname":    h.Hostname,
+		"description": h.Description,
+	}
+}
+
+// ValidateTemplates 检查主机字段的模板语法
+func (h *Host) ValidateTemplates() error {
+	return validateFields(h.templateFields())
+}
+
+// Resolve 在连接时渲染主机字段模板，返回解析后的副本
+func (h *Host) Resolve() (*Host, error) {
+	resolved := *h
+	data := TemplateData{Host: h, Group: &h.Group}
+
+	var err error
+	if resolved.Hostname, err = renderField("hostname", h.Hostname, data); err != nil {
+		return nil, err
+	}
+	if resolved.Description, err = renderField("description", h.Description, data); err != nil {
+		return nil, err
+	}
+	if resolved.Hostname == "" {
+		return nil, fmt.Errorf("%w: hostname resolved to an empty value", ErrInvalidTemplate)
+	}
+	return &resolved, nil
+}
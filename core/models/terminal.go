@@ -0,0 +1,108 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// TerminalRestoreWindow 服务器停止后中断的终端可恢复的时长
+const TerminalRestoreWindow = 24 * time.Hour
+
+// TerminalRecord 网页终端会话的持久化信息（主机、尺寸、环境变量），
+// 服务器重启后前端凭恢复令牌重新打开到同一主机的终端
+type TerminalRecord struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Token  string            `gorm:"size:64;uniqueIndex" json:"token"` // 恢复令牌，前端保存后用于重新打开终端
+	HostID uint              `gorm:"not null;index" json:"host_id"`
+	Cols   int               `json:"cols"`
+	Rows   int               `json:"rows"`
+	Shell  string            `gorm:"size:100" json:"shell,omitempty"`
+	Env    map[string]string `gorm:"type:text;serializer:json" json:"env,omitempty"`
+
+	InterruptedAt *time.Time `gorm:"index" json:"interrupted_at,omitempty"` // 服务器停止时终端仍打开，非空表示可恢复
+	RestoredAt    *time.Time `json:"restored_at,omitempty"`                 // 最近一次恢复的时间
+	Restores      int        `json:"restores"`                              // 已恢复的次数
+}
+
+// Restorable 终端是否因服务器停止而中断，且仍在可恢复时长内
+func (r *TerminalRecord) Restorable(now time.Time) bool {
+	return r.InterruptedAt != nil && now.Sub(*r.InterruptedAt) < TerminalRestoreWindow
+}
+
+// 终端被强制关闭的原因
+const (
+	TerminalClosedIdle        = "idle_timeout" // 没有输入超过空闲超时
+	TerminalClosedMaxDuration = "max_duration" // 打开超过最长时长
+)
+
+// DefaultTerminalWarnBefore 未配置时，断开前在终端中提示的提前量（秒）
+const DefaultTerminalWarnBefore = 60
+
+// ErrInvalidTerminalPolicy is returned for limits below -1
+var ErrInvalidTerminalPolicy = errors.New("terminal policy limits must be -1 (no limit), 0 (inherit) or positive seconds")
+
+// TerminalPolicy 终端会话的空闲超时和最长时长（秒）。主机和分组上为 0 的项沿用上一级
+// （主机 → 分组 → 上级分组 → 全局配置），为 -1 时在该级取消限制
+type TerminalPolicy struct {
+	IdleTimeout int `json:"idle_timeout,omitempty"` // 没有输入超过该时长后断开
+	MaxDuration int `json:"max_duration,omitempty"` // 终端打开超过该时长后断开
+	WarnBefore  int `json:"warn_before,omitempty"`  // 断开前多久在终端中提示，默认 60 秒
+}
+
+// Validate 验证终端策略
+func (p *TerminalPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.IdleTimeout < -1 || p.MaxDuration < -1 || p.WarnBefore < -1 {
+		return ErrInvalidTerminalPolicy
+	}
+	return nil
+}
+
+// Inherit fills the limits p leaves at 0 from parent
+func (p TerminalPolicy) Inherit(parent *TerminalPolicy) TerminalPolicy {
+	if parent == nil {
+		return p
+	}
+	if p.IdleTimeout == 0 {
+		p.IdleTimeout = parent.IdleTimeout
+	}
+	if p.MaxDuration == 0 {
+		p.MaxDuration = parent.MaxDuration
+	}
+	if p.WarnBefore == 0 {
+		p.WarnBefore = parent.WarnBefore
+	}
+	return p
+}
+
+// Limits returns the resolved policy as durations, 0 meaning no limit or,
+// for the warning, no warning
+func (p TerminalPolicy) Limits() (idle, maxDuration, warnBefore time.Duration) {
+	seconds := func(value int) time.Duration {
+		return time.Duration(max(value, 0)) * time.Second
+	}
+	warn := p.WarnBefore
+	if warn == 0 {
+		warn = DefaultTerminalWarnBefore
+	}
+	return seconds(p.IdleTimeout), seconds(p.MaxDuration), seconds(warn)
+}
+
+// TerminalClosure 因空闲超时或超过最长时长被强制关闭的终端，作为审计记录保存
+type TerminalClosure struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"` // 关闭时间
+
+	SessionID   string    `gorm:"size:100;index" json:"session_id"`
+	HostID      uint      `gorm:"not null;index" json:"host_id"`
+	Actor       string    `gorm:"size:255" json:"actor,omitempty"` // 打开终端的用户（X-PortFly-Actor 请求头，缺省为客户端地址）
+	Reason      string    `gorm:"size:20;not null" json:"reason"`  // idle_timeout 或 max_duration
+	Limit       int       `json:"limit"`                           // 触发关闭的限制（秒）
+	OpenedAt    time.Time `json:"opened_at"`
+	LastInputAt time.Time `json:"last_input_at"`
+}
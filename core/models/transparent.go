@@ -0,0 +1,101 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+)
+
+// Transparent proxy validation errors
+var (
+	ErrTransparentNoDestinations = errors.New("transparent proxy needs at least one destination CIDR")
+	ErrTransparentInvalidCIDR    = errors.New("invalid transparent proxy CIDR")
+	ErrTransparentInvalidPort    = errors.New("invalid transparent proxy port")
+	ErrTransparentFirewall       = errors.New("invalid firewall (expected iptables or pf)")
+)
+
+// Firewalls that can redirect connections to a transparent proxy
+const (
+	FirewallIPTables = "iptables" // Linux: nat REDIRECT, original destination from SO_ORIGINAL_DST
+	FirewallPF       = "pf"       // OpenBSD: divert-to, original destination kept as the local address
+)
+
+// TransparentProxy 透明代理：防火墙将发往指定网段的 TCP 连接重定向到本地端口，
+// 再经动态隧道转发到原始目标，应用程序无需配置 SOCKS
+type TransparentProxy struct {
+	Port         int      `json:"port"`               // 重定向规则指向的本地端口，只监听回环地址
+	Destinations []string `json:"destinations"`       // 经隧道转发的目标网段（CIDR）
+	Exclude      []string `json:"exclude,omitempty"`  // 不重定向的网段，如 SSH 服务器本身
+	Firewall     string   `json:"firewall,omitempty"` // iptables 或 pf，为空时按平台选择
+}
+
+// Validate 验证透明代理配置
+func (t TransparentProxy) Validate() error {
+	if t.Port <= 0 || t.Port > 65535 {
+		return fmt.Errorf("%w: %d", ErrTransparentInvalidPort, t.Port)
+	}
+	if len(t.Destinations) == 0 {
+		return ErrTransparentNoDestinations
+	}
+	for _, cidr := range append(slices.Clone(t.Destinations), t.Exclude...) {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("%w %q: %v", ErrTransparentInvalidCIDR, cidr, err)
+		}
+	}
+	switch t.Firewall {
+	case "", FirewallIPTables, FirewallPF:
+	default:
+		return fmt.Errorf("%w: %s", ErrTransparentFirewall, t.Firewall)
+	}
+	return nil
+}
+
+// Prefixes returns the destination and excluded networks, split by address family
+func (t TransparentProxy) Prefixes() (destinations, exclude []netip.Prefix) {
+	for _, cidr := range t.Destinations {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			destinations = append(destinations, prefix.Masked())
+		}
+	}
+	for _, cidr := range t.Exclude {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			exclude = append(exclude, prefix.Masked())
+		}
+	}
+	return destinations, exclude
+}
+
+// Binds returns the loopback listeners the redirected connections arrive on:
+// 127.0.0.1, plus ::1 when an IPv6 network is redirected
+func (t TransparentProxy) Binds() []BindSpec {
+	binds := []BindSpec{{Address: "127.0.0.1", Port: t.Port}}
+	destinations, _ := t.Prefixes()
+	if slices.ContainsFunc(destinations, func(p netip.Prefix) bool { return p.Addr().Is6() }) {
+		binds = append(binds, BindSpec{Address: "::1", Port: t.Port})
+	}
+	return binds
+}
+
+// Excluded reports whether connections to addr bypass the redirect rules
+func (t TransparentProxy) Excluded(addr netip.Addr) bool {
+	destinations, exclude := t.Prefixes()
+	addr = addr.Unmap()
+	for _, prefix := range exclude {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return !slices.ContainsFunc(destinations, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
+
+// HostPrefix returns the single-address network of ip, e.g. for excluding the SSH server
+func HostPrefix(ip net.IP) string {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return ""
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()).String()
+}
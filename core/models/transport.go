@@ -0,0 +1,64 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Transport 端口转发的传输方式
+type Transport string
+
+const (
+	TransportSSH       Transport = "ssh"       // 通过 SSH 连接转发（默认）
+	TransportWireGuard Transport = "wireguard" // 通过已有的 WireGuard 接口直接连接目标
+)
+
+// Transport validation errors
+var (
+	ErrInvalidTransport           = errors.New("transport must be ssh or wireguard")
+	ErrWireGuardInterfaceRequired = errors.New("wireguard transport requires an interface")
+	ErrRemoteForwardOverWireGuard = errors.New("remote forwarding needs the SSH server to listen and is not available over wireguard")
+)
+
+// WireGuardConfig 通过已有 WireGuard 接口转发的配置。接口由系统（wg-quick、NetworkManager 等）管理，
+// 本地转发和 SOCKS 代理从接口地址直接连接目标，目标须在对端的 AllowedIPs 内
+type WireGuardConfig struct {
+	Interface string `json:"interface"`      // 本机 WireGuard 接口名，如 wg0
+	Peer      string `json:"peer,omitempty"` // 主机在隧道内的地址，目标为 localhost 时连接该地址；为空时使用主机地址
+}
+
+// Validate 验证 WireGuard 配置
+func (w WireGuardConfig) Validate() error {
+	if strings.TrimSpace(w.Interface) == "" {
+		return ErrWireGuardInterfaceRequired
+	}
+	return nil
+}
+
+// ParseTransport normalizes a transport name; empty means SSH
+func ParseTransport(name string) (Transport, error) {
+	switch transport := Transport(strings.ToLower(strings.TrimSpace(name))); transport {
+	case "", TransportSSH:
+		return TransportSSH, nil
+	case TransportWireGuard, "wg":
+		return TransportWireGuard, nil
+	default:
+		return "", fmt.Errorf("%w, got %q", ErrInvalidTransport, name)
+	}
+}
+
+// ValidateTransport checks that a tunnel can run over the connection's
+// transport
+func (c SSHConnectionConfig) ValidateTransport(tunnel TunnelConfig) error {
+	if c.WireGuard == nil {
+		return nil
+	}
+	if err := c.WireGuard.Validate(); err != nil {
+		return err
+	}
+	if tunnel.Type == TunnelTypeRemote {
+		return ErrRemoteForwardOverWireGuard
+	}
+	return nil
+}
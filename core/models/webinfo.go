@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// WebApp 转发的 Web 服务识别出的应用
+type WebApp string
+
+const (
+	WebAppGrafana       WebApp = "grafana"
+	WebAppJenkins       WebApp = "jenkins"
+	WebAppPgAdmin       WebApp = "pgadmin"
+	WebAppPrometheus    WebApp = "prometheus"
+	WebAppAlertmanager  WebApp = "alertmanager"
+	WebAppKibana        WebApp = "kibana"
+	WebAppElasticsearch WebApp = "elasticsearch"
+	WebAppGitLab        WebApp = "gitlab"
+	WebAppGitea         WebApp = "gitea"
+	WebAppRabbitMQ      WebApp = "rabbitmq"
+	WebAppPortainer     WebApp = "portainer"
+	WebAppJupyter       WebApp = "jupyter"
+	WebAppArgoCD        WebApp = "argocd"
+	WebAppKubeDashboard WebApp = "kubernetes-dashboard"
+	WebAppPhpMyAdmin    WebApp = "phpmyadmin"
+	WebAppAdminer       WebApp = "adminer"
+	WebAppMinIO         WebApp = "minio"
+	WebAppAirflow       WebApp = "airflow"
+	WebAppSonarQube     WebApp = "sonarqube"
+	WebAppConsul        WebApp = "consul"
+	WebAppVault         WebApp = "vault"
+	WebAppTraefik       WebApp = "traefik"
+)
+
+// webAppNames 应用的显示名称
+var webAppNames = map[WebApp]string{
+	WebAppGrafana:       "Grafana",
+	WebAppJenkins:       "Jenkins",
+	WebAppPgAdmin:       "pgAdmin",
+	WebAppPrometheus:    "Prometheus",
+	WebAppAlertmanager:  "Alertmanager",
+	WebAppKibana:        "Kibana",
+	WebAppElasticsearch: "Elasticsearch",
+	WebAppGitLab:        "GitLab",
+	WebAppGitea:         "Gitea",
+	WebAppRabbitMQ:      "RabbitMQ",
+	WebAppPortainer:     "Portainer",
+	WebAppJupyter:       "Jupyter",
+	WebAppArgoCD:        "Argo CD",
+	WebAppKubeDashboard: "Kubernetes Dashboard",
+	WebAppPhpMyAdmin:    "phpMyAdmin",
+	WebAppAdminer:       "Adminer",
+	WebAppMinIO:         "MinIO",
+	WebAppAirflow:       "Airflow",
+	WebAppSonarQube:     "SonarQube",
+	WebAppConsul:        "Consul",
+	WebAppVault:         "Vault",
+	WebAppTraefik:       "Traefik",
+}
+
+// Name 显示名称，未知应用返回标识本身
+func (a WebApp) Name() string {
+	if name, exists := webAppNames[a]; exists {
+		return name
+	}
+	return string(a)
+}
+
+// WebInfo 本地 HTTP 转发启动后探测到的页面信息，用于界面卡片和分享片段
+type WebInfo struct {
+	App         WebApp     `gorm:"size:30" json:"app,omitempty"`          // 识别出的应用，未识别时为空
+	AppName     string     `gorm:"size:50" json:"app_name,omitempty"`     // 应用的显示名称
+	Title       string     `gorm:"size:255" json:"title,omitempty"`       // 首页标题
+	FaviconType string     `gorm:"size:50" json:"favicon_type,omitempty"` // 图标的 MIME 类型，图标通过 GET /ports/:id/favicon 获取
+	Favicon     []byte     `json:"-"`
+	DetectedAt  *time.Time `json:"detected_at,omitempty"`
+}
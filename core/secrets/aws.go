@@ -0,0 +1,202 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// AWSProvider resolves "aws:<secret-id>#<key>" references using AWS Secrets Manager.
+// The secret id may be a name or a full ARN; with a key, SecretString is parsed as JSON.
+type AWSProvider struct {
+	config   AWSConfig
+	endpoint string
+	client   *http.Client
+}
+
+// NewAWSProvider creates an AWS Secrets Manager provider, falling back to the standard AWS_* environment variables
+func NewAWSProvider(config AWSConfig) (*AWSProvider, error) {
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_REGION")
+	}
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if config.AccessKeyID == "" {
+		config.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		config.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		config.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	if config.Region == "" {
+		return nil, fmt.Errorf("aws region is required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("aws credentials are required")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", config.Region)
+	}
+
+	return &AWSProvider{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Name returns the provider name
+func (a *AWSProvider) Name() string {
+	return "aws"
+}
+
+// Resolve fetches the secret value for ref.Path, optionally selecting the JSON field ref.Key
+func (a *AWSProvider) Resolve(ctx context.Context, ref Reference) (string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": ref.Path})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create secrets manager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	a.sign(req, body, time.Now().UTC())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("secrets manager request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read secrets manager response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &apiErr)
+		if strings.HasSuffix(apiErr.Type, "ResourceNotFoundException") {
+			return "", fmt.Errorf("%w: %s", ErrSecretNotFound, ref.Path)
+		}
+		return "", fmt.Errorf("secrets manager returned %s: %s %s", resp.Status, apiErr.Type, apiErr.Message)
+	}
+
+	var payload struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary string `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(respBody, &payload); err != nil {
+		return "", fmt.Errorf("failed to decode secrets manager response: %w", err)
+	}
+
+	secret := payload.SecretString
+	if secret == "" && payload.SecretBinary != "" {
+		decoded, err := base64.StdEncoding.DecodeString(payload.SecretBinary)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode binary secret: %w", err)
+		}
+		secret = string(decoded)
+	}
+
+	if ref.Key == "" {
+		return secret, nil
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &data); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, cannot select key %q", ref.Path, ref.Key)
+	}
+	return extractKey(data, ref.Key)
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (a *AWSProvider) sign(req *http.Request, body []byte, now time.Time) {
+	const service = "secretsmanager"
+
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.config.SessionToken)
+	}
+
+	host := req.URL.Host
+	if u, err := url.Parse(a.endpoint); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	headers := map[string]string{
+		"content-type": req.Header.Get("Content-Type"),
+		"host":         host,
+		"x-amz-date":   amzDate,
+		"x-amz-target": req.Header.Get("X-Amz-Target"),
+	}
+	names := []string{"content-type", "host", "x-amz-date"}
+	if a.config.SessionToken != "" {
+		headers["x-amz-security-token"] = a.config.SessionToken
+		names = append(names, "x-amz-security-token")
+	}
+	names = append(names, "x-amz-target")
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + a.config.Region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+a.config.SecretAccessKey), date)
+	key = hmacSHA256(key, a.config.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.config.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// hmacSHA256 computes HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,53 @@
+package secrets
+
+import "time"
+
+// Config holds secrets provider configuration; unset providers are disabled
+type Config struct {
+	Vault *VaultConfig `json:"vault,omitempty" yaml:"vault,omitempty"`
+	AWS   *AWSConfig   `json:"aws,omitempty" yaml:"aws,omitempty"`
+}
+
+// VaultConfig configures the HashiCorp Vault provider
+type VaultConfig struct {
+	Address   string        `json:"address" yaml:"address"`     // defaults to $VAULT_ADDR
+	Token     string        `json:"token" yaml:"token"`         // defaults to $VAULT_TOKEN
+	Namespace string        `json:"namespace" yaml:"namespace"` // Vault Enterprise namespace
+	Timeout   time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// AWSConfig configures the AWS Secrets Manager provider
+type AWSConfig struct {
+	Region          string        `json:"region" yaml:"region"`                       // defaults to $AWS_REGION
+	AccessKeyID     string        `json:"access_key_id" yaml:"access_key_id"`         // defaults to $AWS_ACCESS_KEY_ID
+	SecretAccessKey string        `json:"secret_access_key" yaml:"secret_access_key"` // defaults to $AWS_SECRET_ACCESS_KEY
+	SessionToken    string        `json:"session_token" yaml:"session_token"`         // defaults to $AWS_SESSION_TOKEN
+	Endpoint        string        `json:"endpoint" yaml:"endpoint"`                   // override for VPC endpoints or testing
+	Timeout         time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// defaultTimeout bounds a single secret lookup
+const defaultTimeout = 10 * time.Second
+
+// NewRegistryFromConfig creates a registry with the configured providers
+func NewRegistryFromConfig(config Config) (*Registry, error) {
+	registry := NewRegistry()
+
+	if config.Vault != nil {
+		provider, err := NewVaultProvider(*config.Vault)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(provider)
+	}
+
+	if config.AWS != nil {
+		provider, err := NewAWSProvider(*config.AWS)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(provider)
+	}
+
+	return registry, nil
+}
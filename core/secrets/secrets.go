@@ -0,0 +1,167 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Common errors
+var (
+	ErrProviderNotConfigured = errors.New("secrets provider not configured")
+	ErrSecretNotFound        = errors.New("secret not found")
+)
+
+// Provider resolves secret references against an external secret store
+type Provider interface {
+	// Name returns the reference scheme handled by this provider, e.g. "vault"
+	Name() string
+	// Resolve fetches the secret value for the reference
+	Resolve(ctx context.Context, ref Reference) (string, error)
+}
+
+// Reference identifies a secret in an external store.
+// Written as "<provider>:<path>#<key>", e.g. "vault:kv/data/prod/db#ssh_key".
+type Reference struct {
+	Provider string
+	Path     string
+	Key      string
+}
+
+// knownSchemes lists the reference prefixes recognised as secret references;
+// any other value is treated as a literal credential
+var knownSchemes = map[string]bool{
+	"vault": true,
+	"aws":   true,
+}
+
+// ParseReference parses value as a secret reference.
+// It returns false if value is not a reference.
+func ParseReference(value string) (Reference, bool) {
+	scheme, rest, ok := strings.Cut(value, ":")
+	if !ok || !knownSchemes[scheme] || rest == "" {
+		return Reference{}, false
+	}
+
+	path, key, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return Reference{}, false
+	}
+
+	return Reference{Provider: scheme, Path: path, Key: key}, true
+}
+
+// IsReference reports whether value is a secret reference
+func IsReference(value string) bool {
+	_, ok := ParseReference(value)
+	return ok
+}
+
+// String returns the reference in its "<provider>:<path>#<key>" form
+func (r Reference) String() string {
+	if r.Key == "" {
+		return r.Provider + ":" + r.Path
+	}
+	return r.Provider + ":" + r.Path + "#" + r.Key
+}
+
+// Registry dispatches secret references to registered providers
+type Registry struct {
+	providers map[string]Provider
+	mu        sync.RWMutex
+}
+
+// NewRegistry creates an empty provider registry
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: make(map[string]Provider),
+	}
+}
+
+// Register adds a provider, replacing any provider with the same name
+func (r *Registry) Register(provider Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[provider.Name()] = provider
+}
+
+// Providers returns the names of registered providers
+func (r *Registry) Providers() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the secret for value if it is a reference, or value unchanged otherwise
+func (r *Registry) Resolve(ctx context.Context, value string) (string, error) {
+	ref, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+
+	var provider Provider
+	if r != nil {
+		r.mu.RLock()
+		provider = r.providers[ref.Provider]
+		r.mu.RUnlock()
+	}
+	if provider == nil {
+		return "", fmt.Errorf("%w: %s", ErrProviderNotConfigured, ref.Provider)
+	}
+
+	secret, err := provider.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %s: %w", ref, err)
+	}
+
+	return secret, nil
+}
+
+// extractKey picks a field from a secret's key/value data.
+// Without a key, a secret holding exactly one field resolves to that field.
+func extractKey(data map[string]interface{}, key string) (string, error) {
+	if key == "" {
+		if len(data) != 1 {
+			return "", fmt.Errorf("secret has %d fields, a #key is required", len(data))
+		}
+		for _, value := range data {
+			return stringValue(value)
+		}
+	}
+
+	value, exists := data[key]
+	if !exists {
+		return "", fmt.Errorf("%w: key %q", ErrSecretNotFound, key)
+	}
+	return stringValue(value)
+}
+
+// stringValue converts a decoded JSON value to a secret string
+func stringValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", fmt.Errorf("secret value is empty")
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode secret value: %w", err)
+		}
+		return string(encoded), nil
+	}
+}
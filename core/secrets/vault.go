@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// VaultProvider resolves "vault:<path>#<key>" references using the Vault HTTP API.
+// Both KV v1 and KV v2 (paths containing /data/) mounts are supported.
+type VaultProvider struct {
+	config VaultConfig
+	client *http.Client
+}
+
+// NewVaultProvider creates a Vault provider, falling back to VAULT_ADDR and VAULT_TOKEN
+func NewVaultProvider(config VaultConfig) (*VaultProvider, error) {
+	if config.Address == "" {
+		config.Address = os.Getenv("VAULT_ADDR")
+	}
+	if config.Token == "" {
+		config.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if config.Namespace == "" {
+		config.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+
+	if config.Address == "" {
+		return nil, fmt.Errorf("vault address is required")
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("vault token is required")
+	}
+
+	return &VaultProvider{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Name returns the provider name
+func (v *VaultProvider) Name() string {
+	return "vault"
+}
+
+// Resolve reads the secret at ref.Path and returns the field ref.Key
+func (v *VaultProvider) Resolve(ctx context.Context, ref Reference) (string, error) {
+	url := strings.TrimRight(v.config.Address, "/") + "/v1/" + strings.TrimLeft(ref.Path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.config.Token)
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%w: vault path %s", ErrSecretNotFound, ref.Path)
+	case http.StatusForbidden:
+		return "", fmt.Errorf("vault denied access to %s", ref.Path)
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	data := payload.Data
+	// KV v2 nests the secret under data.data alongside data.metadata
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMetadata := data["metadata"]; hasMetadata {
+			data = inner
+		}
+	}
+
+	return extractKey(data, ref.Key)
+}
@@ -0,0 +1,550 @@
+package sftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+)
+
+// Transfer tuning: requests of chunkSize bytes, up to maxInflight of them
+// awaiting a reply, so throughput does not hinge on the round-trip time
+const (
+	chunkSize   = 32 * 1024
+	maxInflight = 16
+)
+
+// ErrChecksumMismatch is returned when a file read back differs from what was sent
+var ErrChecksumMismatch = errors.New("checksum mismatch after upload")
+
+// response is a reply packet, its payload starting after the request ID
+type response struct {
+	typ  byte
+	data []byte
+}
+
+// Client is an SFTP client over a subsystem channel. Requests may be issued
+// concurrently; replies are matched to them by ID.
+type Client struct {
+	rwc        io.ReadWriteCloser
+	extensions map[string]string
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  uint32
+	pending map[uint32]chan response
+	err     error
+	done    chan struct{}
+}
+
+// NewClient negotiates the protocol version over rwc, typically the stdin and
+// stdout of an "sftp" subsystem session, and starts reading replies
+func NewClient(rwc io.ReadWriteCloser) (*Client, error) {
+	if err := writePacket(rwc, packetInit, binary.BigEndian.AppendUint32(nil, protocolVersion)); err != nil {
+		return nil, fmt.Errorf("sftp: failed to send init: %w", err)
+	}
+	typ, payload, err := readPacket(rwc)
+	if err != nil {
+		return nil, fmt.Errorf("sftp: failed to read version: %w", err)
+	}
+	if typ != packetVersion {
+		return nil, fmt.Errorf("sftp: expected version packet, got type %d", typ)
+	}
+
+	d := decoder{data: payload}
+	if version := d.uint32(); d.err == nil && version < protocolVersion {
+		return nil, fmt.Errorf("sftp: unsupported server version %d", version)
+	}
+	extensions := make(map[string]string)
+	for len(d.data) > 0 && d.err == nil {
+		name := d.string()
+		extensions[name] = d.string()
+	}
+
+	c := &Client{
+		rwc:        rwc,
+		extensions: extensions,
+		pending:    make(map[uint32]chan response),
+		done:       make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// Close ends the session
+func (c *Client) Close() error {
+	return c.rwc.Close()
+}
+
+// readLoop delivers replies to the requests waiting for them
+func (c *Client) readLoop() {
+	for {
+		typ, payload, err := readPacket(c.rwc)
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		d := decoder{data: payload}
+		id := d.uint32()
+		if d.err != nil {
+			c.fail(d.err)
+			return
+		}
+
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+		if ok {
+			ch <- response{typ: typ, data: d.data}
+		}
+	}
+}
+
+// fail ends all pending and future requests with err
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("sftp: connection closed")
+		}
+		c.err = err
+		close(c.done)
+	}
+}
+
+// send issues a request and returns the channel its reply arrives on
+func (c *Client) send(typ byte, payload []byte) (chan response, error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	id := c.nextID
+	c.nextID++
+	ch := make(chan response, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	packet := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(payload)), id)
+	packet = append(packet, payload...)
+
+	c.writeMu.Lock()
+	err := writePacket(c.rwc, typ, packet)
+	c.writeMu.Unlock()
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		c.fail(err)
+		return nil, err
+	}
+	return ch, nil
+}
+
+// wait returns the reply arriving on ch
+func (c *Client) wait(ctx context.Context, ch chan response) (response, error) {
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-c.done:
+		// A reply delivered just before the failure still counts
+		select {
+		case resp := <-ch:
+			return resp, nil
+		default:
+		}
+		return response{}, c.err
+	case <-ctx.Done():
+		return response{}, ctx.Err()
+	}
+}
+
+// request issues a request and waits for its reply
+func (c *Client) request(ctx context.Context, typ byte, payload []byte) (response, error) {
+	ch, err := c.send(typ, payload)
+	if err != nil {
+		return response{}, err
+	}
+	return c.wait(ctx, ch)
+}
+
+// status converts a status reply to an error: nil for OK, io.EOF for EOF
+func status(resp response) error {
+	if resp.typ != packetStatus {
+		return fmt.Errorf("sftp: unexpected reply type %d", resp.typ)
+	}
+	d := decoder{data: resp.data}
+	code := d.uint32()
+	message := d.string()
+	if d.err != nil {
+		// Version 3 servers may omit the message
+		message = ""
+	}
+	switch code {
+	case StatusOK:
+		return nil
+	case StatusEOF:
+		return io.EOF
+	default:
+		return &StatusError{Code: code, Message: message}
+	}
+}
+
+// statusRequest issues a request answered by a status
+func (c *Client) statusRequest(ctx context.Context, typ byte, payload []byte) error {
+	resp, err := c.request(ctx, typ, payload)
+	if err != nil {
+		return err
+	}
+	return status(resp)
+}
+
+// Open opens a remote file and returns its handle
+func (c *Client) Open(ctx context.Context, name string, flags uint32, attrs Attributes) (string, error) {
+	payload := appendString(nil, name)
+	payload = binary.BigEndian.AppendUint32(payload, flags)
+	payload = attrs.appendTo(payload)
+
+	resp, err := c.request(ctx, packetOpen, payload)
+	if err != nil {
+		return "", err
+	}
+	if resp.typ != packetHandle {
+		return "", status(resp)
+	}
+	d := decoder{data: resp.data}
+	handle := d.string()
+	return handle, d.err
+}
+
+// CloseHandle closes a file or directory handle
+func (c *Client) CloseHandle(ctx context.Context, handle string) error {
+	return c.statusRequest(ctx, packetClose, appendString(nil, handle))
+}
+
+// ReadAt reads up to length bytes at offset; io.EOF marks the end of the file
+func (c *Client) ReadAt(ctx context.Context, handle string, offset uint64, length uint32) ([]byte, error) {
+	ch, err := c.sendRead(handle, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return c.readReply(ctx, ch)
+}
+
+func (c *Client) sendRead(handle string, offset uint64, length uint32) (chan response, error) {
+	payload := appendString(nil, handle)
+	payload = binary.BigEndian.AppendUint64(payload, offset)
+	payload = binary.BigEndian.AppendUint32(payload, length)
+	return c.send(packetRead, payload)
+}
+
+func (c *Client) readReply(ctx context.Context, ch chan response) ([]byte, error) {
+	resp, err := c.wait(ctx, ch)
+	if err != nil {
+		return nil, err
+	}
+	if resp.typ != packetData {
+		return nil, status(resp)
+	}
+	d := decoder{data: resp.data}
+	data := d.bytes()
+	return data, d.err
+}
+
+// WriteAt writes data at offset
+func (c *Client) WriteAt(ctx context.Context, handle string, offset uint64, data []byte) error {
+	ch, err := c.sendWrite(handle, offset, data)
+	if err != nil {
+		return err
+	}
+	resp, err := c.wait(ctx, ch)
+	if err != nil {
+		return err
+	}
+	return status(resp)
+}
+
+func (c *Client) sendWrite(handle string, offset uint64, data []byte) (chan response, error) {
+	payload := appendString(make([]byte, 0, 16+len(handle)+len(data)), handle)
+	payload = binary.BigEndian.AppendUint64(payload, offset)
+	payload = appendBytes(payload, data)
+	return c.send(packetWrite, payload)
+}
+
+// Stat returns the attributes of a remote path, following symlinks
+func (c *Client) Stat(ctx context.Context, name string) (Attributes, error) {
+	resp, err := c.request(ctx, packetStat, appendString(nil, name))
+	if err != nil {
+		return Attributes{}, err
+	}
+	if resp.typ != packetAttrs {
+		return Attributes{}, status(resp)
+	}
+	d := decoder{data: resp.data}
+	attrs := d.attributes()
+	return attrs, d.err
+}
+
+// Realpath canonicalizes a remote path; relative paths, including ".", are
+// resolved against the directory the session started in, usually the home
+// directory
+func (c *Client) Realpath(ctx context.Context, name string) (string, error) {
+	resp, err := c.request(ctx, packetRealpath, appendString(nil, name))
+	if err != nil {
+		return "", err
+	}
+	if resp.typ != packetName {
+		return "", status(resp)
+	}
+	d := decoder{data: resp.data}
+	if count := d.uint32(); d.err == nil && count != 1 {
+		return "", fmt.Errorf("realpath returned %d names", count)
+	}
+	resolved := d.string()
+	return resolved, d.err
+}
+
+// Setstat changes the attributes of a remote path
+func (c *Client) Setstat(ctx context.Context, name string, attrs Attributes) error {
+	return c.statusRequest(ctx, packetSetstat, attrs.appendTo(appendString(nil, name)))
+}
+
+// Mkdir creates a remote directory
+func (c *Client) Mkdir(ctx context.Context, name string, attrs Attributes) error {
+	return c.statusRequest(ctx, packetMkdir, attrs.appendTo(appendString(nil, name)))
+}
+
+// Remove deletes a remote file
+func (c *Client) Remove(ctx context.Context, name string) error {
+	return c.statusRequest(ctx, packetRemove, appendString(nil, name))
+}
+
+// Rename moves a remote file; it fails when newName exists
+func (c *Client) Rename(ctx context.Context, oldName, newName string) error {
+	return c.statusRequest(ctx, packetRename, appendString(appendString(nil, oldName), newName))
+}
+
+// Replace moves oldName over newName, atomically when the server supports
+// POSIX renames and by removing newName first otherwise
+func (c *Client) Replace(ctx context.Context, oldName, newName string) error {
+	if _, ok := c.extensions[extPosixRename]; ok {
+		payload := appendString(nil, extPosixRename)
+		payload = appendString(payload, oldName)
+		payload = appendString(payload, newName)
+		return c.statusRequest(ctx, packetExtended, payload)
+	}
+
+	if err := c.Remove(ctx, newName); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return c.Rename(ctx, oldName, newName)
+}
+
+// MkdirAll creates a remote directory and any missing parents
+func (c *Client) MkdirAll(ctx context.Context, dir string, perm os.FileMode) error {
+	dir = path.Clean(dir)
+	attrs, err := c.Stat(ctx, dir)
+	if err == nil {
+		if !attrs.IsDir() {
+			return fmt.Errorf("%s exists and is not a directory", dir)
+		}
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if parent := path.Dir(dir); parent != dir {
+		if err := c.MkdirAll(ctx, parent, perm); err != nil {
+			return err
+		}
+	}
+	if err := c.Mkdir(ctx, dir, PermissionsAttr(perm)); err != nil {
+		// Created concurrently, e.g. by another upload
+		if attrs, statErr := c.Stat(ctx, dir); statErr == nil && attrs.IsDir() {
+			return nil
+		}
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	return nil
+}
+
+// PutFile copies r to the remote file name with the given permissions. The
+// data goes to a temporary file in the same directory, which is read back
+// and compared by SHA-256 before it replaces name, so an interrupted or
+// corrupted copy never replaces the previous file. It returns the number of
+// bytes copied and their SHA-256.
+func (c *Client) PutFile(ctx context.Context, name string, r io.Reader, perm os.FileMode) (int64, []byte, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return 0, nil, err
+	}
+	dir, base := path.Split(name)
+	tmp := path.Join(dir, "."+base+".portfly-"+hex.EncodeToString(suffix))
+
+	handle, err := c.Open(ctx, tmp, OpenWrite|OpenCreate|OpenTrunc|OpenExcl, PermissionsAttr(perm))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+
+	hash := sha256.New()
+	written, err := c.writeAll(ctx, handle, io.TeeReader(r, hash))
+	if closeErr := c.CloseHandle(ctx, handle); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close %s: %w", tmp, closeErr)
+	}
+	sum := hash.Sum(nil)
+	if err == nil {
+		err = c.verify(ctx, tmp, written, sum)
+	}
+	if err == nil {
+		// The mode given at creation is subject to the server's umask
+		err = c.Setstat(ctx, tmp, PermissionsAttr(perm))
+	}
+	if err == nil {
+		err = c.Replace(ctx, tmp, name)
+	}
+	if err != nil {
+		c.Remove(context.WithoutCancel(ctx), tmp)
+		return written, sum, err
+	}
+	return written, sum, nil
+}
+
+// GetFile copies the remote file name to w, keeping several reads in flight,
+// and returns the number of bytes copied
+func (c *Client) GetFile(ctx context.Context, name string, w io.Writer) (int64, error) {
+	handle, err := c.Open(ctx, name, OpenRead, Attributes{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer c.CloseHandle(context.WithoutCancel(ctx), handle)
+
+	var inflight []chan response
+	var next, written int64
+	for {
+		for len(inflight) < maxInflight {
+			ch, err := c.sendRead(handle, uint64(next), chunkSize)
+			if err != nil {
+				return written, err
+			}
+			inflight = append(inflight, ch)
+			next += chunkSize
+		}
+
+		// Each reply covers the chunk at written; servers may answer with
+		// less than asked, so the rest of a short chunk is read before the
+		// next one
+		end := written + chunkSize
+		data, err := c.readReply(ctx, inflight[0])
+		inflight = inflight[1:]
+		for err == nil {
+			if len(data) == 0 {
+				err = io.EOF
+				break
+			}
+			n, writeErr := w.Write(data)
+			written += int64(n)
+			if writeErr != nil {
+				return written, writeErr
+			}
+			if written >= end {
+				break
+			}
+			data, err = c.ReadAt(ctx, handle, uint64(written), uint32(end-written))
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+}
+
+// writeAll writes r to the handle, keeping several writes in flight
+func (c *Client) writeAll(ctx context.Context, handle string, r io.Reader) (int64, error) {
+	var inflight []chan response
+	var offset int64
+	var firstErr error
+	buf := make([]byte, chunkSize)
+
+	for firstErr == nil {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			ch, err := c.sendWrite(handle, uint64(offset), buf[:n])
+			if err != nil {
+				firstErr = err
+				break
+			}
+			inflight = append(inflight, ch)
+			offset += int64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			firstErr = readErr
+			break
+		}
+
+		if len(inflight) == maxInflight {
+			if resp, err := c.wait(ctx, inflight[0]); err != nil {
+				firstErr = err
+			} else if err := status(resp); err != nil {
+				firstErr = err
+			}
+			inflight = inflight[1:]
+		}
+	}
+
+	for _, ch := range inflight {
+		if firstErr != nil {
+			break
+		}
+		if resp, err := c.wait(ctx, ch); err != nil {
+			firstErr = err
+		} else if err := status(resp); err != nil {
+			firstErr = err
+		}
+	}
+	return offset, firstErr
+}
+
+// verify reads the remote file back and compares its size and SHA-256
+func (c *Client) verify(ctx context.Context, name string, size int64, sum []byte) error {
+	handle, err := c.Open(ctx, name, OpenRead, Attributes{})
+	if err != nil {
+		return fmt.Errorf("failed to open %s for verification: %w", name, err)
+	}
+	defer c.CloseHandle(context.WithoutCancel(ctx), handle)
+
+	hash := sha256.New()
+	var offset int64
+	for {
+		data, err := c.ReadAt(ctx, handle, uint64(offset), chunkSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read back %s: %w", name, err)
+		}
+		hash.Write(data)
+		offset += int64(len(data))
+	}
+
+	if offset != size || !bytes.Equal(hash.Sum(nil), sum) {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, name)
+	}
+	return nil
+}
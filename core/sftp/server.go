@@ -0,0 +1,348 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Serve answers SFTP requests on rw against the local filesystem until the
+// client disconnects. It implements what file copies and listings need and is
+// meant for tests, not as a hardened server.
+func Serve(rw io.ReadWriter) error {
+	s := &server{
+		w:     rw,
+		files: make(map[string]*os.File),
+		dirs:  make(map[string][]os.FileInfo),
+	}
+	defer s.closeAll()
+
+	typ, _, err := readPacket(rw)
+	if err != nil {
+		return err
+	}
+	if typ != packetInit {
+		return fmt.Errorf("sftp: expected init packet, got type %d", typ)
+	}
+	version := binary.BigEndian.AppendUint32(nil, protocolVersion)
+	version = appendString(appendString(version, extPosixRename), "1")
+	if err := writePacket(rw, packetVersion, version); err != nil {
+		return err
+	}
+
+	for {
+		typ, payload, err := readPacket(rw)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := s.handle(typ, payload); err != nil {
+			return err
+		}
+	}
+}
+
+// server holds the open handles of one session
+type server struct {
+	w          io.Writer
+	files      map[string]*os.File
+	dirs       map[string][]os.FileInfo
+	nextHandle int
+}
+
+func (s *server) closeAll() {
+	for _, f := range s.files {
+		f.Close()
+	}
+}
+
+func (s *server) newHandle() string {
+	s.nextHandle++
+	return strconv.Itoa(s.nextHandle)
+}
+
+// handle answers one request
+func (s *server) handle(typ byte, payload []byte) error {
+	d := decoder{data: payload}
+	id := d.uint32()
+	if d.err != nil {
+		return d.err
+	}
+
+	reply, err := s.dispatch(typ, &d)
+	if err == nil && d.err != nil {
+		err = &StatusError{Code: StatusBadMessage, Message: "malformed request"}
+	}
+	if err != nil || reply == nil {
+		return s.sendStatus(id, err)
+	}
+	return writePacket(s.w, reply[0], append(binary.BigEndian.AppendUint32(nil, id), reply[1:]...))
+}
+
+// dispatch runs a request and returns the reply type followed by its
+// payload, or nil for a plain OK status
+func (s *server) dispatch(typ byte, d *decoder) ([]byte, error) {
+	switch typ {
+	case packetOpen:
+		name := d.string()
+		pflags := d.uint32()
+		attrs := d.attributes()
+		perm := os.FileMode(0o644)
+		if attrs.Flags&attrPermissions != 0 {
+			perm = os.FileMode(attrs.Permissions & 0777)
+		}
+		f, err := os.OpenFile(name, openFlags(pflags), perm)
+		if err != nil {
+			return nil, err
+		}
+		handle := s.newHandle()
+		s.files[handle] = f
+		return appendString([]byte{packetHandle}, handle), nil
+
+	case packetClose:
+		handle := d.string()
+		if f, ok := s.files[handle]; ok {
+			delete(s.files, handle)
+			return okStatus(f.Close())
+		}
+		if _, ok := s.dirs[handle]; ok {
+			delete(s.dirs, handle)
+			return okStatus(nil)
+		}
+		return nil, errBadHandle
+
+	case packetRead:
+		f, err := s.file(d.string())
+		if err != nil {
+			return nil, err
+		}
+		offset := d.uint64()
+		length := min(d.uint32(), 256*1024)
+		buf := make([]byte, length)
+		n, err := f.ReadAt(buf, int64(offset))
+		if n == 0 && err != nil {
+			return nil, err
+		}
+		return appendBytes([]byte{packetData}, buf[:n]), nil
+
+	case packetWrite:
+		f, err := s.file(d.string())
+		if err != nil {
+			return nil, err
+		}
+		offset := d.uint64()
+		_, err = f.WriteAt(d.bytes(), int64(offset))
+		return okStatus(err)
+
+	case packetStat, packetLstat:
+		name := d.string()
+		stat := os.Stat
+		if typ == packetLstat {
+			stat = os.Lstat
+		}
+		info, err := stat(name)
+		if err != nil {
+			return nil, err
+		}
+		return fileAttributes(info).appendTo([]byte{packetAttrs}), nil
+
+	case packetFstat:
+		f, err := s.file(d.string())
+		if err != nil {
+			return nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return fileAttributes(info).appendTo([]byte{packetAttrs}), nil
+
+	case packetSetstat:
+		name := d.string()
+		return okStatus(setAttributes(name, d.attributes()))
+
+	case packetFsetstat:
+		f, err := s.file(d.string())
+		if err != nil {
+			return nil, err
+		}
+		return okStatus(setAttributes(f.Name(), d.attributes()))
+
+	case packetOpendir:
+		name := d.string()
+		entries, err := os.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]os.FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil {
+				infos = append(infos, info)
+			}
+		}
+		handle := s.newHandle()
+		s.dirs[handle] = infos
+		return appendString([]byte{packetHandle}, handle), nil
+
+	case packetReaddir:
+		handle := d.string()
+		infos, ok := s.dirs[handle]
+		if !ok {
+			return nil, errBadHandle
+		}
+		if len(infos) == 0 {
+			return nil, io.EOF
+		}
+		batch := infos[:min(len(infos), 100)]
+		s.dirs[handle] = infos[len(batch):]
+		reply := binary.BigEndian.AppendUint32([]byte{packetName}, uint32(len(batch)))
+		for _, info := range batch {
+			reply = appendString(reply, info.Name())
+			reply = appendString(reply, longName(info))
+			reply = fileAttributes(info).appendTo(reply)
+		}
+		return reply, nil
+
+	case packetRemove:
+		return okStatus(os.Remove(d.string()))
+
+	case packetMkdir:
+		name := d.string()
+		attrs := d.attributes()
+		perm := os.FileMode(0o755)
+		if attrs.Flags&attrPermissions != 0 {
+			perm = os.FileMode(attrs.Permissions & 0777)
+		}
+		return okStatus(os.Mkdir(name, perm))
+
+	case packetRmdir:
+		return okStatus(os.Remove(d.string()))
+
+	case packetRealpath:
+		abs, err := filepath.Abs(d.string())
+		if err != nil {
+			return nil, err
+		}
+		reply := binary.BigEndian.AppendUint32([]byte{packetName}, 1)
+		reply = appendString(reply, filepath.ToSlash(abs))
+		reply = appendString(reply, filepath.ToSlash(abs))
+		return Attributes{}.appendTo(reply), nil
+
+	case packetRename:
+		oldName, newName := d.string(), d.string()
+		if _, err := os.Lstat(newName); err == nil {
+			return nil, &StatusError{Code: StatusFailure, Message: "target exists"}
+		}
+		return okStatus(os.Rename(oldName, newName))
+
+	case packetExtended:
+		if d.string() != extPosixRename {
+			return nil, &StatusError{Code: StatusOpUnsupported, Message: "unsupported extension"}
+		}
+		oldName, newName := d.string(), d.string()
+		return okStatus(os.Rename(oldName, newName))
+
+	default:
+		return nil, &StatusError{Code: StatusOpUnsupported, Message: fmt.Sprintf("unsupported request type %d", typ)}
+	}
+}
+
+var errBadHandle = &StatusError{Code: StatusFailure, Message: "invalid handle"}
+
+// okStatus returns no reply payload, so the outcome is sent as a status
+func okStatus(err error) ([]byte, error) {
+	return nil, err
+}
+
+func (s *server) file(handle string) (*os.File, error) {
+	f, ok := s.files[handle]
+	if !ok {
+		return nil, errBadHandle
+	}
+	return f, nil
+}
+
+// sendStatus replies with the status matching err, OK for nil
+func (s *server) sendStatus(id uint32, err error) error {
+	code, message := uint32(StatusOK), ""
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+	case errors.As(err, &statusErr):
+		code, message = statusErr.Code, statusErr.Message
+	case errors.Is(err, io.EOF):
+		code, message = StatusEOF, "end of file"
+	case errors.Is(err, fs.ErrNotExist):
+		code, message = StatusNoSuchFile, err.Error()
+	case errors.Is(err, fs.ErrPermission):
+		code, message = StatusPermissionDenied, err.Error()
+	default:
+		code, message = StatusFailure, err.Error()
+	}
+
+	reply := binary.BigEndian.AppendUint32(nil, id)
+	reply = binary.BigEndian.AppendUint32(reply, code)
+	reply = appendString(reply, message)
+	reply = appendString(reply, "en")
+	return writePacket(s.w, packetStatus, reply)
+}
+
+// openFlags maps SFTP open flags to os.OpenFile flags
+func openFlags(pflags uint32) int {
+	var flags int
+	switch {
+	case pflags&OpenRead != 0 && pflags&OpenWrite != 0:
+		flags = os.O_RDWR
+	case pflags&OpenWrite != 0:
+		flags = os.O_WRONLY
+	default:
+		flags = os.O_RDONLY
+	}
+	if pflags&OpenAppend != 0 {
+		flags |= os.O_APPEND
+	}
+	if pflags&OpenCreate != 0 {
+		flags |= os.O_CREATE
+	}
+	if pflags&OpenTrunc != 0 {
+		flags |= os.O_TRUNC
+	}
+	if pflags&OpenExcl != 0 {
+		flags |= os.O_EXCL
+	}
+	return flags
+}
+
+// setAttributes applies the size, permissions and times of a setstat request
+func setAttributes(name string, attrs Attributes) error {
+	if attrs.Flags&attrSize != 0 {
+		if err := os.Truncate(name, int64(attrs.Size)); err != nil {
+			return err
+		}
+	}
+	if attrs.Flags&attrPermissions != 0 {
+		if err := os.Chmod(name, os.FileMode(attrs.Permissions&0777)); err != nil {
+			return err
+		}
+	}
+	if attrs.Flags&attrTimes != 0 {
+		if err := os.Chtimes(name, time.Unix(int64(attrs.Atime), 0), time.Unix(int64(attrs.Mtime), 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// longName formats a directory entry like ls -l
+func longName(info os.FileInfo) string {
+	return fmt.Sprintf("%s 1 0 0 %d %s %s",
+		info.Mode().String(), info.Size(), info.ModTime().Format("Jan _2 15:04"), info.Name())
+}
@@ -0,0 +1,300 @@
+// Package sftp implements the parts of SFTP version 3 PortFly needs: a
+// client for copying files to hosts, and a filesystem-backed server for the
+// in-process test SSH server.
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Packet types
+const (
+	packetInit          = 1
+	packetVersion       = 2
+	packetOpen          = 3
+	packetClose         = 4
+	packetRead          = 5
+	packetWrite         = 6
+	packetLstat         = 7
+	packetFstat         = 8
+	packetSetstat       = 9
+	packetFsetstat      = 10
+	packetOpendir       = 11
+	packetReaddir       = 12
+	packetRemove        = 13
+	packetMkdir         = 14
+	packetRmdir         = 15
+	packetRealpath      = 16
+	packetStat          = 17
+	packetRename        = 18
+	packetStatus        = 101
+	packetHandle        = 102
+	packetData          = 103
+	packetName          = 104
+	packetAttrs         = 105
+	packetExtended      = 200
+	packetExtendedReply = 201
+)
+
+// Status codes
+const (
+	StatusOK               = 0
+	StatusEOF              = 1
+	StatusNoSuchFile       = 2
+	StatusPermissionDenied = 3
+	StatusFailure          = 4
+	StatusBadMessage       = 5
+	StatusOpUnsupported    = 8
+)
+
+// Open flags
+const (
+	OpenRead   = 0x01
+	OpenWrite  = 0x02
+	OpenAppend = 0x04
+	OpenCreate = 0x08
+	OpenTrunc  = 0x10
+	OpenExcl   = 0x20
+)
+
+// Attribute flags
+const (
+	attrSize        = 0x00000001
+	attrUIDGID      = 0x00000002
+	attrPermissions = 0x00000004
+	attrTimes       = 0x00000008
+	attrExtended    = 0x80000000
+)
+
+// protocolVersion is the only version spoken
+const protocolVersion = 3
+
+// maxPacketSize bounds incoming packets; servers send at most 256 KiB of data
+const maxPacketSize = 1 << 20
+
+// extPosixRename replaces the target of a rename, which plain renames refuse
+const extPosixRename = "posix-rename@openssh.com"
+
+// File type bits of the permissions attribute
+const (
+	modeType    = 0170000
+	modeDir     = 0040000
+	modeRegular = 0100000
+	modeSymlink = 0120000
+)
+
+// Attributes are the file attributes carried by SFTP packets; only fields
+// whose flag is set are meaningful
+type Attributes struct {
+	Flags       uint32
+	Size        uint64
+	UID, GID    uint32
+	Permissions uint32
+	Atime       uint32
+	Mtime       uint32
+}
+
+// PermissionsAttr returns attributes setting only the permission bits
+func PermissionsAttr(perm os.FileMode) Attributes {
+	return Attributes{Flags: attrPermissions, Permissions: uint32(perm.Perm())}
+}
+
+// IsDir reports whether the attributes describe a directory
+func (a Attributes) IsDir() bool {
+	return a.Flags&attrPermissions != 0 && a.Permissions&modeType == modeDir
+}
+
+// HasSize reports whether the attributes carry the file size
+func (a Attributes) HasSize() bool {
+	return a.Flags&attrSize != 0
+}
+
+// Mode converts the permissions attribute to a FileMode
+func (a Attributes) Mode() os.FileMode {
+	mode := os.FileMode(a.Permissions & 0777)
+	switch a.Permissions & modeType {
+	case modeDir:
+		mode |= os.ModeDir
+	case modeSymlink:
+		mode |= os.ModeSymlink
+	}
+	return mode
+}
+
+// fileAttributes describes a local file
+func fileAttributes(info os.FileInfo) Attributes {
+	perms := uint32(info.Mode().Perm())
+	switch {
+	case info.IsDir():
+		perms |= modeDir
+	case info.Mode()&os.ModeSymlink != 0:
+		perms |= modeSymlink
+	default:
+		perms |= modeRegular
+	}
+	mtime := uint32(info.ModTime().Unix())
+	return Attributes{
+		Flags:       attrSize | attrPermissions | attrTimes,
+		Size:        uint64(info.Size()),
+		Permissions: perms,
+		Atime:       mtime,
+		Mtime:       mtime,
+	}
+}
+
+func (a Attributes) appendTo(b []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, a.Flags&^attrExtended)
+	if a.Flags&attrSize != 0 {
+		b = binary.BigEndian.AppendUint64(b, a.Size)
+	}
+	if a.Flags&attrUIDGID != 0 {
+		b = binary.BigEndian.AppendUint32(b, a.UID)
+		b = binary.BigEndian.AppendUint32(b, a.GID)
+	}
+	if a.Flags&attrPermissions != 0 {
+		b = binary.BigEndian.AppendUint32(b, a.Permissions)
+	}
+	if a.Flags&attrTimes != 0 {
+		b = binary.BigEndian.AppendUint32(b, a.Atime)
+		b = binary.BigEndian.AppendUint32(b, a.Mtime)
+	}
+	return b
+}
+
+// StatusError is a failure status returned by the peer
+type StatusError struct {
+	Code    uint32
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return "sftp: " + e.Message
+	}
+	return fmt.Sprintf("sftp: status %d", e.Code)
+}
+
+// Is matches the os errors for missing files and denied permissions
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case os.ErrNotExist:
+		return e.Code == StatusNoSuchFile
+	case os.ErrPermission:
+		return e.Code == StatusPermissionDenied
+	}
+	return false
+}
+
+// errBadPacket reports a truncated or malformed packet
+var errBadPacket = errors.New("sftp: malformed packet")
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func appendBytes(b, data []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
+
+// decoder reads the fields of a packet, remembering the first error
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) uint32() uint32 {
+	if len(d.data) < 4 {
+		d.err = errBadPacket
+		d.data = nil
+		return 0
+	}
+	v := binary.BigEndian.Uint32(d.data)
+	d.data = d.data[4:]
+	return v
+}
+
+func (d *decoder) uint64() uint64 {
+	if len(d.data) < 8 {
+		d.err = errBadPacket
+		d.data = nil
+		return 0
+	}
+	v := binary.BigEndian.Uint64(d.data)
+	d.data = d.data[8:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uint32()
+	if uint32(len(d.data)) < n {
+		d.err = errBadPacket
+		d.data = nil
+		return nil
+	}
+	v := d.data[:n]
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) attributes() Attributes {
+	var a Attributes
+	a.Flags = d.uint32()
+	if a.Flags&attrSize != 0 {
+		a.Size = d.uint64()
+	}
+	if a.Flags&attrUIDGID != 0 {
+		a.UID = d.uint32()
+		a.GID = d.uint32()
+	}
+	if a.Flags&attrPermissions != 0 {
+		a.Permissions = d.uint32()
+	}
+	if a.Flags&attrTimes != 0 {
+		a.Atime = d.uint32()
+		a.Mtime = d.uint32()
+	}
+	if a.Flags&attrExtended != 0 {
+		for n := d.uint32(); n > 0 && d.err == nil; n-- {
+			d.string()
+			d.string()
+		}
+	}
+	return a
+}
+
+// readPacket reads one length-prefixed packet
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length == 0 || length > maxPacketSize {
+		return 0, nil, fmt.Errorf("sftp: invalid packet length %d", length)
+	}
+	packet := make([]byte, length)
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return 0, nil, err
+	}
+	return packet[0], packet[1:], nil
+}
+
+// writePacket writes one packet in a single call
+func writePacket(w io.Writer, typ byte, payload []byte) error {
+	packet := make([]byte, 0, 5+len(payload))
+	packet = binary.BigEndian.AppendUint32(packet, uint32(1+len(payload)))
+	packet = append(packet, typ)
+	packet = append(packet, payload...)
+	_, err := w.Write(packet)
+	return err
+}
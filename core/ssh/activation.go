@@ -0,0 +1,167 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// ErrPrivilegedPort is returned when binding a port below 1024 is not permitted
+var ErrPrivilegedPort = errors.New("binding a privileged port requires CAP_NET_BIND_SERVICE")
+
+// ErrNoActivatedSocket is returned when socket activation is requested but
+// systemd passed no socket for the bind address
+var ErrNoActivatedSocket = errors.New("no socket passed by systemd")
+
+// listenFDsStart is the first file descriptor systemd passes (SD_LISTEN_FDS_START)
+const listenFDsStart = 3
+
+// activatedSocket is a listening socket inherited from systemd
+type activatedSocket struct {
+	name string   // FileDescriptorName= of the socket unit, or "unknown"
+	file *os.File // TCP socket kept open so a tunnel can take it again after a restart
+}
+
+var (
+	activationOnce    sync.Once
+	activatedSockets  []activatedSocket
+	activationFailure error
+)
+
+// loadActivatedSockets reads the sockets passed through LISTEN_FDS once and
+// unsets the variables so child processes (e.g. ProxyCommand) do not see them
+func loadActivatedSockets() ([]activatedSocket, error) {
+	activationOnce.Do(func() {
+		defer os.Unsetenv("LISTEN_PID")
+		defer os.Unsetenv("LISTEN_FDS")
+		defer os.Unsetenv("LISTEN_FDNAMES")
+
+		pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+		if err != nil || pid != os.Getpid() {
+			return
+		}
+		count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil || count <= 0 {
+			activationFailure = fmt.Errorf("invalid LISTEN_FDS: %q", os.Getenv("LISTEN_FDS"))
+			return
+		}
+		names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+		for i := 0; i < count; i++ {
+			name := "unknown"
+			if i < len(names) && names[i] != "" {
+				name = names[i]
+			}
+
+			// Keep a close-on-exec duplicate and close the inherited descriptor
+			inherited := os.NewFile(uintptr(listenFDsStart+i), name)
+			listener, err := net.FileListener(inherited)
+			inherited.Close()
+			if err != nil {
+				continue // not a stream socket
+			}
+			tcpListener, ok := listener.(*net.TCPListener)
+			if !ok {
+				listener.Close()
+				continue
+			}
+			file, err := tcpListener.File()
+			listener.Close()
+			if err != nil {
+				continue
+			}
+			activatedSockets = append(activatedSockets, activatedSocket{name: name, file: file})
+		}
+	})
+	return activatedSockets, activationFailure
+}
+
+// ActivatedListener returns a listener for the systemd socket matching bind,
+// either by FileDescriptorName= equal to the bind (e.g. "0.0.0.0:443") or by
+// local address. Each call duplicates the socket, so closing the listener
+// leaves it available to the next start of the tunnel.
+func ActivatedListener(bind string) (net.Listener, error) {
+	sockets, err := loadActivatedSockets()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, socket := range sockets {
+		if socket.name == bind {
+			return net.FileListener(socket.file)
+		}
+	}
+	for _, socket := range sockets {
+		listener, err := net.FileListener(socket.file)
+		if err != nil {
+			continue
+		}
+		if sameBind(listener.Addr(), bind) {
+			return listener, nil
+		}
+		listener.Close()
+	}
+
+	return nil, fmt.Errorf("%w for %s; add ListenStream=%s to the .socket unit, or FileDescriptorName=%s to match by name",
+		ErrNoActivatedSocket, bind, bind, bind)
+}
+
+// sameBind reports whether a listener address serves the host:port bind.
+// Unspecified hosts ("", "*", 0.0.0.0, ::) match any unspecified address.
+func sameBind(addr net.Addr, bind string) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	host, portText, err := net.SplitHostPort(bind)
+	if err != nil {
+		return false
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil || port != tcpAddr.Port {
+		return false
+	}
+
+	if host == "" || host == "*" {
+		return tcpAddr.IP.IsUnspecified()
+	}
+	if host == "localhost" {
+		return tcpAddr.IP.IsLoopback()
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsUnspecified() {
+		return tcpAddr.IP.IsUnspecified()
+	}
+	return ip.Equal(tcpAddr.IP)
+}
+
+// privilegedBindError explains how to allow a bind below 1024 that the
+// operating system refused
+func privilegedBindError(addr string, err error) error {
+	if !errors.Is(err, syscall.EACCES) && !errors.Is(err, syscall.EPERM) {
+		return nil
+	}
+	_, portText, splitErr := net.SplitHostPort(addr)
+	if splitErr != nil {
+		return nil
+	}
+	if port, convErr := strconv.Atoi(portText); convErr != nil || port >= 1024 {
+		return nil
+	}
+
+	executable, exeErr := os.Executable()
+	if exeErr != nil {
+		executable = "portfly"
+	}
+	return fmt.Errorf("%w: cannot listen on %s; run `sudo setcap cap_net_bind_service=+ep %s`, "+
+		"enable socket_activation with a systemd .socket unit, or choose a port of 1024 or above: %v",
+		ErrPrivilegedPort, addr, executable, err)
+}
@@ -165,6 +165,7 @@ func NewAuthManager() *AuthManager {
 			models.AuthMethodPassword:   &PasswordAuthProvider{},
 			models.AuthMethodPrivateKey: NewPrivateKeyAuthProvider(),
 			models.AuthMethodAgent:      &AgentAuthProvider{},
+			models.AuthMethodGSSAPI:     &GSSAPIAuthProvider{},
 		},
 	}
 }
@@ -253,7 +254,7 @@ func (am *AuthManager) createInteractiveHostKeyCallback(knownHostsFile string) s
 		}
 
 		// Log the fingerprint for security
-		fmt.Printf("Warning: Accepting host key for %s: %s\n", hostname, fingerprint)
+		fmt.Fprintf(os.Stderr, "Warning: Accepting host key for %s: %s\n", hostname, fingerprint)
 		return nil
 	}
 }
@@ -310,6 +311,10 @@ func (am *AuthManager) ValidateConfig(config models.SSHConnectionConfig) error {
 		if os.Getenv("SSH_AUTH_SOCK") == "" {
 			return fmt.Errorf("SSH agent not available")
 		}
+	case models.AuthMethodGSSAPI:
+		if _, err := FindKerberosTicketCache(config.KerberosCCache); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("unsupported authentication method: %s", config.AuthMethod)
 	}
@@ -0,0 +1,79 @@
+package ssh
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// Backoff tracks reconnect attempts against a retry policy
+type Backoff struct {
+	policy      models.RetryPolicy
+	attempt     int
+	connectedAt time.Time
+	rand        *rand.Rand
+	mu          sync.Mutex
+}
+
+// NewBackoff creates a new backoff tracker for the given policy
+func NewBackoff(policy models.RetryPolicy) *Backoff {
+	return &Backoff{
+		policy: policy,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Policy returns the retry policy in use
+func (b *Backoff) Policy() models.RetryPolicy {
+	return b.policy
+}
+
+// Next records a failed attempt and returns the delay before the next one.
+// It returns false once the policy is exhausted.
+func (b *Backoff) Next() (int, time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// A connection that stayed up for the whole reset window starts over
+	if !b.connectedAt.IsZero() {
+		if b.policy.ResetWindow > 0 && time.Since(b.connectedAt) >= b.policy.ResetWindow {
+			b.attempt = 0
+		}
+		b.connectedAt = time.Time{}
+	}
+
+	b.attempt++
+	if b.policy.Exhausted(b.attempt) {
+		return b.attempt, 0, false
+	}
+
+	return b.attempt, b.policy.Delay(b.attempt, b.rand.Float64()), true
+}
+
+// Succeeded marks the connection as established
+func (b *Backoff) Succeeded() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.connectedAt = time.Now()
+	if b.policy.ResetWindow == 0 {
+		b.attempt = 0
+	}
+}
+
+// Attempt returns the number of consecutive failed attempts
+func (b *Backoff) Attempt() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempt
+}
+
+// Reset clears the attempt counter
+func (b *Backoff) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attempt = 0
+	b.connectedAt = time.Time{}
+}
@@ -0,0 +1,139 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// targetBalancer spreads the connections of a tunnel over its targets and
+// keeps per-target statistics
+type targetBalancer struct {
+	mu      sync.Mutex
+	next    int
+	targets map[string]*models.TargetStats
+	down    map[string]time.Time // targets whose last dial failed, until when they are tried last
+}
+
+// targetRetryDelay is how long a target that could not be reached is tried
+// only after the others
+const targetRetryDelay = 10 * time.Second
+
+func newTargetBalancer() *targetBalancer {
+	return &targetBalancer{
+		targets: make(map[string]*models.TargetStats),
+		down:    make(map[string]time.Time),
+	}
+}
+
+// order returns the targets in the order a new connection tries them: the
+// rotation for round robin, fewest open connections first (ties in rotation
+// order) for least connections, and targets that recently failed last. The
+// later entries are fallbacks when the preferred target cannot be reached.
+func (b *targetBalancer) order(addrs []string, policy string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	start := b.next % len(addrs)
+	b.next++
+	ordered := append(append([]string{}, addrs[start:]...), addrs[:start]...)
+
+	now := time.Now()
+	sort.SliceStable(ordered, func(i, j int) bool {
+		downI, downJ := now.Before(b.down[ordered[i]]), now.Before(b.down[ordered[j]])
+		if downI != downJ {
+			return downJ
+		}
+		if policy == models.LoadBalanceLeastConnections {
+			return b.statsFor(ordered[i]).ActiveConnections < b.statsFor(ordered[j]).ActiveConnections
+		}
+		return false
+	})
+	return ordered
+}
+
+// reserve counts a connection to addr as open while it is being dialed, so
+// concurrent connections see it, and returns a function that records its close
+func (b *targetBalancer) reserve(addr string) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := b.statsFor(addr)
+	stats.ActiveConnections++
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		stats.ActiveConnections--
+	}
+}
+
+// connected records an established connection to addr
+func (b *targetBalancer) connected(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.statsFor(addr).TotalConnections++
+	delete(b.down, addr)
+}
+
+// fail records a failed dial to addr
+func (b *targetBalancer) fail(addr string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.statsFor(addr).FailedConnections++
+	b.down[addr] = time.Now().Add(targetRetryDelay)
+}
+
+// stats returns the statistics of the given targets, in that order
+func (b *targetBalancer) stats(addrs []string) []models.TargetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stats := make([]models.TargetStats, 0, len(addrs))
+	for _, addr := range addrs {
+		stats = append(stats, *b.statsFor(addr))
+	}
+	return stats
+}
+
+// statsFor returns the statistics for a target; callers must hold mu
+func (b *targetBalancer) statsFor(addr string) *models.TargetStats {
+	stats, exists := b.targets[addr]
+	if !exists {
+		stats = &models.TargetStats{Address: addr}
+		b.targets[addr] = stats
+	}
+	return stats
+}
+
+// dialTarget connects to one of the tunnel's targets, trying the others in
+// balance order when one cannot be reached. It returns the connection, the
+// target's address and a function to call when the connection is closed.
+func (tm *TunnelManager) dialTarget(config models.TunnelConfig, dial func(network, addr string) (net.Conn, error)) (net.Conn, string, func(), error) {
+	addrs := config.TargetAddresses()
+	if len(addrs) == 1 {
+		conn, err := dial(config.Network(), addrs[0])
+		return conn, addrs[0], func() {}, err
+	}
+
+	var errs []error
+	for _, addr := range tm.balancer.order(addrs, config.BalancePolicy()) {
+		release := tm.balancer.reserve(addr)
+		conn, err := dial(config.Network(), addr)
+		if err != nil {
+			release()
+			tm.balancer.fail(addr)
+			tm.logger.Warn("target unreachable, trying next", "target", addr, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+			continue
+		}
+		tm.balancer.connected(addr)
+		return conn, addr, release, nil
+	}
+	return nil, "", nil, fmt.Errorf("no target reachable: %w", errors.Join(errs...))
+}
@@ -0,0 +1,286 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// benchmarkChunk is the size of each write in throughput tests
+const benchmarkChunk = 32 * 1024
+
+// BenchmarkDialer opens a connection along the path being measured
+type BenchmarkDialer func(ctx context.Context) (net.Conn, error)
+
+// DirectDialer connects to address without any tunnel
+func DirectDialer(address string) BenchmarkDialer {
+	return func(ctx context.Context) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", address)
+	}
+}
+
+// ForwardDialer connects to address from the remote host, as a local forward
+// would
+func (c *SSHClient) ForwardDialer(address string) BenchmarkDialer {
+	return func(ctx context.Context) (net.Conn, error) {
+		client := c.GetClient()
+		if client == nil {
+			return nil, fmt.Errorf("SSH client not connected")
+		}
+		return client.DialContext(ctx, "tcp", address)
+	}
+}
+
+// BenchmarkConnections measures the path dial opens. Round trips are the time
+// to open a connection, or to get an answer to a request when the target
+// speaks HTTP; only HTTP targets are measured for throughput, by downloading
+// the requested path, as other services cannot be sent arbitrary data.
+func BenchmarkConnections(ctx context.Context, address string, dial BenchmarkDialer, options models.BenchmarkOptions) models.BenchmarkPath {
+	path := models.BenchmarkPath{Address: address}
+	if !options.HTTP {
+		connect := func(ctx context.Context) error {
+			conn, err := dial(ctx)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}
+		// An unreachable path is reported once rather than timing out every sample
+		probeCtx, cancel := context.WithTimeout(ctx, options.Timeout)
+		err := connect(probeCtx)
+		cancel()
+		if err != nil {
+			path.Error = err.Error()
+			return path
+		}
+		path.RTT = measureRTT(ctx, options, connect)
+		return path
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dial(ctx)
+		},
+		MaxIdleConnsPerHost: 1,
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport}
+	url := "http://" + address + options.Path
+
+	// The first request opens the connection the others reuse
+	if err := benchmarkRequest(ctx, client, url, options.Timeout); err != nil {
+		path.Error = err.Error()
+		return path
+	}
+	path.RTT = measureRTT(ctx, options, func(ctx context.Context) error {
+		return benchmarkRequest(ctx, client, url, options.Timeout)
+	})
+
+	started := time.Now()
+	downloaded, err := benchmarkDownload(ctx, client, url, options)
+	if err != nil {
+		path.Error = err.Error()
+		return path
+	}
+	path.Throughput = models.NewThroughputStats(downloaded, time.Since(started))
+	return path
+}
+
+// BenchmarkEcho measures the SSH connection itself: round trips of single
+// bytes echoed by cat on the host, and the upload rate of data the host
+// discards
+func (c *SSHClient) BenchmarkEcho(ctx context.Context, options models.BenchmarkOptions) models.BenchmarkPath {
+	client := c.GetClient()
+	if client == nil {
+		return models.BenchmarkPath{Error: "SSH client not connected"}
+	}
+	path := models.BenchmarkPath{Address: client.RemoteAddr().String()}
+
+	rtt, err := echoRTT(ctx, c, options)
+	if err != nil {
+		path.Error = err.Error()
+		return path
+	}
+	path.RTT = rtt
+
+	started := time.Now()
+	uploaded, err := echoUpload(ctx, c, options)
+	if err != nil {
+		path.Error = err.Error()
+		return path
+	}
+	path.Throughput = models.NewThroughputStats(uploaded, time.Since(started))
+	return path
+}
+
+// measureRTT times options.Samples runs of roundTrip, each bounded by the
+// round trip timeout, and stops early when ctx is done
+func measureRTT(ctx context.Context, options models.BenchmarkOptions, roundTrip func(ctx context.Context) error) *models.LatencyStats {
+	samples := make([]time.Duration, 0, options.Samples)
+	failed := 0
+	for i := 0; i < options.Samples && ctx.Err() == nil; i++ {
+		sampleCtx, cancel := context.WithTimeout(ctx, options.Timeout)
+		started := time.Now()
+		err := roundTrip(sampleCtx)
+		elapsed := time.Since(started)
+		cancel()
+		if err != nil {
+			failed++
+			continue
+		}
+		samples = append(samples, elapsed)
+	}
+	return models.NewLatencyStats(samples, failed)
+}
+
+// benchmarkRequest GETs url and reads the whole answer within timeout
+func benchmarkRequest(ctx context.Context, client *http.Client, url string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// benchmarkDownload reads the answer to GET url until it ends, options.Bytes
+// were read or options.Duration passed, returning the bytes read
+func benchmarkDownload(ctx context.Context, client *http.Client, url string, options models.BenchmarkOptions) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, options.Duration)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	downloaded, err := io.Copy(io.Discard, io.LimitReader(resp.Body, options.Bytes))
+	if err != nil && ctx.Err() != nil && downloaded > 0 {
+		// Running out of time ends the test, not the measurement
+		err = nil
+	}
+	return downloaded, err
+}
+
+// echoRTT writes single bytes to cat on the host and times their return
+func echoRTT(ctx context.Context, c *SSHClient, options models.BenchmarkOptions) (*models.LatencyStats, error) {
+	session, err := c.GetClient().NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := session.Start("cat"); err != nil {
+		return nil, fmt.Errorf("failed to start echo on host: %w", err)
+	}
+
+	// A byte that times out may still arrive and be taken for the next
+	// one's, so the first timeout ends the test
+	var stuck error
+	stats := measureRTT(ctx, options, func(ctx context.Context) error {
+		if stuck != nil {
+			return stuck
+		}
+		done := make(chan error, 1)
+		go func() {
+			if _, err := stdin.Write([]byte{'.'}); err != nil {
+				done <- err
+				return
+			}
+			_, err := io.ReadFull(stdout, make([]byte, 1))
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			stuck = fmt.Errorf("echo timed out after %s", options.Timeout)
+			session.Close()
+			return stuck
+		}
+	})
+	if stats.Samples == 0 {
+		if stuck != nil {
+			return nil, stuck
+		}
+		return nil, errors.New("host did not echo")
+	}
+	return stats, nil
+}
+
+// echoUpload sends data to the host until options.Bytes were sent or
+// options.Duration passed, and waits until the host has read them
+func echoUpload(ctx context.Context, c *SSHClient, options models.BenchmarkOptions) (int64, error) {
+	session, err := c.GetClient().NewSession()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	defer session.Close()
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := session.Start("cat >/dev/null"); err != nil {
+		return 0, fmt.Errorf("failed to start sink on host: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Duration)
+	defer cancel()
+	done := make(chan error, 1)
+	var uploaded int64
+	go func() {
+		chunk := []byte(strings.Repeat("portfly-benchmark", benchmarkChunk/17+1))[:benchmarkChunk]
+		for uploaded < options.Bytes && ctx.Err() == nil {
+			n := min(int64(len(chunk)), options.Bytes-uploaded)
+			if _, err := stdin.Write(chunk[:n]); err != nil {
+				done <- err
+				return
+			}
+			uploaded += n
+		}
+		stdin.Close()
+		done <- session.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// Writes block on the SSH window; closing the session releases them
+		session.Close()
+		<-done
+		err = nil
+	}
+	if uploaded == 0 {
+		return 0, fmt.Errorf("no data could be sent to the host")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("sink on host failed: %w", err)
+	}
+	return uploaded, nil
+}
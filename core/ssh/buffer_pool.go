@@ -0,0 +1,76 @@
+package ssh
+
+import (
+	"io"
+	"net"
+	"sync"
+)
+
+// DefaultBufferSize is the transfer buffer size used when none is configured
+const DefaultBufferSize = 32 * 1024
+
+// bufferPools holds one *sync.Pool per buffer size
+var bufferPools sync.Map
+
+// getBuffer returns a pooled buffer of the given size
+func getBuffer(size int) *[]byte {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	// Load first so the common case does not allocate a pool to throw away
+	pool, ok := bufferPools.Load(size)
+	if !ok {
+		pool, _ = bufferPools.LoadOrStore(size, &sync.Pool{
+			New: func() any {
+				buf := make([]byte, size)
+				return &buf
+			},
+		})
+	}
+	return pool.(*sync.Pool).Get().(*[]byte)
+}
+
+// putBuffer returns a buffer to its pool
+func putBuffer(buf *[]byte) {
+	if pool, ok := bufferPools.Load(len(*buf)); ok {
+		pool.(*sync.Pool).Put(buf)
+	}
+}
+
+// copyConn copies src to dst. When zeroCopy is set and both ends are kernel
+// sockets the copy goes through ReadFrom, which uses splice(2) on Linux;
+// otherwise a pooled buffer of the given size is used.
+func copyConn(dst, src net.Conn, size int, zeroCopy bool) (int64, error) {
+	if zeroCopy && isKernelConn(dst) && isKernelConn(src) {
+		if rf, ok := dst.(io.ReaderFrom); ok {
+			return rf.ReadFrom(src)
+		}
+	}
+
+	buf := getBuffer(size)
+	defer putBuffer(buf)
+
+	// Hide ReaderFrom/WriterTo so io.CopyBuffer uses our buffer instead of
+	// letting the connection allocate its own
+	return io.CopyBuffer(writerOnly{dst}, readerOnly{src}, *buf)
+}
+
+// isKernelConn reports whether conn is backed by a socket file descriptor
+func isKernelConn(conn net.Conn) bool {
+	switch conn.(type) {
+	case *net.TCPConn, *net.UnixConn:
+		return true
+	default:
+		return false
+	}
+}
+
+// writerOnly hides any ReadFrom method of the wrapped writer
+type writerOnly struct {
+	io.Writer
+}
+
+// readerOnly hides any WriteTo method of the wrapped reader
+type readerOnly struct {
+	io.Reader
+}
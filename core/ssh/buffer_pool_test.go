@@ -0,0 +1,134 @@
+package ssh
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+// benchmarkPayload is what one forwarded connection carries in the benchmarks
+const benchmarkPayload = 1 << 20
+
+// memConn is a connection reading from a fixed payload and discarding writes,
+// so benchmarks measure the copy and not the network
+type memConn struct {
+	net.Conn
+	r *bytes.Reader
+}
+
+func (c *memConn) Read(p []byte) (int, error)  { return c.r.Read(p) }
+func (c *memConn) Write(p []byte) (int, error) { return len(p), nil }
+
+// copyUnpooled is the copy path without the pool: a new buffer per connection
+func copyUnpooled(dst, src net.Conn, size int) (int64, error) {
+	return io.CopyBuffer(writerOnly{dst}, readerOnly{src}, make([]byte, size))
+}
+
+func TestCopyConn(t *testing.T) {
+	payload := bytes.Repeat([]byte("portfly"), 1000)
+	var dst bytes.Buffer
+	n, err := copyConn(&bufferConn{buf: &dst}, &memConn{r: bytes.NewReader(payload)}, 1024, true)
+	if err != nil {
+		t.Fatalf("copyConn: %v", err)
+	}
+	if n != int64(len(payload)) || !bytes.Equal(dst.Bytes(), payload) {
+		t.Fatalf("copied %d bytes, want %d identical bytes", n, len(payload))
+	}
+}
+
+// bufferConn is a connection writing into a buffer
+type bufferConn struct {
+	net.Conn
+	buf *bytes.Buffer
+}
+
+func (c *bufferConn) Write(p []byte) (int, error) { return c.buf.Write(p) }
+
+func BenchmarkCopyConn(b *testing.B) {
+	payload := make([]byte, benchmarkPayload)
+	for _, bench := range []struct {
+		name string
+		copy func(dst, src net.Conn, size int) (int64, error)
+	}{
+		{"pooled", func(dst, src net.Conn, size int) (int64, error) { return copyConn(dst, src, size, false) }},
+		{"unpooled", copyUnpooled},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			src := &memConn{r: bytes.NewReader(payload)}
+			dst := &memConn{}
+			b.SetBytes(benchmarkPayload)
+			b.ReportAllocs()
+			for b.Loop() {
+				src.r.Reset(payload)
+				if _, err := bench.copy(dst, src, DefaultBufferSize); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// tcpPair returns both ends of a loopback TCP connection
+func tcpPair(b *testing.B, listener net.Listener) (net.Conn, net.Conn) {
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	server, err := listener.Accept()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return client, server
+}
+
+func BenchmarkCopyConnTCP(b *testing.B) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer listener.Close()
+	payload := make([]byte, benchmarkPayload)
+
+	for _, bench := range []struct {
+		name string
+		copy func(dst, src net.Conn, size int) (int64, error)
+	}{
+		{"pooled", func(dst, src net.Conn, size int) (int64, error) { return copyConn(dst, src, size, false) }},
+		{"unpooled", copyUnpooled},
+		{"zerocopy", func(dst, src net.Conn, size int) (int64, error) { return copyConn(dst, src, size, true) }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.SetBytes(benchmarkPayload)
+			b.ReportAllocs()
+			for b.Loop() {
+				b.StopTimer()
+				// The sender writes the payload into src, the copy forwards
+				// it to dst and the receiver drains it
+				sender, src := tcpPair(b, listener)
+				dst, receiver := tcpPair(b, listener)
+				go func() {
+					sender.Write(payload)
+					sender.Close()
+				}()
+				drained := make(chan struct{})
+				go func() {
+					io.Copy(io.Discard, receiver)
+					close(drained)
+				}()
+				b.StartTimer()
+
+				if _, err := bench.copy(dst, src, DefaultBufferSize); err != nil {
+					b.Fatal(err)
+				}
+				dst.Close()
+				<-drained
+
+				b.StopTimer()
+				src.Close()
+				receiver.Close()
+				b.StartTimer()
+			}
+		})
+	}
+}
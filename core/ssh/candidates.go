@@ -0,0 +1,175 @@
+package ssh
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// CandidateProbeTimeout bounds the TCP connect of a latency probe
+const CandidateProbeTimeout = 5 * time.Second
+
+// ProbeEndpoints measures the TCP connect time of each endpoint concurrently.
+// The result lists the reachable endpoints fastest first, then the others in
+// their given order.
+func ProbeEndpoints(ctx context.Context, endpoints []models.SSHEndpoint, timeout time.Duration) []models.CandidateStatus {
+	if timeout <= 0 {
+		timeout = CandidateProbeTimeout
+	}
+
+	statuses := make([]models.CandidateStatus, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = probeEndpoint(ctx, endpoint, timeout)
+		}()
+	}
+	wg.Wait()
+
+	slices.SortStableFunc(statuses, func(a, b models.CandidateStatus) int {
+		switch {
+		case a.Healthy && b.Healthy:
+			return cmp.Compare(a.Latency, b.Latency)
+		case a.Healthy:
+			return -1
+		case b.Healthy:
+			return 1
+		default:
+			return 0
+		}
+	})
+	return statuses
+}
+
+func probeEndpoint(ctx context.Context, endpoint models.SSHEndpoint, timeout time.Duration) models.CandidateStatus {
+	status := models.CandidateStatus{Endpoint: endpoint, CheckedAt: time.Now()}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint.String())
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Latency = max(time.Since(start), time.Microsecond)
+	status.Healthy = true
+	conn.Close()
+	return status
+}
+
+// connectOrder returns the endpoints to try connecting to. With candidate
+// servers they are probed and tried fastest first; unreachable ones are
+// still tried last, as a probe can fail where the SSH connection would not.
+// Through a proxy the servers cannot be probed and are tried in order.
+func (c *SSHClient) connectOrder(ctx context.Context) ([]models.SSHEndpoint, []models.CandidateStatus) {
+	endpoints := c.config.Endpoints()
+	if len(endpoints) == 1 {
+		return endpoints, nil
+	}
+	if c.config.ProxyCommand != "" || c.config.ProxyURL != "" {
+		statuses := make([]models.CandidateStatus, len(endpoints))
+		for i, endpoint := range endpoints {
+			statuses[i] = models.CandidateStatus{Endpoint: endpoint}
+		}
+		return endpoints, statuses
+	}
+
+	statuses := ProbeEndpoints(ctx, endpoints, min(c.config.ConnectTimeout, CandidateProbeTimeout))
+	order := make([]models.SSHEndpoint, len(statuses))
+	for i, status := range statuses {
+		order[i] = status.Endpoint
+	}
+	return order, statuses
+}
+
+// ActiveEndpoint returns the server the client is connected to
+func (c *SSHClient) ActiveEndpoint() (models.SSHEndpoint, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.active, c.connected && c.client != nil
+}
+
+// Candidates returns the latest probe of the candidate servers, nil without candidates
+func (c *SSHClient) Candidates() []models.CandidateStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.candidates)
+}
+
+// watch marks the client disconnected when its connection closes or stops
+// answering keepalives, so the session monitor reconnects it, failing over
+// to another candidate server
+func (c *SSHClient) watch(config models.SSHConnectionConfig, client *ssh.Client) {
+	closed := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(closed)
+	}()
+
+	var ticks <-chan time.Time
+	if interval := config.KeepAliveTimeout; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-closed:
+			c.connectionLost(config, client)
+			return
+		case <-ticks:
+			if err := keepAlive(client, config.KeepAliveTimeout); err != nil {
+				c.logger.Warn("SSH keepalive failed", "host", config.Host, "error", err)
+				client.Close()
+			}
+		}
+	}
+}
+
+// errKeepAliveTimeout is returned when the server does not answer a keepalive in time
+var errKeepAliveTimeout = errors.New("no keepalive reply")
+
+// keepAlive sends an OpenSSH keepalive request and waits for the reply
+func keepAlive(client *ssh.Client, timeout time.Duration) error {
+	reply := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		reply <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-reply:
+		return err
+	case <-timer.C:
+		return errKeepAliveTimeout
+	}
+}
+
+// connectionLost forgets a connection that ended without Disconnect
+func (c *SSHClient) connectionLost(config models.SSHConnectionConfig, client *ssh.Client) {
+	if c.pool != nil {
+		c.pool.Discard(config, client)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != client {
+		return
+	}
+	c.client = nil
+	c.connected = false
+	c.logger.Warn("SSH connection lost", "host", config.Host, "port", config.Port)
+}
@@ -0,0 +1,297 @@
+package ssh
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// captureBufferSize is the per-subscriber channel capacity; slow viewers drop chunks
+const captureBufferSize = 256
+
+// Capture tees the bytes forwarded by a tunnel into a rotating file and to
+// live subscribers. Redaction works on each read separately, so a secret
+// split across two reads may escape it.
+type Capture struct {
+	config models.CaptureConfig
+	redact []*regexp.Regexp
+	file   *rotatingFile // nil for live view only
+
+	subscribers map[int]chan models.CaptureChunk
+	nextID      int
+	mu          sync.RWMutex
+
+	connections atomic.Uint64
+	dropped     atomic.Uint64
+}
+
+// NewCapture creates a capture, opening its file if one is configured. With
+// dir set the file must be a plain file name and is kept in dir; without it
+// the file is a path, as the CLI passes for its own user.
+func NewCapture(config models.CaptureConfig, dir string) (*Capture, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = config.WithDefaults()
+
+	capture := &Capture{
+		config:      config,
+		subscribers: make(map[int]chan models.CaptureChunk),
+	}
+	for _, pattern := range config.Redactions() {
+		capture.redact = append(capture.redact, regexp.MustCompile(pattern))
+	}
+
+	if config.File != "" {
+		path := config.File
+		if dir != "" {
+			if err := config.ValidateFileName(); err != nil {
+				return nil, err
+			}
+			if err := os.MkdirAll(dir, 0o700); err != nil {
+				return nil, fmt.Errorf("failed to create capture directory: %w", err)
+			}
+			path = filepath.Join(dir, config.File)
+		}
+		file, err := openRotatingFile(path, config.MaxFileSize, config.MaxFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open capture file: %w", err)
+		}
+		capture.file = file
+	}
+	return capture, nil
+}
+
+// Config returns the capture configuration with defaults applied
+func (c *Capture) Config() models.CaptureConfig {
+	return c.config
+}
+
+// Subscribe returns a channel of captured chunks and a function to stop receiving them
+func (c *Capture) Subscribe() (<-chan models.CaptureChunk, func()) {
+	ch := make(chan models.CaptureChunk, captureBufferSize)
+
+	c.mu.Lock()
+	id := c.nextID
+	c.nextID++
+	c.subscribers[id] = ch
+	c.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.mu.Lock()
+			if _, exists := c.subscribers[id]; exists {
+				delete(c.subscribers, id)
+				close(ch)
+			}
+			c.mu.Unlock()
+		})
+	}
+}
+
+// Dropped returns the number of chunks dropped for slow subscribers
+func (c *Capture) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// Close ends the live subscriptions and closes the capture file
+func (c *Capture) Close() error {
+	c.mu.Lock()
+	for id, ch := range c.subscribers {
+		delete(c.subscribers, id)
+		close(ch)
+	}
+	c.mu.Unlock()
+
+	if c.file != nil {
+		return c.file.Close()
+	}
+	return nil
+}
+
+// captureConnection records both directions of one forwarded connection
+type captureConnection struct {
+	capture  *Capture
+	id       uint64
+	listener string
+	client   string
+
+	// Bytes seen per direction; each is only touched by its copy goroutine
+	sent, received int64
+}
+
+// connection starts recording a forwarded connection accepted on listener
+func (c *Capture) connection(listener string, client net.Addr) *captureConnection {
+	cc := &captureConnection{capture: c, id: c.connections.Add(1), listener: listener}
+	if client != nil {
+		cc.client = client.String()
+	}
+	return cc
+}
+
+// observe records data read in direction until the capture limit is reached
+func (cc *captureConnection) observe(direction models.CaptureDirection, data []byte) {
+	offset := &cc.sent
+	if direction == models.CaptureReceived {
+		offset = &cc.received
+	}
+	if *offset < cc.capture.config.MaxBytes {
+		cc.record(direction, *offset, data)
+	}
+	*offset += int64(len(data))
+}
+
+// close implements connectionTap; the capture outlives its connections
+func (cc *captureConnection) close() {}
+
+// record emits data read at offset, of which only the part below the limit is kept
+func (cc *captureConnection) record(direction models.CaptureDirection, offset int64, data []byte) {
+	limit := cc.capture.config.MaxBytes
+	truncated := offset+int64(len(data)) > limit
+	if truncated {
+		data = data[:limit-offset]
+	}
+
+	redacted := cc.capture.redacted(data)
+	chunk := models.CaptureChunk{
+		Connection: cc.id,
+		Listener:   cc.listener,
+		Client:     cc.client,
+		Direction:  direction,
+		Offset:     offset,
+		Data:       redacted,
+		Dump:       hex.Dump(redacted),
+		Truncated:  truncated,
+		Timestamp:  time.Now(),
+	}
+	cc.capture.publish(chunk)
+}
+
+// redacted returns a copy of data with the redaction patterns replaced
+func (c *Capture) redacted(data []byte) []byte {
+	result := append([]byte(nil), data...)
+	for _, pattern := range c.redact {
+		result = pattern.ReplaceAll(result, []byte(models.CaptureRedacted))
+	}
+	return result
+}
+
+// publish writes a chunk to the file and delivers it to subscribers without blocking
+func (c *Capture) publish(chunk models.CaptureChunk) {
+	if c.file != nil {
+		c.file.WriteRecord(formatCaptureRecord(chunk))
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, ch := range c.subscribers {
+		select {
+		case ch <- chunk:
+		default:
+			c.dropped.Add(1)
+		}
+	}
+}
+
+// formatCaptureRecord renders a chunk for the capture file
+func formatCaptureRecord(chunk models.CaptureChunk) []byte {
+	header := fmt.Sprintf("%s conn=%d listener=%s client=%s %s offset=%d len=%d",
+		chunk.Timestamp.Format(time.RFC3339Nano), chunk.Connection, chunk.Listener,
+		chunk.Client, chunk.Direction, chunk.Offset, len(chunk.Data))
+	if chunk.Truncated {
+		header += " truncated"
+	}
+	return []byte(header + "\n" + chunk.Dump + "\n")
+}
+
+// rotatingFile is an append-only file that is renamed to path.1, path.2, …
+// once it exceeds maxSize, keeping at most maxFiles rotated files
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// openRotatingFile opens path for appending. Captures may contain sensitive
+// data, so the file is only readable by its owner.
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	rf := &rotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file, rf.size = file, info.Size()
+	return nil
+}
+
+// WriteRecord appends a record, rotating first if it would exceed the size
+// limit. Records are never split across files.
+func (rf *rotatingFile) WriteRecord(record []byte) error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return os.ErrClosed
+	}
+	if rf.size > 0 && rf.size+int64(len(record)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := rf.file.Write(record)
+	rf.size += int64(n)
+	return err
+}
+
+// rotate shifts the rotated files by one and starts a new file; callers must hold mu
+func (rf *rotatingFile) rotate() error {
+	rf.file.Close()
+	rf.file = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", rf.path, rf.maxFiles))
+	for i := rf.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1))
+	}
+	if err := os.Rename(rf.path, rf.path+".1"); err != nil {
+		return err
+	}
+	return rf.open()
+}
+
+// Close closes the current file
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
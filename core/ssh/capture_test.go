@@ -0,0 +1,45 @@
+package ssh
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+func TestCaptureFileStaysInDir(t *testing.T) {
+	dir := t.TempDir()
+	capture, err := NewCapture(models.CaptureConfig{Enabled: true, File: "web.cap"}, dir)
+	if err != nil {
+		t.Fatalf("NewCapture: %v", err)
+	}
+	defer capture.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, "web.cap")); err != nil {
+		t.Fatalf("capture file not created in the capture directory: %v", err)
+	}
+}
+
+func TestCaptureFileRejectsPaths(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "outside.cap")
+	for _, file := range []string{outside, "../escape.cap", "sub/web.cap", `sub\web.cap`, "..", "."} {
+		capture, err := NewCapture(models.CaptureConfig{Enabled: true, File: file}, dir)
+		if err == nil {
+			capture.Close()
+			t.Errorf("NewCapture(%q) succeeded", file)
+			continue
+		}
+		if !errors.Is(err, models.ErrInvalidCaptureFile) {
+			t.Errorf("NewCapture(%q) error = %v, want ErrInvalidCaptureFile", file, err)
+		}
+	}
+	if _, err := os.Stat(outside); !os.IsNotExist(err) {
+		t.Fatalf("file outside the capture directory was created: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape.cap")); !os.IsNotExist(err) {
+		t.Fatalf("file above the capture directory was created: %v", err)
+	}
+}
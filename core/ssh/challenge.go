@@ -0,0 +1,95 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ErrChallengeUnanswered is returned when a keyboard-interactive challenge
+// gets no answer, e.g. because the user went away
+var ErrChallengeUnanswered = errors.New("authentication challenge was not answered")
+
+// Challenge is a keyboard-interactive prompt sent by a server during
+// authentication, such as a one-time password request
+type Challenge struct {
+	Host        string   `json:"host"`
+	User        string   `json:"user"`
+	Name        string   `json:"name,omitempty"`
+	Instruction string   `json:"instruction,omitempty"`
+	Questions   []string `json:"questions"`
+	Echos       []bool   `json:"echos"` // whether each answer may be shown while typed
+}
+
+// ChallengeHandler answers a challenge with one answer per question. It runs
+// during the handshake and must return once ctx is done.
+type ChallengeHandler func(ctx context.Context, challenge Challenge) ([]string, error)
+
+// SetChallengeHandler relays the keyboard-interactive questions the
+// configured credentials cannot answer, such as OTP prompts, to handler.
+// Without a handler servers asking them fail authentication.
+func (c *SSHClient) SetChallengeHandler(handler ChallengeHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.challenges = handler
+}
+
+// keyboardInteractive answers the first password prompt with the configured
+// password and relays every other question to handler; rounds with nothing
+// left to ask, like the instructions some PAM stacks send, are answered
+// without it
+func keyboardInteractive(ctx context.Context, config models.SSHConnectionConfig, handler ChallengeHandler) ssh.AuthMethod {
+	passwordSent := false
+	return ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		challenge := Challenge{
+			Host:        config.Host,
+			User:        config.Username,
+			Name:        name,
+			Instruction: instruction,
+		}
+
+		var relayed []int
+		for i, question := range questions {
+			echo := i < len(echos) && echos[i]
+			// A second password prompt means the password was wrong; the
+			// user may know better
+			if !passwordSent && config.Password != "" && !echo && isPasswordPrompt(question) {
+				answers[i] = config.Password
+				passwordSent = true
+				continue
+			}
+			relayed = append(relayed, i)
+			challenge.Questions = append(challenge.Questions, question)
+			challenge.Echos = append(challenge.Echos, echo)
+		}
+		if len(relayed) == 0 {
+			return answers, nil
+		}
+
+		replies, err := handler(ctx, challenge)
+		if err != nil {
+			return nil, err
+		}
+		if len(replies) != len(relayed) {
+			return nil, fmt.Errorf("challenge has %d questions, got %d answers", len(relayed), len(replies))
+		}
+		for j, i := range relayed {
+			answers[i] = replies[j]
+		}
+		return answers, nil
+	})
+}
+
+// isPasswordPrompt reports whether a question asks for the account password
+// rather than, say, a verification code
+func isPasswordPrompt(question string) bool {
+	question = strings.ToLower(question)
+	return strings.Contains(question, "password") && !strings.Contains(question, "one-time") &&
+		!strings.Contains(question, "otp")
+}
@@ -2,17 +2,24 @@ package ssh
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/crypto/ssh"
 
 	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/telemetry"
 	"github.com/aqz236/port-fly/core/utils"
 )
 
+// tracer traces SSH connection and tunnel operations
+var tracer = telemetry.Tracer("core/ssh")
+
 // ConnectionPool manages SSH connections with pooling and reuse
 type ConnectionPool struct {
 	connections map[string]*PooledConnection
@@ -56,6 +63,13 @@ type SSHClient struct {
 	logger      utils.Logger
 	connected   bool
 	mu          sync.RWMutex
+
+	// Server connected to, and the latest probe of the candidate servers
+	active     models.SSHEndpoint
+	candidates []models.CandidateStatus
+
+	// Answers keyboard-interactive questions, such as OTP prompts (nil when not relayed)
+	challenges ChallengeHandler
 }
 
 // NewSSHClient creates a new SSH client
@@ -78,7 +92,14 @@ func NewSSHClientWithPool(config models.SSHConnectionConfig, pool *ConnectionPoo
 }
 
 // Connect establishes an SSH connection
-func (c *SSHClient) Connect(ctx context.Context) error {
+func (c *SSHClient) Connect(ctx context.Context) (err error) {
+	ctx, span := telemetry.StartSpan(ctx, tracer, "ssh.connect",
+		attribute.String("ssh.host", c.config.Host),
+		attribute.Int("ssh.port", c.config.Port),
+		attribute.String("ssh.user", c.config.Username),
+		attribute.String("ssh.auth_method", string(c.config.AuthMethod)))
+	defer func() { telemetry.EndSpan(span, err) }()
+	
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
@@ -91,41 +112,67 @@ func (c *SSHClient) Connect(ctx context.Context) error {
 		return fmt.Errorf("invalid SSH configuration: %w", err)
 	}
 	
-	// Try to get connection from pool first
-	if c.pool != nil {
-		if conn, err := c.pool.Get(c.config); err == nil {
-			c.client = conn
-			c.connected = true
-			c.logger.Info("reused pooled SSH connection", 
-				"host", c.config.Host, 
-				"user", c.config.Username)
-			return nil
+	// Try each server in turn, the fastest first when there are candidates
+	endpoints, statuses := c.connectOrder(ctx)
+	c.candidates = statuses
+	span.SetAttributes(attribute.Int("ssh.candidates", len(statuses)))
+	
+	var errs []error
+	for _, endpoint := range endpoints {
+		config := c.config.WithEndpoint(endpoint)
+		
+		// Try to get connection from pool first
+		if c.pool != nil {
+			if conn, err := c.pool.Get(config); err == nil {
+				c.client = conn
+				c.connected = true
+				c.active = endpoint
+				span.SetAttributes(attribute.Bool("ssh.pooled", true))
+				c.logger.Info("reused pooled SSH connection", 
+					"host", config.Host, 
+					"user", config.Username)
+				return nil
+			}
+		}
+		
+		// Create new connection
+		client, err := c.createConnection(ctx, config)
+		if err != nil {
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+			if len(endpoints) > 1 {
+				c.logger.Warn("candidate server failed", "host", config.Host, "port", config.Port, "error", err)
+			}
+			continue
 		}
+		
+		c.client = client
+		c.connected = true
+		c.active = endpoint
+		go c.watch(config, client)
+		
+		c.logger.Info("established SSH connection", 
+			"host", config.Host, 
+			"user", config.Username)
+		
+		return nil
 	}
 	
-	// Create new connection
-	client, err := c.createConnection(ctx)
-	if err != nil {
-		return err
+	if len(errs) == 1 {
+		return errs[0]
 	}
-	
-	c.client = client
-	c.connected = true
-	
-	c.logger.Info("established SSH connection", 
-		"host", c.config.Host, 
-		"user", c.config.Username)
-	
-	return nil
+	return fmt.Errorf("no candidate server reachable: %w", errors.Join(errs...))
 }
 
-// createConnection creates a new SSH connection
-func (c *SSHClient) createConnection(ctx context.Context) (*ssh.Client, error) {
+// createConnection creates a new SSH connection to the server in config
+func (c *SSHClient) createConnection(ctx context.Context, config models.SSHConnectionConfig) (*ssh.Client, error) {
 	// Get authentication methods
-	authMethods, err := c.authManager.GetAuthMethods(c.config)
+	authMethods, err := c.authManager.GetAuthMethods(config)
 	if err != nil {
 		// Try all available methods if specific method fails
-		authMethods = c.authManager.GetAllAuthMethods(c.config)
+		authMethods = c.authManager.GetAllAuthMethods(config)
 		if len(authMethods) == 0 {
 			return nil, fmt.Errorf("no authentication methods available: %w", err)
 		}
@@ -133,61 +180,151 @@ func (c *SSHClient) createConnection(ctx context.Context) (*ssh.Client, error) {
 	
 	// Get host key callback
 	hostKeyCallback, err := c.authManager.HostKeyCallback(
-		c.config.HostKeyCallback, 
-		c.config.KnownHostsFile)
+		config.HostKeyCallback, 
+		config.KnownHostsFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create host key callback: %w", err)
 	}
 	
 	// Create SSH client configuration
-	clientVersion := c.config.ClientVersion
+	clientVersion := config.ClientVersion
 	if clientVersion == "" {
 		clientVersion = "SSH-2.0-PortFly"
 	}
 	
 	sshConfig := &ssh.ClientConfig{
-		User:            c.config.Username,
+		User:            config.Username,
 		Auth:            authMethods,
 		HostKeyCallback: hostKeyCallback,
 		ClientVersion:   clientVersion,
-		Timeout:         c.config.ConnectTimeout,
+		Timeout:         config.ConnectTimeout,
 	}
 	
 	// Set cipher preferences if specified
-	if len(c.config.Extensions) > 0 {
+	if len(config.Extensions) > 0 {
 		// Handle SSH extensions/algorithms configuration
 		// This would require additional implementation
 	}
 	
 	// Create connection with context
-	address := fmt.Sprintf("%s:%d", c.config.Host, c.config.Port)
+	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	
+	dialCtx, dialSpan := telemetry.StartSpan(ctx, tracer, "ssh.dial",
+		attribute.String("net.peer.address", address),
+		attribute.Bool("ssh.proxy_command", config.ProxyCommand != ""),
+		attribute.Bool("ssh.proxy", config.ProxyURL != ""))
+	ReportProgress(ctx, PhaseConnecting)
+	var conn net.Conn
+	if err = beforeDial(dialCtx, address); err == nil {
+		conn, err = c.dial(dialCtx, config, address)
+	}
+	telemetry.EndSpan(dialSpan, err)
+	if err != nil {
+		return nil, err
+	}
+	conn = faultyConn(conn, address)
 	
-	dialer := &net.Dialer{
-		Timeout: c.config.ConnectTimeout,
+	handshakeCtx := ctx
+	if config.HandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		handshakeCtx, cancel = context.WithTimeout(ctx, config.HandshakeTimeout)
+		defer cancel()
 	}
 	
-	conn, err := dialer.DialContext(ctx, "tcp", address)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	// Relay keyboard-interactive questions, bounded by the handshake deadline
+	if c.challenges != nil {
+		sshConfig.Auth = append(sshConfig.Auth, keyboardInteractive(handshakeCtx, config, c.challenges))
 	}
 	
+	// Abort the handshake if the context is cancelled or times out while it is in progress
+	stopWatch := context.AfterFunc(handshakeCtx, func() {
+		conn.Close()
+	})
+	
 	// Perform SSH handshake
+	ReportProgress(ctx, PhaseHandshake)
+	_, handshakeSpan := telemetry.StartSpan(handshakeCtx, tracer, "ssh.handshake")
 	sshConn, channels, requests, err := ssh.NewClientConn(conn, address, sshConfig)
+	if !stopWatch() {
+		if err == nil {
+			sshConn.Close()
+		}
+		if ctx.Err() != nil {
+			err = fmt.Errorf("SSH handshake aborted: %w", ctx.Err())
+		} else {
+			err = fmt.Errorf("SSH handshake timed out after %s: %w", config.HandshakeTimeout, handshakeCtx.Err())
+		}
+		telemetry.EndSpan(handshakeSpan, err)
+		return nil, err
+	}
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("SSH handshake failed: %w", err)
+		err = fmt.Errorf("SSH handshake failed: %w", err)
+		telemetry.EndSpan(handshakeSpan, err)
+		return nil, err
 	}
+	handshakeSpan.SetAttributes(attribute.String("ssh.server_version", string(sshConn.ServerVersion())))
+	telemetry.EndSpan(handshakeSpan, nil)
 	
 	client := ssh.NewClient(sshConn, channels, requests)
 	
 	// Add to pool if available
 	if c.pool != nil {
-		c.pool.Put(c.config, client)
+		c.pool.Put(config, client)
 	}
 	
 	return client, nil
 }
 
+// dial opens the transport to the SSH server, either directly over TCP,
+// through the configured ProxyCommand, or via an HTTP CONNECT/SOCKS5 proxy
+func (c *SSHClient) dial(ctx context.Context, config models.SSHConnectionConfig, address string) (net.Conn, error) {
+	// ConnectTimeout bounds the whole transport setup, including any proxy hop
+	if config.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.ConnectTimeout)
+		defer cancel()
+	}
+	
+	if config.ProxyCommand != "" {
+		command, err := ExpandProxyCommand(config.ProxyCommand, config.Host, config.Port, config.Username)
+		if err != nil {
+			return nil, err
+		}
+		c.logger.Debug("dialing through proxy command", "command", command)
+		
+		conn, err := DialProxyCommand(ctx, command)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s via proxy command: %w", address, err)
+		}
+		return conn, nil
+	}
+	
+	if config.ProxyURL != "" {
+		proxyURL, err := ParseProxyURL(config.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		c.logger.Debug("dialing through proxy", "proxy", proxyURL.Redacted())
+		
+		conn, err := DialViaProxy(ctx, proxyURL, address, config.ConnectTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s via proxy %s: %w", address, proxyURL.Redacted(), err)
+		}
+		return conn, nil
+	}
+	
+	dialer := &net.Dialer{
+		Timeout: config.ConnectTimeout,
+	}
+	
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	return conn, nil
+}
+
 // Disconnect closes the SSH connection
 func (c *SSHClient) Disconnect() error {
 	c.mu.Lock()
@@ -199,7 +336,7 @@ func (c *SSHClient) Disconnect() error {
 	
 	// Return to pool instead of closing if pool is available
 	if c.pool != nil {
-		c.pool.Return(c.config, c.client)
+		c.pool.Return(c.config.WithEndpoint(c.active), c.client)
 		c.client = nil
 		c.connected = false
 		return nil
@@ -215,7 +352,7 @@ func (c *SSHClient) Disconnect() error {
 	}
 	
 	c.logger.Info("closed SSH connection", 
-		"host", c.config.Host, 
+		"host", c.active.Host, 
 		"user", c.config.Username)
 	
 	return nil
@@ -235,8 +372,17 @@ func (c *SSHClient) GetClient() *ssh.Client {
 	return c.client
 }
 
-// Reconnect reconnects the SSH client
+// BackoffNotifier is called after a failed reconnect attempt, before waiting delay
+type BackoffNotifier func(attempt int, delay time.Duration, err error)
+
+// Reconnect reconnects the SSH client using the configured retry policy
 func (c *SSHClient) Reconnect(ctx context.Context) error {
+	return c.ReconnectWithBackoff(ctx, NewBackoff(c.config.GetRetryPolicy()), nil)
+}
+
+// ReconnectWithBackoff reconnects the SSH client, waiting between attempts as
+// dictated by backoff. notify, if set, is called before each wait.
+func (c *SSHClient) ReconnectWithBackoff(ctx context.Context, backoff *Backoff, notify BackoffNotifier) error {
 	c.logger.Info("reconnecting SSH client", 
 		"host", c.config.Host, 
 		"user", c.config.Username)
@@ -246,32 +392,36 @@ func (c *SSHClient) Reconnect(ctx context.Context) error {
 		c.logger.Warn("error during disconnect for reconnect", "error", err)
 	}
 	
-	// Reconnect with retry logic
-	var lastErr error
-	for i := 0; i < c.config.MaxRetries; i++ {
-		if err := c.Connect(ctx); err == nil {
+	for {
+		err := c.Connect(ctx)
+		if err == nil {
 			c.logger.Info("reconnected successfully", 
 				"host", c.config.Host, 
-				"attempt", i+1)
+				"attempt", backoff.Attempt()+1)
+			backoff.Succeeded()
 			return nil
-		} else {
-			lastErr = err
-			c.logger.Warn("reconnect attempt failed", 
-				"host", c.config.Host, 
-				"attempt", i+1, 
-				"error", err)
-			
-			if i < c.config.MaxRetries-1 {
-				select {
-				case <-time.After(c.config.RetryInterval):
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
+		}
+		
+		attempt, delay, ok := backoff.Next()
+		c.logger.Warn("reconnect attempt failed", 
+			"host", c.config.Host, 
+			"attempt", attempt, 
+			"error", err)
+		
+		if !ok {
+			return fmt.Errorf("failed to reconnect after %d attempts: %w", attempt, err)
+		}
+		
+		if notify != nil {
+			notify(attempt, delay, err)
+		}
+		
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
-	
-	return fmt.Errorf("failed to reconnect after %d attempts: %w", c.config.MaxRetries, lastErr)
 }
 
 // Connection Pool Methods
@@ -358,6 +508,18 @@ func (cp *ConnectionPool) Return(config models.SSHConnectionConfig, client *ssh.
 	cp.logger.Debug("returned connection to pool", "key", key)
 }
 
+// Discard removes a connection that was lost from the pool
+func (cp *ConnectionPool) Discard(config models.SSHConnectionConfig, client *ssh.Client) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	
+	key := cp.getConnectionKey(config)
+	if conn, exists := cp.connections[key]; exists && conn.client == client {
+		delete(cp.connections, key)
+		cp.logger.Debug("discarded lost connection from pool", "key", key)
+	}
+}
+
 // cleanup removes idle and dead connections from the pool
 func (cp *ConnectionPool) cleanup() {
 	ticker := time.NewTicker(cp.maxIdleTime / 2)
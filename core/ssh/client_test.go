@@ -0,0 +1,76 @@
+package ssh
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+func TestSSHClientConnect(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+
+	if !client.IsConnected() {
+		t.Fatal("client is not connected")
+	}
+	session, err := client.GetClient().NewSession()
+	if err != nil {
+		t.Fatalf("failed to open session: %v", err)
+	}
+	defer session.Close()
+	output, err := session.Output("uptime")
+	if err != nil {
+		t.Fatalf("failed to run command: %v", err)
+	}
+	if string(output) != "uptime\n" {
+		t.Fatalf("output = %q, want the echoed command", output)
+	}
+
+	if err := client.Disconnect(); err != nil {
+		t.Fatalf("failed to disconnect: %v", err)
+	}
+	if client.IsConnected() {
+		t.Fatal("client is still connected after Disconnect")
+	}
+}
+
+func TestSSHClientRejectedPassword(t *testing.T) {
+	server := newTestServer(t)
+	config := server.ConnectionConfig()
+	config.Password = "wrong"
+	config.MaxRetries = 0
+
+	client := NewSSHClient(config, utils.DiscardLogger())
+	err := client.Connect(context.Background())
+	if err == nil {
+		client.Disconnect()
+		t.Fatal("connected with a wrong password")
+	}
+	if !strings.Contains(err.Error(), "unable to authenticate") {
+		t.Fatalf("error = %v, want an authentication failure", err)
+	}
+	if client.IsConnected() {
+		t.Fatal("client reports connected after a failed login")
+	}
+}
+
+func TestSSHClientReconnect(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	config := localConfig(t, startEchoServer(t))
+	startTestTunnel(t, client, config)
+	assertForwards(t, localAddr(config))
+
+	server.DropConnections()
+	if err := client.Reconnect(context.Background()); err != nil {
+		t.Fatalf("failed to reconnect: %v", err)
+	}
+	if got := server.Connections(); got != 2 {
+		t.Fatalf("server accepted %d connections, want 2", got)
+	}
+
+	// The running tunnel uses the new connection
+	assertForwards(t, localAddr(config))
+}
@@ -0,0 +1,201 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// targetResolver caches the addresses of a remote forward's target names
+// and tracks the connections dialed from the cache. Without it every
+// connection resolves the target on its own, and connections to an address
+// the name no longer points to live on until one side closes them.
+type targetResolver struct {
+	mu    sync.RWMutex
+	addrs map[string][]net.IP // target host -> addresses, in dial order
+	conns sync.Map            // map[net.Conn]string, target connections and the host they were dialed for
+
+	lookup func(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+func newTargetResolver() *targetResolver {
+	return &targetResolver{
+		addrs:  make(map[string][]net.IP),
+		lookup: net.DefaultResolver.LookupIP,
+	}
+}
+
+// lookupNetwork returns the LookupIP network matching a dial network
+func lookupNetwork(network string) string {
+	switch network {
+	case "tcp4":
+		return "ip4"
+	case "tcp6":
+		return "ip6"
+	default:
+		return "ip"
+	}
+}
+
+// targetHosts returns the names among the targets of config; IP literals
+// need no resolving
+func targetHosts(config models.TunnelConfig) []string {
+	var hosts []string
+	for _, addr := range config.TargetAddresses() {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil || slices.Contains(hosts, host) {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// dial connects to addr using the cached addresses of its host, trying them
+// in order. Hosts not resolved yet are dialed by name.
+func (r *targetResolver) dial(network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	ips := r.addrs[host]
+	r.mu.RUnlock()
+
+	var conn net.Conn
+	if len(ips) == 0 {
+		conn, err = net.Dial(network, addr)
+	} else {
+		for _, ip := range ips {
+			conn, err = net.Dial(network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.conns.Store(conn, host)
+	return conn, nil
+}
+
+// forget stops tracking a closed target connection
+func (r *targetResolver) forget(conn net.Conn) {
+	r.conns.Delete(conn)
+}
+
+// refreshTargets re-resolves the target names of config and, when asked to,
+// cycles the connections of hosts whose addresses changed. A failed lookup
+// keeps the addresses resolved before.
+func (tm *TunnelManager) refreshTargets(ctx context.Context, config models.TunnelConfig) {
+	r := tm.resolver
+	network := lookupNetwork(config.Network())
+
+	for _, host := range targetHosts(config) {
+		lookupCtx, cancel := context.WithTimeout(ctx, config.DNSRefresh.Interval)
+		ips, err := r.lookup(lookupCtx, network, host)
+		cancel()
+		if err == nil && len(ips) == 0 {
+			err = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				tm.logger.Warn("failed to re-resolve target, keeping cached addresses",
+					"host", host,
+					"error", err)
+			}
+			continue
+		}
+
+		r.mu.Lock()
+		previous := r.addrs[host]
+		r.addrs[host] = ips
+		r.mu.Unlock()
+
+		if previous == nil || sameAddresses(previous, ips) {
+			continue
+		}
+
+		tm.logger.Info("target addresses changed",
+			"host", host,
+			"previous", previous,
+			"current", ips)
+
+		if config.DNSRefresh.CycleConnections {
+			if closed := r.closeStale(host, ips); closed > 0 {
+				tm.logger.Info("closed connections to previous target addresses",
+					"host", host,
+					"connections", closed)
+			}
+		}
+	}
+}
+
+// closeStale closes the connections to host whose address is not among ips;
+// closing the target side ends the forwarded connection, and the client
+// reconnects to a current address
+func (r *targetResolver) closeStale(host string, ips []net.IP) int {
+	closed := 0
+	r.conns.Range(func(key, value interface{}) bool {
+		conn := key.(net.Conn)
+		if value.(string) != host {
+			return true
+		}
+		addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+		if !ok || slices.ContainsFunc(ips, addr.IP.Equal) {
+			return true
+		}
+		conn.Close()
+		closed++
+		return true
+	})
+	return closed
+}
+
+// sameAddresses reports whether a and b hold the same addresses, in any order
+func sameAddresses(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, ip := range a {
+		if !slices.ContainsFunc(b, ip.Equal) {
+			return false
+		}
+	}
+	return true
+}
+
+// runTargetRefresh re-resolves the targets at the configured interval until
+// ctx is done. The configuration is read on each round, so updates that
+// enable, disable or change the refresh apply without a restart.
+func (tm *TunnelManager) runTargetRefresh(ctx context.Context) {
+	defer tm.wg.Done()
+
+	for {
+		interval := models.MinDNSRefreshInterval
+		if config := tm.Config(); config.DNSRefresh != nil {
+			tm.refreshTargets(ctx, config)
+			interval = config.DNSRefresh.Interval
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// targetDial returns how handleRemoteConnection dials the target of config
+func (tm *TunnelManager) targetDial(config models.TunnelConfig) func(network, addr string) (net.Conn, error) {
+	if config.DNSRefresh == nil {
+		return net.Dial
+	}
+	return tm.resolver.dial
+}
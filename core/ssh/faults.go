@@ -0,0 +1,80 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FaultsEnv holds the faults to inject in binaries built with -tags chaos,
+// e.g. PORTFLY_FAULTS="dial_delay=2s,dial_fail=0.2,drop_after=1m,corrupt_reconnect=0.5"
+const FaultsEnv = "PORTFLY_FAULTS"
+
+// ErrInjectedFault is returned by dials failed on purpose
+var ErrInjectedFault = errors.New("injected fault")
+
+// ErrFaultsDisabled is returned by SetFaults in binaries built without -tags chaos
+var ErrFaultsDisabled = errors.New("fault injection requires building with -tags chaos")
+
+// Faults describes the failures injected into SSH connections, to check that
+// reconnection, backoff and session resume behave as designed. Faults are only
+// compiled in with the chaos build tag, so release binaries cannot enable them.
+type Faults struct {
+	DialDelay            time.Duration // added before every SSH dial
+	DialFailureRate      float64       // share of SSH dials that fail, 0-1
+	DropAfter            time.Duration // close each SSH connection after it has been up this long
+	CorruptReconnectRate float64       // share of reconnects whose handshake data is corrupted, 0-1
+}
+
+// IsZero reports whether no fault is configured
+func (f Faults) IsZero() bool {
+	return f == Faults{}
+}
+
+// ParseFaults parses a comma-separated list of key=value faults as used in
+// PORTFLY_FAULTS: dial_delay, dial_fail, drop_after and corrupt_reconnect
+func ParseFaults(spec string) (Faults, error) {
+	var faults Faults
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, found := strings.Cut(item, "=")
+		if !found {
+			return Faults{}, fmt.Errorf("invalid fault %q: expected key=value", item)
+		}
+
+		var err error
+		switch strings.TrimSpace(key) {
+		case "dial_delay":
+			faults.DialDelay, err = time.ParseDuration(value)
+		case "dial_fail":
+			faults.DialFailureRate, err = parseRate(value)
+		case "drop_after":
+			faults.DropAfter, err = time.ParseDuration(value)
+		case "corrupt_reconnect":
+			faults.CorruptReconnectRate, err = parseRate(value)
+		default:
+			return Faults{}, fmt.Errorf("unknown fault %q", key)
+		}
+		if err != nil {
+			return Faults{}, fmt.Errorf("invalid fault %q: %w", item, err)
+		}
+	}
+	return faults, nil
+}
+
+// parseRate parses a share between 0 and 1
+func parseRate(value string) (float64, error) {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate %v is not between 0 and 1", rate)
+	}
+	return rate, nil
+}
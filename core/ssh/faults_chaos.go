@@ -0,0 +1,113 @@
+//go:build chaos
+
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// corruptFrom and corruptUntil bound the bytes flipped on a corrupted
+// reconnect: past the version line, within key exchange
+const (
+	corruptFrom  = 64
+	corruptUntil = 4096
+)
+
+var (
+	activeFaults atomic.Pointer[Faults]
+
+	// SSH dial attempts per address, so the first connection is never corrupted
+	dialCounts   = make(map[string]int)
+	dialCountsMu sync.Mutex
+)
+
+func init() {
+	spec := os.Getenv(FaultsEnv)
+	if spec == "" {
+		return
+	}
+	faults, err := ParseFaults(spec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ignoring %s: %v\n", FaultsEnv, err)
+		return
+	}
+	activeFaults.Store(&faults)
+}
+
+// SetFaults replaces the injected faults; the zero value disables them
+func SetFaults(faults Faults) error {
+	activeFaults.Store(&faults)
+	return nil
+}
+
+// CurrentFaults returns the injected faults
+func CurrentFaults() Faults {
+	if faults := activeFaults.Load(); faults != nil {
+		return *faults
+	}
+	return Faults{}
+}
+
+// beforeDial delays or fails an SSH dial
+func beforeDial(ctx context.Context, address string) error {
+	dialCountsMu.Lock()
+	dialCounts[address]++
+	dialCountsMu.Unlock()
+
+	faults := CurrentFaults()
+	if faults.DialDelay > 0 {
+		timer := time.NewTimer(faults.DialDelay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if faults.DialFailureRate > 0 && rand.Float64() < faults.DialFailureRate {
+		return fmt.Errorf("%w: dial to %s failed", ErrInjectedFault, address)
+	}
+	return nil
+}
+
+// faultyConn arranges for an SSH transport to be dropped or, on a
+// reconnect, to have its handshake corrupted
+func faultyConn(conn net.Conn, address string) net.Conn {
+	faults := CurrentFaults()
+
+	dialCountsMu.Lock()
+	reconnect := dialCounts[address] > 1
+	dialCountsMu.Unlock()
+
+	if faults.DropAfter > 0 {
+		time.AfterFunc(faults.DropAfter, func() { conn.Close() })
+	}
+	if reconnect && faults.CorruptReconnectRate > 0 && rand.Float64() < faults.CorruptReconnectRate {
+		return &corruptConn{Conn: conn}
+	}
+	return conn
+}
+
+// corruptConn flips the bits of the key exchange data it reads
+type corruptConn struct {
+	net.Conn
+	offset int
+}
+
+func (c *corruptConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	for i := 0; i < n; i++ {
+		if position := c.offset + i; position >= corruptFrom && position < corruptUntil {
+			p[i] ^= 0xff
+		}
+	}
+	c.offset += n
+	return n, err
+}
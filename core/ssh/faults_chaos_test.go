@@ -0,0 +1,205 @@
+//go:build chaos
+
+package ssh
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// setTestFaults injects faults until the test ends
+func setTestFaults(t *testing.T, faults Faults) {
+	t.Helper()
+	if err := SetFaults(faults); err != nil {
+		t.Fatalf("failed to set faults: %v", err)
+	}
+	t.Cleanup(func() { SetFaults(Faults{}) })
+}
+
+// dialCount returns the SSH dials made to address
+func dialCount(address string) int {
+	dialCountsMu.Lock()
+	defer dialCountsMu.Unlock()
+	return dialCounts[address]
+}
+
+// testRetryPolicy retries quickly with predictable delays
+func testRetryPolicy() models.RetryPolicy {
+	return models.RetryPolicy{
+		InitialInterval: 10 * time.Millisecond,
+		MaxInterval:     40 * time.Millisecond,
+		Multiplier:      2,
+		MaxAttempts:     4,
+	}
+}
+
+type backoffCall struct {
+	attempt int
+	delay   time.Duration
+	err     error
+}
+
+func TestChaosDialFailuresExhaustBackoff(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	setTestFaults(t, Faults{DialFailureRate: 1})
+
+	var calls []backoffCall
+	err := client.ReconnectWithBackoff(context.Background(), NewBackoff(testRetryPolicy()),
+		func(attempt int, delay time.Duration, err error) {
+			calls = append(calls, backoffCall{attempt, delay, err})
+		})
+	if !errors.Is(err, ErrInjectedFault) {
+		t.Fatalf("error = %v, want ErrInjectedFault", err)
+	}
+	if client.IsConnected() {
+		t.Fatal("client reports connected after the dials failed")
+	}
+
+	// The delay doubles up to the cap, and the attempt past MaxAttempts gives up
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond}
+	if len(calls) != len(want) {
+		t.Fatalf("notified %d times, want %d", len(calls), len(want))
+	}
+	for i, call := range calls {
+		if call.attempt != i+1 || call.delay != want[i] {
+			t.Errorf("call %d = attempt %d after %v, want attempt %d after %v", i, call.attempt, call.delay, i+1, want[i])
+		}
+		if !errors.Is(call.err, ErrInjectedFault) {
+			t.Errorf("call %d error = %v, want ErrInjectedFault", i, call.err)
+		}
+	}
+	if got := dialCount(server.Addr()); got != 1+len(want)+1 {
+		t.Fatalf("dialed %d times, want %d", got, 1+len(want)+1)
+	}
+	if got := server.Connections(); got != 1 {
+		t.Fatalf("server accepted %d connections, want only the first", got)
+	}
+}
+
+func TestChaosReconnectAfterDialFailures(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	config := localConfig(t, startEchoServer(t))
+	startTestTunnel(t, client, config)
+	setTestFaults(t, Faults{DialFailureRate: 1})
+
+	// The network comes back after the second failure
+	backoff := NewBackoff(testRetryPolicy())
+	var attempts int
+	err := client.ReconnectWithBackoff(context.Background(), backoff,
+		func(attempt int, delay time.Duration, err error) {
+			attempts = attempt
+			if attempt == 2 {
+				SetFaults(Faults{})
+			}
+		})
+	if err != nil {
+		t.Fatalf("failed to reconnect: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("reconnected after %d failed attempts, want 2", attempts)
+	}
+	if got := backoff.Attempt(); got != 0 {
+		t.Fatalf("backoff attempt = %d after success, want 0", got)
+	}
+	if got := server.Connections(); got != 2 {
+		t.Fatalf("server accepted %d connections, want 2", got)
+	}
+	assertForwards(t, localAddr(config))
+}
+
+func TestChaosDialDelay(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	const delay = 200 * time.Millisecond
+	setTestFaults(t, Faults{DialDelay: delay})
+
+	start := time.Now()
+	if err := client.Reconnect(context.Background()); err != nil {
+		t.Fatalf("failed to reconnect: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Fatalf("reconnect took %v, want at least the %v dial delay", elapsed, delay)
+	}
+
+	// The delay honours the dial deadline
+	client.Disconnect()
+	ctx, cancel := context.WithTimeout(context.Background(), delay/4)
+	defer cancel()
+	if err := client.Connect(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want the context deadline", err)
+	}
+}
+
+func TestChaosCorruptedHandshakeRetries(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	config := localConfig(t, startEchoServer(t))
+	startTestTunnel(t, client, config)
+
+	// The first connection was clean, every reconnect handshake is corrupted
+	setTestFaults(t, Faults{CorruptReconnectRate: 1})
+	server.DropConnections()
+
+	var calls []backoffCall
+	err := client.ReconnectWithBackoff(context.Background(), NewBackoff(testRetryPolicy()),
+		func(attempt int, delay time.Duration, err error) {
+			calls = append(calls, backoffCall{attempt, delay, err})
+			if attempt == 3 {
+				SetFaults(Faults{})
+			}
+		})
+	if err != nil {
+		t.Fatalf("failed to reconnect once the handshake was clean: %v", err)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("notified %d times, want 3", len(calls))
+	}
+	for i, call := range calls {
+		if call.err == nil || errors.Is(call.err, ErrInjectedFault) {
+			t.Errorf("call %d error = %v, want a handshake failure", i, call.err)
+		}
+	}
+	if got := server.Connections(); got != 2 {
+		t.Fatalf("server completed %d handshakes, want 2", got)
+	}
+	assertForwards(t, localAddr(config))
+}
+
+func TestChaosDroppedConnectionReconnects(t *testing.T) {
+	const dropAfter = 200 * time.Millisecond
+	setTestFaults(t, Faults{DropAfter: dropAfter})
+	server := newTestServer(t)
+	client := NewSSHClient(server.ConnectionConfig(), utils.DiscardLogger())
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	config := localConfig(t, startEchoServer(t))
+	startTestTunnel(t, client, config)
+	assertForwards(t, localAddr(config))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.IsConnected() {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not notice the dropped connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	SetFaults(Faults{})
+	if err := client.Reconnect(context.Background()); err != nil {
+		t.Fatalf("failed to reconnect: %v", err)
+	}
+	time.Sleep(2 * dropAfter)
+	if !client.IsConnected() {
+		t.Fatal("reconnected client was dropped again after the faults were cleared")
+	}
+	assertForwards(t, localAddr(config))
+}
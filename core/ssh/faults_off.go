@@ -0,0 +1,29 @@
+//go:build !chaos
+
+package ssh
+
+import (
+	"context"
+	"net"
+)
+
+// SetFaults reports that fault injection is not compiled in
+func SetFaults(faults Faults) error {
+	if faults.IsZero() {
+		return nil
+	}
+	return ErrFaultsDisabled
+}
+
+// CurrentFaults returns no faults
+func CurrentFaults() Faults {
+	return Faults{}
+}
+
+func beforeDial(ctx context.Context, address string) error {
+	return nil
+}
+
+func faultyConn(conn net.Conn, address string) net.Conn {
+	return conn
+}
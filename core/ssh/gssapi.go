@@ -0,0 +1,200 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	krbclient "github.com/jcmturner/gokrb5/v8/client"
+	krbconfig "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// GSSAPIAuthProvider implements Kerberos SSO authentication (gssapi-with-mic)
+// using the user's existing ticket cache
+type GSSAPIAuthProvider struct{}
+
+// GetAuthMethods returns the gssapi-with-mic authentication method
+func (g *GSSAPIAuthProvider) GetAuthMethods(config models.SSHConnectionConfig) ([]ssh.AuthMethod, error) {
+	client, err := NewKerberosClient(config.KerberosCCache, config.KerberosConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return []ssh.AuthMethod{
+		ssh.GSSAPIWithMICAuthMethod(&krb5GSSAPIClient{client: client}, config.Host),
+	}, nil
+}
+
+// GetName returns the provider name
+func (g *GSSAPIAuthProvider) GetName() string {
+	return "gssapi"
+}
+
+// FindKerberosTicketCache resolves the ticket cache path from the explicit
+// path, $KRB5CCNAME, or the platform default, and checks that it exists
+func FindKerberosTicketCache(path string) (string, error) {
+	source := "configured path"
+	if path == "" {
+		path = os.Getenv("KRB5CCNAME")
+		source = "KRB5CCNAME"
+	}
+	if path == "" {
+		if runtime.GOOS == "windows" {
+			return "", fmt.Errorf("no Kerberos ticket cache configured; set KRB5CCNAME to a FILE: cache")
+		}
+		path = fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+		source = "default location"
+	}
+
+	if idx := strings.Index(path, ":"); idx > 0 && !strings.HasPrefix(path, "/") {
+		cacheType := strings.ToUpper(path[:idx])
+		if cacheType != "FILE" {
+			return "", fmt.Errorf("unsupported Kerberos ticket cache type %s (from %s); use a FILE: cache, e.g. KRB5CCNAME=FILE:/tmp/krb5cc_$(id -u) kinit", cacheType, source)
+		}
+		path = path[idx+1:]
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no Kerberos ticket found at %s (%s); run kinit to obtain a ticket", path, source)
+		}
+		return "", fmt.Errorf("cannot access Kerberos ticket cache %s: %w", path, err)
+	}
+
+	return path, nil
+}
+
+// NewKerberosClient loads the ticket cache and krb5 configuration and returns
+// a client ready to request service tickets
+func NewKerberosClient(ccachePath, krb5ConfPath string) (*krbclient.Client, error) {
+	path, err := FindKerberosTicketCache(ccachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	ccache, err := credentials.LoadCCache(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Kerberos ticket cache %s: %w", path, err)
+	}
+
+	if err := checkTicketGrantingTicket(ccache, path); err != nil {
+		return nil, err
+	}
+
+	cfg, err := loadKrb5Config(krb5ConfPath, ccache.GetClientRealm())
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := krbclient.NewFromCCache(ccache, cfg, krbclient.DisablePAFXFAST(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kerberos client: %w", err)
+	}
+
+	return client, nil
+}
+
+// checkTicketGrantingTicket verifies that the cache holds an unexpired TGT
+func checkTicketGrantingTicket(ccache *credentials.CCache, path string) error {
+	for _, cred := range ccache.GetEntries() {
+		names := cred.Server.PrincipalName.NameString
+		if len(names) == 0 || names[0] != "krbtgt" {
+			continue
+		}
+		if time.Now().After(cred.EndTime) {
+			return fmt.Errorf("Kerberos ticket for %s expired at %s; run kinit to renew it",
+				ccache.GetClientPrincipalName().PrincipalNameString(), cred.EndTime.Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("no ticket-granting ticket in Kerberos cache %s; run kinit to obtain one", path)
+}
+
+// loadKrb5Config loads krb5.conf from the given path, $KRB5_CONFIG or /etc/krb5.conf,
+// falling back to DNS-based KDC discovery for the ticket's realm
+func loadKrb5Config(path, realm string) (*krbconfig.Config, error) {
+	if path == "" {
+		path = os.Getenv("KRB5_CONFIG")
+	}
+	if path == "" {
+		path = "/etc/krb5.conf"
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		cfg, err := krbconfig.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Kerberos configuration %s: %w", path, err)
+		}
+		return cfg, nil
+	}
+
+	cfg := krbconfig.New()
+	cfg.LibDefaults.DefaultRealm = realm
+	cfg.LibDefaults.DNSLookupKDC = true
+	return cfg, nil
+}
+
+// krb5GSSAPIClient implements ssh.GSSAPIClient with the Kerberos V5 mechanism
+type krb5GSSAPIClient struct {
+	client *krbclient.Client
+	key    types.EncryptionKey
+}
+
+// InitSecContext builds the AP-REQ token for the target service.
+// Mutual authentication is not requested, so a single token completes the context.
+func (k *krb5GSSAPIClient) InitSecContext(target string, token []byte, isGSSDelegCreds bool) ([]byte, bool, error) {
+	if token != nil {
+		return nil, false, nil
+	}
+
+	// x/crypto passes "host@hostname"; Kerberos expects "host/hostname"
+	spn := strings.Replace(target, "@", "/", 1)
+
+	tkt, key, err := k.client.GetServiceTicket(spn)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get Kerberos service ticket for %s: %w", spn, err)
+	}
+
+	flags := []int{gssapi.ContextFlagInteg}
+	if isGSSDelegCreds {
+		flags = append(flags, gssapi.ContextFlagDeleg)
+	}
+
+	apReq, err := spnego.NewKRB5TokenAPREQ(k.client, tkt, key, flags, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to build Kerberos AP-REQ: %w", err)
+	}
+
+	outputToken, err := apReq.Marshal()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal Kerberos AP-REQ: %w", err)
+	}
+
+	k.key = key
+	return outputToken, false, nil
+}
+
+// GetMIC signs the SSH session binding with the established context key
+func (k *krb5GSSAPIClient) GetMIC(micField []byte) ([]byte, error) {
+	micToken, err := gssapi.NewInitiatorMICToken(micField, k.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute GSS-API MIC: %w", err)
+	}
+	return micToken.Marshal()
+}
+
+// DeleteSecContext releases the context key
+func (k *krb5GSSAPIClient) DeleteSecContext() error {
+	k.key = types.EncryptionKey{}
+	return nil
+}
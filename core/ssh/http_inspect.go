@@ -0,0 +1,424 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+const (
+	// httpInspectQueue bounds the reads queued for parsing per direction.
+	// When the parser falls behind it gives up rather than slow the tunnel.
+	httpInspectQueue = 64
+
+	// maxPipelinedRequests bounds the requests waiting for their response
+	maxPipelinedRequests = 32
+)
+
+// httpInspector parses the HTTP/1.x exchanges of one forwarded connection
+// from copies of the bytes read in each direction. It stops at the first
+// bytes that are not HTTP, at a protocol upgrade or CONNECT, or when it
+// falls behind, and never blocks the transfer.
+type httpInspector struct {
+	listener string
+	client   string
+	record   func(models.HTTPExchange)
+	log      *HTTPLog
+
+	requests  *chunkQueue
+	responses *chunkQueue
+	pending   chan pendingRequest
+
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// pendingRequest is a parsed request waiting for its response
+type pendingRequest struct {
+	request   *http.Request
+	startedAt time.Time
+	body      chan capturedBody // the request body, once read
+}
+
+// capturedBody is the start of a body, up to HTTPBodyLimit
+type capturedBody struct {
+	data      []byte
+	truncated bool
+}
+
+// newHTTPInspector starts parsing a connection accepted on listener; record
+// is called for each response as soon as its headers arrive, and the full
+// exchange is kept in log once the response body is read
+func newHTTPInspector(listener string, client net.Addr, record func(models.HTTPExchange), log *HTTPLog) *httpInspector {
+	hi := &httpInspector{
+		listener: listener,
+		record:   record,
+		log:      log,
+		pending:  make(chan pendingRequest, maxPipelinedRequests),
+		stopped:  make(chan struct{}),
+	}
+	if client != nil {
+		hi.client = client.String()
+	}
+	hi.requests = newChunkQueue(hi.stopped)
+	hi.responses = newChunkQueue(hi.stopped)
+
+	go hi.readRequests()
+	go hi.readResponses()
+	return hi
+}
+
+// observe queues a copy of data for the parser of its direction
+func (hi *httpInspector) observe(direction models.CaptureDirection, data []byte) {
+	queue := hi.requests
+	if direction == models.CaptureReceived {
+		queue = hi.responses
+	}
+	if !queue.push(data) {
+		hi.stop()
+	}
+}
+
+// close ends both streams once the connection is done
+func (hi *httpInspector) close() {
+	hi.requests.close()
+	hi.responses.close()
+}
+
+// stop abandons parsing
+func (hi *httpInspector) stop() {
+	hi.stopOnce.Do(func() { close(hi.stopped) })
+}
+
+// readRequests parses requests until the stream ends or is not HTTP
+func (hi *httpInspector) readRequests() {
+	reader := bufio.NewReader(hi.requests)
+	for {
+		request, err := http.ReadRequest(reader)
+		if err != nil {
+			hi.stop()
+			return
+		}
+
+		pending := pendingRequest{request: request, startedAt: time.Now(), body: make(chan capturedBody, 1)}
+		select {
+		case hi.pending <- pending:
+		default:
+			hi.stop()
+			return
+		}
+
+		// Past an upgrade or CONNECT the stream is no longer HTTP; keep
+		// draining so the response can still be recorded
+		if request.Method == http.MethodConnect || request.Header.Get("Upgrade") != "" {
+			pending.body <- capturedBody{}
+			io.Copy(io.Discard, reader)
+			return
+		}
+		body, err := readBody(request.Body)
+		pending.body <- body
+		if err != nil {
+			hi.stop()
+			return
+		}
+	}
+}
+
+// readBody keeps the start of a body and discards the rest
+func readBody(body io.ReadCloser) (capturedBody, error) {
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, models.HTTPBodyLimit))
+	if err != nil {
+		return capturedBody{data: data}, err
+	}
+	rest, err := io.Copy(io.Discard, body)
+	return capturedBody{data: data, truncated: rest > 0}, err
+}
+
+// readResponses parses the response to each pending request
+func (hi *httpInspector) readResponses() {
+	reader := bufio.NewReader(hi.responses)
+	for {
+		var pending pendingRequest
+		select {
+		case pending = <-hi.pending:
+		case <-hi.stopped:
+			return
+		}
+
+		response, err := readFinalResponse(reader, pending.request)
+		if err != nil {
+			hi.stop()
+			return
+		}
+
+		request := pending.request
+		exchange := models.HTTPExchange{
+			Listener:      hi.listener,
+			Client:        hi.client,
+			Method:        request.Method,
+			Host:          request.Host,
+			Path:          request.RequestURI,
+			Proto:         request.Proto,
+			Status:        response.StatusCode,
+			Duration:      time.Since(pending.startedAt),
+			RequestBytes:  request.ContentLength,
+			ResponseBytes: response.ContentLength,
+			StartedAt:     pending.startedAt,
+		}
+		hi.record(exchange)
+
+		record := models.HTTPRecord{
+			HTTPExchange:   exchange,
+			RequestHeader:  request.Header,
+			ResponseHeader: response.Header,
+		}
+		tunneled := response.StatusCode == http.StatusSwitchingProtocols ||
+			(request.Method == http.MethodConnect && response.StatusCode/100 == 2)
+
+		select {
+		case body := <-pending.body:
+			record.RequestBody, record.RequestBodyTruncated = body.data, body.truncated
+		case <-hi.stopped:
+			return
+		}
+
+		var bodyErr error
+		if !tunneled {
+			var body capturedBody
+			body, bodyErr = readBody(response.Body)
+			record.ResponseBody, record.ResponseBodyTruncated = body.data, body.truncated
+		}
+		if hi.log != nil {
+			hi.log.add(record)
+		}
+		if tunneled || bodyErr != nil {
+			hi.stop()
+			return
+		}
+	}
+}
+
+// readFinalResponse reads the response to request, skipping interim 1xx
+// responses such as 100 Continue
+func readFinalResponse(reader *bufio.Reader, request *http.Request) (*http.Response, error) {
+	for {
+		response, err := http.ReadResponse(reader, request)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode >= 200 || response.StatusCode == http.StatusSwitchingProtocols {
+			return response, nil
+		}
+		response.Body.Close()
+	}
+}
+
+// chunkQueue is a reader over copies of the bytes read from one side of a
+// connection. push never blocks; it reports false when the queue is full.
+type chunkQueue struct {
+	chunks  chan []byte
+	current []byte
+	stopped <-chan struct{}
+}
+
+func newChunkQueue(stopped <-chan struct{}) *chunkQueue {
+	return &chunkQueue{chunks: make(chan []byte, httpInspectQueue), stopped: stopped}
+}
+
+// push queues a copy of data unless parsing stopped or the queue is full
+func (q *chunkQueue) push(data []byte) bool {
+	select {
+	case <-q.stopped:
+		return true
+	default:
+	}
+
+	select {
+	case q.chunks <- append([]byte(nil), data...):
+		return true
+	default:
+		return false
+	}
+}
+
+// close ends the stream; push must not be called afterwards
+func (q *chunkQueue) close() {
+	close(q.chunks)
+}
+
+func (q *chunkQueue) Read(p []byte) (int, error) {
+	for len(q.current) == 0 {
+		select {
+		case chunk, ok := <-q.chunks:
+			if !ok {
+				return 0, io.EOF
+			}
+			q.current = chunk
+		case <-q.stopped:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, q.current)
+	q.current = q.current[n:]
+	return n, nil
+}
+
+// HTTPLog returns the recent requests seen by the HTTP inspector
+func (tm *TunnelManager) HTTPLog() *HTTPLog {
+	return tm.httpLog
+}
+
+// ReplayHTTP sends a kept request to the forwarding target again, the way
+// the tunnel reaches it: through the SSH connection for local forwards and
+// directly for remote forwards. The response is kept as a new request.
+func (tm *TunnelManager) ReplayHTTP(ctx context.Context, id uint64) (models.HTTPRecord, error) {
+	original, ok := tm.httpLog.Get(id)
+	if !ok {
+		return models.HTTPRecord{}, fmt.Errorf("%w: %d", models.ErrHTTPRecordNotFound, id)
+	}
+	if original.RequestBodyTruncated {
+		return models.HTTPRecord{}, models.ErrHTTPReplayTruncated
+	}
+
+	config := tm.Config()
+	var dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	switch config.Type {
+	case models.TunnelTypeLocal:
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if tm.wireguard != nil {
+				return tm.wireguard.Dial(config.Network(), config.TargetAddress())
+			}
+			client := tm.sshClient.GetClient()
+			if client == nil {
+				return nil, fmt.Errorf("SSH client not available")
+			}
+			return client.DialContext(ctx, config.Network(), config.TargetAddress())
+		}
+	case models.TunnelTypeRemote:
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, config.Network(), config.TargetAddress())
+		}
+	default:
+		return models.HTTPRecord{}, fmt.Errorf("replay is not supported for %s tunnels", config.Type)
+	}
+
+	target, err := url.Parse(original.Path)
+	if err != nil {
+		return models.HTTPRecord{}, fmt.Errorf("invalid request target %q: %w", original.Path, err)
+	}
+	if !target.IsAbs() {
+		target.Scheme, target.Host = "http", original.Host
+	}
+	request, err := http.NewRequestWithContext(ctx, original.Method, target.String(), bytes.NewReader(original.RequestBody))
+	if err != nil {
+		return models.HTTPRecord{}, err
+	}
+	request.Header = original.RequestHeader.Clone()
+	request.Host = original.Host
+
+	client := &http.Client{
+		Transport: &http.Transport{DialContext: dial, DisableKeepAlives: true, DisableCompression: true},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	startedAt := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		return models.HTTPRecord{}, fmt.Errorf("replay failed: %w", err)
+	}
+	duration := time.Since(startedAt)
+	body, err := readBody(response.Body)
+	if err != nil {
+		return models.HTTPRecord{}, fmt.Errorf("failed to read replayed response: %w", err)
+	}
+
+	replayed := models.HTTPRecord{
+		ReplayOf: id,
+		HTTPExchange: models.HTTPExchange{
+			Listener:      original.Listener,
+			Client:        "replay",
+			Method:        original.Method,
+			Host:          original.Host,
+			Path:          original.Path,
+			Proto:         original.Proto,
+			Status:        response.StatusCode,
+			Duration:      duration,
+			RequestBytes:  int64(len(original.RequestBody)),
+			ResponseBytes: response.ContentLength,
+			StartedAt:     startedAt,
+		},
+		RequestHeader:         original.RequestHeader,
+		RequestBody:           original.RequestBody,
+		ResponseHeader:        response.Header,
+		ResponseBody:          body.data,
+		ResponseBodyTruncated: body.truncated,
+	}
+	replayed.ID = tm.httpLog.add(replayed)
+	tm.recordHTTP(replayed.HTTPExchange)
+	return replayed, nil
+}
+
+// HTTPLog keeps the most recent requests seen by a tunnel's HTTP inspector
+type HTTPLog struct {
+	mu      sync.RWMutex
+	records []models.HTTPRecord // oldest first
+	size    int
+	nextID  uint64
+}
+
+// NewHTTPLog creates a log keeping up to size requests
+func NewHTTPLog(size int) *HTTPLog {
+	return &HTTPLog{size: size}
+}
+
+// add stores a record, evicting the oldest when full, and returns its ID
+func (l *HTTPLog) add(record models.HTTPRecord) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextID++
+	record.ID = l.nextID
+	if len(l.records) >= l.size {
+		l.records = append(l.records[:0], l.records[1:]...)
+	}
+	l.records = append(l.records, record)
+	return record.ID
+}
+
+// List returns summaries of the kept requests, newest first
+func (l *HTTPLog) List() []models.HTTPRecord {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	summaries := make([]models.HTTPRecord, 0, len(l.records))
+	for i := len(l.records) - 1; i >= 0; i-- {
+		summaries = append(summaries, l.records[i].Summary())
+	}
+	return summaries
+}
+
+// Get returns a kept request with its headers and bodies
+func (l *HTTPLog) Get(id uint64) (models.HTTPRecord, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	for _, record := range l.records {
+		if record.ID == id {
+			return record, true
+		}
+	}
+	return models.HTTPRecord{}, false
+}
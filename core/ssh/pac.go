@@ -0,0 +1,77 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// pacReadTimeout bounds reading the request of a PAC file
+const pacReadTimeout = 10 * time.Second
+
+// isSOCKSRequest reports whether a connection's first byte is a SOCKS
+// version rather than the start of an HTTP request
+func isSOCKSRequest(first byte) bool {
+	return first == 4 || first == 5
+}
+
+// servePAC answers an HTTP request on the SOCKS port with the tunnel's PAC
+// file. The proxy address in the file is the one the client reached this
+// listener on, so it works for any bind address.
+func (tm *TunnelManager) servePAC(conn net.Conn, reader *bufio.Reader, config models.TunnelConfig) {
+	conn.SetReadDeadline(time.Now().Add(pacReadTimeout))
+	request, err := http.ReadRequest(reader)
+	if err != nil {
+		tm.logger.Debug("invalid request on SOCKS port", "client_addr", conn.RemoteAddr(), "error", err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	response := &http.Response{
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Close:      true,
+	}
+	switch {
+	case request.URL.Path != models.PACPath:
+		response.StatusCode = http.StatusNotFound
+	case request.Method != http.MethodGet && request.Method != http.MethodHead:
+		response.StatusCode = http.StatusMethodNotAllowed
+	default:
+		proxyAddr := request.Host
+		if _, _, err := net.SplitHostPort(proxyAddr); err != nil {
+			proxyAddr = conn.LocalAddr().String()
+		}
+		body, err := config.PAC.File(proxyAddr, config.SOCKSVersion)
+		if err != nil {
+			tm.logger.Error("failed to generate PAC file", "error", err)
+			response.StatusCode = http.StatusInternalServerError
+			break
+		}
+
+		response.StatusCode = http.StatusOK
+		response.Header.Set("Content-Type", models.PACContentType)
+		response.Header.Set("Cache-Control", "no-cache")
+		response.ContentLength = int64(len(body))
+		if request.Method == http.MethodGet {
+			response.Body = io.NopCloser(strings.NewReader(body))
+		}
+	}
+	response.Status = fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode))
+
+	if err := response.Write(conn); err != nil {
+		tm.logger.Debug("failed to write PAC response", "client_addr", conn.RemoteAddr(), "error", err)
+		return
+	}
+	tm.logger.Debug("served PAC file",
+		"client_addr", conn.RemoteAddr(),
+		"path", request.URL.Path,
+		"status", response.StatusCode)
+}
@@ -0,0 +1,32 @@
+package ssh
+
+import "context"
+
+// StartPhase is a step of bringing a connection and its tunnel up
+type StartPhase string
+
+const (
+	PhaseConnecting    StartPhase = "connecting"     // dialing the SSH server or its proxy
+	PhaseHandshake     StartPhase = "handshake"      // transport is up, SSH handshake and authentication run
+	PhaseListenerBound StartPhase = "listener_bound" // tunnel listeners accept connections
+)
+
+// ProgressFunc is told each startup phase as it begins. It runs on the
+// connecting goroutine and must not block.
+type ProgressFunc func(phase StartPhase)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose connection and session startups report
+// their phases to progress
+func WithProgress(ctx context.Context, progress ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, progress)
+}
+
+// ReportProgress tells the progress function carried by ctx, if any, that
+// phase has begun
+func ReportProgress(ctx context.Context, phase StartPhase) {
+	if progress, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && progress != nil {
+		progress(phase)
+	}
+}
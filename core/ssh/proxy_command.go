@@ -0,0 +1,163 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProxyCommandConn is a net.Conn backed by the stdio of an external process,
+// in the style of OpenSSH's ProxyCommand (cloudflared, aws ssm, nc, ...)
+type ProxyCommandConn struct {
+	cmd     *exec.Cmd
+	stdin   *os.File // we write here, the process reads it
+	stdout  *os.File // the process writes here, we read it
+	command string
+	once    sync.Once
+}
+
+// Proxy command tokens are substituted into a shell command line, so the
+// values must not carry shell syntax or look like options
+var (
+	proxyCommandHost = regexp.MustCompile(`^[A-Za-z0-9_.:\[\]][A-Za-z0-9_.:\[\]-]*$`)
+	proxyCommandUser = regexp.MustCompile(`^[A-Za-z0-9_.][A-Za-z0-9_.@-]*$`)
+)
+
+// ExpandProxyCommand substitutes the OpenSSH tokens %h, %p, %r and %% in
+// command. Hosts and users with characters outside hostnames and user names
+// are refused rather than handed to the shell.
+func ExpandProxyCommand(command, host string, port int, user string) (string, error) {
+	if strings.Contains(command, "%h") && !proxyCommandHost.MatchString(host) {
+		return "", fmt.Errorf("host %q cannot be passed to a proxy command", host)
+	}
+	if strings.Contains(command, "%r") && !proxyCommandUser.MatchString(user) {
+		return "", fmt.Errorf("user %q cannot be passed to a proxy command", user)
+	}
+	replacer := strings.NewReplacer(
+		"%%", "%",
+		"%h", host,
+		"%p", strconv.Itoa(port),
+		"%r", user,
+	)
+	return replacer.Replace(command), nil
+}
+
+// DialProxyCommand starts command through the system shell and returns a
+// connection speaking over its stdin/stdout. The process lives until the
+// connection is closed; ctx only guards the start.
+func DialProxyCommand(ctx context.Context, command string) (*ProxyCommandConn, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("proxy command is empty")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", command)
+	}
+
+	// Use our own pipes so both ends are *os.File and support deadlines
+	procStdin, stdin, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+	stdout, procStdout, err := os.Pipe()
+	if err != nil {
+		procStdin.Close()
+		stdin.Close()
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	cmd.Stdin = procStdin
+	cmd.Stdout = procStdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		procStdin.Close()
+		stdin.Close()
+		stdout.Close()
+		procStdout.Close()
+		return nil, fmt.Errorf("failed to start proxy command %q: %w", command, err)
+	}
+
+	// The child owns its ends now
+	procStdin.Close()
+	procStdout.Close()
+
+	return &ProxyCommandConn{
+		cmd:     cmd,
+		stdin:   stdin,
+		stdout:  stdout,
+		command: command,
+	}, nil
+}
+
+// Read reads data from the proxy command's stdout
+func (c *ProxyCommandConn) Read(b []byte) (int, error) {
+	return c.stdout.Read(b)
+}
+
+// Write writes data to the proxy command's stdin
+func (c *ProxyCommandConn) Write(b []byte) (int, error) {
+	return c.stdin.Write(b)
+}
+
+// Close closes the pipes and terminates the proxy command
+func (c *ProxyCommandConn) Close() error {
+	c.once.Do(func() {
+		c.stdin.Close()
+		c.stdout.Close()
+		if c.cmd.Process != nil {
+			c.cmd.Process.Kill()
+		}
+		// Reap the process in the background
+		go c.cmd.Wait()
+	})
+	return nil
+}
+
+// LocalAddr returns a placeholder address for the pipe
+func (c *ProxyCommandConn) LocalAddr() net.Addr {
+	return proxyCommandAddr("local")
+}
+
+// RemoteAddr returns the proxy command as the remote address
+func (c *ProxyCommandConn) RemoteAddr() net.Addr {
+	return proxyCommandAddr(c.command)
+}
+
+// SetDeadline sets read and write deadlines
+func (c *ProxyCommandConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline
+func (c *ProxyCommandConn) SetReadDeadline(t time.Time) error {
+	return c.stdout.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline
+func (c *ProxyCommandConn) SetWriteDeadline(t time.Time) error {
+	return c.stdin.SetWriteDeadline(t)
+}
+
+// proxyCommandAddr implements net.Addr for proxy command connections
+type proxyCommandAddr string
+
+func (a proxyCommandAddr) Network() string { return "proxycommand" }
+func (a proxyCommandAddr) String() string  { return string(a) }
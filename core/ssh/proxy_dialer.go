@@ -0,0 +1,160 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ParseProxyURL parses and validates an outbound proxy URL.
+// Supported schemes: http, https (HTTP CONNECT) and socks5, socks5h.
+func ParseProxyURL(rawURL string) (*url.URL, error) {
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch strings.ToLower(proxyURL.Scheme) {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %q", proxyURL.Scheme)
+	}
+
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("proxy URL is missing a host")
+	}
+
+	return proxyURL, nil
+}
+
+// DialViaProxy connects to address through the given HTTP CONNECT or SOCKS5 proxy
+func DialViaProxy(ctx context.Context, proxyURL *url.URL, address string, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	switch strings.ToLower(proxyURL.Scheme) {
+	case "socks5", "socks5h":
+		return dialSOCKS5(ctx, dialer, proxyURL, address)
+	case "http", "https":
+		return dialHTTPConnect(ctx, dialer, proxyURL, address)
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %q", proxyURL.Scheme)
+	}
+}
+
+// dialSOCKS5 dials address through an upstream SOCKS5 proxy
+func dialSOCKS5(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, address string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{
+			User:     proxyURL.User.Username(),
+			Password: password,
+		}
+	}
+
+	socksDialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, dialer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
+	}
+
+	contextDialer, ok := socksDialer.(proxy.ContextDialer)
+	if !ok {
+		return socksDialer.Dial("tcp", address)
+	}
+	return contextDialer.DialContext(ctx, "tcp", address)
+}
+
+// dialHTTPConnect dials address by issuing a CONNECT request to an HTTP(S) proxy
+func dialHTTPConnect(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, address string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		if strings.EqualFold(proxyURL.Scheme, "https") {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "443")
+		} else {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy %s: %w", proxyAddr, err)
+	}
+
+	if strings.EqualFold(proxyURL.Scheme, "https") {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with proxy failed: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	// Bound the CONNECT exchange by the dial context
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else if dialer.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT request: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		conn.Close()
+		return nil, fmt.Errorf("proxy authentication required by %s", proxyAddr)
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT to %s failed: %s", address, resp.Status)
+	}
+
+	// Clear the handshake deadline before handing the connection over
+	conn.SetDeadline(time.Time{})
+
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn returns bytes already buffered during the CONNECT handshake before reading from the connection
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Read reads from the buffer first, then the underlying connection
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,123 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// probeNoTool is the exit status of the probe script when the host has
+// neither nc nor bash
+const probeNoTool = 127
+
+// probeTimedOut is the exit status of timeout(1) when the probe ran out of time
+const probeTimedOut = 124
+
+// probeScript connects to %[2]s port %[3]d from the remote host within %[1]d
+// seconds with whichever tool it has
+const probeScript = `if command -v nc >/dev/null 2>&1; then exec nc -z -w %[1]d %[2]s %[3]d; fi
+if command -v bash >/dev/null 2>&1; then
+  if command -v timeout >/dev/null 2>&1; then exec timeout %[1]d bash -c 'exec 3<>"/dev/tcp/$1/$2"' probe %[2]s %[3]d; fi
+  exec bash -c 'exec 3<>"/dev/tcp/$1/$2"' probe %[2]s %[3]d
+fi
+exit 127`
+
+// ProbeResult is the outcome of connecting from the remote host to an address
+type ProbeResult struct {
+	Reachable bool
+	Method    string        // models.ReachabilityMethodForward or Command; empty when the probe itself failed
+	Latency   time.Duration // time to connect; only measured by forward probes
+	Err       error         // why the address is unreachable, or why it could not be probed
+}
+
+// ProbeTCP checks whether the remote host can open a TCP connection to host
+// and port. It asks the SSH server to connect, as a local forward would, and
+// falls back to running nc or bash on the host when the server does not
+// forward TCP.
+func (c *SSHClient) ProbeTCP(ctx context.Context, host string, port int, timeout time.Duration) ProbeResult {
+	client := c.GetClient()
+	if client == nil {
+		return ProbeResult{Err: fmt.Errorf("SSH client not connected")}
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	started := time.Now()
+	conn, err := client.DialContext(dialCtx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err == nil {
+		latency := time.Since(started)
+		conn.Close()
+		return ProbeResult{Reachable: true, Method: models.ReachabilityMethodForward, Latency: latency}
+	}
+
+	var openErr *ssh.OpenChannelError
+	if errors.As(err, &openErr) && openErr.Reason == ssh.Prohibited {
+		return probeCommand(ctx, client, host, port, timeout)
+	}
+	if ctx.Err() != nil {
+		return ProbeResult{Err: ctx.Err()}
+	}
+	if dialCtx.Err() != nil {
+		err = fmt.Errorf("connection timed out after %s", timeout)
+	}
+	return ProbeResult{Method: models.ReachabilityMethodForward, Err: err}
+}
+
+// probeCommand checks reachability by running the probe script on the host
+func probeCommand(ctx context.Context, client *ssh.Client, host string, port int, timeout time.Duration) ProbeResult {
+	session, err := client.NewSession()
+	if err != nil {
+		return ProbeResult{Err: fmt.Errorf("failed to create SSH session: %w", err)}
+	}
+	defer session.Close()
+
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	seconds := max(int(timeout.Round(time.Second)/time.Second), 1)
+
+	done := make(chan error, 1)
+	go func() { done <- session.Run(fmt.Sprintf(probeScript, seconds, quoteShell(host), port)) }()
+	// The script enforces the timeout itself; give it a moment to report
+	limit := time.NewTimer(timeout + 5*time.Second)
+	defer limit.Stop()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		session.Close()
+		<-done
+		return ProbeResult{Err: ctx.Err()}
+	case <-limit.C:
+		session.Close()
+		<-done
+		return ProbeResult{Method: models.ReachabilityMethodCommand, Err: fmt.Errorf("connection timed out after %s", timeout)}
+	}
+
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		return ProbeResult{Reachable: true, Method: models.ReachabilityMethodCommand}
+	case errors.As(err, &exitErr) && exitErr.ExitStatus() == probeNoTool:
+		return ProbeResult{Err: fmt.Errorf("SSH server does not forward TCP and the host has neither nc nor bash")}
+	case errors.As(err, &exitErr) && exitErr.ExitStatus() == probeTimedOut:
+		return ProbeResult{Method: models.ReachabilityMethodCommand, Err: fmt.Errorf("connection timed out after %s", timeout)}
+	case errors.As(err, &exitErr):
+		// bash reports the failure twice; the last line names the address
+		if lines := strings.Split(strings.TrimSpace(stderr.String()), "\n"); lines[len(lines)-1] != "" {
+			err = errors.New(lines[len(lines)-1])
+		} else {
+			err = fmt.Errorf("connection failed (exit status %d)", exitErr.ExitStatus())
+		}
+		return ProbeResult{Method: models.ReachabilityMethodCommand, Err: err}
+	default:
+		return ProbeResult{Err: err}
+	}
+}
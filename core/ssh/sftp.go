@@ -0,0 +1,56 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/aqz236/port-fly/core/sftp"
+)
+
+// SFTP starts the sftp subsystem on a new session of the connection. Closing
+// the returned client closes the session.
+func (c *SSHClient) SFTP() (*sftp.Client, error) {
+	client := c.GetClient()
+	if client == nil {
+		return nil, fmt.Errorf("SSH client not connected")
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("failed to start sftp subsystem: %w", err)
+	}
+
+	sftpClient, err := sftp.NewClient(&sessionPipe{Reader: stdout, Writer: stdin, session: session})
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return sftpClient, nil
+}
+
+// sessionPipe carries a subsystem over a session's stdin and stdout
+type sessionPipe struct {
+	io.Reader
+	io.Writer
+	session *ssh.Session
+}
+
+func (p *sessionPipe) Close() error {
+	return p.session.Close()
+}
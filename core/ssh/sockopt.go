@@ -0,0 +1,56 @@
+package ssh
+
+import (
+	"errors"
+	"net"
+	"syscall"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ErrReusePortUnsupported is returned when SO_REUSEPORT is requested on a
+// platform without it
+var ErrReusePortUnsupported = errors.New("SO_REUSEPORT is not supported on this platform")
+
+// applySocketOptions tunes a forwarded connection. Only TCP connections are
+// affected; SSH channels and unix sockets are left alone.
+func applySocketOptions(conn net.Conn, options models.SocketOptions) error {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok || options.IsZero() {
+		return nil
+	}
+
+	var errs []error
+	switch {
+	case options.DisableKeepAlive:
+		errs = append(errs, tcpConn.SetKeepAlive(false))
+	case options.CustomKeepAlive():
+		errs = append(errs, tcpConn.SetKeepAliveConfig(net.KeepAliveConfig{
+			Enable:   true,
+			Idle:     options.KeepAliveIdle,
+			Interval: options.KeepAliveInterval,
+			Count:    options.KeepAliveCount,
+		}))
+	}
+	if options.DisableNoDelay {
+		errs = append(errs, tcpConn.SetNoDelay(false))
+	}
+	return errors.Join(errs...)
+}
+
+// listenControl returns the control function setting the listener options
+// that must be applied before bind, or nil when there are none
+func listenControl(options models.SocketOptions) func(network, address string, c syscall.RawConn) error {
+	if !options.ReusePort {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		if err := c.Control(func(fd uintptr) {
+			sockErr = setReusePort(fd)
+		}); err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package ssh
+
+// setReusePort reports that SO_REUSEPORT is not available
+func setReusePort(fd uintptr) error {
+	return ErrReusePortUnsupported
+}
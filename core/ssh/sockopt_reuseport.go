@@ -0,0 +1,12 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package ssh
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// setReusePort enables SO_REUSEPORT on a socket before it is bound
+func setReusePort(fd uintptr) error {
+	return unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+}
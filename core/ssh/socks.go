@@ -0,0 +1,230 @@
+package ssh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// socksHandshakeTimeout bounds reading a SOCKS request
+const socksHandshakeTimeout = 30 * time.Second
+
+// SOCKS protocol values used by the proxy
+const (
+	socks4Version = 0x04
+	socks5Version = 0x05
+
+	socksConnect = 0x01
+
+	socks4Granted  = 0x5a
+	socks4Rejected = 0x5b
+
+	socks5NoAuth       = 0x00
+	socks5NoAcceptable = 0xff
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+
+	socks5Succeeded          = 0x00
+	socks5GeneralFailure     = 0x01
+	socks5NetworkUnreachable = 0x03
+	socks5HostUnreachable    = 0x04
+	socks5ConnectionRefused  = 0x05
+	socks5CommandUnsupported = 0x07
+	socks5AddressUnsupported = 0x08
+
+	socks4MaxFieldLength = 255 // user IDs and SOCKS4a domain names
+)
+
+// errSOCKSRejected marks handshakes refused after the reply was sent
+var errSOCKSRejected = errors.New("SOCKS request rejected")
+
+// socksReply answers a SOCKS CONNECT request once the target was dialed, or
+// failed to be
+type socksReply func(dialErr error) error
+
+// handleSOCKSHandshake reads the SOCKS request on conn and returns the target
+// it asks for. SOCKS5 tunnels also accept SOCKS4 clients, which PAC files
+// list as the fallback.
+func (tm *TunnelManager) handleSOCKSHandshake(conn net.Conn, socksVersion int) (string, socksReply, error) {
+	conn.SetReadDeadline(time.Now().Add(socksHandshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var version [1]byte
+	if _, err := io.ReadFull(conn, version[:]); err != nil {
+		return "", nil, fmt.Errorf("failed to read SOCKS version: %w", err)
+	}
+	switch {
+	case version[0] == socks4Version:
+		return tm.handleSOCKS4(conn)
+	case version[0] == socks5Version && socksVersion == 5:
+		return tm.handleSOCKS5(conn)
+	default:
+		return "", nil, fmt.Errorf("unsupported SOCKS version %d on a SOCKS%d proxy", version[0], socksVersion)
+	}
+}
+
+// handleSOCKS4 handles SOCKS4 and SOCKS4a CONNECT requests; the version byte
+// was already read
+func (tm *TunnelManager) handleSOCKS4(conn net.Conn) (string, socksReply, error) {
+	var header [7]byte // command, port, IPv4 address
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return "", nil, fmt.Errorf("failed to read SOCKS4 request: %w", err)
+	}
+	if _, err := readNullTerminated(conn, socks4MaxFieldLength); err != nil {
+		return "", nil, fmt.Errorf("failed to read SOCKS4 user ID: %w", err)
+	}
+
+	reply := func(dialErr error) error {
+		status := byte(socks4Granted)
+		if dialErr != nil {
+			status = socks4Rejected
+		}
+		_, err := conn.Write([]byte{0x00, status, 0, 0, 0, 0, 0, 0})
+		return err
+	}
+
+	if header[0] != socksConnect {
+		reply(errSOCKSRejected)
+		return "", nil, fmt.Errorf("%w: unsupported SOCKS4 command %d", errSOCKSRejected, header[0])
+	}
+	port := binary.BigEndian.Uint16(header[1:3])
+	host := net.IP(header[3:7]).String()
+	// SOCKS4a: 0.0.0.x with x != 0 means the domain name follows the user ID
+	if header[3] == 0 && header[4] == 0 && header[5] == 0 && header[6] != 0 {
+		domain, err := readNullTerminated(conn, socks4MaxFieldLength)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read SOCKS4a domain name: %w", err)
+		}
+		host = domain
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), reply, nil
+}
+
+// handleSOCKS5 handles SOCKS5 CONNECT requests without authentication; the
+// version byte was already read
+func (tm *TunnelManager) handleSOCKS5(conn net.Conn) (string, socksReply, error) {
+	var count [1]byte
+	if _, err := io.ReadFull(conn, count[:]); err != nil {
+		return "", nil, fmt.Errorf("failed to read SOCKS5 methods: %w", err)
+	}
+	methods := make([]byte, count[0])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", nil, fmt.Errorf("failed to read SOCKS5 methods: %w", err)
+	}
+	noAuth := false
+	for _, method := range methods {
+		if method == socks5NoAuth {
+			noAuth = true
+			break
+		}
+	}
+	if !noAuth {
+		conn.Write([]byte{socks5Version, socks5NoAcceptable})
+		return "", nil, fmt.Errorf("%w: client requires SOCKS5 authentication", errSOCKSRejected)
+	}
+	if _, err := conn.Write([]byte{socks5Version, socks5NoAuth}); err != nil {
+		return "", nil, err
+	}
+
+	var header [4]byte // version, command, reserved, address type
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return "", nil, fmt.Errorf("failed to read SOCKS5 request: %w", err)
+	}
+	if header[0] != socks5Version {
+		return "", nil, fmt.Errorf("invalid SOCKS5 request version %d", header[0])
+	}
+
+	reply := func(dialErr error) error {
+		_, err := conn.Write([]byte{socks5Version, socks5ReplyCode(dialErr), 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+		return err
+	}
+	fail := func(code byte, err error) (string, socksReply, error) {
+		conn.Write([]byte{socks5Version, code, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+		return "", nil, err
+	}
+
+	var host string
+	switch header[3] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		size := net.IPv4len
+		if header[3] == socks5AddrIPv6 {
+			size = net.IPv6len
+		}
+		ip := make(net.IP, size)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", nil, fmt.Errorf("failed to read SOCKS5 address: %w", err)
+		}
+		host = ip.String()
+	case socks5AddrDomain:
+		var length [1]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return "", nil, fmt.Errorf("failed to read SOCKS5 address: %w", err)
+		}
+		domain := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", nil, fmt.Errorf("failed to read SOCKS5 address: %w", err)
+		}
+		host = string(domain)
+	default:
+		return fail(socks5AddressUnsupported, fmt.Errorf("%w: unsupported SOCKS5 address type %d", errSOCKSRejected, header[3]))
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(conn, port[:]); err != nil {
+		return "", nil, fmt.Errorf("failed to read SOCKS5 port: %w", err)
+	}
+
+	if header[1] != socksConnect {
+		return fail(socks5CommandUnsupported, fmt.Errorf("%w: unsupported SOCKS5 command %d", errSOCKSRejected, header[1]))
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))), reply, nil
+}
+
+// socks5ReplyCode maps the result of dialing the target to a SOCKS5 reply.
+// Targets are dialed through the SSH server, whose refusals only carry the
+// reason as text.
+func socks5ReplyCode(err error) byte {
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil:
+		return socks5Succeeded
+	case errors.As(err, &dnsErr):
+		return socks5HostUnreachable
+	}
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "connection refused"):
+		return socks5ConnectionRefused
+	case strings.Contains(message, "no route to host"), strings.Contains(message, "host unreachable"),
+		strings.Contains(message, "no such host"):
+		return socks5HostUnreachable
+	case strings.Contains(message, "network is unreachable"):
+		return socks5NetworkUnreachable
+	}
+	return socks5GeneralFailure
+}
+
+// readNullTerminated reads a NUL-terminated string of at most max bytes
+func readNullTerminated(r io.Reader, max int) (string, error) {
+	var buf []byte
+	var b [1]byte
+	for {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return "", err
+		}
+		if b[0] == 0 {
+			return string(buf), nil
+		}
+		if len(buf) == max {
+			return "", fmt.Errorf("field longer than %d bytes", max)
+		}
+		buf = append(buf, b[0])
+	}
+}
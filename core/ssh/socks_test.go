@@ -0,0 +1,231 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/ssh/sshtest"
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// newTestServer starts an sshtest server closed with the test
+func newTestServer(t *testing.T) *sshtest.Server {
+	t.Helper()
+	server, err := sshtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SSH server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+// newTestClient connects a client to server, disconnected with the test
+func newTestClient(t *testing.T, server *sshtest.Server) *SSHClient {
+	t.Helper()
+	client := NewSSHClient(server.ConnectionConfig(), utils.DiscardLogger())
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect() })
+	return client
+}
+
+// startTestTunnel starts a tunnel over client, stopped with the test
+func startTestTunnel(t *testing.T, client *SSHClient, config models.TunnelConfig) *TunnelManager {
+	t.Helper()
+	tunnel := NewTunnelManager(client, config, utils.DiscardLogger())
+	if err := tunnel.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start tunnel: %v", err)
+	}
+	t.Cleanup(func() { tunnel.Stop(context.Background()) })
+	return tunnel
+}
+
+// freePort returns a loopback port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// startEchoServer starts a TCP server echoing what it reads, closed with the test
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start echo server: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// assertEcho writes a line on conn and checks it comes back
+func assertEcho(t *testing.T, conn net.Conn) {
+	t.Helper()
+	if _, err := io.WriteString(conn, "hello\n"); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read echo: %v", err)
+	}
+	if line != "hello\n" {
+		t.Fatalf("echo = %q, want %q", line, "hello\n")
+	}
+}
+
+func dynamicConfig(t *testing.T, version int) models.TunnelConfig {
+	return models.TunnelConfig{
+		Type:             models.TunnelTypeDynamic,
+		SOCKSBindAddress: "127.0.0.1",
+		SOCKSPort:        freePort(t),
+		SOCKSVersion:     version,
+	}
+}
+
+func socksAddr(config models.TunnelConfig) string {
+	return net.JoinHostPort(config.SOCKSBindAddress, strconv.Itoa(config.SOCKSPort))
+}
+
+func TestSOCKS5Connect(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	config := dynamicConfig(t, 5)
+	startTestTunnel(t, client, config)
+	target := startEchoServer(t)
+
+	dialer, err := proxy.SOCKS5("tcp", socksAddr(config), nil, proxy.Direct)
+	if err != nil {
+		t.Fatalf("failed to create SOCKS5 dialer: %v", err)
+	}
+	conn, err := dialer.Dial("tcp", target)
+	if err != nil {
+		t.Fatalf("SOCKS5 connect failed: %v", err)
+	}
+	defer conn.Close()
+	assertEcho(t, conn)
+}
+
+func TestSOCKS5ConnectRefused(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	config := dynamicConfig(t, 5)
+	startTestTunnel(t, client, config)
+
+	dialer, err := proxy.SOCKS5("tcp", socksAddr(config), nil, proxy.Direct)
+	if err != nil {
+		t.Fatalf("failed to create SOCKS5 dialer: %v", err)
+	}
+	conn, err := dialer.Dial("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(freePort(t))))
+	if err == nil {
+		conn.Close()
+		t.Fatal("SOCKS5 connect to a closed port succeeded")
+	}
+	if !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("error = %v, want connection refused", err)
+	}
+}
+
+func TestSOCKS4aConnect(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	// SOCKS5 proxies accept SOCKS4 clients, the fallback PAC files list
+	config := dynamicConfig(t, 5)
+	startTestTunnel(t, client, config)
+	target := startEchoServer(t)
+	_, portText, _ := net.SplitHostPort(target)
+	port, _ := strconv.Atoi(portText)
+
+	conn, err := net.Dial("tcp", socksAddr(config))
+	if err != nil {
+		t.Fatalf("failed to connect to proxy: %v", err)
+	}
+	defer conn.Close()
+
+	request := []byte{socks4Version, socksConnect, 0, 0, 0, 0, 0, 1}
+	binary.BigEndian.PutUint16(request[2:4], uint16(port))
+	request = append(request, "user\x00localhost\x00"...)
+	if _, err := conn.Write(request); err != nil {
+		t.Fatalf("failed to write request: %v", err)
+	}
+	var reply [8]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		t.Fatalf("failed to read reply: %v", err)
+	}
+	if reply[1] != socks4Granted {
+		t.Fatalf("reply status = %#x, want %#x", reply[1], socks4Granted)
+	}
+	assertEcho(t, conn)
+}
+
+func TestSOCKS4ProxyRefusesSOCKS5(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	config := dynamicConfig(t, 4)
+	startTestTunnel(t, client, config)
+
+	dialer, err := proxy.SOCKS5("tcp", socksAddr(config), nil, proxy.Direct)
+	if err != nil {
+		t.Fatalf("failed to create SOCKS5 dialer: %v", err)
+	}
+	if conn, err := dialer.Dial("tcp", startEchoServer(t)); err == nil {
+		conn.Close()
+		t.Fatal("SOCKS4 proxy accepted a SOCKS5 client")
+	}
+}
+
+func TestPACServedOnSOCKSPort(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	config := dynamicConfig(t, 5)
+	config.PAC = &models.PACConfig{Networks: []string{"10.0.0.0/8"}}
+	startTestTunnel(t, client, config)
+
+	response, err := http.Get("http://" + socksAddr(config) + models.PACPath)
+	if err != nil {
+		t.Fatalf("failed to fetch PAC file: %v", err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", response.StatusCode)
+	}
+	if want := "SOCKS5 " + socksAddr(config); !strings.Contains(string(body), want) {
+		t.Fatalf("PAC file does not name the proxy %q:\n%s", want, body)
+	}
+
+	// The same port still proxies
+	dialer, _ := proxy.SOCKS5("tcp", socksAddr(config), nil, proxy.Direct)
+	conn, err := dialer.Dial("tcp", startEchoServer(t))
+	if err != nil {
+		t.Fatalf("SOCKS5 connect failed: %v", err)
+	}
+	defer conn.Close()
+	assertEcho(t, conn)
+}
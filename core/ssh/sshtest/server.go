@@ -0,0 +1,419 @@
+// Package sshtest provides an in-process SSH server for exercising SSHClient,
+// TunnelManager (local, remote and dynamic forwards), reconnection and the
+// terminal without external infrastructure. The server accepts password
+// authentication, forwards TCP both ways, runs sessions whose shell echoes
+// its input back and serves SFTP on the local filesystem.
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/sftp"
+)
+
+// Credentials accepted by the server
+const (
+	User     = "portfly"
+	Password = "portfly"
+)
+
+// Server is an SSH server listening on a loopback port
+type Server struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	hostKey  ssh.Signer
+
+	mu     sync.Mutex
+	conns  map[*ssh.ServerConn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+
+	connections atomic.Int64
+}
+
+// NewServer starts a server on 127.0.0.1 with a fresh ed25519 host key
+func NewServer() (*Server, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate host key: %w", err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create host key signer: %w", err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == User && string(password) == Password {
+				return nil, nil
+			}
+			return nil, errors.New("invalid credentials")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		config:   config,
+		hostKey:  hostKey,
+		conns:    make(map[*ssh.ServerConn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the host:port the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// HostKey returns the public host key clients will see
+func (s *Server) HostKey() ssh.PublicKey {
+	return s.hostKey.PublicKey()
+}
+
+// ConnectionConfig returns a client configuration that logs in to the server
+func (s *Server) ConnectionConfig() models.SSHConnectionConfig {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return models.SSHConnectionConfig{
+		Host:             addr.IP.String(),
+		Port:             addr.Port,
+		Username:         User,
+		AuthMethod:       models.AuthMethodPassword,
+		Password:         Password,
+		HostKeyCallback:  "accept",
+		ConnectTimeout:   5 * time.Second,
+		HandshakeTimeout: 5 * time.Second,
+		KeepAliveTimeout: time.Second,
+		MaxRetries:       3,
+		RetryInterval:    100 * time.Millisecond,
+	}
+}
+
+// Connections returns the number of SSH connections accepted so far, which
+// counts reconnects
+func (s *Server) Connections() int64 {
+	return s.connections.Load()
+}
+
+// DropConnections closes every client connection as if the network failed.
+// The server keeps listening, so clients can reconnect.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// Close stops the server and closes all connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	err := s.listener.Close()
+	s.DropConnections()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn runs one SSH connection until the client or the server closes it
+func (s *Server) handleConn(netConn net.Conn) {
+	defer s.wg.Done()
+
+	conn, channels, requests, err := ssh.NewServerConn(netConn, s.config)
+	if err != nil {
+		netConn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+	s.connections.Add(1)
+
+	forwards := newRemoteForwards(conn)
+	defer func() {
+		forwards.closeAll()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	go forwards.handleRequests(requests)
+
+	for newChannel := range channels {
+		switch newChannel.ChannelType() {
+		case "session":
+			go handleSession(newChannel)
+		case "direct-tcpip":
+			go handleDirectTCPIP(newChannel)
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+// directTCPIP is the payload of a direct-tcpip channel (RFC 4254 7.2)
+type directTCPIP struct {
+	DestAddr string
+	DestPort uint32
+	OrigAddr string
+	OrigPort uint32
+}
+
+// handleDirectTCPIP connects a local forward to its target
+func handleDirectTCPIP(newChannel ssh.NewChannel) {
+	var payload directTCPIP
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, "invalid payload")
+		return
+	}
+
+	target, err := net.Dial("tcp", net.JoinHostPort(payload.DestAddr, strconv.Itoa(int(payload.DestPort))))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		target.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	pipe(channel, target)
+}
+
+// handleSession runs a session: shells echo their input and exec writes the
+// command back, both exiting with status 0
+func handleSession(newChannel ssh.NewChannel) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	for request := range requests {
+		switch request.Type {
+		case "pty-req", "env", "window-change":
+			request.Reply(true, nil)
+		case "shell":
+			request.Reply(true, nil)
+			io.Copy(channel, channel)
+			exit(channel)
+			return
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(request.Payload, &payload); err != nil {
+				request.Reply(false, nil)
+				continue
+			}
+			request.Reply(true, nil)
+			io.WriteString(channel, payload.Command+"\n")
+			exit(channel)
+			return
+		case "subsystem":
+			var payload struct{ Name string }
+			if err := ssh.Unmarshal(request.Payload, &payload); err != nil || payload.Name != "sftp" {
+				request.Reply(false, nil)
+				continue
+			}
+			request.Reply(true, nil)
+			sftp.Serve(channel)
+			exit(channel)
+			return
+		default:
+			request.Reply(false, nil)
+		}
+	}
+}
+
+// exit reports a successful exit status
+func exit(channel ssh.Channel) {
+	channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+}
+
+// remoteForwards holds the listeners opened by tcpip-forward requests of one connection
+type remoteForwards struct {
+	conn *ssh.ServerConn
+
+	mu        sync.Mutex
+	listeners map[string]net.Listener // keyed by bind address and bound port
+}
+
+func newRemoteForwards(conn *ssh.ServerConn) *remoteForwards {
+	return &remoteForwards{conn: conn, listeners: make(map[string]net.Listener)}
+}
+
+// tcpipForward is the payload of tcpip-forward and cancel-tcpip-forward (RFC 4254 7.1)
+type tcpipForward struct {
+	BindAddr string
+	BindPort uint32
+}
+
+// forwardedTCPIP is the payload of a forwarded-tcpip channel (RFC 4254 7.2)
+type forwardedTCPIP struct {
+	Addr       string
+	Port       uint32
+	OriginAddr string
+	OriginPort uint32
+}
+
+// handleRequests answers global requests; keepalives and other unknown
+// requests get a failure reply, as OpenSSH does
+func (rf *remoteForwards) handleRequests(requests <-chan *ssh.Request) {
+	for request := range requests {
+		switch request.Type {
+		case "tcpip-forward":
+			rf.forward(request)
+		case "cancel-tcpip-forward":
+			var payload tcpipForward
+			if err := ssh.Unmarshal(request.Payload, &payload); err != nil {
+				request.Reply(false, nil)
+				continue
+			}
+			request.Reply(rf.cancel(payload), nil)
+		default:
+			if request.WantReply {
+				request.Reply(false, nil)
+			}
+		}
+	}
+}
+
+// forward starts listening for a remote forward, replying with the bound
+// port when the client asked for any port
+func (rf *remoteForwards) forward(request *ssh.Request) {
+	var payload tcpipForward
+	if err := ssh.Unmarshal(request.Payload, &payload); err != nil {
+		request.Reply(false, nil)
+		return
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(payload.BindAddr, strconv.Itoa(int(payload.BindPort))))
+	if err != nil {
+		request.Reply(false, nil)
+		return
+	}
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+
+	rf.mu.Lock()
+	rf.listeners[forwardKey(tcpipForward{BindAddr: payload.BindAddr, BindPort: port})] = listener
+	rf.mu.Unlock()
+
+	var reply []byte
+	if payload.BindPort == 0 {
+		reply = ssh.Marshal(struct{ Port uint32 }{port})
+	}
+	request.Reply(true, reply)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go rf.open(conn, payload.BindAddr, port)
+		}
+	}()
+}
+
+// open carries a connection accepted on a remote forward to the client
+func (rf *remoteForwards) open(conn net.Conn, bindAddr string, port uint32) {
+	origin := conn.RemoteAddr().(*net.TCPAddr)
+	channel, requests, err := rf.conn.OpenChannel("forwarded-tcpip", ssh.Marshal(forwardedTCPIP{
+		Addr:       bindAddr,
+		Port:       port,
+		OriginAddr: origin.IP.String(),
+		OriginPort: uint32(origin.Port),
+	}))
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	pipe(channel, conn)
+}
+
+// cancel stops a remote forward, reporting whether it existed
+func (rf *remoteForwards) cancel(payload tcpipForward) bool {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	listener, exists := rf.listeners[forwardKey(payload)]
+	if exists {
+		listener.Close()
+		delete(rf.listeners, forwardKey(payload))
+	}
+	return exists
+}
+
+// closeAll stops every remote forward of the connection
+func (rf *remoteForwards) closeAll() {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	for key, listener := range rf.listeners {
+		listener.Close()
+		delete(rf.listeners, key)
+	}
+}
+
+func forwardKey(payload tcpipForward) string {
+	return net.JoinHostPort(payload.BindAddr, strconv.Itoa(int(payload.BindPort)))
+}
+
+// pipe copies between a channel and a connection until either side closes
+func pipe(channel ssh.Channel, conn net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(channel, conn)
+		channel.CloseWrite()
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, channel)
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			tcpConn.CloseWrite()
+		}
+		done <- struct{}{}
+	}()
+	<-done
+	<-done
+	channel.Close()
+	conn.Close()
+}
@@ -0,0 +1,50 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ForwardStdio bridges in and out to addr, dialed through the SSH
+// connection, like ssh -W: what is read from in is sent to addr and what
+// addr sends is written to out. When in ends, the write side is closed so the
+// remote end sees EOF. It returns when addr closes the connection or ctx is
+// done.
+func (c *SSHClient) ForwardStdio(ctx context.Context, network, addr string, in io.Reader, out io.Writer) error {
+	client := c.GetClient()
+	if client == nil {
+		return fmt.Errorf("SSH client not connected")
+	}
+
+	conn, err := client.DialContext(ctx, network, addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	go func() {
+		if _, err := io.Copy(conn, in); err != nil {
+			c.logger.Debug("stdin forwarding ended", "target", addr, "error", err)
+		}
+		if closer, ok := conn.(interface{ CloseWrite() error }); ok {
+			closer.CloseWrite()
+		}
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(out, conn)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("forwarding from %s failed: %w", addr, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
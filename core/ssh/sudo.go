@@ -0,0 +1,169 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrSudoPassword is returned when sudo asked for a password and rejected
+// the one given, or none could be had
+var ErrSudoPassword = errors.New("sudo password was rejected")
+
+// SudoPasswordFunc returns the password to type into sudo's prompt. It is
+// only called when sudo actually prompts, so NOPASSWD rules never need one.
+type SudoPasswordFunc func(ctx context.Context) (string, error)
+
+// RedactedSecret replaces secrets scrubbed from command output
+const RedactedSecret = "********"
+
+// RunSudo runs command through sudo on session, writing its output to
+// stdout and stderr. The password goes to sudo over stdin when it prompts,
+// never on the command line, and sudo's prompts are kept out of stderr. The
+// command itself gets no input.
+func RunSudo(ctx context.Context, session *ssh.Session, command string, password SudoPasswordFunc, stdout, stderr io.Writer) error {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return fmt.Errorf("failed to generate sudo prompt: %w", err)
+	}
+	marker := hex.EncodeToString(token)
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+	filter := &sudoFilter{
+		ctx:      ctx,
+		prompt:   "[portfly-sudo-" + marker + "]",
+		ready:    "[portfly-ready-" + marker + "]\n",
+		stdin:    stdin,
+		password: password,
+		out:      stderr,
+	}
+	session.Stdout = stdout
+	session.Stderr = filter
+
+	// -k makes sudo prompt even with cached credentials, so the password is
+	// only written when sudo reads it; the ready marker shows sudo is done
+	// reading and stdin can be closed
+	script := fmt.Sprintf("printf '%%s\\n' %s >&2; %s", quoteShell(strings.TrimSuffix(filter.ready, "\n")), command)
+	err = session.Run(fmt.Sprintf("sudo -S -k -p %s -- sh -c %s", quoteShell(filter.prompt), quoteShell(script)))
+	filter.finish()
+
+	if filter.err != nil {
+		return filter.err
+	}
+	if err != nil && filter.prompts > 1 {
+		return fmt.Errorf("%w: %w", ErrSudoPassword, err)
+	}
+	return err
+}
+
+// ScrubSecret replaces every occurrence of secret in text
+func ScrubSecret(text, secret string) string {
+	if secret == "" {
+		return text
+	}
+	return strings.ReplaceAll(text, secret, RedactedSecret)
+}
+
+// quoteShell quotes s as one POSIX shell word
+func quoteShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sudoFilter sits on the stderr of a sudo command. Until the command starts
+// it holds back stderr, answering sudo's first prompt with the password and
+// giving up on a second one; then it drops the prompts and the ready marker
+// and passes everything through.
+type sudoFilter struct {
+	ctx      context.Context
+	prompt   string
+	ready    string
+	stdin    io.WriteCloser
+	password SudoPasswordFunc
+	out      io.Writer
+
+	mu      sync.Mutex
+	held    bytes.Buffer
+	started bool
+	closed  bool
+	prompts int
+	err     error // the password could not be had
+}
+
+func (f *sudoFilter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.started {
+		return f.out.Write(p)
+	}
+
+	f.held.Write(p)
+	if prompts := strings.Count(f.held.String(), f.prompt); prompts > f.prompts {
+		f.prompts = prompts
+		f.answer()
+	}
+	if i := strings.Index(f.held.String(), f.ready); i >= 0 {
+		f.started = true
+		f.closeStdin()
+		rest := f.held.String()
+		f.held.Reset()
+		if _, err := io.WriteString(f.out, f.clean(rest[:i])+rest[i+len(f.ready):]); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// answer types the password into sudo's first prompt; later prompts mean it
+// was wrong, and closing stdin makes sudo give up
+func (f *sudoFilter) answer() {
+	if f.prompts > 1 || f.closed {
+		f.closeStdin()
+		return
+	}
+	password, err := f.password(f.ctx)
+	if err != nil {
+		f.err = fmt.Errorf("%w: %w", ErrSudoPassword, err)
+		f.closeStdin()
+		return
+	}
+	if _, err := io.WriteString(f.stdin, password+"\n"); err != nil {
+		f.closeStdin()
+	}
+}
+
+func (f *sudoFilter) closeStdin() {
+	if !f.closed {
+		f.closed = true
+		f.stdin.Close()
+	}
+}
+
+// clean removes sudo's prompts, and the line break some versions write after
+// reading the password, from the text sudo wrote
+func (f *sudoFilter) clean(text string) string {
+	text = strings.ReplaceAll(text, f.prompt+"\n", "")
+	return strings.ReplaceAll(text, f.prompt, "")
+}
+
+// finish passes on what sudo wrote when the command never started, such as
+// why it refused
+func (f *sudoFilter) finish() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closeStdin()
+	if !f.started && f.held.Len() > 0 {
+		io.WriteString(f.out, f.clean(f.held.String()))
+		f.held.Reset()
+	}
+}
@@ -0,0 +1,47 @@
+package ssh
+
+import (
+	"io"
+	"net"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// connectionTap observes the bytes read from each side of a forwarded
+// connection, such as a debug capture or the HTTP inspector. observe is
+// called from the copy goroutine of its direction and must not block the
+// transfer; close is called once both directions are done.
+type connectionTap interface {
+	observe(direction models.CaptureDirection, data []byte)
+	close()
+}
+
+// tapReader passes every read of the wrapped reader to the taps
+type tapReader struct {
+	reader    io.Reader
+	taps      []connectionTap
+	direction models.CaptureDirection
+}
+
+func (r tapReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		for _, tap := range r.taps {
+			tap.observe(r.direction, p[:n])
+		}
+	}
+	return n, err
+}
+
+// copyTapped copies src to dst through a user-space buffer so every read
+// can be observed, honouring the bandwidth limiter if any
+func copyTapped(dst, src net.Conn, size int, limiter *BandwidthLimiter, taps []connectionTap, direction models.CaptureDirection) (int64, error) {
+	var reader io.Reader = readerOnly{src}
+	if limiter != nil {
+		reader = limitedReader{reader: src, limiter: limiter}
+	}
+
+	buf := getBuffer(size)
+	defer putBuffer(buf)
+	return io.CopyBuffer(writerOnly{dst}, tapReader{reader: reader, taps: taps, direction: direction}, *buf)
+}
@@ -0,0 +1,98 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ErrTransparentUnsupported is returned when redirected connections cannot be
+// traced back to their original destination on this platform
+var ErrTransparentUnsupported = errors.New("transparent proxying is not supported on this platform")
+
+// startTransparentProxy listens on loopback for the connections the firewall
+// redirects to a dynamic tunnel
+func (tm *TunnelManager) startTransparentProxy(ctx, serveCtx context.Context, config models.TunnelConfig, bind models.BindSpec) (net.Listener, error) {
+	if !transparentSupported {
+		return nil, ErrTransparentUnsupported
+	}
+
+	localAddr := bind.String()
+	lc := net.ListenConfig{Control: listenControl(config.SocketOptions)}
+	listener, err := lc.Listen(ctx, "tcp", localAddr)
+	if err != nil {
+		return nil, bindError(ctx, config, localAddr, err)
+	}
+
+	tm.wg.Add(1)
+	go tm.serve(listener, localAddr, "transparent", func(conn net.Conn) {
+		tm.handleTransparentConnection(serveCtx, conn, localAddr)
+	})
+
+	tm.logger.Info("transparent proxy started",
+		"bind_addr", localAddr,
+		"destinations", config.Transparent.Destinations)
+
+	return listener, nil
+}
+
+// handleTransparentConnection forwards a redirected connection to the
+// destination it was originally addressed to
+func (tm *TunnelManager) handleTransparentConnection(ctx context.Context, conn net.Conn, listenerAddr string) {
+	defer tm.wg.Done()
+	defer conn.Close()
+
+	// Track connection
+	tm.connections.Store(conn, true)
+	defer tm.connections.Delete(conn)
+
+	// Update statistics
+	defer tm.trackConnection(listenerAddr)()
+	tm.tuneConnection(conn, tm.Config())
+
+	targetAddr, err := originalDestination(conn)
+	if err == nil && targetAddr == conn.LocalAddr().String() {
+		// Connected to the listener directly; dialing it would loop
+		err = fmt.Errorf("connection was not redirected")
+	}
+	if err != nil {
+		tm.logger.Error("failed to find original destination",
+			"client_addr", conn.RemoteAddr(),
+			"error", err)
+		tm.updateStats(func(stats *models.SessionStats) {
+			stats.FailedConnections++
+		})
+		return
+	}
+
+	dial, err := tm.forwardDial()
+	if err != nil {
+		tm.logger.Error("forward transport not available", "error", err)
+		tm.updateStats(func(stats *models.SessionStats) {
+			stats.FailedConnections++
+		})
+		return
+	}
+
+	targetConn, err := dial("tcp", targetAddr)
+	if err != nil {
+		tm.logger.Error("failed to connect to target",
+			"target_addr", targetAddr,
+			"error", err)
+		tm.updateStats(func(stats *models.SessionStats) {
+			stats.FailedConnections++
+		})
+		return
+	}
+	defer targetConn.Close()
+
+	tm.logger.Debug("established transparent connection",
+		"client_addr", conn.RemoteAddr(),
+		"target_addr", targetAddr)
+
+	// Start bidirectional data transfer
+	tm.transfer(conn, targetConn, listenerAddr)
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const transparentSupported = true
+
+// ip6tSOOriginalDst is IP6T_SO_ORIGINAL_DST from linux/netfilter_ipv6/ip6_tables.h
+const ip6tSOOriginalDst = 80
+
+// originalDestination returns the address a connection redirected by an
+// iptables REDIRECT rule was sent to, from conntrack via SO_ORIGINAL_DST
+func originalDestination(conn net.Conn) (string, error) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return "", fmt.Errorf("not a TCP connection")
+	}
+	raw, err := tcpConn.SyscallConn()
+	if err != nil {
+		return "", err
+	}
+
+	ipv6 := false
+	if local, ok := conn.LocalAddr().(*net.TCPAddr); ok && local.IP.To4() == nil {
+		ipv6 = true
+	}
+
+	var addr netip.AddrPort
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		if ipv6 {
+			// struct sockaddr_in6, read through a large enough option type
+			var info *unix.IPv6MTUInfo
+			info, sockErr = unix.GetsockoptIPv6MTUInfo(int(fd), unix.SOL_IPV6, ip6tSOOriginalDst)
+			if sockErr == nil {
+				port := (*[2]byte)(unsafe.Pointer(&info.Addr.Port))
+				addr = netip.AddrPortFrom(netip.AddrFrom16(info.Addr.Addr), uint16(port[0])<<8|uint16(port[1]))
+			}
+			return
+		}
+		// struct sockaddr_in: family, port and address in network byte order
+		var mreq *unix.IPv6Mreq
+		mreq, sockErr = unix.GetsockoptIPv6Mreq(int(fd), unix.SOL_IP, unix.SO_ORIGINAL_DST)
+		if sockErr == nil {
+			sin := mreq.Multiaddr
+			addr = netip.AddrPortFrom(netip.AddrFrom4([4]byte(sin[4:8])), uint16(sin[2])<<8|uint16(sin[3]))
+		}
+	}); err != nil {
+		return "", err
+	}
+	if sockErr != nil {
+		return "", fmt.Errorf("SO_ORIGINAL_DST: %w", sockErr)
+	}
+	return net.JoinHostPort(addr.Addr().String(), strconv.Itoa(int(addr.Port()))), nil
+}
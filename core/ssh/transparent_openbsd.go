@@ -0,0 +1,13 @@
+//go:build openbsd
+
+package ssh
+
+import "net"
+
+const transparentSupported = true
+
+// originalDestination returns the address a connection diverted by a pf
+// divert-to rule was sent to, which pf keeps as the local address
+func originalDestination(conn net.Conn) (string, error) {
+	return conn.LocalAddr().String(), nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !openbsd
+
+package ssh
+
+import "net"
+
+const transparentSupported = false
+
+func originalDestination(conn net.Conn) (string, error) {
+	return "", ErrTransparentUnsupported
+}
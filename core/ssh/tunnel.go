@@ -1,34 +1,66 @@
 package ssh
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/crypto/ssh"
+
 	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/telemetry"
 	"github.com/aqz236/port-fly/core/utils"
 )
 
+// ErrRemoteBindRefused is returned when the SSH server rejects a remote forward bind
+var ErrRemoteBindRefused = errors.New("server refused remote forward")
+
 // TunnelManager manages SSH tunnels
 type TunnelManager struct {
 	sshClient *SSHClient
 	config    models.TunnelConfig
+	configMu  sync.RWMutex
+	updateMu  sync.Mutex // serializes UpdateConfig
 	logger    utils.Logger
 
 	// State management
 	running     int32
 	listeners   []net.Listener
+	listenersMu sync.Mutex
 	connections sync.Map // map[net.Conn]bool
-	stopChan    chan struct{}
 	wg          sync.WaitGroup
 
 	// Statistics
-	stats   models.SessionStats
-	statsMu sync.RWMutex
+	stats         models.SessionStats
+	listenerStats map[string]*models.ListenerStats
+	statsMu       sync.RWMutex
+
+	// Optional throughput limit, possibly shared with other tunnels
+	limiter atomic.Pointer[BandwidthLimiter]
+
+	// Optional debug capture of forwarded bytes
+	capture atomic.Pointer[Capture]
+
+	// Recent requests seen when the tunnel inspects HTTP
+	httpLog *HTTPLog
+
+	// Spreads connections over the targets of balanced tunnels
+	balancer *targetBalancer
+
+	// Cached target addresses of remote forwards that re-resolve them
+	resolver    *targetResolver
+	stopRefresh context.CancelFunc
+
+	// Reaches forward targets over WireGuard instead of SSH when set
+	wireguard *WireGuardLink
 }
 
 // NewTunnelManager creates a new tunnel manager
@@ -37,71 +69,96 @@ func NewTunnelManager(sshClient *SSHClient, config models.TunnelConfig, logger u
 		sshClient: sshClient,
 		config:    config,
 		logger:    logger,
-		stopChan:  make(chan struct{}),
+
+		listenerStats: make(map[string]*models.ListenerStats),
+		httpLog:       NewHTTPLog(models.HTTPLogSize),
+		balancer:      newTargetBalancer(),
+		resolver:      newTargetResolver(),
 	}
 }
 
 // Start starts the tunnel based on its type
-func (tm *TunnelManager) Start(ctx context.Context) error {
+func (tm *TunnelManager) Start(ctx context.Context) (err error) {
+	config := tm.Config()
+	ctx, span := telemetry.StartSpan(ctx, tracer, "tunnel.start",
+		attribute.String("tunnel.type", string(config.Type)),
+		attribute.String("tunnel.description", config.GetTunnelDescription()))
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	if !atomic.CompareAndSwapInt32(&tm.running, 0, 1) {
 		return fmt.Errorf("tunnel is already running")
 	}
 
 	// Validate tunnel configuration
-	if err := tm.config.Validate(); err != nil {
+	if err := config.Validate(); err != nil {
 		atomic.StoreInt32(&tm.running, 0)
 		return fmt.Errorf("invalid tunnel configuration: %w", err)
 	}
 
-	// Ensure SSH connection is established
-	if !tm.sshClient.IsConnected() {
+	// Ensure the transport is up
+	if tm.wireguard != nil {
+		if err := tm.wireguard.Check(); err != nil {
+			atomic.StoreInt32(&tm.running, 0)
+			return err
+		}
+	} else if !tm.sshClient.IsConnected() {
 		if err := tm.sshClient.Connect(ctx); err != nil {
 			atomic.StoreInt32(&tm.running, 0)
 			return fmt.Errorf("failed to establish SSH connection: %w", err)
 		}
 	}
 
-	var err error
-	switch tm.config.Type {
-	case models.TunnelTypeLocal:
-		err = tm.startLocalForwarding(ctx)
-	case models.TunnelTypeRemote:
-		err = tm.startRemoteForwarding(ctx)
-	case models.TunnelTypeDynamic:
-		err = tm.startDynamicForwarding(ctx)
-	default:
-		err = fmt.Errorf("unsupported tunnel type: %s", tm.config.Type)
-	}
-
+	listeners, err := tm.listen(ctx, config)
 	if err != nil {
 		atomic.StoreInt32(&tm.running, 0)
 		return err
 	}
 
+	tm.listenersMu.Lock()
+	tm.listeners = append(tm.listeners, listeners...)
+	tm.listenersMu.Unlock()
+
+	// Remote forwards dial their targets here, so only they re-resolve them
+	if config.Type == models.TunnelTypeRemote {
+		refreshCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		tm.stopRefresh = cancel
+		tm.wg.Add(1)
+		go tm.runTargetRefresh(refreshCtx)
+	}
+
 	tm.logger.Info("tunnel started successfully",
-		"type", tm.config.Type,
-		"description", tm.config.GetTunnelDescription())
+		"type", config.Type,
+		"description", config.GetTunnelDescription())
 
 	return nil
 }
 
-// Stop stops the tunnel
-func (tm *TunnelManager) Stop() error {
+// Stop stops the tunnel; ctx parents its trace span
+func (tm *TunnelManager) Stop(ctx context.Context) error {
 	if !atomic.CompareAndSwapInt32(&tm.running, 1, 0) {
 		return fmt.Errorf("tunnel is not running")
 	}
 
-	tm.logger.Info("stopping tunnel")
+	_, span := telemetry.StartSpan(ctx, tracer, "tunnel.stop",
+		attribute.String("tunnel.type", string(tm.Config().Type)))
+	defer span.End()
 
-	// Signal stop
-	close(tm.stopChan)
+	tm.logger.Info("stopping tunnel")
 
-	// Close all listeners
+	// Closing the listeners ends their accept loops
+	tm.listenersMu.Lock()
 	for _, listener := range tm.listeners {
 		if err := listener.Close(); err != nil {
 			tm.logger.Warn("error closing listener", "error", err)
 		}
 	}
+	tm.listeners = nil
+	tm.listenersMu.Unlock()
+
+	if tm.stopRefresh != nil {
+		tm.stopRefresh()
+		tm.stopRefresh = nil
+	}
 
 	// Close all active connections
 	tm.connections.Range(func(key, value interface{}) bool {
@@ -123,73 +180,251 @@ func (tm *TunnelManager) IsRunning() bool {
 	return atomic.LoadInt32(&tm.running) == 1
 }
 
-// GetStats returns tunnel statistics
-func (tm *TunnelManager) GetStats() models.SessionStats {
-	tm.statsMu.RLock()
-	defer tm.statsMu.RUnlock()
-	return tm.stats
+// Config returns the current tunnel configuration
+func (tm *TunnelManager) Config() models.TunnelConfig {
+	tm.configMu.RLock()
+	defer tm.configMu.RUnlock()
+	return tm.config
 }
 
-// startLocalForwarding starts local port forwarding (-L)
-func (tm *TunnelManager) startLocalForwarding(ctx context.Context) error {
-	bindAddr := tm.config.LocalBindAddress
-	if bindAddr == "" {
-		bindAddr = "127.0.0.1"
+// UpdateConfig applies a new configuration to the tunnel. Changes that do not
+// affect the listener (target, timeouts, buffers) take effect for new
+// connections without interrupting existing ones. When the listener changes,
+// the new one is started before the old one is closed, and connections
+// accepted by the old listener drain in the background.
+func (tm *TunnelManager) UpdateConfig(ctx context.Context, config models.TunnelConfig) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid tunnel configuration: %w", err)
 	}
 
-	localAddr := fmt.Sprintf("%s:%d", bindAddr, tm.config.LocalPort)
-	listener, err := net.Listen("tcp", localAddr)
+	tm.updateMu.Lock()
+	defer tm.updateMu.Unlock()
+
+	previous := tm.Config()
+
+	if !tm.IsRunning() || !previous.ListenerChanged(config) {
+		tm.configMu.Lock()
+		tm.config = config
+		tm.configMu.Unlock()
+		tm.logger.Info("tunnel configuration updated", "description", config.GetTunnelDescription())
+		return nil
+	}
+
+	// Bring up the new listener first so the port never stops accepting
+	listeners, err := tm.listen(ctx, config)
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", localAddr, err)
+		return fmt.Errorf("failed to start new listener: %w", err)
 	}
 
-	tm.listeners = append(tm.listeners, listener)
+	tm.configMu.Lock()
+	tm.config = config
+	tm.configMu.Unlock()
 
-	tm.wg.Add(1)
-	go tm.handleLocalConnections(ctx, listener)
+	tm.listenersMu.Lock()
+	old := tm.listeners
+	tm.listeners = listeners
+	tm.listenersMu.Unlock()
 
-	tm.logger.Info("local forwarding started",
-		"local_addr", localAddr,
-		"remote_addr", fmt.Sprintf("%s:%d", tm.config.RemoteHost, tm.config.RemotePort))
+	// Closing the old listeners only stops accepting; active connections keep running
+	for _, l := range old {
+		if err := l.Close(); err != nil {
+			tm.logger.Warn("error closing previous listener", "error", err)
+		}
+	}
+
+	tm.logger.Info("tunnel listener replaced",
+		"previous", previous.GetTunnelDescription(),
+		"description", config.GetTunnelDescription())
 
 	return nil
 }
 
-// handleLocalConnections handles incoming connections for local forwarding
-func (tm *TunnelManager) handleLocalConnections(ctx context.Context, listener net.Listener) {
-	defer tm.wg.Done()
-
-	for {
-		select {
-		case <-tm.stopChan:
-			return
+// listen starts a listener for each bind of the configured tunnel type and
+// their accept loops. Each bind is bounded by ctx and the configured bind
+// timeout; the accept loops keep running after ctx is done. If any bind fails
+// the listeners already started are closed.
+func (tm *TunnelManager) listen(ctx context.Context, config models.TunnelConfig) ([]net.Listener, error) {
+	serveCtx := context.WithoutCancel(ctx)
+
+	var listeners []net.Listener
+	for _, bind := range config.Binds() {
+		bindCtx, cancel := bindContext(ctx, config)
+		var listener net.Listener
+		var err error
+
+		switch config.Type {
+		case models.TunnelTypeLocal:
+			listener, err = tm.startLocalForwarding(bindCtx, serveCtx, config, bind)
+		case models.TunnelTypeRemote:
+			listener, err = tm.startRemoteForwarding(bindCtx, serveCtx, config, bind)
+		case models.TunnelTypeDynamic:
+			listener, err = tm.startDynamicForwarding(bindCtx, serveCtx, config, bind)
 		default:
+			err = fmt.Errorf("unsupported tunnel type: %s", config.Type)
 		}
+		cancel()
 
-		// Set accept timeout to allow checking stop signal
-		if tcpListener, ok := listener.(*net.TCPListener); ok {
-			tcpListener.SetDeadline(time.Now().Add(1 * time.Second))
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
 		}
+		listeners = append(listeners, listener)
+	}
+
+	for _, bind := range config.TransparentBinds() {
+		bindCtx, cancel := bindContext(ctx, config)
+		listener, err := tm.startTransparentProxy(bindCtx, serveCtx, config, bind)
+		cancel()
 
-		conn, err := listener.Accept()
 		if err != nil {
-			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				continue // Timeout, check stop signal and continue
+			for _, l := range listeners {
+				l.Close()
 			}
-			if atomic.LoadInt32(&tm.running) == 0 {
-				return // Tunnel is stopping
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// Bounds of the wait after a failed accept, e.g. when out of file descriptors
+const (
+	minAcceptBackoff = 5 * time.Millisecond
+	maxAcceptBackoff = time.Second
+)
+
+// serve accepts connections on listener and handles each on its own
+// goroutine until the listener is closed. Closing the listener is how Stop
+// and listener replacement end the loop, for every listener type alike: TCP,
+// unix sockets, sockets passed by systemd and listeners on the SSH server.
+func (tm *TunnelManager) serve(listener net.Listener, listenerAddr, kind string, handle func(net.Conn)) {
+	defer tm.wg.Done()
+
+	var backoff time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if isListenerClosed(err) || !tm.IsRunning() {
+				return
 			}
-			tm.logger.Error("failed to accept connection", "error", err)
+			backoff = min(max(2*backoff, minAcceptBackoff), maxAcceptBackoff)
+			tm.logger.Error("failed to accept connection",
+				"kind", kind,
+				"listener", listenerAddr,
+				"retry_in", backoff,
+				"error", err)
+			time.Sleep(backoff)
 			continue
 		}
+		backoff = 0
 
 		tm.wg.Add(1)
-		go tm.handleLocalConnection(ctx, conn)
+		go handle(conn)
+	}
+}
+
+// isListenerClosed reports whether an accept error means the listener was closed
+func isListenerClosed(err error) bool {
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF)
+}
+
+// GetStats returns tunnel statistics
+func (tm *TunnelManager) GetStats() models.SessionStats {
+	tm.statsMu.RLock()
+	defer tm.statsMu.RUnlock()
+
+	stats := tm.stats
+	stats.HTTP = tm.stats.HTTP.Clone()
+	stats.Listeners = make([]models.ListenerStats, 0, len(tm.listenerStats))
+	for _, listener := range tm.listenerStats {
+		stats.Listeners = append(stats.Listeners, *listener)
+	}
+	sort.Slice(stats.Listeners, func(i, j int) bool {
+		return stats.Listeners[i].Address < stats.Listeners[j].Address
+	})
+
+	config := tm.Config()
+	if config.Balanced() {
+		for offset := range config.PortCount() {
+			stats.Targets = append(stats.Targets, tm.balancer.stats(config.AtOffset(offset).TargetAddresses())...)
+		}
+	}
+	for _, r := range config.PortRanges() {
+		total := models.ListenerStats{Address: r.String()}
+		for _, listener := range stats.Listeners {
+			if r.Contains(listener.Address) {
+				total.TotalConnections += listener.TotalConnections
+				total.ActiveConnections += listener.ActiveConnections
+				total.BytesSent += listener.BytesSent
+				total.BytesReceived += listener.BytesReceived
+			}
+		}
+		stats.Ranges = append(stats.Ranges, total)
+	}
+	return stats
+}
+
+// bindContext bounds a single bind by the configured bind timeout
+func bindContext(ctx context.Context, config models.TunnelConfig) (context.Context, context.CancelFunc) {
+	if config.BindTimeout > 0 {
+		return context.WithTimeout(ctx, config.BindTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// bindError describes a failed bind, naming the timeout when it expired
+func bindError(ctx context.Context, config models.TunnelConfig, addr string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && config.BindTimeout > 0 {
+		return fmt.Errorf("timed out after %s binding %s: %w", config.BindTimeout, addr, ctx.Err())
+	}
+	return fmt.Errorf("failed to listen on %s: %w", addr, err)
+}
+
+// bindLocal opens the local listener for bind, taking it from the sockets
+// passed by systemd when the tunnel uses socket activation
+func bindLocal(ctx context.Context, config models.TunnelConfig, bind models.BindSpec) (net.Listener, error) {
+	localAddr := bind.ListenAddress()
+	if config.SocketActivation {
+		return ActivatedListener(localAddr)
+	}
+
+	lc := net.ListenConfig{Control: listenControl(config.SocketOptions)}
+	listener, err := lc.Listen(ctx, config.Network(), localAddr)
+	if err != nil {
+		if privilegedErr := privilegedBindError(localAddr, err); privilegedErr != nil {
+			return nil, privilegedErr
+		}
+		return nil, bindError(ctx, config, localAddr, err)
+	}
+	return listener, nil
+}
+
+// startLocalForwarding starts local port forwarding (-L)
+func (tm *TunnelManager) startLocalForwarding(ctx, serveCtx context.Context, config models.TunnelConfig, bind models.BindSpec) (net.Listener, error) {
+	localAddr := bind.ListenAddress()
+	listener, err := bindLocal(ctx, config, bind)
+	if err != nil {
+		return nil, err
 	}
+
+	offset := config.PortOffset(bind)
+	tm.wg.Add(1)
+	go tm.serve(listener, bind.String(), "local", func(conn net.Conn) {
+		tm.handleLocalConnection(serveCtx, conn, bind.String(), offset)
+	})
+
+	tm.logger.Info("local forwarding started",
+		"local_addr", localAddr,
+		"remote_addr", config.AtOffset(offset).TargetAddress())
+
+	return listener, nil
 }
 
-// handleLocalConnection handles a single local connection
-func (tm *TunnelManager) handleLocalConnection(ctx context.Context, localConn net.Conn) {
+// handleLocalConnection handles a single local connection; offset is the
+// listener's position within the tunnel's port range
+func (tm *TunnelManager) handleLocalConnection(ctx context.Context, localConn net.Conn, listenerAddr string, offset int) {
 	defer tm.wg.Done()
 	defer localConn.Close()
 
@@ -198,35 +433,31 @@ func (tm *TunnelManager) handleLocalConnection(ctx context.Context, localConn ne
 	defer tm.connections.Delete(localConn)
 
 	// Update statistics
-	tm.updateStats(func(stats *models.SessionStats) {
-		stats.TotalConnections++
-		stats.ActiveConnections++
-	})
-	defer tm.updateStats(func(stats *models.SessionStats) {
-		stats.ActiveConnections--
-	})
+	defer tm.trackConnection(listenerAddr)()
 
-	// Establish SSH connection to remote host
-	remoteAddr := fmt.Sprintf("%s:%d", tm.config.RemoteHost, tm.config.RemotePort)
-	sshClient := tm.sshClient.GetClient()
-	if sshClient == nil {
-		tm.logger.Error("SSH client not available")
+	// Reach the remote host through the tunnel's transport
+	config := tm.Config().AtOffset(offset)
+	tm.tuneConnection(localConn, config)
+	dial, err := tm.forwardDial()
+	if err != nil {
+		tm.logger.Error("forward transport not available", "error", err)
 		tm.updateStats(func(stats *models.SessionStats) {
 			stats.FailedConnections++
 		})
 		return
 	}
 
-	remoteConn, err := sshClient.Dial("tcp", remoteAddr)
+	remoteConn, remoteAddr, release, err := tm.dialTarget(config, dial)
 	if err != nil {
 		tm.logger.Error("failed to connect to remote host",
-			"remote_addr", remoteAddr,
+			"remote_addr", config.TargetAddress(),
 			"error", err)
 		tm.updateStats(func(stats *models.SessionStats) {
 			stats.FailedConnections++
 		})
 		return
 	}
+	defer release()
 	defer remoteConn.Close()
 
 	tm.logger.Debug("established connection",
@@ -234,66 +465,85 @@ func (tm *TunnelManager) handleLocalConnection(ctx context.Context, localConn ne
 		"remote_addr", remoteAddr)
 
 	// Start bidirectional data transfer
-	tm.transfer(localConn, remoteConn)
+	tm.transfer(localConn, remoteConn, listenerAddr)
 }
 
 // startRemoteForwarding starts remote port forwarding (-R)
-func (tm *TunnelManager) startRemoteForwarding(ctx context.Context) error {
-	bindAddr := tm.config.RemoteBindAddress
-	if bindAddr == "" {
-		bindAddr = "127.0.0.1"
-	}
+func (tm *TunnelManager) startRemoteForwarding(ctx, serveCtx context.Context, config models.TunnelConfig, bind models.BindSpec) (net.Listener, error) {
+	offset := config.PortOffset(bind)
 
-	remoteAddr := fmt.Sprintf("%s:%d", bindAddr, tm.config.LocalPort)
+	// The SSH client cannot request an empty bind address; "*" means all IPv4 interfaces
+	if bind.Address == "*" {
+		bind.Address = "0.0.0.0"
+	}
+	remoteAddr := bind.String()
+	if tm.wireguard != nil {
+		return nil, models.ErrRemoteForwardOverWireGuard
+	}
 	sshClient := tm.sshClient.GetClient()
 	if sshClient == nil {
-		return fmt.Errorf("SSH client not available")
+		return nil, fmt.Errorf("SSH client not available")
 	}
 
-	listener, err := sshClient.Listen("tcp", remoteAddr)
+	listener, err := listenRemote(ctx, sshClient, config.Network(), remoteAddr)
 	if err != nil {
-		return fmt.Errorf("failed to listen on remote %s: %w", remoteAddr, err)
+		if ctx.Err() != nil {
+			return nil, bindError(ctx, config, remoteAddr, err)
+		}
+		if !models.IsLoopbackAddress(bind.Address) {
+			return nil, fmt.Errorf("%w: %s (non-loopback binds need GatewayPorts yes or clientspecified in the server's sshd_config): %v",
+				ErrRemoteBindRefused, remoteAddr, err)
+		}
+		return nil, fmt.Errorf("%w: %s: %v", ErrRemoteBindRefused, remoteAddr, err)
 	}
 
-	tm.listeners = append(tm.listeners, listener)
+	if !models.IsLoopbackAddress(bind.Address) {
+		tm.logger.Warn("remote forward binds a non-loopback address; servers with GatewayPorts no silently bind loopback instead",
+			"remote_addr", remoteAddr)
+	}
 
 	tm.wg.Add(1)
-	go tm.handleRemoteConnections(ctx, listener)
+	go tm.serve(listener, bind.String(), "remote", func(conn net.Conn) {
+		tm.handleRemoteConnection(serveCtx, conn, bind.String(), offset)
+	})
 
 	tm.logger.Info("remote forwarding started",
 		"remote_addr", remoteAddr,
-		"local_addr", fmt.Sprintf("%s:%d", tm.config.RemoteHost, tm.config.RemotePort))
+		"local_addr", config.AtOffset(offset).TargetAddress())
 
-	return nil
+	return listener, nil
 }
 
-// handleRemoteConnections handles incoming connections for remote forwarding
-func (tm *TunnelManager) handleRemoteConnections(ctx context.Context, listener net.Listener) {
-	defer tm.wg.Done()
-
-	for {
-		select {
-		case <-tm.stopChan:
-			return
-		default:
-		}
+// listenRemote requests a remote forward, giving up when ctx is done. The
+// request itself cannot be cancelled, so a listener granted after ctx is done
+// is closed again.
+func listenRemote(ctx context.Context, client *ssh.Client, network, addr string) (net.Listener, error) {
+	type result struct {
+		listener net.Listener
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		listener, err := client.Listen(network, addr)
+		done <- result{listener, err}
+	}()
 
-		conn, err := listener.Accept()
-		if err != nil {
-			if atomic.LoadInt32(&tm.running) == 0 {
-				return // Tunnel is stopping
+	select {
+	case r := <-done:
+		return r.listener, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.listener != nil {
+				r.listener.Close()
 			}
-			tm.logger.Error("failed to accept remote connection", "error", err)
-			continue
-		}
-
-		tm.wg.Add(1)
-		go tm.handleRemoteConnection(ctx, conn)
+		}()
+		return nil, ctx.Err()
 	}
 }
 
-// handleRemoteConnection handles a single remote connection
-func (tm *TunnelManager) handleRemoteConnection(ctx context.Context, remoteConn net.Conn) {
+// handleRemoteConnection handles a single remote connection; offset is the
+// listener's position within the tunnel's port range
+func (tm *TunnelManager) handleRemoteConnection(ctx context.Context, remoteConn net.Conn, listenerAddr string, offset int) {
 	defer tm.wg.Done()
 	defer remoteConn.Close()
 
@@ -302,96 +552,58 @@ func (tm *TunnelManager) handleRemoteConnection(ctx context.Context, remoteConn
 	defer tm.connections.Delete(remoteConn)
 
 	// Update statistics
-	tm.updateStats(func(stats *models.SessionStats) {
-		stats.TotalConnections++
-		stats.ActiveConnections++
-	})
-	defer tm.updateStats(func(stats *models.SessionStats) {
-		stats.ActiveConnections--
-	})
+	defer tm.trackConnection(listenerAddr)()
 
 	// Connect to local target
-	localAddr := net.JoinHostPort(tm.config.RemoteHost, fmt.Sprintf("%d", tm.config.RemotePort))
-	localConn, err := net.Dial("tcp", localAddr)
+	config := tm.Config().AtOffset(offset)
+	localConn, localAddr, release, err := tm.dialTarget(config, tm.targetDial(config))
 	if err != nil {
 		tm.logger.Error("failed to connect to local target",
-			"local_addr", localAddr,
+			"local_addr", config.TargetAddress(),
 			"error", err)
 		tm.updateStats(func(stats *models.SessionStats) {
 			stats.FailedConnections++
 		})
 		return
 	}
+	defer release()
 	defer localConn.Close()
+	defer tm.resolver.forget(localConn)
+	tm.tuneConnection(localConn, config)
 
 	tm.logger.Debug("established remote connection",
 		"remote_addr", remoteConn.RemoteAddr(),
 		"local_addr", localAddr)
 
 	// Start bidirectional data transfer
-	tm.transfer(remoteConn, localConn)
+	tm.transfer(remoteConn, localConn, listenerAddr)
 }
 
 // startDynamicForwarding starts dynamic port forwarding (SOCKS proxy)
-func (tm *TunnelManager) startDynamicForwarding(ctx context.Context) error {
-	bindAddr := tm.config.SOCKSBindAddress
-	if bindAddr == "" {
-		bindAddr = "127.0.0.1"
-	}
-
-	localAddr := fmt.Sprintf("%s:%d", bindAddr, tm.config.SOCKSPort)
-	listener, err := net.Listen("tcp", localAddr)
+func (tm *TunnelManager) startDynamicForwarding(ctx, serveCtx context.Context, config models.TunnelConfig, bind models.BindSpec) (net.Listener, error) {
+	localAddr := bind.ListenAddress()
+	listener, err := bindLocal(ctx, config, bind)
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", localAddr, err)
+		return nil, err
 	}
 
-	tm.listeners = append(tm.listeners, listener)
-
 	tm.wg.Add(1)
-	go tm.handleSOCKSConnections(ctx, listener)
+	go tm.serve(listener, bind.String(), "SOCKS", func(conn net.Conn) {
+		tm.handleSOCKSConnection(serveCtx, conn, bind.String())
+	})
 
 	tm.logger.Info("SOCKS proxy started",
 		"bind_addr", localAddr,
-		"version", tm.config.SOCKSVersion)
-
-	return nil
-}
-
-// handleSOCKSConnections handles incoming SOCKS connections
-func (tm *TunnelManager) handleSOCKSConnections(ctx context.Context, listener net.Listener) {
-	defer tm.wg.Done()
-
-	for {
-		select {
-		case <-tm.stopChan:
-			return
-		default:
-		}
-
-		// Set accept timeout to allow checking stop signal
-		if tcpListener, ok := listener.(*net.TCPListener); ok {
-			tcpListener.SetDeadline(time.Now().Add(1 * time.Second))
-		}
-
-		conn, err := listener.Accept()
-		if err != nil {
-			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
-				continue // Timeout, check stop signal and continue
-			}
-			if atomic.LoadInt32(&tm.running) == 0 {
-				return // Tunnel is stopping
-			}
-			tm.logger.Error("failed to accept SOCKS connection", "error", err)
-			continue
-		}
-
-		tm.wg.Add(1)
-		go tm.handleSOCKSConnection(ctx, conn)
+		"version", config.SOCKSVersion)
+	if config.PAC != nil {
+		tm.logger.Info("PAC file available", "url", "http://"+bind.String()+models.PACPath)
 	}
+
+	return listener, nil
 }
 
 // handleSOCKSConnection handles a single SOCKS connection
-func (tm *TunnelManager) handleSOCKSConnection(ctx context.Context, conn net.Conn) {
+func (tm *TunnelManager) handleSOCKSConnection(ctx context.Context, conn net.Conn, listenerAddr string) {
 	defer tm.wg.Done()
 	defer conn.Close()
 
@@ -399,52 +611,48 @@ func (tm *TunnelManager) handleSOCKSConnection(ctx context.Context, conn net.Con
 	tm.connections.Store(conn, true)
 	defer tm.connections.Delete(conn)
 
+	// Browsers fetch the PAC file from the SOCKS port itself
+	if config := tm.Config(); config.PAC != nil {
+		reader := bufio.NewReader(conn)
+		first, err := reader.Peek(1)
+		if err != nil {
+			return
+		}
+		if !isSOCKSRequest(first[0]) {
+			tm.servePAC(conn, reader, config)
+			return
+		}
+		conn = &bufferedConn{Conn: conn, reader: reader}
+	}
+
 	// Update statistics
-	tm.updateStats(func(stats *models.SessionStats) {
-		stats.TotalConnections++
-		stats.ActiveConnections++
-	})
-	defer tm.updateStats(func(stats *models.SessionStats) {
-		stats.ActiveConnections--
-	})
+	defer tm.trackConnection(listenerAddr)()
+	tm.tuneConnection(conn, tm.Config())
 
 	// Handle SOCKS protocol
-	var targetAddr string
-	var err error
-
-	switch tm.config.SOCKSVersion {
-	case 4:
-		targetAddr, err = tm.handleSOCKS4(conn)
-	case 5:
-		targetAddr, err = tm.handleSOCKS5(conn)
-	default:
-		tm.logger.Error("unsupported SOCKS version", "version", tm.config.SOCKSVersion)
-		tm.updateStats(func(stats *models.SessionStats) {
-			stats.FailedConnections++
-		})
-		return
-	}
-
+	targetAddr, reply, err := tm.handleSOCKSHandshake(conn, tm.Config().SOCKSVersion)
 	if err != nil {
-		tm.logger.Error("SOCKS protocol error", "error", err)
+		tm.logger.Error("SOCKS protocol error", "client_addr", conn.RemoteAddr(), "error", err)
 		tm.updateStats(func(stats *models.SessionStats) {
 			stats.FailedConnections++
 		})
 		return
 	}
 
-	// Establish SSH connection to target
-	sshClient := tm.sshClient.GetClient()
-	if sshClient == nil {
-		tm.logger.Error("SSH client not available")
+	// Reach the target through the tunnel's transport
+	dial, err := tm.forwardDial()
+	if err != nil {
+		reply(err)
+		tm.logger.Error("forward transport not available", "error", err)
 		tm.updateStats(func(stats *models.SessionStats) {
 			stats.FailedConnections++
 		})
 		return
 	}
 
-	targetConn, err := sshClient.Dial("tcp", targetAddr)
+	targetConn, err := dial(tm.Config().Network(), targetAddr)
 	if err != nil {
+		reply(err)
 		tm.logger.Error("failed to connect to target",
 			"target_addr", targetAddr,
 			"error", err)
@@ -455,83 +663,177 @@ func (tm *TunnelManager) handleSOCKSConnection(ctx context.Context, conn net.Con
 	}
 	defer targetConn.Close()
 
+	if err := reply(nil); err != nil {
+		tm.logger.Debug("failed to answer SOCKS client", "client_addr", conn.RemoteAddr(), "error", err)
+		return
+	}
+
 	tm.logger.Debug("established SOCKS connection",
 		"client_addr", conn.RemoteAddr(),
 		"target_addr", targetAddr)
 
 	// Start bidirectional data transfer
-	tm.transfer(conn, targetConn)
+	tm.transfer(conn, targetConn, listenerAddr)
 }
 
 // transfer handles bidirectional data transfer between two connections
-func (tm *TunnelManager) transfer(conn1, conn2 net.Conn) {
-	var wg sync.WaitGroup
+func (tm *TunnelManager) transfer(conn1, conn2 net.Conn, listenerAddr string) {
+	done := make(chan struct{})
+
+	// conn1 is the client side
+	taps := tm.connectionTaps(listenerAddr, conn1.RemoteAddr())
 
 	// Transfer data from conn1 to conn2
-	wg.Add(1)
 	go func() {
-		defer wg.Done()
-		bytes, err := tm.copyData(conn2, conn1)
-		tm.updateStats(func(stats *models.SessionStats) {
-			stats.BytesSent += bytes
-			now := time.Now()
-			stats.LastActivityAt = &now
-		})
+		defer close(done)
+		bytes, err := tm.copyData(conn2, conn1, taps, models.CaptureSent)
+		tm.recordTransfer(listenerAddr, bytes, 0)
 		if err != nil && err != io.EOF {
 			tm.logger.Debug("transfer error conn1->conn2", "error", err)
 		}
 		conn2.Close()
 	}()
 
-	// Transfer data from conn2 to conn1
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		bytes, err := tm.copyData(conn1, conn2)
-		tm.updateStats(func(stats *models.SessionStats) {
-			stats.BytesReceived += bytes
-			now := time.Now()
-			stats.LastActivityAt = &now
-		})
-		if err != nil && err != io.EOF {
-			tm.logger.Debug("transfer error conn2->conn1", "error", err)
-		}
-		conn1.Close()
-	}()
+	// Transfer data from conn2 to conn1 on the calling goroutine
+	bytes, err := tm.copyData(conn1, conn2, taps, models.CaptureReceived)
+	tm.recordTransfer(listenerAddr, 0, bytes)
+	if err != nil && err != io.EOF {
+		tm.logger.Debug("transfer error conn2->conn1", "error", err)
+	}
+	conn1.Close()
+
+	<-done
+	for _, tap := range taps {
+		tap.close()
+	}
+}
+
+// connectionTaps returns the observers of a new connection: the debug
+// capture and, for tunnels carrying HTTP, the request inspector
+func (tm *TunnelManager) connectionTaps(listenerAddr string, client net.Addr) []connectionTap {
+	var taps []connectionTap
+	if capture := tm.capture.Load(); capture != nil {
+		taps = append(taps, capture.connection(listenerAddr, client))
+	}
+	if tm.Config().InspectHTTP {
+		taps = append(taps, newHTTPInspector(listenerAddr, client, tm.recordHTTP, tm.httpLog))
+	}
+	return taps
+}
+
+// recordHTTP logs a request seen by the HTTP inspector and counts it
+func (tm *TunnelManager) recordHTTP(exchange models.HTTPExchange) {
+	tm.logger.Info("http request",
+		"method", exchange.Method,
+		"host", exchange.Host,
+		"path", exchange.Path,
+		"status", exchange.Status,
+		"duration_ms", float64(exchange.Duration.Microseconds())/1000,
+		"listener", exchange.Listener,
+		"client", exchange.Client)
 
-	wg.Wait()
+	tm.statsMu.Lock()
+	defer tm.statsMu.Unlock()
+	if tm.stats.HTTP == nil {
+		tm.stats.HTTP = &models.HTTPStats{}
+	}
+	tm.stats.HTTP.Record(exchange)
 }
 
-// copyData copies data from src to dst and returns bytes transferred
-func (tm *TunnelManager) copyData(dst, src net.Conn) (int64, error) {
+// copyData copies data from src to dst and returns bytes transferred. Taps
+// observe the reads as direction.
+func (tm *TunnelManager) copyData(dst, src net.Conn, taps []connectionTap, direction models.CaptureDirection) (int64, error) {
+	config := tm.Config()
+
 	// Set timeouts
-	if tm.config.IdleTimeout > 0 {
-		deadline := time.Now().Add(tm.config.IdleTimeout)
+	if config.IdleTimeout > 0 {
+		deadline := time.Now().Add(config.IdleTimeout)
 		src.SetReadDeadline(deadline)
 		dst.SetWriteDeadline(deadline)
 	}
 
-	return io.Copy(dst, src)
+	if len(taps) > 0 {
+		return copyTapped(dst, src, config.BufferSize, tm.limiter.Load(), taps, direction)
+	}
+	if limiter := tm.limiter.Load(); limiter != nil {
+		return copyLimited(dst, src, config.BufferSize, limiter)
+	}
+	return copyConn(dst, src, config.BufferSize, !config.DisableZeroCopy)
 }
 
-// updateStats safely updates statistics
-func (tm *TunnelManager) updateStats(fn func(*models.SessionStats)) {
+// tuneConnection applies the configured socket options to a forwarded
+// connection; a failure is logged and the connection kept
+func (tm *TunnelManager) tuneConnection(conn net.Conn, config models.TunnelConfig) {
+	if err := applySocketOptions(conn, config.SocketOptions); err != nil {
+		tm.logger.Warn("failed to apply socket options", "addr", conn.RemoteAddr(), "error", err)
+	}
+}
+
+// SetBandwidthLimiter limits the combined throughput of the tunnel's
+// connections; nil removes the limit. New reads use it immediately.
+func (tm *TunnelManager) SetBandwidthLimiter(limiter *BandwidthLimiter) {
+	tm.limiter.Store(limiter)
+}
+
+// SetCapture records forwarded bytes of new connections into capture; nil
+// stops capturing. Connections in flight keep their current setting.
+func (tm *TunnelManager) SetCapture(capture *Capture) {
+	tm.capture.Store(capture)
+}
+
+// Capture returns the active debug capture, or nil
+func (tm *TunnelManager) Capture() *Capture {
+	return tm.capture.Load()
+}
+
+// trackConnection records a new connection on a listener and returns a
+// function that records its close
+func (tm *TunnelManager) trackConnection(listenerAddr string) func() {
 	tm.statsMu.Lock()
 	defer tm.statsMu.Unlock()
-	fn(&tm.stats)
+
+	listener := tm.listenerStatsFor(listenerAddr)
+	tm.stats.TotalConnections++
+	tm.stats.ActiveConnections++
+	listener.TotalConnections++
+	listener.ActiveConnections++
+
+	return func() {
+		tm.statsMu.Lock()
+		defer tm.statsMu.Unlock()
+		tm.stats.ActiveConnections--
+		listener.ActiveConnections--
+	}
 }
 
-// SOCKS protocol handlers would be implemented here
-// For brevity, I'll add placeholder implementations
+// recordTransfer adds transferred bytes to the tunnel and listener statistics
+func (tm *TunnelManager) recordTransfer(listenerAddr string, sent, received int64) {
+	tm.statsMu.Lock()
+	defer tm.statsMu.Unlock()
+
+	listener := tm.listenerStatsFor(listenerAddr)
+	tm.stats.BytesSent += sent
+	tm.stats.BytesReceived += received
+	listener.BytesSent += sent
+	listener.BytesReceived += received
+
+	now := time.Now()
+	tm.stats.LastActivityAt = &now
+}
 
-// handleSOCKS4 handles SOCKS4 protocol
-func (tm *TunnelManager) handleSOCKS4(conn net.Conn) (string, error) {
-	// TODO: Implement SOCKS4 protocol
-	return "", fmt.Errorf("SOCKS4 not implemented yet")
+// listenerStatsFor returns the statistics for a listener; callers must hold statsMu
+func (tm *TunnelManager) listenerStatsFor(listenerAddr string) *models.ListenerStats {
+	stats, exists := tm.listenerStats[listenerAddr]
+	if !exists {
+		stats = &models.ListenerStats{Address: listenerAddr}
+		tm.listenerStats[listenerAddr] = stats
+	}
+	return stats
 }
 
-// handleSOCKS5 handles SOCKS5 protocol
-func (tm *TunnelManager) handleSOCKS5(conn net.Conn) (string, error) {
-	// TODO: Implement SOCKS5 protocol
-	return "", fmt.Errorf("SOCKS5 not implemented yet")
+// updateStats safely updates statistics
+func (tm *TunnelManager) updateStats(fn func(*models.SessionStats)) {
+	tm.statsMu.Lock()
+	defer tm.statsMu.Unlock()
+	fn(&tm.stats)
 }
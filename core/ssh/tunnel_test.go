@@ -0,0 +1,91 @@
+package ssh
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// localConfig forwards a free loopback port to target through the SSH server
+func localConfig(t *testing.T, target string) models.TunnelConfig {
+	host, portText, _ := net.SplitHostPort(target)
+	port, _ := strconv.Atoi(portText)
+	return models.TunnelConfig{
+		Type:             models.TunnelTypeLocal,
+		LocalBindAddress: "127.0.0.1",
+		LocalPort:        freePort(t),
+		RemoteHost:       host,
+		RemotePort:       port,
+	}
+}
+
+func localAddr(config models.TunnelConfig) string {
+	return net.JoinHostPort(config.LocalBindAddress, strconv.Itoa(config.LocalPort))
+}
+
+// assertForwards connects to address and checks the echo server behind it
+func assertForwards(t *testing.T, address string) {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", address, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	assertEcho(t, conn)
+}
+
+func TestLocalForward(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	config := localConfig(t, startEchoServer(t))
+	tunnel := startTestTunnel(t, client, config)
+
+	assertForwards(t, localAddr(config))
+	if !tunnel.IsRunning() {
+		t.Fatal("tunnel is not running")
+	}
+	// Traffic is counted when the connection ends
+	if stats := tunnel.GetStats(); stats.TotalConnections != 1 {
+		t.Fatalf("stats = %+v, want one connection", stats)
+	}
+}
+
+func TestRemoteForward(t *testing.T) {
+	server := newTestServer(t)
+	client := newTestClient(t, server)
+	target := startEchoServer(t)
+	host, portText, _ := net.SplitHostPort(target)
+	targetPort, _ := strconv.Atoi(portText)
+	config := models.TunnelConfig{
+		Type:              models.TunnelTypeRemote,
+		RemoteBindAddress: "127.0.0.1",
+		LocalPort:         freePort(t),
+		RemoteHost:        host,
+		RemotePort:        targetPort,
+	}
+	tunnel := startTestTunnel(t, client, config)
+
+	// The SSH server listens on the forwarded port
+	address := net.JoinHostPort(config.RemoteBindAddress, strconv.Itoa(config.LocalPort))
+	assertForwards(t, address)
+
+	if err := tunnel.Stop(t.Context()); err != nil {
+		t.Fatalf("failed to stop tunnel: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("SSH server still listens after the tunnel stopped")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
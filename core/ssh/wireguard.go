@@ -0,0 +1,125 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ErrWireGuardDown is returned when the WireGuard interface of a tunnel is
+// missing, down or has no address
+var ErrWireGuardDown = errors.New("wireguard interface is not up")
+
+// WireGuardLink dials forward targets over an existing WireGuard interface
+// instead of through an SSH connection. The interface and its peers are
+// managed by the system; the link only checks it is up and dials from its
+// addresses.
+type WireGuardLink struct {
+	iface   string
+	peer    string // the host's tunnel address, dialed for loopback targets
+	timeout time.Duration
+}
+
+// NewWireGuardLink creates the link for a connection using the wireguard
+// transport; config.WireGuard must be set
+func NewWireGuardLink(config models.SSHConnectionConfig) *WireGuardLink {
+	peer := config.WireGuard.Peer
+	if peer == "" {
+		peer = config.Host
+	}
+	return &WireGuardLink{
+		iface:   config.WireGuard.Interface,
+		peer:    peer,
+		timeout: config.ConnectTimeout,
+	}
+}
+
+// Interface returns the name of the WireGuard interface
+func (l *WireGuardLink) Interface() string {
+	return l.iface
+}
+
+// Check reports whether the interface exists, is up and has an address
+func (l *WireGuardLink) Check() error {
+	_, err := l.addresses()
+	return err
+}
+
+// addresses returns the IP addresses of the interface once it is up
+func (l *WireGuardLink) addresses() ([]net.IP, error) {
+	iface, err := net.InterfaceByName(l.iface)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrWireGuardDown, l.iface, err)
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("%w: %s is down", ErrWireGuardDown, l.iface)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrWireGuardDown, l.iface, err)
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("%w: %s has no address", ErrWireGuardDown, l.iface)
+	}
+	return ips, nil
+}
+
+// Dial connects to addr from the interface address of the target's family.
+// A loopback target means the host itself, as it would over SSH, and is
+// dialed at the host's tunnel address.
+func (l *WireGuardLink) Dial(network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if models.IsLoopbackAddress(host) {
+		addr = net.JoinHostPort(l.peer, port)
+	}
+
+	target, err := net.ResolveTCPAddr(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := l.addresses()
+	if err != nil {
+		return nil, err
+	}
+
+	targetIPv4 := target.IP.To4() != nil
+	for _, ip := range ips {
+		if (ip.To4() != nil) == targetIPv4 {
+			dialer := net.Dialer{Timeout: l.timeout, LocalAddr: &net.TCPAddr{IP: ip}}
+			return dialer.Dial(network, target.String())
+		}
+	}
+	return nil, fmt.Errorf("wireguard interface %s has no address to reach %s", l.iface, target.IP)
+}
+
+// SetWireGuard makes the tunnel reach its targets over link instead of the
+// SSH connection, which is then never opened. It must be called before Start.
+func (tm *TunnelManager) SetWireGuard(link *WireGuardLink) {
+	tm.wireguard = link
+}
+
+// forwardDial returns how local, dynamic and transparent forwards reach their
+// targets: over the WireGuard link when set, else through the SSH connection
+func (tm *TunnelManager) forwardDial() (func(network, addr string) (net.Conn, error), error) {
+	if tm.wireguard != nil {
+		return tm.wireguard.Dial, nil
+	}
+	client := tm.sshClient.GetClient()
+	if client == nil {
+		return nil, errors.New("SSH client not available")
+	}
+	return client.Dial, nil
+}
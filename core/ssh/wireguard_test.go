@@ -0,0 +1,56 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// loopbackInterface returns the name of the loopback interface, which
+// stands in for a WireGuard interface that is up and has an address
+func loopbackInterface(t *testing.T) string {
+	t.Helper()
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatalf("failed to list interfaces: %v", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+			return iface.Name
+		}
+	}
+	t.Skip("no loopback interface is up")
+	return ""
+}
+
+func TestSOCKSOverWireGuard(t *testing.T) {
+	connection := models.SSHConnectionConfig{
+		Host:      "127.0.0.1",
+		WireGuard: &models.WireGuardConfig{Interface: loopbackInterface(t), Peer: "127.0.0.1"},
+	}
+	config := dynamicConfig(t, 5)
+
+	// No SSH server: the tunnel must not need one
+	tunnel := NewTunnelManager(NewSSHClient(connection, utils.DiscardLogger()), config, utils.DiscardLogger())
+	tunnel.SetWireGuard(NewWireGuardLink(connection))
+	if err := tunnel.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start tunnel: %v", err)
+	}
+	t.Cleanup(func() { tunnel.Stop(context.Background()) })
+
+	dialer, err := proxy.SOCKS5("tcp", socksAddr(config), nil, proxy.Direct)
+	if err != nil {
+		t.Fatalf("failed to create SOCKS5 dialer: %v", err)
+	}
+	conn, err := dialer.Dial("tcp", startEchoServer(t))
+	if err != nil {
+		t.Fatalf("SOCKS5 connect over WireGuard failed: %v", err)
+	}
+	defer conn.Close()
+	assertEcho(t, conn)
+}
@@ -0,0 +1,109 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationPrefix prefixes the tracer name of each instrumented package
+const instrumentationPrefix = "github.com/aqz236/port-fly/"
+
+// Config holds OpenTelemetry tracing configuration
+type Config struct {
+	Enabled     bool              `json:"enabled" yaml:"enabled"`
+	Endpoint    string            `json:"endpoint" yaml:"endpoint"`         // OTLP/HTTP collector, e.g. "localhost:4318"
+	URLPath     string            `json:"url_path" yaml:"url_path"`         // defaults to /v1/traces
+	Insecure    bool              `json:"insecure" yaml:"insecure"`         // use http instead of https
+	Headers     map[string]string `json:"headers" yaml:"headers"`           // e.g. authentication headers for hosted collectors
+	ServiceName string            `json:"service_name" yaml:"service_name"` // defaults to "portfly"
+	SampleRatio float64           `json:"sample_ratio" yaml:"sample_ratio"` // 0 or 1 samples everything
+}
+
+// Setup installs the global tracer provider and returns a function that flushes
+// and shuts it down. When tracing is disabled the no-op provider stays in place.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	if !config.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{}
+	if config.Endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+	}
+	if config.URLPath != "" {
+		options = append(options, otlptracehttp.WithURLPath(config.URLPath))
+	}
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	if len(config.Headers) > 0 {
+		options = append(options, otlptracehttp.WithHeaders(config.Headers))
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = "portfly"
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if config.SampleRatio > 0 && config.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(config.SampleRatio)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer for an instrumented package, e.g. Tracer("core/ssh")
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer(instrumentationPrefix + name)
+}
+
+// StartSpan starts a span on the package tracer with the given attributes
+func StartSpan(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records err on the span, if any, and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ANSI colors of the console output
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+	ansiGray   = "\x1b[90m"
+)
+
+// ConsoleHandler is a slog handler for people at a terminal: one line per
+// record with the time, a fixed-width level, the message and its attributes
+// as key=value, optionally colored by level
+type ConsoleHandler struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	opts   slog.HandlerOptions
+	color  bool
+	prefix string // attributes added with WithAttrs, already formatted
+	group  string // key prefix of the open groups, e.g. "pool."
+}
+
+// NewConsoleHandler creates a console handler writing to w. opts may be nil.
+func NewConsoleHandler(w io.Writer, opts *slog.HandlerOptions, color bool) *ConsoleHandler {
+	handler := &ConsoleHandler{w: w, mu: &sync.Mutex{}, color: color}
+	if opts != nil {
+		handler.opts = *opts
+	}
+	return handler
+}
+
+// Enabled reports whether records at level are written
+func (h *ConsoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle writes the record as one line
+func (h *ConsoleHandler) Handle(_ context.Context, record slog.Record) error {
+	var line strings.Builder
+	if !record.Time.IsZero() {
+		line.WriteString(h.paint(ansiGray, record.Time.Format(time.TimeOnly)))
+		line.WriteByte(' ')
+	}
+	line.WriteString(h.paint(levelColor(record.Level), fmt.Sprintf("%-5s", record.Level.String())))
+	line.WriteByte(' ')
+	line.WriteString(record.Message)
+	line.WriteString(h.prefix)
+	record.Attrs(func(attr slog.Attr) bool {
+		h.appendAttr(&line, h.group, attr)
+		return true
+	})
+	if h.opts.AddSource && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		line.WriteString(h.paint(ansiGray, fmt.Sprintf(" (%s:%d)", filepath.Base(frame.File), frame.Line)))
+	}
+	line.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line.String())
+	return err
+}
+
+// WithAttrs returns a handler that writes attrs with every record
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var prefix strings.Builder
+	for _, attr := range attrs {
+		h.appendAttr(&prefix, h.group, attr)
+	}
+	clone := *h
+	clone.prefix += prefix.String()
+	return &clone
+}
+
+// WithGroup returns a handler that qualifies the keys of later attributes
+// with name
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group += name + "."
+	return &clone
+}
+
+// appendAttr writes " key=value", flattening groups into dotted keys
+func (h *ConsoleHandler) appendAttr(line *strings.Builder, group string, attr slog.Attr) {
+	if h.opts.ReplaceAttr != nil && attr.Value.Kind() != slog.KindGroup {
+		attr = h.opts.ReplaceAttr(nil, attr)
+	}
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			group += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			h.appendAttr(line, group, member)
+		}
+		return
+	}
+
+	line.WriteByte(' ')
+	line.WriteString(h.paint(ansiDim, group+attr.Key+"="))
+	line.WriteString(consoleValue(attr.Value))
+}
+
+// paint colors s when the handler writes colors
+func (h *ConsoleHandler) paint(color, s string) string {
+	if !h.color {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// levelColor is the color a level is written in
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return ansiRed
+	case level >= slog.LevelWarn:
+		return ansiYellow
+	case level >= slog.LevelInfo:
+		return ansiCyan
+	default:
+		return ansiGray
+	}
+}
+
+// consoleValue formats a value, quoting strings that would not read as one word
+func consoleValue(value slog.Value) string {
+	var s string
+	switch value.Kind() {
+	case slog.KindTime:
+		s = value.Time().Format(time.RFC3339)
+	case slog.KindDuration:
+		s = value.Duration().String()
+	default:
+		s = value.String()
+	}
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) }) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// multiHandler passes records to every handler that takes their level
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range m {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, record slog.Record) error {
+	var firstErr error
+	for _, handler := range m {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, handler := range m {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, handler := range m {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,412 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log scope keys; records carrying one of these attributes are captured per session/port
+const (
+	LogScopeSession = "session_id"
+	LogScopePort    = "port_id"
+)
+
+// DefaultLogBufferSize is the number of entries kept per session/port
+const DefaultLogBufferSize = 500
+
+// DefaultRecentLogSize is the number of recent entries of any scope kept for
+// diagnostic bundles
+const DefaultRecentLogSize = 1000
+
+// logFollowBufferSize is the per-follower channel capacity; slow followers drop entries
+const logFollowBufferSize = 128
+
+// LogEntry is a single captured log record
+type LogEntry struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"message"`
+	Attrs   map[string]any `json:"attrs,omitempty"`
+}
+
+// LogStoreConfig configures per-session/port log capture
+type LogStoreConfig struct {
+	BufferSize int    `json:"buffer_size" yaml:"buffer_size"` // entries kept in memory per session/port
+	Dir        string `json:"dir" yaml:"dir"`                 // optional directory for persisted logs (JSON lines)
+	RecentSize int    `json:"recent_size" yaml:"recent_size"` // entries of all records kept in memory, scoped or not
+}
+
+// LogStore keeps a ring buffer of recent log entries per session and port,
+// and one of the most recent entries overall
+type LogStore struct {
+	config  LogStoreConfig
+	buffers map[string]*logRing
+	recent  *logRing
+	mu      sync.Mutex
+}
+
+// logRing is a fixed-size ring buffer with live followers
+type logRing struct {
+	entries   []LogEntry
+	next      int
+	full      bool
+	followers map[int]chan LogEntry
+	nextID    int
+	file      *os.File
+	mu        sync.Mutex
+}
+
+// NewLogStore creates a new log store
+func NewLogStore(config LogStoreConfig) *LogStore {
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultLogBufferSize
+	}
+	if config.RecentSize <= 0 {
+		config.RecentSize = DefaultRecentLogSize
+	}
+	return &LogStore{
+		config:  config,
+		buffers: make(map[string]*logRing),
+		recent:  &logRing{entries: make([]LogEntry, config.RecentSize)},
+	}
+}
+
+// Handler wraps next so that scoped records are also captured in the store
+func (s *LogStore) Handler(next slog.Handler) slog.Handler {
+	return &logStoreHandler{next: next, store: s}
+}
+
+// Entries returns buffered entries for the scope at or above minLevel, oldest first.
+// A positive limit keeps only the most recent entries.
+func (s *LogStore) Entries(scope, id string, minLevel slog.Level, limit int) []LogEntry {
+	ring := s.ring(scope, id, false)
+	if ring == nil {
+		return []LogEntry{}
+	}
+	return ring.snapshot(minLevel, limit)
+}
+
+// Recent returns the most recent entries of any scope, or none, at or above
+// minLevel, oldest first. A positive limit keeps only the most recent entries.
+func (s *LogStore) Recent(minLevel slog.Level, limit int) []LogEntry {
+	return s.recent.snapshot(minLevel, limit)
+}
+
+// snapshot copies the ring's entries at or above minLevel, oldest first
+func (ring *logRing) snapshot(minLevel slog.Level, limit int) []LogEntry {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	var ordered []LogEntry
+	if ring.full {
+		ordered = append(ordered, ring.entries[ring.next:]...)
+	}
+	ordered = append(ordered, ring.entries[:ring.next]...)
+
+	entries := make([]LogEntry, 0, len(ordered))
+	for _, entry := range ordered {
+		if LevelAtLeast(entry.Level, minLevel) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries
+}
+
+// add appends entry, overwriting the oldest once the ring is full; callers
+// must hold ring.mu
+func (ring *logRing) add(entry LogEntry) {
+	ring.entries[ring.next] = entry
+	ring.next = (ring.next + 1) % len(ring.entries)
+	if ring.next == 0 {
+		ring.full = true
+	}
+}
+
+// Follow subscribes to new entries for the scope and returns an unsubscribe function
+func (s *LogStore) Follow(scope, id string) (<-chan LogEntry, func()) {
+	ring := s.ring(scope, id, true)
+	ch := make(chan LogEntry, logFollowBufferSize)
+
+	ring.mu.Lock()
+	followerID := ring.nextID
+	ring.nextID++
+	ring.followers[followerID] = ch
+	ring.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			ring.mu.Lock()
+			delete(ring.followers, followerID)
+			ring.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Remove drops the buffer for the scope and closes its log file
+func (s *LogStore) Remove(scope, id string) {
+	s.mu.Lock()
+	ring, exists := s.buffers[scopeKey(scope, id)]
+	delete(s.buffers, scopeKey(scope, id))
+	s.mu.Unlock()
+
+	if exists {
+		ring.mu.Lock()
+		if ring.file != nil {
+			ring.file.Close()
+			ring.file = nil
+		}
+		ring.mu.Unlock()
+	}
+}
+
+// record appends an entry to the scope's buffer, log file and followers
+func (s *LogStore) record(scope, id string, entry LogEntry) {
+	ring := s.ring(scope, id, true)
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	ring.add(entry)
+
+	if s.config.Dir != "" {
+		s.persist(ring, scope, id, entry)
+	}
+
+	for _, ch := range ring.followers {
+		select {
+		case ch <- entry:
+		default:
+			// Follower is not keeping up, drop the entry
+		}
+	}
+}
+
+// persist appends entry to the scope's log file; callers must hold ring.mu
+func (s *LogStore) persist(ring *logRing, scope, id string, entry LogEntry) {
+	if ring.file == nil {
+		if err := os.MkdirAll(s.config.Dir, 0755); err != nil {
+			return
+		}
+		path := filepath.Join(s.config.Dir, fmt.Sprintf("%s-%s.log", scope, unsafeFileChars.ReplaceAllString(id, "_")))
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return
+		}
+		ring.file = file
+	}
+
+	if line, err := json.Marshal(entry); err == nil {
+		ring.file.Write(append(line, '\n'))
+	}
+}
+
+// PruneFiles removes persisted log files last written before the cutoff.
+// Files still open for a live session or port are kept.
+func (s *LogStore) PruneFiles(before time.Time) (int, error) {
+	if s.config.Dir == "" {
+		return 0, nil
+	}
+
+	open := make(map[string]bool)
+	s.mu.Lock()
+	for _, ring := range s.buffers {
+		ring.mu.Lock()
+		if ring.file != nil {
+			open[filepath.Base(ring.file.Name())] = true
+		}
+		ring.mu.Unlock()
+	}
+	s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.config.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to list log files: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".log") || open[name] {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.config.Dir, name)); err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// ring returns the buffer for the scope, creating it if requested
+func (s *LogStore) ring(scope, id string, create bool) *logRing {
+	key := scopeKey(scope, id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ring, exists := s.buffers[key]
+	if !exists && create {
+		ring = &logRing{
+			entries:   make([]LogEntry, s.config.BufferSize),
+			followers: make(map[int]chan LogEntry),
+		}
+		s.buffers[key] = ring
+	}
+	return ring
+}
+
+// scopeKey builds the buffer key for a scope
+func scopeKey(scope, id string) string {
+	return scope + ":" + id
+}
+
+// unsafeFileChars matches characters not allowed in log file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// ParseLogLevel parses a level name (debug, info, warn, error)
+func ParseLogLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level: %s", level)
+	}
+}
+
+// LevelAtLeast reports whether the level name is at or above min
+func LevelAtLeast(level string, min slog.Level) bool {
+	l, err := ParseLogLevel(level)
+	if err != nil {
+		return true
+	}
+	return l >= min
+}
+
+// logStoreHandler forwards records to the next handler and captures scoped ones
+type logStoreHandler struct {
+	next   slog.Handler
+	store  *LogStore
+	attrs  []slog.Attr
+	prefix string
+}
+
+// Enabled reports whether the next handler handles the level
+func (h *logStoreHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle writes the record, keeps it among the recent entries and captures it
+// for any session/port it belongs to
+func (h *logStoreHandler) Handle(ctx context.Context, record slog.Record) error {
+	err := h.next.Handle(ctx, record)
+
+	attrs := make(map[string]any, len(h.attrs)+record.NumAttrs())
+	scopes := make(map[string]string)
+
+	for _, attr := range h.attrs {
+		collectAttr(attrs, scopes, "", attr)
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		collectAttr(attrs, scopes, h.prefix, attr)
+		return true
+	})
+
+	entry := LogEntry{
+		Time:    record.Time,
+		Level:   record.Level.String(),
+		Message: record.Message,
+		Attrs:   attrs,
+	}
+	h.store.recent.mu.Lock()
+	h.store.recent.add(entry)
+	h.store.recent.mu.Unlock()
+
+	for scope, id := range scopes {
+		h.store.record(scope, id, entry)
+	}
+
+	return err
+}
+
+// WithAttrs returns a handler with additional attributes
+func (h *logStoreHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithAttrs(attrs)
+	clone.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, attr := range attrs {
+		if h.prefix != "" {
+			attr.Key = h.prefix + attr.Key
+		}
+		clone.attrs = append(clone.attrs, attr)
+	}
+	return &clone
+}
+
+// WithGroup returns a handler that nests subsequent attributes under name
+func (h *logStoreHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.next = h.next.WithGroup(name)
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// collectAttr flattens attr into attrs and records session/port scope ids
+func collectAttr(attrs map[string]any, scopes map[string]string, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		for _, nested := range value.Group() {
+			collectAttr(attrs, scopes, prefix+attr.Key+".", nested)
+		}
+		return
+	}
+
+	leaf := attr.Key[strings.LastIndex(attr.Key, ".")+1:]
+	if leaf == LogScopeSession || leaf == LogScopePort {
+		scopes[leaf] = value.String()
+	}
+
+	switch value.Kind() {
+	case slog.KindString:
+		attrs[prefix+attr.Key] = value.String()
+	case slog.KindInt64:
+		attrs[prefix+attr.Key] = value.Int64()
+	case slog.KindUint64:
+		attrs[prefix+attr.Key] = value.Uint64()
+	case slog.KindFloat64:
+		attrs[prefix+attr.Key] = value.Float64()
+	case slog.KindBool:
+		attrs[prefix+attr.Key] = value.Bool()
+	default:
+		if err, ok := value.Any().(error); ok {
+			attrs[prefix+attr.Key] = err.Error()
+		} else {
+			attrs[prefix+attr.Key] = value.String()
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
@@ -41,66 +42,41 @@ type PortFlyLogger struct {
 // LoggerConfig contains logger configuration
 type LoggerConfig struct {
 	Level      string `json:"level" yaml:"level"`
-	Format     string `json:"format" yaml:"format"`     // "json" or "text"
+	Format     string `json:"format" yaml:"format"`     // "json", "text" or "console"
 	Output     string `json:"output" yaml:"output"`     // "stdout", "stderr", or file path
+	Color      bool   `json:"color" yaml:"color"`       // color console output by level
 	MaxSize    int    `json:"max_size" yaml:"max_size"` // megabytes
 	MaxBackups int    `json:"max_backups" yaml:"max_backups"`
 	MaxAge     int    `json:"max_age" yaml:"max_age"` // days
 	Compress   bool   `json:"compress" yaml:"compress"`
+
+	// File, when set, additionally receives every record, debug included,
+	// as JSON; it is rotated like a file Output
+	File string `json:"file" yaml:"file"`
+
+	// Store, when set, additionally captures session/port scoped records
+	Store *LogStore `json:"-" yaml:"-"`
 }
 
 // NewLogger creates a new logger instance
 func NewLogger(config LoggerConfig) (Logger, error) {
-	var writer io.Writer
-
-	// Determine output destination
-	switch strings.ToLower(config.Output) {
-	case "stdout", "":
-		writer = os.Stdout
-	case "stderr":
-		writer = os.Stderr
-	default:
-		// File output with rotation
-		if err := os.MkdirAll(filepath.Dir(config.Output), 0755); err != nil {
-			return nil, fmt.Errorf("failed to create log directory: %w", err)
-		}
-
-		writer = &lumberjack.Logger{
-			Filename:   config.Output,
-			MaxSize:    config.MaxSize,
-			MaxBackups: config.MaxBackups,
-			MaxAge:     config.MaxAge,
-			Compress:   config.Compress,
-		}
+	writer, err := logWriter(config, config.Output)
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse log level
-	var level slog.Level
-	switch strings.ToLower(config.Level) {
-	case "debug":
-		level = slog.LevelDebug
-	case "info", "":
-		level = slog.LevelInfo
-	case "warn", "warning":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		return nil, fmt.Errorf("invalid log level: %s", config.Level)
+	level, err := ParseLogLevel(config.Level)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create handler based on format
 	var handler slog.Handler
 	opts := &slog.HandlerOptions{
-		Level:     level,
-		AddSource: level == slog.LevelDebug,
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			// Add timestamp formatting
-			if a.Key == slog.TimeKey {
-				a.Value = slog.StringValue(a.Value.Time().Format(time.RFC3339))
-			}
-			return a
-		},
+		Level:       level,
+		AddSource:   level == slog.LevelDebug,
+		ReplaceAttr: formatLogTime,
 	}
 
 	switch strings.ToLower(config.Format) {
@@ -108,33 +84,94 @@ func NewLogger(config LoggerConfig) (Logger, error) {
 		handler = slog.NewJSONHandler(writer, opts)
 	case "text":
 		handler = slog.NewTextHandler(writer, opts)
+	case "console":
+		handler = NewConsoleHandler(writer, opts, config.Color)
 	default:
 		return nil, fmt.Errorf("invalid log format: %s", config.Format)
 	}
 
+	if config.File != "" {
+		file, err := logWriter(config, config.File)
+		if err != nil {
+			return nil, err
+		}
+		handler = multiHandler{handler, slog.NewJSONHandler(file, &slog.HandlerOptions{
+			Level:       slog.LevelDebug,
+			AddSource:   true,
+			ReplaceAttr: formatLogTime,
+		})}
+	}
+
+	if config.Store != nil {
+		handler = config.Store.Handler(handler)
+	}
+
 	logger := slog.New(handler)
 
 	return &PortFlyLogger{logger: logger}, nil
 }
 
+// logWriter opens a log destination: stdout, stderr or a rotated file
+func logWriter(config LoggerConfig, output string) (io.Writer, error) {
+	switch strings.ToLower(output) {
+	case "stdout", "":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+
+	// File output with rotation
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	return &lumberjack.Logger{
+		Filename:   output,
+		MaxSize:    config.MaxSize,
+		MaxBackups: config.MaxBackups,
+		MaxAge:     config.MaxAge,
+		Compress:   config.Compress,
+	}, nil
+}
+
+// formatLogTime writes record times as RFC 3339
+func formatLogTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		a.Value = slog.StringValue(a.Value.Time().Format(time.RFC3339))
+	}
+	return a
+}
+
 // Debug logs a debug message
 func (l *PortFlyLogger) Debug(msg string, args ...any) {
-	l.logger.Debug(msg, args...)
+	l.log(slog.LevelDebug, msg, args...)
 }
 
 // Info logs an info message
 func (l *PortFlyLogger) Info(msg string, args ...any) {
-	l.logger.Info(msg, args...)
+	l.log(slog.LevelInfo, msg, args...)
 }
 
 // Warn logs a warning message
 func (l *PortFlyLogger) Warn(msg string, args ...any) {
-	l.logger.Warn(msg, args...)
+	l.log(slog.LevelWarn, msg, args...)
 }
 
 // Error logs an error message
 func (l *PortFlyLogger) Error(msg string, args ...any) {
-	l.logger.Error(msg, args...)
+	l.log(slog.LevelError, msg, args...)
+}
+
+// log records msg with the caller of the Logger method as its source
+func (l *PortFlyLogger) log(level slog.Level, msg string, args ...any) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip Callers, log and the level method
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.Add(args...)
+	_ = l.logger.Handler().Handle(ctx, record)
 }
 
 // With returns a new logger with the given attributes
@@ -157,6 +194,11 @@ func DefaultLogger() Logger {
 	return logger
 }
 
+// DiscardLogger returns a logger that drops every record
+func DiscardLogger() Logger {
+	return &PortFlyLogger{logger: slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))}
+}
+
 // LoggerContext is a context key for logger
 type loggerContextKey struct{}
 
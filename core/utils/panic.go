@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// PanicHandler is told about a panic the process recovered from: where it
+// happened, the value it panicked with and the stack of the panicking goroutine
+type PanicHandler func(where string, value any, stack []byte)
+
+var panicHandler atomic.Pointer[PanicHandler]
+
+// SetPanicHandler sets the handler ReportPanic passes panics to; nil removes it
+func SetPanicHandler(handler PanicHandler) {
+	if handler == nil {
+		panicHandler.Store(nil)
+		return
+	}
+	panicHandler.Store(&handler)
+}
+
+// ReportPanic passes a recovered panic to the panic handler, if one is set.
+// Call it from the deferred function that recovered, so the stack still shows
+// where the panic came from.
+func ReportPanic(where string, value any) {
+	if handler := panicHandler.Load(); handler != nil {
+		(*handler)(where, value, debug.Stack())
+	}
+}
@@ -0,0 +1,155 @@
+// Package version describes PortFly builds and decides whether a CLI, an
+// agent and a server built from different releases can work together.
+package version
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// APIRevision is the revision of the REST API this build speaks. It is bumped
+// on changes that builds speaking an older revision cannot handle.
+// MinAPIRevision is the oldest revision this build still works with, as a
+// server serving older clients or as a client of an older server.
+const (
+	APIRevision    = 1
+	MinAPIRevision = 1
+)
+
+// Headers carrying the build of the other side on every API request and response
+const (
+	HeaderVersion     = "X-PortFly-Version"
+	HeaderAPIRevision = "X-PortFly-API-Revision"
+)
+
+// Dev is the version of builds made without release ldflags
+const Dev = "dev"
+
+// ErrIncompatible is returned when two builds cannot work together
+var ErrIncompatible = errors.New("incompatible PortFly versions")
+
+// Info identifies a build
+type Info struct {
+	Version        string `json:"version"`
+	BuildTime      string `json:"build_time,omitempty"`
+	APIRevision    int    `json:"api_revision"`
+	MinAPIRevision int    `json:"min_api_revision"`
+	GoVersion      string `json:"go_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	Arch           string `json:"arch,omitempty"`
+}
+
+// Current describes this build, given the version and build time set by ldflags
+func Current(version, buildTime string) Info {
+	if version == "" {
+		version = Dev
+	}
+	return Info{
+		Version:        version,
+		BuildTime:      buildTime,
+		APIRevision:    APIRevision,
+		MinAPIRevision: MinAPIRevision,
+		GoVersion:      runtime.Version(),
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+	}
+}
+
+// CheckAPI returns an ErrIncompatible error naming the side to upgrade when
+// the API revisions of local and peer do not overlap. A peer revision of 0
+// is unknown, e.g. a build from before revisions, and is not checked.
+func CheckAPI(localName string, local Info, peerName string, peer Info) error {
+	if peer.APIRevision == 0 {
+		return nil
+	}
+	if peer.APIRevision < local.MinAPIRevision {
+		return fmt.Errorf("%w: the %s (%s) speaks API revision %d but the %s (%s) needs at least %d; upgrade the %s",
+			ErrIncompatible, peerName, peer.Version, peer.APIRevision, localName, local.Version, local.MinAPIRevision, peerName)
+	}
+	if peer.MinAPIRevision > local.APIRevision {
+		return fmt.Errorf("%w: the %s (%s) needs API revision %d but the %s (%s) speaks %d; upgrade the %s",
+			ErrIncompatible, peerName, peer.Version, peer.MinAPIRevision, localName, local.Version, local.APIRevision, localName)
+	}
+	return nil
+}
+
+// Skew describes the difference between two compatible versions worth a
+// warning: a different major or minor release. Development builds and
+// unparsable versions are not compared; the result is then empty.
+func Skew(localName, local, peerName, peer string) string {
+	a, okA := Parse(local)
+	b, okB := Parse(peer)
+	if !okA || !okB || (a[0] == b[0] && a[1] == b[1]) {
+		return ""
+	}
+	newer := localName
+	if Compare(local, peer) < 0 {
+		newer = peerName
+	}
+	return fmt.Sprintf("%s %s and %s %s are different releases; the %s is newer and some features may not work until both match",
+		localName, local, peerName, peer, newer)
+}
+
+// Parse reads a version such as v1.2.3 or 1.2.3-rc.1 into its major, minor
+// and patch numbers
+func Parse(version string) ([3]int, bool) {
+	var parts [3]int
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	version, _, _ = strings.Cut(version, "+")
+	version, _, _ = strings.Cut(version, "-")
+	fields := strings.Split(version, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// Compare orders two versions, returning -1, 0 or 1. Pre-releases sort
+// before their release; unparsable versions, such as dev, sort first.
+func Compare(a, b string) int {
+	pa, okA := Parse(a)
+	pb, okB := Parse(b)
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	for i := range pa {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	preA, preB := prerelease(a), prerelease(b)
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return strings.Compare(preA, preB)
+}
+
+// prerelease returns the pre-release part of a version, e.g. rc.1
+func prerelease(version string) string {
+	version, _, _ = strings.Cut(version, "+")
+	_, pre, _ := strings.Cut(version, "-")
+	return pre
+}
@@ -0,0 +1,82 @@
+package webinfo
+
+import (
+	"strings"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// signature recognizes an application. Any one marker is enough; all
+// comparisons ignore case.
+type signature struct {
+	app     models.WebApp
+	headers []string // response headers the application sets
+	cookies []string // cookie names
+	title   []string // substrings of the title or generator
+	body    []string // substrings of the page
+}
+
+// signatures are tried in order, the more specific markers first
+var signatures = []signature{
+	{app: models.WebAppJenkins, headers: []string{"X-Jenkins"}, title: []string{"jenkins"}},
+	{app: models.WebAppKibana, headers: []string{"Kbn-Name", "Kbn-Version"}, title: []string{"kibana"}},
+	{app: models.WebAppGitea, cookies: []string{"i_like_gitea"}, title: []string{"gitea"}},
+	{app: models.WebAppGitLab, cookies: []string{"_gitlab_session"}, title: []string{"gitlab"}},
+	{app: models.WebAppGrafana, cookies: []string{"grafana_session"}, title: []string{"grafana"}, body: []string{"grafanabootdata"}},
+	{app: models.WebAppPgAdmin, cookies: []string{"pga4_session"}, title: []string{"pgadmin"}},
+	{app: models.WebAppPhpMyAdmin, cookies: []string{"phpmyadmin"}, title: []string{"phpmyadmin"}},
+	{app: models.WebAppAdminer, cookies: []string{"adminer_sid"}, title: []string{"adminer"}},
+	{app: models.WebAppAlertmanager, title: []string{"alertmanager"}},
+	{app: models.WebAppPrometheus, title: []string{"prometheus"}},
+	{app: models.WebAppElasticsearch, body: []string{"you know, for search"}},
+	{app: models.WebAppRabbitMQ, title: []string{"rabbitmq"}},
+	{app: models.WebAppPortainer, title: []string{"portainer"}},
+	{app: models.WebAppJupyter, title: []string{"jupyter"}},
+	{app: models.WebAppArgoCD, title: []string{"argo cd"}},
+	{app: models.WebAppKubeDashboard, title: []string{"kubernetes dashboard"}},
+	{app: models.WebAppMinIO, headers: []string{"X-Minio-Deployment-Id"}, title: []string{"minio"}},
+	{app: models.WebAppAirflow, title: []string{"airflow"}},
+	{app: models.WebAppSonarQube, title: []string{"sonarqube"}},
+	{app: models.WebAppConsul, title: []string{"consul by hashicorp"}},
+	{app: models.WebAppVault, title: []string{"vault"}, body: []string{"vault-ui"}},
+	{app: models.WebAppTraefik, title: []string{"traefik"}},
+}
+
+// identify returns the application the page belongs to, or "" when none matches
+func identify(p *page) models.WebApp {
+	title := strings.ToLower(p.title + " " + p.generator)
+	body := strings.ToLower(p.body)
+
+	for _, sig := range signatures {
+		if sig.matches(p, title, body) {
+			return sig.app
+		}
+	}
+	return ""
+}
+
+func (s *signature) matches(p *page, title, body string) bool {
+	for _, header := range s.headers {
+		if p.header.Get(header) != "" {
+			return true
+		}
+	}
+	for _, name := range s.cookies {
+		for _, cookie := range p.cookies {
+			if strings.EqualFold(cookie.Name, name) {
+				return true
+			}
+		}
+	}
+	for _, marker := range s.title {
+		if strings.Contains(title, marker) {
+			return true
+		}
+	}
+	for _, marker := range s.body {
+		if strings.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+// Package webinfo identifies the web application behind a forwarded HTTP
+// port from its front page: the page title, the favicon and the markers of
+// well-known applications such as Grafana or Jenkins.
+package webinfo
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// DefaultTimeout bounds a detection, both pages included
+const DefaultTimeout = 10 * time.Second
+
+const (
+	maxPageSize    = 512 << 10
+	maxFaviconSize = 64 << 10
+	maxRedirects   = 5
+	maxTitleLength = 255
+)
+
+// page is what detection reads from the front page
+type page struct {
+	title     string
+	generator string // <meta name="generator"> or application-name
+	icon      string // href of the first <link rel="icon">
+	body      string
+	header    http.Header
+	cookies   []*http.Cookie
+}
+
+// Detect fetches the front page at baseURL and its favicon. Certificates
+// are not verified, as they name the internal service rather than the
+// forwarded address. Redirects are followed on the same host only.
+func Detect(ctx context.Context, baseURL string) (*models.WebInfo, error) {
+	client := newClient()
+
+	resp, err := get(ctx, client, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", baseURL, err)
+	}
+
+	p := parsePage(body)
+	p.header = resp.Header
+	p.cookies = resp.Cookies()
+
+	now := time.Now()
+	info := &models.WebInfo{
+		App:        identify(p),
+		Title:      p.title,
+		DetectedAt: &now,
+	}
+	if info.App != "" {
+		info.AppName = info.App.Name()
+	}
+
+	// The page's own icon, else the conventional location
+	iconURL, err := resp.Request.URL.Parse("/favicon.ico")
+	if p.icon != "" && !strings.HasPrefix(p.icon, "data:") {
+		iconURL, err = resp.Request.URL.Parse(p.icon)
+	}
+	if err == nil {
+		info.Favicon, info.FaviconType = fetchFavicon(ctx, client, iconURL.String())
+	}
+	return info, nil
+}
+
+func newClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects || req.URL.Host != via[0].URL.Host {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+}
+
+func get(ctx context.Context, client *http.Client, target string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "portfly-webinfo")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	return client.Do(req)
+}
+
+// fetchFavicon returns the icon and its type, or nothing when it is missing,
+// too large or not an image
+func fetchFavicon(ctx context.Context, client *http.Client, target string) ([]byte, string) {
+	resp, err := get(ctx, client, target)
+	if err != nil {
+		return nil, ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, ""
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconSize+1))
+	if err != nil || len(data) == 0 || len(data) > maxFaviconSize {
+		return nil, ""
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		// Servers often send icons as octet-stream or text/plain
+		contentType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, ""
+	}
+	return data, contentType
+}
+
+// parsePage extracts the title, generator and icon link of an HTML page
+func parsePage(body []byte) *page {
+	p := &page{body: string(body)}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	inTitle := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return p
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			attrs := map[string]string{}
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = tokenizer.TagAttr()
+				attrs[string(key)] = string(value)
+			}
+			switch string(name) {
+			case "title":
+				inTitle = p.title == ""
+			case "meta":
+				switch strings.ToLower(attrs["name"]) {
+				case "generator", "application-name":
+					if p.generator == "" {
+						p.generator = attrs["content"]
+					}
+				}
+			case "link":
+				if p.icon == "" && isIconLink(attrs["rel"]) {
+					p.icon = attrs["href"]
+				}
+			}
+		case html.TextToken:
+			if inTitle {
+				p.title = cleanTitle(string(tokenizer.Text()))
+				inTitle = false
+			}
+		case html.EndTagToken:
+			inTitle = false
+		}
+	}
+}
+
+// isIconLink reports whether a rel attribute names a favicon
+func isIconLink(rel string) bool {
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		if value == "icon" {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanTitle collapses whitespace and bounds the length
+func cleanTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if len(title) > maxTitleLength {
+		title = strings.ToValidUTF8(title[:maxTitleLength], "")
+	}
+	return title
+}
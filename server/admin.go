@@ -0,0 +1,135 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/middleware"
+	"github.com/aqz236/port-fly/server/selfstats"
+)
+
+// setupAdminRoutes registers the sensitive operations: backup and restore,
+// pruning, forced reconciliation, profiling, diagnostic bundles and binary
+// upgrades
+func (s *Server) setupAdminRoutes(admin *gin.RouterGroup) {
+	h := s.handlers
+
+	admin.POST("/backup", h.CreateBackup)
+	admin.POST("/restore", h.RestoreBackup)
+	admin.GET("/backups", h.GetBackups)
+	admin.GET("/backups/:name", h.DownloadBackup)
+	admin.GET("/prune", h.GetPruneStatus)
+	admin.POST("/prune", h.PruneData)
+	admin.GET("/reconcile", h.GetReconcileStatus)
+	admin.POST("/reconcile", h.ReconcilePorts)
+	admin.POST("/upgrade", h.UpgradeServer)
+
+	// Profiling and resource use of the running server
+	admin.GET("/stats", h.GetSelfStats)
+	admin.GET("/debug/pprof/*profile", h.Pprof)
+	admin.POST("/debug/pprof/*profile", h.Pprof) // symbol lookups post addresses
+
+	// Support bundles with stacks, recent logs and the redacted configuration
+	admin.POST("/diagnostics", h.CreateDiagnostics)
+	admin.GET("/diagnostics", h.GetDiagnostics)
+	admin.GET("/diagnostics/:name", h.DownloadDiagnostics)
+}
+
+// setupAdminRouter serves the admin API on its own router, keeping it off the
+// network listener. Access is governed by the socket's file permissions.
+func (s *Server) setupAdminRouter() {
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.Logger(s.logger))
+	router.Use(middleware.Version(buildInfo))
+
+	router.GET("/health", s.handlers.Health)
+	s.setupAdminRoutes(router.Group("/api/v1/admin"))
+
+	s.adminRouter = router
+}
+
+// listenAdminSocket listens on a unix socket only the server's user can use.
+// A socket left behind by a server that did not shut down cleanly is
+// replaced; one that still accepts connections is not.
+func listenAdminSocket(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create admin socket directory: %w", err)
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("admin socket %s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("admin socket %s is in use by another server", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale admin socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on admin socket: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict admin socket: %w", err)
+	}
+	return listener, nil
+}
+
+// selfStatsSubsystems attributes goroutines to the subsystems that start
+// them per tunnel, terminal or pooled connection, so ones left behind after
+// their owner went away show up as suspected leaks
+func (s *Server) selfStatsSubsystems() []selfstats.Subsystem {
+	return []selfstats.Subsystem{
+		{
+			Name: "tunnels",
+			Functions: []string{
+				"github.com/aqz236/port-fly/core/ssh.(*TunnelManager)",
+				"github.com/aqz236/port-fly/core/manager.(*SessionManager)",
+			},
+			Owners: func() int {
+				sessions, _ := s.sessionManager.ListSessions()
+				running := 0
+				for _, session := range sessions {
+					switch session.Status {
+					case models.StatusCreated, models.StatusStopped, models.StatusError:
+					default:
+						running++
+					}
+				}
+				return running
+			},
+		},
+		{
+			Name: "terminals",
+			Functions: []string{
+				"github.com/aqz236/port-fly/server/handlers.(*TerminalManager)",
+			},
+			Owners: s.terminalManager.GetSessionCount,
+		},
+		{
+			Name: "pool",
+			Functions: []string{
+				"github.com/aqz236/port-fly/core/ssh.(*ConnectionPool)",
+			},
+			Owners: func() int {
+				total, _ := s.sessionManager.PoolStats()["total"].(int)
+				return total
+			},
+			Idle: 1, // the cleanup loop
+		},
+	}
+}
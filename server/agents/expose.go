@@ -0,0 +1,165 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aqz236/port-fly/core/agent"
+)
+
+var (
+	// ErrStreamNotFound is returned for unknown or expired stream IDs
+	ErrStreamNotFound = errors.New("stream not found")
+)
+
+// streamTimeout bounds how long a client waits for the agent to attach
+const streamTimeout = 10 * time.Second
+
+// defaultExposeBindAddress keeps exposed services local to the server unless
+// the agent asks for a wider bind
+const defaultExposeBindAddress = "127.0.0.1"
+
+// ServiceInfo describes a service an agent exposes through the server
+type ServiceInfo struct {
+	Name        string `json:"name"`
+	Target      string `json:"target"`  // address dialed on the agent
+	Address     string `json:"address"` // where the server listens
+	Connections int64  `json:"connections"`
+}
+
+// exposedService is a server-side listener relaying to an agent
+type exposedService struct {
+	info        ServiceInfo
+	listener    net.Listener
+	connections atomic.Int64
+}
+
+// pendingStream is a client connection waiting for the agent's stream
+type pendingStream struct {
+	agentName string
+	client    net.Conn
+}
+
+// snapshot copies the service info with current counters
+func (s *exposedService) snapshot() ServiceInfo {
+	info := s.info
+	info.Connections = s.connections.Load()
+	return info
+}
+
+// expose starts listening for a service announced by an agent, replacing a
+// previous announcement with the same name
+func (h *Hub) expose(agentConn *connection, requestID string, payload agent.ExposePayload) {
+	if payload.Name == "" || payload.Target == "" {
+		agentConn.send(agent.MsgError, requestID, agent.ErrorPayload{Error: "service name and target are required"})
+		return
+	}
+	bindAddress := payload.BindAddress
+	if bindAddress == "" {
+		bindAddress = defaultExposeBindAddress
+	}
+
+	h.mu.Lock()
+	if previous, exists := agentConn.services[payload.Name]; exists {
+		previous.listener.Close()
+		delete(agentConn.services, payload.Name)
+	}
+	h.mu.Unlock()
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(bindAddress, strconv.Itoa(payload.RemotePort)))
+	if err != nil {
+		h.logger.Warn("Failed to expose agent service", "agent", agentConn.info.Name, "service", payload.Name, "error", err)
+		agentConn.send(agent.MsgError, requestID, agent.ErrorPayload{Error: fmt.Sprintf("failed to listen for %s: %v", payload.Name, err)})
+		return
+	}
+
+	service := &exposedService{
+		info: ServiceInfo{
+			Name:    payload.Name,
+			Target:  payload.Target,
+			Address: listener.Addr().String(),
+		},
+		listener: listener,
+	}
+
+	h.mu.Lock()
+	agentConn.services[payload.Name] = service
+	h.mu.Unlock()
+
+	go h.acceptService(agentConn, service)
+
+	h.logger.Info("Agent service exposed", "agent", agentConn.info.Name, "service", payload.Name, "target", payload.Target, "address", service.info.Address)
+	agentConn.send(agent.MsgExposed, requestID, agent.ExposedPayload{Name: payload.Name, Address: service.info.Address})
+}
+
+// acceptService hands each client of service to the agent until the listener closes
+func (h *Hub) acceptService(agentConn *connection, service *exposedService) {
+	for {
+		client, err := service.listener.Accept()
+		if err != nil {
+			return
+		}
+		service.connections.Add(1)
+
+		streamID := uuid.New().String()
+		h.mu.Lock()
+		h.streams[streamID] = &pendingStream{agentName: agentConn.info.Name, client: client}
+		h.mu.Unlock()
+
+		err = agentConn.send(agent.MsgOpenStream, streamID, agent.OpenStreamPayload{StreamID: streamID, Name: service.info.Name})
+		if err != nil {
+			h.abortStream(streamID)
+			continue
+		}
+		time.AfterFunc(streamTimeout, func() {
+			if h.abortStream(streamID) {
+				h.logger.Warn("Agent did not attach stream in time", "agent", agentConn.info.Name, "service", service.info.Name)
+			}
+		})
+	}
+}
+
+// ClaimStream returns the client waiting on streamID if token is valid for
+// the agent the stream was sent to
+func (h *Hub) ClaimStream(streamID, token string) (net.Conn, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stream, exists := h.streams[streamID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+	if !h.config.Authorize(stream.agentName, token) {
+		return nil, fmt.Errorf("%w for agent %s", ErrUnauthorized, stream.agentName)
+	}
+	delete(h.streams, streamID)
+	return stream.client, nil
+}
+
+// abortStream drops a pending stream and closes its client; reports whether
+// the stream was still pending
+func (h *Hub) abortStream(streamID string) bool {
+	h.mu.Lock()
+	stream, exists := h.streams[streamID]
+	delete(h.streams, streamID)
+	h.mu.Unlock()
+
+	if exists {
+		stream.client.Close()
+	}
+	return exists
+}
+
+// closeServices stops every listener of the agent; the caller holds the hub lock
+func (c *connection) closeServices() {
+	for name, service := range c.services {
+		service.listener.Close()
+		delete(c.services, name)
+	}
+}
@@ -0,0 +1,457 @@
+// Package agents tracks remote agents connected to the server and relays
+// forward requests to them.
+package agents
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"github.com/aqz236/port-fly/core/agent"
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/core/version"
+)
+
+var (
+	// ErrAgentNotFound is returned for unknown or disconnected agents
+	ErrAgentNotFound = errors.New("agent not found")
+	// ErrForwardNotFound is returned for unknown forwards
+	ErrForwardNotFound = errors.New("forward not found")
+	// ErrUnauthorized is returned for tokens not valid for the agent
+	ErrUnauthorized = errors.New("invalid agent token")
+	// ErrIncompatible is returned for agents speaking an unsupported protocol version
+	ErrIncompatible = errors.New("incompatible agent")
+)
+
+// Config configures agent authentication
+type Config struct {
+	Token  string            `json:"token"`  // shared token accepted from any agent name
+	Tokens map[string]string `json:"tokens"` // per-agent tokens keyed by agent name; take precedence over Token
+}
+
+// Enabled reports whether any token is configured
+func (c Config) Enabled() bool {
+	return c.Token != "" || len(c.Tokens) > 0
+}
+
+// Known reports whether token is valid for some agent
+func (c Config) Known(token string) bool {
+	if token == "" {
+		return false
+	}
+	if tokenEqual(token, c.Token) {
+		return true
+	}
+	for _, agentToken := range c.Tokens {
+		if tokenEqual(token, agentToken) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize reports whether token is valid for the named agent. An agent
+// with its own token cannot authenticate with the shared one.
+func (c Config) Authorize(name, token string) bool {
+	if agentToken, exists := c.Tokens[name]; exists {
+		return tokenEqual(token, agentToken)
+	}
+	return token != "" && tokenEqual(token, c.Token)
+}
+
+// ParseTokens parses "name=token,name=token" into per-agent tokens
+func ParseTokens(value string) map[string]string {
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, token, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if found && name != "" && token != "" {
+			tokens[name] = token
+		}
+	}
+	return tokens
+}
+
+// tokenEqual compares tokens in constant time
+func tokenEqual(a, b string) bool {
+	return b != "" && subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// registerTimeout bounds the wait for the register message
+const registerTimeout = 10 * time.Second
+
+// ForwardInfo describes a tunnel running on an agent
+type ForwardInfo struct {
+	ID        string               `json:"id"`
+	HostID    uint                 `json:"host_id"`
+	Tunnel    models.TunnelConfig  `json:"tunnel"`
+	Status    models.SessionStatus `json:"status"`
+	Message   string               `json:"message,omitempty"`
+	Error     string               `json:"error,omitempty"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// AgentInfo describes a connected agent
+type AgentInfo struct {
+	ID            string        `json:"id"`
+	Name          string        `json:"name"`
+	Hostname      string        `json:"hostname"`
+	OS            string        `json:"os"`
+	Version       string        `json:"version"`
+	Protocol      int           `json:"protocol_version"`
+	VersionSkew   string        `json:"version_skew,omitempty"` // warning when the agent is from another release than the server
+	RemoteAddr    string        `json:"remote_addr"`
+	ConnectedAt   time.Time     `json:"connected_at"`
+	LastHeartbeat time.Time     `json:"last_heartbeat"`
+	Forwards      []ForwardInfo `json:"forwards"`
+	Services      []ServiceInfo `json:"services"`
+}
+
+// connection is one registered agent
+type connection struct {
+	info AgentInfo
+	conn *websocket.Conn
+
+	writeMu  sync.Mutex
+	forwards map[string]*ForwardInfo
+	services map[string]*exposedService
+}
+
+// Hub tracks connected agents. Agents are keyed by name, so a reconnecting
+// agent replaces its previous connection and keeps its forwards.
+type Hub struct {
+	config        Config
+	serverVersion string
+	logger        utils.Logger
+
+	mu      sync.RWMutex
+	agents  map[string]*connection    // agent name -> connection
+	streams map[string]*pendingStream // stream ID -> client waiting for the agent
+}
+
+// NewHub creates an empty hub
+func NewHub(config Config, logger utils.Logger) *Hub {
+	return &Hub{
+		config:  config,
+		logger:  logger,
+		agents:  make(map[string]*connection),
+		streams: make(map[string]*pendingStream),
+	}
+}
+
+// SetServerVersion sets the server version agents are told and compared with
+func (h *Hub) SetServerVersion(serverVersion string) {
+	h.serverVersion = serverVersion
+}
+
+// Known reports whether token is valid for some agent; used to reject
+// connections before upgrading them
+func (h *Hub) Known(token string) bool {
+	return h.config.Known(token)
+}
+
+// Serve registers an agent on conn and processes its messages until the
+// connection closes or the agent misses its heartbeats
+func (h *Hub) Serve(conn *websocket.Conn, remoteAddr, token string) error {
+	agentConn, err := h.register(conn, remoteAddr, token)
+	if err != nil {
+		failure := agent.ErrorPayload{Error: err.Error()}
+		if errors.Is(err, ErrIncompatible) {
+			failure.Code = agent.ErrorIncompatible
+		}
+		agentConn = &connection{conn: conn}
+		agentConn.send(agent.MsgError, "", failure)
+		return err
+	}
+	defer h.unregister(agentConn)
+
+	done := make(chan struct{})
+	defer close(done)
+	go agentConn.heartbeat(done)
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(3 * agent.HeartbeatInterval))
+		var msg agent.Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return nil
+		}
+
+		h.mu.Lock()
+		agentConn.info.LastHeartbeat = time.Now()
+		h.mu.Unlock()
+
+		h.handle(agentConn, msg)
+	}
+}
+
+// register performs the handshake and adds the agent to the hub
+func (h *Hub) register(conn *websocket.Conn, remoteAddr, token string) (*connection, error) {
+	conn.SetReadDeadline(time.Now().Add(registerTimeout))
+	var msg agent.Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		return nil, fmt.Errorf("failed to read register message: %w", err)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	if msg.Type != agent.MsgRegister {
+		return nil, fmt.Errorf("expected %s message, got %s", agent.MsgRegister, msg.Type)
+	}
+	var payload agent.RegisterPayload
+	if err := msg.Decode(&payload); err != nil {
+		return nil, err
+	}
+	if payload.Name == "" {
+		return nil, errors.New("agent name is required")
+	}
+	if !h.config.Authorize(payload.Name, token) {
+		return nil, fmt.Errorf("%w for agent %s", ErrUnauthorized, payload.Name)
+	}
+	switch {
+	case payload.ProtocolVersion < agent.MinProtocolVersion:
+		return nil, fmt.Errorf("%w: agent %s (%s) speaks protocol version %d, the server (%s) needs at least %d; upgrade the agent",
+			ErrIncompatible, payload.Name, payload.Version, payload.ProtocolVersion, h.serverVersion, agent.MinProtocolVersion)
+	case payload.ProtocolVersion > agent.ProtocolVersion:
+		return nil, fmt.Errorf("%w: agent %s (%s) speaks protocol version %d, the server (%s) at most %d; upgrade the server",
+			ErrIncompatible, payload.Name, payload.Version, payload.ProtocolVersion, h.serverVersion, agent.ProtocolVersion)
+	}
+	skew := version.Skew("server", h.serverVersion, "agent", payload.Version)
+
+	agentConn := &connection{
+		info: AgentInfo{
+			ID:            uuid.New().String(),
+			Name:          payload.Name,
+			Hostname:      payload.Hostname,
+			OS:            payload.OS,
+			Version:       payload.Version,
+			Protocol:      payload.ProtocolVersion,
+			VersionSkew:   skew,
+			RemoteAddr:    remoteAddr,
+			ConnectedAt:   time.Now(),
+			LastHeartbeat: time.Now(),
+		},
+		conn:     conn,
+		forwards: make(map[string]*ForwardInfo),
+		services: make(map[string]*exposedService),
+	}
+
+	h.mu.Lock()
+	previous, replaced := h.agents[payload.Name]
+	if replaced {
+		// Forwards keep running on the agent; it re-reports them after
+		// registering and re-exposes its services on the new connection
+		agentConn.forwards = previous.forwards
+		previous.closeServices()
+	}
+	h.agents[payload.Name] = agentConn
+	h.mu.Unlock()
+
+	if replaced {
+		previous.conn.Close()
+	}
+	registered := agent.RegisteredPayload{
+		AgentID:         agentConn.info.ID,
+		ServerVersion:   h.serverVersion,
+		ProtocolVersion: agent.ProtocolVersion,
+	}
+	if err := agentConn.send(agent.MsgRegistered, msg.RequestID, registered); err != nil {
+		h.unregister(agentConn)
+		return nil, err
+	}
+
+	h.logger.Info("Agent connected", "agent", payload.Name, "agent_id", agentConn.info.ID, "remote_addr", remoteAddr, "replaced", replaced)
+	if skew != "" {
+		h.logger.Warn("Agent version differs from the server", "agent", payload.Name, "agent_version", payload.Version, "server_version", h.serverVersion)
+	}
+	return agentConn, nil
+}
+
+// unregister removes agentConn unless a newer connection replaced it
+func (h *Hub) unregister(agentConn *connection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.agents[agentConn.info.Name] == agentConn {
+		delete(h.agents, agentConn.info.Name)
+		agentConn.closeServices()
+		h.logger.Info("Agent disconnected", "agent", agentConn.info.Name, "agent_id", agentConn.info.ID)
+	}
+}
+
+// handle processes an agent message
+func (h *Hub) handle(agentConn *connection, msg agent.Message) {
+	switch msg.Type {
+	case agent.MsgForwardStatus:
+		var payload agent.ForwardStatusPayload
+		if err := msg.Decode(&payload); err != nil {
+			h.logger.Warn("Invalid agent message", "agent", agentConn.info.Name, "error", err)
+			return
+		}
+
+		h.mu.Lock()
+		if forward, exists := agentConn.forwards[payload.ForwardID]; exists {
+			forward.Status = payload.Status
+			forward.Message = payload.Message
+			forward.Error = payload.Error
+			forward.UpdatedAt = payload.Timestamp
+		}
+		h.mu.Unlock()
+	case agent.MsgExpose:
+		var payload agent.ExposePayload
+		if err := msg.Decode(&payload); err != nil {
+			agentConn.send(agent.MsgError, msg.RequestID, agent.ErrorPayload{Error: err.Error()})
+			return
+		}
+		h.expose(agentConn, msg.RequestID, payload)
+	case agent.MsgHeartbeat:
+	case agent.MsgError:
+		var payload agent.ErrorPayload
+		msg.Decode(&payload)
+		if h.abortStream(msg.RequestID) {
+			h.logger.Warn("Agent could not reach exposed service", "agent", agentConn.info.Name, "stream_id", msg.RequestID, "error", payload.Error)
+			return
+		}
+		h.logger.Warn("Agent reported an error", "agent", agentConn.info.Name, "request_id", msg.RequestID, "error", payload.Error)
+	default:
+		h.logger.Warn("Unsupported agent message", "agent", agentConn.info.Name, "type", msg.Type)
+	}
+}
+
+// List returns connected agents sorted by name
+func (h *Hub) List() []AgentInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	agents := make([]AgentInfo, 0, len(h.agents))
+	for _, agentConn := range h.agents {
+		agents = append(agents, agentConn.snapshot())
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	return agents
+}
+
+// Get returns an agent by ID or name
+func (h *Hub) Get(ref string) (AgentInfo, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	agentConn, err := h.lookup(ref)
+	if err != nil {
+		return AgentInfo{}, err
+	}
+	return agentConn.snapshot(), nil
+}
+
+// OpenForward asks an agent to run a tunnel through the given SSH server
+func (h *Hub) OpenForward(ref string, hostID uint, sshConfig models.SSHConnectionConfig, tunnel models.TunnelConfig) (ForwardInfo, error) {
+	h.mu.Lock()
+	agentConn, err := h.lookup(ref)
+	if err != nil {
+		h.mu.Unlock()
+		return ForwardInfo{}, err
+	}
+	forward := &ForwardInfo{
+		ID:        uuid.New().String(),
+		HostID:    hostID,
+		Tunnel:    tunnel,
+		Status:    models.StatusCreated,
+		UpdatedAt: time.Now(),
+	}
+	agentConn.forwards[forward.ID] = forward
+	info := *forward
+	h.mu.Unlock()
+
+	err = agentConn.send(agent.MsgOpenForward, forward.ID, agent.OpenForwardPayload{
+		ForwardID: forward.ID,
+		SSH:       sshConfig,
+		Tunnel:    tunnel,
+	})
+	if err != nil {
+		h.mu.Lock()
+		delete(agentConn.forwards, forward.ID)
+		h.mu.Unlock()
+		return ForwardInfo{}, err
+	}
+	return info, nil
+}
+
+// CloseForward asks an agent to stop a tunnel
+func (h *Hub) CloseForward(ref, forwardID string) error {
+	h.mu.Lock()
+	agentConn, err := h.lookup(ref)
+	if err != nil {
+		h.mu.Unlock()
+		return err
+	}
+	if _, exists := agentConn.forwards[forwardID]; !exists {
+		h.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrForwardNotFound, forwardID)
+	}
+	delete(agentConn.forwards, forwardID)
+	h.mu.Unlock()
+
+	return agentConn.send(agent.MsgCloseForward, forwardID, agent.CloseForwardPayload{ForwardID: forwardID})
+}
+
+// lookup finds an agent by ID or name; the caller holds h.mu
+func (h *Hub) lookup(ref string) (*connection, error) {
+	if agentConn, exists := h.agents[ref]; exists {
+		return agentConn, nil
+	}
+	for _, agentConn := range h.agents {
+		if agentConn.info.ID == ref {
+			return agentConn, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, ref)
+}
+
+// snapshot copies the agent info with its forwards; the caller holds the hub lock
+func (c *connection) snapshot() AgentInfo {
+	info := c.info
+	info.Forwards = make([]ForwardInfo, 0, len(c.forwards))
+	for _, forward := range c.forwards {
+		info.Forwards = append(info.Forwards, *forward)
+	}
+	sort.Slice(info.Forwards, func(i, j int) bool { return info.Forwards[i].ID < info.Forwards[j].ID })
+	info.Services = make([]ServiceInfo, 0, len(c.services))
+	for _, service := range c.services {
+		info.Services = append(info.Services, service.snapshot())
+	}
+	sort.Slice(info.Services, func(i, j int) bool { return info.Services[i].Name < info.Services[j].Name })
+	return info
+}
+
+// heartbeat lets the agent detect a dead server until done
+func (c *connection) heartbeat(done <-chan struct{}) {
+	ticker := time.NewTicker(agent.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.send(agent.MsgHeartbeat, "", nil)
+		}
+	}
+}
+
+// send writes a message to the agent
+func (c *connection) send(msgType agent.MessageType, requestID string, payload any) error {
+	msg, err := agent.NewMessage(msgType, requestID, payload)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.conn.WriteJSON(msg); err != nil {
+		return fmt.Errorf("failed to send %s to agent: %w", msgType, err)
+	}
+	return nil
+}
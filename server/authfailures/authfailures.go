@@ -0,0 +1,139 @@
+// Package authfailures records the outcome of SSH authentications per host
+// and raises an event when a host keeps failing, giving early warning of
+// expired keys and changed passwords across a fleet.
+package authfailures
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// DefaultThreshold is the number of consecutive failures raising an alert
+const DefaultThreshold = 3
+
+// watcherBufferSize is how many events a slow watcher may fall behind
+const watcherBufferSize = 32
+
+// Config is the auth failure section of the configuration
+type Config struct {
+	Threshold int `json:"threshold"` // consecutive failures before host.auth_failures; 0 uses DefaultThreshold
+}
+
+// Event is a host crossing the threshold, or recovering after it did
+type Event struct {
+	Type      models.EventType      `json:"type"`
+	Host      models.HostAuthStatus `json:"host"`
+	Threshold int                   `json:"threshold"`
+	Timestamp time.Time             `json:"timestamp"`
+}
+
+// Tracker records authentication attempts and alerts on repeated failures
+type Tracker struct {
+	store     storage.StorageInterface
+	threshold int
+	logger    utils.Logger
+
+	// Serializes updates so concurrent attempts on a host are all counted
+	mu       sync.Mutex
+	watchers map[chan Event]struct{}
+}
+
+// NewTracker creates a tracker saving attempts to store
+func NewTracker(store storage.StorageInterface, config Config, logger utils.Logger) *Tracker {
+	if config.Threshold <= 0 {
+		config.Threshold = DefaultThreshold
+	}
+	return &Tracker{
+		store:     store,
+		threshold: config.Threshold,
+		logger:    logger,
+		watchers:  make(map[chan Event]struct{}),
+	}
+}
+
+// Threshold returns the consecutive failures raising an alert
+func (t *Tracker) Threshold() int {
+	return t.threshold
+}
+
+// Record saves an attempt. Reaching the threshold publishes
+// host.auth_failures; the first success after it publishes host.auth_recovered.
+func (t *Tracker) Record(ctx context.Context, attempt models.AuthAttempt) {
+	// Attempts are recorded even when the connecting request went away
+	ctx = context.WithoutCancel(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status, err := t.store.GetHostAuthStatus(ctx, attempt.HostID)
+	if err != nil {
+		t.logger.Warn("Failed to read host auth status", "host_id", attempt.HostID, "error", err)
+		return
+	}
+	before := status.ConsecutiveFailures
+	status.Apply(attempt)
+	if err := t.store.SaveHostAuthStatus(ctx, status); err != nil {
+		t.logger.Warn("Failed to save host auth status", "host_id", attempt.HostID, "error", err)
+		return
+	}
+
+	switch {
+	case !attempt.Success && before < t.threshold && status.ConsecutiveFailures >= t.threshold:
+		t.logger.Warn("Host keeps failing SSH authentication", "host_id", attempt.HostID,
+			"failures", status.ConsecutiveFailures, "source", attempt.Source, "actor", attempt.Actor, "error", attempt.Error)
+		t.publish(models.EventAuthFailures, *status)
+	case attempt.Success && before >= t.threshold:
+		t.logger.Info("Host authenticates again", "host_id", attempt.HostID, "failures", before)
+		t.publish(models.EventAuthRecovered, *status)
+	}
+}
+
+// Failures lists the hosts whose last attempts failed; projectID and groupID
+// 0 cover all
+func (t *Tracker) Failures(ctx context.Context, projectID, groupID uint) ([]models.HostAuthStatus, error) {
+	return t.store.GetAuthFailures(ctx, projectID, groupID)
+}
+
+// Watch returns a channel of threshold events and a function to stop watching
+func (t *Tracker) Watch() (<-chan Event, func()) {
+	watcher := make(chan Event, watcherBufferSize)
+	t.mu.Lock()
+	t.watchers[watcher] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	return watcher, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.watchers, watcher)
+			t.mu.Unlock()
+		})
+	}
+}
+
+// publish delivers an event to the watchers without blocking. The caller
+// holds t.mu.
+func (t *Tracker) publish(eventType models.EventType, status models.HostAuthStatus) {
+	if host, err := t.store.GetHost(context.Background(), status.HostID); err == nil {
+		status.HostName, status.GroupID = host.Name, host.GroupID
+	}
+	event := Event{Type: eventType, Host: status, Threshold: t.threshold, Timestamp: time.Now()}
+	for watcher := range t.watchers {
+		select {
+		case watcher <- event:
+		default:
+			// Watcher is not keeping up; the failures stay in the host stats
+		}
+	}
+}
+
+// IsAuthFailure reports whether a connection error is a rejected
+// authentication, as opposed to a host that could not be reached
+func IsAuthFailure(err error) bool {
+	return err != nil && models.ClassifyPortError(err).Code == models.PortErrorAuthFailed
+}
@@ -0,0 +1,251 @@
+// Package backup takes scheduled, encrypted snapshots of the server database
+// and restores them, keeping the newest MaxFiles snapshots on disk.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// Snapshot file naming
+const (
+	filePrefix = "portfly-"
+	fileSuffix = ".db.enc"
+	timeLayout = "20060102-150405"
+)
+
+// Defaults applied to zero config fields
+const (
+	DefaultInterval = 24 * time.Hour
+	DefaultPath     = "./data/backups"
+	DefaultMaxFiles = 7
+)
+
+// ErrBackupNotFound is returned for unknown snapshot names
+var ErrBackupNotFound = errors.New("backup not found")
+
+// Config is the backup section of the configuration
+type Config = models.BackupConfig
+
+// Info describes a snapshot on disk
+type Info struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manager creates, prunes and restores snapshots
+type Manager struct {
+	store  storage.Backupper
+	config Config
+	logger utils.Logger
+
+	// Serializes backups and restores so a restore never races a snapshot
+	mu sync.Mutex
+}
+
+// NewManager creates a manager writing snapshots of store to config.Path
+func NewManager(store storage.Backupper, config Config, logger utils.Logger) (*Manager, error) {
+	if config.Passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.Path == "" {
+		config.Path = DefaultPath
+	}
+	if config.MaxFiles <= 0 {
+		config.MaxFiles = DefaultMaxFiles
+	}
+	if err := os.MkdirAll(config.Path, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	return &Manager{store: store, config: config, logger: logger}, nil
+}
+
+// Run takes a snapshot every interval until ctx is done
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	m.logger.Info("scheduled backups started", "interval", m.config.Interval, "path", m.config.Path, "max_files", m.config.MaxFiles)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if info, err := m.Create(ctx); err != nil {
+				m.logger.Error("scheduled backup failed", "error", err)
+			} else {
+				m.logger.Info("scheduled backup created", "name", info.Name, "size", info.Size)
+			}
+		}
+	}
+}
+
+// Create takes an encrypted snapshot and prunes old ones
+func (m *Manager) Create(ctx context.Context) (Info, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The plaintext snapshot only lives next to the backups, readable by us
+	plain, err := os.CreateTemp(m.config.Path, ".snapshot-*.db")
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	plain.Close()
+	os.Remove(plain.Name())
+	defer os.Remove(plain.Name())
+
+	if err := m.store.BackupTo(ctx, plain.Name()); err != nil {
+		return Info{}, err
+	}
+	data, err := os.ReadFile(plain.Name())
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	sealed, err := encrypt(data, m.config.Passphrase)
+	if err != nil {
+		return Info{}, err
+	}
+
+	createdAt := time.Now().UTC()
+	name := filePrefix + createdAt.Format(timeLayout) + fileSuffix
+	path := filepath.Join(m.config.Path, name)
+	// Write then rename so a crash never leaves a truncated backup
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0600); err != nil {
+		return Info{}, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return Info{}, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	m.prune()
+	return Info{Name: name, Size: int64(len(sealed)), CreatedAt: createdAt}, nil
+}
+
+// List returns snapshots on disk, newest first
+func (m *Manager) List() ([]Info, error) {
+	entries, err := os.ReadDir(m.config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var backups []Info
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		fileInfo, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		createdAt, err := time.Parse(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			createdAt = fileInfo.ModTime()
+		}
+		backups = append(backups, Info{Name: name, Size: fileInfo.Size(), CreatedAt: createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// Open returns a snapshot file for download
+func (m *Manager) Open(name string) (*os.File, error) {
+	path, err := m.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Restore replaces the database with the named snapshot
+func (m *Manager) Restore(ctx context.Context, name string) error {
+	path, err := m.path(name)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	return m.restore(ctx, data)
+}
+
+// RestoreFrom replaces the database with an uploaded snapshot
+func (m *Manager) RestoreFrom(ctx context.Context, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	return m.restore(ctx, data)
+}
+
+// restore decrypts data and copies it over the live database
+func (m *Manager) restore(ctx context.Context, data []byte) error {
+	plain, err := decrypt(data, m.config.Passphrase)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.CreateTemp(m.config.Path, ".restore-*.db")
+	if err != nil {
+		return fmt.Errorf("failed to stage backup: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(plain); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stage backup: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to stage backup: %w", err)
+	}
+
+	return m.store.RestoreFrom(ctx, file.Name())
+}
+
+// prune removes the oldest snapshots beyond MaxFiles
+func (m *Manager) prune() {
+	backups, err := m.List()
+	if err != nil {
+		m.logger.Warn("failed to list backups for pruning", "error", err)
+		return
+	}
+	for _, old := range backups[min(len(backups), m.config.MaxFiles):] {
+		if err := os.Remove(filepath.Join(m.config.Path, old.Name)); err != nil {
+			m.logger.Warn("failed to remove old backup", "name", old.Name, "error", err)
+		}
+	}
+}
+
+// path resolves a snapshot name inside the backup directory
+func (m *Manager) path(name string) (string, error) {
+	if name != filepath.Base(name) || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+		return "", fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+	path := filepath.Join(m.config.Path, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrBackupNotFound, name)
+	}
+	return path, nil
+}
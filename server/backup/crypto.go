@@ -0,0 +1,99 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// fileMagic starts every encrypted snapshot and identifies the format version
+var fileMagic = []byte("PORTFLY-BACKUP-1\n")
+
+// Key derivation and AEAD parameters
+const (
+	saltSize = 16
+	keySize  = 32 // AES-256
+
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+var (
+	// ErrNoPassphrase is returned when backups are used without a passphrase
+	ErrNoPassphrase = errors.New("backup passphrase is not configured")
+	// ErrInvalidBackup is returned for files that are not PortFly backups
+	ErrInvalidBackup = errors.New("not a PortFly backup")
+	// ErrDecrypt is returned when the passphrase is wrong or the file was modified
+	ErrDecrypt = errors.New("failed to decrypt backup: wrong passphrase or corrupted file")
+)
+
+// encrypt seals plaintext as magic | salt | nonce | ciphertext
+func encrypt(plaintext []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(fileMagic)+saltSize+len(nonce))
+	header = append(header, fileMagic...)
+	header = append(header, salt...)
+	header = append(header, nonce...)
+
+	out := make([]byte, len(header), len(header)+len(plaintext)+aead.Overhead())
+	copy(out, header)
+	// The header is authenticated so it cannot be swapped between files
+	return aead.Seal(out, nonce, plaintext, header), nil
+}
+
+// decrypt opens a file produced by encrypt
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(data, fileMagic) || len(data) < len(fileMagic)+saltSize {
+		return nil, ErrInvalidBackup
+	}
+	salt := data[len(fileMagic) : len(fileMagic)+saltSize]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	headerSize := len(fileMagic) + saltSize + aead.NonceSize()
+	if len(data) < headerSize+aead.Overhead() {
+		return nil, ErrInvalidBackup
+	}
+	nonce := data[len(fileMagic)+saltSize : headerSize]
+	plaintext, err := aead.Open(nil, nonce, data[headerSize:], data[:headerSize])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// newAEAD derives the AES-GCM cipher for passphrase and salt
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive backup key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,239 @@
+package cloudsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// AWSConfig holds the credentials of an EC2 source; empty fields fall back
+// to the standard AWS_* environment variables. The credentials only need
+// ec2:DescribeInstances.
+type AWSConfig struct {
+	Region          string `json:"region" yaml:"region"`
+	AccessKeyID     string `json:"access_key_id" yaml:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" yaml:"secret_access_key"`
+	SessionToken    string `json:"session_token" yaml:"session_token"`
+	Endpoint        string `json:"endpoint" yaml:"endpoint"` // default https://ec2.<region>.amazonaws.com
+}
+
+// AWSProvider lists EC2 instances with the DescribeInstances Query API
+type AWSProvider struct {
+	config   AWSConfig
+	endpoint string
+	client   *http.Client
+}
+
+// NewAWSProvider creates an EC2 provider
+func NewAWSProvider(config AWSConfig) (*AWSProvider, error) {
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_REGION")
+	}
+	if config.Region == "" {
+		config.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if config.AccessKeyID == "" {
+		config.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		config.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		config.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+
+	if config.Region == "" {
+		return nil, fmt.Errorf("aws region is required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("aws credentials are required")
+	}
+
+	endpoint := strings.TrimSuffix(config.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://ec2.%s.amazonaws.com", config.Region)
+	}
+	return &AWSProvider{
+		config:   config,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: DefaultTimeout},
+	}, nil
+}
+
+// ec2Instances is the part of a DescribeInstances response the sync reads
+type ec2Instances struct {
+	Reservations []struct {
+		Instances []struct {
+			ID        string `xml:"instanceId"`
+			PrivateIP string `xml:"privateIpAddress"`
+			PublicIP  string `xml:"ipAddress"`
+			State     string `xml:"instanceState>name"`
+			Zone      string `xml:"placement>availabilityZone"`
+			Tags      []struct {
+				Key   string `xml:"key"`
+				Value string `xml:"value"`
+			} `xml:"tagSet>item"`
+		} `xml:"instancesSet>item"`
+	} `xml:"reservationSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+// ec2Error is the error body of the Query API
+type ec2Error struct {
+	Code    string `xml:"Errors>Error>Code"`
+	Message string `xml:"Errors>Error>Message"`
+}
+
+// Instances lists the region's instances that are not terminated. The Name
+// tag names the host; tags in the reserved aws: namespace are left out.
+func (a *AWSProvider) Instances(ctx context.Context) ([]Instance, error) {
+	var instances []Instance
+	nextToken := ""
+	for {
+		form := url.Values{
+			"Action":     {"DescribeInstances"},
+			"Version":    {"2016-11-15"},
+			"MaxResults": {"1000"},
+		}
+		// Terminated instances stay listed for about an hour
+		for i, state := range []string{"pending", "running", "stopping", "stopped"} {
+			form.Set("Filter.1.Name", "instance-state-name")
+			form.Set(fmt.Sprintf("Filter.1.Value.%d", i+1), state)
+		}
+		if nextToken != "" {
+			form.Set("NextToken", nextToken)
+		}
+
+		var page ec2Instances
+		if err := a.call(ctx, form, &page); err != nil {
+			return nil, err
+		}
+		for _, reservation := range page.Reservations {
+			for _, item := range reservation.Instances {
+				if item.State == "terminated" || item.State == "shutting-down" {
+					continue
+				}
+				instance := Instance{
+					ID:        item.ID,
+					PrivateIP: item.PrivateIP,
+					PublicIP:  item.PublicIP,
+					Zone:      item.Zone,
+					Tags:      make(map[string]string),
+				}
+				for _, tag := range item.Tags {
+					switch {
+					case tag.Key == "Name":
+						instance.Name = tag.Value
+					case !strings.HasPrefix(tag.Key, "aws:"):
+						instance.Tags[tag.Key] = tag.Value
+					}
+				}
+				instances = append(instances, instance)
+			}
+		}
+
+		if page.NextToken == "" {
+			return instances, nil
+		}
+		nextToken = page.NextToken
+	}
+}
+
+// call posts a signed Query API request and decodes the XML response
+func (a *AWSProvider) call(ctx context.Context, form url.Values, out any) error {
+	body := []byte(form.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create ec2 request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	a.sign(req, body, time.Now().UTC())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ec2 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read ec2 response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr ec2Error
+		xml.Unmarshal(respBody, &apiErr)
+		return fmt.Errorf("ec2 returned %s: %s %s", resp.Status, apiErr.Code, apiErr.Message)
+	}
+	if err := xml.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode ec2 response: %w", err)
+	}
+	return nil
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (a *AWSProvider) sign(req *http.Request, body []byte, now time.Time) {
+	const service = "ec2"
+
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.config.SessionToken)
+	}
+
+	headers := map[string]string{
+		"content-type": req.Header.Get("Content-Type"),
+		"host":         req.URL.Host,
+		"x-amz-date":   amzDate,
+	}
+	names := []string{"content-type", "host", "x-amz-date"}
+	if a.config.SessionToken != "" {
+		headers["x-amz-security-token"] = a.config.SessionToken
+		names = append(names, "x-amz-security-token")
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + a.config.Region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+a.config.SecretAccessKey), date)
+	key = hmacSHA256(key, a.config.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.config.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// hmacSHA256 computes HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
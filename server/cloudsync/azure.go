@@ -0,0 +1,258 @@
+package cloudsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Azure endpoints and API versions
+const (
+	azureManagementEndpoint = "https://management.azure.com"
+	azureAuthorityHost      = "https://login.microsoftonline.com"
+	azureComputeAPIVersion  = "2023-03-01"
+	azureNetworkAPIVersion  = "2023-05-01"
+)
+
+// AzureConfig holds the service principal and subscription of an Azure
+// source; empty fields fall back to the AZURE_* environment variables. The
+// principal only needs the Reader role.
+type AzureConfig struct {
+	TenantID       string `json:"tenant_id" yaml:"tenant_id"`
+	ClientID       string `json:"client_id" yaml:"client_id"`
+	ClientSecret   string `json:"client_secret" yaml:"client_secret"`
+	SubscriptionID string `json:"subscription_id" yaml:"subscription_id"`
+	ResourceGroup  string `json:"resource_group" yaml:"resource_group"` // default: the whole subscription
+	Endpoint       string `json:"endpoint" yaml:"endpoint"`             // default https://management.azure.com
+	AuthorityHost  string `json:"authority_host" yaml:"authority_host"` // default https://login.microsoftonline.com
+}
+
+// AzureProvider lists the virtual machines of a subscription
+type AzureProvider struct {
+	config AzureConfig
+	client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewAzureProvider creates an Azure provider
+func NewAzureProvider(config AzureConfig) (*AzureProvider, error) {
+	if config.TenantID == "" {
+		config.TenantID = os.Getenv("AZURE_TENANT_ID")
+	}
+	if config.ClientID == "" {
+		config.ClientID = os.Getenv("AZURE_CLIENT_ID")
+		config.ClientSecret = os.Getenv("AZURE_CLIENT_SECRET")
+	}
+	if config.SubscriptionID == "" {
+		config.SubscriptionID = os.Getenv("AZURE_SUBSCRIPTION_ID")
+	}
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	if config.Endpoint == "" {
+		config.Endpoint = azureManagementEndpoint
+	}
+	config.AuthorityHost = strings.TrimSuffix(config.AuthorityHost, "/")
+	if config.AuthorityHost == "" {
+		config.AuthorityHost = azureAuthorityHost
+	}
+
+	if config.TenantID == "" || config.ClientID == "" || config.ClientSecret == "" {
+		return nil, fmt.Errorf("azure tenant_id, client_id and client_secret are required")
+	}
+	if config.SubscriptionID == "" {
+		return nil, fmt.Errorf("azure subscription_id is required")
+	}
+	return &AzureProvider{
+		config: config,
+		client: &http.Client{Timeout: DefaultTimeout},
+	}, nil
+}
+
+// azureVM is the part of a virtual machine resource the sync reads
+type azureVM struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Location   string            `json:"location"`
+	Tags       map[string]string `json:"tags"`
+	Zones      []string          `json:"zones"`
+	Properties struct {
+		VMID           string `json:"vmId"`
+		NetworkProfile struct {
+			NetworkInterfaces []struct {
+				ID         string `json:"id"`
+				Properties struct {
+					Primary bool `json:"primary"`
+				} `json:"properties"`
+			} `json:"networkInterfaces"`
+		} `json:"networkProfile"`
+	} `json:"properties"`
+}
+
+// azureNIC is the part of a network interface resource the sync reads
+type azureNIC struct {
+	ID         string `json:"id"`
+	Properties struct {
+		IPConfigurations []struct {
+			Properties struct {
+				Primary          bool   `json:"primary"`
+				PrivateIPAddress string `json:"privateIPAddress"`
+				PublicIPAddress  *struct {
+					ID string `json:"id"`
+				} `json:"publicIPAddress"`
+			} `json:"properties"`
+		} `json:"ipConfigurations"`
+	} `json:"properties"`
+}
+
+// azurePublicIP is the part of a public IP address resource the sync reads
+type azurePublicIP struct {
+	ID         string `json:"id"`
+	Properties struct {
+		IPAddress string `json:"ipAddress"`
+	} `json:"properties"`
+}
+
+// Instances lists the virtual machines with the addresses of their primary
+// network interface. Resource IDs are compared case-insensitively, as Azure
+// does not keep their case consistent across resources.
+func (a *AzureProvider) Instances(ctx context.Context) ([]Instance, error) {
+	var vms []azureVM
+	if err := a.list(ctx, "Microsoft.Compute/virtualMachines", azureComputeAPIVersion, &vms); err != nil {
+		return nil, err
+	}
+	var nics []azureNIC
+	if err := a.list(ctx, "Microsoft.Network/networkInterfaces", azureNetworkAPIVersion, &nics); err != nil {
+		return nil, err
+	}
+	var publicIPs []azurePublicIP
+	if err := a.list(ctx, "Microsoft.Network/publicIPAddresses", azureNetworkAPIVersion, &publicIPs); err != nil {
+		return nil, err
+	}
+
+	nicByID := make(map[string]*azureNIC, len(nics))
+	for i := range nics {
+		nicByID[strings.ToLower(nics[i].ID)] = &nics[i]
+	}
+	publicIPByID := make(map[string]string, len(publicIPs))
+	for _, ip := range publicIPs {
+		publicIPByID[strings.ToLower(ip.ID)] = ip.Properties.IPAddress
+	}
+
+	instances := make([]Instance, 0, len(vms))
+	for _, vm := range vms {
+		instance := Instance{
+			ID:   vm.Properties.VMID,
+			Name: vm.Name,
+			Zone: vm.Location,
+			Tags: vm.Tags,
+		}
+		if instance.ID == "" {
+			instance.ID = strings.ToLower(vm.ID)
+		}
+		if len(vm.Zones) > 0 {
+			instance.Zone += "-" + vm.Zones[0]
+		}
+
+		interfaces := vm.Properties.NetworkProfile.NetworkInterfaces
+		for i, ref := range interfaces {
+			if !ref.Properties.Primary && !(i == 0 && len(interfaces) == 1) {
+				continue
+			}
+			nic := nicByID[strings.ToLower(ref.ID)]
+			if nic == nil {
+				break
+			}
+			for j, config := range nic.Properties.IPConfigurations {
+				if !config.Properties.Primary && j > 0 {
+					continue
+				}
+				instance.PrivateIP = config.Properties.PrivateIPAddress
+				if config.Properties.PublicIPAddress != nil {
+					instance.PublicIP = publicIPByID[strings.ToLower(config.Properties.PublicIPAddress.ID)]
+				}
+			}
+			break
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// list reads every page of a resource type in the subscription or resource
+// group into out, a pointer to a slice
+func (a *AzureProvider) list(ctx context.Context, resourceType, apiVersion string, out any) error {
+	scope := "/subscriptions/" + url.PathEscape(a.config.SubscriptionID)
+	if a.config.ResourceGroup != "" {
+		scope += "/resourceGroups/" + url.PathEscape(a.config.ResourceGroup)
+	}
+	next := fmt.Sprintf("%s%s/providers/%s?api-version=%s", a.config.Endpoint, scope, resourceType, apiVersion)
+
+	var items []json.RawMessage
+	for next != "" {
+		token, err := a.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create azure request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		var page struct {
+			Value    []json.RawMessage `json:"value"`
+			NextLink string            `json:"nextLink"`
+		}
+		if err := doJSON(a.client, req, "azure resource manager", &page); err != nil {
+			return err
+		}
+		items = append(items, page.Value...)
+		next = page.NextLink
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode azure %s: %w", resourceType, err)
+	}
+	return nil
+}
+
+// accessToken returns a cached client-credentials token for Resource
+// Manager, fetching a new one shortly before it expires
+func (a *AzureProvider) accessToken(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Until(a.expires) > time.Minute {
+		return a.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {a.config.ClientID},
+		"client_secret": {a.config.ClientSecret},
+		"scope":         {azureManagementEndpoint + "/.default"},
+	}
+	endpoint := fmt.Sprintf("%s/%s/oauth2/v2.0/token", a.config.AuthorityHost, url.PathEscape(a.config.TenantID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token oauthToken
+	if err := doJSON(a.client, req, "azure token endpoint", &token); err != nil {
+		return "", err
+	}
+	a.token, a.expires = token.AccessToken, time.Now().Add(time.Duration(token.ExpiresIn)*time.Second)
+	return a.token, nil
+}
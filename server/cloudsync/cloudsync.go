@@ -0,0 +1,582 @@
+// Package cloudsync keeps hosts in step with the instances of cloud
+// accounts. Each configured source lists the instances of an AWS EC2 region,
+// a GCP project or an Azure subscription with read-only credentials, creates
+// a host in its group for every new instance, updates the address, name and
+// tags of the hosts it created, and archives (soft-deletes) the hosts of
+// terminated instances. Synced hosts are tagged with the source and the
+// instance ID; every change is written to the host's change history.
+package cloudsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// Defaults applied to zero source fields
+const (
+	DefaultInterval = 15 * time.Minute
+	MinInterval     = time.Minute
+	DefaultTimeout  = 30 * time.Second
+)
+
+// Tag prefixes marking the hosts a source manages
+const (
+	SourceTagPrefix   = "cloud-sync:"
+	InstanceTagPrefix = "instance:"
+)
+
+// Providers
+const (
+	ProviderAWS   = "aws"
+	ProviderGCP   = "gcp"
+	ProviderAzure = "azure"
+)
+
+// Address preferences
+const (
+	AddressPrivate = "private"
+	AddressPublic  = "public"
+)
+
+var (
+	// ErrSourceNotFound is returned for a source name that is not configured
+	ErrSourceNotFound = errors.New("cloud sync source not found")
+	// ErrSyncInProgress is returned when another replica holds the source's lease
+	ErrSyncInProgress = errors.New("source is being synced by another instance")
+)
+
+// Config configures cloud host sync; no sources disables it
+type Config struct {
+	File    string   `json:"file" yaml:"-"` // JSON or YAML file with more sources, e.g. from $PORTFLY_CLOUD_SYNC_FILE
+	Sources []Source `json:"sources" yaml:"sources"`
+}
+
+// Source is a cloud account whose instances are synced into a group
+type Source struct {
+	Name     string            `json:"name" yaml:"name"`         // unique; synced hosts are tagged cloud-sync:<name>
+	Provider string            `json:"provider" yaml:"provider"` // aws, gcp or azure
+	GroupID  uint              `json:"group_id" yaml:"group_id"` // group new hosts are created in
+	Interval time.Duration     `json:"interval" yaml:"interval"` // time between syncs
+	Address  string            `json:"address" yaml:"address"`   // private (default) or public IP as the hostname; falls back to the other
+	Filters  map[string]string `json:"filters" yaml:"filters"`   // only instances with these tags or labels
+
+	// Settings of new hosts; later syncs leave them to the user
+	Username   string `json:"username" yaml:"username"`
+	Port       int    `json:"port" yaml:"port"`               // SSH port, default 22
+	AuthMethod string `json:"auth_method" yaml:"auth_method"` // default key
+	PrivateKey string `json:"private_key" yaml:"private_key"` // key or secret reference, e.g. vault:kv/data/ops#ssh_key
+
+	AWS   *AWSConfig   `json:"aws,omitempty" yaml:"aws,omitempty"`
+	GCP   *GCPConfig   `json:"gcp,omitempty" yaml:"gcp,omitempty"`
+	Azure *AzureConfig `json:"azure,omitempty" yaml:"azure,omitempty"`
+}
+
+// Instance is a virtual machine as listed by a provider
+type Instance struct {
+	ID        string
+	Name      string
+	PrivateIP string
+	PublicIP  string
+	Zone      string
+	Tags      map[string]string
+}
+
+// Provider lists the live instances of a cloud account; terminated
+// instances are left out
+type Provider interface {
+	Instances(ctx context.Context) ([]Instance, error)
+}
+
+// Report describes the outcome of syncing a source
+type Report struct {
+	Source     string    `json:"source"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs float64   `json:"duration_ms"`
+	Instances  int       `json:"instances"` // matching the filters
+	Created    int       `json:"created"`
+	Updated    int       `json:"updated"`
+	Archived   int       `json:"archived"`
+	Unchanged  int       `json:"unchanged"`
+	Skipped    []string  `json:"skipped,omitempty"` // instances not synced and why
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status is a configured source, without credentials, and its last sync
+type Status struct {
+	Name     string            `json:"name"`
+	Provider string            `json:"provider"`
+	GroupID  uint              `json:"group_id"`
+	Interval time.Duration     `json:"interval"`
+	Filters  map[string]string `json:"filters,omitempty"`
+	Last     *Report           `json:"last,omitempty"`
+}
+
+// LoadFile reads sources from a JSON or YAML file
+func LoadFile(path string) ([]Source, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cloud sync file: %w", err)
+	}
+	var file struct {
+		Sources []Source `json:"sources" yaml:"sources"`
+	}
+	// YAML is a superset of JSON
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	return file.Sources, nil
+}
+
+// source is a configured source with its provider client
+type source struct {
+	Source
+	provider Provider
+}
+
+// Syncer runs the configured sources
+type Syncer struct {
+	store   storage.StorageInterface
+	owner   string // lease owner, so only one replica syncs a source at a time
+	logger  utils.Logger
+	sources []*source
+
+	mu   sync.Mutex
+	last map[string]*Report
+	busy map[string]*sync.Mutex
+}
+
+// NewSyncer validates the sources and creates their provider clients. owner
+// identifies this instance for the leases that keep replicas from syncing
+// the same source together.
+func NewSyncer(store storage.StorageInterface, sources []Source, owner string, logger utils.Logger) (*Syncer, error) {
+	s := &Syncer{
+		store:  store,
+		owner:  owner,
+		logger: logger,
+		last:   make(map[string]*Report),
+		busy:   make(map[string]*sync.Mutex),
+	}
+
+	for _, config := range sources {
+		if config.Name == "" {
+			return nil, fmt.Errorf("cloud sync source needs a name")
+		}
+		if _, exists := s.busy[config.Name]; exists {
+			return nil, fmt.Errorf("cloud sync source %q is configured twice", config.Name)
+		}
+		if config.GroupID == 0 {
+			return nil, fmt.Errorf("cloud sync source %q needs a group_id", config.Name)
+		}
+		if config.Interval <= 0 {
+			config.Interval = DefaultInterval
+		}
+		config.Interval = max(config.Interval, MinInterval)
+		switch config.Address {
+		case "":
+			config.Address = AddressPrivate
+		case AddressPrivate, AddressPublic:
+		default:
+			return nil, fmt.Errorf("cloud sync source %q: address must be private or public", config.Name)
+		}
+		if config.Port == 0 {
+			config.Port = 22
+		}
+		if config.AuthMethod == "" {
+			config.AuthMethod = "key"
+		}
+
+		provider, err := newProvider(config)
+		if err != nil {
+			return nil, fmt.Errorf("cloud sync source %q: %w", config.Name, err)
+		}
+		s.sources = append(s.sources, &source{Source: config, provider: provider})
+		s.busy[config.Name] = &sync.Mutex{}
+	}
+	return s, nil
+}
+
+// newProvider creates the client of a source's provider
+func newProvider(config Source) (Provider, error) {
+	switch strings.ToLower(config.Provider) {
+	case ProviderAWS:
+		return NewAWSProvider(valueOrZero(config.AWS))
+	case ProviderGCP:
+		return NewGCPProvider(valueOrZero(config.GCP))
+	case ProviderAzure:
+		return NewAzureProvider(valueOrZero(config.Azure))
+	default:
+		return nil, fmt.Errorf("unknown provider %q, expected aws, gcp or azure", config.Provider)
+	}
+}
+
+func valueOrZero[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
+
+// Enabled reports whether any source is configured
+func (s *Syncer) Enabled() bool {
+	return len(s.sources) > 0
+}
+
+// Status returns the sources and their last sync, by name
+func (s *Syncer) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.sources))
+	for _, src := range s.sources {
+		statuses = append(statuses, Status{
+			Name:     src.Name,
+			Provider: src.Provider,
+			GroupID:  src.GroupID,
+			Interval: src.Interval,
+			Filters:  src.Filters,
+			Last:     s.last[src.Name],
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Run syncs every source at start and then at its interval until ctx is done
+func (s *Syncer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, src := range s.sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runSource(ctx, src)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Syncer) runSource(ctx context.Context, src *source) {
+	ticker := time.NewTicker(src.Interval)
+	defer ticker.Stop()
+
+	s.logger.Info("cloud sync started", "source", src.Name, "provider", src.Provider, "interval", src.Interval)
+	for {
+		report, err := s.sync(ctx, src)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, ErrSyncInProgress):
+		case err != nil:
+			s.logger.Warn("cloud sync failed", "source", src.Name, "error", err)
+		case report.Created+report.Updated+report.Archived > 0:
+			s.logger.Info("cloud sync changed hosts", "source", src.Name,
+				"created", report.Created, "updated", report.Updated, "archived", report.Archived)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync runs a source now
+func (s *Syncer) Sync(ctx context.Context, name string) (Report, error) {
+	for _, src := range s.sources {
+		if src.Name == name {
+			return s.sync(ctx, src)
+		}
+	}
+	return Report{}, fmt.Errorf("%w: %s", ErrSourceNotFound, name)
+}
+
+// sync lists the source's instances and reconciles its hosts with them. The
+// report is returned, and recorded, even when the sync fails part way.
+func (s *Syncer) sync(ctx context.Context, src *source) (Report, error) {
+	busy := s.busy[src.Name]
+	busy.Lock()
+	defer busy.Unlock()
+
+	lease := "cloud-sync:" + src.Name
+	acquired, err := s.store.AcquireLease(ctx, lease, s.owner, src.Interval)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to take sync lease: %w", err)
+	}
+	if !acquired {
+		return Report{}, ErrSyncInProgress
+	}
+
+	start := time.Now()
+	report := Report{Source: src.Name, StartedAt: start.UTC()}
+	err = s.reconcile(ctx, src, &report)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	report.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+	s.mu.Lock()
+	s.last[src.Name] = &report
+	s.mu.Unlock()
+	return report, err
+}
+
+// reconcile creates, updates and archives the source's hosts to match its
+// instances
+func (s *Syncer) reconcile(ctx context.Context, src *source, report *Report) error {
+	group, err := s.store.GetGroup(ctx, src.GroupID)
+	if err != nil {
+		return fmt.Errorf("group %d: %w", src.GroupID, err)
+	}
+
+	listed, err := src.provider.Instances(ctx)
+	if err != nil {
+		return err
+	}
+	var instances []Instance
+	for _, instance := range listed {
+		if matchesFilters(instance, src.Filters) {
+			instances = append(instances, instance)
+		}
+	}
+	report.Instances = len(instances)
+
+	all, err := s.store.GetHosts(ctx)
+	if err != nil {
+		return err
+	}
+	synced := make(map[string]*models.Host)
+	for i := range all {
+		if id := instanceID(&all[i], src.Name); id != "" {
+			synced[id] = &all[i]
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, instance := range instances {
+		seen[instance.ID] = true
+		address := instance.address(src.Address)
+		if address == "" {
+			report.Skipped = append(report.Skipped, instance.ID+": no IP address")
+			continue
+		}
+
+		host, exists := synced[instance.ID]
+		if !exists {
+			if err := s.create(ctx, src, group, instance, address); err != nil {
+				report.Skipped = append(report.Skipped, instance.ID+": "+err.Error())
+				continue
+			}
+			report.Created++
+			continue
+		}
+
+		changed, err := s.update(ctx, src, host, instance, address)
+		switch {
+		case err != nil:
+			report.Skipped = append(report.Skipped, instance.ID+": "+err.Error())
+		case changed:
+			report.Updated++
+		default:
+			report.Unchanged++
+		}
+	}
+
+	// An empty listing more likely means wrong credentials, region or
+	// filters than an account without instances; keep the hosts then
+	if len(instances) == 0 && len(synced) > 0 {
+		report.Skipped = append(report.Skipped, "no instances listed, archiving skipped")
+		return nil
+	}
+	for _, id := range slices.Sorted(maps.Keys(synced)) {
+		if seen[id] {
+			continue
+		}
+		if err := s.archive(ctx, src, synced[id]); err != nil {
+			report.Skipped = append(report.Skipped, id+": "+err.Error())
+			continue
+		}
+		report.Archived++
+	}
+	return nil
+}
+
+// create adds the host of a new instance
+func (s *Syncer) create(ctx context.Context, src *source, group *models.Group, instance Instance, address string) error {
+	if usage, err := s.store.GetProjectUsage(ctx, group.ProjectID); err == nil && usage.HostsExceeded(1) {
+		return fmt.Errorf("%w: project %d allows at most %d hosts", models.ErrQuotaExceeded, group.ProjectID, usage.MaxHosts)
+	}
+
+	host := &models.Host{
+		Name:        instance.hostName(),
+		Hostname:    address,
+		Port:        src.Port,
+		Username:    src.Username,
+		AuthMethod:  src.AuthMethod,
+		PrivateKey:  src.PrivateKey,
+		Description: instance.description(src.Source),
+		Tags:        instance.hostTags(src.Name),
+		GroupID:     group.ID,
+	}
+	if err := s.store.CreateHost(ctx, host); err != nil {
+		return err
+	}
+	s.record(ctx, src, host.ID, models.ChangeCreate, nil, s.fields(host))
+	s.logger.Info("cloud sync created host", "source", src.Name, "instance", instance.ID, "host_id", host.ID, "address", address)
+	return nil
+}
+
+// update brings the synced fields of a host in line with its instance
+func (s *Syncer) update(ctx context.Context, src *source, host *models.Host, instance Instance, address string) (bool, error) {
+	before := s.fields(host)
+	host.Name = instance.hostName()
+	host.Hostname = address
+	host.Description = instance.description(src.Source)
+	host.Tags = instance.hostTags(src.Name)
+	after := s.fields(host)
+	if len(models.DiffFields(models.EntityHost, before, after)) == 0 {
+		return false, nil
+	}
+
+	host.Group = models.Group{} // saved by ID, not through the preloaded association
+	if err := s.store.UpdateHost(ctx, host); err != nil {
+		return false, err
+	}
+	s.record(ctx, src, host.ID, models.ChangeUpdate, before, after)
+	return true, nil
+}
+
+// archive soft-deletes the host of a terminated instance; it can be brought
+// back from its change history
+func (s *Syncer) archive(ctx context.Context, src *source, host *models.Host) error {
+	before := s.fields(host)
+	if err := s.store.DeleteHost(ctx, host.ID); err != nil {
+		return err
+	}
+	s.record(ctx, src, host.ID, models.ChangeDelete, before, nil)
+	s.logger.Info("cloud sync archived host of terminated instance", "source", src.Name, "host_id", host.ID, "name", host.Name)
+	return nil
+}
+
+// fields returns the host's fields for the change history
+func (s *Syncer) fields(host *models.Host) map[string]any {
+	fields, err := models.HistoryFields(models.EntityHost, host)
+	if err != nil {
+		s.logger.Warn("Failed to read fields for change history", "entity", models.EntityHost, "error", err)
+	}
+	return fields
+}
+
+// record writes a change made by the sync to the host's history; failing to
+// is only logged
+func (s *Syncer) record(ctx context.Context, src *source, id uint, action string, before, after map[string]any) {
+	record := &models.ChangeRecord{
+		EntityType: models.EntityHost,
+		EntityID:   id,
+		Action:     action,
+		Actor:      SourceTagPrefix + src.Name,
+	}
+	switch action {
+	case models.ChangeCreate:
+		record.Snapshot = models.RedactFields(models.EntityHost, after)
+	case models.ChangeDelete:
+		record.Snapshot = models.RedactFields(models.EntityHost, before)
+	default:
+		record.Changes = models.DiffFields(models.EntityHost, before, after)
+		record.Snapshot = models.RedactFields(models.EntityHost, after)
+	}
+	if err := s.store.CreateChangeRecord(ctx, record); err != nil {
+		s.logger.Warn("Failed to record change", "entity", models.EntityHost, "id", id, "action", action, "error", err)
+	}
+}
+
+// instanceID returns the instance a host was synced from by the source, or
+// "" for other hosts
+func instanceID(host *models.Host, sourceName string) string {
+	var id string
+	fromSource := false
+	for _, tag := range host.Tags {
+		if tag == SourceTagPrefix+sourceName {
+			fromSource = true
+		} else if value, ok := strings.CutPrefix(tag, InstanceTagPrefix); ok {
+			id = value
+		}
+	}
+	if !fromSource {
+		return ""
+	}
+	return id
+}
+
+// matchesFilters reports whether the instance has every filter tag; a filter
+// value of "*" only requires the tag
+func matchesFilters(instance Instance, filters map[string]string) bool {
+	for key, want := range filters {
+		value, ok := instance.Tags[key]
+		if !ok || (want != "*" && value != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// address returns the IP the host connects to, preferring the configured kind
+func (i Instance) address(preference string) string {
+	if preference == AddressPublic && i.PublicIP != "" {
+		return i.PublicIP
+	}
+	if i.PrivateIP != "" {
+		return i.PrivateIP
+	}
+	return i.PublicIP
+}
+
+// hostName returns the instance's name, or its ID when unnamed
+func (i Instance) hostName() string {
+	name := i.Name
+	if name == "" {
+		name = i.ID
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return name
+}
+
+// description summarizes where the host comes from
+func (i Instance) description(src Source) string {
+	description := fmt.Sprintf("Synced from %s source %s, instance %s", src.Provider, src.Name, i.ID)
+	if i.Zone != "" {
+		description += " in " + i.Zone
+	}
+	return description
+}
+
+// hostTags returns the tags of a synced host: the source and instance
+// markers, then the instance's tags as key=value
+func (i Instance) hostTags(sourceName string) []string {
+	tags := []string{SourceTagPrefix + sourceName, InstanceTagPrefix + i.ID}
+	for _, key := range slices.Sorted(maps.Keys(i.Tags)) {
+		if value := i.Tags[key]; value != "" {
+			tags = append(tags, key+"="+value)
+		} else {
+			tags = append(tags, key)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,305 @@
+package cloudsync
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Google endpoints; the metadata server gives the token of the VM's service
+// account when no credentials file is configured
+const (
+	gcpComputeEndpoint = "https://compute.googleapis.com"
+	gcpMetadataToken   = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	gcpScope           = "https://www.googleapis.com/auth/compute.readonly"
+)
+
+// GCPConfig holds the project and credentials of a Compute Engine source.
+// The credentials file is a service account key; without one
+// GOOGLE_APPLICATION_CREDENTIALS is used, then the metadata server.
+type GCPConfig struct {
+	Project         string `json:"project" yaml:"project"` // default: the project of the service account
+	CredentialsFile string `json:"credentials_file" yaml:"credentials_file"`
+	Endpoint        string `json:"endpoint" yaml:"endpoint"` // default https://compute.googleapis.com
+}
+
+// gcpServiceAccount is the part of a service account key file the sync reads
+type gcpServiceAccount struct {
+	Type        string `json:"type"`
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// GCPProvider lists Compute Engine instances of a project
+type GCPProvider struct {
+	project  string
+	endpoint string
+	account  *gcpServiceAccount
+	key      *rsa.PrivateKey
+	client   *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewGCPProvider creates a Compute Engine provider
+func NewGCPProvider(config GCPConfig) (*GCPProvider, error) {
+	if config.CredentialsFile == "" {
+		config.CredentialsFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if config.Project == "" {
+		config.Project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+
+	g := &GCPProvider{
+		project:  config.Project,
+		endpoint: strings.TrimSuffix(config.Endpoint, "/"),
+		client:   &http.Client{Timeout: DefaultTimeout},
+	}
+	if g.endpoint == "" {
+		g.endpoint = gcpComputeEndpoint
+	}
+
+	if config.CredentialsFile != "" {
+		data, err := os.ReadFile(config.CredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gcp credentials: %w", err)
+		}
+		var account gcpServiceAccount
+		if err := json.Unmarshal(data, &account); err != nil {
+			return nil, fmt.Errorf("failed to parse gcp credentials: %w", err)
+		}
+		if account.Type != "service_account" || account.ClientEmail == "" || account.PrivateKey == "" {
+			return nil, fmt.Errorf("gcp credentials must be a service account key")
+		}
+		if account.TokenURI == "" {
+			account.TokenURI = "https://oauth2.googleapis.com/token"
+		}
+		key, err := parseRSAKey(account.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("gcp service account key: %w", err)
+		}
+		g.account, g.key = &account, key
+		if g.project == "" {
+			g.project = account.ProjectID
+		}
+	}
+
+	if g.project == "" {
+		return nil, fmt.Errorf("gcp project is required")
+	}
+	return g, nil
+}
+
+// parseRSAKey parses a PEM encoded PKCS#8 or PKCS#1 RSA private key
+func parseRSAKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// gcpInstanceList is a page of an aggregated instance list
+type gcpInstanceList struct {
+	Items map[string]struct {
+		Instances []struct {
+			ID                uint64            `json:"id,string"`
+			Name              string            `json:"name"`
+			Status            string            `json:"status"`
+			Zone              string            `json:"zone"`
+			Labels            map[string]string `json:"labels"`
+			NetworkInterfaces []struct {
+				NetworkIP     string `json:"networkIP"`
+				AccessConfigs []struct {
+					NatIP string `json:"natIP"`
+				} `json:"accessConfigs"`
+			} `json:"networkInterfaces"`
+		} `json:"instances"`
+	} `json:"items"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// Instances lists the project's instances in every zone. Deleted instances
+// are no longer listed; stopped ones (TERMINATED) are kept. Labels become
+// tags; the first network interface gives the addresses.
+func (g *GCPProvider) Instances(ctx context.Context) ([]Instance, error) {
+	var instances []Instance
+	pageToken := ""
+	for {
+		query := url.Values{"maxResults": {"500"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		var page gcpInstanceList
+		endpoint := fmt.Sprintf("%s/compute/v1/projects/%s/aggregated/instances?%s", g.endpoint, url.PathEscape(g.project), query.Encode())
+		if err := g.get(ctx, endpoint, &page); err != nil {
+			return nil, err
+		}
+
+		for _, scope := range page.Items {
+			for _, item := range scope.Instances {
+				instance := Instance{
+					ID:   fmt.Sprint(item.ID),
+					Name: item.Name,
+					Zone: path.Base(item.Zone),
+					Tags: item.Labels,
+				}
+				if len(item.NetworkInterfaces) > 0 {
+					nic := item.NetworkInterfaces[0]
+					instance.PrivateIP = nic.NetworkIP
+					for _, access := range nic.AccessConfigs {
+						if access.NatIP != "" {
+							instance.PublicIP = access.NatIP
+							break
+						}
+					}
+				}
+				instances = append(instances, instance)
+			}
+		}
+
+		if page.NextPageToken == "" {
+			return instances, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// get sends an authorized GET request and decodes the JSON response
+func (g *GCPProvider) get(ctx context.Context, endpoint string, out any) error {
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create compute request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return doJSON(g.client, req, "compute", out)
+}
+
+// accessToken returns a cached OAuth token, fetching a new one shortly
+// before it expires
+func (g *GCPProvider) accessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Until(g.expires) > time.Minute {
+		return g.token, nil
+	}
+
+	var req *http.Request
+	var err error
+	if g.account != nil {
+		assertion, signErr := g.assertion(time.Now())
+		if signErr != nil {
+			return "", signErr
+		}
+		form := url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, g.account.TokenURI, strings.NewReader(form.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataToken, nil)
+		if err == nil {
+			req.Header.Set("Metadata-Flavor", "Google")
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+
+	var token oauthToken
+	if err := doJSON(g.client, req, "gcp token endpoint", &token); err != nil {
+		return "", err
+	}
+	g.token, g.expires = token.AccessToken, time.Now().Add(time.Duration(token.ExpiresIn)*time.Second)
+	return g.token, nil
+}
+
+// assertion signs the JWT exchanged for an access token
+func (g *GCPProvider) assertion(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   g.account.ClientEmail,
+		"scope": gcpScope,
+		"aud":   g.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, g.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// oauthToken is an OAuth 2.0 token response
+type oauthToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// doJSON sends req and decodes a JSON response; service names the API in errors
+func doJSON(client *http.Client, req *http.Request, service string, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", service, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", service, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s: %s", service, resp.Status, truncate(strings.TrimSpace(string(body)), 300))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", service, err)
+	}
+	return nil
+}
+
+// truncate shortens s to at most n bytes for error messages
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
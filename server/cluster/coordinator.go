@@ -0,0 +1,259 @@
+// Package cluster coordinates server replicas that share a database, so each
+// tunnel is owned by exactly one instance and fails over when its owner dies.
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// DefaultLeaseTTL is how long an instance owns a resource without renewing
+const DefaultLeaseTTL = 30 * time.Second
+
+// Config configures instance coordination
+type Config struct {
+	Enabled    bool          `json:"enabled"`
+	InstanceID string        `json:"instance_id"` // defaults to <hostname>-<random>
+	LeaseTTL   time.Duration `json:"lease_ttl"`   // failover delay after an instance dies
+}
+
+// ResourceHandler is called when ownership of a resource changes
+type ResourceHandler func(ctx context.Context, resource string)
+
+// Coordinator claims and renews leases for the resources this instance
+// should run. Watched resources are claimed whenever they are free, which is
+// how a surviving instance takes over after the owner stops renewing.
+type Coordinator struct {
+	store      storage.StorageInterface
+	instanceID string
+	ttl        time.Duration
+	logger     utils.Logger
+
+	mu      sync.Mutex
+	watched map[string]bool
+	owned   map[string]bool
+
+	onAcquired ResourceHandler
+	onLost     ResourceHandler
+}
+
+// Status describes this instance and the leases it holds
+type Status struct {
+	InstanceID string   `json:"instance_id"`
+	LeaseTTL   string   `json:"lease_ttl"`
+	Watched    []string `json:"watched"`
+	Owned      []string `json:"owned"`
+}
+
+// NewCoordinator creates a coordinator; call Run to start claiming leases
+func NewCoordinator(store storage.StorageInterface, config Config, logger utils.Logger) *Coordinator {
+	if config.InstanceID == "" {
+		config.InstanceID = defaultInstanceID()
+	}
+	if config.LeaseTTL <= 0 {
+		config.LeaseTTL = DefaultLeaseTTL
+	}
+
+	// The presence lease tells other instances this one is alive
+	return &Coordinator{
+		store:      store,
+		instanceID: config.InstanceID,
+		ttl:        config.LeaseTTL,
+		logger:     logger,
+		watched:    map[string]bool{InstanceResource(config.InstanceID): true},
+		owned:      make(map[string]bool),
+	}
+}
+
+// InstanceID returns the ID this instance holds leases under
+func (c *Coordinator) InstanceID() string {
+	return c.instanceID
+}
+
+// OnAcquired sets the handler run when this instance becomes owner of a resource
+func (c *Coordinator) OnAcquired(handler ResourceHandler) {
+	c.onAcquired = handler
+}
+
+// OnLost sets the handler run when this instance loses or releases a resource
+func (c *Coordinator) OnLost(handler ResourceHandler) {
+	c.onLost = handler
+}
+
+// Watch asks the coordinator to own resource whenever no other instance does
+func (c *Coordinator) Watch(resource string) {
+	c.mu.Lock()
+	c.watched[resource] = true
+	c.mu.Unlock()
+}
+
+// Unwatch stops competing for resource and releases it if owned
+func (c *Coordinator) Unwatch(ctx context.Context, resource string) {
+	c.mu.Lock()
+	delete(c.watched, resource)
+	c.mu.Unlock()
+
+	c.release(ctx, resource)
+}
+
+// Owns reports whether this instance currently holds the lease for resource
+func (c *Coordinator) Owns(resource string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.owned[resource]
+}
+
+// Status returns the watched and owned resources
+func (c *Coordinator) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Status{
+		InstanceID: c.instanceID,
+		LeaseTTL:   c.ttl.String(),
+		Watched:    sortedKeys(c.watched),
+		Owned:      sortedKeys(c.owned),
+	}
+}
+
+// Run claims and renews leases until ctx is done, then releases them so
+// another instance can take over without waiting for expiry
+func (c *Coordinator) Run(ctx context.Context) {
+	// Renew well within the TTL so a slow tick does not lose ownership
+	ticker := time.NewTicker(c.ttl / 3)
+	defer ticker.Stop()
+
+	c.logger.Info("cluster coordination started", "instance_id", c.instanceID, "lease_ttl", c.ttl)
+	c.tick(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.releaseAll()
+			return
+		case <-ticker.C:
+			c.tick(ctx)
+		}
+	}
+}
+
+// tick renews owned leases and tries to claim free watched resources
+func (c *Coordinator) tick(ctx context.Context) {
+	c.mu.Lock()
+	resources := sortedKeys(c.watched)
+	c.mu.Unlock()
+
+	for _, resource := range resources {
+		acquired, err := c.store.AcquireLease(ctx, resource, c.instanceID, c.ttl)
+		if ctx.Err() != nil {
+			// Shutting down; Run releases the leases
+			return
+		}
+		if err != nil {
+			// Keep ownership on transient errors; the lease stays valid until expiry
+			c.logger.Warn("failed to renew lease", "resource", resource, "error", err)
+			continue
+		}
+
+		c.mu.Lock()
+		wasOwned := c.owned[resource]
+		if acquired {
+			c.owned[resource] = true
+		} else {
+			delete(c.owned, resource)
+		}
+		c.mu.Unlock()
+
+		if resource == InstanceResource(c.instanceID) {
+			continue
+		}
+
+		switch {
+		case acquired && !wasOwned:
+			c.logger.Info("lease acquired", "resource", resource, "instance_id", c.instanceID)
+			if c.onAcquired != nil {
+				c.onAcquired(ctx, resource)
+			}
+		case !acquired && wasOwned:
+			c.logger.Warn("lease lost to another instance", "resource", resource, "instance_id", c.instanceID)
+			if c.onLost != nil {
+				c.onLost(ctx, resource)
+			}
+		}
+	}
+}
+
+// release gives up resource if owned
+func (c *Coordinator) release(ctx context.Context, resource string) {
+	c.mu.Lock()
+	owned := c.owned[resource]
+	delete(c.owned, resource)
+	c.mu.Unlock()
+
+	if !owned {
+		return
+	}
+	if c.onLost != nil {
+		c.onLost(ctx, resource)
+	}
+	if err := c.store.ReleaseLease(ctx, resource, c.instanceID); err != nil {
+		c.logger.Warn("failed to release lease", "resource", resource, "error", err)
+	}
+}
+
+// releaseAll releases every owned lease on shutdown
+func (c *Coordinator) releaseAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c.mu.Lock()
+	resources := sortedKeys(c.owned)
+	c.mu.Unlock()
+
+	for _, resource := range resources {
+		c.release(ctx, resource)
+	}
+}
+
+// PortResource is the lease name for a port's tunnel
+func PortResource(portID uint) string {
+	return fmt.Sprintf("port:%d", portID)
+}
+
+// InstanceResource is the lease name an instance holds while it is running
+func InstanceResource(instanceID string) string {
+	return "instance:" + instanceID
+}
+
+// defaultInstanceID combines the hostname with a random suffix so restarts
+// on the same machine do not inherit leases of the previous process
+func defaultInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "portfly"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	return hostname + "-" + hex.EncodeToString(suffix)
+}
+
+// sortedKeys returns the keys of set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/diagnostics"
+	"github.com/aqz236/port-fly/server/selfstats"
+)
+
+// setupDiagnostics writes diagnostic bundles on request, for recovered panics
+// and for the crash of a previous process
+func (s *Server) setupDiagnostics(logStore *utils.LogStore, selfStats *selfstats.Collector) error {
+	collector, err := diagnostics.NewCollector(s.config.Diagnostics, buildInfo, s.config, logStore, s.logger)
+	if err != nil {
+		return err
+	}
+	collector.AddSection("health", func() any {
+		return s.health.Check(context.Background())
+	})
+	collector.AddSection("stats", func() any {
+		return selfStats.Collect()
+	})
+	collector.AddSection("sessions", func() any {
+		sessions, _ := s.sessionManager.ListSessions()
+		return sessions
+	})
+	collector.AddSection("pool", func() any {
+		return s.sessionManager.PoolStats()
+	})
+
+	if err := collector.CatchCrashes(); err != nil {
+		s.logger.Warn("Crashes will not be written as diagnostic bundles", "error", err)
+	}
+	utils.SetPanicHandler(collector.Panic)
+	s.handlers.SetDiagnostics(collector)
+	return nil
+}
+
+// WriteDiagnostics writes a diagnostic bundle without a running server, for
+// when the server cannot start. It holds the configuration and environment,
+// but no logs or server state.
+func WriteDiagnostics(config *Config) (diagnostics.Info, error) {
+	collector, err := diagnostics.NewCollector(config.Diagnostics, buildInfo, config, nil, utils.DiscardLogger())
+	if err != nil {
+		return diagnostics.Info{}, err
+	}
+	return collector.Create(diagnostics.ReasonOffline)
+}
@@ -0,0 +1,405 @@
+// Package diagnostics writes support bundles: zip archives with the stack
+// traces, recent logs, configuration with secrets redacted and version of the
+// server. Bundles are written on request and when the server panics; a crash
+// that kills the process is written at the next start.
+package diagnostics
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/core/version"
+)
+
+// Bundle file naming
+const (
+	filePrefix = "diagnostics-"
+	fileSuffix = ".zip"
+	timeLayout = "20060102-150405"
+	crashFile  = "crash.log"
+)
+
+// Defaults applied to zero config fields
+const (
+	DefaultPath     = "./data/diagnostics"
+	DefaultMaxFiles = 10
+	DefaultLogLimit = 1000
+)
+
+// panicInterval is the least time between bundles for recovered panics, so a
+// handler panicking on every request does not fill the disk
+const panicInterval = time.Minute
+
+// Reasons a bundle was written for
+const (
+	ReasonRequested = "requested"
+	ReasonPanic     = "panic"
+	ReasonCrash     = "crash"
+	ReasonOffline   = "offline"
+)
+
+// ErrBundleNotFound is returned for unknown bundle names
+var ErrBundleNotFound = errors.New("diagnostic bundle not found")
+
+// Config is the diagnostics section of the configuration
+type Config struct {
+	Path     string `json:"path"`      // directory bundles are written to
+	MaxFiles int    `json:"max_files"` // bundles kept; the oldest are removed
+}
+
+// Info describes a bundle on disk
+type Info struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Panic is the panic or crash a bundle was written for
+type Panic struct {
+	Where string `json:"where,omitempty"` // e.g. the request route or job name
+	Value string `json:"value,omitempty"`
+	Stack string `json:"-"` // stack of the panicking goroutine, or the crash output
+}
+
+// Summary is the summary.json of a bundle
+type Summary struct {
+	Reason     string       `json:"reason"`
+	CreatedAt  time.Time    `json:"created_at"`
+	Build      version.Info `json:"build"`
+	Hostname   string       `json:"hostname"`
+	PID        int          `json:"pid"`
+	StartedAt  time.Time    `json:"started_at"`
+	Uptime     string       `json:"uptime"`
+	Goroutines int          `json:"goroutines"`
+	NumCPU     int          `json:"num_cpu"`
+	HeapAlloc  uint64       `json:"heap_alloc"`
+	Sys        uint64       `json:"sys"`
+	NumGC      uint32       `json:"num_gc"`
+	Panic      *Panic       `json:"panic,omitempty"`
+}
+
+// section is extra state added to bundles as <name>.json
+type section struct {
+	name    string
+	collect func() any
+}
+
+// Collector writes, lists and prunes bundles
+type Collector struct {
+	config   Config
+	build    version.Info
+	settings any // configuration, redacted when written
+	logs     *utils.LogStore
+	logger   utils.Logger
+	started  time.Time
+
+	mu        sync.Mutex
+	sections  []section
+	lastPanic time.Time
+}
+
+// NewCollector creates a collector writing bundles to config.Path. settings
+// is the configuration written with secrets redacted; logs, when set, supplies
+// the recent log entries.
+func NewCollector(config Config, build version.Info, settings any, logs *utils.LogStore, logger utils.Logger) (*Collector, error) {
+	if config.Path == "" {
+		config.Path = DefaultPath
+	}
+	if config.MaxFiles <= 0 {
+		config.MaxFiles = DefaultMaxFiles
+	}
+	if err := os.MkdirAll(config.Path, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create diagnostics directory: %w", err)
+	}
+	return &Collector{
+		config:   config,
+		build:    build,
+		settings: settings,
+		logs:     logs,
+		logger:   logger,
+		started:  time.Now(),
+	}, nil
+}
+
+// AddSection adds the state collect returns to every bundle as name.json
+func (c *Collector) AddSection(name string, collect func() any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sections = append(c.sections, section{name: name, collect: collect})
+}
+
+// Create writes a bundle of the server's current state
+func (c *Collector) Create(reason string) (Info, error) {
+	return c.write(reason, nil)
+}
+
+// Panic writes a bundle for a recovered panic, at most one per minute. It
+// fits utils.PanicHandler.
+func (c *Collector) Panic(where string, value any, stack []byte) {
+	c.mu.Lock()
+	if time.Since(c.lastPanic) < panicInterval {
+		c.mu.Unlock()
+		return
+	}
+	c.lastPanic = time.Now()
+	c.mu.Unlock()
+
+	info, err := c.write(ReasonPanic, &Panic{Where: where, Value: fmt.Sprint(value), Stack: string(stack)})
+	if err != nil {
+		c.logger.Error("Failed to write diagnostic bundle for panic", "where", where, "error", err)
+		return
+	}
+	c.logger.Error("Panic recovered, diagnostic bundle written", "where", where, "panic", value, "bundle", info.Path)
+}
+
+// CatchCrashes makes the runtime write the report of a crash that kills the
+// process next to the bundles, and turns the report a previous crash left
+// behind into a bundle
+func (c *Collector) CatchCrashes() error {
+	path := filepath.Join(c.config.Path, crashFile)
+	if report, err := os.ReadFile(path); err == nil && len(strings.TrimSpace(string(report))) > 0 {
+		crash := &Panic{Value: strings.TrimPrefix(firstLine(string(report)), "panic: "), Stack: string(report)}
+		if info, err := c.write(ReasonCrash, crash); err != nil {
+			c.logger.Error("Failed to write diagnostic bundle for previous crash", "error", err)
+		} else {
+			c.logger.Warn("The previous server process crashed, diagnostic bundle written", "bundle", info.Path)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open crash report file: %w", err)
+	}
+	defer file.Close() // SetCrashOutput keeps its own duplicate
+	if err := debug.SetCrashOutput(file, debug.CrashOptions{}); err != nil {
+		return fmt.Errorf("failed to set crash output: %w", err)
+	}
+	return nil
+}
+
+// List returns bundles on disk, newest first
+func (c *Collector) List() ([]Info, error) {
+	entries, err := os.ReadDir(c.config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list diagnostic bundles: %w", err)
+	}
+
+	bundles := []Info{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		fileInfo, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		bundles = append(bundles, Info{
+			Name:      name,
+			Path:      filepath.Join(c.config.Path, name),
+			Size:      fileInfo.Size(),
+			CreatedAt: fileInfo.ModTime().UTC(),
+		})
+	}
+	sort.Slice(bundles, func(i, j int) bool { return bundles[i].Name > bundles[j].Name })
+	return bundles, nil
+}
+
+// Open returns a bundle file for download
+func (c *Collector) Open(name string) (*os.File, error) {
+	if name != filepath.Base(name) || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+		return nil, fmt.Errorf("%w: %s", ErrBundleNotFound, name)
+	}
+	file, err := os.Open(filepath.Join(c.config.Path, name))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrBundleNotFound, name)
+	}
+	return file, nil
+}
+
+// write writes a bundle and prunes old ones
+func (c *Collector) write(reason string, panicked *Panic) (Info, error) {
+	createdAt := time.Now().UTC()
+	name := fmt.Sprintf("%s%s-%s-%d%s", filePrefix, createdAt.Format(timeLayout), reason, os.Getpid(), fileSuffix)
+	path := filepath.Join(c.config.Path, name)
+
+	// Write then rename so a crash never leaves a truncated bundle
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to create diagnostic bundle: %w", err)
+	}
+	archive := zip.NewWriter(file)
+	err = c.writeFiles(archive, reason, createdAt, panicked)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return Info{}, fmt.Errorf("failed to write diagnostic bundle: %w", err)
+	}
+
+	c.prune()
+	info := Info{Name: name, Path: path, CreatedAt: createdAt}
+	if stat, err := os.Stat(path); err == nil {
+		info.Size = stat.Size()
+	}
+	return info, nil
+}
+
+// writeFiles adds the bundle's files to archive. Sections that fail are
+// recorded in errors.txt rather than failing the bundle.
+func (c *Collector) writeFiles(archive *zip.Writer, reason string, createdAt time.Time, panicked *Panic) error {
+	var failures []string
+	add := func(name string, write func(w io.Writer) error) error {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: createdAt})
+		if err != nil {
+			return err
+		}
+		if err := write(w); err != nil {
+			failures = append(failures, name+": "+err.Error())
+		}
+		return nil
+	}
+	addJSON := func(name string, value any) error {
+		return add(name, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(value)
+		})
+	}
+
+	if err := addJSON("summary.json", c.summary(reason, createdAt, panicked)); err != nil {
+		return err
+	}
+	if panicked != nil {
+		err := add("panic.txt", func(w io.Writer) error {
+			if panicked.Where != "" {
+				fmt.Fprintf(w, "where: %s\n", panicked.Where)
+			}
+			_, err := fmt.Fprintf(w, "panic: %s\n\n%s", panicked.Value, panicked.Stack)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := add("goroutines.txt", func(w io.Writer) error {
+		return pprof.Lookup("goroutine").WriteTo(w, 2)
+	}); err != nil {
+		return err
+	}
+	if c.logs != nil {
+		if err := addJSON("logs.json", c.logs.Recent(slog.LevelDebug, DefaultLogLimit)); err != nil {
+			return err
+		}
+	}
+	if c.settings != nil {
+		if err := addJSON("config.json", Redact(c.settings)); err != nil {
+			return err
+		}
+	}
+	if err := addJSON("env.json", redactedEnv()); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	sections := append([]section(nil), c.sections...)
+	c.mu.Unlock()
+	for _, s := range sections {
+		value, err := collectSection(s)
+		if err != nil {
+			failures = append(failures, s.name+".json: "+err.Error())
+			continue
+		}
+		if err := addJSON(s.name+".json", value); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		return add("errors.txt", func(w io.Writer) error {
+			_, err := io.WriteString(w, strings.Join(failures, "\n")+"\n")
+			return err
+		})
+	}
+	return nil
+}
+
+// summary describes the process the bundle was written by
+func (c *Collector) summary(reason string, createdAt time.Time, panicked *Panic) Summary {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	hostname, _ := os.Hostname()
+	return Summary{
+		Reason:     reason,
+		CreatedAt:  createdAt,
+		Build:      c.build,
+		Hostname:   hostname,
+		PID:        os.Getpid(),
+		StartedAt:  c.started,
+		Uptime:     time.Since(c.started).Round(time.Second).String(),
+		Goroutines: runtime.NumGoroutine(),
+		NumCPU:     runtime.NumCPU(),
+		HeapAlloc:  mem.HeapAlloc,
+		Sys:        mem.Sys,
+		NumGC:      mem.NumGC,
+		Panic:      panicked,
+	}
+}
+
+// collectSection runs a section's collector; the state of a server that just
+// panicked may well make it panic too
+func collectSection(s section) (value any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("collecting panicked: %v", recovered)
+		}
+	}()
+	return s.collect(), nil
+}
+
+// prune removes the oldest bundles beyond MaxFiles
+func (c *Collector) prune() {
+	bundles, err := c.List()
+	if err != nil {
+		c.logger.Warn("Failed to list diagnostic bundles for pruning", "error", err)
+		return
+	}
+	for _, old := range bundles[min(len(bundles), c.config.MaxFiles):] {
+		if err := os.Remove(old.Path); err != nil {
+			c.logger.Warn("Failed to remove old diagnostic bundle", "name", old.Name, "error", err)
+		}
+	}
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secret values in bundles
+const Redacted = "[REDACTED]"
+
+// secretKey matches configuration keys and environment variable names that
+// hold secrets
+var secretKey = regexp.MustCompile(`(?i)(password|passphrase|secret|token|private_?key|api_?key|credential|dsn)`)
+
+// urlPassword matches the password of a URL with user info
+var urlPassword = regexp.MustCompile(`(://[^/:@\s]*:)[^/@\s]+@`)
+
+// Redact returns value as generic JSON with the values of secret keys and the
+// passwords in URLs replaced. Values that do not marshal are dropped.
+func Redact(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return map[string]string{"error": "configuration does not marshal: " + err.Error()}
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil
+	}
+	return redactValue(generic)
+}
+
+// redactValue redacts a decoded JSON value in place
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, member := range v {
+			if secretKey.MatchString(key) && !empty(member) {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = redactValue(member)
+		}
+		return v
+	case []any:
+		for i, member := range v {
+			v[i] = redactValue(member)
+		}
+		return v
+	case string:
+		return RedactString(v)
+	default:
+		return v
+	}
+}
+
+// RedactString replaces the passwords of URLs in s
+func RedactString(s string) string {
+	return urlPassword.ReplaceAllString(s, "${1}"+Redacted+"@")
+}
+
+// empty reports whether a secret value is unset, which is worth showing
+func empty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return true // flags such as enabled are not secrets
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// redactedEnv returns the PORTFLY_* environment variables, secrets redacted
+func redactedEnv() map[string]string {
+	env := make(map[string]string)
+	for _, variable := range os.Environ() {
+		name, value, _ := strings.Cut(variable, "=")
+		if !strings.HasPrefix(name, "PORTFLY_") {
+			continue
+		}
+		if secretKey.MatchString(name) && value != "" {
+			value = Redacted
+		}
+		env[name] = RedactString(value)
+	}
+	return env
+}
@@ -0,0 +1,205 @@
+// Package failover keeps ports with redundant hosts on the fastest reachable
+// one: a port starts on the host with the lowest connect latency, and when
+// the host in use stops answering it is switched to the best remaining one.
+// Switches are logged with the port ID, so they appear in the port's logs.
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	coressh "github.com/aqz236/port-fly/core/ssh"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// DefaultInterval is how often the hosts of active ports are checked
+const DefaultInterval = 30 * time.Second
+
+// ErrNoHostReachable is returned when none of a port's hosts answers
+var ErrNoHostReachable = errors.New("no candidate host is reachable")
+
+// HostCandidate is the probe result of one of a port's hosts
+type HostCandidate struct {
+	HostID uint   `json:"host_id"`
+	Name   string `json:"name"`
+	Active bool   `json:"active"` // the host the port currently uses
+	models.CandidateStatus
+}
+
+// Monitor selects and switches the hosts of ports with candidates
+type Monitor struct {
+	store  storage.StorageInterface
+	logger utils.Logger
+}
+
+// NewMonitor creates a failover monitor for store
+func NewMonitor(store storage.StorageInterface, logger utils.Logger) *Monitor {
+	return &Monitor{store: store, logger: logger}
+}
+
+// Probe measures the connect latency of each of the port's hosts, listing
+// the reachable ones fastest first. Hosts reached through a proxy cannot be
+// probed; they follow the reachable hosts, marked healthy without a latency.
+func (m *Monitor) Probe(ctx context.Context, port *models.Port) ([]HostCandidate, error) {
+	var probed, proxied []HostCandidate
+	var endpoints []models.SSHEndpoint
+	byEndpoint := make(map[models.SSHEndpoint]*models.Host)
+
+	for _, id := range port.HostIDs() {
+		host, err := m.store.GetHost(ctx, id)
+		if err != nil {
+			m.logger.Warn("Candidate host not found", "port_id", port.ID, "host_id", id, "error", err)
+			continue
+		}
+		endpoint := models.SSHEndpoint{Host: host.Hostname, Port: host.Port}
+		if host.ProxyCommand != "" || host.ProxyURL != "" {
+			proxied = append(proxied, HostCandidate{
+				HostID:          host.ID,
+				Name:            host.Name,
+				CandidateStatus: models.CandidateStatus{Endpoint: endpoint, Healthy: true, CheckedAt: time.Now()},
+			})
+			continue
+		}
+		if _, exists := byEndpoint[endpoint]; !exists {
+			byEndpoint[endpoint] = host
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	if len(endpoints)+len(proxied) == 0 {
+		return nil, fmt.Errorf("port %d has no hosts", port.ID)
+	}
+
+	for _, status := range coressh.ProbeEndpoints(ctx, endpoints, coressh.CandidateProbeTimeout) {
+		host := byEndpoint[status.Endpoint]
+		probed = append(probed, HostCandidate{HostID: host.ID, Name: host.Name, CandidateStatus: status})
+	}
+
+	// Reachable probed hosts, then proxied hosts, then unreachable ones
+	split := 0
+	for split < len(probed) && probed[split].Healthy {
+		split++
+	}
+	candidates := append(append(probed[:split:split], proxied...), probed[split:]...)
+	for i := range candidates {
+		candidates[i].Active = port.ActiveHostID != nil && *port.ActiveHostID == candidates[i].HostID
+	}
+	return candidates, nil
+}
+
+// Activate selects the fastest reachable host of a port with candidates and
+// records it as the port's active host. Ports without candidates are left
+// alone.
+func (m *Monitor) Activate(ctx context.Context, port *models.Port) error {
+	if len(port.CandidateHostIDs) == 0 {
+		return nil
+	}
+
+	candidates, err := m.Probe(ctx, port)
+	if err != nil {
+		return err
+	}
+	if !candidates[0].Healthy {
+		return fmt.Errorf("%w: %s", ErrNoHostReachable, candidates[0].Error)
+	}
+	if port.ActiveHostID != nil && *port.ActiveHostID == candidates[0].HostID {
+		return nil
+	}
+
+	hostID := candidates[0].HostID
+	port.ActiveHostID = &hostID
+	if err := m.store.UpdatePort(ctx, port); err != nil {
+		return fmt.Errorf("failed to record active host: %w", err)
+	}
+	m.logger.Info("Port host selected",
+		"port_id", port.ID,
+		"host_id", hostID,
+		"host", candidates[0].Name,
+		"latency", candidates[0].Latency)
+	return nil
+}
+
+// Run checks the hosts of active ports every interval until ctx is done
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Check(ctx); err != nil && ctx.Err() == nil {
+			m.logger.Warn("Failed to check port hosts", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check switches each active port with candidates whose host is unreachable
+// to the fastest reachable candidate
+func (m *Monitor) Check(ctx context.Context) error {
+	ports, err := m.store.GetPorts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load ports: %w", err)
+	}
+
+	for i := range ports {
+		port := &ports[i]
+		if !port.IsActive() || len(port.CandidateHostIDs) == 0 {
+			continue
+		}
+		if err := m.check(ctx, port); err != nil {
+			m.logger.Warn("Failed to check port hosts", "port_id", port.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+func (m *Monitor) check(ctx context.Context, port *models.Port) error {
+	candidates, err := m.Probe(ctx, port)
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range candidates {
+		if candidate.Active && candidate.Healthy {
+			return nil
+		}
+	}
+
+	best := candidates[0]
+	if !best.Healthy {
+		message := "no candidate host reachable"
+		if port.StatusMessage == message {
+			return nil
+		}
+		port.StatusMessage = message
+		m.logger.Warn("Port hosts unreachable", "port_id", port.ID, "error", best.Error)
+		return m.store.UpdatePort(ctx, port)
+	}
+
+	var previous uint
+	if port.ActiveHostID != nil {
+		previous = *port.ActiveHostID
+	}
+	port.ActiveHostID = &best.HostID
+	port.StatusMessage = "failed over to host " + best.Name
+	if err := m.store.UpdatePort(ctx, port); err != nil {
+		return err
+	}
+	m.logger.Info("Port failed over",
+		"port_id", port.ID,
+		"from_host_id", previous,
+		"host_id", best.HostID,
+		"host", best.Name,
+		"latency", best.Latency)
+	return nil
+}
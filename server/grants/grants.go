@@ -0,0 +1,253 @@
+// Package grants manages temporary access grants for ports: issuing tokens,
+// checking them when a port starts and, once a grant expires or is revoked,
+// stopping the ports started with it and archiving the grant. Every change is
+// logged with the port ID, so it appears in the port's logs as an audit trail.
+package grants
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// DefaultInterval is how often ended grants are enforced
+const DefaultInterval = 30 * time.Second
+
+// Header carries the grant token on a port start
+const Header = "X-PortFly-Grant"
+
+// Grant check errors
+var (
+	ErrGrantRequired = errors.New("port requires a temporary access grant")
+	ErrGrantInvalid  = errors.New("grant is not valid for this port")
+)
+
+// stoppedMessage is recorded on ports stopped when their grant ended
+const stoppedMessage = "stopped: temporary access grant ended"
+
+// Manager issues, checks and enforces port grants
+type Manager struct {
+	store  storage.StorageInterface
+	logger utils.Logger
+	now    func() time.Time
+
+	onPortStopped func(ctx context.Context, portID uint)
+}
+
+// NewManager creates a grant manager for store
+func NewManager(store storage.StorageInterface, logger utils.Logger) *Manager {
+	return &Manager{store: store, logger: logger, now: time.Now}
+}
+
+// OnPortStopped registers a callback run after a port is stopped because its
+// grant ended, e.g. to withdraw its DNS name
+func (m *Manager) OnPortStopped(fn func(ctx context.Context, portID uint)) {
+	m.onPortStopped = fn
+}
+
+// Issue stores grant with a new token and returns the token, which is not
+// kept and cannot be shown again
+func (m *Manager) Issue(ctx context.Context, grant *models.PortGrant) (string, error) {
+	if grant.StartsAt.IsZero() {
+		grant.StartsAt = m.now()
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate grant token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	grant.TokenHash = hashToken(token)
+
+	if err := m.store.CreatePortGrant(ctx, grant); err != nil {
+		return "", err
+	}
+
+	m.logger.Info("Port grant issued",
+		"port_id", grant.PortID,
+		"grant_id", grant.ID,
+		"grantee", grant.Grantee,
+		"created_by", grant.CreatedBy,
+		"reason", grant.Reason,
+		"expires_at", grant.ExpiresAt)
+	return token, nil
+}
+
+// Revoke ends a grant early and stops the ports started with it
+func (m *Manager) Revoke(ctx context.Context, id uint, by string) (*models.PortGrant, error) {
+	grant, err := m.store.GetPortGrant(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if grant.ArchivedAt != nil || grant.RevokedAt != nil {
+		return grant, nil
+	}
+
+	now := m.now()
+	grant.RevokedAt = &now
+	if err := m.store.UpdatePortGrant(ctx, grant); err != nil {
+		return nil, err
+	}
+	m.logger.Info("Port grant revoked",
+		"port_id", grant.PortID,
+		"grant_id", grant.ID,
+		"grantee", grant.Grantee,
+		"revoked_by", by)
+
+	if err := m.end(ctx, grant); err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// Authorize checks the grant token presented to start port and records its
+// use. It returns nil without a token for ports that do not require a grant.
+func (m *Manager) Authorize(ctx context.Context, port *models.Port, token string) (*models.PortGrant, error) {
+	if token == "" {
+		if port.RequireGrant {
+			return nil, ErrGrantRequired
+		}
+		return nil, nil
+	}
+
+	grant, err := m.store.GetPortGrantByToken(ctx, hashToken(token))
+	now := m.now()
+	if err != nil || grant.PortID != port.ID || !grant.ActiveAt(now) {
+		m.logger.Warn("Port start refused: grant not valid", "port_id", port.ID)
+		return nil, ErrGrantInvalid
+	}
+
+	grant.UsedAt = &now
+	if err := m.store.UpdatePortGrant(ctx, grant); err != nil {
+		return nil, err
+	}
+	m.logger.Info("Port started with grant",
+		"port_id", port.ID,
+		"grant_id", grant.ID,
+		"grantee", grant.Grantee,
+		"expires_at", grant.ExpiresAt)
+	return grant, nil
+}
+
+// Run enforces ended grants every interval until ctx is done
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Enforce(ctx); err != nil && ctx.Err() == nil {
+			m.logger.Warn("Failed to enforce port grants", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Enforce stops the ports started with grants that expired or were revoked
+// and archives those grants
+func (m *Manager) Enforce(ctx context.Context) error {
+	grants, err := m.store.GetPortGrants(ctx, 0, false)
+	if err != nil {
+		return err
+	}
+
+	now := m.now()
+	for i := range grants {
+		if grants[i].Ended(now) {
+			if err := m.end(ctx, &grants[i]); err != nil {
+				m.logger.Warn("Failed to end port grant", "port_id", grants[i].PortID, "grant_id", grants[i].ID, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// end stops the grant's port if the grant started it and no other grant in
+// use still covers it, then archives the grant
+func (m *Manager) end(ctx context.Context, grant *models.PortGrant) error {
+	now := m.now()
+	if grant.UsedAt != nil {
+		covered, err := m.coveredByOtherGrant(ctx, grant, now)
+		if err != nil {
+			return err
+		}
+		if !covered {
+			if err := m.stopPort(ctx, grant); err != nil {
+				return err
+			}
+		}
+	}
+
+	grant.ArchivedAt = &now
+	if err := m.store.UpdatePortGrant(ctx, grant); err != nil {
+		return err
+	}
+	m.logger.Info("Port grant archived",
+		"port_id", grant.PortID,
+		"grant_id", grant.ID,
+		"grantee", grant.Grantee,
+		"revoked", grant.RevokedAt != nil)
+	return nil
+}
+
+// coveredByOtherGrant reports whether another grant that was used to start
+// the port is still active
+func (m *Manager) coveredByOtherGrant(ctx context.Context, grant *models.PortGrant, now time.Time) (bool, error) {
+	grants, err := m.store.GetPortGrants(ctx, grant.PortID, false)
+	if err != nil {
+		return false, err
+	}
+	for i := range grants {
+		other := &grants[i]
+		if other.ID != grant.ID && other.UsedAt != nil && other.ActiveAt(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// stopPort stops the grant's port if it is running
+func (m *Manager) stopPort(ctx context.Context, grant *models.PortGrant) error {
+	port, err := m.store.GetPort(ctx, grant.PortID)
+	if err != nil {
+		return err
+	}
+	if !port.IsActive() {
+		return nil
+	}
+
+	if err := port.TransitionTo(models.PortStatusAvailable, nil); err != nil {
+		return err
+	}
+	port.StatusMessage = stoppedMessage
+	if err := m.store.UpdatePort(ctx, port); err != nil {
+		return err
+	}
+	m.logger.Info("Port stopped: grant ended", "port_id", port.ID, "grant_id", grant.ID, "grantee", grant.Grantee)
+	if m.onPortStopped != nil {
+		m.onPortStopped(ctx, port.ID)
+	}
+	return nil
+}
+
+// hashToken returns the stored form of a grant token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
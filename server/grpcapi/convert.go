@@ -0,0 +1,124 @@
+package grpcapi
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	portflyv1 "github.com/aqz236/port-fly/api/portfly/v1"
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// hostMessage converts a host, leaving out its credentials
+func hostMessage(host *models.Host) *portflyv1.Host {
+	return &portflyv1.Host{
+		Id:            uint32(host.ID),
+		Name:          host.Name,
+		Hostname:      host.Hostname,
+		Port:          int32(host.Port),
+		Username:      host.Username,
+		Description:   host.Description,
+		AuthMethod:    host.AuthMethod,
+		Status:        host.Status,
+		LastConnected: timestamp(host.LastConnected),
+		GroupId:       uint32(host.GroupID),
+		Tags:          host.Tags,
+		CreatedAt:     timestamppb.New(host.CreatedAt),
+		UpdatedAt:     timestamppb.New(host.UpdatedAt),
+	}
+}
+
+func portMessage(port *models.Port) *portflyv1.Port {
+	message := &portflyv1.Port{
+		Id:            uint32(port.ID),
+		Name:          port.Name,
+		Type:          string(port.Type),
+		Port:          int32(port.Port),
+		PortEnd:       int32(port.PortEnd),
+		BindAddress:   port.BindAddress,
+		Description:   port.Description,
+		ServiceType:   string(port.GetServiceType()),
+		Status:        string(port.Status),
+		StatusMessage: port.StatusMessage,
+		LastActive:    timestamp(port.LastActive),
+		AutoStart:     port.AutoStart,
+		GroupId:       uint32(port.GroupID),
+		HostId:        optionalID(port.HostID),
+		TargetPortId:  optionalID(port.TargetPortID),
+		ActiveHostId:  optionalID(port.ActiveHostID),
+		Tags:          port.Tags,
+		CreatedAt:     timestamppb.New(port.CreatedAt),
+		UpdatedAt:     timestamppb.New(port.UpdatedAt),
+	}
+	if port.LastError.Code != "" || port.LastError.Message != "" {
+		message.LastError = &portflyv1.PortError{
+			Code:      string(port.LastError.Code),
+			Message:   port.LastError.Message,
+			Timestamp: timestamp(port.LastError.Timestamp),
+			Retryable: port.LastError.Retryable,
+		}
+	}
+	return message
+}
+
+func sessionMessage(session *models.TunnelSession) *portflyv1.Session {
+	return &portflyv1.Session{
+		Id:              uint32(session.ID),
+		Name:            session.Name,
+		Status:          string(session.Status),
+		StartTime:       timestamp(session.StartTime),
+		EndTime:         timestamp(session.EndTime),
+		ErrorMessage:    session.ErrorMessage,
+		DataTransferred: session.DataTransferred,
+		LocalAddress:    session.LocalAddress,
+		RemoteAddress:   session.RemoteAddress,
+		HostId:          uint32(session.HostID),
+		PortId:          optionalID(session.PortID),
+		CreatedAt:       timestamppb.New(session.CreatedAt),
+		UpdatedAt:       timestamppb.New(session.UpdatedAt),
+	}
+}
+
+func eventMessage(event *models.SessionEvent) *portflyv1.Event {
+	message := &portflyv1.Event{
+		Type:      string(event.Type),
+		SessionId: event.SessionID,
+		Status:    string(event.Status),
+		Message:   event.Message,
+		Error:     event.Error,
+		Attempt:   int32(event.Attempt),
+		Timestamp: timestamppb.New(event.Timestamp),
+	}
+	if event.RetryIn > 0 {
+		message.RetryIn = durationpb.New(event.RetryIn)
+	}
+	if stats := event.Stats; stats != nil {
+		message.Stats = &portflyv1.StatsUpdate{
+			Interval:          durationpb.New(stats.Interval),
+			BytesSent:         stats.BytesSent,
+			BytesReceived:     stats.BytesReceived,
+			SendRate:          stats.SendRate,
+			ReceiveRate:       stats.ReceiveRate,
+			NewConnections:    stats.NewConnections,
+			ActiveConnections: stats.ActiveConnections,
+		}
+	}
+	return message
+}
+
+// timestamp converts an optional time, nil staying unset
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func optionalID(id *uint) *uint32 {
+	if id == nil {
+		return nil
+	}
+	value := uint32(*id)
+	return &value
+}
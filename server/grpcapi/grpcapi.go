@@ -0,0 +1,243 @@
+// Package grpcapi serves the gRPC API defined in api/portfly/v1 on its own
+// listener, next to the REST API. It reads hosts, ports and tunnel sessions
+// from the same storage and streams the session events the /ws endpoint
+// sends, so integrations get typed messages instead of polling JSON.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	portflyv1 "github.com/aqz236/port-fly/api/portfly/v1"
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// DefaultAddress is the listen address when none is configured
+const DefaultAddress = "localhost:9090"
+
+// Config configures the gRPC listener
+type Config struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address"` // host:port to listen on
+}
+
+// EventSource provides the session events to stream
+type EventSource interface {
+	Subscribe() (<-chan models.SessionEvent, func())
+}
+
+// Server serves the PortFly gRPC service
+type Server struct {
+	portflyv1.UnimplementedPortFlyServiceServer
+
+	store  storage.StorageInterface
+	events EventSource
+	config Config
+	logger utils.Logger
+	server *grpc.Server
+}
+
+// NewServer creates a gRPC server for store and events
+func NewServer(store storage.StorageInterface, events EventSource, config Config, logger utils.Logger) *Server {
+	if config.Address == "" {
+		config.Address = DefaultAddress
+	}
+	s := &Server{
+		store:  store,
+		events: events,
+		config: config,
+		logger: logger,
+	}
+	s.server = grpc.NewServer(grpc.ChainUnaryInterceptor(s.logFailures))
+	portflyv1.RegisterPortFlyServiceServer(s.server, s)
+	// Lets grpcurl and similar tools discover the service
+	reflection.Register(s.server)
+	return s
+}
+
+// Address returns the address the server listens on
+func (s *Server) Address() string {
+	return s.config.Address
+}
+
+// Listen opens the listener, so an address in use fails server start-up
+func (s *Server) Listen() (net.Listener, error) {
+	listener, err := net.Listen("tcp", s.config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for gRPC on %s: %w", s.config.Address, err)
+	}
+	return listener, nil
+}
+
+// Serve serves gRPC on listener until Shutdown
+func (s *Server) Serve(listener net.Listener) error {
+	return s.server.Serve(listener)
+}
+
+// Shutdown waits for unary calls to finish and ends open streams; calls
+// still running when ctx is done are cancelled
+func (s *Server) Shutdown(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+}
+
+// logFailures logs calls that failed on the server side; lookups of missing
+// records and invalid requests are the caller's concern
+func (s *Server) logFailures(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if status.Code(err) == codes.Internal {
+		s.logger.Warn("gRPC call failed", "method", info.FullMethod, "error", err)
+	}
+	return resp, err
+}
+
+// ListHosts lists the hosts, optionally of one group
+func (s *Server) ListHosts(ctx context.Context, req *portflyv1.ListHostsRequest) (*portflyv1.ListHostsResponse, error) {
+	var hosts []models.Host
+	var err error
+	if req.GetGroupId() != 0 {
+		hosts, err = s.store.GetHostsByGroup(ctx, uint(req.GetGroupId()))
+	} else {
+		hosts, err = s.store.GetHosts(ctx)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &portflyv1.ListHostsResponse{Hosts: make([]*portflyv1.Host, 0, len(hosts))}
+	for i := range hosts {
+		resp.Hosts = append(resp.Hosts, hostMessage(&hosts[i]))
+	}
+	return resp, nil
+}
+
+// GetHost returns one host
+func (s *Server) GetHost(ctx context.Context, req *portflyv1.GetHostRequest) (*portflyv1.Host, error) {
+	host, err := s.store.GetHost(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "host %d not found", req.GetId())
+	}
+	return hostMessage(host), nil
+}
+
+// ListPorts lists the ports, optionally of one group or host
+func (s *Server) ListPorts(ctx context.Context, req *portflyv1.ListPortsRequest) (*portflyv1.ListPortsResponse, error) {
+	var ports []models.Port
+	var err error
+	switch {
+	case req.GetGroupId() != 0 && req.GetHostId() != 0:
+		return nil, status.Error(codes.InvalidArgument, "group_id and host_id cannot be combined")
+	case req.GetGroupId() != 0:
+		ports, err = s.store.GetPortsByGroup(ctx, uint(req.GetGroupId()))
+	case req.GetHostId() != 0:
+		ports, err = s.store.GetPortsByHost(ctx, uint(req.GetHostId()))
+	default:
+		ports, err = s.store.GetPorts(ctx)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &portflyv1.ListPortsResponse{Ports: make([]*portflyv1.Port, 0, len(ports))}
+	for i := range ports {
+		resp.Ports = append(resp.Ports, portMessage(&ports[i]))
+	}
+	return resp, nil
+}
+
+// GetPort returns one port by ID or name
+func (s *Server) GetPort(ctx context.Context, req *portflyv1.GetPortRequest) (*portflyv1.Port, error) {
+	id := uint(req.GetId())
+	if name := req.GetName(); name != "" {
+		port, err := s.store.GetPortByName(ctx, name, 0)
+		switch {
+		case errors.Is(err, storage.ErrNameNotFound):
+			return nil, status.Error(codes.NotFound, err.Error())
+		case errors.Is(err, storage.ErrAmbiguousName):
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case err != nil:
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		id = port.ID
+	}
+
+	port, err := s.store.GetPort(ctx, id)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "port %d not found", id)
+	}
+	return portMessage(port), nil
+}
+
+// ListSessions lists the tunnel session records
+func (s *Server) ListSessions(ctx context.Context, req *portflyv1.ListSessionsRequest) (*portflyv1.ListSessionsResponse, error) {
+	var sessions []models.TunnelSession
+	var err error
+	if req.GetActiveOnly() {
+		sessions, err = s.store.GetActiveTunnelSessions(ctx)
+	} else {
+		sessions, err = s.store.GetTunnelSessions(ctx)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &portflyv1.ListSessionsResponse{Sessions: make([]*portflyv1.Session, 0, len(sessions))}
+	for i := range sessions {
+		resp.Sessions = append(resp.Sessions, sessionMessage(&sessions[i]))
+	}
+	return resp, nil
+}
+
+// GetSession returns one tunnel session record
+func (s *Server) GetSession(ctx context.Context, req *portflyv1.GetSessionRequest) (*portflyv1.Session, error) {
+	session, err := s.store.GetTunnelSession(ctx, uint(req.GetId()))
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "session %d not found", req.GetId())
+	}
+	return sessionMessage(session), nil
+}
+
+// WatchEvents streams session events until the client goes away or the
+// server shuts down
+func (s *Server) WatchEvents(req *portflyv1.WatchEventsRequest, stream grpc.ServerStreamingServer[portflyv1.Event]) error {
+	types := req.GetTypes()
+
+	events, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if len(types) > 0 && !slices.Contains(types, string(event.Type)) {
+				continue
+			}
+			if err := stream.Send(eventMessage(&event)); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/server/backup"
+	"github.com/aqz236/port-fly/server/reconcile"
+	"github.com/aqz236/port-fly/server/retention"
+)
+
+// RestoreBackupRequest 从备份目录中的快照恢复
+type RestoreBackupRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// PruneRequest 立即清理旧数据；可覆盖配置的保留天数
+type PruneRequest struct {
+	OlderThanDays int `json:"older_than_days"`
+}
+
+// PruneStatus 数据保留配置及最近一次清理结果
+type PruneStatus struct {
+	Config retention.Config  `json:"config"`
+	Last   *retention.Report `json:"last,omitempty"`
+}
+
+// SetBackupManager sets the manager used by the backup and restore endpoints
+func (h *Handlers) SetBackupManager(manager *backup.Manager) {
+	h.backups = manager
+}
+
+// CreateBackup 立即创建一个加密快照
+func (h *Handlers) CreateBackup(c *gin.Context) {
+	if !h.requireBackups(c) {
+		return
+	}
+
+	info, err := h.backups.Create(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("Backup created", "name", info.Name, "size", info.Size)
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    info,
+		Message: "Backup created",
+	})
+}
+
+// GetBackups 列出备份目录中的快照，最新的在前
+func (h *Handlers) GetBackups(c *gin.Context) {
+	if !h.requireBackups(c) {
+		return
+	}
+
+	backups, err := h.backups.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    backups,
+	})
+}
+
+// DownloadBackup 下载加密快照，可用于异地保存
+func (h *Handlers) DownloadBackup(c *gin.Context) {
+	if !h.requireBackups(c) {
+		return
+	}
+
+	file, err := h.backups.Open(c.Param("name"))
+	if err != nil {
+		c.JSON(backupErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	var modTime time.Time
+	if stat, err := file.Stat(); err == nil {
+		modTime = stat.ModTime()
+	}
+	c.Header("Content-Disposition", "attachment; filename="+c.Param("name"))
+	c.Header("Content-Type", "application/octet-stream")
+	http.ServeContent(c.Writer, c.Request, c.Param("name"), modTime, file)
+}
+
+// RestoreBackup 用快照替换当前数据库
+// JSON 请求体 {"name": ...} 从备份目录恢复；application/octet-stream 请求体为上传的快照
+func (h *Handlers) RestoreBackup(c *gin.Context) {
+	if !h.requireBackups(c) {
+		return
+	}
+
+	var err error
+	source := "upload"
+	if c.ContentType() == "application/octet-stream" {
+		err = h.backups.RestoreFrom(c.Request.Context(), c.Request.Body)
+	} else {
+		var req RestoreBackupRequest
+		if bindErr := c.ShouldBindJSON(&req); bindErr != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   bindErr.Error(),
+			})
+			return
+		}
+		source = req.Name
+		err = h.backups.Restore(c.Request.Context(), req.Name)
+	}
+	if err != nil {
+		c.JSON(backupErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	h.logger.Warn("Database restored from backup", "source", source)
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Database restored",
+	})
+}
+
+// SetPruner sets the pruner used by the data retention endpoints
+func (h *Handlers) SetPruner(pruner *retention.Pruner) {
+	h.pruner = pruner
+}
+
+// PruneData 立即删除超过保留期的会话记录和日志文件，返回删除统计
+// 即使后台清理已禁用也会执行
+func (h *Handlers) PruneData(c *gin.Context) {
+	if !h.requirePruner(c) {
+		return
+	}
+
+	var req PruneRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+	if req.OlderThanDays < 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "older_than_days must not be negative",
+		})
+		return
+	}
+
+	maxAge := h.pruner.Config().MaxAge
+	if req.OlderThanDays > 0 {
+		maxAge = time.Duration(req.OlderThanDays) * 24 * time.Hour
+	}
+
+	report, err := h.pruner.Prune(c.Request.Context(), maxAge)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Data:    report,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("Old data pruned", "sessions", report.SessionsRemoved, "log_files", report.LogFilesRemoved, "cutoff", report.Cutoff)
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+		Message: "Old data pruned",
+	})
+}
+
+// GetPruneStatus 获取数据保留配置及最近一次清理结果
+func (h *Handlers) GetPruneStatus(c *gin.Context) {
+	if !h.requirePruner(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: PruneStatus{
+			Config: h.pruner.Config(),
+			Last:   h.pruner.Last(),
+		},
+	})
+}
+
+// SetReconciler sets the reconciler that records forward owners and resets orphans
+func (h *Handlers) SetReconciler(reconciler *reconcile.Reconciler) {
+	h.reconciler = reconciler
+}
+
+// ReconcilePorts 立即重置所有者已退出的转发及其端口，返回处理的孤儿列表
+// 请求体 {"unowned": true} 时同时重置没有任何存活转发支撑的活跃端口
+func (h *Handlers) ReconcilePorts(c *gin.Context) {
+	if !h.requireReconciler(c) {
+		return
+	}
+
+	var options reconcile.Options
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&options); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	report, err := h.reconciler.Reconcile(c.Request.Context(), options)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Data:    report,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("Port states reconciled", "orphans", len(report.Orphans), "unowned", options.Unowned)
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+		Message: "Port states reconciled",
+	})
+}
+
+// GetReconcileStatus 获取最近一次端口状态修复结果
+func (h *Handlers) GetReconcileStatus(c *gin.Context) {
+	if !h.requireReconciler(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    h.reconciler.Last(),
+	})
+}
+
+// requireReconciler 未配置端口状态修复时返回 503
+func (h *Handlers) requireReconciler(c *gin.Context) bool {
+	if h.reconciler == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Port reconciliation is not configured",
+		})
+		return false
+	}
+	return true
+}
+
+// requirePruner 未配置数据保留时返回 503
+func (h *Handlers) requirePruner(c *gin.Context) bool {
+	if h.pruner == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Data retention is not configured",
+		})
+		return false
+	}
+	return true
+}
+
+// requireBackups 未配置备份口令时返回 503
+func (h *Handlers) requireBackups(c *gin.Context) bool {
+	if h.backups == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Backups are not configured; set a backup passphrase",
+		})
+		return false
+	}
+	return true
+}
+
+// backupErrorStatus 将备份错误映射为 HTTP 状态码
+func backupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, backup.ErrBackupNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, backup.ErrInvalidBackup), errors.Is(err, backup.ErrDecrypt):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/aqz236/port-fly/core/agent"
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/agents"
+)
+
+// agentUpgrader 代理连接不经过浏览器，无需检查来源
+var agentUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// CreateAgentForwardRequest 在代理上打开转发的请求
+type CreateAgentForwardRequest struct {
+	HostID uint                `json:"host_id" binding:"required"`
+	Tunnel models.TunnelConfig `json:"tunnel"`
+}
+
+// SetAgentHub sets the hub tracking remote agents
+func (h *Handlers) SetAgentHub(hub *agents.Hub) {
+	h.agentHub = hub
+}
+
+// AgentWebSocketHandler 接受远程代理的出站连接
+func (h *Handlers) AgentWebSocketHandler(c *gin.Context) {
+	if !h.requireAgentHub(c) {
+		return
+	}
+
+	// 先校验令牌是否有效，注册时再校验令牌与代理名称是否匹配
+	token := agentToken(c)
+	if !h.agentHub.Known(token) {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Error:   "Invalid agent token",
+		})
+		return
+	}
+
+	conn, err := agentUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.Error("Failed to upgrade agent connection", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	if err := h.agentHub.Serve(conn, c.ClientIP(), token); err != nil {
+		h.logger.Warn("Agent registration failed", "remote_addr", c.ClientIP(), "error", err)
+	}
+}
+
+// AgentStreamHandler 代理为暴露服务的每个客户端连接建立的数据流
+func (h *Handlers) AgentStreamHandler(c *gin.Context) {
+	if !h.requireAgentHub(c) {
+		return
+	}
+
+	client, err := h.agentHub.ClaimStream(c.Param("streamId"), agentToken(c))
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, agents.ErrUnauthorized) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	conn, err := agentUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		client.Close()
+		h.logger.Error("Failed to upgrade agent stream", "error", err)
+		return
+	}
+
+	agent.Pipe(conn, client)
+}
+
+// GetAgents 获取已连接的代理
+func (h *Handlers) GetAgents(c *gin.Context) {
+	if !h.requireAgentHub(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    h.agentHub.List(),
+	})
+}
+
+// GetAgent 按 ID 或名称获取代理及其转发
+func (h *Handlers) GetAgent(c *gin.Context) {
+	if !h.requireAgentHub(c) {
+		return
+	}
+
+	info, err := h.agentHub.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(agentErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    info,
+	})
+}
+
+// CreateAgentForward 让代理通过指定主机在其本机打开隧道
+func (h *Handlers) CreateAgentForward(c *gin.Context) {
+	if !h.requireAgentHub(c) {
+		return
+	}
+
+	var req CreateAgentForwardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := req.Tunnel.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	host, err := h.storage.GetHost(c.Request.Context(), req.HostID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Host not found",
+		})
+		return
+	}
+	if h.rejectHostInMaintenance(c, "agent forward", host.ID) {
+		return
+	}
+	// 项目带宽配额由代理的会话管理器按项目共享执行
+	h.applyBandwidthQuota(c.Request.Context(), host.Group.ProjectID, &req.Tunnel)
+
+	// 凭据在服务端解析后下发给代理
+	sshConfig, err := h.hostSSHConfig(c.Request.Context(), host)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	forward, err := h.agentHub.OpenForward(c.Param("id"), host.ID, sshConfig, req.Tunnel)
+	if err != nil {
+		c.JSON(agentErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    forward,
+		Message: "Forward requested",
+	})
+}
+
+// DeleteAgentForward 关闭代理上的隧道
+func (h *Handlers) DeleteAgentForward(c *gin.Context) {
+	if !h.requireAgentHub(c) {
+		return
+	}
+
+	if err := h.agentHub.CloseForward(c.Param("id"), c.Param("forwardId")); err != nil {
+		c.JSON(agentErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Forward closed",
+	})
+}
+
+// requireAgentHub 未启用远程代理时返回 503
+func (h *Handlers) requireAgentHub(c *gin.Context) bool {
+	if h.agentHub == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Remote agents are not enabled",
+		})
+		return false
+	}
+	return true
+}
+
+// agentToken 读取 Authorization 头中的代理令牌
+func agentToken(c *gin.Context) string {
+	return strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+}
+
+// agentErrorStatus 将代理错误映射为 HTTP 状态码
+func agentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, agents.ErrAgentNotFound), errors.Is(err, agents.ErrForwardNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadGateway
+	}
+}
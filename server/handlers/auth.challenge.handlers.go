@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/aqz236/port-fly/core/models"
+	sshpkg "github.com/aqz236/port-fly/core/ssh"
+)
+
+// challengeTimeout bounds how long a relayed challenge waits for an answer
+const challengeTimeout = 5 * time.Minute
+
+// eventAuthChallenge is the event type of challenges on the event WebSocket
+const eventAuthChallenge models.EventType = "auth.challenge"
+
+// errChallengeNotFound is returned for challenges that were answered, timed
+// out or never existed
+var errChallengeNotFound = errors.New("authentication challenge not found")
+
+// AuthChallenge 主机 SSH 认证时的键盘交互质询（如一次性密码），转发给发起连接的客户端回答
+type AuthChallenge struct {
+	ID       string `json:"id"`
+	HostID   uint   `json:"host_id"`
+	HostName string `json:"host_name"`
+	Actor    string `json:"actor,omitempty"` // 发起连接的用户，即 X-PortFly-Actor 请求头
+	sshpkg.Challenge
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AnswerChallengeRequest 回答质询，按问题顺序每个问题一个答案
+type AnswerChallengeRequest struct {
+	Answers []string `json:"answers"`
+}
+
+// authChallengeEvent is a challenge as sent on the event WebSocket
+type authChallengeEvent struct {
+	Type      models.EventType `json:"type"`
+	Challenge AuthChallenge    `json:"challenge"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+// challengeRouter holds the challenges waiting for an answer and the event
+// streams watching for new ones
+type challengeRouter struct {
+	mu       sync.Mutex
+	pending  map[string]*pendingChallenge
+	watchers map[chan AuthChallenge]struct{}
+}
+
+// pendingChallenge is a challenge and where its answer goes
+type pendingChallenge struct {
+	challenge AuthChallenge
+	answers   chan []string
+}
+
+func newChallengeRouter() *challengeRouter {
+	return &challengeRouter{
+		pending:  make(map[string]*pendingChallenge),
+		watchers: make(map[chan AuthChallenge]struct{}),
+	}
+}
+
+// challengeScope says whom the challenges of connections made under a
+// context go to
+type challengeScope struct {
+	actor string
+	sink  chan<- AuthChallenge // stream of the requesting client, if it has one
+}
+
+type challengeScopeKey struct{}
+
+// withChallengeScope routes the challenges of connections made under ctx to
+// actor, and to sink when the request streams its progress
+func withChallengeScope(ctx context.Context, actor string, sink chan<- AuthChallenge) context.Context {
+	return context.WithValue(ctx, challengeScopeKey{}, challengeScope{actor: actor, sink: sink})
+}
+
+// ask publishes a challenge and waits for its answer. Event streams are told
+// without waiting; the requesting client's own stream may hold it up.
+func (r *challengeRouter) ask(ctx context.Context, challenge AuthChallenge, sink chan<- AuthChallenge) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, challengeTimeout)
+	defer cancel()
+
+	challenge.ID = uuid.New().String()
+	challenge.ExpiresAt, _ = ctx.Deadline()
+	pending := &pendingChallenge{challenge: challenge, answers: make(chan []string, 1)}
+
+	r.mu.Lock()
+	r.pending[challenge.ID] = pending
+	for watcher := range r.watchers {
+		select {
+		case watcher <- challenge:
+		default:
+		}
+	}
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, challenge.ID)
+		r.mu.Unlock()
+	}()
+
+	if sink != nil {
+		select {
+		case sink <- challenge:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", sshpkg.ErrChallengeUnanswered, ctx.Err())
+		}
+	}
+
+	select {
+	case answers := <-pending.answers:
+		return answers, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %w", sshpkg.ErrChallengeUnanswered, ctx.Err())
+	}
+}
+
+// answer hands the answers to the waiting connection; the first answer wins
+func (r *challengeRouter) answer(id string, answers []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending, exists := r.pending[id]
+	if !exists {
+		return errChallengeNotFound
+	}
+	if len(answers) != len(pending.challenge.Questions) {
+		return fmt.Errorf("challenge has %d questions, got %d answers", len(pending.challenge.Questions), len(answers))
+	}
+	delete(r.pending, id)
+	pending.answers <- answers
+	return nil
+}
+
+// list returns the challenges waiting for an answer
+func (r *challengeRouter) list() []AuthChallenge {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenges := make([]AuthChallenge, 0, len(r.pending))
+	for _, pending := range r.pending {
+		challenges = append(challenges, pending.challenge)
+	}
+	return challenges
+}
+
+// watch delivers new challenges until the returned function is called
+func (r *challengeRouter) watch() (<-chan AuthChallenge, func()) {
+	watcher := make(chan AuthChallenge, 16)
+	r.mu.Lock()
+	r.watchers[watcher] = struct{}{}
+	r.mu.Unlock()
+
+	var once sync.Once
+	return watcher, func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.watchers, watcher)
+			r.mu.Unlock()
+		})
+	}
+}
+
+// relayChallenges lets a host connection ask the requesting client the
+// questions its credentials cannot answer, such as a one-time password
+func (h *Handlers) relayChallenges(client *sshpkg.SSHClient, host *models.Host) {
+	client.SetChallengeHandler(func(ctx context.Context, challenge sshpkg.Challenge) ([]string, error) {
+		scope, _ := ctx.Value(challengeScopeKey{}).(challengeScope)
+		h.logger.Info("Relaying authentication challenge", "host_id", host.ID, "actor", scope.actor, "questions", len(challenge.Questions))
+		return h.challenges.ask(ctx, AuthChallenge{
+			HostID:    host.ID,
+			HostName:  host.Name,
+			Actor:     scope.actor,
+			Challenge: challenge,
+		}, scope.sink)
+	})
+}
+
+// terminalChallenges asks a web terminal's user the host's challenges over
+// the terminal WebSocket: an auth_challenge message, answered by an
+// auth_response message with the challenge id and answers. Connecting runs
+// on the terminal's read loop, so the answer is read here.
+func (tm *TerminalManager) terminalChallenges(session *TerminalSession, host *models.Host) sshpkg.ChallengeHandler {
+	return func(ctx context.Context, challenge sshpkg.Challenge) ([]string, error) {
+		ctx, cancel := context.WithTimeout(ctx, challengeTimeout)
+		defer cancel()
+		// Unblock the read when the handshake gives up
+		stop := context.AfterFunc(ctx, func() {
+			session.WebSocket.SetReadDeadline(time.Now())
+		})
+		defer stop()
+
+		request := AuthChallenge{
+			ID:        uuid.New().String(),
+			HostID:    host.ID,
+			HostName:  host.Name,
+			Challenge: challenge,
+		}
+		request.ExpiresAt, _ = ctx.Deadline()
+		tm.sendMessage(session.WebSocket, "auth_challenge", request)
+
+		for {
+			var msg TerminalMessage
+			if err := session.WebSocket.ReadJSON(&msg); err != nil {
+				if ctx.Err() != nil {
+					return nil, fmt.Errorf("%w: %w", sshpkg.ErrChallengeUnanswered, ctx.Err())
+				}
+				return nil, fmt.Errorf("terminal closed during authentication: %w", err)
+			}
+
+			switch msg.Type {
+			case "auth_response":
+				var response struct {
+					ID      string   `json:"id"`
+					Answers []string `json:"answers"`
+				}
+				if data, err := json.Marshal(msg.Data); err == nil {
+					json.Unmarshal(data, &response)
+				}
+				if response.ID != request.ID {
+					continue
+				}
+				if len(response.Answers) != len(challenge.Questions) {
+					tm.sendError(session.WebSocket, fmt.Sprintf("质询有 %d 个问题，收到 %d 个答案", len(challenge.Questions), len(response.Answers)))
+					continue
+				}
+				return response.Answers, nil
+
+			case "terminal_disconnect":
+				return nil, errors.New("terminal closed during authentication")
+			}
+		}
+	}
+}
+
+// challengeStream routes the challenges of the host connections made for a
+// request to its actor and, when the request streams, to the returned
+// channel for streamResults to send
+func (h *Handlers) challengeStream(c *gin.Context, stream bool) (context.Context, <-chan AuthChallenge) {
+	if !stream {
+		return withChallengeScope(c.Request.Context(), changeActor(c), nil), nil
+	}
+	challenges := make(chan AuthChallenge)
+	return withChallengeScope(c.Request.Context(), changeActor(c), challenges), challenges
+}
+
+// streamResults passes each host's result to fn until all are in, sending
+// the challenges raised meanwhile to the client as challenge events
+func streamResults[T any](c *gin.Context, results <-chan T, challenges <-chan AuthChallenge, fn func(T)) {
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				return
+			}
+			fn(result)
+		case challenge := <-challenges:
+			c.SSEvent("challenge", challenge)
+			c.Writer.Flush()
+		}
+	}
+}
+
+// GetAuthChallenges 列出等待回答的认证质询
+func (h *Handlers) GetAuthChallenges(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    h.challenges.list(),
+	})
+}
+
+// AnswerAuthChallenge 回答认证质询，答案转发给等待中的 SSH 认证
+func (h *Handlers) AnswerAuthChallenge(c *gin.Context) {
+	var request AnswerChallengeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.challenges.answer(c.Param("id"), request.Answers); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, errChallengeNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Challenge answered",
+	})
+}
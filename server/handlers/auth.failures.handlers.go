@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/authfailures"
+)
+
+// AuthFailuresResponse 认证失败的主机列表
+type AuthFailuresResponse struct {
+	Threshold int                     `json:"threshold"` // 连续失败达到此次数时发出 host.auth_failures 事件
+	Hosts     []models.HostAuthStatus `json:"hosts"`
+}
+
+// SetAuthFailures sets the tracker recording SSH authentication failures
+func (h *Handlers) SetAuthFailures(tracker *authfailures.Tracker) {
+	h.authFailures = tracker
+}
+
+// GetAuthFailures 列出最近 SSH 认证失败的主机：连续失败次数、最近的错误、来源功能和发起者，
+// 连续失败多的在前。查询参数 project_id、group_id 限定范围
+func (h *Handlers) GetAuthFailures(c *gin.Context) {
+	if h.authFailures == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Authentication failure tracking is not configured",
+		})
+		return
+	}
+
+	var ids [2]uint
+	for i, name := range []string{"project_id", "group_id"} {
+		id, err := strconv.ParseUint(c.DefaultQuery(name, "0"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid " + name,
+			})
+			return
+		}
+		ids[i] = uint(id)
+	}
+
+	hosts, err := h.authFailures.Failures(c.Request.Context(), ids[0], ids[1])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    AuthFailuresResponse{Threshold: h.authFailures.Threshold(), Hosts: hosts},
+	})
+}
+
+// recordAuth records the outcome of connecting to a saved host for its
+// authentication failure stats. Errors other than a rejected authentication,
+// such as an unreachable host, are not counted either way.
+func (h *Handlers) recordAuth(ctx context.Context, hostID uint, source string, err error) {
+	if h.authFailures == nil || hostID == 0 || (err != nil && !authfailures.IsAuthFailure(err)) {
+		return
+	}
+
+	scope, _ := ctx.Value(challengeScopeKey{}).(challengeScope)
+	attempt := models.AuthAttempt{
+		HostID:  hostID,
+		Success: err == nil,
+		Source:  source,
+		Actor:   scope.actor,
+		At:      time.Now(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	h.authFailures.Record(ctx, attempt)
+}
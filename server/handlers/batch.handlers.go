@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// 批量操作的数量与并发限制
+const (
+	maxBatchOperations      = 200
+	defaultBatchConcurrency = 4
+	maxBatchConcurrency     = 16
+)
+
+// batchRoute 批量操作对应的接口；path 中的 %s 为操作的 id
+type batchRoute struct {
+	method string
+	path   string
+	body   string // 操作省略 data 时使用的请求体
+}
+
+// batchRoutes 支持的批量操作
+var batchRoutes = map[string]batchRoute{
+	"create_host":    {http.MethodPost, "/api/v1/hosts", ""},
+	"update_host":    {http.MethodPut, "/api/v1/hosts/%s", ""},
+	"delete_host":    {http.MethodDelete, "/api/v1/hosts/%s", ""},
+	"create_port":    {http.MethodPost, "/api/v1/ports", ""},
+	"update_port":    {http.MethodPut, "/api/v1/ports/%s", ""},
+	"delete_port":    {http.MethodDelete, "/api/v1/ports/%s", ""},
+	"start_port":     {http.MethodPost, "/api/v1/ports/%s/control", `{"action":"start"}`},
+	"stop_port":      {http.MethodPost, "/api/v1/ports/%s/control", `{"action":"stop"}`},
+	"create_forward": {http.MethodPost, "/api/v1/port-connections", ""},
+	"delete_forward": {http.MethodDelete, "/api/v1/port-connections/%s", ""},
+}
+
+// BatchOperation 批量请求中的一项操作
+type BatchOperation struct {
+	Op   string          `json:"op"`             // create_host, start_port, delete_forward 等
+	ID   string          `json:"id,omitempty"`   // 目标的 ID（端口也可为名称），创建时省略
+	Data json.RawMessage `json:"data,omitempty"` // 请求体，与单独调用对应接口时相同
+}
+
+// BatchRequest 批量执行操作；各项相互独立，一项失败不影响其他项
+type BatchRequest struct {
+	Operations  []BatchOperation `json:"operations" binding:"required"`
+	Concurrency int              `json:"concurrency"` // 同时执行的操作数，默认 4，最多 16；为 1 时按顺序执行
+}
+
+// BatchResult 单项操作的结果
+type BatchResult struct {
+	Index    int             `json:"index"` // 在请求中的位置
+	Op       string          `json:"op"`
+	ID       string          `json:"id,omitempty"`
+	Status   int             `json:"status"` // 对应接口的 HTTP 状态码，未执行时为 0
+	Success  bool            `json:"success"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Duration int64           `json:"duration"` // 毫秒
+}
+
+// BatchReport 批量操作的汇总
+type BatchReport struct {
+	Total     int           `json:"total"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Duration  int64         `json:"duration"` // 毫秒
+	Results   []BatchResult `json:"results"`  // 按请求中的顺序
+}
+
+// SetBatchRouter sets the routes batch operations are dispatched to
+func (h *Handlers) SetBatchRouter(router http.Handler) {
+	h.batchRouter = router
+}
+
+// ExecuteBatch 批量执行创建主机、启动端口、删除转发等操作，减少界面批量操作和脚本的请求次数
+// 每项操作按对应的单独接口执行（相同的校验、变更历史和请求头），以有限的并发运行，
+// 执行顺序不保证，有依赖的操作应分批提交或设置 concurrency 为 1
+// 请求本身有效时总是返回 200，每项的状态码和结果在 data.results 中
+func (h *Handlers) ExecuteBatch(c *gin.Context) {
+	var request BatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+	if err := validateBatch(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	concurrency := request.Concurrency
+	if concurrency == 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	concurrency = min(concurrency, len(request.Operations))
+
+	ctx := c.Request.Context()
+	startTime := time.Now()
+	report := BatchReport{
+		Total:   len(request.Operations),
+		Results: make([]BatchResult, 0, len(request.Operations)),
+	}
+	results := make(chan BatchResult)
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, operation := range request.Operations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+				results <- h.runBatchOperation(ctx, c.Request, i, operation)
+			case <-ctx.Done():
+				results <- BatchResult{Index: i, Op: operation.Op, ID: operation.ID, Error: ctx.Err().Error()}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		if result.Success {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].Index < report.Results[j].Index
+	})
+	report.Duration = time.Since(startTime).Milliseconds()
+
+	h.logger.Info("Batch executed",
+		"operations", report.Total,
+		"succeeded", report.Succeeded,
+		"failed", report.Failed,
+		"duration_ms", report.Duration)
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+	})
+}
+
+// validateBatch rejects the whole batch before anything runs when one of
+// its operations cannot be dispatched
+func validateBatch(request *BatchRequest) error {
+	if len(request.Operations) == 0 {
+		return fmt.Errorf("operations must not be empty")
+	}
+	if len(request.Operations) > maxBatchOperations {
+		return fmt.Errorf("at most %d operations per batch", maxBatchOperations)
+	}
+	if request.Concurrency < 0 || request.Concurrency > maxBatchConcurrency {
+		return fmt.Errorf("concurrency must be between 1 and %d", maxBatchConcurrency)
+	}
+	for i, operation := range request.Operations {
+		route, ok := batchRoutes[operation.Op]
+		if !ok {
+			return fmt.Errorf("operations[%d]: unknown op %q", i, operation.Op)
+		}
+		needsID := strings.Contains(route.path, "%s")
+		if needsID && operation.ID == "" {
+			return fmt.Errorf("operations[%d]: %s requires an id", i, operation.Op)
+		}
+		if !needsID && operation.ID != "" {
+			return fmt.Errorf("operations[%d]: %s does not take an id", i, operation.Op)
+		}
+	}
+	return nil
+}
+
+// runBatchOperation dispatches one operation to its route as if the client
+// had called it, with the batch request's headers and client address
+func (h *Handlers) runBatchOperation(ctx context.Context, parent *http.Request, index int, operation BatchOperation) BatchResult {
+	startTime := time.Now()
+	result := BatchResult{Index: index, Op: operation.Op, ID: operation.ID}
+	route := batchRoutes[operation.Op]
+
+	path := route.path
+	if operation.ID != "" {
+		path = fmt.Sprintf(route.path, url.PathEscape(operation.ID))
+	}
+	body := []byte(route.body)
+	if len(operation.Data) > 0 {
+		body = operation.Data
+	}
+
+	req, err := http.NewRequestWithContext(ctx, route.method, path, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header = parent.Header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Del("Content-Length")
+	req.RemoteAddr = parent.RemoteAddr
+	// Sub-requests show up as children of the batch in traces
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	recorder := &batchRecorder{header: make(http.Header)}
+	h.batchRouter.ServeHTTP(recorder, req)
+
+	var response struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   string          `json:"error"`
+	}
+	result.Status = recorder.statusCode()
+	if err := json.Unmarshal(recorder.body.Bytes(), &response); err != nil {
+		result.Error = fmt.Sprintf("unexpected response (HTTP %d)", result.Status)
+	} else {
+		result.Success = response.Success && result.Status < http.StatusBadRequest
+		result.Data = response.Data
+		result.Error = response.Error
+	}
+	result.Duration = time.Since(startTime).Milliseconds()
+	return result
+}
+
+// batchRecorder captures the response of a dispatched operation
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *batchRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *batchRecorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+func (r *batchRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/server/cloudsync"
+)
+
+// SetCloudSync sets the syncer behind the cloud sync endpoints
+func (h *Handlers) SetCloudSync(syncer *cloudsync.Syncer) {
+	h.cloudSync = syncer
+}
+
+// cloudSyncEnabled 检查是否配置了云同步，未配置时已写入错误响应
+func (h *Handlers) cloudSyncEnabled(c *gin.Context) bool {
+	if h.cloudSync == nil || !h.cloudSync.Enabled() {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Cloud sync is not configured",
+		})
+		return false
+	}
+	return true
+}
+
+// GetCloudSyncStatus 获取云同步来源（不含凭据）及每个来源最近一次同步的结果
+func (h *Handlers) GetCloudSyncStatus(c *gin.Context) {
+	if !h.cloudSyncEnabled(c) {
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    h.cloudSync.Status(),
+	})
+}
+
+// RunCloudSync 立即同步一个来源，返回创建、更新和归档的主机数
+func (h *Handlers) RunCloudSync(c *gin.Context) {
+	if !h.cloudSyncEnabled(c) {
+		return
+	}
+
+	report, err := h.cloudSync.Sync(c.Request.Context(), c.Param("name"))
+	switch {
+	case errors.Is(err, cloudsync.ErrSourceNotFound):
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	case errors.Is(err, cloudsync.ErrSyncInProgress):
+		c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadGateway, Response{
+			Success: false,
+			Data:    report,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+		Message: "Cloud sync completed",
+	})
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/cluster"
+)
+
+// SetCoordinator sets the coordinator assigning auto-start tunnels to one instance
+func (h *Handlers) SetCoordinator(coordinator *cluster.Coordinator) {
+	h.coordinator = coordinator
+}
+
+// GetClusterStatus 获取本实例信息以及所有实例的租约
+func (h *Handlers) GetClusterStatus(c *gin.Context) {
+	if h.coordinator == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Cluster coordination is not enabled",
+		})
+		return
+	}
+
+	leases, err := h.storage.GetLeases(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"instance": h.coordinator.Status(),
+			"leases":   leases,
+		},
+	})
+}
+
+// syncPortOwnership 自动启动的端口参与实例间的所有权竞争，其他端口退出竞争
+func (h *Handlers) syncPortOwnership(ctx context.Context, port *models.Port) {
+	if h.coordinator == nil {
+		return
+	}
+
+	resource := cluster.PortResource(port.ID)
+	if port.AutoStart {
+		h.coordinator.Watch(resource)
+	} else {
+		h.coordinator.Unwatch(ctx, resource)
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/server/selfstats"
+)
+
+// SetSelfStats sets the collector behind the admin stats endpoint
+func (h *Handlers) SetSelfStats(collector *selfstats.Collector) {
+	h.selfStats = collector
+}
+
+// GetSelfStats 服务器自身的资源使用：goroutine 数量、堆内存、GC，以及按子系统（隧道、终端、连接池）统计的 goroutine 和疑似泄漏
+func (h *Handlers) GetSelfStats(c *gin.Context) {
+	if h.selfStats == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Self stats are not available",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    h.selfStats.Collect(),
+	})
+}
+
+// Pprof 提供 net/http/pprof 的性能剖析，路径为 debug/pprof/ 下的剖析名称，
+// 例如 heap、goroutine?debug=2、profile?seconds=30（CPU）、trace?seconds=5
+func (h *Handlers) Pprof(c *gin.Context) {
+	switch name := strings.TrimPrefix(c.Param("profile"), "/"); name {
+	case "":
+		pprof.Index(c.Writer, c.Request)
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
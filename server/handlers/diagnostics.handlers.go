@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/server/diagnostics"
+)
+
+// SetDiagnostics sets the collector behind the diagnostics endpoints
+func (h *Handlers) SetDiagnostics(collector *diagnostics.Collector) {
+	h.diagnostics = collector
+}
+
+// CreateDiagnostics 立即生成诊断包：goroutine 堆栈、最近日志、隐去密钥的配置、版本信息以及健康和会话状态，
+// 写入服务器的诊断目录，用于提交给支持人员
+func (h *Handlers) CreateDiagnostics(c *gin.Context) {
+	if !h.requireDiagnostics(c) {
+		return
+	}
+
+	info, err := h.diagnostics.Create(diagnostics.ReasonRequested)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("Diagnostic bundle written", "name", info.Name, "size", info.Size)
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    info,
+		Message: "Diagnostic bundle written",
+	})
+}
+
+// GetDiagnostics 列出诊断目录中的诊断包，最新的在前，包括崩溃和 panic 时自动生成的
+func (h *Handlers) GetDiagnostics(c *gin.Context) {
+	if !h.requireDiagnostics(c) {
+		return
+	}
+
+	bundles, err := h.diagnostics.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    bundles,
+	})
+}
+
+// DownloadDiagnostics 下载诊断包（zip）
+func (h *Handlers) DownloadDiagnostics(c *gin.Context) {
+	if !h.requireDiagnostics(c) {
+		return
+	}
+
+	file, err := h.diagnostics.Open(c.Param("name"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, diagnostics.ErrBundleNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	var modTime time.Time
+	if stat, err := file.Stat(); err == nil {
+		modTime = stat.ModTime()
+	}
+	c.Header("Content-Disposition", "attachment; filename="+c.Param("name"))
+	c.Header("Content-Type", "application/zip")
+	http.ServeContent(c.Writer, c.Request, c.Param("name"), modTime, file)
+}
+
+// requireDiagnostics 未配置诊断目录时返回 503
+func (h *Handlers) requireDiagnostics(c *gin.Context) bool {
+	if h.diagnostics == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Diagnostics are not configured",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/discovery"
+)
+
+// maxDiscoveryTimeout 单次扫描中 mDNS 监听窗口和单个地址拨号的最长超时
+const maxDiscoveryTimeout = 10 * time.Second
+
+// DiscoverHostsRequest 局域网主机发现请求
+type DiscoverHostsRequest struct {
+	MDNS    *bool    `json:"mdns"`    // 浏览 _ssh._tcp.local，默认开启
+	CIDRs   []string `json:"cidrs"`   // 需要扫描的网段，如 192.168.1.0/24
+	Port    int      `json:"port"`    // 扫描端口，默认 22
+	Timeout int      `json:"timeout"` // 超时（毫秒），默认 2000
+}
+
+// DiscoverHosts 从服务端所在网络发现 SSH 主机，并标记已添加的主机
+func (h *Handlers) DiscoverHosts(c *gin.Context) {
+	var req DiscoverHostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	opts := discovery.Options{
+		MDNS:    req.MDNS == nil || *req.MDNS,
+		CIDRs:   req.CIDRs,
+		Port:    req.Port,
+		Timeout: min(time.Duration(req.Timeout)*time.Millisecond, maxDiscoveryTimeout),
+	}
+
+	candidates, err := discovery.Discover(c.Request.Context(), opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, discovery.ErrSweepTooLarge) || errors.Is(err, discovery.ErrInvalidCIDR) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	hosts, err := h.storage.GetHosts(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	discovery.MarkExisting(candidates, hosts)
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    candidates,
+	})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/localdns"
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// SetLocalDNS sets the manager publishing <port>.<group>.portfly names for active ports
+func (h *Handlers) SetLocalDNS(manager *localdns.Manager) {
+	h.localDNS = manager
+}
+
+// GetDNSRecords 获取当前发布的本地 DNS 记录
+func (h *Handlers) GetDNSRecords(c *gin.Context) {
+	if h.localDNS == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Local DNS is not enabled",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    h.localDNS.Records(),
+	})
+}
+
+// portDNSName 端口的本地 DNS 名称，例如 db.staging.portfly
+func (h *Handlers) portDNSName(port *models.Port) string {
+	return h.localDNS.Name(port.GetDisplayName(), port.Group.Name)
+}
+
+// RestorePortDNS 重新发布所有活跃端口的本地 DNS 名称，用于接管另一进程（如升级前的旧进程）的端口后
+func (h *Handlers) RestorePortDNS(ctx context.Context) {
+	if h.localDNS == nil {
+		return
+	}
+
+	ports, err := h.storage.GetPorts(ctx)
+	if err != nil {
+		h.logger.Warn("Failed to restore local DNS names", "error", err)
+		return
+	}
+	for _, port := range ports {
+		if port.Status == models.PortStatusActive {
+			h.syncPortDNS(ctx, port.ID, models.PortStatusActive)
+		}
+	}
+}
+
+// syncPortDNS 根据端口状态发布或移除本地 DNS 名称
+// 远程端口在 SSH 服务端监听，不发布
+func (h *Handlers) syncPortDNS(ctx context.Context, portID uint, status models.PortStatus) {
+	if h.localDNS == nil {
+		return
+	}
+
+	port, err := h.storage.GetPort(ctx, portID)
+	if err != nil || port.IsRemotePort() {
+		return
+	}
+
+	name := h.portDNSName(port)
+	if status == models.PortStatusActive {
+		var resolved *models.Port
+		if resolved, err = port.Resolve(); err == nil {
+			err = h.localDNS.Register(name, resolved.GetBindAddress())
+		}
+	} else {
+		err = h.localDNS.Unregister(name)
+	}
+	if err != nil {
+		h.logger.Warn("Failed to update local DNS name", "port_id", portID, "name", name, "error", err)
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/manager"
+)
+
+// SetExecScheduler replaces the scheduler sharing SSH connections among the
+// commands run on hosts with one using config
+func (h *Handlers) SetExecScheduler(config manager.ExecSchedulerConfig) {
+	h.execScheduler.Close()
+	h.execScheduler = manager.NewExecScheduler(h.hostBroker, config)
+}
+
+// ExecStats returns the connection, session and queue state of the exec scheduler
+func (h *Handlers) ExecStats() manager.ExecStats {
+	return h.execScheduler.Stats()
+}
+
+// GetExecQueue 批量执行、命令片段和端口钩子共享的 SSH 连接：每台主机打开的连接、
+// 执行中和排队的命令，以及启动以来的累计数
+func (h *Handlers) GetExecQueue(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    h.execScheduler.Stats(),
+	})
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ExportAnsibleInventory 将项目、分组和主机导出为 Ansible 动态清单 JSON（--list 格式），
+// 项目和分组按层级成为嵌套的组，主机的连接参数在 _meta.hostvars 中。响应不使用 Response 包装，
+// 可直接由 inventory 脚本输出给 Ansible；密码和私钥不会导出
+// Query: project_id/group_id=仅导出该项目或分组（含子级）, prefix=主机名前缀,
+// identity_dir=私钥文件目录, host=只返回该主机的变量（--host 格式）
+func (h *Handlers) ExportAnsibleInventory(c *gin.Context) {
+	var projectID, groupID uint64
+	var err error
+	if raw := c.Query("project_id"); raw != "" {
+		if projectID, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid project_id parameter",
+			})
+			return
+		}
+	}
+	if raw := c.Query("group_id"); raw != "" {
+		if groupID, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid group_id parameter",
+			})
+			return
+		}
+	}
+	if projectID != 0 && groupID != 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Use either project_id or group_id",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	projects, err := h.storage.GetProjects(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	groups, err := h.storage.GetGroups(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	hosts, err := h.storage.GetHosts(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	projects, groups, hosts = filterInventory(projects, groups, hosts, uint(projectID), uint(groupID))
+
+	inventory := models.BuildAnsibleInventory(projects, groups, hosts, models.AnsibleInventoryOptions{
+		AliasPrefix: c.Query("prefix"),
+		IdentityDir: c.Query("identity_dir"),
+	})
+	for _, skipped := range inventory.Skipped {
+		h.logger.Warn("Host left out of Ansible inventory", "host", skipped)
+	}
+
+	if host, ok := c.GetQuery("host"); ok {
+		vars := inventory.HostVars[host]
+		if vars == nil {
+			vars = map[string]any{} // Ansible expects an empty object for unknown hosts
+		}
+		c.JSON(http.StatusOK, vars)
+		return
+	}
+	c.JSON(http.StatusOK, inventory)
+}
+
+// filterInventory keeps the project or group with the given ID, its
+// descendants and their hosts; with neither ID everything is kept
+func filterInventory(projects []models.Project, groups []models.Group, hosts []models.Host, projectID, groupID uint) ([]models.Project, []models.Group, []models.Host) {
+	if projectID == 0 && groupID == 0 {
+		return projects, groups, hosts
+	}
+
+	keepProjects := make(map[uint]bool)
+	keepGroups := make(map[uint]bool)
+	if projectID != 0 {
+		keepProjects[projectID] = true
+		for grown := true; grown; {
+			grown = false
+			for _, project := range projects {
+				if project.ParentID != nil && keepProjects[*project.ParentID] && !keepProjects[project.ID] {
+					keepProjects[project.ID], grown = true, true
+				}
+			}
+		}
+		for _, group := range groups {
+			keepGroups[group.ID] = keepProjects[group.ProjectID]
+		}
+	} else {
+		keepGroups[groupID] = true
+		for grown := true; grown; {
+			grown = false
+			for _, group := range groups {
+				if group.ParentID != nil && keepGroups[*group.ParentID] && !keepGroups[group.ID] {
+					keepGroups[group.ID], grown = true, true
+				}
+			}
+		}
+	}
+
+	var keptProjects []models.Project
+	for _, project := range projects {
+		if keepProjects[project.ID] {
+			keptProjects = append(keptProjects, project)
+		}
+	}
+	var keptGroups []models.Group
+	for _, group := range groups {
+		if keepGroups[group.ID] {
+			keptGroups = append(keptGroups, group)
+		}
+	}
+	var keptHosts []models.Host
+	for _, host := range hosts {
+		if keepGroups[host.GroupID] {
+			keptHosts = append(keptHosts, host)
+		}
+	}
+	return keptProjects, keptGroups, keptHosts
+}
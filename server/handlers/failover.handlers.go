@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/failover"
+)
+
+// PortHosts 端口的冗余主机探测结果
+type PortHosts struct {
+	PortID       uint                     `json:"port_id"`
+	ActiveHostID *uint                    `json:"active_host_id,omitempty"`
+	Hosts        []failover.HostCandidate `json:"hosts"` // 可达主机按延迟从低到高排列
+}
+
+// SetFailover sets the host selection and failover monitor
+func (h *Handlers) SetFailover(monitor *failover.Monitor) {
+	h.failover = monitor
+}
+
+// GetPortHosts 探测端口的关联主机和候选主机，返回各自的延迟与当前使用的主机
+func (h *Handlers) GetPortHosts(c *gin.Context) {
+	if h.failover == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Host failover is not enabled",
+		})
+		return
+	}
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	port, err := h.storage.GetPort(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if port.HostID == nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Port has no host",
+		})
+		return
+	}
+
+	hosts, err := h.failover.Probe(c.Request.Context(), port)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: PortHosts{
+			PortID:       port.ID,
+			ActiveHostID: port.ActiveHostID,
+			Hosts:        hosts,
+		},
+	})
+}
+
+// rejectWithoutReachableHost 为有候选主机的端口选择延迟最低的可达主机；都不可达时返回 503
+func (h *Handlers) rejectWithoutReachableHost(c *gin.Context, port *models.Port) bool {
+	if h.failover == nil {
+		return false
+	}
+
+	err := h.failover.Activate(c.Request.Context(), port)
+	if err == nil {
+		return false
+	}
+
+	status := http.StatusInternalServerError
+	if errors.Is(err, failover.ErrNoHostReachable) {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, Response{
+		Success: false,
+		Error:   err.Error(),
+	})
+	return true
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// AddFavoriteRequest 收藏端口
+type AddFavoriteRequest struct {
+	PortID uint `json:"port_id" binding:"required"`
+}
+
+// SetFavoritesRequest 按顺序替换收藏的端口
+type SetFavoritesRequest struct {
+	PortIDs []uint `json:"port_ids"`
+}
+
+// favoriteErrorStatus maps unknown ports and favorites to 404
+func favoriteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrFavoriteOwnerRequired), errors.Is(err, models.ErrInvalidPort):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// GetFavorites 当前用户收藏的端口（含端口详情），按面板顺序排列；用户由 X-PortFly-Actor 请求头标识
+func (h *Handlers) GetFavorites(c *gin.Context) {
+	favorites, err := h.storage.GetPortFavorites(c.Request.Context(), changeActor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    favorites,
+	})
+}
+
+// AddFavorite 收藏端口，追加到面板末尾；已收藏的端口保持原位置
+func (h *Handlers) AddFavorite(c *gin.Context) {
+	var request AddFavoriteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	favorite := &models.PortFavorite{Owner: changeActor(c), PortID: request.PortID}
+	if err := h.storage.AddPortFavorite(c.Request.Context(), favorite); err != nil {
+		c.JSON(favoriteErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    favorite,
+	})
+}
+
+// SetFavorites 按 port_ids 的顺序替换当前用户的收藏，用于调整面板顺序
+func (h *Handlers) SetFavorites(c *gin.Context) {
+	var request SetFavoritesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	owner := changeActor(c)
+	if err := h.storage.SetPortFavorites(c.Request.Context(), owner, request.PortIDs); err != nil {
+		c.JSON(favoriteErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	favorites, err := h.storage.GetPortFavorites(c.Request.Context(), owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    favorites,
+	})
+}
+
+// DeleteFavorite 取消收藏端口（端口 ID 或名称）
+func (h *Handlers) DeleteFavorite(c *gin.Context) {
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.DeletePortFavorite(c.Request.Context(), changeActor(c), id); err != nil {
+		c.JSON(favoriteErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Favorite removed successfully",
+	})
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/grants"
+)
+
+// CreateGrantRequest 为端口创建临时访问授权
+type CreateGrantRequest struct {
+	Grantee   string     `json:"grantee" binding:"required"`
+	Reason    string     `json:"reason"`
+	CreatedBy string     `json:"created_by"`
+	StartsAt  *time.Time `json:"starts_at"`  // 为空时立即生效
+	ExpiresAt *time.Time `json:"expires_at"` // 与 duration_minutes 二选一
+	Duration  int        `json:"duration_minutes"`
+}
+
+// CreatedGrant 新授权及其令牌，令牌只返回这一次
+type CreatedGrant struct {
+	Grant *models.PortGrant `json:"grant"`
+	Token string            `json:"token"`
+}
+
+// SetGrants sets the manager of temporary port access grants
+func (h *Handlers) SetGrants(manager *grants.Manager) {
+	h.grants = manager
+}
+
+// PortStoppedForGrant withdraws the DNS name of a port stopped when its grant ended
+func (h *Handlers) PortStoppedForGrant(ctx context.Context, portID uint) {
+	h.syncPortDNS(ctx, portID, models.PortStatusAvailable)
+}
+
+// GetGrants 列出所有端口的有效授权，archived=true 时包含已归档的历史
+func (h *Handlers) GetGrants(c *gin.Context) {
+	if !h.requireGrants(c) {
+		return
+	}
+
+	list, err := h.storage.GetPortGrants(c.Request.Context(), 0, c.Query("archived") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    list,
+	})
+}
+
+// GetPortGrants 列出端口的授权，archived=true 时包含已归档的历史
+func (h *Handlers) GetPortGrants(c *gin.Context) {
+	if !h.requireGrants(c) {
+		return
+	}
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.storage.GetPortGrants(c.Request.Context(), id, c.Query("archived") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    list,
+	})
+}
+
+// CreatePortGrant 创建临时访问授权，返回只显示一次的令牌
+func (h *Handlers) CreatePortGrant(c *gin.Context) {
+	if !h.requireGrants(c) {
+		return
+	}
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	var request CreateGrantRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if _, err := h.storage.GetPort(c.Request.Context(), id); err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	grant := &models.PortGrant{
+		PortID:    id,
+		Grantee:   request.Grantee,
+		Reason:    request.Reason,
+		CreatedBy: request.CreatedBy,
+		StartsAt:  time.Now(),
+	}
+	if request.StartsAt != nil {
+		grant.StartsAt = *request.StartsAt
+	}
+	switch {
+	case request.ExpiresAt != nil:
+		grant.ExpiresAt = *request.ExpiresAt
+	case request.Duration > 0:
+		grant.ExpiresAt = grant.StartsAt.Add(time.Duration(request.Duration) * time.Minute)
+	default:
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "expires_at or duration_minutes is required",
+		})
+		return
+	}
+
+	token, err := h.grants.Issue(c.Request.Context(), grant)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrGrantGranteeRequired) || errors.Is(err, models.ErrGrantInvalidWindow) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    CreatedGrant{Grant: grant, Token: token},
+		Message: "Grant created; pass the token in the " + grants.Header + " header to start the port",
+	})
+}
+
+// RevokePortGrant 提前撤销授权，停止通过它启动的端口并归档
+func (h *Handlers) RevokePortGrant(c *gin.Context) {
+	if !h.requireGrants(c) {
+		return
+	}
+	portID, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+	grantID, err := strconv.ParseUint(c.Param("grantId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid grant ID",
+		})
+		return
+	}
+
+	grant, err := h.storage.GetPortGrant(c.Request.Context(), uint(grantID))
+	if err != nil || grant.PortID != portID {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Grant not found",
+		})
+		return
+	}
+
+	grant, err = h.grants.Revoke(c.Request.Context(), grant.ID, c.Query("by"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    grant,
+		Message: "Grant revoked",
+	})
+}
+
+// rejectWithoutGrant 检查启动端口时提供的授权令牌；需要授权的端口没有有效令牌时返回 403
+func (h *Handlers) rejectWithoutGrant(c *gin.Context, port *models.Port) bool {
+	if h.grants == nil {
+		return false
+	}
+
+	_, err := h.grants.Authorize(c.Request.Context(), port, c.GetHeader(grants.Header))
+	if err == nil {
+		return false
+	}
+
+	status := http.StatusInternalServerError
+	if errors.Is(err, grants.ErrGrantRequired) || errors.Is(err, grants.ErrGrantInvalid) {
+		status = http.StatusForbidden
+	}
+	c.JSON(status, Response{
+		Success: false,
+		Error:   err.Error(),
+	})
+	return true
+}
+
+// requireGrants 未启用临时授权时返回 503
+func (h *Handlers) requireGrants(c *gin.Context) bool {
+	if h.grants == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Port grants are not enabled",
+		})
+		return false
+	}
+	return true
+}
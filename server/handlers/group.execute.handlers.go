@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/aqz236/port-fly/core/manager"
+	"github.com/aqz236/port-fly/core/models"
+	sshpkg "github.com/aqz236/port-fly/core/ssh"
+)
+
+// 批量执行的并发与输出限制
+const (
+	defaultExecConcurrency = 10
+	maxExecConcurrency     = 100
+	maxExecOutput          = 1 << 20 // 每台主机 stdout、stderr 各保留的字节数
+)
+
+// GroupExecRequest 在分组的所有主机上执行命令
+type GroupExecRequest struct {
+	Command      string `json:"command" binding:"required"`
+	Timeout      int    `json:"timeout"`                 // 每台主机的超时（毫秒），默认 30 秒
+	Concurrency  int    `json:"concurrency"`             // 同时执行的主机数，默认 10，最多 100
+	Sudo         bool   `json:"sudo"`                    // 通过 sudo 执行，密码经 stdin 输入
+	SudoPassword string `json:"sudo_password,omitempty"` // 本次使用的 sudo 密码，覆盖各主机保存的密码
+}
+
+// HostExecResult 单台主机的执行结果
+type HostExecResult struct {
+	HostID    uint   `json:"host_id"`
+	HostName  string `json:"host_name"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	ExitCode  int    `json:"exit_code"`           // 命令未能执行完时为 -1
+	Error     string `json:"error,omitempty"`     // 连接失败、主机繁忙、超时等
+	Truncated bool   `json:"truncated,omitempty"` // 输出超过 1 MiB，只保留开头
+	Duration  int64  `json:"duration"`            // 毫秒
+}
+
+// GroupExecReport 所有主机执行结果的汇总
+type GroupExecReport struct {
+	GroupID   uint             `json:"group_id"`
+	Command   string           `json:"command"`
+	Hosts     int              `json:"hosts"`
+	Succeeded int              `json:"succeeded"` // 退出码为 0
+	Failed    int              `json:"failed"`    // 退出码非 0
+	Errors    int              `json:"errors"`    // 命令未能执行完
+	Duration  int64            `json:"duration"`  // 毫秒
+	Results   []HostExecResult `json:"results"`   // 按主机 ID 排序
+}
+
+// add counts one host's result into the report
+func (r *GroupExecReport) add(result HostExecResult) {
+	switch {
+	case result.Error != "":
+		r.Errors++
+	case result.ExitCode == 0:
+		r.Succeeded++
+	default:
+		r.Failed++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// ExecuteGroupCommand 在分组的所有主机上并发执行命令。Accept 为 text/event-stream
+// 或 stream=true 时，每台主机完成后推送 result 事件，最后推送 report 事件；否则返回汇总报告
+func (h *Handlers) ExecuteGroupCommand(c *gin.Context) {
+	groupID, ok := h.resolveGroupID(c)
+	if !ok {
+		return
+	}
+
+	var req GroupExecRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if req.Timeout < 0 || req.Concurrency < 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "timeout and concurrency must not be negative",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	hosts, ok := h.groupHosts(c, groupID)
+	if !ok {
+		return
+	}
+
+	timeout := time.Duration(req.Timeout) * time.Millisecond
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	concurrency := fanOut(req.Concurrency, len(hosts))
+
+	// Slow hosts outlive the server write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	stream := c.Query("stream") == "true" || strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	ctx, challenges := h.challengeStream(c, stream)
+	startTime := time.Now()
+	report := GroupExecReport{
+		GroupID: groupID,
+		Command: req.Command,
+		Hosts:   len(hosts),
+		Results: make([]HostExecResult, 0, len(hosts)),
+	}
+	results := forEachHost(ctx, hosts, concurrency,
+		func(host *models.Host) HostExecResult {
+			return h.runHostCommand(ctx, host, req.Command, timeout, req.Sudo, req.SudoPassword)
+		},
+		func(host *models.Host, err error) HostExecResult {
+			return HostExecResult{HostID: host.ID, HostName: host.Name, ExitCode: -1, Error: err.Error()}
+		})
+	streamResults(c, results, challenges, func(result HostExecResult) {
+		report.add(result)
+		if stream {
+			c.SSEvent("result", result)
+			c.Writer.Flush()
+		}
+	})
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].HostID < report.Results[j].HostID
+	})
+	report.Duration = time.Since(startTime).Milliseconds()
+
+	h.logger.Info("Group command executed",
+		"group_id", groupID,
+		"hosts", report.Hosts,
+		"succeeded", report.Succeeded,
+		"failed", report.Failed,
+		"errors", report.Errors,
+		"duration_ms", report.Duration)
+
+	if stream {
+		c.SSEvent("report", report)
+		return
+	}
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+	})
+}
+
+// groupHosts returns the hosts of a group, writing the error response when
+// the group does not exist or has no hosts
+func (h *Handlers) groupHosts(c *gin.Context, groupID uint) ([]models.Host, bool) {
+	ctx := c.Request.Context()
+	if _, err := h.storage.GetGroup(ctx, groupID); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Group not found",
+		})
+		return nil, false
+	}
+	hosts, err := h.storage.GetHostsByGroup(ctx, groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+	if len(hosts) == 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Group has no hosts",
+		})
+		return nil, false
+	}
+	return hosts, true
+}
+
+// fanOut returns how many of the hosts to work on at once for a requested
+// concurrency, 0 meaning the default
+func fanOut(requested, hosts int) int {
+	if requested == 0 {
+		requested = defaultExecConcurrency
+	}
+	return min(requested, maxExecConcurrency, hosts)
+}
+
+// forEachHost runs fn on every host, at most concurrency at a time, and
+// delivers each result as the host finishes; hosts still waiting for a slot
+// when ctx ends get canceled instead. The channel is closed once all hosts
+// are done.
+func forEachHost[T any](ctx context.Context, hosts []models.Host, concurrency int, fn func(*models.Host) T, canceled func(*models.Host, error) T) <-chan T {
+	results := make(chan T)
+	slots := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i := range hosts {
+		host := &hosts[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+				results <- fn(host)
+			case <-ctx.Done():
+				results <- canceled(host, ctx.Err())
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// runHostCommand connects to the host and runs command, through sudo when
+// asked, keeping stdout and stderr apart and reporting the remote exit code.
+// sudoPassword overrides the host's sudo password.
+func (h *Handlers) runHostCommand(ctx context.Context, host *models.Host, command string, timeout time.Duration, sudo bool, sudoPassword string) HostExecResult {
+	startTime := time.Now()
+	result := HostExecResult{HostID: host.ID, HostName: host.Name, ExitCode: -1}
+	fail := func(err error) HostExecResult {
+		result.Error = err.Error()
+		result.Duration = time.Since(startTime).Milliseconds()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	session, closeSession, err := h.execSession(ctx, host, models.AuthSourceExec, timeout)
+	if err != nil {
+		return fail(err)
+	}
+	defer closeSession()
+
+	stdout := &limitedBuffer{limit: maxExecOutput}
+	stderr := &limitedBuffer{limit: maxExecOutput}
+
+	done := make(chan error, 1)
+	var password *sudoAuth
+	if sudo {
+		password = h.newSudoAuth(host, sudoPassword)
+		go func() { done <- sshpkg.RunSudo(ctx, session, command, password.get, stdout, stderr) }()
+	} else {
+		session.Stdout = stdout
+		session.Stderr = stderr
+		go func() { done <- session.Run(command) }()
+	}
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// Closing the session unblocks Run; the command may keep running
+		// on hosts that ignore the hangup
+		session.Close()
+		<-done
+		err = fmt.Errorf("command did not finish: %w", ctx.Err())
+	}
+
+	result.Stdout = stdout.buf.String()
+	result.Stderr = stderr.buf.String()
+	result.Truncated = stdout.truncated || stderr.truncated
+	result.Duration = time.Since(startTime).Milliseconds()
+
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		result.ExitCode = 0
+	case errors.Is(err, sshpkg.ErrSudoPassword):
+		result.Error = err.Error()
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitStatus()
+	default:
+		result.Error = err.Error()
+	}
+	if password != nil {
+		result.Stdout = password.scrub(result.Stdout)
+		result.Stderr = password.scrub(result.Stderr)
+		result.Error = password.scrub(result.Error)
+	}
+	return result
+}
+
+// connectHost opens an SSH connection to the host within its concurrency
+// limit; disconnect closes it and frees the slot
+func (h *Handlers) connectHost(ctx context.Context, host *models.Host, source string, timeout time.Duration) (*sshpkg.SSHClient, func(), error) {
+	// 遵守主机的并发会话限制
+	release, err := h.acquireHost(ctx, host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sshClient, err := h.dialHost(ctx, host, source, timeout)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return sshClient, func() {
+		sshClient.Disconnect()
+		release()
+	}, nil
+}
+
+// execSession opens an SSH session for one command on a connection to the
+// host shared with other commands of the same actor, within the host's
+// concurrency limit; closeSession ends it and frees the slot
+func (h *Handlers) execSession(ctx context.Context, host *models.Host, source string, timeout time.Duration) (*ssh.Session, func(), error) {
+	scope, _ := ctx.Value(challengeScopeKey{}).(challengeScope)
+	return h.execScheduler.Session(ctx, manager.ExecTarget{
+		HostID: host.ID,
+		// Edited hosts get new connections, and connections whose
+		// challenges one user answered are not handed to another
+		Key:   fmt.Sprintf("%d/%s", host.UpdatedAt.UnixNano(), scope.actor),
+		Limit: manager.HostLimitFor(host),
+		Dial: func(ctx context.Context) (*ssh.Client, func(), error) {
+			sshClient, err := h.dialHost(ctx, host, source, timeout)
+			if err != nil {
+				return nil, nil, err
+			}
+			client := sshClient.GetClient()
+			if client == nil {
+				sshClient.Disconnect()
+				return nil, nil, fmt.Errorf("SSH client not available")
+			}
+			return client, func() { sshClient.Disconnect() }, nil
+		},
+	})
+}
+
+// dialHost opens an SSH connection to the host, relaying its challenges to
+// the requesting client and recording the authentication outcome
+func (h *Handlers) dialHost(ctx context.Context, host *models.Host, source string, timeout time.Duration) (*sshpkg.SSHClient, error) {
+	sshConfig, err := h.hostSSHConfig(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	sshConfig.ConnectTimeout = timeout
+	sshConfig.HostKeyCallback = "accept"
+
+	sshClient := sshpkg.NewSSHClient(sshConfig, h.logger.With("host_id", host.ID))
+	h.relayChallenges(sshClient, host)
+	err = sshClient.Connect(ctx)
+	h.recordAuth(ctx, host.ID, source, err)
+	if err != nil {
+		return nil, fmt.Errorf("SSH connection failed: %w", err)
+	}
+	return sshClient, nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and drops the rest
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:max(room, 0)])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
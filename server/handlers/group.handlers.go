@@ -13,6 +13,7 @@ import (
 func (h *Handlers) GetGroups(c *gin.Context) {
 	// 检查是否有 project_id 查询参数
 	projectIDStr := c.Query("project_id")
+	asTree := c.Query("as_tree") == "true"
 	
 	if projectIDStr != "" {
 		// 如果有 project_id 参数，按项目获取组
@@ -25,6 +26,12 @@ func (h *Handlers) GetGroups(c *gin.Context) {
 			return
 		}
 
+		if asTree {
+			// 返回树状结构
+			h.getGroupTree(c, uint(projectID))
+			return
+		}
+
 		groups, err := h.storage.GetGroupsByProject(c.Request.Context(), uint(projectID))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, Response{
@@ -41,6 +48,11 @@ func (h *Handlers) GetGroups(c *gin.Context) {
 		return
 	}
 
+	if asTree {
+		h.getGroupTree(c, 0)
+		return
+	}
+
 	// 否则获取所有组
 	groups, err := h.storage.GetGroups(c.Request.Context())
 	if err != nil {
@@ -92,13 +104,34 @@ func (h *Handlers) CreateGroup(c *gin.Context) {
 		return
 	}
 
+	if err := models.ValidateMaintenanceWindows(group.MaintenanceWindows); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := group.TerminalPolicy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if h.rejectInvalidMetadata(c, models.EntityGroup, group.Metadata) {
+		return
+	}
+
 	if err := h.storage.CreateGroup(c.Request.Context(), &group); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
+		c.JSON(groupErrorStatus(err), Response{
 			Success: false,
 			Error:   err.Error(),
 		})
 		return
 	}
+	h.recordChange(c, models.EntityGroup, group.ID, models.ChangeCreate, nil, h.entityFields(models.EntityGroup, &group))
 
 	c.JSON(http.StatusCreated, Response{
 		Success: true,
@@ -150,14 +183,40 @@ func (h *Handlers) UpdateGroup(c *gin.Context) {
 		return
 	}
 
+	if err := models.ValidateMaintenanceWindows(group.MaintenanceWindows); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := group.TerminalPolicy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if h.rejectInvalidMetadata(c, models.EntityGroup, group.Metadata) {
+		return
+	}
+
+	var before map[string]any
+	if existing, err := h.storage.GetGroup(c.Request.Context(), uint(id)); err == nil {
+		before = h.entityFields(models.EntityGroup, existing)
+	}
+
 	group.ID = uint(id)
 	if err := h.storage.UpdateGroup(c.Request.Context(), &group); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
+		c.JSON(groupErrorStatus(err), Response{
 			Success: false,
 			Error:   err.Error(),
 		})
 		return
 	}
+	h.recordChange(c, models.EntityGroup, group.ID, models.ChangeUpdate, before, h.entityFields(models.EntityGroup, &group))
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
@@ -175,13 +234,21 @@ func (h *Handlers) DeleteGroup(c *gin.Context) {
 		return
 	}
 
+	var before map[string]any
+	if existing, err := h.storage.GetGroup(c.Request.Context(), uint(id)); err == nil {
+		before = h.entityFields(models.EntityGroup, existing)
+	}
+
 	if err := h.storage.DeleteGroup(c.Request.Context(), uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
+		c.JSON(groupErrorStatus(err), Response{
 			Success: false,
 			Error:   err.Error(),
 		})
 		return
 	}
+	if before != nil {
+		h.recordChange(c, models.EntityGroup, uint(id), models.ChangeDelete, before, nil)
+	}
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// 文件分发的限制
+const (
+	maxPushSize        = 512 << 20 // 上传的 tar 包大小上限
+	defaultPushTimeout = 5 * time.Minute
+)
+
+// PushedFile 分发的文件或目录
+type PushedFile struct {
+	Path   string      `json:"path"` // 目标路径
+	Dir    bool        `json:"dir,omitempty"`
+	Mode   fs.FileMode `json:"mode"`
+	Size   int64       `json:"size,omitempty"`
+	SHA256 string      `json:"sha256,omitempty"`
+
+	staged string // 暂存在服务器上的副本
+}
+
+// HostPushResult 单台主机的分发结果
+type HostPushResult struct {
+	HostID   uint   `json:"host_id"`
+	HostName string `json:"host_name"`
+	Files    int    `json:"files"` // 已写入并校验的文件数
+	Bytes    int64  `json:"bytes"`
+	Error    string `json:"error,omitempty"`
+	Duration int64  `json:"duration"` // 毫秒
+}
+
+// GroupPushReport 所有主机分发结果的汇总
+type GroupPushReport struct {
+	GroupID   uint             `json:"group_id"`
+	Path      string           `json:"path"`
+	Files     []PushedFile     `json:"files"`
+	Bytes     int64            `json:"bytes"` // 每台主机写入的字节数
+	Hosts     int              `json:"hosts"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Duration  int64            `json:"duration"` // 毫秒
+	Results   []HostPushResult `json:"results"`  // 按主机 ID 排序
+}
+
+// PushGroupFiles 通过 SFTP 将 tar 包（application/x-tar）中的文件和目录分发到分组的所有主机，
+// 解压到 path 下。每个文件先写入临时文件，读回校验 SHA-256 后再替换目标文件。
+// 查询参数 concurrency、timeout（每台主机，毫秒，默认 5 分钟）；流式输出与 execute 相同
+func (h *Handlers) PushGroupFiles(c *gin.Context) {
+	groupID, ok := h.resolveGroupID(c)
+	if !ok {
+		return
+	}
+
+	dest := c.Query("path")
+	concurrency, concurrencyErr := strconv.Atoi(c.DefaultQuery("concurrency", "0"))
+	timeoutMS, timeoutErr := strconv.Atoi(c.DefaultQuery("timeout", "0"))
+	switch {
+	case dest == "":
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "path is required",
+		})
+		return
+	case concurrencyErr != nil || timeoutErr != nil || concurrency < 0 || timeoutMS < 0:
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid concurrency or timeout",
+		})
+		return
+	}
+	dest = path.Clean(dest)
+
+	hosts, ok := h.groupHosts(c, groupID)
+	if !ok {
+		return
+	}
+
+	// Uploads and slow hosts outlive the server read and write timeouts
+	controller := http.NewResponseController(c.Writer)
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+
+	stageDir, err := os.MkdirTemp("", "portfly-push-")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer os.RemoveAll(stageDir)
+
+	files, err := stageArchive(http.MaxBytesReader(c.Writer, c.Request.Body, maxPushSize), stageDir, dest)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	timeout := time.Duration(timeoutMS) * time.Millisecond
+	if timeout == 0 {
+		timeout = defaultPushTimeout
+	}
+
+	stream := c.Query("stream") == "true" || strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	ctx, challenges := h.challengeStream(c, stream)
+	startTime := time.Now()
+	report := GroupPushReport{
+		GroupID: groupID,
+		Path:    dest,
+		Files:   files,
+		Hosts:   len(hosts),
+		Results: make([]HostPushResult, 0, len(hosts)),
+	}
+	for _, file := range files {
+		report.Bytes += file.Size
+	}
+
+	results := forEachHost(ctx, hosts, fanOut(concurrency, len(hosts)),
+		func(host *models.Host) HostPushResult {
+			return h.pushToHost(ctx, host, files, timeout)
+		},
+		func(host *models.Host, err error) HostPushResult {
+			return HostPushResult{HostID: host.ID, HostName: host.Name, Error: err.Error()}
+		})
+	streamResults(c, results, challenges, func(result HostPushResult) {
+		if result.Error == "" {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+		if stream {
+			c.SSEvent("result", result)
+			c.Writer.Flush()
+		}
+	})
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].HostID < report.Results[j].HostID
+	})
+	report.Duration = time.Since(startTime).Milliseconds()
+
+	h.logger.Info("Files pushed to group",
+		"group_id", groupID,
+		"path", dest,
+		"files", len(files),
+		"hosts", report.Hosts,
+		"succeeded", report.Succeeded,
+		"failed", report.Failed,
+		"duration_ms", report.Duration)
+
+	if stream {
+		c.SSEvent("report", report)
+		return
+	}
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+	})
+}
+
+// stageArchive extracts the regular files and directories of a tar archive
+// into stageDir, recording where each goes under dest and its SHA-256.
+// Entries are returned directories first, each after its parent.
+func stageArchive(r io.Reader, stageDir, dest string) ([]PushedFile, error) {
+	var files []PushedFile
+	seen := make(map[string]bool)
+
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tar archive: %w", err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if !fs.ValidPath(name) {
+			return nil, fmt.Errorf("invalid path in archive: %q", header.Name)
+		}
+		if name == "." {
+			continue
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate path in archive: %q", name)
+		}
+		seen[name] = true
+
+		file := PushedFile{Path: path.Join(dest, name), Mode: fs.FileMode(header.Mode).Perm()}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			file.Dir = true
+		case tar.TypeReg:
+			file.staged = filepath.Join(stageDir, strconv.Itoa(len(files)))
+			if file.Size, file.SHA256, err = stageFile(archive, file.staged); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%s: only regular files and directories can be pushed", name)
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("archive contains no files")
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Dir != files[j].Dir {
+			return files[i].Dir
+		}
+		return files[i].Dir && strings.Count(files[i].Path, "/") < strings.Count(files[j].Path, "/")
+	})
+	return files, nil
+}
+
+// stageFile copies r to a new file and returns its size and SHA-256
+func stageFile(r io.Reader, name string) (int64, string, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(f, io.TeeReader(r, hash))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read archive: %w", err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), f.Close()
+}
+
+// pushToHost writes the staged files to the host over SFTP, stopping at the
+// first failure
+func (h *Handlers) pushToHost(ctx context.Context, host *models.Host, files []PushedFile, timeout time.Duration) HostPushResult {
+	startTime := time.Now()
+	result := HostPushResult{HostID: host.ID, HostName: host.Name}
+	fail := func(err error) HostPushResult {
+		result.Error = err.Error()
+		result.Duration = time.Since(startTime).Milliseconds()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sshClient, disconnect, err := h.connectHost(ctx, host, models.AuthSourcePush, timeout)
+	if err != nil {
+		return fail(err)
+	}
+	defer disconnect()
+
+	client, err := sshClient.SFTP()
+	if err != nil {
+		return fail(err)
+	}
+	defer client.Close()
+
+	created := make(map[string]bool)
+	for _, file := range files {
+		if file.Dir {
+			if err := client.MkdirAll(ctx, file.Path, file.Mode|0o700); err != nil {
+				return fail(err)
+			}
+			created[file.Path] = true
+			continue
+		}
+
+		if dir := path.Dir(file.Path); !created[dir] {
+			if err := client.MkdirAll(ctx, dir, 0o755); err != nil {
+				return fail(err)
+			}
+			created[dir] = true
+		}
+
+		staged, err := os.Open(file.staged)
+		if err != nil {
+			return fail(err)
+		}
+		written, sum, err := client.PutFile(ctx, file.Path, staged, file.Mode)
+		staged.Close()
+		if err == nil && hex.EncodeToString(sum) != file.SHA256 {
+			err = fmt.Errorf("staged copy of %s changed during the push", file.Path)
+		}
+		if err != nil {
+			return fail(fmt.Errorf("%s: %w", file.Path, err))
+		}
+		result.Files++
+		result.Bytes += written
+	}
+
+	result.Duration = time.Since(startTime).Milliseconds()
+	return result
+}
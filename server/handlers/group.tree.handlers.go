@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// EffectiveGroup 继承祖先默认值后的分组，以及参与继承的祖先（最近的在前）
+type EffectiveGroup struct {
+	Group     *models.Group  `json:"group"`
+	Ancestors []models.Group `json:"ancestors"`
+}
+
+// groupErrorStatus maps invalid nesting to 400, unknown groups to 404 and
+// changes blocked by subgroups to 409
+func groupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrGroupCycle), errors.Is(err, models.ErrGroupParentProject):
+		return http.StatusBadRequest
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrGroupHasChildren), errors.Is(err, models.ErrNestedGroupProject):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// getGroupTree 返回分组树，查询参数 project_id 限定项目，parent_id 只返回该分组下的子树
+func (h *Handlers) getGroupTree(c *gin.Context, projectID uint) {
+	var rootID *uint
+	if parentIDStr := c.Query("parent_id"); parentIDStr != "" {
+		id, err := strconv.ParseUint(parentIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid parent_id parameter",
+			})
+			return
+		}
+		uid := uint(id)
+		rootID = &uid
+	}
+
+	tree, err := h.storage.GetGroupTree(c.Request.Context(), projectID, rootID)
+	if err != nil {
+		c.JSON(groupErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    tree,
+	})
+}
+
+// GetGroupChildren 获取分组的直接子分组
+func (h *Handlers) GetGroupChildren(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid group ID",
+		})
+		return
+	}
+
+	children, err := h.storage.GetGroupChildren(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    children,
+	})
+}
+
+// GetEffectiveGroup 获取继承祖先默认值后的分组：颜色和图标为默认值时取自最近的祖先，
+// 标签和维护窗口累加所有祖先
+func (h *Handlers) GetEffectiveGroup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid group ID",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	group, err := h.storage.GetGroup(ctx, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Group not found",
+		})
+		return
+	}
+
+	ancestors, err := h.storage.GetGroupAncestors(ctx, group.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if ancestors == nil {
+		ancestors = []models.Group{}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: EffectiveGroup{
+			Group:     group.Inherit(ancestors),
+			Ancestors: ancestors,
+		},
+	})
+}
+
+// MoveGroup 移动分组（连同子分组）到同一项目的另一个分组下，parent_id 为空时移到顶层
+func (h *Handlers) MoveGroup(c *gin.Context) {
+	var params models.MoveGroupParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := h.storage.MoveGroup(c.Request.Context(), &params); err != nil {
+		c.JSON(groupErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Group moved successfully",
+	})
+}
@@ -1,9 +1,39 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/localdns"
 	"github.com/aqz236/port-fly/core/manager"
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/secrets"
 	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/core/version"
+	"github.com/aqz236/port-fly/server/agents"
+	"github.com/aqz236/port-fly/server/authfailures"
+	"github.com/aqz236/port-fly/server/backup"
+	"github.com/aqz236/port-fly/server/cloudsync"
+	"github.com/aqz236/port-fly/server/cluster"
+	"github.com/aqz236/port-fly/server/diagnostics"
+	"github.com/aqz236/port-fly/server/failover"
+	"github.com/aqz236/port-fly/server/grants"
+	"github.com/aqz236/port-fly/server/health"
+	"github.com/aqz236/port-fly/server/hoststatus"
+	"github.com/aqz236/port-fly/server/maintenance"
+	"github.com/aqz236/port-fly/server/operations"
+	"github.com/aqz236/port-fly/server/reconcile"
+	"github.com/aqz236/port-fly/server/retention"
+	"github.com/aqz236/port-fly/server/selfstats"
+	"github.com/aqz236/port-fly/server/setup"
+	"github.com/aqz236/port-fly/server/statuspage"
 	"github.com/aqz236/port-fly/server/storage"
+	"github.com/aqz236/port-fly/server/upgrade"
 )
 
 // Handlers contains all HTTP handlers for the new Project->Group->Resource architecture
@@ -11,17 +41,191 @@ type Handlers struct {
 	storage        storage.StorageInterface
 	sessionManager *manager.SessionManager
 	logger         utils.Logger
+
+	// Defaults applied to host SSH connections
+	defaultSSHProxy string
+
+	// Resolves vault:/aws: credential references at connection time
+	secrets *secrets.Registry
+
+	// Enforces per-host connection limits
+	hostBroker *manager.HostBroker
+
+	// Shares SSH connections among the commands run on a host
+	execScheduler *manager.ExecScheduler
+
+	// Per-session/port log buffers
+	logStore *utils.LogStore
+
+	// Publishes friendly names for active ports (nil when disabled)
+	localDNS *localdns.Manager
+
+	// Assigns auto-start tunnels to one server replica (nil when disabled)
+	coordinator *cluster.Coordinator
+
+	// Remote agents running tunnels on other machines (nil when disabled)
+	agentHub *agents.Hub
+
+	// Encrypted database snapshots (nil without a backup passphrase)
+	backups *backup.Manager
+
+	// Session and log retention
+	pruner *retention.Pruner
+
+	// Forward ownership and orphan cleanup
+	reconciler *reconcile.Reconciler
+
+	// Maintenance windows on hosts and groups
+	maintenance *maintenance.Checker
+
+	// Temporary access grants for ports
+	grants *grants.Manager
+
+	// Host selection and failover for ports with redundant hosts
+	failover *failover.Monitor
+
+	// Subsystem probes behind /health and /metrics
+	health *health.Registry
+
+	// Public status of published ports (nil when disabled)
+	statusPage *statuspage.Monitor
+
+	// Live reachability of hosts joined into host lists
+	hostStatus *hoststatus.Monitor
+
+	// Host sync from cloud provider instances (nil when not configured)
+	cloudSync *cloudsync.Syncer
+
+	// Ranges local ports are allocated from, by project and user
+	portPools []models.PortPool
+
+	// Token external systems send with webhooks (empty disables webhooks)
+	webhookToken string
+
+	// Whether port hooks may run commands on the server itself
+	localHooks bool
+
+	// Whether hosts may reach their SSH server through a proxy command run on the server
+	proxyCommands bool
+
+	// Goroutine and heap report behind the admin stats endpoint
+	selfStats *selfstats.Collector
+
+	// Support bundles written on request and when the server panics
+	diagnostics *diagnostics.Collector
+
+	// Version of the server binary
+	buildInfo version.Info
+
+	// First-run setup state behind the setup endpoints
+	setup *setup.Manager
+
+	// Per-host SSH authentication failures and their threshold events
+	authFailures *authfailures.Tracker
+
+	// Hands the listeners to a new server binary
+	upgrader *upgrade.Upgrader
+
+	// Keyboard-interactive challenges waiting for an answer from the requesting client
+	challenges *challengeRouter
+
+	// Asynchronous port commands and the sessions running port tunnels
+	operations  *operations.Tracker
+	portTunnels *portTunnels
+
+	// Routes the operations of a batch request are dispatched to
+	batchRouter http.Handler
+
+	// Schemas entity metadata is validated against, by entity type
+	metadataSchemas models.MetadataSchemas
+
+	// Global idle timeout and max duration of web terminals
+	terminalPolicy models.TerminalPolicy
 }
 
 // NewHandlers creates a new handlers instance
 func NewHandlers(storage storage.StorageInterface, sessionManager *manager.SessionManager, logger utils.Logger) *Handlers {
+	hostBroker := manager.NewHostBroker()
 	return &Handlers{
 		storage:        storage,
 		sessionManager: sessionManager,
 		logger:         logger,
+		hostBroker:     hostBroker,
+		execScheduler:  manager.NewExecScheduler(hostBroker, manager.ExecSchedulerConfig{}),
+		challenges:     newChallengeRouter(),
+		portTunnels:    newPortTunnels(),
 	}
 }
 
+// SetDefaultSSHProxy sets the outbound proxy used by hosts that do not configure their own
+func (h *Handlers) SetDefaultSSHProxy(proxyURL string) {
+	h.defaultSSHProxy = proxyURL
+}
+
+// errProxyCommandsDisabled is returned for hosts with a proxy_command on
+// servers that do not enable them
+var errProxyCommandsDisabled = errors.New("host proxy commands are disabled on this server; set PORTFLY_PROXY_COMMANDS=true to allow them")
+
+// SetProxyCommands sets whether hosts may connect through their proxy_command
+func (h *Handlers) SetProxyCommands(enabled bool) {
+	h.proxyCommands = enabled
+}
+
+// checkProxyCommand refuses a host proxy command unless the server enables
+// them; the command runs through the server's shell
+func (h *Handlers) checkProxyCommand(host *models.Host) error {
+	if host.ProxyCommand != "" && !h.proxyCommands {
+		return errProxyCommandsDisabled
+	}
+	return nil
+}
+
+// SetSecretsRegistry sets the registry used to resolve host credential references
+func (h *Handlers) SetSecretsRegistry(registry *secrets.Registry) {
+	h.secrets = registry
+}
+
+// SetLogStore sets the store used to serve session and port logs
+func (h *Handlers) SetLogStore(store *utils.LogStore) {
+	h.logStore = store
+}
+
+// hostSSHConfig builds the SSH connection config for a host, applying server
+// defaults, rendering field templates and resolving credential references
+// from the secrets providers
+func (h *Handlers) hostSSHConfig(ctx context.Context, host *models.Host) (models.SSHConnectionConfig, error) {
+	resolved, err := host.Resolve()
+	if err != nil {
+		return models.SSHConnectionConfig{}, err
+	}
+	if err := h.checkProxyCommand(resolved); err != nil {
+		return models.SSHConnectionConfig{}, err
+	}
+	sshConfig := resolved.SSHConnectionConfig()
+	if sshConfig.ProxyURL == "" && sshConfig.ProxyCommand == "" {
+		sshConfig.ProxyURL = h.defaultSSHProxy
+	}
+
+	password, err := h.secrets.Resolve(ctx, host.Password)
+	if err != nil {
+		return sshConfig, fmt.Errorf("failed to resolve host password: %w", err)
+	}
+	sshConfig.Password = password
+
+	privateKey, err := h.secrets.Resolve(ctx, host.PrivateKey)
+	if err != nil {
+		return sshConfig, fmt.Errorf("failed to resolve host private key: %w", err)
+	}
+	sshConfig.PrivateKeyData = []byte(privateKey)
+
+	return sshConfig, nil
+}
+
+// acquireHost reserves a connection slot on the host according to its concurrency limit
+func (h *Handlers) acquireHost(ctx context.Context, host *models.Host) (func(), error) {
+	return h.hostBroker.Acquire(ctx, host.ID, manager.HostLimitFor(host))
+}
+
 // Response represents a standard API response
 type Response struct {
 	Success bool        `json:"success"`
@@ -29,3 +233,98 @@ type Response struct {
 	Error   string      `json:"error,omitempty"`
 	Message string      `json:"message,omitempty"`
 }
+
+// resolvePortID 解析路径参数 :id，支持端口 ID 或名称（名称可用 group_id 查询参数限定分组）
+// 失败时已写入错误响应
+func (h *Handlers) resolvePortID(c *gin.Context) (uint, bool) {
+	ref := c.Param("id")
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		return uint(id), true
+	}
+
+	groupID, err := strconv.ParseUint(c.DefaultQuery("group_id", "0"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid group ID",
+		})
+		return 0, false
+	}
+
+	port, err := h.storage.GetPortByName(c.Request.Context(), ref, uint(groupID))
+	if err != nil {
+		c.JSON(lookupErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return 0, false
+	}
+	return port.ID, true
+}
+
+// resolveSessionID 解析路径参数 :id，支持会话 ID 或名称（名称可用 host_id 查询参数限定主机）
+// 失败时已写入错误响应
+func (h *Handlers) resolveSessionID(c *gin.Context) (uint, bool) {
+	ref := c.Param("id")
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		return uint(id), true
+	}
+
+	hostID, err := strconv.ParseUint(c.DefaultQuery("host_id", "0"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid host ID",
+		})
+		return 0, false
+	}
+
+	session, err := h.storage.GetTunnelSessionByName(c.Request.Context(), ref, uint(hostID))
+	if err != nil {
+		c.JSON(lookupErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return 0, false
+	}
+	return session.ID, true
+}
+
+// resolveGroupID 解析路径参数 :id，支持分组 ID 或名称（名称可用 project_id 查询参数限定项目）
+// 失败时已写入错误响应
+func (h *Handlers) resolveGroupID(c *gin.Context) (uint, bool) {
+	ref := c.Param("id")
+	if id, err := strconv.ParseUint(ref, 10, 32); err == nil {
+		return uint(id), true
+	}
+
+	projectID, err := strconv.ParseUint(c.DefaultQuery("project_id", "0"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid project ID",
+		})
+		return 0, false
+	}
+
+	group, err := h.storage.GetGroupByName(c.Request.Context(), ref, uint(projectID))
+	if err != nil {
+		c.JSON(lookupErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return 0, false
+	}
+	return group.ID, true
+}
+
+// lookupErrorStatus 名称解析错误对应的 HTTP 状态码
+func lookupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrNameNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrAmbiguousName), errors.Is(err, storage.ErrNameConflict):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/manager"
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/ssh/sshtest"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/operations"
+	"github.com/aqz236/port-fly/server/storage"
+	"github.com/aqz236/port-fly/server/storage/sqlite"
+)
+
+func TestProxyCommandsDisabledByDefault(t *testing.T) {
+	h := NewHandlers(nil, nil, utils.DiscardLogger())
+	host := &models.Host{Hostname: "app.internal", Port: 22, Username: "deploy", ProxyCommand: "nc %h %p"}
+
+	if _, err := h.hostSSHConfig(context.Background(), host); !errors.Is(err, errProxyCommandsDisabled) {
+		t.Fatalf("hostSSHConfig error = %v, want errProxyCommandsDisabled", err)
+	}
+
+	h.SetProxyCommands(true)
+	if err := h.checkProxyCommand(host); err != nil {
+		t.Fatalf("checkProxyCommand with proxy commands enabled: %v", err)
+	}
+}
+
+// newTestHandlers returns handlers over a fresh SQLite database and a session
+// manager accepting any host key, with port operations enabled
+func newTestHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	store, err := sqlite.NewSQLiteStorage(storage.StorageConfig{
+		Type:     string(storage.StorageTypeSQLite),
+		Database: filepath.Join(t.TempDir(), "portfly.db"),
+		Options:  map[string]string{"log_level": "silent"},
+	})
+	if err != nil {
+		t.Fatalf("failed to open storage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Migrate(); err != nil {
+		t.Fatalf("failed to migrate storage: %v", err)
+	}
+
+	sshConfig := models.DefaultConfig().SSH
+	sshConfig.HostKeyCallback = "accept"
+	sessionManager := manager.NewSessionManager(sshConfig, utils.DiscardLogger())
+	t.Cleanup(func() {
+		sessions, _ := sessionManager.ListSessions()
+		for _, session := range sessions {
+			sessionManager.DeleteSession(session.ID)
+		}
+	})
+
+	h := NewHandlers(store, sessionManager, utils.DiscardLogger())
+	h.SetOperations(operations.NewTracker(operations.DefaultRetention, utils.DiscardLogger()))
+	return h
+}
+
+// serve sends a request with an optional JSON body to handler, registered on
+// pattern, and decodes the response envelope
+func serve(t *testing.T, handler gin.HandlerFunc, method, pattern, path string, body any) (int, Response) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Handle(method, pattern, handler)
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	request := httptest.NewRequest(method, path, reader)
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var response Response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("%s %s: invalid response %q: %v", method, path, recorder.Body.String(), err)
+	}
+	return recorder.Code, response
+}
+
+// decodeData re-decodes the data of a response envelope into v
+func decodeData(t *testing.T, response Response, v any) {
+	t.Helper()
+	data, err := json.Marshal(response.Data)
+	if err != nil {
+		t.Fatalf("failed to encode response data: %v", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatalf("failed to decode response data %s: %v", data, err)
+	}
+}
+
+// startEchoServer starts a TCP server echoing what it reads, closed with the
+// test, and returns its port
+func startEchoServer(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start echo server: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// freePort returns a loopback port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// assertEcho dials address and checks a line comes back
+func assertEcho(t *testing.T, address string) {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", address, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(conn, "hello\n"); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read echo: %v", err)
+	}
+	if line != "hello\n" {
+		t.Fatalf("echo = %q, want %q", line, "hello\n")
+	}
+}
+
+// createTestHost saves a host for server in a new project and group
+func createTestHost(t *testing.T, h *Handlers, server *sshtest.Server) *models.Host {
+	t.Helper()
+	ctx := context.Background()
+	project := &models.Project{Name: "test"}
+	if err := h.storage.CreateProject(ctx, project); err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	group := &models.Group{Name: "test", ProjectID: project.ID}
+	if err := h.storage.CreateGroup(ctx, group); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	config := server.ConnectionConfig()
+	host := &models.Host{
+		Name:       "sshtest",
+		Hostname:   config.Host,
+		Port:       config.Port,
+		Username:   config.Username,
+		Password:   config.Password,
+		AuthMethod: string(models.AuthMethodPassword),
+		GroupID:    group.ID,
+	}
+	if err := h.storage.CreateHost(ctx, host); err != nil {
+		t.Fatalf("failed to create host: %v", err)
+	}
+	return host
+}
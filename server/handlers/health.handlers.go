@@ -2,26 +2,74 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/server/health"
+	"github.com/aqz236/port-fly/server/storage"
 )
 
-// Health check endpoint
+// SetHealth sets the registry probing the server's subsystems
+func (h *Handlers) SetHealth(registry *health.Registry) {
+	h.health = registry
+}
+
+// Health reports readiness with per-subsystem states. Degraded servers still
+// answer 200 so load balancers keep them; down servers answer 503.
 func (h *Handlers) Health(c *gin.Context) {
-	// Check storage health
-	if err := h.storage.Health(); err != nil {
+	report := h.health.Check(c.Request.Context())
+
+	if report.Status == health.StateDown {
 		c.JSON(http.StatusServiceUnavailable, Response{
 			Success: false,
-			Error:   "Storage unhealthy: " + err.Error(),
+			Data:    report,
+			Error:   "Server unhealthy: " + strings.Join(report.Reasons, "; "),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
-		Data: gin.H{
-			"status":  "healthy",
-			"service": "portfly-api",
-		},
+		Data:    report,
+	})
+}
+
+// Metrics exposes the health report in the Prometheus text format
+func (h *Handlers) Metrics(c *gin.Context) {
+	report := h.health.Check(c.Request.Context())
+
+	var body strings.Builder
+	if err := health.WritePrometheus(&body, report); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(body.String()))
+}
+
+// GetStorageCacheStats returns hit/miss counters of the storage cache
+func (h *Handlers) GetStorageCacheStats(c *gin.Context) {
+	stats := storage.CacheStats{}
+	if cached, ok := h.storage.(*storage.CachedStorage); ok {
+		stats = cached.Stats()
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    stats,
+	})
+}
+
+// GetStorageQueryStats returns query counts and latencies per operation and
+// table, along with the connection pool usage
+func (h *Handlers) GetStorageQueryStats(c *gin.Context) {
+	stats := storage.QueryStats{}
+	if reporter, ok := h.storage.(storage.QueryStatsReporter); ok {
+		stats = reporter.QueryStats()
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    stats,
 	})
 }
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// actorHeader 标识变更发起者的请求头，CLI 发送 用户名@主机名
+const actorHeader = "X-PortFly-Actor"
+
+// maxHistoryLimit 单次返回的历史版本上限
+const maxHistoryLimit = 500
+
+// changeActor 变更发起者：请求头 X-PortFly-Actor，缺省为客户端地址
+func changeActor(c *gin.Context) string {
+	if actor := c.GetHeader(actorHeader); actor != "" {
+		if len(actor) > 255 {
+			actor = actor[:255]
+		}
+		return actor
+	}
+	return c.ClientIP()
+}
+
+// entityFields 读取实体计入历史的字段，失败时记录日志并返回 nil
+func (h *Handlers) entityFields(entityType string, entity any) map[string]any {
+	fields, err := models.HistoryFields(entityType, entity)
+	if err != nil {
+		h.logger.Warn("Failed to read fields for change history", "entity", entityType, "error", err)
+		return nil
+	}
+	return fields
+}
+
+// newChangeRecord 生成一次变更的记录。before 和 after 为变更前后的字段：
+// 创建时 before 为空，删除时 after 为空。修改没有改变任何字段时返回 nil
+func newChangeRecord(c *gin.Context, entityType string, id uint, action string, before, after map[string]any) *models.ChangeRecord {
+	record := &models.ChangeRecord{
+		EntityType: entityType,
+		EntityID:   id,
+		Action:     action,
+		Actor:      changeActor(c),
+		RequestID:  c.GetString("request_id"),
+	}
+
+	switch action {
+	case models.ChangeCreate:
+		record.Snapshot = models.RedactFields(entityType, after)
+	case models.ChangeDelete:
+		record.Snapshot = models.RedactFields(entityType, before)
+	case models.ChangeRestore:
+		// 恢复总是记录，即使字段与当前相同
+		record.Changes = models.DiffFields(entityType, before, after)
+		record.Snapshot = models.RedactFields(entityType, after)
+	default:
+		record.Changes = models.DiffFields(entityType, before, after)
+		if len(record.Changes) == 0 {
+			return nil
+		}
+		record.Snapshot = models.RedactFields(entityType, after)
+	}
+	return record
+}
+
+// saveChange 保存变更记录；历史是附带信息，保存失败只记录日志，不影响请求结果
+func (h *Handlers) saveChange(ctx context.Context, record *models.ChangeRecord) {
+	if record == nil {
+		return
+	}
+	if err := h.storage.CreateChangeRecord(ctx, record); err != nil {
+		h.logger.Warn("Failed to record change",
+			"entity", record.EntityType, "id", record.EntityID, "action", record.Action, "error", err)
+	}
+}
+
+// recordChange 记录实体的一次创建、修改或删除
+func (h *Handlers) recordChange(c *gin.Context, entityType string, id uint, action string, before, after map[string]any) {
+	h.saveChange(c.Request.Context(), newChangeRecord(c, entityType, id, action, before, after))
+}
+
+// GetHostHistory 主机的变更历史，最新的版本在前
+func (h *Handlers) GetHostHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid host ID",
+		})
+		return
+	}
+	h.writeHistory(c, models.EntityHost, uint(id))
+}
+
+// GetPortHistory 端口的变更历史，最新的版本在前
+func (h *Handlers) GetPortHistory(c *gin.Context) {
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+	h.writeHistory(c, models.EntityPort, id)
+}
+
+// GetGroupHistory 分组的变更历史，最新的版本在前
+func (h *Handlers) GetGroupHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid group ID",
+		})
+		return
+	}
+	h.writeHistory(c, models.EntityGroup, uint(id))
+}
+
+// writeHistory 返回实体的历史版本，?limit 限制数量（默认 50）
+func (h *Handlers) writeHistory(c *gin.Context, entityType string, id uint) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid limit",
+		})
+		return
+	}
+
+	records, err := h.storage.GetChangeHistory(c.Request.Context(), entityType, id, min(limit, maxHistoryLimit))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    records,
+	})
+}
+
+// GetHostVersion 主机的一个历史版本
+func (h *Handlers) GetHostVersion(c *gin.Context) {
+	if record, ok := h.findVersion(c, models.EntityHost); ok {
+		c.JSON(http.StatusOK, Response{Success: true, Data: record})
+	}
+}
+
+// GetPortVersion 端口的一个历史版本
+func (h *Handlers) GetPortVersion(c *gin.Context) {
+	if record, ok := h.findVersion(c, models.EntityPort); ok {
+		c.JSON(http.StatusOK, Response{Success: true, Data: record})
+	}
+}
+
+// GetGroupVersion 分组的一个历史版本
+func (h *Handlers) GetGroupVersion(c *gin.Context) {
+	if record, ok := h.findVersion(c, models.EntityGroup); ok {
+		c.JSON(http.StatusOK, Response{Success: true, Data: record})
+	}
+}
+
+// findVersion 按路径参数 :id 和 :version 查找历史版本，失败时已写入错误响应。
+// 端口支持按名称引用，但已删除的端口只能使用 ID
+func (h *Handlers) findVersion(c *gin.Context, entityType string) (*models.ChangeRecord, bool) {
+	var id uint
+	if entityType == models.EntityPort {
+		portID, ok := h.resolvePortID(c)
+		if !ok {
+			return nil, false
+		}
+		id = portID
+	} else {
+		parsed, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid " + entityType + " ID",
+			})
+			return nil, false
+		}
+		id = uint(parsed)
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid version",
+		})
+		return nil, false
+	}
+
+	record, err := h.storage.GetChangeRecord(c.Request.Context(), entityType, id, version)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Version not found",
+		})
+		return nil, false
+	}
+	return record, true
+}
+
+// RestoreHostVersion 将主机恢复到某个历史版本；已删除的主机会被重新启用。
+// 密码和私钥不保存在历史中，恢复后保持当前取值
+func (h *Handlers) RestoreHostVersion(c *gin.Context) {
+	restoreVersion(h, c, models.EntityHost, historyTarget[models.Host]{
+		get: h.storage.GetHost,
+		prepare: func(host *models.Host) {
+			host.Group = models.Group{}
+			host.PortForwards = nil
+			host.TunnelSessions = nil
+		},
+		validate: validateHost,
+		save:     h.storage.UpdateHost,
+		remove:   h.storage.DeleteHost,
+	})
+}
+
+// RestorePortVersion 将端口恢复到某个历史版本；已删除的端口会被重新启用
+func (h *Handlers) RestorePortVersion(c *gin.Context) {
+	restoreVersion(h, c, models.EntityPort, historyTarget[models.Port]{
+		get: h.storage.GetPort,
+		prepare: func(port *models.Port) {
+			port.Group = models.Group{}
+			port.Host = nil
+			port.TargetPort = nil
+			port.SourcePorts = nil
+			port.TunnelSessions = nil
+		},
+		validate: func(port *models.Port) error { return h.checkLocalHooks(port.Hooks) }, // 其余由 UpdatePort 校验
+		save: func(ctx context.Context, port *models.Port) error {
+			if err := h.storage.UpdatePort(ctx, port); err != nil {
+				return err
+			}
+			h.syncPortOwnership(ctx, port)
+			return nil
+		},
+		remove: h.storage.DeletePort,
+	})
+}
+
+// RestoreGroupVersion 将分组恢复到某个历史版本；已删除的分组会被重新启用
+func (h *Handlers) RestoreGroupVersion(c *gin.Context) {
+	restoreVersion(h, c, models.EntityGroup, historyTarget[models.Group]{
+		get: h.storage.GetGroup,
+		prepare: func(group *models.Group) {
+			group.Project = models.Project{}
+			group.Hosts = nil
+			group.PortForwards = nil
+		},
+		validate: func(group *models.Group) error {
+			return models.ValidateMaintenanceWindows(group.MaintenanceWindows)
+		},
+		save:   h.storage.UpdateGroup,
+		remove: h.storage.DeleteGroup,
+	})
+}
+
+// historyTarget 恢复历史版本所需的存储操作
+type historyTarget[T any] struct {
+	get      func(ctx context.Context, id uint) (*T, error)
+	prepare  func(entity *T) // 清空关联对象，避免保存时连带写入
+	validate func(entity *T) error
+	save     func(ctx context.Context, entity *T) error
+	remove   func(ctx context.Context, id uint) error // 恢复失败时重新删除已启用的实体
+}
+
+// restoreVersion 将实体的字段恢复为历史版本的快照，并记录一次 restore 变更
+func restoreVersion[T any](h *Handlers, c *gin.Context, entityType string, target historyTarget[T]) {
+	record, ok := h.findVersion(c, entityType)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	id := record.EntityID
+
+	// 读取失败时尝试重新启用已删除的实体；未删除的实体不受影响
+	entity, err := target.get(ctx, id)
+	undeleted := false
+	if err != nil {
+		if err = h.storage.UndeleteEntity(ctx, entityType, id); err == nil {
+			undeleted = true
+			entity, err = target.get(ctx, id)
+		}
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// 失败时撤销重新启用
+	fail := func(status int, err error) {
+		if undeleted {
+			if removeErr := target.remove(ctx, id); removeErr != nil {
+				h.logger.Warn("Failed to delete entity again after a failed restore",
+					"entity", entityType, "id", id, "error", removeErr)
+			}
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+	}
+
+	before := h.entityFields(entityType, entity)
+	target.prepare(entity)
+	if err := models.ApplySnapshot(entity, record.Snapshot); err != nil {
+		fail(http.StatusInternalServerError, err)
+		return
+	}
+	if err := target.validate(entity); err != nil {
+		fail(http.StatusBadRequest, err)
+		return
+	}
+	if err := target.save(ctx, entity); err != nil {
+		fail(portErrorStatus(err), err)
+		return
+	}
+
+	change := newChangeRecord(c, entityType, id, models.ChangeRestore, before, h.entityFields(entityType, entity))
+	change.RestoredFrom = &record.Version
+	h.saveChange(ctx, change)
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    entity,
+		Message: "Restored to version " + strconv.Itoa(record.Version),
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"strconv"
@@ -13,8 +14,10 @@ import (
 
 // SSHExecRequest represents SSH command execution request
 type SSHExecRequest struct {
-	Command string `json:"command" binding:"required"`
-	Timeout int    `json:"timeout"` // in milliseconds
+	Command      string `json:"command" binding:"required"`
+	Timeout      int    `json:"timeout"`                 // in milliseconds
+	Sudo         bool   `json:"sudo"`                    // run the command through sudo
+	SudoPassword string `json:"sudo_password,omitempty"` // overrides the host's sudo password for this command
 }
 
 // SSHExecResponse represents SSH command execution response
@@ -48,22 +51,35 @@ func (h *Handlers) ConnectHost(c *gin.Context) {
 	}
 
 	// 创建SSH配置
-	sshConfig := models.SSHConnectionConfig{
-		Host:            host.Hostname,
-		Port:            host.Port,
-		Username:        host.Username,
-		AuthMethod:      models.AuthMethod(host.AuthMethod),
-		Password:        host.Password,
-		PrivateKeyData:  []byte(host.PrivateKey),
-		ConnectTimeout:  30 * time.Second,
-		HostKeyCallback: "accept", // 对于API连接，接受所有主机密钥
+	sshConfig, err := h.hostSSHConfig(c.Request.Context(), host)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
 	}
+	sshConfig.ConnectTimeout = 30 * time.Second
+	sshConfig.HostKeyCallback = "accept" // 对于API连接，接受所有主机密钥
 
 	// 创建SSH客户端
 	sshClient := sshpkg.NewSSHClient(
 		sshConfig,
 		h.logger.With("host_id", id),
 	)
+	// 主机要求一次性密码等键盘交互认证时，转发给发起请求的客户端回答
+	h.relayChallenges(sshClient, host)
+
+	// 获取主机连接槽位
+	release, err := h.acquireHost(c.Request.Context(), host)
+	if err != nil {
+		c.JSON(http.StatusTooManyRequests, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer release()
 
 	// 更新主机状态为连接中
 	host.Status = "connecting"
@@ -72,10 +88,11 @@ func (h *Handlers) ConnectHost(c *gin.Context) {
 	}
 
 	// 尝试连接
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(withChallengeScope(c.Request.Context(), changeActor(c), nil), 30*time.Second)
 	defer cancel()
 
 	err = sshClient.Connect(ctx)
+	h.recordAuth(ctx, host.ID, models.AuthSourceConnect, err)
 	if err != nil {
 		// 连接失败，更新状态
 		host.Status = "error"
@@ -173,22 +190,35 @@ func (h *Handlers) ExecuteSSHCommand(c *gin.Context) {
 	}
 
 	// 创建SSH配置
-	sshConfig := models.SSHConnectionConfig{
-		Host:            host.Hostname,
-		Port:            host.Port,
-		Username:        host.Username,
-		AuthMethod:      models.AuthMethod(host.AuthMethod),
-		Password:        host.Password,
-		PrivateKeyData:  []byte(host.PrivateKey),
-		ConnectTimeout:  30 * time.Second,
-		HostKeyCallback: "accept",
+	sshConfig, err := h.hostSSHConfig(c.Request.Context(), host)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
 	}
+	sshConfig.ConnectTimeout = 30 * time.Second
+	sshConfig.HostKeyCallback = "accept"
 
 	// 创建SSH客户端
 	sshClient := sshpkg.NewSSHClient(
 		sshConfig,
 		h.logger.With("host_id", id),
 	)
+	// 主机要求一次性密码等键盘交互认证时，转发给发起请求的客户端回答
+	h.relayChallenges(sshClient, host)
+
+	// 获取主机连接槽位
+	release, err := h.acquireHost(c.Request.Context(), host)
+	if err != nil {
+		c.JSON(http.StatusTooManyRequests, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer release()
 
 	// 设置超时
 	timeout := time.Duration(req.Timeout) * time.Millisecond
@@ -196,11 +226,12 @@ func (h *Handlers) ExecuteSSHCommand(c *gin.Context) {
 		timeout = 30 * time.Second
 	}
 
-	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	ctx, cancel := context.WithTimeout(withChallengeScope(c.Request.Context(), changeActor(c), nil), timeout)
 	defer cancel()
 
 	// 连接SSH
 	err = sshClient.Connect(ctx)
+	h.recordAuth(ctx, host.ID, models.AuthSourceCommand, err)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
@@ -233,7 +264,17 @@ func (h *Handlers) ExecuteSSHCommand(c *gin.Context) {
 	defer session.Close()
 
 	// 执行命令并获取输出
-	output, err := session.CombinedOutput(req.Command)
+	var output []byte
+	if req.Sudo {
+		// sudo 的密码经 stdin 输入，不出现在命令行和输出中
+		sudo := h.newSudoAuth(host, req.SudoPassword)
+		var combined bytes.Buffer
+		writer := &syncWriter{w: &combined}
+		err = sshpkg.RunSudo(ctx, session, req.Command, sudo.get, writer, writer)
+		output = []byte(sudo.scrub(combined.String()))
+	} else {
+		output, err = session.CombinedOutput(req.Command)
+	}
 	duration := time.Since(startTime)
 
 	response := SSHExecResponse{
@@ -282,28 +323,42 @@ func (h *Handlers) TestHostConnection(c *gin.Context) {
 	}
 
 	// 创建SSH配置
-	sshConfig := models.SSHConnectionConfig{
-		Host:            host.Hostname,
-		Port:            host.Port,
-		Username:        host.Username,
-		AuthMethod:      models.AuthMethod(host.AuthMethod),
-		Password:        host.Password,
-		PrivateKeyData:  []byte(host.PrivateKey),
-		ConnectTimeout:  10 * time.Second,
-		HostKeyCallback: "accept",
+	sshConfig, err := h.hostSSHConfig(c.Request.Context(), host)
+	if err != nil {
+		c.JSON(http.StatusOK, Response{
+			Success: false,
+			Message: "Connection failed: " + err.Error(),
+		})
+		return
 	}
+	sshConfig.ConnectTimeout = 10 * time.Second
+	sshConfig.HostKeyCallback = "accept"
 
 	// 创建SSH客户端
 	sshClient := sshpkg.NewSSHClient(
 		sshConfig,
 		h.logger.With("host_id", id),
 	)
+	// 主机要求一次性密码等键盘交互认证时，转发给发起请求的客户端回答
+	h.relayChallenges(sshClient, host)
+
+	// 获取主机连接槽位
+	release, err := h.acquireHost(c.Request.Context(), host)
+	if err != nil {
+		c.JSON(http.StatusOK, Response{
+			Success: false,
+			Message: "Connection failed: " + err.Error(),
+		})
+		return
+	}
+	defer release()
 
 	// 测试连接
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(withChallengeScope(c.Request.Context(), changeActor(c), nil), 15*time.Second)
 	defer cancel()
 
 	err = sshClient.Connect(ctx)
+	h.recordAuth(ctx, host.ID, models.AuthSourceTest, err)
 	if err != nil {
 		c.JSON(http.StatusOK, Response{
 			Success: false,
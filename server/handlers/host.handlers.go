@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/aqz236/port-fly/core/models"
 	"github.com/gin-gonic/gin"
@@ -10,8 +11,22 @@ import (
 
 // ===== Host Operations =====
 
+// GetHosts 获取主机列表，附带主机监控缓存中的实时状态（live：可达性、延迟、运行中的隧道数）
+// Query: metadata.<键>=<值> 按元数据过滤，如 metadata.env=prod，嵌套键用点分隔，重复同一参数匹配任一值；
+// live=false 不附带实时状态
 func (h *Handlers) GetHosts(c *gin.Context) {
-	hosts, err := h.storage.GetHosts(c.Request.Context())
+	filters, ok := metadataFilters(c)
+	if !ok {
+		return
+	}
+
+	var hosts []models.Host
+	var err error
+	if len(filters) > 0 {
+		hosts, err = h.storage.GetHostsByMetadata(c.Request.Context(), filters)
+	} else {
+		hosts, err = h.storage.GetHosts(c.Request.Context())
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
@@ -19,6 +34,7 @@ func (h *Handlers) GetHosts(c *gin.Context) {
 		})
 		return
 	}
+	h.withLiveState(c, hosts)
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
@@ -26,6 +42,57 @@ func (h *Handlers) GetHosts(c *gin.Context) {
 	})
 }
 
+// ExportSSHConfig 将托管主机导出为 OpenSSH 配置，便于直接使用 ssh 连接
+// Query: group_id=仅导出该分组, prefix=别名前缀, identity_dir=私钥文件目录
+func (h *Handlers) ExportSSHConfig(c *gin.Context) {
+	var groupID uint64
+	if raw := c.Query("group_id"); raw != "" {
+		var err error
+		if groupID, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid group_id parameter",
+			})
+			return
+		}
+	}
+
+	hosts, err := h.storage.GetHosts(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if groupID != 0 {
+		filtered := hosts[:0]
+		for _, host := range hosts {
+			if host.GroupID == uint(groupID) {
+				filtered = append(filtered, host)
+			}
+		}
+		hosts = filtered
+	}
+
+	var config strings.Builder
+	config.WriteString("# Generated by PortFly. Private keys are not exported; save them as the\n")
+	config.WriteString("# IdentityFile paths below.\n\n")
+	if err := models.WriteSSHConfig(&config, hosts, models.SSHConfigOptions{
+		AliasPrefix: c.Query("prefix"),
+		IdentityDir: c.Query("identity_dir"),
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="portfly_ssh_config"`)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(config.String()))
+}
+
 func (h *Handlers) GetHostsByGroup(c *gin.Context) {
 	groupID, err := strconv.ParseUint(c.Param("groupId"), 10, 32)
 	if err != nil {
@@ -44,6 +111,7 @@ func (h *Handlers) GetHostsByGroup(c *gin.Context) {
 		})
 		return
 	}
+	h.withLiveState(c, hosts)
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
@@ -61,6 +129,28 @@ func (h *Handlers) CreateHost(c *gin.Context) {
 		return
 	}
 
+	if err := validateHost(&host); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := h.checkProxyCommand(&host); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if h.rejectInvalidMetadata(c, models.EntityHost, host.Metadata) {
+		return
+	}
+
+	if h.rejectOverHostQuota(c, host.GroupID) {
+		return
+	}
+
 	if err := h.storage.CreateHost(c.Request.Context(), &host); err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
@@ -68,6 +158,7 @@ func (h *Handlers) CreateHost(c *gin.Context) {
 		})
 		return
 	}
+	h.recordChange(c, models.EntityHost, host.ID, models.ChangeCreate, nil, h.entityFields(models.EntityHost, &host))
 
 	c.JSON(http.StatusCreated, Response{
 		Success: true,
@@ -93,10 +184,12 @@ func (h *Handlers) GetHost(c *gin.Context) {
 		})
 		return
 	}
+	hosts := []models.Host{*host}
+	h.withLiveState(c, hosts)
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
-		Data:    host,
+		Data:    hosts[0],
 	})
 }
 
@@ -119,6 +212,29 @@ func (h *Handlers) UpdateHost(c *gin.Context) {
 		return
 	}
 
+	if err := validateHost(&host); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := h.checkProxyCommand(&host); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if h.rejectInvalidMetadata(c, models.EntityHost, host.Metadata) {
+		return
+	}
+
+	var before map[string]any
+	if existing, err := h.storage.GetHost(c.Request.Context(), uint(id)); err == nil {
+		before = h.entityFields(models.EntityHost, existing)
+	}
+
 	host.ID = uint(id)
 	if err := h.storage.UpdateHost(c.Request.Context(), &host); err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
@@ -127,6 +243,10 @@ func (h *Handlers) UpdateHost(c *gin.Context) {
 		})
 		return
 	}
+	h.recordChange(c, models.EntityHost, host.ID, models.ChangeUpdate, before, h.entityFields(models.EntityHost, &host))
+	if h.hostStatus != nil {
+		h.hostStatus.Forget(host.ID)
+	}
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
@@ -144,6 +264,11 @@ func (h *Handlers) DeleteHost(c *gin.Context) {
 		return
 	}
 
+	var before map[string]any
+	if existing, err := h.storage.GetHost(c.Request.Context(), uint(id)); err == nil {
+		before = h.entityFields(models.EntityHost, existing)
+	}
+
 	if err := h.storage.DeleteHost(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
@@ -151,6 +276,12 @@ func (h *Handlers) DeleteHost(c *gin.Context) {
 		})
 		return
 	}
+	if before != nil {
+		h.recordChange(c, models.EntityHost, uint(id), models.ChangeDelete, before, nil)
+	}
+	if h.hostStatus != nil {
+		h.hostStatus.Forget(uint(id))
+	}
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
@@ -201,9 +332,27 @@ func (h *Handlers) SearchHosts(c *gin.Context) {
 		})
 		return
 	}
+	h.withLiveState(c, hosts)
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
 		Data:    hosts,
 	})
 }
+
+// validateHost 检查维护窗口、传输方式与字段模板
+func validateHost(host *models.Host) error {
+	if err := models.ValidateMaintenanceWindows(host.MaintenanceWindows); err != nil {
+		return err
+	}
+	if err := host.ValidateTransport(); err != nil {
+		return err
+	}
+	if err := host.TerminalPolicy.Validate(); err != nil {
+		return err
+	}
+	if err := models.ValidateOSFamily(host.OSFamily); err != nil {
+		return err
+	}
+	return host.ValidateTemplates()
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/importer"
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// maxImportSize 导入文件的最大大小
+const maxImportSize = 8 << 20
+
+// ImportedHost 导入文件中的一个会话及其导入结果
+type ImportedHost struct {
+	importer.Entry
+	ID     uint   `json:"id,omitempty"` // 创建的主机 ID
+	Status string `json:"status"`       // new（预览）、existing、duplicate（文件中重复）、created、failed
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportHostsResult 主机导入结果
+type ImportHostsResult struct {
+	Format  importer.Format    `json:"format"`
+	DryRun  bool               `json:"dry_run"`
+	Hosts   []ImportedHost     `json:"hosts"`
+	Skipped []importer.Skipped `json:"skipped"` // 无法导入的会话，如 telnet、串口会话
+	Created int                `json:"created"`
+}
+
+// ImportHosts 从其他 SSH 客户端的导出文件导入主机（PuTTY 注册表导出、Termius CSV、SecureCRT XML、OpenSSH 配置）。
+// 请求体为 multipart/form-data（字段 file），或原始文件内容配合查询参数 name。
+// 查询参数 format 指定格式，省略时按内容和文件名识别；group_id 为目标分组；dry_run=true 时只预览不创建。
+// 已存在的主机（地址、端口和用户名相同）不会重复创建
+func (h *Handlers) ImportHosts(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "true"
+	groupID, err := strconv.ParseUint(c.DefaultQuery("group_id", "0"), 10, 32)
+	if err != nil || (groupID == 0 && !dryRun) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid group ID",
+		})
+		return
+	}
+
+	name, data, err := readImportFile(c)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var format importer.Format
+	if c.Query("format") != "" {
+		format, err = importer.ParseFormat(c.Query("format"))
+	} else {
+		format, err = importer.DetectFormat(name, data)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	parsed, err := importer.Parse(format, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	result, ok := h.importEntries(c, parsed, uint(groupID), dryRun)
+	if !ok {
+		return
+	}
+
+	response := Response{
+		Success: true,
+		Data:    result,
+	}
+	if !dryRun {
+		response.Message = fmt.Sprintf("Imported %d host(s)", result.Created)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// importEntries creates the hosts of a parsed export in a group, skipping
+// those that already exist or repeat in the export; with dryRun it only
+// reports what would be created. Errors are written to c.
+func (h *Handlers) importEntries(c *gin.Context, parsed *importer.Result, groupID uint, dryRun bool) (*ImportHostsResult, bool) {
+	ctx := c.Request.Context()
+	existing, err := h.storage.GetHosts(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+
+	result := &ImportHostsResult{
+		Format:  parsed.Format,
+		DryRun:  dryRun,
+		Hosts:   make([]ImportedHost, 0, len(parsed.Entries)),
+		Skipped: parsed.Skipped,
+	}
+	newHosts := 0
+	for _, entry := range parsed.Entries {
+		imported := ImportedHost{Entry: entry, Status: "new"}
+		for _, host := range existing {
+			if entry.Matches(host) {
+				imported.ID, imported.Status = host.ID, "existing"
+				break
+			}
+		}
+		for _, earlier := range result.Hosts {
+			if imported.Status == "new" && earlier.Status == "new" && entry.Matches(earlier.Host(0)) {
+				imported.Status = "duplicate"
+			}
+		}
+		if imported.Status == "new" {
+			newHosts++
+		}
+		result.Hosts = append(result.Hosts, imported)
+	}
+
+	if dryRun {
+		return result, true
+	}
+
+	group, err := h.storage.GetGroup(ctx, groupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Group not found",
+		})
+		return nil, false
+	}
+	if usage, err := h.storage.GetProjectUsage(ctx, group.ProjectID); err == nil && usage.HostsExceeded(newHosts) {
+		h.rejectOverQuota(c, usage, fmt.Sprintf("project %d allows at most %d hosts", group.ProjectID, usage.MaxHosts))
+		return nil, false
+	}
+
+	for i := range result.Hosts {
+		imported := &result.Hosts[i]
+		if imported.Status != "new" {
+			continue
+		}
+		host := imported.Host(group.ID)
+		if err := validateHost(&host); err == nil {
+			err = h.storage.CreateHost(ctx, &host)
+		}
+		if err != nil {
+			imported.Status, imported.Error = "failed", err.Error()
+			continue
+		}
+		h.recordChange(c, models.EntityHost, host.ID, models.ChangeCreate, nil, h.entityFields(models.EntityHost, &host))
+		imported.ID, imported.Status = host.ID, "created"
+		result.Created++
+	}
+	h.logger.Info("Hosts imported", "format", parsed.Format, "group_id", group.ID, "created", result.Created, "skipped", len(result.Skipped))
+	return result, true
+}
+
+// readImportFile reads the uploaded export and its file name
+func readImportFile(c *gin.Context) (string, []byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	if mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type")); mediaType == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return "", nil, err
+		}
+		file, err := header.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		return header.Filename, data, err
+	}
+
+	data, err := io.ReadAll(c.Request.Body)
+	if err == nil && len(data) == 0 {
+		err = errors.New("no file in the request")
+	}
+	return c.Query("name"), data, err
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/hoststatus"
+)
+
+// SetHostStatus sets the monitor whose live host state is joined into host lists
+func (h *Handlers) SetHostStatus(monitor *hoststatus.Monitor) {
+	h.hostStatus = monitor
+}
+
+// withLiveState fills in the live state of hosts from the host monitor:
+// reachability and latency of their SSH port and the tunnels running through
+// them. Clients pass live=false to skip it.
+func (h *Handlers) withLiveState(c *gin.Context, hosts []models.Host) {
+	if h.hostStatus == nil || len(hosts) == 0 || c.Query("live") == "false" {
+		return
+	}
+	ctx := c.Request.Context()
+
+	states := h.hostStatus.Lookup(ctx, hosts)
+	tunnels, err := h.storage.GetActiveTunnelCounts(ctx)
+	if err != nil {
+		h.logger.Warn("Failed to count active tunnels", "error", err)
+		tunnels = map[uint]int{}
+	}
+	for hostID, sessionIDs := range h.portTunnels.byHost() {
+		for _, sessionID := range sessionIDs {
+			if session, err := h.sessionManager.GetSession(sessionID); err == nil && session.Status == models.StatusActive {
+				tunnels[hostID]++
+			}
+		}
+	}
+
+	for i := range hosts {
+		state, ok := states[hosts[i].ID]
+		if !ok {
+			state.Reachability = models.HostUnprobed
+		}
+		state.ActiveTunnels = tunnels[hosts[i].ID]
+		hosts[i].Live = &state
+	}
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	sshpkg "github.com/aqz236/port-fly/core/ssh"
+)
+
+// 主机预检各项检查的超时
+const (
+	validateDNSTimeout  = 5 * time.Second
+	validateTCPTimeout  = 5 * time.Second
+	validateAuthTimeout = 20 * time.Second
+	validateSudoTimeout = 10 * time.Second
+)
+
+// 主机预检单项检查的状态
+const (
+	HostCheckPassed  = "passed"
+	HostCheckFailed  = "failed"
+	HostCheckSkipped = "skipped"
+)
+
+// ValidateHostRequest 预检未保存的主机定义，不会写入数据库
+type ValidateHostRequest struct {
+	Host      models.Host `json:"host"`
+	CheckSudo bool        `json:"check_sudo,omitempty"` // 同时检查登录用户能否使用 sudo
+}
+
+// HostCheck 单项检查的结果
+type HostCheck struct {
+	Name       string   `json:"name"`   // dns, tcp, auth, sudo
+	Status     string   `json:"status"` // passed, failed, skipped
+	Message    string   `json:"message,omitempty"`
+	Addresses  []string `json:"addresses,omitempty"` // dns 检查解析到的地址
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// HostValidation 主机预检结果，没有失败的检查时 Valid 为 true
+type HostValidation struct {
+	Valid  bool        `json:"valid"`
+	Checks []HostCheck `json:"checks"`
+}
+
+// add 记录一项检查，失败时整体预检不通过
+func (v *HostValidation) add(check HostCheck, started time.Time) {
+	if !started.IsZero() {
+		check.DurationMs = time.Since(started).Milliseconds()
+	}
+	if check.Status == HostCheckFailed {
+		v.Valid = false
+	}
+	v.Checks = append(v.Checks, check)
+}
+
+// skip 记录跳过的检查
+func (v *HostValidation) skip(reason string, names ...string) *HostValidation {
+	for _, name := range names {
+		v.add(HostCheck{Name: name, Status: HostCheckSkipped, Message: reason}, time.Time{})
+	}
+	return v
+}
+
+// ValidateHost 在保存主机前依次检查 DNS 解析、TCP 连通、SSH 认证，以及可选的 sudo 是否可用
+// 前一项失败时跳过其后的检查；经代理连接的主机由代理解析和连接，只检查认证
+// 请求体: {"host": {...}, "check_sudo": true}；检查失败仍返回 200，结果在 data.checks 中
+func (h *Handlers) ValidateHost(c *gin.Context) {
+	var request ValidateHostRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+	if request.Host.Hostname == "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "host.hostname is required",
+		})
+		return
+	}
+	if err := validateHost(&request.Host); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := h.checkProxyCommand(&request.Host); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	// 与保存时的数据库默认值一致
+	if request.Host.Port == 0 {
+		request.Host.Port = 22
+	}
+	if request.Host.AuthMethod == "" {
+		request.Host.AuthMethod = string(models.AuthMethodPassword)
+	}
+
+	ctx := withChallengeScope(c.Request.Context(), changeActor(c), nil)
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    h.runHostChecks(ctx, &request.Host, request.CheckSudo),
+	})
+}
+
+// runHostChecks runs the preflight checks in order, skipping the ones after
+// the first failure
+func (h *Handlers) runHostChecks(ctx context.Context, host *models.Host, checkSudo bool) *HostValidation {
+	result := &HostValidation{Valid: true}
+	after := func(name string) []string {
+		names := []string{"dns", "tcp", "auth"}
+		if checkSudo {
+			names = append(names, "sudo")
+		}
+		for i, n := range names {
+			if n == name {
+				return names[i+1:]
+			}
+		}
+		return nil
+	}
+
+	sshConfig, configErr := h.hostSSHConfig(ctx, host)
+	address := net.JoinHostPort(sshConfig.Host, strconv.Itoa(sshConfig.Port))
+
+	if sshConfig.ProxyURL != "" || sshConfig.ProxyCommand != "" {
+		result.skip("host is reached through a proxy, which resolves and connects to it", "dns", "tcp")
+	} else {
+		if !checkHostDNS(ctx, result, sshConfig.Host) {
+			return result.skip("dns check failed", after("dns")...)
+		}
+		if !checkHostTCP(ctx, result, address) {
+			return result.skip("tcp check failed", after("tcp")...)
+		}
+	}
+
+	if configErr != nil {
+		result.add(HostCheck{Name: "auth", Status: HostCheckFailed, Message: configErr.Error()}, time.Time{})
+		return result.skip("auth check failed", after("auth")...)
+	}
+
+	sshConfig.ConnectTimeout = validateTCPTimeout
+	sshConfig.HandshakeTimeout = validateAuthTimeout
+	sshConfig.HostKeyCallback = "accept"
+	sshClient := sshpkg.NewSSHClient(sshConfig, h.logger.With("host", address))
+	// 主机要求一次性密码等键盘交互认证时，转发给发起请求的客户端回答
+	h.relayChallenges(sshClient, host)
+
+	started := time.Now()
+	// Saved hosts are validated within their concurrency limit
+	if host.ID != 0 {
+		release, err := h.acquireHost(ctx, host)
+		if err != nil {
+			result.add(HostCheck{Name: "auth", Status: HostCheckFailed, Message: err.Error()}, started)
+			return result.skip("auth check failed", after("auth")...)
+		}
+		defer release()
+	}
+
+	authCtx, cancel := context.WithTimeout(ctx, validateAuthTimeout)
+	err := sshClient.Connect(authCtx)
+	cancel()
+	h.recordAuth(ctx, host.ID, models.AuthSourceValidate, err)
+	if err != nil {
+		result.add(HostCheck{Name: "auth", Status: HostCheckFailed, Message: err.Error()}, started)
+		return result.skip("auth check failed", after("auth")...)
+	}
+	defer sshClient.Disconnect()
+
+	result.add(HostCheck{
+		Name:    "auth",
+		Status:  HostCheckPassed,
+		Message: fmt.Sprintf("authenticated as %s with %s", sshConfig.Username, sshConfig.AuthMethod),
+	}, started)
+
+	if checkSudo {
+		password, source := sshConfig.Password, "the login password"
+		if host.SudoPassword != "" {
+			if password, err = h.secrets.Resolve(ctx, host.SudoPassword); err != nil {
+				result.add(HostCheck{Name: "sudo", Status: HostCheckFailed, Message: "failed to resolve sudo password: " + err.Error()}, time.Now())
+				return result
+			}
+			source = "the sudo password"
+		}
+		checkHostSudo(result, sshClient, password, source)
+	}
+	return result
+}
+
+// checkHostDNS 解析主机名；IP 地址直接通过
+func checkHostDNS(ctx context.Context, result *HostValidation, hostname string) bool {
+	started := time.Now()
+	if ip := net.ParseIP(hostname); ip != nil {
+		result.add(HostCheck{Name: "dns", Status: HostCheckPassed, Message: "hostname is an IP address", Addresses: []string{ip.String()}}, started)
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, validateDNSTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, hostname)
+	if err != nil {
+		result.add(HostCheck{Name: "dns", Status: HostCheckFailed, Message: err.Error()}, started)
+		return false
+	}
+	result.add(HostCheck{Name: "dns", Status: HostCheckPassed, Addresses: addrs}, started)
+	return true
+}
+
+// checkHostTCP 连接 SSH 端口，并读取服务端的版本标识
+func checkHostTCP(ctx context.Context, result *HostValidation, address string) bool {
+	started := time.Now()
+	dialer := net.Dialer{Timeout: validateTCPTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		result.add(HostCheck{Name: "tcp", Status: HostCheckFailed, Message: err.Error()}, started)
+		return false
+	}
+	defer conn.Close()
+
+	message := "connected to " + address
+	conn.SetReadDeadline(time.Now().Add(validateTCPTimeout))
+	buf := make([]byte, 255)
+	if n, err := conn.Read(buf); err == nil {
+		if banner := strings.TrimSpace(string(buf[:n])); strings.HasPrefix(banner, "SSH-") {
+			message += ", server " + strings.SplitN(banner, "\n", 2)[0]
+		}
+	}
+	result.add(HostCheck{Name: "tcp", Status: HostCheckPassed, Message: message}, started)
+	return true
+}
+
+// checkHostSudo 检查登录用户能否使用 sudo：先试免密码，再用 sudo 密码或登录密码
+func checkHostSudo(result *HostValidation, client *sshpkg.SSHClient, password, source string) {
+	started := time.Now()
+	run := func(command, stdin string) error {
+		session, err := client.GetClient().NewSession()
+		if err != nil {
+			return err
+		}
+		defer session.Close()
+		if stdin != "" {
+			session.Stdin = strings.NewReader(stdin + "\n")
+		}
+		timer := time.AfterFunc(validateSudoTimeout, func() { session.Close() })
+		defer timer.Stop()
+		output, err := session.CombinedOutput(command)
+		if err != nil && len(output) > 0 {
+			return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+		}
+		return err
+	}
+
+	err := run("sudo -n true", "")
+	if err == nil {
+		result.add(HostCheck{Name: "sudo", Status: HostCheckPassed, Message: "passwordless sudo"}, started)
+		return
+	}
+	if password != "" {
+		if err = run("sudo -S -k -p '' true", password); err == nil {
+			result.add(HostCheck{Name: "sudo", Status: HostCheckPassed, Message: "sudo with " + source}, started)
+			return
+		}
+	}
+	result.add(HostCheck{Name: "sudo", Status: HostCheckFailed, Message: err.Error()}, started)
+}
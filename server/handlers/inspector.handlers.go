@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ListHTTPRequests returns the recent requests seen by a session that
+// inspects HTTP, newest first, without headers and bodies
+func (h *Handlers) ListHTTPRequests(c *gin.Context) {
+	records, err := h.sessionManager.ListHTTPRequests(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    records,
+	})
+}
+
+// GetHTTPRequest returns a recent request with its headers and bodies
+func (h *Handlers) GetHTTPRequest(c *gin.Context) {
+	id, ok := httpRequestID(c)
+	if !ok {
+		return
+	}
+
+	record, err := h.sessionManager.GetHTTPRequest(c.Param("id"), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    record,
+	})
+}
+
+// ReplayHTTPRequest sends a recent request through the tunnel again and
+// returns the new exchange, which is also kept in the session's requests
+func (h *Handlers) ReplayHTTPRequest(c *gin.Context) {
+	id, ok := httpRequestID(c)
+	if !ok {
+		return
+	}
+
+	sessionID := c.Param("id")
+	if _, err := h.sessionManager.GetHTTPRequest(sessionID, id); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	record, err := h.sessionManager.ReplayHTTPRequest(c.Request.Context(), sessionID, id)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, models.ErrHTTPReplayTruncated) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    record,
+		Message: "Request replayed",
+	})
+}
+
+// httpRequestID parses the :requestId parameter, writing a 400 when invalid
+func httpRequestID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("requestId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
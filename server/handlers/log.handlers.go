@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// defaultLogLimit is the number of entries returned when no limit is given
+const defaultLogLimit = 200
+
+// GetPortLogs returns recent log entries for a port
+// Query: level=debug|info|warn|error, limit=N, follow=true (server-sent events)
+func (h *Handlers) GetPortLogs(c *gin.Context) {
+	h.serveLogs(c, utils.LogScopePort)
+}
+
+// GetSessionLogs returns recent log entries for a tunnel session
+// Query: level=debug|info|warn|error, limit=N, follow=true (server-sent events)
+func (h *Handlers) GetSessionLogs(c *gin.Context) {
+	h.serveLogs(c, utils.LogScopeSession)
+}
+
+// serveLogs writes buffered entries for the scope and optionally streams new ones
+func (h *Handlers) serveLogs(c *gin.Context, scope string) {
+	id := c.Param("id")
+
+	if h.logStore == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Log capture is not enabled",
+		})
+		return
+	}
+
+	minLevel, err := utils.ParseLogLevel(c.DefaultQuery("level", "debug"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	limit := defaultLogLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid limit",
+			})
+			return
+		}
+	}
+
+	if c.Query("follow") != "true" {
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data:    h.logStore.Entries(scope, id, minLevel, limit),
+		})
+		return
+	}
+
+	// Subscribe before sending the backlog so no entries are missed in between
+	entries, unsubscribe := h.logStore.Follow(scope, id)
+	defer unsubscribe()
+
+	// Streams outlive the server write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	var lastSent time.Time
+	for _, entry := range h.logStore.Entries(scope, id, minLevel, limit) {
+		c.SSEvent("log", entry)
+		lastSent = entry.Time
+	}
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				return false
+			}
+			// Skip entries already sent as part of the backlog
+			if entry.Time.After(lastSent) && utils.LevelAtLeast(entry.Level, minLevel) {
+				c.SSEvent("log", entry)
+			}
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
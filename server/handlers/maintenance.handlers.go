@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/maintenance"
+)
+
+// SetMaintenance sets the checker used to refuse starts during maintenance windows
+func (h *Handlers) SetMaintenance(checker *maintenance.Checker) {
+	h.maintenance = checker
+}
+
+// PortStoppedForMaintenance withdraws the DNS name of a port stopped by a window
+func (h *Handlers) PortStoppedForMaintenance(ctx context.Context, portID uint) {
+	h.syncPortDNS(ctx, portID, models.PortStatusAvailable)
+}
+
+// GetMaintenance 列出当前处于维护窗口内的主机和分组
+func (h *Handlers) GetMaintenance(c *gin.Context) {
+	if !h.requireMaintenance(c) {
+		return
+	}
+
+	active, err := h.maintenance.Active(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    active,
+	})
+}
+
+// GetHostMaintenance 获取主机的维护状态（包含所属分组的窗口）
+func (h *Handlers) GetHostMaintenance(c *gin.Context) {
+	if !h.requireMaintenance(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid host ID",
+		})
+		return
+	}
+
+	status, err := h.maintenance.HostStatus(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Host not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    status,
+	})
+}
+
+// GetGroupMaintenance 获取分组的维护状态
+func (h *Handlers) GetGroupMaintenance(c *gin.Context) {
+	if !h.requireMaintenance(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid group ID",
+		})
+		return
+	}
+
+	status, err := h.maintenance.GroupStatus(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Group not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    status,
+	})
+}
+
+// rejectHostInMaintenance 主机处于维护窗口时返回 409 及维护状态，并返回 true
+func (h *Handlers) rejectHostInMaintenance(c *gin.Context, what string, hostID uint) bool {
+	if h.maintenance == nil {
+		return false
+	}
+	status, err := h.maintenance.HostStatus(c.Request.Context(), hostID)
+	// Unknown hosts are left to the caller's own lookup
+	return err == nil && h.rejectInMaintenance(c, what, status)
+}
+
+// rejectPortInMaintenance 端口所属主机或分组处于维护窗口时返回 409，并返回 true
+func (h *Handlers) rejectPortInMaintenance(c *gin.Context, port *models.Port) bool {
+	if h.maintenance == nil {
+		return false
+	}
+	status, err := h.maintenance.PortStatus(c.Request.Context(), port)
+	return err == nil && h.rejectInMaintenance(c, "port "+port.GetDisplayName(), status)
+}
+
+// rejectInMaintenance 写入拒绝响应，Data 为维护状态，便于客户端展示原因
+func (h *Handlers) rejectInMaintenance(c *gin.Context, what string, status models.MaintenanceStatus) bool {
+	if !status.Active {
+		return false
+	}
+
+	err := maintenance.Error(what, status)
+	h.logger.Info("Start refused by maintenance window", "target", what, "scope", status.Scope, "scope_id", status.ScopeID, "window", status.Window)
+	c.JSON(http.StatusConflict, Response{
+		Success: false,
+		Data:    status,
+		Error:   err.Error(),
+	})
+	return true
+}
+
+// requireMaintenance 未启用维护窗口时返回 503
+func (h *Handlers) requireMaintenance(c *gin.Context) bool {
+	if h.maintenance == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Maintenance windows are not enabled",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// SetMetadataSchemas sets the schemas entity metadata is validated against
+func (h *Handlers) SetMetadataSchemas(schemas models.MetadataSchemas) {
+	h.metadataSchemas = schemas
+}
+
+// GetMetadataSchemas 获取各实体类型（project、group、host、port）的元数据模式，未配置时为空
+func (h *Handlers) GetMetadataSchemas(c *gin.Context) {
+	schemas := h.metadataSchemas
+	if schemas == nil {
+		schemas = models.MetadataSchemas{}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    schemas,
+	})
+}
+
+// rejectInvalidMetadata 元数据不符合实体类型的模式时返回 400
+func (h *Handlers) rejectInvalidMetadata(c *gin.Context, entity string, metadata models.Metadata) bool {
+	if err := h.metadataSchemas.Validate(entity, metadata); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return true
+	}
+	return false
+}
+
+// metadataFilters 解析 metadata.<键>=<值> 查询参数，参数无效时返回 400
+func metadataFilters(c *gin.Context) ([]models.MetadataFilter, bool) {
+	filters, err := models.ParseMetadataFilters(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+	return filters, true
+}
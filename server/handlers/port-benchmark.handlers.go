@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// benchmarkTimeout 限制一次基准测试的总时长，包括隧道与直连两条路径
+const benchmarkTimeout = 3 * time.Minute
+
+// PortBenchmarkRequest 端口隧道基准测试的选项，均可省略
+type PortBenchmarkRequest struct {
+	Mode     string `json:"mode"`     // echo（默认）测量 SSH 连接，target 经隧道连接目标
+	Samples  int    `json:"samples"`  // 往返次数，默认 20
+	Bytes    int64  `json:"bytes"`    // 吞吐测试最多传输的字节数，默认 16 MiB
+	Duration int    `json:"duration"` // 吞吐测试最长时间（毫秒），默认 5 秒
+	Timeout  int    `json:"timeout"`  // 每次往返的超时（毫秒），默认 5 秒
+	HTTP     *bool  `json:"http"`     // 目标是否为 HTTP，默认取目标端口的服务类型
+	Path     string `json:"path"`     // HTTP 目标请求的路径，默认 /
+}
+
+// BenchmarkPort 对运行中的端口隧道进行短时的延迟与吞吐测试，并与不经隧道的直连对比
+// echo 模式在主机上回显字节，测量 SSH 连接的往返和上传速率；target 模式经隧道连接目标，
+// HTTP 目标按请求测量延迟并下载 path 测量吞吐，其他目标只测量建立连接的时间
+func (h *Handlers) BenchmarkPort(c *gin.Context) {
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	var request PortBenchmarkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid request body: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	sessionID, running := h.portTunnels.get(id)
+	if !running {
+		c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Error:   "Port tunnel is not running",
+		})
+		return
+	}
+	port, err := h.storage.GetPort(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	options := models.BenchmarkOptions{
+		Mode:     request.Mode,
+		Samples:  request.Samples,
+		Bytes:    request.Bytes,
+		Duration: time.Duration(request.Duration) * time.Millisecond,
+		Timeout:  time.Duration(request.Timeout) * time.Millisecond,
+		HTTP:     port.TargetPort != nil && port.TargetPort.ServiceType == models.ServiceHTTP,
+		Path:     request.Path,
+	}
+	if request.HTTP != nil {
+		options.HTTP = *request.HTTP
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), benchmarkTimeout)
+	defer cancel()
+	result, err := h.sessionManager.Benchmark(ctx, sessionID, options)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrInvalidBenchmark) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("Port benchmark completed", "port_id", id, "mode", result.Mode, "duration_ms", result.Duration)
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    result,
+	})
+}
@@ -0,0 +1,555 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	sshpkg "github.com/aqz236/port-fly/core/ssh"
+	"github.com/aqz236/port-fly/server/operations"
+)
+
+// 端口控制操作的超时
+const (
+	portStartTimeout  = 2 * time.Minute
+	portStopTimeout   = 30 * time.Second
+	portHealthTimeout = 5 * time.Second
+)
+
+// 端口控制动作
+const (
+	PortActionStart = "start"
+	PortActionStop  = "stop"
+)
+
+// ControlPortRequest 启动或停止远程端口的隧道
+type ControlPortRequest struct {
+	Action string `json:"action" binding:"required"` // start 或 stop
+}
+
+// portTunnels maps ports to the sessions running their tunnels, the hosts
+// those sessions connect to, the tunnel session records kept for them and
+// the host broker slots they hold
+type portTunnels struct {
+	mu       sync.Mutex
+	sessions map[uint]string
+	hosts    map[uint]uint
+	records  map[uint]uint
+	releases map[uint]func()
+}
+
+func newPortTunnels() *portTunnels {
+	return &portTunnels{
+		sessions: make(map[uint]string),
+		hosts:    make(map[uint]uint),
+		records:  make(map[uint]uint),
+		releases: make(map[uint]func()),
+	}
+}
+
+func (t *portTunnels) get(portID uint) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sessionID, ok := t.sessions[portID]
+	return sessionID, ok
+}
+
+// set records a running tunnel and the ID of its tunnel session record (0
+// when none was saved); release frees its host slot once the tunnel is removed
+func (t *portTunnels) set(portID, hostID uint, sessionID string, recordID uint, release func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sessions[portID] = sessionID
+	t.hosts[portID] = hostID
+	t.records[portID] = recordID
+	t.releases[portID] = release
+}
+
+// remove forgets a port's tunnel and returns the ID of its tunnel session
+// record, or 0
+func (t *portTunnels) remove(portID uint) uint {
+	t.mu.Lock()
+	release := t.releases[portID]
+	recordID := t.records[portID]
+	delete(t.sessions, portID)
+	delete(t.hosts, portID)
+	delete(t.records, portID)
+	delete(t.releases, portID)
+	t.mu.Unlock()
+	if release != nil {
+		release()
+	}
+	return recordID
+}
+
+// record returns the port and session of the running tunnel a tunnel session
+// record was saved for
+func (t *portTunnels) record(recordID uint) (uint, string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for portID, id := range t.records {
+		if id == recordID && recordID != 0 {
+			return portID, t.sessions[portID], true
+		}
+	}
+	return 0, "", false
+}
+
+// port returns the port whose tunnel runs in a session
+func (t *portTunnels) port(sessionID string) (uint, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for portID, id := range t.sessions {
+		if id == sessionID {
+			return portID, true
+		}
+	}
+	return 0, false
+}
+
+// sessionRecords returns the tunnel session record of each running tunnel's
+// session that has one
+func (t *portTunnels) sessionRecords() map[string]uint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	records := make(map[string]uint, len(t.records))
+	for portID, recordID := range t.records {
+		if recordID != 0 {
+			records[t.sessions[portID]] = recordID
+		}
+	}
+	return records
+}
+
+// byHost returns the sessions of the running tunnels by host
+func (t *portTunnels) byHost() map[uint][]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sessions := make(map[uint][]string, len(t.hosts))
+	for portID, hostID := range t.hosts {
+		sessions[hostID] = append(sessions[hostID], t.sessions[portID])
+	}
+	return sessions
+}
+
+// SetOperations sets the tracker of asynchronous port commands
+func (h *Handlers) SetOperations(tracker *operations.Tracker) {
+	h.operations = tracker
+}
+
+// ControlPort 异步启动或停止远程端口到其目标本地端口的隧道，立即返回 202 和操作
+// 启动依次经过 connecting、handshake、listener_bound、healthy 阶段，停止经过 stopping、stopped；
+// 进度以 operation.progress、完成以 operation.completed 事件推送到 /ws，也可通过 /operations/:id 查询
+// 端口已有进行中的操作时返回 409 和该操作
+func (h *Handlers) ControlPort(c *gin.Context) {
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	var request ControlPortRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	var run func(ctx context.Context, report operations.Report) error
+	switch request.Action {
+	case PortActionStart:
+		if run, ok = h.prepareStartPort(c, id); !ok {
+			return
+		}
+	case PortActionStop:
+		sessionID, running := h.portTunnels.get(id)
+		if !running {
+			c.JSON(http.StatusConflict, Response{
+				Success: false,
+				Error:   "Port tunnel is not running",
+			})
+			return
+		}
+		run = func(ctx context.Context, report operations.Report) error {
+			return h.stopPortTunnel(ctx, id, sessionID, report)
+		}
+	default:
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid action: " + request.Action,
+		})
+		return
+	}
+
+	h.runPortOperation(c, id, request.Action, run)
+}
+
+// runPortOperation starts run as the port's asynchronous start or stop
+// operation and writes the 202 response, or 409 when the port is busy
+func (h *Handlers) runPortOperation(c *gin.Context, id uint, action string, run func(ctx context.Context, report operations.Report) error) {
+	timeout := portStartTimeout
+	if action == PortActionStop {
+		timeout = portStopTimeout
+	}
+	// Hooks get their own time on top
+	if port, err := h.storage.GetPort(c.Request.Context(), id); err == nil {
+		timeout += portHooksTimeout(port, action)
+	}
+	actor := changeActor(c)
+	ctx := withChallengeScope(c.Request.Context(), actor, nil)
+	operation, err := h.operations.Start(ctx, "port."+action, id, actor, timeout, run)
+	if errors.Is(err, operations.ErrBusy) {
+		c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Data:    operation,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("Port operation started", "port_id", id, "action", action, "operation_id", operation.ID)
+	c.JSON(http.StatusAccepted, Response{
+		Success: true,
+		Data:    operation,
+		Message: "Port " + action + " accepted",
+	})
+}
+
+// prepareStartPort checks that the port can start and returns the operation
+// bringing its tunnel up. On failure the response is already written.
+func (h *Handlers) prepareStartPort(c *gin.Context, id uint) (func(ctx context.Context, report operations.Report) error, bool) {
+	fail := func(status int, message string) (func(context.Context, operations.Report) error, bool) {
+		c.JSON(status, Response{
+			Success: false,
+			Error:   message,
+		})
+		return nil, false
+	}
+
+	if _, running := h.portTunnels.get(id); running {
+		return fail(http.StatusConflict, "Port tunnel is already running")
+	}
+	port, err := h.storage.GetPort(c.Request.Context(), id)
+	if err != nil {
+		return fail(portErrorStatus(err), err.Error())
+	}
+	if !port.IsRemotePort() {
+		return fail(http.StatusBadRequest, "Only remote ports run tunnels, start the remote port forwarding to this one")
+	}
+	if port.TargetPort == nil {
+		return fail(http.StatusBadRequest, "Port has no target local port")
+	}
+	if port.HostID == nil {
+		return fail(http.StatusBadRequest, "Port has no host")
+	}
+	for _, p := range []*models.Port{port, port.TargetPort} {
+		if _, err := p.Resolve(); err != nil {
+			return fail(portErrorStatus(err), err.Error())
+		}
+	}
+	if h.rejectPortInMaintenance(c, port) || h.rejectOverPortQuota(c, port) ||
+		h.rejectWithoutGrant(c, port) || h.rejectWithoutReachableHost(c, port) {
+		return nil, false
+	}
+
+	hostID := *port.HostID
+	if port.ActiveHostID != nil {
+		hostID = *port.ActiveHostID
+	}
+	host, err := h.storage.GetHost(c.Request.Context(), hostID)
+	if err != nil {
+		return fail(http.StatusNotFound, "Host not found: "+err.Error())
+	}
+
+	return func(ctx context.Context, report operations.Report) error {
+		return h.startPortTunnel(ctx, port, host, report)
+	}, true
+}
+
+// startPortTunnel brings a remote port's tunnel up and waits until its target
+// accepts connections, marking both ports active, or in error when it fails
+func (h *Handlers) startPortTunnel(ctx context.Context, port *models.Port, host *models.Host, report operations.Report) (err error) {
+	target := port.TargetPort
+	// Statuses are recorded even when the operation timed out
+	statusCtx := context.WithoutCancel(ctx)
+	setStatus := func(status models.PortStatus, portErr *models.PortError) {
+		for _, id := range []uint{port.ID, target.ID} {
+			if err := h.storage.UpdatePortStatus(statusCtx, id, status, portErr); err != nil {
+				h.logger.Warn("Failed to update port status", "port_id", id, "status", status, "error", err)
+			}
+		}
+		h.syncPortDNS(statusCtx, target.ID, status)
+		h.syncPortWebInfo(statusCtx, target.ID, status)
+	}
+
+	// The tunnel holds a slot of the host's concurrency limit while it runs
+	release, err := h.acquireHost(ctx, host)
+	if err != nil {
+		portErr := models.ClassifyPortError(err)
+		setStatus(models.PortStatusError, &portErr)
+		return err
+	}
+
+	var sessionID string
+	defer func() {
+		if err == nil {
+			return
+		}
+		if sessionID != "" {
+			h.sessionManager.DeleteSession(sessionID)
+		}
+		release()
+		portErr := models.ClassifyPortError(err)
+		setStatus(models.PortStatusError, &portErr)
+	}()
+
+	setStatus(models.PortStatusConnecting, nil)
+	report(operations.PhaseConnecting, "connecting to "+host.Hostname)
+
+	sshConfig, err := h.hostSSHConfig(ctx, host)
+	if err != nil {
+		return err
+	}
+	resolved, err := port.Resolve()
+	if err != nil {
+		return err
+	}
+	resolvedTarget, err := target.Resolve()
+	if err != nil {
+		return err
+	}
+	tunnelConfig, err := resolved.RemoteTunnelConfig(resolvedTarget)
+	if err != nil {
+		return err
+	}
+	// The tunnel reconnects following the port's retry policy
+	retryPolicy := port.GetRetryPolicy()
+	sshConfig.RetryPolicy = &retryPolicy
+	session, err := h.sessionManager.CreateSession(ctx, sshConfig, tunnelConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	sessionID = session.ID
+
+	progress := func(phase sshpkg.StartPhase) {
+		switch phase {
+		case sshpkg.PhaseHandshake:
+			report(operations.PhaseHandshake, "")
+		case sshpkg.PhaseListenerBound:
+			report(operations.PhaseListenerBound, "")
+		}
+	}
+	err = h.sessionManager.StartSession(sshpkg.WithProgress(ctx, progress), sessionID)
+	h.recordAuth(ctx, host.ID, models.AuthSourcePort, err)
+	if err != nil {
+		return err
+	}
+	report(operations.PhaseListenerBound, "")
+
+	address := net.JoinHostPort(resolvedTarget.GetBindAddress(), strconv.Itoa(resolvedTarget.Port))
+	dialer := net.Dialer{Timeout: portHealthTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("tunnel is up but its target %s is not reachable: %w", address, err)
+	}
+	conn.Close()
+
+	if portHooksTimeout(port, models.HookEventStart) > 0 {
+		report(operations.PhaseHooks, "running start hooks")
+		if err := h.runPortHooks(ctx, port, host, models.HookEventStart); err != nil {
+			return err
+		}
+	}
+
+	recordID := h.createPortSessionRecord(statusCtx, port, host, tunnelConfig, address)
+	h.portTunnels.set(port.ID, host.ID, sessionID, recordID, release)
+	setStatus(models.PortStatusActive, nil)
+	report(operations.PhaseHealthy, "forwarding to "+address)
+	return nil
+}
+
+// createPortSessionRecord saves the tunnel session record of a port tunnel
+// that came up, so the sessions API lists and stops it. It returns the
+// record's ID, or 0 when it could not be saved.
+func (h *Handlers) createPortSessionRecord(ctx context.Context, port *models.Port, host *models.Host, config models.TunnelConfig, target string) uint {
+	now := time.Now()
+	record := &models.TunnelSession{
+		Status:        models.StatusActive,
+		StartTime:     &now,
+		LocalAddress:  config.Binds()[0].String(),
+		RemoteAddress: target,
+		HostID:        host.ID,
+		PortID:        &port.ID,
+	}
+	if err := h.storage.CreateTunnelSession(ctx, record); err != nil {
+		h.logger.Warn("Failed to save port tunnel session", "port_id", port.ID, "error", err)
+		return 0
+	}
+	return record.ID
+}
+
+// endPortSessionRecord marks the tunnel session record of a port tunnel that
+// went down with status
+func (h *Handlers) endPortSessionRecord(ctx context.Context, recordID uint, status models.SessionStatus, message string) {
+	if recordID == 0 {
+		return
+	}
+	record, err := h.storage.GetTunnelSession(ctx, recordID)
+	if err != nil {
+		h.logger.Warn("Failed to load port tunnel session", "session_id", recordID, "error", err)
+		return
+	}
+	now := time.Now()
+	record.Status = status
+	record.EndTime = &now
+	record.ErrorMessage = message
+	if err := h.storage.UpdateTunnelSession(ctx, record); err != nil {
+		h.logger.Warn("Failed to update port tunnel session", "session_id", recordID, "error", err)
+	}
+}
+
+// WatchPortSessions keeps the status of running ports in step with their
+// tunnel sessions until ctx is done: backoff with its retry message while a
+// session waits to reconnect, active once it is back, error or available
+// when its retry policy gave up
+func (h *Handlers) WatchPortSessions(ctx context.Context) {
+	events, unsubscribe := h.sessionManager.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			switch event.Type {
+			case models.EventSessionBackoff, models.EventSessionReconnected, models.EventSessionGaveUp:
+				h.portSessionEvent(ctx, event)
+			}
+		}
+	}
+}
+
+// portSessionEvent applies a reconnect event of a port tunnel's session to
+// the port and its target
+func (h *Handlers) portSessionEvent(ctx context.Context, event models.SessionEvent) {
+	portID, ok := h.portTunnels.port(event.SessionID)
+	if !ok {
+		return
+	}
+	ids := []uint{portID}
+	if port, err := h.storage.GetPort(ctx, portID); err == nil && port.TargetPortID != nil {
+		ids = append(ids, *port.TargetPortID)
+	}
+
+	var cause *models.PortError
+	if event.Error != "" {
+		classified := models.ClassifyPortError(errors.New(event.Error))
+		cause = &classified
+	}
+	if event.Type == models.EventSessionGaveUp {
+		recordID := h.portTunnels.remove(portID)
+		h.endPortSessionRecord(ctx, recordID, event.Status, event.Error)
+	}
+
+	for _, id := range ids {
+		var err error
+		switch event.Type {
+		case models.EventSessionBackoff:
+			err = h.storage.UpdatePortBackoff(ctx, id, event.Attempt, event.RetryIn, cause)
+		case models.EventSessionReconnected:
+			err = h.storage.UpdatePortStatus(ctx, id, models.PortStatusActive, nil)
+		case models.EventSessionGaveUp:
+			// Policies giving up with "stop" leave the port available
+			status := models.PortStatusAvailable
+			if event.Status == models.StatusError {
+				status = models.PortStatusError
+			}
+			err = h.storage.UpdatePortStatus(ctx, id, status, cause)
+			h.syncPortDNS(ctx, id, status)
+		}
+		if err != nil {
+			h.logger.Warn("Failed to update port status", "port_id", id, "event", event.Type, "error", err)
+		}
+	}
+}
+
+// stopPortTunnel stops a remote port's tunnel and makes both ports available
+func (h *Handlers) stopPortTunnel(ctx context.Context, portID uint, sessionID string, report operations.Report) error {
+	report(operations.PhaseStopping, "")
+	recordID := h.portTunnels.remove(portID)
+	if err := h.sessionManager.DeleteSession(sessionID); err != nil {
+		h.logger.Warn("Failed to delete port session", "port_id", portID, "session_id", sessionID, "error", err)
+	}
+	h.endPortSessionRecord(ctx, recordID, models.StatusStopped, "")
+
+	ids := []uint{portID}
+	port, err := h.storage.GetPort(ctx, portID)
+	if err == nil && port.TargetPortID != nil {
+		ids = append(ids, *port.TargetPortID)
+	}
+	for _, id := range ids {
+		if err := h.storage.UpdatePortStatus(ctx, id, models.PortStatusAvailable, nil); err != nil {
+			return err
+		}
+		h.syncPortDNS(ctx, id, models.PortStatusAvailable)
+	}
+
+	// Stop hooks cannot undo the stop; their failures are only logged
+	if port != nil && portHooksTimeout(port, models.HookEventStop) > 0 {
+		report(operations.PhaseHooks, "running stop hooks")
+		h.runPortHooks(ctx, port, h.portHost(ctx, port), models.HookEventStop)
+	}
+	report(operations.PhaseStopped, "")
+	return nil
+}
+
+// GetOperations 列出异步操作，最新的在前；完成的操作保留一小时
+// Query: port_id=仅列出该端口的操作
+func (h *Handlers) GetOperations(c *gin.Context) {
+	var portID uint
+	if raw := c.Query("port_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid port_id parameter",
+			})
+			return
+		}
+		portID = uint(id)
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    h.operations.List(portID),
+	})
+}
+
+// GetOperation 查询异步操作的状态和已经过的阶段
+func (h *Handlers) GetOperation(c *gin.Context) {
+	operation, err := h.operations.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    operation,
+	})
+}
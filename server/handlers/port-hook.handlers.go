@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// maxHookOutput 每个钩子保留的输出字节数
+const maxHookOutput = 4 << 10
+
+// SetLocalHooks sets whether port hooks may run commands on the server itself
+func (h *Handlers) SetLocalHooks(enabled bool) {
+	h.localHooks = enabled
+}
+
+// checkLocalHooks refuses hooks running commands on the server unless the
+// server enables them
+func (h *Handlers) checkLocalHooks(hooks []models.PortHook) error {
+	if h.localHooks {
+		return nil
+	}
+	for _, hook := range hooks {
+		if hook.Local() {
+			return fmt.Errorf("%w: %s: set PORTFLY_LOCAL_HOOKS=true to allow them, or run the command on the host with remote", models.ErrLocalHooksDisabled, hook.DisplayName())
+		}
+	}
+	return nil
+}
+
+// portHookEvent describes a port's tunnel to its hooks
+func portHookEvent(event string, port *models.Port, host *models.Host) models.PortHookEvent {
+	hookEvent := models.PortHookEvent{
+		Event:    event,
+		PortID:   port.ID,
+		PortName: port.Name,
+		Address:  net.JoinHostPort(port.GetRemoteBindAddress(), strconv.Itoa(port.Port)),
+		Time:     time.Now(),
+	}
+	if port.TargetPort != nil {
+		hookEvent.Target = net.JoinHostPort(port.TargetPort.GetBindAddress(), strconv.Itoa(port.TargetPort.Port))
+	}
+	if host != nil {
+		hookEvent.HostID = host.ID
+		hookEvent.Host = host.Hostname
+	}
+	return hookEvent
+}
+
+// portHost looks up the host running the port's tunnel, or nil
+func (h *Handlers) portHost(ctx context.Context, port *models.Port) *models.Host {
+	hostID := port.HostID
+	if port.ActiveHostID != nil {
+		hostID = port.ActiveHostID
+	}
+	if hostID == nil {
+		return nil
+	}
+	host, err := h.storage.GetHost(ctx, *hostID)
+	if err != nil {
+		h.logger.Warn("Failed to load port host for hooks", "port_id", port.ID, "error", err)
+		return nil
+	}
+	return host
+}
+
+// portHooksTimeout is how long the port's hooks for event may run in all
+func portHooksTimeout(port *models.Port, event string) time.Duration {
+	var timeout time.Duration
+	for _, hook := range port.Hooks {
+		if hook.Event == event {
+			timeout += hook.GetTimeout()
+		}
+	}
+	return timeout
+}
+
+// runPortHooks runs the port's hooks for event one after another, logging
+// each result under the port. It returns an error for the first failed hook
+// that blocks, without running the rest.
+func (h *Handlers) runPortHooks(ctx context.Context, port *models.Port, host *models.Host, event string) error {
+	hookEvent := portHookEvent(event, port, host)
+	for _, hook := range port.Hooks {
+		if hook.Event != event {
+			continue
+		}
+
+		result := h.runPortHook(ctx, hook, hookEvent, host)
+		if result.Success {
+			h.logger.Info("Port hook succeeded", "port_id", port.ID, "hook", result.Hook, "event", event, "duration_ms", result.Duration)
+			continue
+		}
+		h.logger.Warn("Port hook failed", "port_id", port.ID, "hook", result.Hook, "event", event, "error", result.Summary())
+		if hook.Blocks() {
+			return fmt.Errorf("%s hook failed: %s", event, result.Summary())
+		}
+	}
+	return nil
+}
+
+// runPortHook runs one hook within its timeout
+func (h *Handlers) runPortHook(ctx context.Context, hook models.PortHook, event models.PortHookEvent, host *models.Host) models.PortHookResult {
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, hook.GetTimeout())
+	defer cancel()
+
+	var result models.PortHookResult
+	switch {
+	case hook.URL != "":
+		result = callHookWebhook(ctx, hook.URL, event)
+	case hook.Remote:
+		result = h.runRemoteHook(ctx, hook, event, host)
+	case !h.localHooks:
+		result = models.PortHookResult{Error: models.ErrLocalHooksDisabled.Error()}
+	default:
+		result = runLocalHook(ctx, hook.Command, event)
+	}
+	result.Hook = hook.DisplayName()
+	result.Event = event.Event
+	result.Duration = time.Since(started).Milliseconds()
+	return result
+}
+
+// runLocalHook runs command through the server's shell with the event in its
+// environment
+func runLocalHook(ctx context.Context, command string, event models.PortHookEvent) models.PortHookResult {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Env = append(os.Environ(), event.Env()...)
+	output := &limitedBuffer{limit: maxHookOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Children left holding the output must not keep the hook running
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	result := models.PortHookResult{Output: output.buf.String()}
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		result.Error = fmt.Sprintf("command did not finish: %v", ctx.Err())
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		result.Error = err.Error()
+	default:
+		result.Success = true
+	}
+	return result
+}
+
+// runRemoteHook runs the hook's command on the port's host over SSH
+func (h *Handlers) runRemoteHook(ctx context.Context, hook models.PortHook, event models.PortHookEvent, host *models.Host) models.PortHookResult {
+	if host == nil {
+		return models.PortHookResult{Error: "port has no host to run the command on"}
+	}
+
+	// SSH servers rarely accept environment variables, so the command exports them
+	var command strings.Builder
+	for _, variable := range event.Env() {
+		name, value, _ := strings.Cut(variable, "=")
+		command.WriteString("export " + name + "='" + strings.ReplaceAll(value, "'", `'\''`) + "'; ")
+	}
+	command.WriteString(hook.Command)
+
+	execResult := h.runHostCommand(ctx, host, command.String(), hook.GetTimeout(), false, "")
+	result := models.PortHookResult{
+		Success:  execResult.Error == "" && execResult.ExitCode == 0,
+		ExitCode: execResult.ExitCode,
+		Output:   execResult.Stdout + execResult.Stderr,
+		Error:    execResult.Error,
+	}
+	if len(result.Output) > maxHookOutput {
+		result.Output = result.Output[:maxHookOutput]
+	}
+	return result
+}
+
+// callHookWebhook POSTs the event to url; any 2xx answer is a success
+func callHookWebhook(ctx context.Context, url string, event models.PortHookEvent) models.PortHookResult {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return models.PortHookResult{Error: err.Error()}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return models.PortHookResult{Error: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "portfly-hook")
+	req.Header.Set("X-PortFly-Event", "port."+event.Event)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return models.PortHookResult{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	output, _ := io.ReadAll(io.LimitReader(resp.Body, maxHookOutput))
+	return models.PortHookResult{
+		Success: resp.StatusCode >= 200 && resp.StatusCode < 300,
+		Status:  resp.StatusCode,
+		Output:  string(output),
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+func TestLocalHooksDisabledByDefault(t *testing.T) {
+	h := NewHandlers(nil, nil, utils.DiscardLogger())
+	marker := filepath.Join(t.TempDir(), "ran")
+	hook := models.PortHook{Event: models.HookEventStart, Command: "touch " + marker}
+
+	if err := h.checkLocalHooks([]models.PortHook{hook}); !errors.Is(err, models.ErrLocalHooksDisabled) {
+		t.Fatalf("checkLocalHooks error = %v, want ErrLocalHooksDisabled", err)
+	}
+	result := h.runPortHook(context.Background(), hook, models.PortHookEvent{Event: models.HookEventStart}, nil)
+	if result.Success {
+		t.Fatal("local hook succeeded while local hooks are disabled")
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("local hook command ran: %v", err)
+	}
+
+	// Remote commands and webhooks do not run on the server
+	remote := []models.PortHook{
+		{Event: models.HookEventStart, Command: "true", Remote: true},
+		{Event: models.HookEventStart, URL: "https://example.com/hook"},
+	}
+	if err := h.checkLocalHooks(remote); err != nil {
+		t.Fatalf("checkLocalHooks refused remote hooks: %v", err)
+	}
+}
+
+func TestLocalHooksEnabled(t *testing.T) {
+	h := NewHandlers(nil, nil, utils.DiscardLogger())
+	h.SetLocalHooks(true)
+	marker := filepath.Join(t.TempDir(), "ran")
+	hook := models.PortHook{Event: models.HookEventStart, Command: "touch " + marker}
+
+	if err := h.checkLocalHooks([]models.PortHook{hook}); err != nil {
+		t.Fatalf("checkLocalHooks: %v", err)
+	}
+	result := h.runPortHook(context.Background(), hook, models.PortHookEvent{Event: models.HookEventStart}, nil)
+	if !result.Success {
+		t.Fatalf("local hook failed: %s", result.Summary())
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("local hook command did not run: %v", err)
+	}
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// CreateReservationRequest 预留本地端口；省略 port 时从项目和用户的端口段中自动分配 size 个连续端口
+type CreateReservationRequest struct {
+	ProjectID uint   `json:"project_id" binding:"required"`
+	Port      int    `json:"port,omitempty"`
+	PortEnd   int    `json:"port_end,omitempty"`
+	Size      int    `json:"size,omitempty"` // 自动分配的端口数，默认为 1
+	Note      string `json:"note,omitempty"`
+}
+
+// SetPortPools sets the ranges local ports are allocated from
+func (h *Handlers) SetPortPools(pools []models.PortPool) {
+	h.portPools = pools
+}
+
+// reservationOwner 预留所属的用户，取自 X-PortFly-Actor 请求头；为空时预留由项目共用
+func reservationOwner(c *gin.Context) string {
+	return strings.TrimSpace(c.GetHeader(actorHeader))
+}
+
+// reservationErrorStatus maps invalid reservations to 400 and taken ports to 409
+func reservationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidReservation), errors.Is(err, models.ErrInvalidPortRange),
+		errors.Is(err, models.ErrInvalidPortPool):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrPortReserved), errors.Is(err, models.ErrNoFreePort):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// GetPortReservations 列出本地端口预留，按端口排列
+// Query: project_id=仅列出该项目的预留
+func (h *Handlers) GetPortReservations(c *gin.Context) {
+	projectID, ok := parseProjectIDQuery(c)
+	if !ok {
+		return
+	}
+
+	reservations, err := h.storage.GetPortReservations(c.Request.Context(), projectID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    reservations,
+	})
+}
+
+// CreatePortReservation 手动预留本地端口，供不在 PortFly 中配置的转发使用
+// 指定 port 时预留该端口（或 port–port_end 端口段），被其他项目或用户预留时返回 409；
+// 省略 port 时从端口段中自动分配
+func (h *Handlers) CreatePortReservation(c *gin.Context) {
+	var request CreateReservationRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	reservation := models.PortReservation{
+		Port:      request.Port,
+		PortEnd:   request.PortEnd,
+		ProjectID: request.ProjectID,
+		Owner:     reservationOwner(c),
+		Note:      request.Note,
+	}
+	var err error
+	if request.Port == 0 {
+		pool := models.SelectPortPool(h.portPools, reservation.ProjectID, reservation.Owner)
+		err = h.storage.AllocatePort(c.Request.Context(), pool, request.Size, &reservation)
+	} else {
+		err = h.storage.ReservePort(c.Request.Context(), &reservation)
+	}
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    reservation,
+	})
+}
+
+// DeletePortReservation 删除本地端口预留；端口持有的预留在端口删除时自动释放
+func (h *Handlers) DeletePortReservation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid reservation ID",
+		})
+		return
+	}
+
+	if err := h.storage.DeletePortReservation(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Port reservation deleted successfully",
+	})
+}
+
+// PlanPortReservations 预演自动分配：返回项目和用户的端口段中接下来会分配的端口，不预留
+// Query: project_id=项目, size=每次分配的端口数（默认 1）, count=预演的次数（默认 1）
+func (h *Handlers) PlanPortReservations(c *gin.Context) {
+	projectID, ok := parseProjectIDQuery(c)
+	if !ok {
+		return
+	}
+	size, count := 1, 1
+	for name, value := range map[string]*int{"size": &size, "count": &count} {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > models.MaxPortRange {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   fmt.Sprintf("Invalid %s parameter", name),
+			})
+			return
+		}
+		*value = n
+	}
+
+	pool := models.SelectPortPool(h.portPools, projectID, reservationOwner(c))
+	ports, err := h.storage.PlanPorts(c.Request.Context(), pool, size, count)
+	if err != nil {
+		c.JSON(reservationErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    models.PortPlan{Pool: pool, Size: size, Ports: ports},
+	})
+}
+
+// parseProjectIDQuery 解析可选的 project_id 查询参数，失败时已写入错误响应
+func parseProjectIDQuery(c *gin.Context) (uint, bool) {
+	raw := c.Query("project_id")
+	if raw == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid project_id parameter",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// reserveLocalPort reserves the numbers of a local port for its project and
+// the requesting user, allocating one from the pool when the port has none.
+// Remote ports hold no reservation and release the one they had.
+func (h *Handlers) reserveLocalPort(c *gin.Context, port *models.Port) (*models.PortReservation, error) {
+	ctx := c.Request.Context()
+	if port.Type != models.PortTypeLocal {
+		if port.ID != 0 {
+			return nil, h.storage.ReleasePortReservation(ctx, port.ID)
+		}
+		return nil, nil
+	}
+	if port.GroupID == 0 {
+		return nil, models.ErrGroupRequired
+	}
+	group, err := h.storage.GetGroup(ctx, port.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	reservation := &models.PortReservation{
+		Port:      port.Port,
+		PortEnd:   port.PortEnd,
+		ProjectID: group.ProjectID,
+		Owner:     reservationOwner(c),
+	}
+	if port.ID != 0 {
+		reservation.PortID = &port.ID
+	}
+	if port.Port != 0 {
+		return reservation, h.storage.ReservePort(ctx, reservation)
+	}
+
+	pool := models.SelectPortPool(h.portPools, group.ProjectID, reservation.Owner)
+	if err := h.storage.AllocatePort(ctx, pool, 1, reservation); err != nil {
+		return nil, err
+	}
+	port.Port, port.PortEnd = reservation.Port, 0
+	return reservation, nil
+}
+
+// attachReservation hands the reservation made before a port was created to
+// the new port, or drops it when creating the port failed
+func (h *Handlers) attachReservation(ctx context.Context, reservation *models.PortReservation, port *models.Port, created bool) {
+	if reservation == nil {
+		return
+	}
+	var err error
+	if created {
+		reservation.PortID = &port.ID
+		err = h.storage.ReservePort(ctx, reservation)
+	} else {
+		err = h.storage.DeletePortReservation(ctx, reservation.ID)
+	}
+	if err != nil {
+		h.logger.Warn("Failed to update port reservation", "port_id", port.ID, "error", err)
+	}
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// InstantiateTemplateRequest 在主机上按模板创建端口，省略的字段使用模板的默认值
+type InstantiateTemplateRequest struct {
+	HostID      uint     `json:"host_id" binding:"required"`
+	GroupID     uint     `json:"group_id,omitempty"` // 默认为主机所在分组
+	Name        string   `json:"name,omitempty"`     // 默认为"模板名-主机名"
+	Port        int      `json:"port,omitempty"`
+	PortEnd     int      `json:"port_end,omitempty"`
+	BindAddress string   `json:"bind_address,omitempty"`
+	Tags        []string `json:"tags,omitempty"` // 追加在模板标签之后
+}
+
+// portTemplateErrorStatus maps template validation errors to 400, unknown
+// templates to 404 and name conflicts to 409
+func portTemplateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrTemplateNameRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	}
+	return portErrorStatus(err)
+}
+
+// parseTemplateID 解析路径参数 :id，失败时已写入错误响应
+func parseTemplateID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid template ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetPortTemplates 列出所有端口模板
+func (h *Handlers) GetPortTemplates(c *gin.Context) {
+	templates, err := h.storage.GetPortTemplates(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    templates,
+	})
+}
+
+// GetPortTemplate 获取端口模板
+func (h *Handlers) GetPortTemplate(c *gin.Context) {
+	id, ok := parseTemplateID(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.storage.GetPortTemplate(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(portTemplateErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    template,
+	})
+}
+
+// CreatePortTemplate 创建端口模板
+func (h *Handlers) CreatePortTemplate(c *gin.Context) {
+	var template models.PortTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+	template.ID = 0
+
+	if err := h.storage.CreatePortTemplate(c.Request.Context(), &template); err != nil {
+		c.JSON(portTemplateErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    template,
+	})
+}
+
+// UpdatePortTemplate 修改端口模板，已创建的端口不受影响
+func (h *Handlers) UpdatePortTemplate(c *gin.Context) {
+	id, ok := parseTemplateID(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.storage.GetPortTemplate(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(portTemplateErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := c.ShouldBindJSON(template); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+	template.ID = id
+
+	if err := h.storage.UpdatePortTemplate(c.Request.Context(), template); err != nil {
+		c.JSON(portTemplateErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    template,
+	})
+}
+
+// DeletePortTemplate 删除端口模板，已创建的端口保留
+func (h *Handlers) DeletePortTemplate(c *gin.Context) {
+	id, ok := parseTemplateID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.storage.DeletePortTemplate(c.Request.Context(), id); err != nil {
+		c.JSON(portTemplateErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Port template deleted successfully",
+	})
+}
+
+// InstantiatePortTemplate 按模板在主机上创建端口，与直接创建端口一样校验并记录变更历史
+func (h *Handlers) InstantiatePortTemplate(c *gin.Context) {
+	id, ok := parseTemplateID(c)
+	if !ok {
+		return
+	}
+
+	var request InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	template, err := h.storage.GetPortTemplate(ctx, id)
+	if err != nil {
+		c.JSON(portTemplateErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	host, err := h.storage.GetHost(ctx, request.HostID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	port := template.NewPort(host)
+	if request.GroupID != 0 {
+		port.GroupID = request.GroupID
+	}
+	if request.Name != "" {
+		port.Name = request.Name
+	}
+	if request.Port != 0 {
+		// A range keeps its width when moved to another first port
+		if port.PortEnd != 0 && request.PortEnd == 0 {
+			port.PortEnd = request.Port + port.PortEnd - port.Port
+		}
+		port.Port = request.Port
+	}
+	if request.PortEnd != 0 {
+		port.PortEnd = request.PortEnd
+	}
+	if request.BindAddress != "" {
+		port.BindAddress = request.BindAddress
+	}
+	port.Tags = append(port.Tags, request.Tags...)
+
+	if err := h.checkLocalHooks(port.Hooks); err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := h.storage.CreatePort(ctx, port); err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	h.syncPortOwnership(ctx, port)
+	h.recordChange(c, models.EntityPort, port.ID, models.ChangeCreate, nil, h.entityFields(models.EntityPort, port))
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    port,
+	})
+}
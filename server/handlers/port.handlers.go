@@ -1,24 +1,40 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/storage"
 	"github.com/gin-gonic/gin"
 )
 
 // ===== Port Operations =====
 
 // GetPorts retrieves all ports with optional filtering
+// Query: group_id, host_id, metadata.<键>=<值> 按元数据过滤
 func (h *Handlers) GetPorts(c *gin.Context) {
 	groupIDStr := c.Query("group_id")
 	hostIDStr := c.Query("host_id")
 
+	filters, ok := metadataFilters(c)
+	if !ok {
+		return
+	}
+
 	var ports []models.Port
 	var err error
 
-	if groupIDStr != "" {
+	if len(filters) > 0 {
+		ports, err = h.storage.GetPortsByMetadata(c.Request.Context(), filters)
+		if err == nil {
+			ports, ok = filterPortsByOwner(c, ports, groupIDStr, hostIDStr)
+			if !ok {
+				return
+			}
+		}
+	} else if groupIDStr != "" {
 		groupID, parseErr := strconv.ParseUint(groupIDStr, 10, 32)
 		if parseErr != nil {
 			c.JSON(http.StatusBadRequest, Response{
@@ -56,17 +72,104 @@ func (h *Handlers) GetPorts(c *gin.Context) {
 	})
 }
 
+// filterPortsByOwner keeps the ports in the group_id or host_id given with
+// a metadata query. On an invalid ID the response is already written.
+func filterPortsByOwner(c *gin.Context, ports []models.Port, groupIDStr, hostIDStr string) ([]models.Port, bool) {
+	var groupID, hostID uint64
+	var err error
+	if groupIDStr != "" {
+		if groupID, err = strconv.ParseUint(groupIDStr, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid group_id parameter",
+			})
+			return nil, false
+		}
+	}
+	if hostIDStr != "" {
+		if hostID, err = strconv.ParseUint(hostIDStr, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid host_id parameter",
+			})
+			return nil, false
+		}
+	}
+
+	filtered := ports[:0]
+	for _, port := range ports {
+		if groupID != 0 && port.GroupID != uint(groupID) {
+			continue
+		}
+		if hostID != 0 && (port.HostID == nil || *port.HostID != uint(hostID)) {
+			continue
+		}
+		filtered = append(filtered, port)
+	}
+	return filtered, true
+}
+
 // GetPort retrieves a single port by ID
 func (h *Handlers) GetPort(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	port, err := h.storage.GetPort(c.Request.Context(), uint(id))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    port,
+	})
+}
+
+// GetResolvedPort 获取模板字段按当前环境解析后的端口，用于启动前预览
+func (h *Handlers) GetResolvedPort(c *gin.Context) {
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	port, err := h.storage.GetPort(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
 			Success: false,
-			Error:   "Invalid port ID",
+			Error:   err.Error(),
 		})
 		return
 	}
 
+	resolved, err := port.Resolve()
+	if err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    resolved,
+	})
+}
+
+// GetPortShare 生成端口的可复制连接片段（URL、客户端命令、示例）
+// Query: host=覆盖连接主机, service=覆盖服务类型
+func (h *Handlers) GetPortShare(c *gin.Context) {
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
 	port, err := h.storage.GetPort(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, Response{
@@ -76,9 +179,35 @@ func (h *Handlers) GetPort(c *gin.Context) {
 		return
 	}
 
+	// 已发布本地 DNS 名称时优先使用
+	host := c.Query("host")
+	if host == "" && h.localDNS != nil && !port.IsRemotePort() {
+		if _, published := h.localDNS.Records()[h.portDNSName(port)]; published {
+			host = h.portDNSName(port)
+		}
+	}
+
+	resolved, err := port.Resolve()
+	if err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	link, err := resolved.ShareLink(host, models.ServiceType(c.Query("service")))
+	if err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, Response{
 		Success: true,
-		Data:    port,
+		Data:    link,
 	})
 }
 
@@ -93,13 +222,38 @@ func (h *Handlers) CreatePort(c *gin.Context) {
 		return
 	}
 
+	if h.rejectInvalidMetadata(c, models.EntityPort, port.Metadata) {
+		return
+	}
+	if err := h.checkLocalHooks(port.Hooks); err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// 本地端口先预留端口号，未指定端口号时自动分配
+	reservation, err := h.reserveLocalPort(c, &port)
+	if err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	if err := h.storage.CreatePort(c.Request.Context(), &port); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
+		h.attachReservation(c.Request.Context(), reservation, &port, false)
+		c.JSON(portErrorStatus(err), Response{
 			Success: false,
 			Error:   err.Error(),
 		})
 		return
 	}
+	h.attachReservation(c.Request.Context(), reservation, &port, true)
+	h.syncPortOwnership(c.Request.Context(), &port)
+	h.recordChange(c, models.EntityPort, port.ID, models.ChangeCreate, nil, h.entityFields(models.EntityPort, &port))
 
 	c.JSON(http.StatusCreated, Response{
 		Success: true,
@@ -109,12 +263,8 @@ func (h *Handlers) CreatePort(c *gin.Context) {
 
 // UpdatePort updates an existing port
 func (h *Handlers) UpdatePort(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Success: false,
-			Error:   "Invalid port ID",
-		})
+	id, ok := h.resolvePortID(c)
+	if !ok {
 		return
 	}
 
@@ -128,6 +278,9 @@ func (h *Handlers) UpdatePort(c *gin.Context) {
 		return
 	}
 
+	before := h.entityFields(models.EntityPort, existingPort)
+	previous := *existingPort
+
 	// Bind JSON to existing port
 	if err := c.ShouldBindJSON(existingPort); err != nil {
 		c.JSON(http.StatusBadRequest, Response{
@@ -140,13 +293,38 @@ func (h *Handlers) UpdatePort(c *gin.Context) {
 	// Ensure ID is not changed
 	existingPort.ID = uint(id)
 
+	if h.rejectInvalidMetadata(c, models.EntityPort, existingPort.Metadata) {
+		return
+	}
+	if err := h.checkLocalHooks(existingPort.Hooks); err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// 重新预留修改后的本地端口号，改为远程端口时释放预留
+	if _, err := h.reserveLocalPort(c, existingPort); err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
 	if err := h.storage.UpdatePort(c.Request.Context(), existingPort); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
+		if _, restoreErr := h.reserveLocalPort(c, &previous); restoreErr != nil {
+			h.logger.Warn("Failed to restore port reservation", "port_id", id, "error", restoreErr)
+		}
+		c.JSON(portErrorStatus(err), Response{
 			Success: false,
 			Error:   err.Error(),
 		})
 		return
 	}
+	h.syncPortOwnership(c.Request.Context(), existingPort)
+	h.recordChange(c, models.EntityPort, existingPort.ID, models.ChangeUpdate, before, h.entityFields(models.EntityPort, existingPort))
 
 	c.JSON(http.StatusOK, Response{
 		Success: true,
@@ -156,15 +334,20 @@ func (h *Handlers) UpdatePort(c *gin.Context) {
 
 // DeletePort deletes a port by ID
 func (h *Handlers) DeletePort(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Success: false,
-			Error:   "Invalid port ID",
-		})
+	id, ok := h.resolvePortID(c)
+	if !ok {
 		return
 	}
 
+	var before map[string]any
+	if existing, err := h.storage.GetPort(c.Request.Context(), id); err == nil {
+		before = h.entityFields(models.EntityPort, existing)
+	}
+
+	// 删除前移除本地 DNS 名称并释放所有权
+	h.syncPortDNS(c.Request.Context(), uint(id), models.PortStatusUnavailable)
+	h.syncPortOwnership(c.Request.Context(), &models.Port{ID: uint(id)})
+
 	if err := h.storage.DeletePort(c.Request.Context(), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
@@ -173,6 +356,10 @@ func (h *Handlers) DeletePort(c *gin.Context) {
 		return
 	}
 
+	if before != nil {
+		h.recordChange(c, models.EntityPort, id, models.ChangeDelete, before, nil)
+	}
+
 	c.JSON(http.StatusOK, Response{
 		Success: true,
 		Message: "Port deleted successfully",
@@ -181,12 +368,8 @@ func (h *Handlers) DeletePort(c *gin.Context) {
 
 // GetPortStats retrieves statistics for a port
 func (h *Handlers) GetPortStats(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Success: false,
-			Error:   "Invalid port ID",
-		})
+	id, ok := h.resolvePortID(c)
+	if !ok {
 		return
 	}
 
@@ -231,16 +414,50 @@ func (h *Handlers) SearchPorts(c *gin.Context) {
 	})
 }
 
+// portErrorStatus maps port validation errors to 400, name conflicts,
+// reserved local ports and refused status transitions to 409
+// and everything else to 500
+func portErrorStatus(err error) int {
+	for _, validationErr := range []error{
+		models.ErrInvalidName,
+		models.ErrNumericName,
+		models.ErrInvalidPort,
+		models.ErrInvalidPortRange,
+		models.ErrPortRangeMismatch,
+		models.ErrInvalidPortType,
+		models.ErrGroupRequired,
+		models.ErrRemoteBindOnLocalPort,
+		models.ErrRemoteBindNotAllowed,
+		models.ErrInvalidServiceType,
+		models.ErrInvalidPortStatus,
+		models.ErrInvalidTemplate,
+		models.ErrInvalidHook,
+		models.ErrHooksOnLocalPort,
+		models.ErrLocalHooksDisabled,
+		models.ErrInvalidCaptureLimit,
+		models.ErrInvalidCaptureRedact,
+		models.ErrInvalidCaptureFile,
+	} {
+		if errors.Is(err, validationErr) {
+			return http.StatusBadRequest
+		}
+	}
+	if errors.Is(err, storage.ErrNameConflict) ||
+		errors.Is(err, models.ErrPortReserved) ||
+		errors.Is(err, models.ErrNoFreePort) ||
+		errors.Is(err, models.ErrInvalidPortTransition) ||
+		errors.Is(err, models.ErrPortStatusChanged) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 // ===== Port Control Operations =====
 
 // TestPortConnection tests the connection from a host to a port
 func (h *Handlers) TestPortConnection(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Success: false,
-			Error:   "Invalid port ID",
-		})
+	id, ok := h.resolvePortID(c)
+	if !ok {
 		return
 	}
 
@@ -280,6 +497,8 @@ func (h *Handlers) TestPortConnection(c *gin.Context) {
 	// For now, we'll simulate the test
 	success := true // This should be replaced with actual testing logic
 
+	h.logger.Info("Port connection test completed", "port_id", id, "host_id", request.HostID, "success", success)
+
 	// Update port status based on test result
 	port.SetConnectionTestResult(success)
 	port.HostID = &request.HostID
@@ -354,6 +573,32 @@ func (h *Handlers) CreatePortForward(c *gin.Context) {
 		return
 	}
 
+	if h.rejectPortInMaintenance(c, remotePort) || h.rejectPortInMaintenance(c, localPort) {
+		return
+	}
+	if h.rejectOverPortQuota(c, remotePort, localPort) {
+		return
+	}
+
+	// Both ports must resolve their templates and be able to go active,
+	// e.g. not while backing off
+	for _, port := range []*models.Port{remotePort, localPort} {
+		if _, err := port.Resolve(); err != nil {
+			c.JSON(portErrorStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if err := port.TransitionTo(models.PortStatusActive, nil); err != nil {
+			c.JSON(portErrorStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
 	// Check if connection already exists
 	existingConnection, _ := h.storage.GetPortConnectionByPorts(c.Request.Context(), request.RemotePortID, request.LocalPortID)
 	if existingConnection != nil {
@@ -370,6 +615,9 @@ func (h *Handlers) CreatePortForward(c *gin.Context) {
 		LocalPortID:  request.LocalPortID,
 		Status:       models.PortStatusConnecting,
 	}
+	if h.reconciler != nil {
+		h.reconciler.Claim(connection)
+	}
 
 	if err := h.storage.CreatePortConnection(c.Request.Context(), connection); err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
@@ -391,12 +639,11 @@ func (h *Handlers) CreatePortForward(c *gin.Context) {
 		return
 	}
 
-	// Update port statuses
-	remotePort.UpdateStatus(models.PortStatusActive)
-	localPort.UpdateStatus(models.PortStatusActive)
-
+	// Persist the port statuses set by the transition check above
 	h.storage.UpdatePort(c.Request.Context(), remotePort)
 	h.storage.UpdatePort(c.Request.Context(), localPort)
+	h.syncPortDNS(c.Request.Context(), localPort.ID, models.PortStatusActive)
+	h.syncPortWebInfo(c.Request.Context(), localPort.ID, models.PortStatusActive)
 
 	c.JSON(http.StatusCreated, Response{
 		Success: true,
@@ -439,13 +686,14 @@ func (h *Handlers) RemovePortForward(c *gin.Context) {
 
 	// Update port statuses
 	if connection.RemotePort.IsActive() {
-		connection.RemotePort.UpdateStatus(models.PortStatusAvailable)
+		connection.RemotePort.TransitionTo(models.PortStatusAvailable, nil)
 		h.storage.UpdatePort(c.Request.Context(), &connection.RemotePort)
 	}
 
 	if connection.LocalPort.IsActive() {
-		connection.LocalPort.UpdateStatus(models.PortStatusAvailable)
+		connection.LocalPort.TransitionTo(models.PortStatusAvailable, nil)
 		h.storage.UpdatePort(c.Request.Context(), &connection.LocalPort)
+		h.syncPortDNS(c.Request.Context(), connection.LocalPortID, models.PortStatusAvailable)
 	}
 
 	c.JSON(http.StatusOK, Response{
@@ -456,17 +704,14 @@ func (h *Handlers) RemovePortForward(c *gin.Context) {
 
 // UpdatePortStatus updates the status of a port
 func (h *Handlers) UpdatePortStatus(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Success: false,
-			Error:   "Invalid port ID",
-		})
+	id, ok := h.resolvePortID(c)
+	if !ok {
 		return
 	}
 
 	var request struct {
 		Status models.PortStatus `json:"status" binding:"required"`
+		Error  *models.PortError `json:"error"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -476,15 +721,58 @@ func (h *Handlers) UpdatePortStatus(c *gin.Context) {
 		})
 		return
 	}
+	if request.Error != nil && request.Error.Code != "" && !request.Error.Code.IsValid() {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid error code: " + string(request.Error.Code),
+		})
+		return
+	}
 
-	if err := h.storage.UpdatePortStatus(c.Request.Context(), uint(id), request.Status); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
+	if request.Status == models.PortStatusActive || request.Status == models.PortStatusConnecting {
+		port, err := h.storage.GetPort(c.Request.Context(), uint(id))
+		if err != nil {
+			c.JSON(portErrorStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		// Templates must resolve before the port can start
+		if _, err := port.Resolve(); err != nil {
+			c.JSON(portErrorStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if h.rejectPortInMaintenance(c, port) {
+			return
+		}
+		if request.Status == models.PortStatusActive && h.rejectOverPortQuota(c, port) {
+			return
+		}
+		if h.rejectWithoutGrant(c, port) {
+			return
+		}
+		if h.rejectWithoutReachableHost(c, port) {
+			return
+		}
+	}
+
+	if err := h.storage.UpdatePortStatus(c.Request.Context(), uint(id), request.Status, request.Error); err != nil {
+		h.logger.Error("Failed to update port status", "port_id", id, "status", request.Status, "error", err)
+		c.JSON(portErrorStatus(err), Response{
 			Success: false,
 			Error:   err.Error(),
 		})
 		return
 	}
 
+	h.logger.Info("Port status updated", "port_id", id, "status", request.Status)
+	h.syncPortDNS(c.Request.Context(), uint(id), request.Status)
+	h.syncPortWebInfo(c.Request.Context(), uint(id), request.Status)
+
 	c.JSON(http.StatusOK, Response{
 		Success: true,
 		Message: "Port status updated successfully",
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+func TestPortErrorStatusCapture(t *testing.T) {
+	for _, capture := range []models.CaptureConfig{
+		{Enabled: true, File: "../escape.cap"},
+		{Enabled: true, File: "/tmp/web.cap"},
+		{Enabled: true, MaxBytes: -1},
+		{Enabled: true, Redact: []string{"("}},
+	} {
+		err := capture.Validate()
+		if err == nil {
+			err = capture.ValidateFileName()
+		}
+		if err == nil {
+			t.Fatalf("capture config %+v passed validation", capture)
+		}
+		if status := portErrorStatus(err); status != http.StatusBadRequest {
+			t.Errorf("portErrorStatus(%v) = %d, want 400", err, status)
+		}
+	}
+}
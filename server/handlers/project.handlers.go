@@ -90,6 +90,18 @@ func (h *Handlers) CreateProject(c *gin.Context) {
 		return
 	}
 
+	if err := project.Quota.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if h.rejectInvalidMetadata(c, models.EntityProject, project.Metadata) {
+		return
+	}
+
 	if err := h.storage.CreateProject(c.Request.Context(), &project); err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
@@ -148,6 +160,18 @@ func (h *Handlers) UpdateProject(c *gin.Context) {
 		return
 	}
 
+	if err := project.Quota.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if h.rejectInvalidMetadata(c, models.EntityProject, project.Metadata) {
+		return
+	}
+
 	project.ID = uint(id)
 	if err := h.storage.UpdateProject(c.Request.Context(), &project); err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
@@ -212,6 +236,40 @@ func (h *Handlers) GetProjectStats(c *gin.Context) {
 	})
 }
 
+// GetProjectDashboard 获取项目仪表盘所需的全部数据
+func (h *Handlers) GetProjectDashboard(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid project ID",
+		})
+		return
+	}
+
+	if _, err := h.storage.GetProject(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Project not found",
+		})
+		return
+	}
+
+	dashboard, err := h.storage.GetProjectDashboard(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    dashboard,
+	})
+}
+
 // GetProjectChildren 获取项目的直接子项目
 func (h *Handlers) GetProjectChildren(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// GetProjectQuota 获取项目配额及当前用量
+func (h *Handlers) GetProjectQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid project ID",
+		})
+		return
+	}
+
+	usage, err := h.storage.GetProjectUsage(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Project not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    usage,
+	})
+}
+
+// rejectOverHostQuota 分组所属项目的主机数达到上限时返回 403，并返回 true
+func (h *Handlers) rejectOverHostQuota(c *gin.Context, groupID uint) bool {
+	group, err := h.storage.GetGroup(c.Request.Context(), groupID)
+	if err != nil {
+		// Unknown groups are left to the caller's own validation
+		return false
+	}
+	usage, err := h.storage.GetProjectUsage(c.Request.Context(), group.ProjectID)
+	if err != nil || !usage.HostsExceeded(1) {
+		return false
+	}
+	return h.rejectOverQuota(c, usage, fmt.Sprintf("project %d allows at most %d hosts", group.ProjectID, usage.MaxHosts))
+}
+
+// rejectOverTunnelQuota 启动 n 条隧道会超出项目的活跃隧道上限时返回 403，并返回 true
+func (h *Handlers) rejectOverTunnelQuota(c *gin.Context, projectID uint, n int) bool {
+	if projectID == 0 || n == 0 {
+		return false
+	}
+	usage, err := h.storage.GetProjectUsage(c.Request.Context(), projectID)
+	if err != nil || !usage.TunnelsExceeded(n) {
+		return false
+	}
+	return h.rejectOverQuota(c, usage, fmt.Sprintf("project %d allows at most %d active tunnels", projectID, usage.MaxActiveTunnels))
+}
+
+// rejectOverPortQuota 激活尚未活跃的端口会超出所属项目的活跃隧道上限时返回 403，并返回 true
+func (h *Handlers) rejectOverPortQuota(c *gin.Context, ports ...*models.Port) bool {
+	activations := make(map[uint]int)
+	for _, port := range ports {
+		if !port.IsActive() {
+			activations[h.portProjectID(c.Request.Context(), port)]++
+		}
+	}
+	for projectID, n := range activations {
+		if h.rejectOverTunnelQuota(c, projectID, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectOverQuota 写入拒绝响应，Data 为配额用量
+func (h *Handlers) rejectOverQuota(c *gin.Context, usage *models.QuotaUsage, reason string) bool {
+	h.logger.Info("Request refused by project quota", "reason", reason)
+	c.JSON(http.StatusForbidden, Response{
+		Success: false,
+		Data:    usage,
+		Error:   fmt.Sprintf("%v: %s", models.ErrQuotaExceeded, reason),
+	})
+	return true
+}
+
+// hostProjectID 获取主机所属项目，找不到时返回 0
+func (h *Handlers) hostProjectID(ctx context.Context, hostID uint) uint {
+	host, err := h.storage.GetHost(ctx, hostID)
+	if err != nil {
+		return 0
+	}
+	return host.Group.ProjectID
+}
+
+// portProjectID 获取端口所属项目，找不到时返回 0
+func (h *Handlers) portProjectID(ctx context.Context, port *models.Port) uint {
+	group, err := h.storage.GetGroup(ctx, port.GroupID)
+	if err != nil {
+		return 0
+	}
+	return group.ProjectID
+}
+
+// applyBandwidthQuota 将项目带宽上限写入隧道配置，同一项目的隧道共享该上限
+// 项目设置了带宽配额时，隧道自身的带宽设置被项目配额取代
+func (h *Handlers) applyBandwidthQuota(ctx context.Context, projectID uint, tunnel *models.TunnelConfig) {
+	if projectID == 0 {
+		return
+	}
+	usage, err := h.storage.GetProjectUsage(ctx, projectID)
+	if err != nil || usage.MaxBandwidth <= 0 {
+		return
+	}
+	tunnel.BandwidthLimit = usage.MaxBandwidth
+	tunnel.BandwidthGroup = fmt.Sprintf("project:%d", projectID)
+}
@@ -0,0 +1,476 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	sshpkg "github.com/aqz236/port-fly/core/ssh"
+)
+
+// 可达性探测的默认值与限制
+const (
+	defaultReachabilityTimeout = 5 * time.Second  // 每次探测的超时
+	reachabilityConnectTimeout = 30 * time.Second // 连接源主机的超时
+	maxProbesPerSource         = 8                // 每台源主机同时进行的探测数
+	maxReachabilityPorts       = 20
+)
+
+// ReachabilityProbeRequest 从源主机探测一个目标，target_host_id 与 target 二选一
+type ReachabilityProbeRequest struct {
+	TargetHostID uint   `json:"target_host_id"` // 已保存的目标主机，端口默认为其 SSH 端口
+	Target       string `json:"target"`         // 任意主机名或 IP
+	Port         int    `json:"port"`           // 目标端口，target 为地址时必填
+	Timeout      int    `json:"timeout"`        // 毫秒，默认 5 秒
+}
+
+// ReachabilityMatrixRequest 在分组的主机之间两两探测
+type ReachabilityMatrixRequest struct {
+	Ports       []int  `json:"ports"`       // 目标端口，默认为各目标主机的 SSH 端口，最多 20 个
+	Sources     []uint `json:"sources"`     // 只从这些主机探测，默认为分组的所有主机
+	Timeout     int    `json:"timeout"`     // 每次探测的超时（毫秒），默认 5 秒
+	Concurrency int    `json:"concurrency"` // 同时作为源的主机数，默认 10，最多 100
+}
+
+// ReachabilityHost 矩阵中的主机
+type ReachabilityHost struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	Port     int    `json:"port"`
+}
+
+// ReachabilityMatrix 分组的可达性矩阵：每个源主机到各目标的最新探测结果
+type ReachabilityMatrix struct {
+	GroupID     uint                  `json:"group_id"`
+	Hosts       []ReachabilityHost    `json:"hosts"`
+	Reachable   int                   `json:"reachable"`
+	Unreachable int                   `json:"unreachable"`
+	Unknown     int                   `json:"unknown"`              // 未能探测，如连不上源主机
+	CheckedAt   *time.Time            `json:"checked_at,omitempty"` // 最近一次探测的时间
+	Results     []models.Reachability `json:"results"`              // 按源主机、目标排序
+}
+
+// add counts a probe result into the matrix
+func (m *ReachabilityMatrix) add(result models.Reachability) {
+	switch {
+	case result.Reachable:
+		m.Reachable++
+	case result.Method == "":
+		m.Unknown++
+	default:
+		m.Unreachable++
+	}
+	if m.CheckedAt == nil || result.CheckedAt.After(*m.CheckedAt) {
+		checkedAt := result.CheckedAt
+		m.CheckedAt = &checkedAt
+	}
+	m.Results = append(m.Results, result)
+}
+
+// newReachabilityMatrix lays out results between the group's hosts
+func newReachabilityMatrix(groupID uint, hosts []models.Host) *ReachabilityMatrix {
+	matrix := &ReachabilityMatrix{
+		GroupID: groupID,
+		Hosts:   make([]ReachabilityHost, 0, len(hosts)),
+		Results: []models.Reachability{},
+	}
+	for _, host := range hosts {
+		matrix.Hosts = append(matrix.Hosts, ReachabilityHost{ID: host.ID, Name: host.Name, Hostname: host.Hostname, Port: host.Port})
+	}
+	return matrix
+}
+
+// reachabilityTarget is an address the source hosts are probed against
+type reachabilityTarget struct {
+	hostID *uint
+	host   string
+	port   int
+}
+
+// hostTarget is a saved host as a probe target, on port or its SSH port
+func hostTarget(host *models.Host, port int) (reachabilityTarget, error) {
+	resolved, err := host.Resolve()
+	if err != nil {
+		return reachabilityTarget{}, err
+	}
+	if port == 0 {
+		port = resolved.Port
+	}
+	id := host.ID
+	return reachabilityTarget{hostID: &id, host: resolved.Hostname, port: port}, nil
+}
+
+// ProbeHostReachability 从主机探测能否连接另一台主机或任意地址的端口，结果计入源主机所在分组的矩阵。
+// 连不上源主机时同样返回 200，结果中 method 为空并给出原因
+func (h *Handlers) ProbeHostReachability(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid host ID",
+		})
+		return
+	}
+
+	var req ReachabilityProbeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if (req.TargetHostID == 0) == (req.Target == "") {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "exactly one of target_host_id and target is required",
+		})
+		return
+	}
+	if req.Port < 0 || req.Port > 65535 || req.Timeout < 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "port must be between 1 and 65535 and timeout must not be negative",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	source, err := h.storage.GetHost(ctx, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Host not found",
+		})
+		return
+	}
+
+	var target reachabilityTarget
+	if req.TargetHostID != 0 {
+		targetHost, err := h.storage.GetHost(ctx, req.TargetHostID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Error:   "Target host not found",
+			})
+			return
+		}
+		if target, err = hostTarget(targetHost, req.Port); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	} else {
+		if req.Port == 0 {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "port is required when probing an address",
+			})
+			return
+		}
+		target = reachabilityTarget{host: req.Target, port: req.Port}
+	}
+
+	timeout := time.Duration(req.Timeout) * time.Millisecond
+	if timeout == 0 {
+		timeout = defaultReachabilityTimeout
+	}
+
+	ctx = withChallengeScope(ctx, changeActor(c), nil)
+	result := h.probeFrom(ctx, source.GroupID, source, []reachabilityTarget{target}, timeout)[0]
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// BuildReachabilityMatrix 从分组的每台主机探测能否连接组内其他主机的端口，保存并返回结果矩阵。
+// Accept 为 text/event-stream 或 stream=true 时，每台源主机完成后推送 source 事件，最后推送 matrix 事件
+func (h *Handlers) BuildReachabilityMatrix(c *gin.Context) {
+	groupID, ok := h.resolveGroupID(c)
+	if !ok {
+		return
+	}
+
+	var req ReachabilityMatrixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if req.Timeout < 0 || req.Concurrency < 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "timeout and concurrency must not be negative",
+		})
+		return
+	}
+	if len(req.Ports) > maxReachabilityPorts {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   fmt.Sprintf("at most %d ports can be probed at once", maxReachabilityPorts),
+		})
+		return
+	}
+	for _, port := range req.Ports {
+		if port < 1 || port > 65535 {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   fmt.Sprintf("invalid port %d", port),
+			})
+			return
+		}
+	}
+
+	hosts, ok := h.groupHosts(c, groupID)
+	if !ok {
+		return
+	}
+	sources, err := reachabilitySources(hosts, req.Sources)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	// Every host is a target on each requested port, or on its SSH port
+	ports := req.Ports
+	if len(ports) == 0 {
+		ports = []int{0}
+	}
+	var targets []reachabilityTarget
+	for i := range hosts {
+		for _, port := range ports {
+			target, err := hostTarget(&hosts[i], port)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, Response{
+					Success: false,
+					Error:   fmt.Sprintf("host %s: %v", hosts[i].Name, err),
+				})
+				return
+			}
+			targets = append(targets, target)
+		}
+	}
+
+	timeout := time.Duration(req.Timeout) * time.Millisecond
+	if timeout == 0 {
+		timeout = defaultReachabilityTimeout
+	}
+	concurrency := fanOut(req.Concurrency, len(sources))
+
+	// Large groups outlive the server write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	stream := c.Query("stream") == "true" || strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	ctx, challenges := h.challengeStream(c, stream)
+	startTime := time.Now()
+	matrix := newReachabilityMatrix(groupID, hosts)
+	results := forEachHost(ctx, sources, concurrency,
+		func(source *models.Host) []models.Reachability {
+			return h.probeFrom(ctx, groupID, source, otherTargets(targets, source.ID), timeout)
+		},
+		func(source *models.Host, err error) []models.Reachability {
+			return nil
+		})
+	streamResults(c, results, challenges, func(sourceResults []models.Reachability) {
+		for _, result := range sourceResults {
+			matrix.add(result)
+		}
+		if stream && len(sourceResults) > 0 {
+			c.SSEvent("source", sourceResults)
+			c.Writer.Flush()
+		}
+	})
+	sortReachability(matrix.Results)
+
+	h.logger.Info("Reachability matrix built",
+		"group_id", groupID,
+		"sources", len(sources),
+		"reachable", matrix.Reachable,
+		"unreachable", matrix.Unreachable,
+		"unknown", matrix.Unknown,
+		"duration_ms", time.Since(startTime).Milliseconds())
+
+	if stream {
+		c.SSEvent("matrix", matrix)
+		return
+	}
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    matrix,
+	})
+}
+
+// GetReachabilityMatrix 获取分组保存的可达性矩阵，只包含仍在组内的源主机
+func (h *Handlers) GetReachabilityMatrix(c *gin.Context) {
+	groupID, ok := h.resolveGroupID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.storage.GetGroup(ctx, groupID); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Group not found",
+		})
+		return
+	}
+	hosts, err := h.storage.GetHostsByGroup(ctx, groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	results, err := h.storage.GetReachability(ctx, groupID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	inGroup := make(map[uint]bool, len(hosts))
+	for _, host := range hosts {
+		inGroup[host.ID] = true
+	}
+	matrix := newReachabilityMatrix(groupID, hosts)
+	for _, result := range results {
+		if inGroup[result.SourceHostID] {
+			matrix.add(result)
+		}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    matrix,
+	})
+}
+
+// reachabilitySources picks the hosts to probe from, all of them unless ids
+// names some
+func reachabilitySources(hosts []models.Host, ids []uint) ([]models.Host, error) {
+	if len(ids) == 0 {
+		return hosts, nil
+	}
+	byID := make(map[uint]models.Host, len(hosts))
+	for _, host := range hosts {
+		byID[host.ID] = host
+	}
+	sources := make([]models.Host, 0, len(ids))
+	for _, id := range ids {
+		host, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("source host %d is not in the group", id)
+		}
+		sources = append(sources, host)
+	}
+	return sources, nil
+}
+
+// otherTargets drops the source itself from the targets
+func otherTargets(targets []reachabilityTarget, sourceID uint) []reachabilityTarget {
+	others := make([]reachabilityTarget, 0, len(targets))
+	for _, target := range targets {
+		if target.hostID == nil || *target.hostID != sourceID {
+			others = append(others, target)
+		}
+	}
+	return others
+}
+
+// sortReachability orders results by source host, then target
+func sortReachability(results []models.Reachability) {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.SourceHostID != b.SourceHostID {
+			return a.SourceHostID < b.SourceHostID
+		}
+		if a.TargetHost != b.TargetHost {
+			return a.TargetHost < b.TargetHost
+		}
+		return a.TargetPort < b.TargetPort
+	})
+}
+
+// probeFrom connects to source and probes the targets from it, a few at a
+// time, saving each result in the group's matrix. When the source cannot be
+// reached every target is recorded as unknown with the reason.
+func (h *Handlers) probeFrom(ctx context.Context, groupID uint, source *models.Host, targets []reachabilityTarget, timeout time.Duration) []models.Reachability {
+	results := make([]models.Reachability, len(targets))
+	record := func(i int, probe sshpkg.ProbeResult) {
+		results[i] = models.Reachability{
+			GroupID:      groupID,
+			SourceHostID: source.ID,
+			TargetHostID: targets[i].hostID,
+			TargetHost:   targets[i].host,
+			TargetPort:   targets[i].port,
+			Reachable:    probe.Reachable,
+			Method:       probe.Method,
+			CheckedAt:    time.Now(),
+		}
+		if probe.Latency > 0 {
+			latency := float64(probe.Latency.Microseconds()) / 1000
+			results[i].LatencyMs = &latency
+		}
+		if probe.Err != nil {
+			message := probe.Err.Error()
+			if len(message) > 500 {
+				message = message[:500]
+			}
+			results[i].Error = message
+		}
+	}
+
+	sshClient, disconnect, err := h.connectHost(ctx, source, models.AuthSourceReachability, reachabilityConnectTimeout)
+	if err != nil {
+		for i := range targets {
+			record(i, sshpkg.ProbeResult{Err: err})
+		}
+	} else {
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, maxProbesPerSource)
+		for i := range targets {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				record(i, sshClient.ProbeTCP(ctx, targets[i].host, targets[i].port, timeout))
+			}()
+		}
+		wg.Wait()
+		disconnect()
+	}
+
+	// Probes cut short by the client leaving say nothing about the network
+	if ctx.Err() != nil {
+		return results
+	}
+	for i := range results {
+		if err := h.storage.SaveReachability(ctx, &results[i]); err != nil {
+			h.logger.Warn("Failed to save reachability", "source_host_id", source.ID, "target", results[i].TargetHost, "error", err)
+		}
+	}
+	return results
+}
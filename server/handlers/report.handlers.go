@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// GetUnusedReport 闲置资源报告：?days 天内（默认 30）未使用的端口、未连接的主机和没有流量的分组
+func (h *Handlers) GetUnusedReport(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(models.DefaultUnusedDays)))
+	if err != nil {
+		days = 0
+	}
+	now := time.Now()
+	since, err := models.UnusedSince(now, days)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	report, err := h.storage.GetUsageReport(c.Request.Context(), since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	report.Days = days
+	report.GeneratedAt = now
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+	})
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// RuntimeState 合并视图中会话记录与运行时状态的对应关系
+type RuntimeState string
+
+const (
+	RuntimeLive     RuntimeState = "live"     // 记录对应正在运行的会话
+	RuntimeStopped  RuntimeState = "stopped"  // 记录未在运行，也没有运行时会话
+	RuntimeOrphaned RuntimeState = "orphaned" // 记录显示正在运行，但本实例没有对应的运行时会话
+	RuntimeUnknown  RuntimeState = "unknown"  // 运行时会话没有对应的记录
+)
+
+// RuntimeSession 持久化的隧道会话与运行时状态的合并视图
+type RuntimeSession struct {
+	ID              uint                  `json:"id,omitempty"`         // 数据库记录 ID，未持久化的会话为 0
+	RuntimeID       string                `json:"runtime_id,omitempty"` // 会话管理器中的 ID，未运行时为空
+	Name            string                `json:"name,omitempty"`
+	State           RuntimeState          `json:"state"`
+	Status          models.SessionStatus  `json:"status"`                     // 运行时状态优先，否则为记录中的状态
+	PersistedStatus models.SessionStatus  `json:"persisted_status,omitempty"` // 记录中的状态
+	StatusMismatch  bool                  `json:"status_mismatch,omitempty"`  // 记录与运行时状态不一致
+	LocalAddress    string                `json:"local_address,omitempty"`
+	Stats           *models.SessionStats  `json:"stats,omitempty"` // 仅运行中的会话
+	Record          *models.TunnelSession `json:"record,omitempty"`
+}
+
+// GetRuntimeSessions returns the persisted tunnel sessions joined with the
+// sessions running in this process, so stale records show as orphaned and
+// sessions without a record as unknown
+func (h *Handlers) GetRuntimeSessions(c *gin.Context) {
+	records, err := h.storage.GetTunnelSessions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	live, err := h.sessionManager.ListSessions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    mergeRuntimeSessions(records, live, h.portTunnels.sessionRecords()),
+	})
+}
+
+// mergeRuntimeSessions matches records to running sessions: port tunnels by
+// the record saved when they started (portRecords maps their sessions to
+// it), other sessions by name, then by the address the tunnel listens on
+func mergeRuntimeSessions(records []models.TunnelSession, live []*models.Session, portRecords map[string]uint) []RuntimeSession {
+	byRecord := make(map[uint]*models.Session)
+	byName := make(map[string]*models.Session)
+	byAddress := make(map[string]*models.Session)
+	for _, session := range live {
+		if recordID, ok := portRecords[session.ID]; ok {
+			byRecord[recordID] = session
+			continue
+		}
+		if session.Name != "" {
+			byName[session.Name] = session
+		}
+		byAddress[session.TunnelConfig.Binds()[0].String()] = session
+	}
+	matched := make(map[string]bool)
+
+	find := func(record models.TunnelSession) *models.Session {
+		if session, ok := byRecord[record.ID]; ok {
+			return session
+		}
+		// Records of port tunnels only match the tunnel they were saved for
+		if record.PortID != nil {
+			return nil
+		}
+		if session, ok := byName[record.Name]; ok && record.Name != "" && !matched[session.ID] {
+			return session
+		}
+		if session, ok := byAddress[record.LocalAddress]; ok && record.LocalAddress != "" && !matched[session.ID] {
+			return session
+		}
+		return nil
+	}
+
+	merged := make([]RuntimeSession, 0, len(records)+len(live))
+	for i := range records {
+		record := &records[i]
+		entry := RuntimeSession{
+			ID:              record.ID,
+			Name:            record.Name,
+			Status:          record.Status,
+			PersistedStatus: record.Status,
+			LocalAddress:    record.LocalAddress,
+			Record:          record,
+		}
+
+		if session := find(*record); session != nil {
+			matched[session.ID] = true
+			entry.State = RuntimeLive
+			entry.RuntimeID = session.ID
+			entry.Status = session.Status
+			entry.StatusMismatch = session.Status != record.Status
+			stats := session.Stats
+			entry.Stats = &stats
+		} else if sessionRunning(record.Status) {
+			entry.State = RuntimeOrphaned
+		} else {
+			entry.State = RuntimeStopped
+		}
+		merged = append(merged, entry)
+	}
+
+	for _, session := range live {
+		if matched[session.ID] {
+			continue
+		}
+		stats := session.Stats
+		merged = append(merged, RuntimeSession{
+			RuntimeID:    session.ID,
+			Name:         session.Name,
+			State:        RuntimeUnknown,
+			Status:       session.Status,
+			LocalAddress: session.TunnelConfig.Binds()[0].String(),
+			Stats:        &stats,
+		})
+	}
+
+	// Problems first, then by record
+	rank := map[RuntimeState]int{RuntimeOrphaned: 0, RuntimeUnknown: 1, RuntimeLive: 2, RuntimeStopped: 3}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return rank[merged[i].State] < rank[merged[j].State]
+	})
+	return merged
+}
+
+// sessionRunning reports whether a session record claims its tunnel is up or
+// being brought up
+func sessionRunning(status models.SessionStatus) bool {
+	switch status {
+	case models.StatusConnecting, models.StatusConnected, models.StatusActive,
+		models.StatusBackingOff, models.StatusDisconnected, models.StatusStopping:
+		return true
+	default:
+		return false
+	}
+}
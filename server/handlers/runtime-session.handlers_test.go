@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/ssh/sshtest"
+)
+
+func TestMergeRuntimeSessionsByPortRecord(t *testing.T) {
+	portID := uint(7)
+	records := []models.TunnelSession{
+		{ID: 1, Status: models.StatusStopped, PortID: &portID, LocalAddress: "127.0.0.1:9000"},
+		{ID: 2, Status: models.StatusActive, PortID: &portID, LocalAddress: "127.0.0.1:9000"},
+		{ID: 3, Status: models.StatusActive, Name: "db"},
+	}
+	tunnel := &models.Session{
+		ID:     "port-session",
+		Status: models.StatusActive,
+		TunnelConfig: models.TunnelConfig{
+			Type:              models.TunnelTypeRemote,
+			RemoteBindAddress: "127.0.0.1",
+			LocalPort:         9000,
+		},
+	}
+	named := &models.Session{
+		ID:     "named-session",
+		Name:   "db",
+		Status: models.StatusActive,
+		TunnelConfig: models.TunnelConfig{
+			Type:             models.TunnelTypeLocal,
+			LocalBindAddress: "127.0.0.1",
+			LocalPort:        5432,
+		},
+	}
+
+	merged := mergeRuntimeSessions(records, []*models.Session{tunnel, named}, map[string]uint{tunnel.ID: 2})
+	states := make(map[uint]RuntimeSession)
+	for _, entry := range merged {
+		if entry.ID == 0 {
+			t.Fatalf("live session %s has no record", entry.RuntimeID)
+		}
+		states[entry.ID] = entry
+	}
+	if entry := states[2]; entry.State != RuntimeLive || entry.RuntimeID != tunnel.ID {
+		t.Fatalf("current port record = %s (%s), want live on %s", entry.State, entry.RuntimeID, tunnel.ID)
+	}
+	// An earlier record of the same port listening on the same address
+	if entry := states[1]; entry.State != RuntimeStopped {
+		t.Fatalf("earlier port record = %s, want stopped", entry.State)
+	}
+	if entry := states[3]; entry.State != RuntimeLive || entry.RuntimeID != named.ID {
+		t.Fatalf("named record = %s (%s), want live on %s", entry.State, entry.RuntimeID, named.ID)
+	}
+}
+
+func TestRuntimeSessionsShowPortTunnelsLive(t *testing.T) {
+	server, err := sshtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SSH server: %v", err)
+	}
+	defer server.Close()
+	h := newTestHandlers(t)
+	port := createTestTunnelPorts(t, h, createTestHost(t, h, server))
+	startTestPort(t, h, port)
+
+	status, response := serve(t, h.GetRuntimeSessions, http.MethodGet, "/sessions/runtime", "/sessions/runtime", nil)
+	if status != http.StatusOK {
+		t.Fatalf("status = %d: %s", status, response.Error)
+	}
+	var merged []RuntimeSession
+	decodeData(t, response, &merged)
+	if len(merged) != 1 {
+		t.Fatalf("got %d runtime sessions, want 1: %+v", len(merged), merged)
+	}
+	entry := merged[0]
+	if entry.State != RuntimeLive || entry.Record == nil || entry.Record.PortID == nil || *entry.Record.PortID != port.ID {
+		t.Fatalf("port tunnel = %s with record %+v, want live with the port's record", entry.State, entry.Record)
+	}
+}
@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/importer"
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/setup"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// Names of the project and group setup imports hosts into when no group is given
+const (
+	setupProjectName = "Default"
+	setupGroupName   = "SSH config"
+)
+
+// SetupStatus 首次运行设置的状态
+type SetupStatus struct {
+	Required        bool             `json:"required"`  // 数据库为空且设置未完成，客户端应显示设置向导
+	Completed       bool             `json:"completed"` // 设置已完成
+	Locked          bool             `json:"locked"`    // 设置接口已锁定：设置已完成，或数据库在设置开始前已有数据
+	CompletedAt     *time.Time       `json:"completed_at,omitempty"`
+	Steps           SetupSteps       `json:"steps"`
+	Storage         *SetupStorage    `json:"storage,omitempty"`    // 选定的存储，下次启动时生效
+	MasterKey       *setup.MasterKey `json:"master_key,omitempty"` // 主密钥指纹，密钥本身不会返回
+	StorageDrivers  []string         `json:"storage_drivers"`      // 本服务器可用的存储驱动
+	RestartRequired bool             `json:"restart_required"`     // 存储或主密钥在重启后生效
+	Admin           string           `json:"admin,omitempty"`      // 管理员用户名
+}
+
+// SetupSteps 各设置步骤是否完成
+type SetupSteps struct {
+	Admin         bool `json:"admin"`
+	Storage       bool `json:"storage"`
+	MasterKey     bool `json:"master_key"`
+	ImportedHosts int  `json:"imported_hosts"`
+}
+
+// SetupStorage 选定存储的摘要，不含密码
+type SetupStorage struct {
+	Type     string `json:"type"`
+	Host     string `json:"host,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Database string `json:"database"`
+	Username string `json:"username,omitempty"`
+}
+
+// SetupAdminRequest 创建管理员请求
+type SetupAdminRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// SetupMasterKeyRequest 设置主密钥请求，key 为空时生成随机密钥
+type SetupMasterKeyRequest struct {
+	Key string `json:"key"`
+}
+
+// SetupMasterKeyResponse 主密钥设置结果，密钥只在此返回一次
+type SetupMasterKeyResponse struct {
+	Key string `json:"key"`
+	setup.MasterKey
+}
+
+// SetupImportRequest 导入 OpenSSH 配置请求。私钥不会导入，IdentityFile 的路径记录在主机描述中
+type SetupImportRequest struct {
+	Content string `json:"content"`  // 配置内容，省略时读取服务器用户的 ~/.ssh/config
+	GroupID uint   `json:"group_id"` // 目标分组，省略时创建 Default 项目下的 "SSH config" 分组
+	DryRun  bool   `json:"dry_run"`
+}
+
+// SetSetup sets the first-run setup state behind the setup endpoints
+func (h *Handlers) SetSetup(manager *setup.Manager) {
+	h.setup = manager
+}
+
+// GetSetup 返回首次运行设置的状态：数据库为空且设置未完成时 required 为 true
+func (h *Handlers) GetSetup(c *gin.Context) {
+	if !h.requireSetup(c) {
+		return
+	}
+
+	locked, err := h.setupLocked(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	state := h.setup.State()
+	status := SetupStatus{
+		Required:    !locked,
+		Completed:   state.Completed(),
+		Locked:      locked,
+		CompletedAt: state.CompletedAt,
+		Steps: SetupSteps{
+			Admin:         state.Admin != nil,
+			Storage:       state.Storage != nil,
+			MasterKey:     state.MasterKey != nil,
+			ImportedHosts: state.ImportedHosts,
+		},
+		MasterKey:       state.MasterKey,
+		StorageDrivers:  storage.Drivers(),
+		RestartRequired: h.setup.RestartRequired(),
+	}
+	if state.Admin != nil {
+		status.Admin = state.Admin.Username
+	}
+	if state.Storage != nil {
+		status.Storage = &SetupStorage{
+			Type:     state.Storage.Type,
+			Host:     state.Storage.Host,
+			Port:     state.Storage.Port,
+			Database: state.Storage.Database,
+			Username: state.Storage.Username,
+		}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    status,
+	})
+}
+
+// SetupAdmin 创建管理员账户，密码以 bcrypt 哈希保存；设置完成前可重新设置
+func (h *Handlers) SetupAdmin(c *gin.Context) {
+	if !h.requireSetupOpen(c) {
+		return
+	}
+
+	var req SetupAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := h.setup.SetAdmin(req.Username, req.Password); err != nil {
+		h.setupError(c, err)
+		return
+	}
+
+	h.logger.Info("Setup admin created", "username", strings.TrimSpace(req.Username))
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Message: "Admin user created",
+	})
+}
+
+// SetupStorage 选择存储后端：校验配置并测试连接，保存后在服务器下次启动时生效
+func (h *Handlers) SetupStorage(c *gin.Context) {
+	if !h.requireSetupOpen(c) {
+		return
+	}
+
+	var req storage.StorageConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if err := h.setup.SetStorage(req); err != nil {
+		h.setupError(c, err)
+		return
+	}
+
+	h.logger.Info("Setup storage chosen", "type", req.Type, "database", req.Database)
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Storage saved; restart the server to use it",
+	})
+}
+
+// SetupMasterKey 设置主密钥（用作备份加密口令），未提供时生成随机密钥。
+// 密钥只在响应中返回一次，需离线保存；已有主密钥时不可替换，以免已有备份无法解密
+func (h *Handlers) SetupMasterKey(c *gin.Context) {
+	if !h.requireSetupOpen(c) {
+		return
+	}
+
+	var req SetupMasterKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	key, err := h.setup.SetMasterKey(req.Key)
+	if err != nil {
+		h.setupError(c, err)
+		return
+	}
+
+	response := SetupMasterKeyResponse{Key: key}
+	if masterKey := h.setup.State().MasterKey; masterKey != nil {
+		response.MasterKey = *masterKey
+	}
+	h.logger.Info("Setup master key set", "fingerprint", response.Fingerprint, "generated", response.Generated)
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    response,
+		Message: "Master key set; store it safely, it is not shown again",
+	})
+}
+
+// SetupImportSSHConfig 导入 OpenSSH 客户端配置中的主机，默认读取服务器用户的 ~/.ssh/config。
+// 未指定 group_id 时导入 Default 项目下的 "SSH config" 分组（不存在时创建）
+func (h *Handlers) SetupImportSSHConfig(c *gin.Context) {
+	if !h.requireSetupOpen(c) {
+		return
+	}
+
+	var req SetupImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	data := []byte(req.Content)
+	if req.Content == "" {
+		path, err := userSSHConfig()
+		if err == nil {
+			data, err = os.ReadFile(path)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Failed to read SSH config: " + err.Error(),
+			})
+			return
+		}
+	}
+	parsed, err := importer.Parse(importer.FormatOpenSSH, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	groupID := req.GroupID
+	if groupID == 0 && !req.DryRun {
+		group, err := h.setupGroup(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		groupID = group.ID
+	}
+
+	result, ok := h.importEntries(c, parsed, groupID, req.DryRun)
+	if !ok {
+		return
+	}
+	if !req.DryRun {
+		if err := h.setup.AddImportedHosts(result.Created); err != nil {
+			h.setupError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// CompleteSetup 完成设置并锁定所有设置接口，需先创建管理员
+func (h *Handlers) CompleteSetup(c *gin.Context) {
+	if !h.requireSetupOpen(c) {
+		return
+	}
+
+	if err := h.setup.Complete(); err != nil {
+		h.setupError(c, err)
+		return
+	}
+
+	message := "Setup completed"
+	if h.setup.RestartRequired() {
+		message += "; restart the server to apply the storage and master key"
+	}
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: message,
+	})
+}
+
+// AdminAuth 管理接口在网络监听上提供时的认证：HTTP Basic 认证，用户名和密码为设置时创建的管理员
+func (h *Handlers) AdminAuth(c *gin.Context) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok || h.setup == nil || !h.setup.CheckAdmin(username, password) {
+		c.Header("WWW-Authenticate", `Basic realm="PortFly admin"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Error:   "Admin credentials required",
+		})
+		return
+	}
+	c.Next()
+}
+
+// setupLocked reports whether the setup steps are refused: once setup is
+// completed, and on servers whose database held data before setup started,
+// which were set up before the setup flow existed
+func (h *Handlers) setupLocked(ctx context.Context) (bool, error) {
+	state := h.setup.State()
+	if state.Completed() {
+		return true, nil
+	}
+	if state.Started() {
+		return false, nil
+	}
+
+	projects, err := h.storage.GetProjects(ctx)
+	if err != nil {
+		return false, err
+	}
+	hosts, err := h.storage.GetHosts(ctx)
+	if err != nil {
+		return false, err
+	}
+	return len(projects) > 0 || len(hosts) > 0, nil
+}
+
+// setupGroup returns the group setup imports into, creating it and its
+// project when missing
+func (h *Handlers) setupGroup(c *gin.Context) (*models.Group, error) {
+	ctx := c.Request.Context()
+	groups, err := h.storage.GetGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := h.storage.GetProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var project *models.Project
+	for i := range projects {
+		if projects[i].Name == setupProjectName && projects[i].ParentID == nil {
+			project = &projects[i]
+			break
+		}
+	}
+	if project != nil {
+		for i := range groups {
+			if groups[i].ProjectID == project.ID && groups[i].Name == setupGroupName {
+				return &groups[i], nil
+			}
+		}
+	} else {
+		project = &models.Project{
+			Name:        setupProjectName,
+			Description: "Created by setup",
+			IsDefault:   true,
+		}
+		if err := h.storage.CreateProject(ctx, project); err != nil {
+			return nil, err
+		}
+	}
+
+	group := &models.Group{
+		Name:        setupGroupName,
+		Description: "Hosts imported from an OpenSSH config during setup",
+		ProjectID:   project.ID,
+	}
+	if err := h.storage.CreateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+	h.recordChange(c, models.EntityGroup, group.ID, models.ChangeCreate, nil, h.entityFields(models.EntityGroup, group))
+	return group, nil
+}
+
+// userSSHConfig returns the OpenSSH client config of the server user
+func userSSHConfig() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ssh", "config"), nil
+}
+
+// setupTokenHeader carries the setup token written to the token file at start
+const setupTokenHeader = "X-PortFly-Setup-Token"
+
+// requireSetupOpen checks that setup is configured and not locked, and that
+// the request carries the setup token
+func (h *Handlers) requireSetupOpen(c *gin.Context) bool {
+	if !h.requireSetup(c) {
+		return false
+	}
+	locked, err := h.setupLocked(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return false
+	}
+	if locked {
+		c.JSON(http.StatusForbidden, Response{
+			Success: false,
+			Error:   "Setup is locked",
+		})
+		return false
+	}
+	if !h.setup.CheckToken(c.GetHeader(setupTokenHeader)) {
+		c.JSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Error:   "Invalid setup token; send the token from " + h.setup.TokenFile() + " in the " + setupTokenHeader + " header",
+		})
+		return false
+	}
+	return true
+}
+
+// setupError maps setup errors to responses
+func (h *Handlers) setupError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, setup.ErrCompleted):
+		status = http.StatusForbidden
+	case errors.Is(err, setup.ErrKeyExists):
+		status = http.StatusConflict
+	case errors.Is(err, setup.ErrInvalidInput), errors.Is(err, setup.ErrAdminRequired):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, Response{
+		Success: false,
+		Error:   err.Error(),
+	})
+}
+
+func (h *Handlers) requireSetup(c *gin.Context) bool {
+	if h.setup == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Setup is not configured",
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// RunSnippetRequest 在一台主机或分组的所有主机上执行命令片段，host_id 与 group_id 二选一
+type RunSnippetRequest struct {
+	HostID       uint              `json:"host_id,omitempty"`
+	GroupID      uint              `json:"group_id,omitempty"`
+	Params       map[string]string `json:"params,omitempty"`        // 参数值，省略的参数使用默认值
+	Timeout      int               `json:"timeout"`                 // 每台主机的超时（毫秒），默认 30 秒
+	Concurrency  int               `json:"concurrency"`             // 同时执行的主机数，默认 10，最多 100
+	Sudo         *bool             `json:"sudo,omitempty"`          // 覆盖片段的 sudo 设置
+	SudoPassword string            `json:"sudo_password,omitempty"` // 本次使用的 sudo 密码，覆盖各主机保存的密码
+}
+
+// SnippetRunResult 单台主机的执行结果，附带识别的操作系统系列和实际执行的命令
+type SnippetRunResult struct {
+	HostExecResult
+	OSFamily string `json:"os_family,omitempty"`
+	Command  string `json:"command,omitempty"`
+}
+
+// SnippetRunReport 所有主机执行结果的汇总
+type SnippetRunReport struct {
+	SnippetID uint               `json:"snippet_id"`
+	Name      string             `json:"name"`
+	Hosts     int                `json:"hosts"`
+	Succeeded int                `json:"succeeded"` // 退出码为 0
+	Failed    int                `json:"failed"`    // 退出码非 0
+	Errors    int                `json:"errors"`    // 命令未能执行完，或没有适用于主机系统的命令
+	Duration  int64              `json:"duration"`  // 毫秒
+	Results   []SnippetRunResult `json:"results"`   // 按主机 ID 排序
+}
+
+// add counts one host's result into the report
+func (r *SnippetRunReport) add(result SnippetRunResult) {
+	switch {
+	case result.Error != "":
+		r.Errors++
+	case result.ExitCode == 0:
+		r.Succeeded++
+	default:
+		r.Failed++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// snippetErrorStatus maps snippet validation errors to 400, unknown snippets
+// to 404 and name conflicts to 409
+func snippetErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidSnippet), errors.Is(err, models.ErrInvalidSnippetParam):
+		return http.StatusBadRequest
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrNameConflict):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// loadSnippet 解析路径参数 :id 并读取命令片段，失败时已写入错误响应
+func (h *Handlers) loadSnippet(c *gin.Context) (*models.Snippet, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid snippet ID",
+		})
+		return nil, false
+	}
+
+	snippet, err := h.storage.GetSnippet(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(snippetErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+	return snippet, true
+}
+
+// GetSnippets 列出命令片段
+// Query: os_family=只列出有适用于该系列命令的片段, tag=按标签过滤
+func (h *Handlers) GetSnippets(c *gin.Context) {
+	family := c.Query("os_family")
+	if err := models.ValidateOSFamily(family); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	snippets, err := h.storage.GetSnippets(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	tag := c.Query("tag")
+	filtered := snippets[:0]
+	for _, snippet := range snippets {
+		if _, ok := snippet.CommandFor(family); family != "" && !ok {
+			continue
+		}
+		if tag != "" && !slices.Contains(snippet.Tags, tag) {
+			continue
+		}
+		filtered = append(filtered, snippet)
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    filtered,
+	})
+}
+
+// GetSnippet 获取命令片段
+func (h *Handlers) GetSnippet(c *gin.Context) {
+	snippet, ok := h.loadSnippet(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    snippet,
+	})
+}
+
+// CreateSnippet 创建命令片段，请求方（X-PortFly-Actor）记为创建者
+func (h *Handlers) CreateSnippet(c *gin.Context) {
+	var snippet models.Snippet
+	if err := c.ShouldBindJSON(&snippet); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+	snippet.ID = 0
+	snippet.CreatedBy = changeActor(c)
+
+	if err := h.storage.CreateSnippet(c.Request.Context(), &snippet); err != nil {
+		c.JSON(snippetErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    snippet,
+	})
+}
+
+// UpdateSnippet 修改命令片段，仅限创建者和 editors 中的用户，创建者不可更改
+func (h *Handlers) UpdateSnippet(c *gin.Context) {
+	snippet, ok := h.loadSnippet(c)
+	if !ok || h.rejectSnippetEdit(c, snippet) {
+		return
+	}
+
+	id, createdBy, commands := snippet.ID, snippet.CreatedBy, snippet.Commands
+	// Commands given in the body replace the saved ones rather than merging
+	snippet.Commands = nil
+	if err := c.ShouldBindJSON(snippet); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+	snippet.ID, snippet.CreatedBy = id, createdBy
+	if snippet.Commands == nil {
+		snippet.Commands = commands
+	}
+
+	if err := h.storage.UpdateSnippet(c.Request.Context(), snippet); err != nil {
+		c.JSON(snippetErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    snippet,
+	})
+}
+
+// DeleteSnippet 删除命令片段，仅限创建者和 editors 中的用户
+func (h *Handlers) DeleteSnippet(c *gin.Context) {
+	snippet, ok := h.loadSnippet(c)
+	if !ok || h.rejectSnippetEdit(c, snippet) {
+		return
+	}
+
+	if err := h.storage.DeleteSnippet(c.Request.Context(), snippet.ID); err != nil {
+		c.JSON(snippetErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Snippet deleted successfully",
+	})
+}
+
+// RunSnippet 在主机或分组上执行命令片段。未设置操作系统系列的主机先检测并保存其系列，
+// 每台主机执行适用于其系列的命令，参数值加引号后替换占位符。runners 非空时仅限其中的用户、
+// 创建者和 editors 执行。Accept 为 text/event-stream 或 stream=true 时逐台推送 result 事件，
+// 最后推送 report 事件
+func (h *Handlers) RunSnippet(c *gin.Context) {
+	snippet, ok := h.loadSnippet(c)
+	if !ok {
+		return
+	}
+	actor := changeActor(c)
+	if !snippet.CanRun(actor) {
+		c.JSON(http.StatusForbidden, Response{
+			Success: false,
+			Error:   "Not allowed to run snippet " + strconv.Quote(snippet.Name),
+		})
+		return
+	}
+
+	var req RunSnippetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+	if (req.HostID == 0) == (req.GroupID == 0) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Exactly one of host_id and group_id is required",
+		})
+		return
+	}
+	if req.Timeout < 0 || req.Concurrency < 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "timeout and concurrency must not be negative",
+		})
+		return
+	}
+	params, err := snippet.ResolveParams(req.Params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var hosts []models.Host
+	if req.GroupID != 0 {
+		if hosts, ok = h.groupHosts(c, req.GroupID); !ok {
+			return
+		}
+	} else {
+		host, err := h.storage.GetHost(c.Request.Context(), req.HostID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Error:   "Host not found",
+			})
+			return
+		}
+		hosts = []models.Host{*host}
+	}
+
+	timeout := time.Duration(req.Timeout) * time.Millisecond
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	sudo := snippet.Sudo
+	if req.Sudo != nil {
+		sudo = *req.Sudo
+	}
+
+	// Slow hosts outlive the server write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	stream := c.Query("stream") == "true" || strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	ctx, challenges := h.challengeStream(c, stream)
+	startTime := time.Now()
+	report := SnippetRunReport{
+		SnippetID: snippet.ID,
+		Name:      snippet.Name,
+		Hosts:     len(hosts),
+		Results:   make([]SnippetRunResult, 0, len(hosts)),
+	}
+	results := forEachHost(ctx, hosts, fanOut(req.Concurrency, len(hosts)),
+		func(host *models.Host) SnippetRunResult {
+			return h.runSnippetOnHost(ctx, snippet, host, params, timeout, sudo, req.SudoPassword)
+		},
+		func(host *models.Host, err error) SnippetRunResult {
+			return SnippetRunResult{HostExecResult: HostExecResult{HostID: host.ID, HostName: host.Name, ExitCode: -1, Error: err.Error()}}
+		})
+	streamResults(c, results, challenges, func(result SnippetRunResult) {
+		report.add(result)
+		if stream {
+			c.SSEvent("result", result)
+			c.Writer.Flush()
+		}
+	})
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].HostID < report.Results[j].HostID
+	})
+	report.Duration = time.Since(startTime).Milliseconds()
+
+	h.logger.Info("Snippet executed",
+		"snippet_id", snippet.ID,
+		"snippet", snippet.Name,
+		"actor", actor,
+		"hosts", report.Hosts,
+		"succeeded", report.Succeeded,
+		"failed", report.Failed,
+		"errors", report.Errors,
+		"duration_ms", report.Duration)
+
+	if stream {
+		c.SSEvent("report", report)
+		return
+	}
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+	})
+}
+
+// rejectSnippetEdit 请求方不能修改片段时返回 403
+func (h *Handlers) rejectSnippetEdit(c *gin.Context, snippet *models.Snippet) bool {
+	if snippet.CanEdit(changeActor(c)) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, Response{
+		Success: false,
+		Error:   "Only the creator and editors may change snippet " + strconv.Quote(snippet.Name),
+	})
+	return true
+}
+
+// runSnippetOnHost renders the snippet for the host's OS family, detecting
+// and saving the family first when the host has none, and runs it
+func (h *Handlers) runSnippetOnHost(ctx context.Context, snippet *models.Snippet, host *models.Host, params map[string]string, timeout time.Duration, sudo bool, sudoPassword string) SnippetRunResult {
+	family := host.OSFamily
+	if family == "" {
+		detected := h.runHostCommand(ctx, host, models.OSFamilyProbe, timeout, false, "")
+		if detected.Error != "" {
+			return SnippetRunResult{HostExecResult: detected}
+		}
+		family = models.DetectOSFamily(detected.Stdout + "\n" + detected.Stderr)
+		if family != "" {
+			if err := h.storage.SetHostOSFamily(context.WithoutCancel(ctx), host.ID, family); err != nil {
+				h.logger.Warn("Failed to save host os family", "host_id", host.ID, "os_family", family, "error", err)
+			}
+		}
+	}
+
+	command, err := snippet.Render(family, params)
+	if err != nil {
+		return SnippetRunResult{
+			HostExecResult: HostExecResult{HostID: host.ID, HostName: host.Name, ExitCode: -1, Error: err.Error()},
+			OSFamily:       family,
+		}
+	}
+	return SnippetRunResult{
+		HostExecResult: h.runHostCommand(ctx, host, command, timeout, sudo, sudoPassword),
+		OSFamily:       family,
+		Command:        command,
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/server/statuspage"
+)
+
+// SetStatusPage sets the monitor behind the public status page
+func (h *Handlers) SetStatusPage(monitor *statuspage.Monitor) {
+	h.statusPage = monitor
+}
+
+// loadStatusPage 生成状态页数据，未启用或失败时已写入错误响应
+func (h *Handlers) loadStatusPage(c *gin.Context) (*statuspage.Page, bool) {
+	if h.statusPage == nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Status page is disabled",
+		})
+		return nil, false
+	}
+
+	page, err := h.statusPage.Page(c.Request.Context())
+	if err != nil {
+		h.logger.Warn("Failed to build status page", "error", err)
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   "Status unavailable",
+		})
+		return nil, false
+	}
+	c.Header("Cache-Control", "no-cache")
+	return page, true
+}
+
+// StatusPage 公开状态页（无需认证），显示已发布端口的状态、延迟和可用率
+func (h *Handlers) StatusPage(c *gin.Context) {
+	page, ok := h.loadStatusPage(c)
+	if !ok {
+		return
+	}
+
+	var body bytes.Buffer
+	if err := page.WriteHTML(&body); err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
+
+// StatusPageJSON 状态页数据的 JSON 形式，供其他系统嵌入或监控
+func (h *Handlers) StatusPageJSON(c *gin.Context) {
+	page, ok := h.loadStatusPage(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    page,
+	})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aqz236/port-fly/core/models"
+	sshpkg "github.com/aqz236/port-fly/core/ssh"
+)
+
+// sudoAuth supplies the password sudo asks for when running a command on
+// a host, and scrubs the passwords it knows of from the command's output
+type sudoAuth struct {
+	h       *Handlers
+	host    *models.Host
+	given   string   // one-off password sent with the request
+	secrets []string // passwords that may have been typed into sudo
+}
+
+// newSudoAuth prepares the sudo password of a host; given, when set,
+// overrides the stored one for this command only
+func (h *Handlers) newSudoAuth(host *models.Host, given string) *sudoAuth {
+	return &sudoAuth{h: h, host: host, given: given}
+}
+
+// get returns the first of the request's password, the host's sudo password
+// and its login password that is set, or asks the requesting client like an
+// authentication challenge. sudo only calls it when it prompts.
+func (s *sudoAuth) get(ctx context.Context) (string, error) {
+	password, err := s.lookup(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.secrets = append(s.secrets, password)
+	return password, nil
+}
+
+func (s *sudoAuth) lookup(ctx context.Context) (string, error) {
+	if s.given != "" {
+		return s.given, nil
+	}
+	if s.host.SudoPassword != "" {
+		password, err := s.h.secrets.Resolve(ctx, s.host.SudoPassword)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve host sudo password: %w", err)
+		}
+		return password, nil
+	}
+	if s.host.Password != "" {
+		password, err := s.h.secrets.Resolve(ctx, s.host.Password)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve host password: %w", err)
+		}
+		return password, nil
+	}
+
+	scope, _ := ctx.Value(challengeScopeKey{}).(challengeScope)
+	s.h.logger.Info("Asking for sudo password", "host_id", s.host.ID, "actor", scope.actor)
+	answers, err := s.h.challenges.ask(ctx, AuthChallenge{
+		HostID:   s.host.ID,
+		HostName: s.host.Name,
+		Actor:    scope.actor,
+		Challenge: sshpkg.Challenge{
+			Host:      s.host.Hostname,
+			User:      s.host.Username,
+			Name:      "sudo",
+			Questions: []string{fmt.Sprintf("[sudo] password for %s: ", s.host.Username)},
+			Echos:     []bool{false},
+		},
+	}, scope.sink)
+	if err != nil {
+		return "", err
+	}
+	if len(answers) != 1 {
+		return "", fmt.Errorf("expected 1 answer, got %d", len(answers))
+	}
+	return answers[0], nil
+}
+
+// scrub hides the passwords handed to sudo in text, in case the command
+// echoed them back
+func (s *sudoAuth) scrub(text string) string {
+	for _, secret := range s.secrets {
+		text = sshpkg.ScrubSecret(text, secret)
+	}
+	if s.given != "" {
+		text = sshpkg.ScrubSecret(text, s.given)
+	}
+	return text
+}
+
+// syncWriter lets the stdout and stderr of a command share one writer
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
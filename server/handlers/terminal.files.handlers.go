@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/sftp"
+)
+
+// 终端文件传输的限制
+const (
+	maxTerminalUpload  = 512 << 20 // 单次上传的大小上限
+	terminalUploadMode = 0o644
+)
+
+// TerminalFile 经终端传输的文件，也作为 terminal_file 消息推送到终端的 WebSocket
+type TerminalFile struct {
+	SessionID string `json:"sessionId"`
+	Direction string `json:"direction"` // upload 或 download
+	Path      string `json:"path"`      // 主机上的绝对路径
+	Size      int64  `json:"size"`
+	SHA256    string `json:"sha256,omitempty"` // 上传时读回校验的摘要
+}
+
+// terminalSFTP opens an SFTP client on the SSH connection of an open
+// terminal, so transfers need no second login to the host
+func (h *Handlers) terminalSFTP(c *gin.Context, terminalManager *TerminalManager) (*TerminalSession, *sftp.Client, bool) {
+	session, exists := terminalManager.GetSession(c.Param("sessionId"))
+	if !exists {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Terminal session not found",
+		})
+		return nil, nil, false
+	}
+	if session.SSHClient == nil || !session.SSHClient.IsConnected() {
+		c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Error:   "Terminal is not connected",
+		})
+		return nil, nil, false
+	}
+
+	client, err := session.SSHClient.SFTP()
+	if err != nil {
+		c.JSON(http.StatusBadGateway, Response{
+			Success: false,
+			Error:   fmt.Sprintf("Failed to start SFTP: %v", err),
+		})
+		return nil, nil, false
+	}
+	return session, client, true
+}
+
+// resolveTerminalPath makes a remote path absolute; relative paths and ~ are
+// taken from the home directory, where the SFTP session starts
+func resolveTerminalPath(ctx context.Context, client *sftp.Client, name string) (string, error) {
+	if name == "~" || name == "" {
+		return client.Realpath(ctx, ".")
+	}
+	name = strings.TrimPrefix(name, "~/")
+	if path.IsAbs(name) {
+		return path.Clean(name), nil
+	}
+	home, err := client.Realpath(ctx, ".")
+	if err != nil {
+		return "", err
+	}
+	return path.Join(home, name), nil
+}
+
+// uploadFileName returns the base name of an uploaded file, rejecting names
+// that would leave the target directory
+func uploadFileName(name string) (string, error) {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "", errors.New("file name is required")
+	}
+	return name, nil
+}
+
+// sftpErrorStatus maps an SFTP failure to an HTTP status
+func sftpErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, os.ErrPermission):
+		return http.StatusForbidden
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// UploadTerminalFile 通过已打开终端的 SSH 连接（SFTP）上传文件，供网页终端拖放文件使用。
+// 请求体为 multipart/form-data（字段 file，可有多个），或原始文件内容配合查询参数 name。
+// 查询参数 path 为目标目录或文件，省略时为主目录，相对路径和 ~ 相对于主目录；
+// 目标已存在时需 overwrite=true。每个文件写入临时文件并读回校验后再替换，完成后向终端推送 terminal_file 消息
+func (h *Handlers) UploadTerminalFile(terminalManager *TerminalManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, client, ok := h.terminalSFTP(c, terminalManager)
+		if !ok {
+			return
+		}
+		defer client.Close()
+
+		// Uploads outlive the server read and write timeouts
+		controller := http.NewResponseController(c.Writer)
+		controller.SetReadDeadline(time.Time{})
+		controller.SetWriteDeadline(time.Time{})
+
+		ctx := c.Request.Context()
+		overwrite := c.Query("overwrite") == "true"
+		dest, err := resolveTerminalPath(ctx, client, c.Query("path"))
+		if err != nil {
+			c.JSON(sftpErrorStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		// A target that is a directory, or written as one, receives the files
+		// under their own names
+		intoDir := strings.HasSuffix(c.Query("path"), "/") || c.Query("path") == ""
+		if attrs, err := client.Stat(ctx, dest); err == nil && attrs.IsDir() {
+			intoDir = true
+		}
+		target := func(name string) (string, error) {
+			if !intoDir {
+				return dest, nil
+			}
+			base, err := uploadFileName(name)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(dest, base), nil
+		}
+
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, maxTerminalUpload)
+		var files []TerminalFile
+		put := func(name string, r io.Reader) (int, error) {
+			remote, err := target(name)
+			if err != nil {
+				return http.StatusBadRequest, err
+			}
+			if !overwrite {
+				if _, err := client.Stat(ctx, remote); err == nil {
+					return http.StatusConflict, fmt.Errorf("%s already exists", remote)
+				}
+			}
+			written, sum, err := client.PutFile(ctx, remote, r, terminalUploadMode)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					return http.StatusRequestEntityTooLarge, err
+				}
+				return sftpErrorStatus(err), fmt.Errorf("%s: %w", remote, err)
+			}
+
+			file := TerminalFile{
+				SessionID: session.ID,
+				Direction: "upload",
+				Path:      remote,
+				Size:      written,
+				SHA256:    hex.EncodeToString(sum),
+			}
+			files = append(files, file)
+			terminalManager.sendMessage(session.WebSocket, "terminal_file", file)
+			return http.StatusOK, nil
+		}
+
+		var status int
+		if mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type")); mediaType == "multipart/form-data" {
+			c.Request.Body = body
+			status, err = h.putMultipart(c, put)
+		} else {
+			status, err = put(c.Query("name"), body)
+		}
+		if err == nil && len(files) == 0 {
+			status, err = http.StatusBadRequest, errors.New("no file in the request")
+		}
+		if err != nil {
+			h.logger.Warn("Terminal file upload failed", "session_id", session.ID, "uploaded", len(files), "error", err)
+			c.JSON(status, Response{
+				Success: false,
+				Data:    files,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data:    files,
+			Message: fmt.Sprintf("Uploaded %d file(s)", len(files)),
+		})
+	}
+}
+
+// putMultipart streams each file part of a multipart upload to put, without
+// buffering the files on the server
+func (h *Handlers) putMultipart(c *gin.Context, put func(name string, r io.Reader) (int, error)) (int, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return http.StatusOK, nil
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return http.StatusRequestEntityTooLarge, err
+			}
+			return http.StatusBadRequest, err
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		status, err := put(part.FileName(), part)
+		part.Close()
+		if err != nil {
+			return status, err
+		}
+	}
+}
+
+// DownloadTerminalFile 通过已打开终端的 SSH 连接（SFTP）下载文件。查询参数 path 为文件路径，
+// 相对路径和 ~ 相对于主目录；响应为文件内容（Content-Disposition: attachment），完成后向终端推送 terminal_file 消息
+func (h *Handlers) DownloadTerminalFile(terminalManager *TerminalManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("path") == "" {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "path is required",
+			})
+			return
+		}
+
+		session, client, ok := h.terminalSFTP(c, terminalManager)
+		if !ok {
+			return
+		}
+		defer client.Close()
+
+		ctx := c.Request.Context()
+		remote, err := resolveTerminalPath(ctx, client, c.Query("path"))
+		if err != nil {
+			c.JSON(sftpErrorStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		attrs, err := client.Stat(ctx, remote)
+		if err != nil {
+			c.JSON(sftpErrorStatus(err), Response{
+				Success: false,
+				Error:   fmt.Sprintf("%s: %v", remote, err),
+			})
+			return
+		}
+		if attrs.IsDir() {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   fmt.Sprintf("%s is a directory", remote),
+			})
+			return
+		}
+
+		// Large files outlive the server write timeout
+		http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+		c.Header("Content-Type", "application/octet-stream")
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(remote)}))
+		if attrs.HasSize() {
+			// A declared length lets the client tell a failed copy from a
+			// complete one; a file growing meanwhile is cut at this size
+			c.Header("Content-Length", strconv.FormatUint(attrs.Size, 10))
+		}
+		c.Status(http.StatusOK)
+
+		written, err := client.GetFile(ctx, remote, c.Writer)
+		if errors.Is(err, http.ErrContentLength) {
+			err = nil
+		}
+		if err != nil {
+			// The status is sent; the client sees a truncated body
+			h.logger.Warn("Terminal file download failed", "session_id", session.ID, "path", remote, "written", written, "error", err)
+			return
+		}
+
+		terminalManager.sendMessage(session.WebSocket, "terminal_file", TerminalFile{
+			SessionID: session.ID,
+			Direction: "download",
+			Path:      remote,
+			Size:      written,
+		})
+	}
+}
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -18,6 +22,10 @@ import (
 	sshpkg "github.com/aqz236/port-fly/core/ssh"
 )
 
+// ErrTerminalNotRestorable is returned when a resume token is unknown, expired
+// or belongs to another host
+var ErrTerminalNotRestorable = errors.New("terminal session cannot be restored")
+
 // TerminalMessage represents a terminal WebSocket message
 type TerminalMessage struct {
 	Type         string      `json:"type"`
@@ -27,10 +35,17 @@ type TerminalMessage struct {
 
 // TerminalConnectionParams represents terminal connection parameters
 type TerminalConnectionParams struct {
-	HostID int    `json:"hostId"`
-	Width  int    `json:"width"`
-	Height int    `json:"height"`
-	Shell  string `json:"shell"`
+	HostID int               `json:"hostId"`
+	Width  int               `json:"width"`
+	Height int               `json:"height"`
+	Shell  string            `json:"shell"`
+	Env    map[string]string `json:"env,omitempty"`
+
+	// ResumeToken 服务器重启前 terminal_connected 返回的恢复令牌，用于重新打开中断的终端
+	ResumeToken string `json:"resumeToken,omitempty"`
+
+	// Resilient 弹性模式：连接中断后服务器保留 shell，客户端凭 resumeKey 重新连接并补收错过的输出
+	Resilient bool `json:"resilient,omitempty"`
 }
 
 // TerminalResizeData represents terminal resize data
@@ -51,6 +66,13 @@ type TerminalSession struct {
 	Context    context.Context
 	Cancel     context.CancelFunc
 	CreatedAt  time.Time
+
+	releaseHost func()                 // 释放主机连接槽位
+	actor       string                 // 打开终端的用户，记录在策略关闭的审计记录中
+	lastInput   atomic.Int64           // 最近一次输入的时间（UnixNano），用于空闲超时
+	record      *models.TerminalRecord // 持久化的终端信息，服务器重启后用于恢复
+	resilient   *resilientTerminal     // 弹性模式的输出缓冲和输入序号，普通终端为 nil
+	closeOnce   sync.Once
 }
 
 // TerminalManager manages terminal sessions
@@ -58,6 +80,7 @@ type TerminalManager struct {
 	sessions map[string]*TerminalSession
 	mutex    sync.RWMutex
 	handlers *Handlers
+	closing  bool // 服务器正在停止，保留终端记录以便重启后恢复
 }
 
 // NewTerminalManager creates a new terminal manager
@@ -89,6 +112,8 @@ func (h *Handlers) TerminalWebSocketHandler(terminalManager *TerminalManager) gi
 			return
 		}
 
+		actor := changeActor(c)
+
 		// 升级到WebSocket连接
 		conn, err := terminalUpgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
@@ -98,21 +123,22 @@ func (h *Handlers) TerminalWebSocketHandler(terminalManager *TerminalManager) gi
 		defer conn.Close()
 
 		// 处理终端连接
-		terminalManager.HandleTerminalConnection(hostID, conn)
+		terminalManager.HandleTerminalConnection(hostID, actor, conn)
 	}
 }
 
 // HandleTerminalConnection handles a terminal WebSocket connection
-func (tm *TerminalManager) HandleTerminalConnection(hostID int, ws *websocket.Conn) {
+func (tm *TerminalManager) HandleTerminalConnection(hostID int, actor string, ws *websocket.Conn) {
 	sessionID := fmt.Sprintf("terminal_%d_%d", hostID, time.Now().UnixNano())
 
+	// 终端的生命周期可能长于这次连接（弹性终端），由 closeTerminal 取消
 	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
 	session := &TerminalSession{
 		ID:        sessionID,
 		HostID:    hostID,
 		WebSocket: ws,
+		actor:     actor,
 		Context:   ctx,
 		Cancel:    cancel,
 		CreatedAt: time.Now(),
@@ -122,16 +148,11 @@ func (tm *TerminalManager) HandleTerminalConnection(hostID int, ws *websocket.Co
 	tm.sessions[sessionID] = session
 	tm.mutex.Unlock()
 
+	// 弹性终端在连接中断后保留 shell，等待客户端恢复；session 可能已换成恢复的终端
+	disconnect := false
 	defer func() {
-		tm.mutex.Lock()
-		delete(tm.sessions, sessionID)
-		tm.mutex.Unlock()
-
-		if session.SSHSession != nil {
-			session.SSHSession.Close()
-		}
-		if session.SSHClient != nil {
-			session.SSHClient.Disconnect()
+		if !tm.detach(session, ws, disconnect) {
+			tm.closeTerminal(session)
 		}
 	}()
 
@@ -154,7 +175,20 @@ func (tm *TerminalManager) HandleTerminalConnection(hostID int, ws *websocket.Co
 				return
 			}
 
+		case "terminal_resume":
+			resumed, err := tm.handleTerminalResume(session, msg.Data)
+			if err != nil {
+				tm.sendError(ws, fmt.Sprintf("恢复失败: %v", err))
+				return
+			}
+			session = resumed
+
+		case "terminal_input":
+			session.touch()
+			tm.handleTerminalInput(session, msg.Data)
+
 		case "terminal_data":
+			session.touch()
 			if session.Stdin != nil {
 				data, ok := msg.Data.(string)
 				if ok {
@@ -168,11 +202,13 @@ func (tm *TerminalManager) HandleTerminalConnection(hostID int, ws *websocket.Co
 				if dataBytes, err := json.Marshal(msg.Data); err == nil {
 					if err := json.Unmarshal(dataBytes, &resizeData); err == nil {
 						session.SSHSession.WindowChange(resizeData.Rows, resizeData.Cols)
+						tm.resizeRecord(session, resizeData)
 					}
 				}
 			}
 
 		case "terminal_disconnect":
+			disconnect = true
 			return
 		}
 	}
@@ -180,6 +216,48 @@ func (tm *TerminalManager) HandleTerminalConnection(hostID int, ws *websocket.Co
 
 // handleTerminalConnect establishes SSH connection and starts terminal session
 func (tm *TerminalManager) handleTerminalConnect(session *TerminalSession, data interface{}) error {
+	// 解析连接参数
+	var params TerminalConnectionParams
+	if dataBytes, err := json.Marshal(data); err == nil {
+		json.Unmarshal(dataBytes, &params)
+	}
+
+	// 恢复服务器重启前中断的终端，沿用其尺寸和环境变量
+	var record *models.TerminalRecord
+	if params.ResumeToken != "" {
+		var err error
+		if record, err = tm.restorableRecord(session, params.ResumeToken); err != nil {
+			return err
+		}
+		if params.Width == 0 {
+			params.Width = record.Cols
+		}
+		if params.Height == 0 {
+			params.Height = record.Rows
+		}
+		if params.Shell == "" {
+			params.Shell = record.Shell
+		}
+		env := make(map[string]string, len(record.Env)+len(params.Env))
+		for k, v := range record.Env {
+			env[k] = v
+		}
+		for k, v := range params.Env {
+			env[k] = v
+		}
+		params.Env = env
+	}
+
+	if params.Width == 0 {
+		params.Width = 80
+	}
+	if params.Height == 0 {
+		params.Height = 24
+	}
+	if params.Shell == "" {
+		params.Shell = "bash"
+	}
+
 	// 获取主机信息
 	host, err := tm.handlers.storage.GetHost(context.Background(), uint(session.HostID))
 	if err != nil {
@@ -187,15 +265,20 @@ func (tm *TerminalManager) handleTerminalConnect(session *TerminalSession, data
 	}
 
 	// 创建SSH配置
-	sshConfig := models.SSHConnectionConfig{
-		Host:            host.Hostname,
-		Port:            host.Port,
-		Username:        host.Username,
-		AuthMethod:      models.AuthMethod(host.AuthMethod),
-		Password:        host.Password,
-		PrivateKeyData:  []byte(host.PrivateKey),
-		ConnectTimeout:  30 * time.Second,
-		HostKeyCallback: "accept", // 终端连接接受所有主机密钥
+	sshConfig, err := tm.handlers.hostSSHConfig(session.Context, host)
+	if err != nil {
+		return err
+	}
+	sshConfig.ConnectTimeout = 30 * time.Second
+	sshConfig.HostKeyCallback = "accept" // 终端连接接受所有主机密钥
+
+	// 获取主机连接槽位，终端关闭时释放
+	if session.releaseHost == nil {
+		release, err := tm.handlers.acquireHost(session.Context, host)
+		if err != nil {
+			return err
+		}
+		session.releaseHost = release
 	}
 
 	// 创建SSH客户端
@@ -203,9 +286,12 @@ func (tm *TerminalManager) handleTerminalConnect(session *TerminalSession, data
 		sshConfig,
 		tm.handlers.logger.With("host_id", session.HostID),
 	)
+	// 主机要求一次性密码等键盘交互认证时，通过终端的 WebSocket 询问用户
+	sshClient.SetChallengeHandler(tm.terminalChallenges(session, host))
 
 	// 连接SSH
 	err = sshClient.Connect(session.Context)
+	tm.handlers.recordAuth(withChallengeScope(session.Context, session.actor, nil), host.ID, models.AuthSourceTerminal, err)
 	if err != nil {
 		return fmt.Errorf("SSH connection failed: %w", err)
 	}
@@ -232,20 +318,11 @@ func (tm *TerminalManager) handleTerminalConnect(session *TerminalSession, data
 		ssh.TTY_OP_OSPEED: 14400,
 	}
 
-	// 解析连接参数
-	var params TerminalConnectionParams
-	if dataBytes, err := json.Marshal(data); err == nil {
-		json.Unmarshal(dataBytes, &params)
-	}
-
-	if params.Width == 0 {
-		params.Width = 80
-	}
-	if params.Height == 0 {
-		params.Height = 24
-	}
-	if params.Shell == "" {
-		params.Shell = "bash"
+	// 设置环境变量，服务器的 AcceptEnv 未允许的变量会被拒绝，不影响终端
+	for name, value := range params.Env {
+		if err := sshSession.Setenv(name, value); err != nil {
+			tm.handlers.logger.Debug("Terminal environment variable rejected", "host_id", session.HostID, "name", name, "error", err)
+		}
 	}
 
 	// 请求伪终端
@@ -277,15 +354,51 @@ func (tm *TerminalManager) handleTerminalConnect(session *TerminalSession, data
 		return fmt.Errorf("failed to start shell: %w", err)
 	}
 
+	// 保存终端信息，服务器重启后凭恢复令牌重新打开
+	connected := map[string]interface{}{
+		"sessionId": session.ID,
+		"hostId":    session.HostID,
+	}
+	if params.Resilient {
+		resilient, err := newResilientTerminal()
+		if err != nil {
+			return fmt.Errorf("failed to generate terminal resume key: %w", err)
+		}
+		tm.mutex.Lock()
+		session.resilient = resilient
+		tm.mutex.Unlock()
+		connected["resilient"] = true
+		connected["resumeKey"] = resilient.key
+
+		// shell 退出或终端被关闭时，无论客户端是否在线都结束终端
+		go tm.waitShell(session)
+		context.AfterFunc(session.Context, func() { tm.closeTerminal(session) })
+		tm.keepAlive(session, session.WebSocket)
+	}
+	var interruptedAt time.Time
+	if record != nil {
+		interruptedAt = *record.InterruptedAt
+		connected["restored"] = true
+		connected["interruptedAt"] = interruptedAt
+	}
+	if token := tm.saveRecord(session, record, params); token != "" {
+		connected["resumeToken"] = token
+	}
+
+	// 发送连接成功消息，恢复的终端先显示提示
+	tm.sendMessage(session.WebSocket, "terminal_connected", connected)
+	if record != nil {
+		tm.sendMessage(session.WebSocket, "terminal_data",
+			fmt.Sprintf("\x1b[33m[PortFly] 会话已恢复，原终端于 %s 因服务器重启中断\x1b[0m\r\n",
+				interruptedAt.Local().Format("2006-01-02 15:04:05")))
+	}
+
 	// 启动输出读取goroutine
 	go tm.handleTerminalOutput(session, stdout, "stdout")
 	go tm.handleTerminalOutput(session, stderr, "stderr")
 
-	// 发送连接成功消息
-	tm.sendMessage(session.WebSocket, "terminal_connected", map[string]interface{}{
-		"sessionId": session.ID,
-		"hostId":    session.HostID,
-	})
+	// 按主机、分组和全局配置的空闲超时和最长时长关闭终端
+	tm.enforcePolicy(session, tm.handlers.resolveTerminalPolicy(context.Background(), host))
 
 	// 更新主机状态为已连接
 	host.Status = "connected"
@@ -312,6 +425,12 @@ func (tm *TerminalManager) handleTerminalOutput(session *TerminalSession, reader
 				return
 			}
 
+			if n > 0 && session.resilient != nil {
+				// 弹性终端先写入缓冲，客户端断开时也继续读取，避免 shell 阻塞
+				tm.sendOutput(session, buffer[:n])
+				continue
+			}
+
 			if n > 0 {
 				// 使用会话的WebSocket锁
 				session.WSMutex.Lock()
@@ -335,6 +454,11 @@ func (tm *TerminalManager) handleTerminalOutput(session *TerminalSession, reader
 
 // sendMessage sends a message to the WebSocket (with concurrency protection)
 func (tm *TerminalManager) sendMessage(ws *websocket.Conn, msgType string, data interface{}) {
+	// 断开等待恢复的弹性终端没有 WebSocket
+	if ws == nil {
+		return
+	}
+
 	// 获取会话以使用其锁
 	var session *TerminalSession
 	tm.mutex.RLock()
@@ -367,6 +491,11 @@ func (tm *TerminalManager) sendMessage(ws *websocket.Conn, msgType string, data
 
 // sendError sends an error message to the WebSocket (with concurrency protection)
 func (tm *TerminalManager) sendError(ws *websocket.Conn, message string) {
+	// 断开等待恢复的弹性终端没有 WebSocket
+	if ws == nil {
+		return
+	}
+
 	// 获取会话以使用其锁
 	var session *TerminalSession
 	tm.mutex.RLock()
@@ -468,3 +597,208 @@ func (tm *TerminalManager) GetHostSessions(hostID int) []*TerminalSession {
 	}
 	return sessions
 }
+
+// restorableRecord returns the record of an interrupted terminal on the
+// session's host
+func (tm *TerminalManager) restorableRecord(session *TerminalSession, token string) (*models.TerminalRecord, error) {
+	record, err := tm.handlers.storage.GetTerminalRecordByToken(session.Context, token)
+	if err != nil || !record.Restorable(time.Now()) || record.HostID != uint(session.HostID) {
+		return nil, ErrTerminalNotRestorable
+	}
+	return record, nil
+}
+
+// saveRecord persists the terminal's host, size and environment, reusing the
+// record of a restored terminal, and returns its resume token. Failures are
+// logged: the terminal works, it just cannot be restored.
+func (tm *TerminalManager) saveRecord(session *TerminalSession, record *models.TerminalRecord, params TerminalConnectionParams) string {
+	now := time.Now()
+	if record == nil {
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			tm.handlers.logger.Warn("Failed to generate terminal resume token", "error", err)
+			return ""
+		}
+		record = &models.TerminalRecord{
+			Token:  base64.RawURLEncoding.EncodeToString(secret),
+			HostID: uint(session.HostID),
+		}
+	} else {
+		record.InterruptedAt = nil
+		record.RestoredAt = &now
+		record.Restores++
+	}
+	record.Cols = params.Width
+	record.Rows = params.Height
+	record.Shell = params.Shell
+	record.Env = params.Env
+
+	var err error
+	if record.ID == 0 {
+		err = tm.handlers.storage.CreateTerminalRecord(context.Background(), record)
+	} else {
+		err = tm.handlers.storage.UpdateTerminalRecord(context.Background(), record)
+	}
+	if err != nil {
+		tm.handlers.logger.Warn("Failed to save terminal record", "session_id", session.ID, "error", err)
+		return ""
+	}
+
+	tm.mutex.Lock()
+	session.record = record
+	tm.mutex.Unlock()
+	return record.Token
+}
+
+// resizeRecord keeps the persisted terminal size current
+func (tm *TerminalManager) resizeRecord(session *TerminalSession, size TerminalResizeData) {
+	tm.mutex.RLock()
+	record := session.record
+	tm.mutex.RUnlock()
+	if record == nil || size.Cols <= 0 || size.Rows <= 0 {
+		return
+	}
+
+	record.Cols = size.Cols
+	record.Rows = size.Rows
+	if err := tm.handlers.storage.UpdateTerminalRecord(context.Background(), record); err != nil {
+		tm.handlers.logger.Warn("Failed to save terminal size", "session_id", session.ID, "error", err)
+	}
+}
+
+// forgetRecord deletes the record of a terminal closed while the server keeps
+// running; terminals cut off by a shutdown stay restorable
+func (tm *TerminalManager) forgetRecord(session *TerminalSession) {
+	tm.mutex.RLock()
+	record, closing := session.record, tm.closing
+	tm.mutex.RUnlock()
+	if record == nil || closing {
+		return
+	}
+
+	if err := tm.handlers.storage.DeleteTerminalRecord(context.Background(), record.ID); err != nil {
+		tm.handlers.logger.Warn("Failed to delete terminal record", "session_id", session.ID, "error", err)
+	}
+}
+
+// Recover marks the terminals still open when the server last stopped, e.g.
+// after a crash, as restorable and drops those past the restore window
+func (tm *TerminalManager) Recover(ctx context.Context) {
+	now := time.Now()
+	count, err := tm.handlers.storage.InterruptTerminalRecords(ctx, now, now.Add(-models.TerminalRestoreWindow))
+	if err != nil {
+		tm.handlers.logger.Warn("Failed to recover terminal sessions", "error", err)
+		return
+	}
+	if count > 0 {
+		tm.handlers.logger.Info("Terminal sessions interrupted by the last shutdown can be restored", "count", count)
+	}
+}
+
+// Shutdown marks the open terminals as restorable and closes their
+// WebSockets with a service restart status, which tells the frontend to
+// reconnect and restore them
+func (tm *TerminalManager) Shutdown(ctx context.Context) {
+	tm.mutex.Lock()
+	tm.closing = true
+	sessions := make([]*TerminalSession, 0, len(tm.sessions))
+	for _, session := range tm.sessions {
+		sessions = append(sessions, session)
+	}
+	tm.mutex.Unlock()
+
+	now := time.Now()
+	if _, err := tm.handlers.storage.InterruptTerminalRecords(ctx, now, now.Add(-models.TerminalRestoreWindow)); err != nil {
+		tm.handlers.logger.Warn("Failed to save terminal sessions for restore", "error", err)
+	}
+
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting")
+	for _, session := range sessions {
+		session.WSMutex.Lock()
+		// 已断开等待恢复的弹性终端没有 WebSocket
+		if ws := session.WebSocket; ws != nil {
+			ws.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+			ws.Close()
+		}
+		session.WSMutex.Unlock()
+		session.Cancel()
+	}
+}
+
+// RestorableTerminal 可恢复的终端及其主机名称
+type RestorableTerminal struct {
+	models.TerminalRecord
+	HostName string `json:"host_name"`
+}
+
+// RestoreTerminalsRequest 前端保存的恢复令牌，为空时返回所有可恢复的终端
+type RestoreTerminalsRequest struct {
+	Tokens []string `json:"tokens"`
+}
+
+// RestoreTerminalsResponse 可重新打开的终端，以及已过期或未知的令牌
+type RestoreTerminalsResponse struct {
+	Terminals []RestorableTerminal `json:"terminals"`
+	Expired   []string             `json:"expired,omitempty"`
+}
+
+// RestoreTerminals 返回服务器重启前中断的终端，前端据此通过 /ws/terminal/:hostId
+// 发送带 resumeToken 的 terminal_connect 重新打开
+func (h *Handlers) RestoreTerminals(c *gin.Context) {
+	var req RestoreTerminalsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	now := time.Now()
+	var records []models.TerminalRecord
+	var expired []string
+	if len(req.Tokens) == 0 {
+		all, err := h.storage.GetInterruptedTerminalRecords(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		for _, record := range all {
+			if record.Restorable(now) {
+				records = append(records, record)
+			}
+		}
+	} else {
+		for _, token := range req.Tokens {
+			record, err := h.storage.GetTerminalRecordByToken(ctx, token)
+			if err != nil || !record.Restorable(now) {
+				expired = append(expired, token)
+				continue
+			}
+			records = append(records, *record)
+		}
+	}
+
+	terminals := make([]RestorableTerminal, 0, len(records))
+	for _, record := range records {
+		host, err := h.storage.GetHost(ctx, record.HostID)
+		if err != nil {
+			// 主机已删除
+			expired = append(expired, record.Token)
+			continue
+		}
+		terminals = append(terminals, RestorableTerminal{TerminalRecord: record, HostName: host.Name})
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: RestoreTerminalsResponse{
+			Terminals: terminals,
+			Expired:   expired,
+		},
+	})
+}
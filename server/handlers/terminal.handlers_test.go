@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/aqz236/port-fly/core/ssh/sshtest"
+)
+
+// readTerminalMessage reads the next message of the given type, skipping others
+func readTerminalMessage(t *testing.T, ws *websocket.Conn, msgType string) TerminalMessage {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		var msg TerminalMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			t.Fatalf("failed to read %s: %v", msgType, err)
+		}
+		if msg.Type == "terminal_error" {
+			t.Fatalf("terminal error: %v", msg.Data)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func TestTerminalWebSocket(t *testing.T) {
+	server, err := sshtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SSH server: %v", err)
+	}
+	defer server.Close()
+	h := newTestHandlers(t)
+	host := createTestHost(t, h, server)
+	terminals := NewTerminalManager(h)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws/terminal/:hostId", h.TerminalWebSocketHandler(terminals))
+	httpServer := httptest.NewServer(router)
+	defer httpServer.Close()
+
+	url := fmt.Sprintf("ws%s/ws/terminal/%d", strings.TrimPrefix(httpServer.URL, "http"), host.ID)
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to open terminal WebSocket: %v", err)
+	}
+	defer ws.Close()
+
+	connect := TerminalMessage{Type: "terminal_connect", Data: TerminalConnectionParams{HostID: int(host.ID), Width: 120, Height: 40}}
+	if err := ws.WriteJSON(connect); err != nil {
+		t.Fatalf("failed to send terminal_connect: %v", err)
+	}
+	connected := readTerminalMessage(t, ws, "terminal_connected")
+	if data, ok := connected.Data.(map[string]any); !ok || data["sessionId"] == "" {
+		t.Fatalf("terminal_connected data = %v, want a session ID", connected.Data)
+	}
+	if terminals.GetSessionCount() != 1 {
+		t.Fatalf("open terminals = %d, want 1", terminals.GetSessionCount())
+	}
+
+	// The test server's shell echoes its input
+	if err := ws.WriteJSON(TerminalMessage{Type: "terminal_data", Data: "echo hi\n"}); err != nil {
+		t.Fatalf("failed to send input: %v", err)
+	}
+	var output strings.Builder
+	for !strings.Contains(output.String(), "echo hi") {
+		msg := readTerminalMessage(t, ws, "terminal_data")
+		output.WriteString(fmt.Sprint(msg.Data))
+	}
+
+	if err := ws.WriteJSON(TerminalMessage{Type: "terminal_disconnect"}); err != nil {
+		t.Fatalf("failed to send terminal_disconnect: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for terminals.GetSessionCount() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("terminal is still open after terminal_disconnect")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
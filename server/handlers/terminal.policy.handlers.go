@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// defaultTerminalClosureLimit 终端关闭审计记录默认返回的条数
+const defaultTerminalClosureLimit = 100
+
+// SetTerminalPolicy sets the global idle timeout and max duration of web
+// terminals, which hosts and groups override
+func (h *Handlers) SetTerminalPolicy(policy models.TerminalPolicy) {
+	h.terminalPolicy = policy
+}
+
+// resolveTerminalPolicy merges the terminal policies of the host, its group
+// and the group's ancestors over the global one, the nearest setting winning
+func (h *Handlers) resolveTerminalPolicy(ctx context.Context, host *models.Host) models.TerminalPolicy {
+	var policy models.TerminalPolicy
+	policy = policy.Inherit(host.TerminalPolicy)
+
+	group := host.Group
+	if group.ID != host.GroupID {
+		if loaded, err := h.storage.GetGroup(ctx, host.GroupID); err == nil {
+			group = *loaded
+		}
+	}
+	policy = policy.Inherit(group.TerminalPolicy)
+	if group.ParentID != nil {
+		ancestors, err := h.storage.GetGroupAncestors(ctx, group.ID)
+		if err != nil {
+			h.logger.Warn("Failed to load group ancestors for terminal policy", "group_id", group.ID, "error", err)
+		}
+		for _, ancestor := range ancestors {
+			policy = policy.Inherit(ancestor.TerminalPolicy)
+		}
+	}
+
+	return policy.Inherit(&h.terminalPolicy)
+}
+
+// touch records input from the user, which resets the idle timeout
+func (s *TerminalSession) touch() {
+	s.lastInput.Store(time.Now().UnixNano())
+}
+
+// lastInputAt is the time of the user's last input, or when the terminal
+// opened if there was none
+func (s *TerminalSession) lastInputAt() time.Time {
+	if at := s.lastInput.Load(); at != 0 {
+		return time.Unix(0, at)
+	}
+	return s.CreatedAt
+}
+
+// terminalDeadline returns the limit a terminal reaches first and when
+func terminalDeadline(session *TerminalSession, idle, maxDuration time.Duration) (reason string, limit time.Duration, deadline time.Time) {
+	if idle > 0 {
+		reason, limit, deadline = models.TerminalClosedIdle, idle, session.lastInputAt().Add(idle)
+	}
+	if maxDuration > 0 {
+		if end := session.CreatedAt.Add(maxDuration); deadline.IsZero() || end.Before(deadline) {
+			reason, limit, deadline = models.TerminalClosedMaxDuration, maxDuration, end
+		}
+	}
+	return reason, limit, deadline
+}
+
+// enforcePolicy closes the terminal once it has been idle or open longer
+// than the policy allows, warning in the terminal beforehand. Input after an
+// idle warning postpones the closure.
+func (tm *TerminalManager) enforcePolicy(session *TerminalSession, policy models.TerminalPolicy) {
+	idle, maxDuration, warnBefore := policy.Limits()
+	if idle == 0 && maxDuration == 0 {
+		return
+	}
+
+	go func() {
+		var warned time.Time // deadline the last warning was about
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+			case <-session.Context.Done():
+				return
+			}
+
+			now := time.Now()
+			reason, limit, deadline := terminalDeadline(session, idle, maxDuration)
+			if !now.Before(deadline) {
+				tm.closeForPolicy(session, reason, limit)
+				return
+			}
+			// Short limits are warned about halfway through at the earliest
+			lead := min(warnBefore, limit/2)
+			wake := deadline
+			if warnAt := deadline.Add(-lead); lead > 0 && now.Before(warnAt) {
+				wake = warnAt
+			} else if lead > 0 && !warned.Equal(deadline) {
+				warned = deadline
+				tm.warnTerminal(session, reason, limit, deadline.Sub(now))
+			}
+			timer.Reset(wake.Sub(now))
+		}
+	}()
+}
+
+// warnTerminal shows the upcoming closure in the terminal and tells the
+// client when it happens
+func (tm *TerminalManager) warnTerminal(session *TerminalSession, reason string, limit, remaining time.Duration) {
+	remaining = remaining.Round(time.Second)
+	var text string
+	if reason == models.TerminalClosedIdle {
+		text = fmt.Sprintf("\r\n\x1b[33m[PortFly] 终端已空闲 %s，%s 后断开，输入任意内容可继续使用\x1b[0m\r\n", limit-remaining, remaining)
+	} else {
+		text = fmt.Sprintf("\r\n\x1b[33m[PortFly] 终端最长可使用 %s，%s 后断开\x1b[0m\r\n", limit, remaining)
+	}
+	tm.writeNotice(session, text, TerminalMessage{
+		Type: "terminal_expiring",
+		Data: map[string]interface{}{
+			"reason":   reason,
+			"closesAt": time.Now().Add(remaining),
+		},
+	})
+}
+
+// closeForPolicy ends a terminal that reached a limit, telling its client
+// why, and records the closure for audit
+func (tm *TerminalManager) closeForPolicy(session *TerminalSession, reason string, limit time.Duration) {
+	var text string
+	if reason == models.TerminalClosedIdle {
+		text = fmt.Sprintf("\r\n\x1b[31m[PortFly] 终端空闲超过 %s，已断开\x1b[0m\r\n", limit)
+	} else {
+		text = fmt.Sprintf("\r\n\x1b[31m[PortFly] 终端已使用 %s，达到最长时长，已断开\x1b[0m\r\n", limit)
+	}
+	tm.writeNotice(session, text, TerminalMessage{
+		Type: "terminal_closed",
+		Data: map[string]interface{}{"reason": reason},
+	})
+
+	session.WSMutex.Lock()
+	if ws := session.WebSocket; ws != nil {
+		ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+			time.Now().Add(terminalWriteWait))
+		ws.Close()
+	}
+	session.WSMutex.Unlock()
+	tm.closeTerminal(session)
+
+	closure := &models.TerminalClosure{
+		SessionID:   session.ID,
+		HostID:      uint(session.HostID),
+		Actor:       session.actor,
+		Reason:      reason,
+		Limit:       int(limit / time.Second),
+		OpenedAt:    session.CreatedAt,
+		LastInputAt: session.lastInputAt(),
+	}
+	if err := tm.handlers.storage.CreateTerminalClosure(context.Background(), closure); err != nil {
+		tm.handlers.logger.Warn("Failed to record terminal closure", "session_id", session.ID, "error", err)
+	}
+	tm.handlers.logger.Info("Terminal closed by policy",
+		"session_id", session.ID,
+		"host_id", session.HostID,
+		"actor", session.actor,
+		"reason", reason,
+		"limit", limit)
+}
+
+// writeNotice injects text into the terminal, through the replay buffer for
+// resilient terminals, followed by message
+func (tm *TerminalManager) writeNotice(session *TerminalSession, text string, message TerminalMessage) {
+	if session.resilient != nil {
+		tm.sendOutput(session, []byte(text))
+	}
+
+	session.WSMutex.Lock()
+	defer session.WSMutex.Unlock()
+	ws := session.WebSocket
+	if ws == nil {
+		return
+	}
+	ws.SetWriteDeadline(time.Now().Add(terminalWriteWait))
+	if session.resilient == nil {
+		ws.WriteJSON(TerminalMessage{Type: "terminal_data", Data: text})
+	}
+	ws.WriteJSON(message)
+}
+
+// GetTerminalClosures 列出因空闲超时或超过最长时长被强制关闭的终端，最新的在前
+// Query: host_id=仅列出该主机的终端, limit=条数（默认 100）
+func (h *Handlers) GetTerminalClosures(c *gin.Context) {
+	var hostID uint64
+	if raw := c.Query("host_id"); raw != "" {
+		var err error
+		if hostID, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid host_id parameter",
+			})
+			return
+		}
+	}
+	limit := defaultTerminalClosureLimit
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid limit parameter",
+			})
+			return
+		}
+	}
+
+	closures, err := h.storage.GetTerminalClosures(c.Request.Context(), uint(hostID), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    closures,
+	})
+}
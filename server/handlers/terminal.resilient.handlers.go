@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Resilient terminals keep their shell while the browser reconnects, e.g.
+// after a mobile client changes networks
+const (
+	terminalDetachTimeout = 10 * time.Minute // how long a detached shell waits for its client
+	terminalOutputBuffer  = 256 << 10        // output kept for replay after a reconnect
+	terminalPingInterval  = 10 * time.Second
+	terminalPongWait      = 30 * time.Second // a link silent this long is considered lost
+	terminalWriteWait     = 10 * time.Second
+)
+
+// errTerminalNotResumable is returned when a resilient terminal is unknown,
+// expired or the resume key does not match
+var errTerminalNotResumable = errors.New("terminal session cannot be resumed")
+
+// TerminalResumeParams 弹性终端重新连接的参数：terminal_connected 返回的会话 ID 和恢复密钥，
+// 以及已收到的输出位置（最后一条 terminal_output 的 next）
+type TerminalResumeParams struct {
+	SessionID string `json:"sessionId"`
+	ResumeKey string `json:"resumeKey"`
+	Offset    int64  `json:"offset"`
+	Cols      int    `json:"cols,omitempty"`
+	Rows      int    `json:"rows,omitempty"`
+}
+
+// TerminalOutput 弹性终端的输出，offset 和 next 为这段输出在会话输出流中的起止字节位置
+type TerminalOutput struct {
+	Offset int64  `json:"offset"`
+	Next   int64  `json:"next"`
+	Data   string `json:"data"`
+}
+
+// TerminalInput 弹性终端的输入，seq 逐条递增，重新连接后服务器跳过已收到的输入
+type TerminalInput struct {
+	Seq  int64  `json:"seq"`
+	Data string `json:"data"`
+}
+
+// resilientTerminal is the state a resilient terminal keeps across
+// WebSocket connections: recent output for replay and the last input
+// applied, so that neither side loses or repeats data on a reconnect
+type resilientTerminal struct {
+	key string
+	mu  sync.Mutex // also orders output on the wire with the buffer
+
+	output   []byte
+	start    int64 // stream offset of output[0]
+	next     int64 // stream offset of the next output byte
+	inputSeq int64 // last input applied
+
+	expire *time.Timer // closes the terminal while detached
+}
+
+func newResilientTerminal() (*resilientTerminal, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &resilientTerminal{key: base64.RawURLEncoding.EncodeToString(secret)}, nil
+}
+
+// append adds output to the replay buffer, dropping the oldest bytes beyond
+// its size, and returns the offset of data in the stream
+func (r *resilientTerminal) append(data []byte) int64 {
+	offset := r.next
+	r.output = append(r.output, data...)
+	r.next += int64(len(data))
+	if excess := len(r.output) - terminalOutputBuffer; excess > 0 {
+		r.output = append(r.output[:0], r.output[excess:]...)
+		r.start += int64(excess)
+	}
+	return offset
+}
+
+// since returns the buffered output from offset on, or from the oldest byte
+// still buffered when offset was dropped
+func (r *resilientTerminal) since(offset int64) ([]byte, int64) {
+	offset = min(max(offset, r.start), r.next)
+	return r.output[offset-r.start:], offset
+}
+
+// sendOutput buffers terminal output and sends it to the attached client.
+// A client that cannot keep up is disconnected rather than stalling the
+// shell; it catches up from the buffer when it reconnects.
+func (tm *TerminalManager) sendOutput(session *TerminalSession, data []byte) {
+	r := session.resilient
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	offset := r.append(data)
+
+	session.WSMutex.Lock()
+	defer session.WSMutex.Unlock()
+	if ws := session.WebSocket; ws != nil {
+		ws.SetWriteDeadline(time.Now().Add(terminalWriteWait))
+		err := ws.WriteJSON(TerminalMessage{
+			Type: "terminal_output",
+			Data: TerminalOutput{Offset: offset, Next: r.next, Data: string(data)},
+		})
+		if err != nil {
+			tm.handlers.logger.Debug("Resilient terminal client lost", "session_id", session.ID, "error", err)
+			ws.Close()
+		}
+	}
+}
+
+// handleTerminalInput writes a numbered input to the shell once; inputs the
+// client resends after a reconnect are skipped
+func (tm *TerminalManager) handleTerminalInput(session *TerminalSession, data interface{}) {
+	if session.Stdin == nil || session.resilient == nil {
+		return
+	}
+	var input TerminalInput
+	if dataBytes, err := json.Marshal(data); err == nil {
+		json.Unmarshal(dataBytes, &input)
+	}
+
+	r := session.resilient
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if input.Seq <= r.inputSeq {
+		return
+	}
+	r.inputSeq = input.Seq
+	session.Stdin.Write([]byte(input.Data))
+}
+
+// keepAlive pings the client of a resilient terminal so a link that went
+// silent, as after a network change, is noticed within terminalPongWait
+func (tm *TerminalManager) keepAlive(session *TerminalSession, ws *websocket.Conn) {
+	ws.SetReadDeadline(time.Now().Add(terminalPongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(terminalPongWait))
+	})
+
+	go func() {
+		ticker := time.NewTicker(terminalPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				session.WSMutex.Lock()
+				err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(terminalWriteWait))
+				session.WSMutex.Unlock()
+				if err != nil {
+					return
+				}
+			case <-session.Context.Done():
+				return
+			}
+		}
+	}()
+}
+
+// detach keeps a resilient terminal's shell running after its WebSocket ws
+// went away, closing it if no client resumes within terminalDetachTimeout.
+// It reports whether the terminal was kept (or already taken over by another
+// connection); other terminals are for the caller to close.
+func (tm *TerminalManager) detach(session *TerminalSession, ws *websocket.Conn, disconnect bool) bool {
+	if session.resilient == nil || session.SSHSession == nil || disconnect || session.Context.Err() != nil {
+		return false
+	}
+
+	r := session.resilient
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tm.mutex.Lock()
+	session.WSMutex.Lock()
+	current := session.WebSocket
+	if current == ws {
+		session.WebSocket = nil
+	}
+	session.WSMutex.Unlock()
+	tm.mutex.Unlock()
+	if current != ws {
+		// Another connection resumed the terminal
+		return true
+	}
+
+	r.expire = time.AfterFunc(terminalDetachTimeout, func() {
+		tm.handlers.logger.Info("Detached terminal expired", "session_id", session.ID)
+		tm.closeTerminal(session)
+	})
+	tm.handlers.logger.Info("Terminal detached, waiting for the client to resume", "session_id", session.ID, "timeout", terminalDetachTimeout)
+	return true
+}
+
+// handleTerminalResume moves a resilient terminal to the new WebSocket of
+// placeholder, replays the output the client missed and returns the resumed
+// terminal. A client still attached on a stale connection is cut off.
+func (tm *TerminalManager) handleTerminalResume(placeholder *TerminalSession, data interface{}) (*TerminalSession, error) {
+	var params TerminalResumeParams
+	if dataBytes, err := json.Marshal(data); err == nil {
+		json.Unmarshal(dataBytes, &params)
+	}
+	ws := placeholder.WebSocket
+
+	tm.mutex.RLock()
+	session, exists := tm.sessions[params.SessionID]
+	tm.mutex.RUnlock()
+	if !exists || session.resilient == nil || session.HostID != placeholder.HostID ||
+		subtle.ConstantTimeCompare([]byte(session.resilient.key), []byte(params.ResumeKey)) != 1 {
+		return nil, errTerminalNotResumable
+	}
+
+	r := session.resilient
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.expire != nil {
+		if !r.expire.Stop() {
+			// The terminal is being closed
+			return nil, errTerminalNotResumable
+		}
+		r.expire = nil
+	}
+	if session.Context.Err() != nil {
+		return nil, errTerminalNotResumable
+	}
+
+	tm.mutex.Lock()
+	delete(tm.sessions, placeholder.ID)
+	session.WSMutex.Lock()
+	stale := session.WebSocket
+	session.WebSocket = ws
+	session.WSMutex.Unlock()
+	tm.mutex.Unlock()
+	placeholder.Cancel()
+	if stale != nil {
+		stale.Close()
+	}
+
+	if params.Cols > 0 && params.Rows > 0 {
+		size := TerminalResizeData{Cols: params.Cols, Rows: params.Rows}
+		session.SSHSession.WindowChange(size.Rows, size.Cols)
+		tm.resizeRecord(session, size)
+	}
+
+	missed, offset := r.since(params.Offset)
+	session.WSMutex.Lock()
+	ws.SetWriteDeadline(time.Now().Add(terminalWriteWait))
+	ws.WriteJSON(TerminalMessage{
+		Type: "terminal_resumed",
+		Data: map[string]interface{}{
+			"sessionId": session.ID,
+			"hostId":    session.HostID,
+			"inputSeq":  r.inputSeq,
+			"offset":    offset,
+			"next":      r.next,
+			"lost":      offset > params.Offset, // 缺失的输出超出了缓冲区，屏幕可能不完整
+		},
+	})
+	if len(missed) > 0 {
+		ws.WriteJSON(TerminalMessage{
+			Type: "terminal_output",
+			Data: TerminalOutput{Offset: offset, Next: r.next, Data: string(missed)},
+		})
+	}
+	session.WSMutex.Unlock()
+
+	tm.keepAlive(session, ws)
+	tm.handlers.logger.Info("Terminal resumed", "session_id", session.ID, "replayed", len(missed))
+	return session, nil
+}
+
+// closeTerminal ends a terminal: its shell, SSH connection, host slot and
+// client connection. It may be called more than once.
+func (tm *TerminalManager) closeTerminal(session *TerminalSession) {
+	session.closeOnce.Do(func() {
+		tm.mutex.Lock()
+		if tm.sessions[session.ID] == session {
+			delete(tm.sessions, session.ID)
+		}
+		tm.mutex.Unlock()
+		session.Cancel()
+
+		if session.SSHSession != nil {
+			session.SSHSession.Close()
+		}
+		if session.SSHClient != nil {
+			session.SSHClient.Disconnect()
+		}
+		if session.releaseHost != nil {
+			session.releaseHost()
+		}
+		tm.forgetRecord(session)
+
+		if session.resilient == nil {
+			return
+		}
+		r := session.resilient
+		r.mu.Lock()
+		if r.expire != nil {
+			r.expire.Stop()
+		}
+		r.mu.Unlock()
+
+		// Tell the client not to resume
+		session.WSMutex.Lock()
+		if ws := session.WebSocket; ws != nil {
+			ws.SetWriteDeadline(time.Now().Add(terminalWriteWait))
+			ws.WriteJSON(TerminalMessage{Type: "terminal_closed"})
+			ws.Close()
+		}
+		session.WSMutex.Unlock()
+	})
+}
+
+// waitShell closes a resilient terminal once its shell exits, whether or
+// not a client is attached
+func (tm *TerminalManager) waitShell(session *TerminalSession) {
+	session.SSHSession.Wait()
+	tm.closeTerminal(session)
+}
@@ -1,25 +1,98 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/operations"
 	"github.com/gin-gonic/gin"
 )
 
 // ===== Tunnel Control =====
 
+// StartTunnel 重新启动隧道会话所属端口的隧道，与 POST /ports/:id/control 的 start 相同，
+// 立即返回 202 和操作；隧道建立后保存新的会话记录。只有端口隧道的会话可以启动
 func (h *Handlers) StartTunnel(c *gin.Context) {
-	// TODO: Implement tunnel start logic using sessionManager
-	c.JSON(http.StatusNotImplemented, Response{
-		Success: false,
-		Error:   "Tunnel start not implemented yet",
-	})
+	id, ok := h.resolveSessionID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.storage.GetTunnelSession(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Tunnel session not found",
+		})
+		return
+	}
+	if session.PortID == nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Tunnel session does not belong to a port, only port tunnels can be started",
+		})
+		return
+	}
+
+	run, ok := h.prepareStartPort(c, *session.PortID)
+	if !ok {
+		return
+	}
+	h.runPortOperation(c, *session.PortID, PortActionStart, run)
 }
 
+// StopTunnel 停止隧道会话对应的正在运行的端口隧道，并将会话记录标记为已停止
+// 本实例中没有对应的运行中隧道时返回 409，记录不变
 func (h *Handlers) StopTunnel(c *gin.Context) {
-	// TODO: Implement tunnel stop logic using sessionManager
-	c.JSON(http.StatusNotImplemented, Response{
-		Success: false,
-		Error:   "Tunnel stop not implemented yet",
+	id, ok := h.resolveSessionID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.storage.GetTunnelSession(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Tunnel session not found",
+		})
+		return
+	}
+
+	if session.Status == models.StatusStopped || session.Status == models.StatusStopping {
+		c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Error:   "Tunnel session is already stopped",
+		})
+		return
+	}
+
+	portID, sessionID, running := h.portTunnels.record(session.ID)
+	if !running {
+		c.JSON(http.StatusConflict, Response{
+			Success: false,
+			Error:   "No running tunnel on this server belongs to the session",
+		})
+		return
+	}
+
+	ctx := context.WithoutCancel(c.Request.Context())
+	if err := h.stopPortTunnel(ctx, portID, sessionID, func(operations.Phase, string) {}); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if stopped, err := h.storage.GetTunnelSession(ctx, session.ID); err == nil {
+		session = stopped
+	}
+	h.logger.Info("Tunnel session stopped", "session_id", session.ID, "port_id", portID)
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+		Message: "Tunnel session stopped",
 	})
 }
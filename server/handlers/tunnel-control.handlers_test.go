@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/ssh/sshtest"
+	"github.com/aqz236/port-fly/server/operations"
+)
+
+// createTestTunnelPorts saves a remote port on host forwarding to a local
+// port on an echo server, and returns the remote port
+func createTestTunnelPorts(t *testing.T, h *Handlers, host *models.Host) *models.Port {
+	t.Helper()
+	ctx := context.Background()
+	target := &models.Port{
+		Name:        "echo",
+		Type:        models.PortTypeLocal,
+		Port:        startEchoServer(t),
+		BindAddress: "127.0.0.1",
+		GroupID:     host.GroupID,
+	}
+	if err := h.storage.CreatePort(ctx, target); err != nil {
+		t.Fatalf("failed to create local port: %v", err)
+	}
+	remote := &models.Port{
+		Name:              "echo-remote",
+		Type:              models.PortTypeRemote,
+		Port:              freePort(t),
+		RemoteBindAddress: "127.0.0.1",
+		GroupID:           host.GroupID,
+		HostID:            &host.ID,
+		TargetPortID:      &target.ID,
+	}
+	if err := h.storage.CreatePort(ctx, remote); err != nil {
+		t.Fatalf("failed to create remote port: %v", err)
+	}
+	return remote
+}
+
+// waitOperation waits for the operation in an accepted response to finish
+// and fails the test unless it succeeded
+func waitOperation(t *testing.T, h *Handlers, response Response) {
+	t.Helper()
+	var operation operations.Operation
+	decodeData(t, response, &operation)
+	deadline := time.Now().Add(30 * time.Second)
+	for !operation.Done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("operation %s did not finish, phase %s", operation.ID, operation.Phase)
+		}
+		time.Sleep(20 * time.Millisecond)
+		var err error
+		if operation, err = h.operations.Get(operation.ID); err != nil {
+			t.Fatalf("failed to get operation: %v", err)
+		}
+	}
+	if operation.State != operations.StateSucceeded {
+		t.Fatalf("operation %s failed: %s", operation.Kind, operation.Error)
+	}
+}
+
+// startTestPort starts the port's tunnel through the port control API
+func startTestPort(t *testing.T, h *Handlers, port *models.Port) {
+	t.Helper()
+	status, response := serve(t, h.ControlPort, http.MethodPost, "/ports/:id/control",
+		fmt.Sprintf("/ports/%d/control", port.ID), ControlPortRequest{Action: PortActionStart})
+	if status != http.StatusAccepted {
+		t.Fatalf("start port status = %d: %s", status, response.Error)
+	}
+	waitOperation(t, h, response)
+}
+
+// portSessionRecord returns the latest tunnel session record of a port
+func portSessionRecord(t *testing.T, h *Handlers, portID uint) models.TunnelSession {
+	t.Helper()
+	records, err := h.storage.GetTunnelSessions(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list tunnel sessions: %v", err)
+	}
+	var latest *models.TunnelSession
+	for i := range records {
+		if records[i].PortID != nil && *records[i].PortID == portID && (latest == nil || records[i].ID > latest.ID) {
+			latest = &records[i]
+		}
+	}
+	if latest == nil {
+		t.Fatalf("port %d has no tunnel session record", portID)
+	}
+	return *latest
+}
+
+// waitClosed waits until nothing accepts connections on address
+func waitClosed(t *testing.T, address string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err != nil {
+			return
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatalf("%s still accepts connections", address)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestStopTunnelStopsPortTunnel(t *testing.T) {
+	server, err := sshtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SSH server: %v", err)
+	}
+	defer server.Close()
+	h := newTestHandlers(t)
+	port := createTestTunnelPorts(t, h, createTestHost(t, h, server))
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(port.Port))
+
+	startTestPort(t, h, port)
+	assertEcho(t, address)
+	record := portSessionRecord(t, h, port.ID)
+	if record.Status != models.StatusActive {
+		t.Fatalf("record status = %s, want %s", record.Status, models.StatusActive)
+	}
+
+	path := fmt.Sprintf("/sessions/%d/stop", record.ID)
+	status, response := serve(t, h.StopTunnel, http.MethodPost, "/sessions/:id/stop", path, nil)
+	if status != http.StatusOK {
+		t.Fatalf("stop status = %d: %s", status, response.Error)
+	}
+	var stopped models.TunnelSession
+	decodeData(t, response, &stopped)
+	if stopped.Status != models.StatusStopped || stopped.EndTime == nil {
+		t.Fatalf("stopped record = %s, end time %v", stopped.Status, stopped.EndTime)
+	}
+	waitClosed(t, address)
+	if _, running := h.portTunnels.get(port.ID); running {
+		t.Fatal("port tunnel is still tracked after stop")
+	}
+
+	if status, _ := serve(t, h.StopTunnel, http.MethodPost, "/sessions/:id/stop", path, nil); status != http.StatusConflict {
+		t.Fatalf("second stop status = %d, want 409", status)
+	}
+}
+
+func TestStartTunnelRestartsPortTunnel(t *testing.T) {
+	server, err := sshtest.NewServer()
+	if err != nil {
+		t.Fatalf("failed to start SSH server: %v", err)
+	}
+	defer server.Close()
+	h := newTestHandlers(t)
+	port := createTestTunnelPorts(t, h, createTestHost(t, h, server))
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(port.Port))
+
+	startTestPort(t, h, port)
+	record := portSessionRecord(t, h, port.ID)
+	stop := fmt.Sprintf("/sessions/%d/stop", record.ID)
+	if status, response := serve(t, h.StopTunnel, http.MethodPost, "/sessions/:id/stop", stop, nil); status != http.StatusOK {
+		t.Fatalf("stop status = %d: %s", status, response.Error)
+	}
+	waitClosed(t, address)
+
+	start := fmt.Sprintf("/sessions/%d/start", record.ID)
+	status, response := serve(t, h.StartTunnel, http.MethodPost, "/sessions/:id/start", start, nil)
+	if status != http.StatusAccepted {
+		t.Fatalf("start status = %d: %s", status, response.Error)
+	}
+	waitOperation(t, h, response)
+	assertEcho(t, address)
+	if restarted := portSessionRecord(t, h, port.ID); restarted.ID == record.ID || restarted.Status != models.StatusActive {
+		t.Fatalf("restarted record = %d (%s), want a new active record", restarted.ID, restarted.Status)
+	}
+
+	if status, _ := serve(t, h.StartTunnel, http.MethodPost, "/sessions/:id/start", start, nil); status != http.StatusConflict {
+		t.Fatalf("start of a running port status = %d, want 409", status)
+	}
+}
+
+func TestStopTunnelWithoutLiveTunnel(t *testing.T) {
+	h := newTestHandlers(t)
+	ctx := context.Background()
+	now := time.Now()
+	record := &models.TunnelSession{Status: models.StatusActive, StartTime: &now, HostID: 1}
+	if err := h.storage.CreateTunnelSession(ctx, record); err != nil {
+		t.Fatalf("failed to create tunnel session: %v", err)
+	}
+
+	path := fmt.Sprintf("/sessions/%d/stop", record.ID)
+	status, response := serve(t, h.StopTunnel, http.MethodPost, "/sessions/:id/stop", path, nil)
+	if status != http.StatusConflict || response.Success {
+		t.Fatalf("stop status = %d, success %v, want 409", status, response.Success)
+	}
+	if unchanged, err := h.storage.GetTunnelSession(ctx, record.ID); err != nil || unchanged.Status != models.StatusActive {
+		t.Fatalf("record changed by a failed stop: %v %v", unchanged, err)
+	}
+}
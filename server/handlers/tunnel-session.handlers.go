@@ -1,10 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/storage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -36,8 +37,15 @@ func (h *Handlers) CreateTunnelSession(c *gin.Context) {
 		return
 	}
 
+	if sessionStarting(session.Status) && h.rejectHostInMaintenance(c, "tunnel session", session.HostID) {
+		return
+	}
+	if session.Status == models.StatusActive && h.rejectOverTunnelQuota(c, h.hostProjectID(c.Request.Context(), session.HostID), 1) {
+		return
+	}
+
 	if err := h.storage.CreateTunnelSession(c.Request.Context(), &session); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
+		c.JSON(sessionErrorStatus(err), Response{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -51,12 +59,8 @@ func (h *Handlers) CreateTunnelSession(c *gin.Context) {
 }
 
 func (h *Handlers) GetTunnelSession(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Success: false,
-			Error:   "Invalid session ID",
-		})
+	id, ok := h.resolveSessionID(c)
+	if !ok {
 		return
 	}
 
@@ -76,12 +80,8 @@ func (h *Handlers) GetTunnelSession(c *gin.Context) {
 }
 
 func (h *Handlers) UpdateTunnelSession(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Success: false,
-			Error:   "Invalid session ID",
-		})
+	id, ok := h.resolveSessionID(c)
+	if !ok {
 		return
 	}
 
@@ -95,8 +95,18 @@ func (h *Handlers) UpdateTunnelSession(c *gin.Context) {
 	}
 
 	session.ID = uint(id)
+	if sessionStarting(session.Status) && h.rejectHostInMaintenance(c, "tunnel session", session.HostID) {
+		return
+	}
+	// Only a session becoming active takes a tunnel slot
+	if session.Status == models.StatusActive {
+		if existing, err := h.storage.GetTunnelSession(c.Request.Context(), session.ID); err == nil && existing.Status != models.StatusActive &&
+			h.rejectOverTunnelQuota(c, h.hostProjectID(c.Request.Context(), session.HostID), 1) {
+			return
+		}
+	}
 	if err := h.storage.UpdateTunnelSession(c.Request.Context(), &session); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
+		c.JSON(sessionErrorStatus(err), Response{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -110,12 +120,8 @@ func (h *Handlers) UpdateTunnelSession(c *gin.Context) {
 }
 
 func (h *Handlers) DeleteTunnelSession(c *gin.Context) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, Response{
-			Success: false,
-			Error:   "Invalid session ID",
-		})
+	id, ok := h.resolveSessionID(c)
+	if !ok {
 		return
 	}
 
@@ -148,3 +154,25 @@ func (h *Handlers) GetActiveTunnelSessions(c *gin.Context) {
 		Data:    sessions,
 	})
 }
+
+// sessionErrorStatus maps session name errors to 400/409 and everything else to 500
+func sessionErrorStatus(err error) int {
+	if errors.Is(err, models.ErrNumericName) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, storage.ErrNameConflict) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// sessionStarting reports whether a session record in this status runs a
+// tunnel, which maintenance windows refuse
+func sessionStarting(status models.SessionStatus) bool {
+	switch status {
+	case models.StatusCreated, models.StatusConnecting, models.StatusConnected, models.StatusActive:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/server/upgrade"
+)
+
+// UpgradeResult 接管监听的新进程
+type UpgradeResult struct {
+	PID int `json:"pid"`
+}
+
+// SetUpgrader sets the upgrader handing the listeners to a new binary
+func (h *Handlers) SetUpgrader(upgrader *upgrade.Upgrader) {
+	h.upgrader = upgrader
+}
+
+// UpgradeServer 启动磁盘上的新版本服务器并移交监听，新进程就绪后本进程处理完进行中的请求后退出
+// 新进程启动失败或未在限定时间内就绪时本进程继续服务
+func (h *Handlers) UpgradeServer(c *gin.Context) {
+	if h.upgrader == nil {
+		c.JSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Graceful upgrades are not configured",
+		})
+		return
+	}
+
+	pid, err := h.upgrader.Upgrade(c.Request.Context())
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, upgrade.ErrUnsupported):
+			status = http.StatusNotImplemented
+		case errors.Is(err, upgrade.ErrInProgress):
+			status = http.StatusConflict
+		}
+		h.logger.Warn("Server upgrade failed", "error", err)
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	h.logger.Info("Server upgraded", "pid", pid)
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    UpgradeResult{PID: pid},
+		Message: "New server process is serving; this process exits after draining",
+	})
+}
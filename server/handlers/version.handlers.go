@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/agent"
+	"github.com/aqz236/port-fly/core/version"
+)
+
+// VersionResponse 服务器的版本、构建信息，以及支持的 API 修订号和 agent 协议版本范围
+type VersionResponse struct {
+	version.Info
+	AgentProtocol    int `json:"agent_protocol"`
+	MinAgentProtocol int `json:"min_agent_protocol"`
+}
+
+// SetBuildInfo sets the server build reported by the version endpoint
+func (h *Handlers) SetBuildInfo(info version.Info) {
+	h.buildInfo = info
+}
+
+// GetVersion 获取服务器版本；CLI 据此检查兼容性，不兼容的组合拒绝使用
+func (h *Handlers) GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: VersionResponse{
+			Info:             h.buildInfo,
+			AgentProtocol:    agent.ProtocolVersion,
+			MinAgentProtocol: agent.MinProtocolVersion,
+		},
+	})
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// hostnamePattern 合法的 DNS 主机名（不含 IP 地址）
+var hostnamePattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*\.?$`)
+
+// errHostNameAmbiguous 分组中有多个同名主机
+var errHostNameAmbiguous = errors.New("more than one host in the group has that name")
+
+// WebhookHostRequest 外部系统（cloud-init、自动伸缩钩子等）注册主机的请求。
+// 同一分组中已有同名主机时更新其地址，否则创建新主机
+type WebhookHostRequest struct {
+	GroupID     uint     `json:"group_id" binding:"required"`
+	Name        string   `json:"name" binding:"required"`
+	Hostname    string   `json:"hostname" binding:"required"`
+	Port        int      `json:"port"` // SSH 端口，默认 22
+	Username    string   `json:"username" binding:"required"`
+	AuthMethod  string   `json:"auth_method"` // 为空时保持原值（新主机为 password）
+	Password    string   `json:"password"`    // 为空时保持原值
+	PrivateKey  string   `json:"private_key"` // 为空时保持原值
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"` // 为空时保持原值
+}
+
+// WebhookTargetRequest 更新端口的目标：所在主机和端口号，至少指定一项
+type WebhookTargetRequest struct {
+	HostID *uint  `json:"host_id"`
+	Host   string `json:"host"` // 主机名称，在端口所在分组中查找；与 host_id 二选一
+	Port   *int   `json:"port"` // 端口范围整体平移，保持长度不变
+}
+
+// SetWebhookToken sets the token external systems authenticate webhooks
+// with; an empty token disables the webhook endpoints
+func (h *Handlers) SetWebhookToken(token string) {
+	h.webhookToken = token
+}
+
+// WebhookAuth 校验 Authorization: Bearer <token>，未配置令牌时返回 503
+func (h *Handlers) WebhookAuth(c *gin.Context) {
+	if h.webhookToken == "" {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, Response{
+			Success: false,
+			Error:   "Webhooks are not enabled",
+		})
+		return
+	}
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.webhookToken)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, Response{
+			Success: false,
+			Error:   "Invalid webhook token",
+		})
+		return
+	}
+	c.Next()
+}
+
+// RegisterWebhookHost 注册或更新主机，新建时返回 201，更新时返回 200
+func (h *Handlers) RegisterWebhookHost(c *gin.Context) {
+	var request WebhookHostRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+	if request.Port == 0 {
+		request.Port = 22
+	}
+	if err := validateWebhookHost(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.storage.GetGroup(ctx, request.GroupID); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   fmt.Sprintf("Group %d not found", request.GroupID),
+		})
+		return
+	}
+
+	existing, err := h.findGroupHost(ctx, request.GroupID, request.Name)
+	if err != nil {
+		c.JSON(hostLookupStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if existing == nil {
+		if h.rejectOverHostQuota(c, request.GroupID) {
+			return
+		}
+		host := &models.Host{GroupID: request.GroupID, Name: request.Name}
+		applyWebhookHost(host, &request)
+		if err := h.storage.CreateHost(ctx, host); err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		h.recordChange(c, models.EntityHost, host.ID, models.ChangeCreate, nil, h.entityFields(models.EntityHost, host))
+		h.logger.Info("Host registered by webhook", "host_id", host.ID, "name", host.Name, "hostname", host.Hostname)
+
+		c.JSON(http.StatusCreated, Response{
+			Success: true,
+			Data:    withoutCredentials(host),
+			Message: "Host registered",
+		})
+		return
+	}
+
+	before := h.entityFields(models.EntityHost, existing)
+	existing.Group = models.Group{}
+	existing.PortForwards = nil
+	existing.TunnelSessions = nil
+	applyWebhookHost(existing, &request)
+	if err := h.storage.UpdateHost(ctx, existing); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	h.recordChange(c, models.EntityHost, existing.ID, models.ChangeUpdate, before, h.entityFields(models.EntityHost, existing))
+	h.logger.Info("Host updated by webhook", "host_id", existing.ID, "name", existing.Name, "hostname", existing.Hostname)
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    withoutCredentials(existing),
+		Message: "Host updated",
+	})
+}
+
+// DeregisterWebhookHost 按名称删除分组中的主机，分组由 group_id 查询参数指定
+func (h *Handlers) DeregisterWebhookHost(c *gin.Context) {
+	var query struct {
+		GroupID uint `form:"group_id" binding:"required"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "group_id is required",
+		})
+		return
+	}
+
+	host, err := h.findGroupHost(c.Request.Context(), query.GroupID, c.Param("name"))
+	if err != nil {
+		c.JSON(hostLookupStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if host == nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "Host not found",
+		})
+		return
+	}
+
+	before := h.entityFields(models.EntityHost, host)
+	if err := h.storage.DeleteHost(c.Request.Context(), host.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	h.recordChange(c, models.EntityHost, host.ID, models.ChangeDelete, before, nil)
+	h.logger.Info("Host deregistered by webhook", "host_id", host.ID, "name", host.Name)
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "Host deregistered",
+	})
+}
+
+// UpdateWebhookPortTarget 更新端口所在的主机或端口号。运行中的隧道在重新启动后使用新目标
+func (h *Handlers) UpdateWebhookPortTarget(c *gin.Context) {
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	var request WebhookTargetRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request body: " + err.Error(),
+		})
+		return
+	}
+	if request.HostID == nil && request.Host == "" && request.Port == nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Specify host_id, host or port",
+		})
+		return
+	}
+	if request.HostID != nil && request.Host != "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "host_id and host cannot be combined",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	port, err := h.storage.GetPort(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	before := h.entityFields(models.EntityPort, port)
+
+	switch {
+	case request.HostID != nil:
+		if _, err := h.storage.GetHost(ctx, *request.HostID); err != nil {
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Error:   fmt.Sprintf("Host %d not found", *request.HostID),
+			})
+			return
+		}
+		port.HostID = request.HostID
+	case request.Host != "":
+		host, err := h.findGroupHost(ctx, port.GroupID, request.Host)
+		if err != nil {
+			c.JSON(hostLookupStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		if host == nil {
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Error:   fmt.Sprintf("Host %q not found in group %d", request.Host, port.GroupID),
+			})
+			return
+		}
+		port.HostID = &host.ID
+	}
+
+	if request.Port != nil {
+		if port.PortEnd > 0 {
+			port.PortEnd = *request.Port + port.PortEnd - port.Port
+		}
+		port.Port = *request.Port
+	}
+
+	port.Group = models.Group{}
+	port.Host = nil
+	port.TargetPort = nil
+	port.SourcePorts = nil
+	port.TunnelSessions = nil
+	if err := h.storage.UpdatePort(ctx, port); err != nil {
+		c.JSON(portErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	h.recordChange(c, models.EntityPort, port.ID, models.ChangeUpdate, before, h.entityFields(models.EntityPort, port))
+	var hostID uint
+	if port.HostID != nil {
+		hostID = *port.HostID
+	}
+	h.logger.Info("Port target updated by webhook", "port_id", port.ID, "host_id", hostID, "port", port.Port)
+
+	message := "Port target updated"
+	if port.IsActive() {
+		message += "; restart the port to use it"
+	}
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    port,
+		Message: message,
+	})
+}
+
+// findGroupHost 按名称查找分组中的主机，不存在时返回 nil
+func (h *Handlers) findGroupHost(ctx context.Context, groupID uint, name string) (*models.Host, error) {
+	hosts, err := h.storage.GetHostsByGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	var found *models.Host
+	for i := range hosts {
+		if hosts[i].Name != name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%w: %q", errHostNameAmbiguous, name)
+		}
+		found = &hosts[i]
+	}
+	return found, nil
+}
+
+// hostLookupStatus 将按名称查找主机的错误映射为 HTTP 状态码
+func hostLookupStatus(err error) int {
+	if errors.Is(err, errHostNameAmbiguous) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// validateWebhookHost 校验主机名称、地址和端口
+func validateWebhookHost(request *WebhookHostRequest) error {
+	if len(request.Name) > 100 {
+		return errors.New("name is longer than 100 characters")
+	}
+	if len(request.Username) > 100 {
+		return errors.New("username is longer than 100 characters")
+	}
+	if net.ParseIP(request.Hostname) == nil &&
+		(len(request.Hostname) > 253 || !hostnamePattern.MatchString(request.Hostname)) {
+		return fmt.Errorf("invalid hostname: %q", request.Hostname)
+	}
+	if request.Port < 1 || request.Port > 65535 {
+		return fmt.Errorf("invalid port: %d", request.Port)
+	}
+	return nil
+}
+
+// applyWebhookHost 将请求中的字段写入主机，未提供的凭据和标签保持原值
+func applyWebhookHost(host *models.Host, request *WebhookHostRequest) {
+	host.Hostname = request.Hostname
+	host.Port = request.Port
+	host.Username = request.Username
+	if request.AuthMethod != "" {
+		host.AuthMethod = request.AuthMethod
+	}
+	if request.Password != "" {
+		host.Password = request.Password
+	}
+	if request.PrivateKey != "" {
+		host.PrivateKey = request.PrivateKey
+	}
+	if request.Description != "" {
+		host.Description = request.Description
+	}
+	if request.Tags != nil {
+		host.Tags = request.Tags
+	}
+}
+
+// withoutCredentials 返回不含密码、sudo 密码和私钥的副本，不向外部系统回传凭据
+func withoutCredentials(host *models.Host) *models.Host {
+	clean := *host
+	clean.Password = ""
+	clean.PrivateKey = ""
+	clean.SudoPassword = ""
+	return &clean
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/webinfo"
+)
+
+// errNotWebPort 只有本地 HTTP/HTTPS 端口可以探测
+var errNotWebPort = errors.New("web detection needs a local port with service_type http or https")
+
+// detectWebInfo 经端口访问目标首页，记录标题、图标和识别出的应用
+func (h *Handlers) detectWebInfo(ctx context.Context, port *models.Port) (*models.WebInfo, error) {
+	service := port.GetServiceType()
+	if !port.IsLocalPort() || (service != models.ServiceHTTP && service != models.ServiceHTTPS) {
+		return nil, errNotWebPort
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webinfo.DefaultTimeout)
+	defer cancel()
+
+	address := net.JoinHostPort(port.GetShareHost(), strconv.Itoa(port.Port))
+	info, err := webinfo.Detect(ctx, string(service)+"://"+address+"/")
+	if err != nil {
+		return nil, err
+	}
+	if err := h.storage.UpdatePortWebInfo(ctx, port.ID, info); err != nil {
+		return nil, err
+	}
+
+	h.logger.Info("Web service detected",
+		"port_id", port.ID,
+		"app", info.App,
+		"title", info.Title,
+		"favicon", info.FaviconType != "")
+	return info, nil
+}
+
+// syncPortWebInfo 本地 HTTP 端口启动后在后台探测 Web 服务
+func (h *Handlers) syncPortWebInfo(ctx context.Context, portID uint, status models.PortStatus) {
+	if status != models.PortStatusActive {
+		return
+	}
+
+	go func() {
+		ctx := context.WithoutCancel(ctx)
+		port, err := h.storage.GetPort(ctx, portID)
+		if err != nil {
+			return
+		}
+		if _, err := h.detectWebInfo(ctx, port); err != nil && !errors.Is(err, errNotWebPort) {
+			h.logger.Warn("Failed to detect web service", "port_id", portID, "error", err)
+		}
+	}()
+}
+
+// DetectPortWebInfo 立即探测端口背后的 Web 服务
+func (h *Handlers) DetectPortWebInfo(c *gin.Context) {
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	port, err := h.storage.GetPort(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	info, err := h.detectWebInfo(c.Request.Context(), port)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, errNotWebPort) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    info,
+	})
+}
+
+// GetPortFavicon 返回探测到的网站图标
+func (h *Handlers) GetPortFavicon(c *gin.Context) {
+	id, ok := h.resolvePortID(c)
+	if !ok {
+		return
+	}
+
+	port, err := h.storage.GetPort(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	if len(port.Web.Favicon) == 0 {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   "No favicon detected",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("X-Content-Type-Options", "nosniff")
+	// Icons come from the forwarded service; SVG may carry scripts
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	c.Data(http.StatusOK, port.Web.FaviconType, port.Web.Favicon)
+}
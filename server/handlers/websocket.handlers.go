@@ -1,40 +1,189 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/authfailures"
+	"github.com/aqz236/port-fly/server/operations"
 )
 
 // ===== WebSocket Handler =====
 
+// WebSocketHandler streams session events as JSON: status changes, reconnects
+// and, at the configured stats interval, transfer deltas. ?types= takes a
+// comma-separated list of event types to receive, e.g. types=session.stats.
+// Host authentication challenges arrive as auth.challenge events and are
+// answered with {"type": "auth.answer", "id", "answers"} messages. Port
+// control commands report their phases as operation.progress events and
+// their outcome as an operation.completed event. Hosts reaching the
+// consecutive SSH authentication failure threshold send host.auth_failures,
+// and host.auth_recovered once they authenticate again.
 func (h *Handlers) WebSocketHandler(upgrader websocket.Upgrader) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var types map[models.EventType]bool
+		if filter := c.Query("types"); filter != "" {
+			types = make(map[models.EventType]bool)
+			for _, eventType := range strings.Split(filter, ",") {
+				types[models.EventType(strings.TrimSpace(eventType))] = true
+			}
+		}
+
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
-			h.logger.Error("WebSocket upgrade failed: %v", err)
+			h.logger.Error("WebSocket upgrade failed", "error", err)
 			return
 		}
 		defer conn.Close()
 
-		// TODO: Implement WebSocket logic for real-time updates
-		// This should include:
-		// - Session status updates
-		// - Host connection status
-		// - Port forward status
-		// - Real-time logs
+		events, unsubscribe := h.sessionManager.Subscribe()
+		defer unsubscribe()
+		var challenges <-chan AuthChallenge
+		if types == nil || types[eventAuthChallenge] {
+			var unwatch func()
+			challenges, unwatch = h.challenges.watch()
+			defer unwatch()
+		}
+		var operationEvents <-chan operations.Event
+		if h.operations != nil && (types == nil || types[operations.EventProgress] || types[operations.EventCompleted]) {
+			var unwatch func()
+			operationEvents, unwatch = h.operations.Watch()
+			defer unwatch()
+		}
+
+		var authEvents <-chan authfailures.Event
+		if h.authFailures != nil && (types == nil || types[models.EventAuthFailures] || types[models.EventAuthRecovered]) {
+			var unwatch func()
+			authEvents, unwatch = h.authFailures.Watch()
+			defer unwatch()
+		}
+
+		// Stop when the dashboard goes away; it only sends challenge answers
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					unsubscribe()
+					return
+				}
+				var answer struct {
+					Type    string   `json:"type"`
+					ID      string   `json:"id"`
+					Answers []string `json:"answers"`
+				}
+				if json.Unmarshal(data, &answer) != nil || answer.Type != "auth.answer" {
+					continue
+				}
+				if err := h.challenges.answer(answer.ID, answer.Answers); err != nil {
+					h.logger.Warn("Challenge answer rejected", "challenge_id", answer.ID, "error", err)
+				}
+			}
+		}()
 
 		for {
-			messageType, p, err := conn.ReadMessage()
-			if err != nil {
-				h.logger.Error("WebSocket read error: %v", err)
-				break
+			var message any
+			select {
+			case event, ok := <-events:
+				if !ok && (challenges != nil || operationEvents != nil || authEvents != nil) {
+					// Challenges, operations and auth alerts still come when there are no session events
+					events = nil
+					continue
+				}
+				if !ok {
+					conn.WriteMessage(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseNormalClosure, "event stream closed"))
+					return
+				}
+				if types != nil && !types[event.Type] {
+					continue
+				}
+				message = event
+			case challenge := <-challenges:
+				message = authChallengeEvent{Type: eventAuthChallenge, Challenge: challenge, Timestamp: time.Now()}
+			case event := <-operationEvents:
+				if types != nil && !types[event.Type] {
+					continue
+				}
+				message = event
+			case event := <-authEvents:
+				if types != nil && !types[event.Type] {
+					continue
+				}
+				message = event
+			case <-done:
+				return
+			}
+			if err := conn.WriteJSON(message); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// CaptureWebSocketHandler streams the debug capture of a session's forwarded
+// bytes. Each chunk is sent as JSON, or with ?format=dump as a text frame
+// holding a header line and a hex/ASCII dump.
+func (h *Handlers) CaptureWebSocketHandler(upgrader websocket.Upgrader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID := c.Param("id")
+		chunks, unsubscribe, err := h.sessionManager.SubscribeCapture(sessionID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		defer unsubscribe()
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			h.logger.Error("WebSocket upgrade failed", "error", err)
+			return
+		}
+		defer conn.Close()
+
+		// Stop when the viewer goes away; the viewer sends nothing else
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					unsubscribe()
+					return
+				}
 			}
+		}()
 
-			// Echo the message back for now
-			if err := conn.WriteMessage(messageType, p); err != nil {
-				h.logger.Error("WebSocket write error: %v", err)
-				break
+		dump := c.Query("format") == "dump"
+		for chunk := range chunks {
+			if dump {
+				err = conn.WriteMessage(websocket.TextMessage, []byte(captureDumpFrame(chunk)))
+			} else {
+				err = conn.WriteJSON(chunk)
+			}
+			if err != nil {
+				return
 			}
 		}
+		conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, "capture stopped"))
+	}
+}
+
+// captureDumpFrame renders a chunk for the text view
+func captureDumpFrame(chunk models.CaptureChunk) string {
+	header := fmt.Sprintf("#%d %s %s %s (%d bytes at %d)",
+		chunk.Connection, chunk.Client, chunk.Direction, chunk.Listener, len(chunk.Data), chunk.Offset)
+	if chunk.Truncated {
+		header += ", capture limit reached"
 	}
+	return header + "\n" + chunk.Dump
 }
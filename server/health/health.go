@@ -0,0 +1,250 @@
+// Package health aggregates the state of the server's subsystems into a
+// readiness report with machine-readable states and degradation reasons.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// DefaultTimeout bounds each probe so one hung subsystem cannot stall the report
+const DefaultTimeout = 2 * time.Second
+
+// State is the machine-readable state of a check or of the whole server
+type State string
+
+const (
+	StateOK       State = "ok"
+	StateDegraded State = "degraded" // serving, with reduced function
+	StateDown     State = "down"     // not ready to serve
+	StateDisabled State = "disabled" // subsystem not configured
+)
+
+// severity orders states so the report takes the worst one
+func (s State) severity() int {
+	switch s {
+	case StateDegraded:
+		return 1
+	case StateDown:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Check is the result of one probe. Numeric details are also exported as
+// Prometheus gauges named portfly_<check>_<detail>.
+type Check struct {
+	Name      string         `json:"name"`
+	State     State          `json:"state"`
+	Reason    string         `json:"reason,omitempty"`
+	LatencyMs float64        `json:"latency_ms"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// Report is the readiness report served by /health
+type Report struct {
+	Status    State     `json:"status"`
+	Service   string    `json:"service"`
+	Uptime    string    `json:"uptime"`
+	CheckedAt time.Time `json:"checked_at"`
+	Reasons   []string  `json:"reasons,omitempty"` // "<check>: <reason>" for every check not ok
+	Checks    []Check   `json:"checks"`
+}
+
+// Probe inspects one subsystem; Name and LatencyMs are filled in by the registry
+type Probe func(ctx context.Context) Check
+
+// OK reports a healthy subsystem
+func OK(details map[string]any) Check {
+	return Check{State: StateOK, Details: details}
+}
+
+// Degraded reports a subsystem that works with reduced function
+func Degraded(reason string, details map[string]any) Check {
+	return Check{State: StateDegraded, Reason: reason, Details: details}
+}
+
+// Down reports a subsystem that cannot serve
+func Down(reason string, details map[string]any) Check {
+	return Check{State: StateDown, Reason: reason, Details: details}
+}
+
+// Disabled reports a subsystem that is not configured
+func Disabled() Check { return Check{State: StateDisabled} }
+
+// Registry runs the registered probes and tracks background jobs
+type Registry struct {
+	service string
+	started time.Time
+	timeout time.Duration
+
+	mu     sync.Mutex
+	names  []string
+	probes map[string]Probe
+	jobs   map[string]*job
+}
+
+// job records whether a background loop is still running
+type job struct {
+	running bool
+	panic   string
+}
+
+// NewRegistry creates a registry reporting for service
+func NewRegistry(service string) *Registry {
+	r := &Registry{
+		service: service,
+		started: time.Now(),
+		timeout: DefaultTimeout,
+		probes:  make(map[string]Probe),
+		jobs:    make(map[string]*job),
+	}
+	r.Register("scheduler", r.schedulerProbe)
+	return r
+}
+
+// Register adds a probe; registering a name again replaces it
+func (r *Registry) Register(name string, probe Probe) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.probes[name]; !exists {
+		r.names = append(r.names, name)
+	}
+	r.probes[name] = probe
+}
+
+// Go runs a background job and records its lifetime for the scheduler
+// check. A job that returns or panics while ctx is live degrades the report;
+// panics are recovered so one broken job does not take down the server.
+func (r *Registry) Go(ctx context.Context, name string, run func()) {
+	r.mu.Lock()
+	r.jobs[name] = &job{running: true}
+	r.mu.Unlock()
+
+	go func() {
+		var panicked string
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				panicked = fmt.Sprint(recovered)
+				utils.ReportPanic("job "+name, recovered)
+			}
+			r.mu.Lock()
+			j := r.jobs[name]
+			j.running = false
+			j.panic = panicked
+			if ctx.Err() != nil {
+				// Stopped on shutdown rather than failed
+				delete(r.jobs, name)
+			}
+			r.mu.Unlock()
+		}()
+		run()
+	}()
+}
+
+// Check runs every probe concurrently and combines the results
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.Lock()
+	names := append([]string(nil), r.names...)
+	probes := make([]Probe, len(names))
+	for i, name := range names {
+		probes[i] = r.probes[name]
+	}
+	r.mu.Unlock()
+
+	checks := make([]Check, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			checks[i] = r.run(ctx, names[i], probes[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StateOK,
+		Service:   r.service,
+		Uptime:    time.Since(r.started).Round(time.Second).String(),
+		CheckedAt: time.Now(),
+		Checks:    checks,
+	}
+	for _, check := range checks {
+		if check.State.severity() > report.Status.severity() {
+			report.Status = check.State
+		}
+		if check.State == StateDegraded || check.State == StateDown {
+			report.Reasons = append(report.Reasons, check.Name+": "+check.Reason)
+		}
+	}
+	return report
+}
+
+// run executes one probe under the registry timeout; a probe that does not
+// return in time is reported down
+func (r *Registry) run(ctx context.Context, name string, probe Probe) Check {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := make(chan Check, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				utils.ReportPanic("probe "+name, recovered)
+				result <- Down(fmt.Sprintf("probe panicked: %v", recovered), nil)
+			}
+		}()
+		result <- probe(ctx)
+	}()
+
+	var check Check
+	select {
+	case check = <-result:
+	case <-ctx.Done():
+		check = Down("probe timed out after "+r.timeout.String(), nil)
+	}
+	check.Name = name
+	check.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	return check
+}
+
+// schedulerProbe reports background jobs that stopped while the server runs
+func (r *Registry) schedulerProbe(ctx context.Context) Check {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.jobs))
+	for name := range r.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	details := map[string]any{"jobs": len(names)}
+	var stopped []string
+	for _, name := range names {
+		j := r.jobs[name]
+		state := "running"
+		if !j.running {
+			state = "stopped"
+			if j.panic != "" {
+				state = "panicked: " + j.panic
+			}
+			stopped = append(stopped, name)
+		}
+		details["job_"+name] = state
+	}
+	details["stopped"] = len(stopped)
+
+	if len(stopped) > 0 {
+		return Degraded(fmt.Sprintf("background jobs stopped: %v", stopped), details)
+	}
+	return OK(details)
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// metricUnsafe matches characters not allowed in Prometheus metric names
+var metricUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// stateValues encodes states for portfly_check_state; alert rules compare against these
+var stateValues = map[State]int{
+	StateOK:       0,
+	StateDegraded: 1,
+	StateDown:     2,
+	StateDisabled: -1,
+}
+
+// WritePrometheus writes a report in the Prometheus text exposition format:
+// portfly_up, portfly_check_state{check} and one gauge per numeric detail
+func WritePrometheus(w io.Writer, report Report) error {
+	var b strings.Builder
+
+	up := 1
+	if report.Status == StateDown {
+		up = 0
+	}
+	b.WriteString("# HELP portfly_up Whether the server is ready to serve (1) or down (0).\n")
+	b.WriteString("# TYPE portfly_up gauge\n")
+	fmt.Fprintf(&b, "portfly_up %d\n", up)
+
+	b.WriteString("# HELP portfly_check_state Subsystem state: 0 ok, 1 degraded, 2 down, -1 disabled.\n")
+	b.WriteString("# TYPE portfly_check_state gauge\n")
+	for _, check := range report.Checks {
+		fmt.Fprintf(&b, "portfly_check_state{check=%q} %d\n", check.Name, stateValues[check.State])
+	}
+
+	b.WriteString("# HELP portfly_check_latency_seconds Time taken by the subsystem probe.\n")
+	b.WriteString("# TYPE portfly_check_latency_seconds gauge\n")
+	for _, check := range report.Checks {
+		fmt.Fprintf(&b, "portfly_check_latency_seconds{check=%q} %g\n", check.Name, check.LatencyMs/1000)
+	}
+
+	for _, check := range report.Checks {
+		keys := make([]string, 0, len(check.Details))
+		for key := range check.Details {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value, ok := numeric(check.Details[key])
+			if !ok {
+				continue
+			}
+			name := metricName(check.Name, key)
+			metricType := "gauge"
+			if strings.HasSuffix(name, "_total") {
+				metricType = "counter"
+			}
+			fmt.Fprintf(&b, "# TYPE %s %s\n%s %g\n", name, metricType, name, value)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// metricName builds portfly_<check>_<detail>
+func metricName(check, detail string) string {
+	return "portfly_" + metricUnsafe.ReplaceAllString(strings.ToLower(check+"_"+detail), "_")
+}
+
+// numeric converts detail values that can be exported as samples
+func numeric(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
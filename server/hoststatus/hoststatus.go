@@ -0,0 +1,176 @@
+// Package hoststatus keeps the live reachability of every host in memory.
+// The SSH port of each host is probed periodically, and host lists are
+// joined with the results so they show whether a host answers now rather
+// than the status last saved with it. Reads of hosts not probed recently
+// probe them on the spot, so the cache never serves a result older than
+// its maximum age.
+package hoststatus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	coressh "github.com/aqz236/port-fly/core/ssh"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// DefaultInterval is how often all hosts are probed
+const DefaultInterval = time.Minute
+
+// readThroughTimeout bounds the probes made while a host list is read, so a
+// dead host delays the list by at most this long
+const readThroughTimeout = 2 * time.Second
+
+// Config configures the host monitor
+type Config struct {
+	Interval time.Duration `json:"interval"` // time between probes of all hosts
+	MaxAge   time.Duration `json:"max_age"`  // older results are probed again when read; 0 uses twice the interval
+}
+
+// Monitor probes hosts and caches their live state
+type Monitor struct {
+	store  storage.StorageInterface
+	config Config
+	logger utils.Logger
+	now    func() time.Time
+
+	mu     sync.RWMutex
+	states map[uint]models.HostLiveState
+}
+
+// NewMonitor creates a host monitor for store
+func NewMonitor(store storage.StorageInterface, config Config, logger utils.Logger) *Monitor {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = 2 * config.Interval
+	}
+	return &Monitor{
+		store:  store,
+		config: config,
+		logger: logger,
+		now:    time.Now,
+		states: make(map[uint]models.HostLiveState),
+	}
+}
+
+// Run probes all hosts every interval until ctx is done
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Check(ctx); err != nil && ctx.Err() == nil {
+			m.logger.Warn("Failed to probe hosts", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check probes every host once and replaces the cache, dropping deleted hosts
+func (m *Monitor) Check(ctx context.Context) error {
+	hosts, err := m.store.GetHosts(ctx)
+	if err != nil {
+		return err
+	}
+
+	states := m.probe(ctx, hosts, coressh.CandidateProbeTimeout)
+	m.mu.Lock()
+	m.states = states
+	m.mu.Unlock()
+	return nil
+}
+
+// Lookup returns the live state of hosts by ID. Hosts without a result
+// younger than the maximum age are probed first.
+func (m *Monitor) Lookup(ctx context.Context, hosts []models.Host) map[uint]models.HostLiveState {
+	states := make(map[uint]models.HostLiveState, len(hosts))
+	var stale []models.Host
+
+	m.mu.RLock()
+	for _, host := range hosts {
+		state, ok := m.states[host.ID]
+		if !ok || state.CheckedAt == nil || m.now().Sub(*state.CheckedAt) > m.config.MaxAge {
+			stale = append(stale, host)
+			continue
+		}
+		states[host.ID] = state
+	}
+	m.mu.RUnlock()
+
+	if len(stale) == 0 {
+		return states
+	}
+	probed := m.probe(ctx, stale, readThroughTimeout)
+	// Probes cut short by the request going away say nothing about the hosts
+	cache := ctx.Err() == nil
+	m.mu.Lock()
+	for id, state := range probed {
+		if cache {
+			m.states[id] = state
+		}
+		states[id] = state
+	}
+	m.mu.Unlock()
+	return states
+}
+
+// Forget drops the cached state of a host, for hosts that were deleted or
+// moved to another address
+func (m *Monitor) Forget(hostID uint) {
+	m.mu.Lock()
+	delete(m.states, hostID)
+	m.mu.Unlock()
+}
+
+// probe measures the connect latency of the hosts' SSH ports. Hosts sharing
+// an address are probed once. Hosts reached through a proxy cannot be
+// probed and are reported as unknown.
+func (m *Monitor) probe(ctx context.Context, hosts []models.Host, timeout time.Duration) map[uint]models.HostLiveState {
+	states := make(map[uint]models.HostLiveState, len(hosts))
+	byEndpoint := make(map[models.SSHEndpoint][]uint)
+	var endpoints []models.SSHEndpoint
+
+	for _, host := range hosts {
+		if host.ProxyCommand != "" || host.ProxyURL != "" {
+			checkedAt := m.now()
+			states[host.ID] = models.HostLiveState{Reachability: models.HostUnprobed, CheckedAt: &checkedAt}
+			continue
+		}
+		endpoint := models.SSHEndpoint{Host: host.Hostname, Port: host.Port}
+		if endpoint.Port == 0 {
+			endpoint.Port = 22
+		}
+		if _, ok := byEndpoint[endpoint]; !ok {
+			endpoints = append(endpoints, endpoint)
+		}
+		byEndpoint[endpoint] = append(byEndpoint[endpoint], host.ID)
+	}
+
+	for _, status := range coressh.ProbeEndpoints(ctx, endpoints, timeout) {
+		checkedAt := status.CheckedAt
+		state := models.HostLiveState{
+			Reachability: models.HostUnreachable,
+			Error:        status.Error,
+			CheckedAt:    &checkedAt,
+		}
+		if status.Healthy {
+			ms := float64(status.Latency) / float64(time.Millisecond)
+			state.Reachability = models.HostReachable
+			state.LatencyMs = &ms
+		}
+		for _, id := range byEndpoint[status.Endpoint] {
+			states[id] = state
+		}
+	}
+	return states
+}
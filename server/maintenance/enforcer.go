@@ -0,0 +1,132 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// stoppedMessage is recorded on sessions and ports stopped by a window
+const stoppedMessage = "stopped for maintenance window"
+
+// OnPortStopped registers a callback run after a port is stopped by a
+// window, e.g. to withdraw its DNS name
+func (c *Checker) OnPortStopped(fn func(ctx context.Context, portID uint)) {
+	c.onPortStopped = fn
+}
+
+// Run stops tunnels covered by stop windows every interval until ctx is done
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Enforce(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Warn("Failed to enforce maintenance windows", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Enforce stops the active sessions and ports of hosts and groups that are
+// inside a window with the stop action
+func (c *Checker) Enforce(ctx context.Context) error {
+	now := c.now()
+
+	groups, err := c.store.GetGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load groups: %w", err)
+	}
+	byID := make(map[uint]*models.Group, len(groups))
+	for i := range groups {
+		byID[groups[i].ID] = &groups[i]
+	}
+	stopGroups := make(map[uint]models.MaintenanceStatus)
+	for i := range groups {
+		if status := groupStatus(groupChain(byID, groups[i].ID), now); status.Action == models.MaintenanceActionStop {
+			stopGroups[groups[i].ID] = status
+		}
+	}
+
+	hosts, err := c.store.GetHosts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load hosts: %w", err)
+	}
+	stopHosts := make(map[uint]models.MaintenanceStatus)
+	for i := range hosts {
+		if status := hostStatus(&hosts[i], groupChain(byID, hosts[i].GroupID), now); status.Action == models.MaintenanceActionStop {
+			stopHosts[hosts[i].ID] = status
+		}
+	}
+
+	if len(stopHosts) == 0 && len(stopGroups) == 0 {
+		return nil
+	}
+
+	sessions, err := c.store.GetActiveTunnelSessions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load sessions: %w", err)
+	}
+	for i := range sessions {
+		session := &sessions[i]
+		status, stop := stopHosts[session.HostID]
+		if !stop {
+			continue
+		}
+		session.Status = models.StatusStopped
+		session.EndTime = &now
+		session.ErrorMessage = stoppedMessage
+		if err := c.store.UpdateTunnelSession(ctx, session); err != nil {
+			c.logger.Warn("Failed to stop session for maintenance", "session_id", session.ID, "error", err)
+			continue
+		}
+		c.logger.Info("Session stopped for maintenance window", "session_id", session.ID, "host_id", session.HostID, "window", status.Window)
+	}
+
+	ports, err := c.store.GetPorts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load ports: %w", err)
+	}
+	for i := range ports {
+		port := &ports[i]
+		if !port.IsActive() {
+			continue
+		}
+		// A port follows its host's windows when it has one, as in PortStatus
+		var status models.MaintenanceStatus
+		var stop bool
+		if port.HostID != nil {
+			status, stop = stopHosts[*port.HostID]
+		} else {
+			status, stop = stopGroups[port.GroupID]
+		}
+		if !stop {
+			continue
+		}
+
+		if err := port.TransitionTo(models.PortStatusAvailable, nil); err != nil {
+			c.logger.Warn("Failed to stop port for maintenance", "port_id", port.ID, "error", err)
+			continue
+		}
+		port.StatusMessage = stoppedMessage
+		if err := c.store.UpdatePort(ctx, port); err != nil {
+			c.logger.Warn("Failed to stop port for maintenance", "port_id", port.ID, "error", err)
+			continue
+		}
+		c.logger.Info("Port stopped for maintenance window", "port_id", port.ID, "scope", status.Scope, "scope_id", status.ScopeID, "window", status.Window)
+		if c.onPortStopped != nil {
+			c.onPortStopped(ctx, port.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+// Package maintenance evaluates host and group maintenance windows: tunnel
+// starts are refused while a window is open, tunnels are stopped during
+// windows with the stop action, and alerts for the affected hosts are muted.
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// DefaultInterval is how often the enforcer checks for stop windows
+const DefaultInterval = time.Minute
+
+// ErrInMaintenance is returned when a start is refused by a maintenance window
+var ErrInMaintenance = errors.New("maintenance window is active")
+
+// Scopes a window can be defined on
+const (
+	ScopeHost  = "host"
+	ScopeGroup = "group"
+)
+
+// Checker evaluates maintenance windows against the stored hosts and groups
+type Checker struct {
+	store  storage.StorageInterface
+	logger utils.Logger
+	now    func() time.Time
+
+	onPortStopped func(ctx context.Context, portID uint)
+}
+
+// NewChecker creates a checker for store
+func NewChecker(store storage.StorageInterface, logger utils.Logger) *Checker {
+	return &Checker{store: store, logger: logger, now: time.Now}
+}
+
+// HostStatus returns the maintenance status of a host, taking the windows of
+// the host, its group and the group's ancestors into account
+func (c *Checker) HostStatus(ctx context.Context, hostID uint) (models.MaintenanceStatus, error) {
+	host, err := c.store.GetHost(ctx, hostID)
+	if err != nil {
+		return models.MaintenanceStatus{}, fmt.Errorf("failed to load host %d: %w", hostID, err)
+	}
+	chain, err := c.groupChain(ctx, &host.Group)
+	if err != nil {
+		return models.MaintenanceStatus{}, err
+	}
+	return hostStatus(host, chain, c.now()), nil
+}
+
+// GroupStatus returns the maintenance status of a group, including windows
+// inherited from its ancestor groups
+func (c *Checker) GroupStatus(ctx context.Context, groupID uint) (models.MaintenanceStatus, error) {
+	group, err := c.store.GetGroup(ctx, groupID)
+	if err != nil {
+		return models.MaintenanceStatus{}, fmt.Errorf("failed to load group %d: %w", groupID, err)
+	}
+	chain, err := c.groupChain(ctx, group)
+	if err != nil {
+		return models.MaintenanceStatus{}, err
+	}
+	return groupStatus(chain, c.now()), nil
+}
+
+// groupChain loads the ancestors of a group and returns them after it
+func (c *Checker) groupChain(ctx context.Context, group *models.Group) ([]models.Group, error) {
+	ancestors, err := c.store.GetGroupAncestors(ctx, group.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ancestors of group %d: %w", group.ID, err)
+	}
+	return append([]models.Group{*group}, ancestors...), nil
+}
+
+// PortStatus returns the status governing a port: its host's when it has
+// one, otherwise its group's
+func (c *Checker) PortStatus(ctx context.Context, port *models.Port) (models.MaintenanceStatus, error) {
+	if port.HostID != nil {
+		return c.HostStatus(ctx, *port.HostID)
+	}
+	return c.GroupStatus(ctx, port.GroupID)
+}
+
+// Muted reports whether alerts for a host are silenced by an open window
+func (c *Checker) Muted(ctx context.Context, hostID uint) bool {
+	status, err := c.HostStatus(ctx, hostID)
+	return err == nil && status.AlertsMuted
+}
+
+// Active lists every host and group with an open window
+func (c *Checker) Active(ctx context.Context) ([]models.MaintenanceStatus, error) {
+	now := c.now()
+
+	groups, err := c.store.GetGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load groups: %w", err)
+	}
+	hosts, err := c.store.GetHosts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load hosts: %w", err)
+	}
+
+	active := []models.MaintenanceStatus{}
+	for i := range groups {
+		// Subgroups only inheriting an ancestor window are covered by the ancestor entry
+		if status := scopedStatus(groups[i].MaintenanceWindows, ScopeGroup, groups[i].ID, groups[i].Name, now); status.Active {
+			active = append(active, status)
+		}
+	}
+	for i := range hosts {
+		// Hosts only inheriting a group window are covered by the group entry
+		if status := scopedStatus(hosts[i].MaintenanceWindows, ScopeHost, hosts[i].ID, hosts[i].Name, now); status.Active {
+			active = append(active, status)
+		}
+	}
+	return active, nil
+}
+
+// Error describes a refused start for API responses
+func Error(what string, status models.MaintenanceStatus) error {
+	window := status.Window
+	if window == "" {
+		window = status.Schedule
+	}
+	until := ""
+	if status.Until != nil {
+		until = " until " + status.Until.UTC().Format(time.RFC3339)
+	}
+	return fmt.Errorf("%w: cannot start %s, %s %q is in maintenance window %q%s",
+		ErrInMaintenance, what, status.Scope, status.ScopeName, window, until)
+}
+
+// hostStatus combines host windows with those of its group chain; the
+// stronger action wins
+func hostStatus(host *models.Host, chain []models.Group, now time.Time) models.MaintenanceStatus {
+	own := scopedStatus(host.MaintenanceWindows, ScopeHost, host.ID, host.Name, now)
+	return stronger(own, groupStatus(chain, now))
+}
+
+// groupStatus evaluates the windows of a group chain, the group followed by
+// its ancestors nearest first; the stronger action wins, the nearer group on
+// a tie
+func groupStatus(chain []models.Group, now time.Time) models.MaintenanceStatus {
+	var status models.MaintenanceStatus
+	for i := range chain {
+		status = stronger(status, scopedStatus(chain[i].MaintenanceWindows, ScopeGroup, chain[i].ID, chain[i].Name, now))
+	}
+	return status
+}
+
+// stronger returns the status with the stronger action, a on a tie
+func stronger(a, b models.MaintenanceStatus) models.MaintenanceStatus {
+	if !b.Active || (a.Active && (a.Action == models.MaintenanceActionStop || b.Action != models.MaintenanceActionStop)) {
+		return a
+	}
+	return b
+}
+
+// groupChain returns a group followed by its ancestors, looked up in byID
+func groupChain(byID map[uint]*models.Group, id uint) []models.Group {
+	var chain []models.Group
+	// The length bound stops at a corrupt, cyclic parent chain
+	for group := byID[id]; group != nil && len(chain) <= len(byID); {
+		chain = append(chain, *group)
+		if group.ParentID == nil {
+			break
+		}
+		group = byID[*group.ParentID]
+	}
+	return chain
+}
+
+// scopedStatus evaluates windows and records where they are defined
+func scopedStatus(windows []models.MaintenanceWindow, scope string, id uint, name string, now time.Time) models.MaintenanceStatus {
+	status := models.MaintenanceStatusAt(windows, now)
+	if status.Active {
+		status.Scope = scope
+		status.ScopeID = id
+		status.ScopeName = name
+	}
+	return status
+}
@@ -1,12 +1,23 @@
 package middleware
 
 import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/aqz236/port-fly/core/telemetry"
 	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/core/version"
 )
 
 // RequestID middleware adds a unique request ID to each request
@@ -22,6 +33,48 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
+// Tracing middleware starts an OpenTelemetry span for each request,
+// continuing any trace propagated by the caller
+func Tracing() gin.HandlerFunc {
+	tracer := telemetry.Tracer("server")
+
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("client.address", c.ClientIP()),
+			),
+		)
+		defer span.End()
+
+		if requestID, ok := c.Get("request_id"); ok {
+			span.SetAttributes(attribute.String("request.id", fmt.Sprint(requestID)))
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+		if len(c.Errors) > 0 {
+			span.RecordError(c.Errors.Last())
+		}
+	}
+}
+
 // Logger middleware logs HTTP requests
 func Logger(logger utils.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -55,7 +108,8 @@ func ErrorHandler(logger utils.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				logger.Error("Panic recovered: %v", err)
+				logger.Error("Panic recovered", "panic", err)
+				utils.ReportPanic(c.Request.Method+" "+c.FullPath(), err)
 				c.JSON(500, gin.H{"error": "Internal server error"})
 				c.Abort()
 			}
@@ -63,3 +117,34 @@ func ErrorHandler(logger utils.Logger) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// Recovery is gin.Recovery that also reports the panic, so the server writes
+// a diagnostic bundle for it
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, err any) {
+		utils.ReportPanic(c.Request.Method+" "+c.FullPath(), err)
+		c.AbortWithStatus(500)
+	})
+}
+
+// Version adds the server's version and API revision to every response and
+// refuses API requests from clients whose API revision the server no longer
+// serves with 426 Upgrade Required. Clients that do not send their revision
+// and the version endpoint itself are always served.
+func Version(server version.Info) gin.HandlerFunc {
+	apiRevision := strconv.Itoa(server.APIRevision)
+	return func(c *gin.Context) {
+		c.Header(version.HeaderVersion, server.Version)
+		c.Header(version.HeaderAPIRevision, apiRevision)
+
+		revision, err := strconv.Atoi(c.GetHeader(version.HeaderAPIRevision))
+		if err == nil && !strings.HasSuffix(c.Request.URL.Path, "/version") {
+			client := version.Info{Version: c.GetHeader(version.HeaderVersion), APIRevision: revision}
+			if err := version.CheckAPI("server", server, "client", client); err != nil {
+				c.AbortWithStatusJSON(http.StatusUpgradeRequired, gin.H{"success": false, "error": err.Error()})
+				return
+			}
+		}
+		c.Next()
+	}
+}
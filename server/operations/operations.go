@@ -0,0 +1,312 @@
+// Package operations tracks long-running commands, such as starting a port's
+// tunnel, that return before they finish. Each command gets an operation ID;
+// its phases are kept for polling and published to watchers as they happen,
+// so clients learn when a tunnel is really up instead of when it was asked to.
+package operations
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// Event types of operation updates on the event WebSocket
+const (
+	EventProgress  models.EventType = "operation.progress"  // the operation entered a new phase
+	EventCompleted models.EventType = "operation.completed" // the operation succeeded or failed
+)
+
+// Finished operations are kept this long, and at most maxFinished of them
+const (
+	DefaultRetention = time.Hour
+	maxFinished      = 1000
+)
+
+// watcherBufferSize is the per-watcher channel capacity; slow watchers drop events
+const watcherBufferSize = 64
+
+// Operation errors
+var (
+	ErrNotFound = errors.New("operation not found")
+	ErrBusy     = errors.New("port has an operation in progress")
+)
+
+// State is where an operation is in its life
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+)
+
+// Phase is a step of an operation. Starting a port goes queued, connecting,
+// handshake, listener_bound, healthy; stopping goes queued, stopping, stopped.
+// Ports with hooks pass through hooks before healthy or stopped.
+type Phase string
+
+const (
+	PhaseQueued        Phase = "queued"
+	PhaseConnecting    Phase = "connecting"
+	PhaseHandshake     Phase = "handshake"
+	PhaseListenerBound Phase = "listener_bound"
+	PhaseHooks         Phase = "hooks"
+	PhaseHealthy       Phase = "healthy"
+	PhaseStopping      Phase = "stopping"
+	PhaseStopped       Phase = "stopped"
+)
+
+// Step records when an operation entered a phase
+type Step struct {
+	Phase   Phase     `json:"phase"`
+	Message string    `json:"message,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Operation is a command running in the background
+type Operation struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"` // e.g. port.start, port.stop
+	PortID     uint       `json:"port_id,omitempty"`
+	Actor      string     `json:"actor,omitempty"`
+	State      State      `json:"state"`
+	Phase      Phase      `json:"phase"`
+	Steps      []Step     `json:"steps"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Done reports whether the operation succeeded or failed
+func (o *Operation) Done() bool {
+	return o.State == StateSucceeded || o.State == StateFailed
+}
+
+// clone copies the operation so callers cannot race with its updates
+func (o *Operation) clone() Operation {
+	c := *o
+	c.Steps = append([]Step(nil), o.Steps...)
+	return c
+}
+
+// Event is an operation update sent to watchers
+type Event struct {
+	Type      models.EventType `json:"type"`
+	Operation Operation        `json:"operation"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+// Report moves the operation to phase; message explains it, or is empty
+type Report func(phase Phase, message string)
+
+// Tracker runs operations and keeps their progress
+type Tracker struct {
+	logger    utils.Logger
+	retention time.Duration
+
+	mu         sync.Mutex
+	operations map[string]*Operation
+	watchers   map[chan Event]struct{}
+}
+
+// NewTracker creates a tracker keeping finished operations for retention
+func NewTracker(retention time.Duration, logger utils.Logger) *Tracker {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Tracker{
+		logger:     logger,
+		retention:  retention,
+		operations: make(map[string]*Operation),
+		watchers:   make(map[chan Event]struct{}),
+	}
+}
+
+// Start runs fn in the background as a new operation and returns it in the
+// queued phase. fn reports its phases; its error fails the operation. Only
+// the values of ctx are kept, fn is bounded by timeout instead. A port runs
+// one operation at a time; while one is in progress, Start returns it with
+// ErrBusy.
+func (t *Tracker) Start(ctx context.Context, kind string, portID uint, actor string, timeout time.Duration, fn func(ctx context.Context, report Report) error) (Operation, error) {
+	now := time.Now()
+	op := &Operation{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		PortID:    portID,
+		Actor:     actor,
+		State:     StatePending,
+		Phase:     PhaseQueued,
+		Steps:     []Step{{Phase: PhaseQueued, At: now}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	t.mu.Lock()
+	for _, other := range t.operations {
+		if portID != 0 && other.PortID == portID && !other.Done() {
+			busy := other.clone()
+			t.mu.Unlock()
+			return busy, ErrBusy
+		}
+	}
+	t.prune(now)
+	t.operations[op.ID] = op
+	queued := op.clone()
+	t.mu.Unlock()
+	t.publish(EventProgress, queued)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		var err error
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = errors.New("operation panicked")
+					t.logger.Error("Operation panic recovered", "operation_id", op.ID, "panic", r)
+					utils.ReportPanic("operation "+op.Kind, r)
+				}
+			}()
+			err = fn(ctx, func(phase Phase, message string) { t.advance(op.ID, phase, message) })
+		}()
+		t.finish(op.ID, err)
+	}()
+	return queued, nil
+}
+
+// advance records that an operation entered phase
+func (t *Tracker) advance(id string, phase Phase, message string) {
+	t.mu.Lock()
+	op, ok := t.operations[id]
+	if !ok || op.Done() || (op.Phase == phase && message == "") {
+		t.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	op.State = StateRunning
+	op.Phase = phase
+	op.Steps = append(op.Steps, Step{Phase: phase, Message: message, At: now})
+	op.UpdatedAt = now
+	update := op.clone()
+	t.mu.Unlock()
+
+	t.publish(EventProgress, update)
+}
+
+// finish ends an operation, failing it when err is not nil
+func (t *Tracker) finish(id string, err error) {
+	t.mu.Lock()
+	op, ok := t.operations[id]
+	if !ok {
+		t.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	op.State = StateSucceeded
+	if err != nil {
+		op.State = StateFailed
+		op.Error = err.Error()
+	}
+	op.UpdatedAt = now
+	op.FinishedAt = &now
+	update := op.clone()
+	t.mu.Unlock()
+
+	if err != nil {
+		t.logger.Warn("Operation failed", "operation_id", id, "kind", update.Kind, "port_id", update.PortID, "phase", update.Phase, "error", err)
+	} else {
+		t.logger.Info("Operation succeeded", "operation_id", id, "kind", update.Kind, "port_id", update.PortID)
+	}
+	t.publish(EventCompleted, update)
+}
+
+// Get returns an operation by ID
+func (t *Tracker) Get(id string) (Operation, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	op, ok := t.operations[id]
+	if !ok {
+		return Operation{}, ErrNotFound
+	}
+	return op.clone(), nil
+}
+
+// List returns the operations, newest first; portID 0 lists those of all ports
+func (t *Tracker) List(portID uint) []Operation {
+	t.mu.Lock()
+	list := make([]Operation, 0, len(t.operations))
+	for _, op := range t.operations {
+		if portID == 0 || op.PortID == portID {
+			list = append(list, op.clone())
+		}
+	}
+	t.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// Watch returns a channel of operation updates and a function to stop them
+func (t *Tracker) Watch() (<-chan Event, func()) {
+	watcher := make(chan Event, watcherBufferSize)
+	t.mu.Lock()
+	t.watchers[watcher] = struct{}{}
+	t.mu.Unlock()
+
+	var once sync.Once
+	return watcher, func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.watchers, watcher)
+			t.mu.Unlock()
+		})
+	}
+}
+
+// publish delivers an update to the watchers without blocking
+func (t *Tracker) publish(eventType models.EventType, op Operation) {
+	event := Event{Type: eventType, Operation: op, Timestamp: time.Now()}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for watcher := range t.watchers {
+		select {
+		case watcher <- event:
+		default:
+			// Watcher is not keeping up; it can still poll the operation
+		}
+	}
+}
+
+// prune forgets finished operations past the retention, and the oldest ones
+// beyond maxFinished. The caller holds t.mu.
+func (t *Tracker) prune(now time.Time) {
+	var finished []*Operation
+	for id, op := range t.operations {
+		if op.FinishedAt == nil {
+			continue
+		}
+		if now.Sub(*op.FinishedAt) > t.retention {
+			delete(t.operations, id)
+			continue
+		}
+		finished = append(finished, op)
+	}
+	if len(finished) < maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(*finished[j].FinishedAt) })
+	for _, op := range finished[:len(finished)-maxFinished+1] {
+		delete(t.operations, op.ID)
+	}
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/agents"
+	"github.com/aqz236/port-fly/server/health"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// slowStorageThreshold is the storage ping latency reported as degraded
+const slowStorageThreshold = 250 * time.Millisecond
+
+// registerHealthProbes adds a probe for each subsystem to the health registry
+func (s *Server) registerHealthProbes(agentHub *agents.Hub) {
+	s.health.Register("storage", s.storageProbe)
+	s.health.Register("sessions", s.sessionsProbe)
+	s.health.Register("monitor", s.monitorProbe)
+	s.health.Register("pool", s.poolProbe)
+	s.health.Register("event_bus", s.eventBusProbe)
+	s.health.Register("http", s.httpProbe)
+	s.health.Register("exec", s.execProbe)
+	s.health.Register("relay", func(ctx context.Context) health.Check {
+		if agentHub == nil {
+			return health.Disabled()
+		}
+		return health.OK(map[string]any{"agents": len(agentHub.List())})
+	})
+	s.health.Register("cluster", s.clusterProbe)
+	s.health.Register("grpc", func(ctx context.Context) health.Check {
+		if s.grpc == nil {
+			return health.Disabled()
+		}
+		return health.OK(map[string]any{"address": s.grpc.Address()})
+	})
+}
+
+// storageProbe pings the database and reports slow responses
+func (s *Server) storageProbe(ctx context.Context) health.Check {
+	start := time.Now()
+	err := s.storage.Health()
+	latency := time.Since(start)
+
+	details := map[string]any{
+		"ping_seconds": latency.Seconds(),
+		"driver":       storage.DriverName(s.config.StorageConfig.Type),
+		"drivers":      storage.Drivers(),
+	}
+	if cached, ok := s.storage.(*storage.CachedStorage); ok {
+		stats := cached.Stats()
+		details["cache_hits_total"] = stats.Hits
+		details["cache_misses_total"] = stats.Misses
+	}
+	if reporter, ok := s.storage.(storage.QueryStatsReporter); ok {
+		if stats := reporter.QueryStats(); stats.Enabled {
+			details["queries_total"] = stats.Queries
+			details["query_errors_total"] = stats.Errors
+			details["slow_queries_total"] = stats.Slow
+			details["query_seconds_total"] = stats.TotalSeconds
+			if stats.Pool != nil {
+				details["pool_open_connections"] = stats.Pool.Open
+				details["pool_in_use_connections"] = stats.Pool.InUse
+				details["pool_wait_total"] = stats.Pool.WaitCount
+			}
+		}
+	}
+
+	if err != nil {
+		return health.Down(err.Error(), details)
+	}
+	if latency > slowStorageThreshold {
+		return health.Degraded(fmt.Sprintf("storage ping took %s", latency.Round(time.Millisecond)), details)
+	}
+	return health.OK(details)
+}
+
+// sessionsProbe counts tunnel sessions recorded as active
+func (s *Server) sessionsProbe(ctx context.Context) health.Check {
+	active, err := s.storage.GetActiveTunnelSessions(ctx)
+	if err != nil {
+		return health.Down("failed to count active sessions: "+err.Error(), nil)
+	}
+	return health.OK(map[string]any{"active": len(active)})
+}
+
+// monitorProbe reports sessions the session monitor could not keep connected
+func (s *Server) monitorProbe(ctx context.Context) health.Check {
+	sessions, err := s.sessionManager.ListSessions()
+	if err != nil {
+		return health.Down(err.Error(), nil)
+	}
+
+	var running, reconnecting, failed int
+	for _, session := range sessions {
+		switch session.Status {
+		case models.StatusError:
+			failed++
+		case models.StatusBackingOff, models.StatusDisconnected:
+			reconnecting++
+		case models.StatusConnected, models.StatusActive:
+			running++
+		}
+	}
+
+	details := map[string]any{"running": running, "reconnecting": reconnecting, "failed": failed}
+	if failed > 0 {
+		return health.Degraded(fmt.Sprintf("%d sessions failed", failed), details)
+	}
+	return health.OK(details)
+}
+
+// execProbe reports the SSH connections shared by commands run on hosts,
+// exported as portfly_exec_queued, portfly_exec_commands_total, …
+func (s *Server) execProbe(ctx context.Context) health.Check {
+	stats := s.handlers.ExecStats()
+	return health.OK(map[string]any{
+		"connections":              stats.Connections,
+		"sessions":                 stats.Sessions,
+		"queued":                   stats.Queued,
+		"commands_total":           stats.Commands,
+		"connections_total":        stats.ConnectionsOpened,
+		"reused_total":             stats.Reused,
+		"sessions_refused_total":   stats.SessionsRefused,
+		"queue_wait_seconds_total": stats.QueueWaitSeconds,
+	})
+}
+
+// httpProbe sums the requests seen by sessions that inspect HTTP, exported as
+// portfly_http_requests_total, portfly_http_responses_<class>_total, …
+func (s *Server) httpProbe(ctx context.Context) health.Check {
+	sessions, err := s.sessionManager.ListSessions()
+	if err != nil {
+		return health.Down(err.Error(), nil)
+	}
+
+	total := models.HTTPStats{Responses: make(map[string]int64)}
+	inspecting := 0
+	for _, session := range sessions {
+		if !session.TunnelConfig.InspectHTTP {
+			continue
+		}
+		inspecting++
+		stats, err := s.sessionManager.GetSessionStats(session.ID)
+		if err != nil || stats.HTTP == nil {
+			continue
+		}
+		total.Requests += stats.HTTP.Requests
+		total.TotalDuration += stats.HTTP.TotalDuration
+		for class, count := range stats.HTTP.Responses {
+			total.Responses[class] += count
+		}
+	}
+	if inspecting == 0 {
+		return health.Disabled()
+	}
+
+	details := map[string]any{
+		"sessions":               inspecting,
+		"requests_total":         total.Requests,
+		"duration_seconds_total": total.TotalDuration.Seconds(),
+	}
+	for class, count := range total.Responses {
+		details["responses_"+class+"_total"] = count
+	}
+	return health.OK(details)
+}
+
+// poolProbe reports an exhausted SSH connection pool
+func (s *Server) poolProbe(ctx context.Context) health.Check {
+	stats := s.sessionManager.PoolStats()
+	if stats == nil {
+		return health.Disabled()
+	}
+	if inUse, size := stats["in_use"].(int), stats["max_size"].(int); size > 0 && inUse >= size {
+		return health.Degraded(fmt.Sprintf("all %d pooled connections are in use", size), stats)
+	}
+	return health.OK(stats)
+}
+
+// eventBusProbe reports subscribers falling behind on session events
+func (s *Server) eventBusProbe(ctx context.Context) health.Check {
+	stats := s.sessionManager.EventStats()
+	details := map[string]any{
+		"subscribers":   stats.Subscribers,
+		"queued":        stats.Queued,
+		"max_queued":    stats.MaxQueued,
+		"capacity":      stats.Capacity,
+		"dropped_total": stats.Dropped,
+	}
+	if stats.MaxQueued*4 >= stats.Capacity*3 && stats.MaxQueued > 0 {
+		return health.Degraded(fmt.Sprintf("a subscriber queue holds %d of %d events", stats.MaxQueued, stats.Capacity), details)
+	}
+	return health.OK(details)
+}
+
+// clusterProbe reports the tunnel leases held by this replica
+func (s *Server) clusterProbe(ctx context.Context) health.Check {
+	if s.coordinator == nil {
+		return health.Disabled()
+	}
+	status := s.coordinator.Status()
+	return health.OK(map[string]any{
+		"instance_id": status.InstanceID,
+		"watched":     len(status.Watched),
+		"owned":       len(status.Owned),
+	})
+}
@@ -0,0 +1,333 @@
+// Package reconcile brings persisted port state back in line with the
+// tunnels that actually run. Forwards record the instance and process that
+// own them; when that owner is gone, e.g. after a crash, the forward and its
+// ports are reset instead of staying "active" forever.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/cluster"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// DefaultInterval is the time between background reconcile runs
+const DefaultInterval = time.Minute
+
+// Actions taken on an orphan
+const (
+	ActionStopped = "stopped" // forward and its session marked failed
+	ActionReset   = "reset"   // port returned to available
+	ActionSkipped = "skipped" // changed concurrently, left as is
+	ActionFailed  = "failed"
+)
+
+// Options select what a run reconciles
+type Options struct {
+	// Unowned also resets active ports that no live forward backs, such as
+	// ports set active through the status endpoint before a crash
+	Unowned bool `json:"unowned"`
+}
+
+// Orphan is a forward, session or port whose owner is gone
+type Orphan struct {
+	Kind     string `json:"kind"` // port_connection, tunnel_session or port
+	ID       uint   `json:"id"`
+	Owner    string `json:"owner,omitempty"`
+	OwnerPID int    `json:"owner_pid,omitempty"`
+	Action   string `json:"action"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report describes the outcome of a reconcile run
+type Report struct {
+	Options    Options   `json:"options"`
+	Orphans    []Orphan  `json:"orphans"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs float64   `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Reconciler claims forwards for this process and resets those of owners
+// that no longer run
+type Reconciler struct {
+	store      storage.StorageInterface
+	instanceID string
+	pid        int
+	logger     utils.Logger
+
+	// Serializes runs; last is the most recent report
+	mu   sync.Mutex
+	last *Report
+}
+
+// NewReconciler creates a reconciler for this process. instanceID is the
+// cluster instance ID, or empty for a single server, which uses the hostname.
+func NewReconciler(store storage.StorageInterface, instanceID string, logger utils.Logger) *Reconciler {
+	if instanceID == "" {
+		instanceID, _ = os.Hostname()
+	}
+	return &Reconciler{
+		store:      store,
+		instanceID: instanceID,
+		pid:        os.Getpid(),
+		logger:     logger,
+	}
+}
+
+// Claim records this process as the owner of connection
+func (r *Reconciler) Claim(connection *models.PortConnection) {
+	connection.Owner = r.instanceID
+	connection.OwnerPID = r.pid
+}
+
+// InstanceID returns the instance the forwards of this process are claimed under
+func (r *Reconciler) InstanceID() string {
+	return r.instanceID
+}
+
+// Adopt claims the active forwards owned by another process, such as the one
+// this process replaced in an upgrade, so they are not reset once it exits.
+// It returns the number of forwards adopted.
+func (r *Reconciler) Adopt(ctx context.Context, owner string, pid int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	connections, err := r.store.GetActivePortConnections(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	adopted := 0
+	for _, connection := range connections {
+		if connection.Owner != owner || connection.OwnerPID != pid {
+			continue
+		}
+		r.Claim(&connection)
+		if err := r.store.UpdatePortConnection(ctx, &connection); err != nil {
+			return adopted, fmt.Errorf("failed to adopt forward %d: %w", connection.ID, err)
+		}
+		adopted++
+	}
+	return adopted, nil
+}
+
+// Last returns the report of the most recent run, or nil before the first one
+func (r *Reconciler) Last() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// Run reconciles once at start and then every interval until ctx is done
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := r.Reconcile(ctx, Options{})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			r.logger.Error("port reconcile failed", "error", err)
+		} else if len(report.Orphans) > 0 {
+			r.logger.Warn("reset orphaned forwards", "orphans", len(report.Orphans))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile stops active forwards whose owner is gone and resets their
+// ports. Port updates only apply if the status is unchanged since it was
+// read, so a forward started concurrently is left alone. The report is
+// returned, and recorded, even when a step fails part way.
+func (r *Reconciler) Reconcile(ctx context.Context, options Options) (Report, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start := time.Now()
+	report := Report{Options: options, Orphans: []Orphan{}, StartedAt: start.UTC()}
+
+	err := r.reconcile(ctx, options, &report)
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	report.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	r.last = &report
+	return report, err
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, options Options, report *Report) error {
+	live, err := r.liveInstances(ctx)
+	if err != nil {
+		return err
+	}
+
+	connections, err := r.store.GetActivePortConnections(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Ports still backed by a live forward are never reset
+	backed := make(map[uint]bool)
+	var orphaned []models.PortConnection
+	for _, connection := range connections {
+		if r.ownerAlive(connection.Owner, connection.OwnerPID, live) {
+			backed[connection.RemotePortID] = true
+			backed[connection.LocalPortID] = true
+			continue
+		}
+		orphaned = append(orphaned, connection)
+	}
+
+	reset := make(map[uint]bool)
+	for _, connection := range orphaned {
+		report.Orphans = append(report.Orphans, r.stopConnection(ctx, connection)...)
+		for _, port := range []models.Port{connection.RemotePort, connection.LocalPort} {
+			if backed[port.ID] || reset[port.ID] || !holdsTunnel(port.Status) {
+				continue
+			}
+			reset[port.ID] = true
+			orphan := r.resetPort(ctx, port)
+			orphan.Owner, orphan.OwnerPID = connection.Owner, connection.OwnerPID
+			report.Orphans = append(report.Orphans, orphan)
+		}
+	}
+
+	if !options.Unowned {
+		return nil
+	}
+
+	ports, err := r.store.GetPorts(ctx)
+	if err != nil {
+		return err
+	}
+	for _, port := range ports {
+		if backed[port.ID] || reset[port.ID] || !holdsTunnel(port.Status) {
+			continue
+		}
+		report.Orphans = append(report.Orphans, r.resetPort(ctx, port))
+	}
+	return nil
+}
+
+// stopConnection marks an orphaned forward, and its session if any, as stopped
+func (r *Reconciler) stopConnection(ctx context.Context, connection models.PortConnection) []Orphan {
+	message := fmt.Sprintf("owner %s (pid %d) is no longer running", connection.Owner, connection.OwnerPID)
+	if connection.Owner == "" {
+		message = "forward has no recorded owner"
+	}
+	r.logger.Warn("stopping orphaned forward",
+		"connection_id", connection.ID,
+		"owner", connection.Owner,
+		"owner_pid", connection.OwnerPID)
+
+	var orphans []Orphan
+	now := time.Now()
+	orphan := Orphan{Kind: "port_connection", ID: connection.ID, Owner: connection.Owner, OwnerPID: connection.OwnerPID, Action: ActionStopped}
+
+	session := connection.TunnelSession
+	connection.Status = models.PortStatusError
+	connection.StoppedAt = &now
+	if err := r.store.UpdatePortConnection(ctx, &connection); err != nil {
+		orphan.Action, orphan.Error = ActionFailed, err.Error()
+	}
+	orphans = append(orphans, orphan)
+
+	if session != nil && session.Status == models.StatusActive {
+		sessionOrphan := Orphan{Kind: "tunnel_session", ID: session.ID, Owner: connection.Owner, OwnerPID: connection.OwnerPID, Action: ActionStopped}
+		session.Status = models.StatusError
+		session.ErrorMessage = message
+		session.EndTime = &now
+		if err := r.store.UpdateTunnelSession(ctx, session); err != nil {
+			sessionOrphan.Action, sessionOrphan.Error = ActionFailed, err.Error()
+		}
+		orphans = append(orphans, sessionOrphan)
+	}
+	return orphans
+}
+
+// resetPort returns a port to available if nobody changed it meanwhile
+func (r *Reconciler) resetPort(ctx context.Context, port models.Port) Orphan {
+	orphan := Orphan{Kind: "port", ID: port.ID, Action: ActionReset}
+
+	err := r.store.UpdatePortStatus(ctx, port.ID, models.PortStatusAvailable, nil)
+	switch {
+	case errors.Is(err, models.ErrPortStatusChanged):
+		orphan.Action = ActionSkipped
+	case err != nil:
+		orphan.Action, orphan.Error = ActionFailed, err.Error()
+	default:
+		r.logger.Info("reset orphaned port", "port_id", port.ID, "previous_status", port.Status)
+	}
+	return orphan
+}
+
+// liveInstances returns the instances holding an unexpired presence lease
+func (r *Reconciler) liveInstances(ctx context.Context) (map[string]bool, error) {
+	leases, err := r.store.GetLeases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leases: %w", err)
+	}
+
+	now := time.Now()
+	live := make(map[string]bool)
+	for _, lease := range leases {
+		if lease.Name == cluster.InstanceResource(lease.Owner) && !lease.IsExpired(now) {
+			live[lease.Owner] = true
+		}
+	}
+	return live, nil
+}
+
+// ownerAlive reports whether the owner of a forward may still run it. Forwards
+// of this instance are live while their process is; other instances are live
+// while they renew their presence lease.
+func (r *Reconciler) ownerAlive(owner string, pid int, live map[string]bool) bool {
+	switch {
+	case owner == "":
+		return false
+	case owner == r.instanceID:
+		return pid == r.pid || processAlive(pid)
+	default:
+		return live[owner]
+	}
+}
+
+// holdsTunnel reports whether a port status claims a running tunnel
+func holdsTunnel(status models.PortStatus) bool {
+	switch status {
+	case models.PortStatusActive, models.PortStatusConnecting, models.PortStatusBackoff:
+		return true
+	default:
+		return false
+	}
+}
+
+// processAlive reports whether a process with pid exists on this machine
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
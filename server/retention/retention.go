@@ -0,0 +1,129 @@
+// Package retention prunes tunnel session history and persisted session logs
+// older than a configured age, so the database and log directory do not grow
+// without bound.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// Defaults applied to zero config fields
+const (
+	DefaultMaxAge    = 90 * 24 * time.Hour
+	DefaultInterval  = 6 * time.Hour
+	DefaultBatchSize = 500
+)
+
+// Config configures data retention
+type Config struct {
+	Enabled   bool          `json:"enabled"`    // run the pruning job in the background
+	MaxAge    time.Duration `json:"max_age"`    // keep finished sessions and logs this long
+	Interval  time.Duration `json:"interval"`   // time between background runs
+	BatchSize int           `json:"batch_size"` // rows deleted per transaction
+}
+
+// Report describes the outcome of a pruning run
+type Report struct {
+	Cutoff          time.Time `json:"cutoff"`
+	SessionsRemoved int64     `json:"sessions_removed"`
+	LogFilesRemoved int       `json:"log_files_removed"`
+	StartedAt       time.Time `json:"started_at"`
+	DurationMs      float64   `json:"duration_ms"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// Pruner deletes data older than the retention age
+type Pruner struct {
+	store  storage.StorageInterface
+	logs   *utils.LogStore
+	config Config
+	logger utils.Logger
+
+	// Serializes runs; last is the most recent report
+	mu   sync.Mutex
+	last *Report
+}
+
+// NewPruner creates a pruner for store and the persisted logs of logs, which may be nil
+func NewPruner(store storage.StorageInterface, logs *utils.LogStore, config Config, logger utils.Logger) *Pruner {
+	if config.MaxAge <= 0 {
+		config.MaxAge = DefaultMaxAge
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	return &Pruner{store: store, logs: logs, config: config, logger: logger}
+}
+
+// Config returns the effective configuration
+func (p *Pruner) Config() Config {
+	return p.config
+}
+
+// Last returns the report of the most recent run, or nil before the first one
+func (p *Pruner) Last() *Report {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last
+}
+
+// Run prunes once at start and then every interval until ctx is done
+func (p *Pruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	p.logger.Info("data retention started", "max_age", p.config.MaxAge, "interval", p.config.Interval)
+	for {
+		report, err := p.Prune(ctx, p.config.MaxAge)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			p.logger.Error("data pruning failed", "error", err)
+		} else if report.SessionsRemoved > 0 || report.LogFilesRemoved > 0 {
+			p.logger.Info("pruned old data", "sessions", report.SessionsRemoved, "log_files", report.LogFilesRemoved, "cutoff", report.Cutoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune removes finished sessions and log files older than maxAge. The
+// report is returned, and recorded, even when a step fails part way.
+func (p *Pruner) Prune(ctx context.Context, maxAge time.Duration) (Report, error) {
+	if maxAge <= 0 {
+		return Report{}, fmt.Errorf("retention age must be positive")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	start := time.Now()
+	report := Report{Cutoff: start.Add(-maxAge).UTC(), StartedAt: start.UTC()}
+
+	var err error
+	report.SessionsRemoved, err = p.store.PruneTunnelSessions(ctx, report.Cutoff, p.config.BatchSize)
+	if err == nil && p.logs != nil {
+		report.LogFilesRemoved, err = p.logs.PruneFiles(report.Cutoff)
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	report.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	p.last = &report
+	return report, err
+}
@@ -0,0 +1,241 @@
+// Package selfstats reports the server's own resource use: goroutines, heap
+// and garbage collection, and which subsystems the goroutines belong to. A
+// subsystem holding goroutines while it has nothing to run, e.g. tunnel
+// goroutines with no tunnel running, is reported as a suspected leak.
+package selfstats
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LongBlocked is how long a goroutine waits before it counts as blocked long
+const LongBlocked = 10 * time.Minute
+
+// Subsystem attributes goroutines to a part of the server by the functions
+// on their stacks
+type Subsystem struct {
+	Name string
+	// Function name prefixes; a goroutine belongs to the first subsystem one
+	// of whose prefixes appears on its stack or as its creator
+	Functions []string
+	// Owners counts what the subsystem currently runs (tunnels, terminals,
+	// pooled connections); nil when it has nothing to count
+	Owners func() int
+	// Idle is how many goroutines the subsystem keeps with no owners, such
+	// as a cleanup loop
+	Idle int
+}
+
+// SubsystemStats is the goroutine use of one subsystem
+type SubsystemStats struct {
+	Name          string `json:"name"`
+	Goroutines    int    `json:"goroutines"`
+	LongBlocked   int    `json:"long_blocked"` // waiting for LongBlocked or more
+	Owners        *int   `json:"owners,omitempty"`
+	SuspectedLeak bool   `json:"suspected_leak"` // goroutines left with no owners
+}
+
+// HeapStats summarizes runtime.MemStats
+type HeapStats struct {
+	Alloc         uint64     `json:"alloc_bytes"`    // bytes of allocated heap objects
+	InUse         uint64     `json:"in_use_bytes"`   // bytes in in-use spans
+	Idle          uint64     `json:"idle_bytes"`     // bytes in idle spans
+	Released      uint64     `json:"released_bytes"` // bytes returned to the OS
+	Objects       uint64     `json:"objects"`
+	Sys           uint64     `json:"sys_bytes"` // total bytes obtained from the OS
+	StackInUse    uint64     `json:"stack_in_use_bytes"`
+	TotalAlloc    uint64     `json:"total_alloc_bytes"`
+	Mallocs       uint64     `json:"mallocs"`
+	Frees         uint64     `json:"frees"`
+	NextGC        uint64     `json:"next_gc_bytes"`
+	NumGC         uint32     `json:"num_gc"`
+	PauseTotal    string     `json:"gc_pause_total"`
+	LastGC        *time.Time `json:"last_gc,omitempty"`
+	GCCPUFraction float64    `json:"gc_cpu_fraction"`
+}
+
+// Report is a snapshot of the server's resource use
+type Report struct {
+	GoVersion   string           `json:"go_version"`
+	CPUs        int              `json:"cpus"`
+	GOMAXPROCS  int              `json:"gomaxprocs"`
+	Uptime      string           `json:"uptime"`
+	Goroutines  int              `json:"goroutines"`
+	LongBlocked int              `json:"long_blocked"`
+	Heap        HeapStats        `json:"heap"`
+	Subsystems  []SubsystemStats `json:"subsystems"`
+	Other       int              `json:"other_goroutines"` // not attributed to a subsystem
+	CollectedAt time.Time        `json:"collected_at"`
+}
+
+// Collector produces reports for a set of subsystems
+type Collector struct {
+	subsystems []Subsystem
+	started    time.Time
+}
+
+// NewCollector creates a collector attributing goroutines to subsystems
+func NewCollector(subsystems ...Subsystem) *Collector {
+	return &Collector{
+		subsystems: subsystems,
+		started:    time.Now(),
+	}
+}
+
+// Collect takes a snapshot. It stops the world briefly to read the memory
+// statistics and the goroutine stacks, so it is meant for diagnosis rather
+// than frequent polling.
+func (c *Collector) Collect() Report {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	report := Report{
+		GoVersion:   runtime.Version(),
+		CPUs:        runtime.NumCPU(),
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+		Uptime:      time.Since(c.started).Round(time.Second).String(),
+		Heap:        heapStats(&mem),
+		Subsystems:  make([]SubsystemStats, len(c.subsystems)),
+		CollectedAt: time.Now(),
+	}
+	for i, subsystem := range c.subsystems {
+		report.Subsystems[i].Name = subsystem.Name
+	}
+
+	for _, g := range goroutines() {
+		report.Goroutines++
+		long := g.waiting >= LongBlocked
+		if long {
+			report.LongBlocked++
+		}
+
+		i := c.subsystemOf(g)
+		if i < 0 {
+			report.Other++
+			continue
+		}
+		report.Subsystems[i].Goroutines++
+		if long {
+			report.Subsystems[i].LongBlocked++
+		}
+	}
+
+	for i, subsystem := range c.subsystems {
+		if subsystem.Owners == nil {
+			continue
+		}
+		owners := subsystem.Owners()
+		stats := &report.Subsystems[i]
+		stats.Owners = &owners
+		stats.SuspectedLeak = owners == 0 && stats.Goroutines > subsystem.Idle
+	}
+	return report
+}
+
+func (c *Collector) subsystemOf(g goroutine) int {
+	for i, subsystem := range c.subsystems {
+		for _, fn := range g.functions {
+			for _, prefix := range subsystem.Functions {
+				if strings.HasPrefix(fn, prefix) {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+func heapStats(mem *runtime.MemStats) HeapStats {
+	stats := HeapStats{
+		Alloc:         mem.HeapAlloc,
+		InUse:         mem.HeapInuse,
+		Idle:          mem.HeapIdle,
+		Released:      mem.HeapReleased,
+		Objects:       mem.HeapObjects,
+		Sys:           mem.Sys,
+		StackInUse:    mem.StackInuse,
+		TotalAlloc:    mem.TotalAlloc,
+		Mallocs:       mem.Mallocs,
+		Frees:         mem.Frees,
+		NextGC:        mem.NextGC,
+		NumGC:         mem.NumGC,
+		PauseTotal:    time.Duration(mem.PauseTotalNs).String(),
+		GCCPUFraction: mem.GCCPUFraction,
+	}
+	if mem.LastGC > 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC))
+		stats.LastGC = &lastGC
+	}
+	return stats
+}
+
+// goroutine is one entry of a full stack dump
+type goroutine struct {
+	waiting   time.Duration // as reported by the runtime, in whole minutes
+	functions []string      // the stack's functions, innermost first, then the creator
+}
+
+// goroutineHeader matches "goroutine 7 [chan receive, 12 minutes]:"
+var goroutineHeader = regexp.MustCompile(`^goroutine \d+ \[([^\]]*)\]:$`)
+
+// goroutines dumps and parses the stacks of all goroutines
+func goroutines() []goroutine {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	return parseStacks(buf)
+}
+
+// parseStacks parses the output of runtime.Stack for all goroutines. Each
+// frame is a function line followed by a tab-indented file line; the last
+// line names the function that started the goroutine.
+func parseStacks(dump []byte) []goroutine {
+	var result []goroutine
+
+	scanner := bufio.NewScanner(bytes.NewReader(dump))
+	scanner.Buffer(make([]byte, 64*1024), len(dump)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := goroutineHeader.FindStringSubmatch(line); match != nil {
+			result = append(result, goroutine{waiting: waitTime(match[1])})
+			continue
+		}
+		if len(result) == 0 || line == "" || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "created by ")
+		if i := strings.LastIndex(line, "("); i > 0 && strings.HasSuffix(line, ")") {
+			line = line[:i]
+		} else if i := strings.Index(line, " in goroutine "); i > 0 {
+			line = line[:i]
+		}
+		current := &result[len(result)-1]
+		current.functions = append(current.functions, line)
+	}
+	return result
+}
+
+// waitTime extracts the wait from a goroutine state such as
+// "select, 5 minutes" or "IO wait, 1 minutes, locked to thread"
+func waitTime(state string) time.Duration {
+	for _, part := range strings.Split(state, ", ") {
+		if minutes, ok := strings.CutSuffix(part, " minutes"); ok {
+			if n, err := strconv.Atoi(minutes); err == nil {
+				return time.Duration(n) * time.Minute
+			}
+		}
+	}
+	return 0
+}
@@ -3,9 +3,13 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,51 +17,133 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
+	"github.com/aqz236/port-fly/core/agent"
+	"github.com/aqz236/port-fly/core/localdns"
 	"github.com/aqz236/port-fly/core/manager"
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/secrets"
+	"github.com/aqz236/port-fly/core/telemetry"
 	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/core/version"
+	"github.com/aqz236/port-fly/server/agents"
+	"github.com/aqz236/port-fly/server/authfailures"
+	"github.com/aqz236/port-fly/server/backup"
+	"github.com/aqz236/port-fly/server/cloudsync"
+	"github.com/aqz236/port-fly/server/cluster"
+	"github.com/aqz236/port-fly/server/diagnostics"
+	"github.com/aqz236/port-fly/server/failover"
+	"github.com/aqz236/port-fly/server/grants"
+	"github.com/aqz236/port-fly/server/grpcapi"
 	"github.com/aqz236/port-fly/server/handlers"
+	"github.com/aqz236/port-fly/server/health"
+	"github.com/aqz236/port-fly/server/hoststatus"
+	"github.com/aqz236/port-fly/server/maintenance"
 	"github.com/aqz236/port-fly/server/middleware"
+	"github.com/aqz236/port-fly/server/operations"
+	"github.com/aqz236/port-fly/server/reconcile"
+	"github.com/aqz236/port-fly/server/retention"
+	"github.com/aqz236/port-fly/server/selfstats"
+	"github.com/aqz236/port-fly/server/setup"
+	"github.com/aqz236/port-fly/server/statuspage"
 	"github.com/aqz236/port-fly/server/storage"
+	"github.com/aqz236/port-fly/server/upgrade"
 )
 
 // Server represents the HTTP API server
 type Server struct {
 	config          *Config
 	router          *gin.Engine
+	adminRouter     *gin.Engine // nil unless the admin API has its own socket
 	storage         storage.StorageInterface
 	sessionManager  *manager.SessionManager
 	terminalManager *handlers.TerminalManager
+	handlers        *handlers.Handlers
 	logger          utils.Logger
+	shutdownTracing func(context.Context) error
 	upgrader        websocket.Upgrader
+	localDNS        *localdns.Manager
+	coordinator     *cluster.Coordinator
+	backups         *backup.Manager
+	pruner          *retention.Pruner
+	reconciler      *reconcile.Reconciler
+	maintenance     *maintenance.Checker
+	grants          *grants.Manager
+	failover        *failover.Monitor
+	hostStatus      *hoststatus.Monitor
+	statusPage      *statuspage.Monitor // nil when the status page is disabled
+	cloudSync       *cloudsync.Syncer   // nil when no cloud sync source is configured
+	grpc            *grpcapi.Server     // nil when the gRPC API is disabled
+	health          *health.Registry
+	upgrades        *upgrade.Upgrader
 }
 
 // Config holds server configuration
 type Config struct {
-	Host            string                `json:"host"`
-	Port            int                   `json:"port"`
-	Mode            string                `json:"mode"` // debug, release, test
-	EnableCORS      bool                  `json:"enable_cors"`
-	CORSOrigins     []string              `json:"cors_origins"`
-	EnableWebSocket bool                  `json:"enable_websocket"`
-	JWTSecret       string                `json:"jwt_secret"`
-	StorageConfig   storage.StorageConfig `json:"storage"`
+	Host            string                      `json:"host"`
+	Port            int                         `json:"port"`
+	Mode            string                      `json:"mode"` // debug, release, test
+	EnableCORS      bool                        `json:"enable_cors"`
+	CORSOrigins     []string                    `json:"cors_origins"`
+	EnableWebSocket bool                        `json:"enable_websocket"`
+	JWTSecret       string                      `json:"jwt_secret"`
+	SSHProxy        string                      `json:"ssh_proxy"`      // Default outbound proxy for host SSH connections
+	ProxyCommands   bool                        `json:"proxy_commands"` // Let hosts connect through their proxy_command, run by the server's shell; off by default
+	StorageConfig   storage.StorageConfig       `json:"storage"`
+	StorageCache    storage.CacheConfig         `json:"storage_cache"` // Read-through cache for project/group listings
+	Secrets         secrets.Config              `json:"secrets"`       // External secret stores for host credentials
+	SessionLogs     utils.LogStoreConfig        `json:"session_logs"`
+	Tracing         telemetry.Config            `json:"tracing"`          // OpenTelemetry trace export via OTLP
+	LocalDNS        localdns.Config             `json:"local_dns"`        // <port>.<group>.portfly names for active ports
+	Cluster         cluster.Config              `json:"cluster"`          // Lease-based tunnel ownership across replicas
+	Agents          agents.Config               `json:"agents"`           // Remote agent tokens; no token disables agents
+	Backup          backup.Config               `json:"backup"`           // Encrypted database snapshots; needs a passphrase
+	Retention       retention.Config            `json:"retention"`        // Pruning of old sessions and session logs
+	AdminSocket     string                      `json:"admin_socket"`     // Unix socket serving /api/v1/admin
+	AdminHTTP       bool                        `json:"admin_http"`       // Also serve /api/v1/admin on the network listener, behind the setup admin's credentials
+	StatusPage      statuspage.Config           `json:"status_page"`      // Public /status page for published ports
+	GRPC            grpcapi.Config              `json:"grpc"`             // gRPC API on its own listener
+	CloudSync       cloudsync.Config            `json:"cloud_sync"`       // Hosts synced from AWS, GCP and Azure instances
+	PortPools       string                      `json:"port_pools"`       // Local port allocation ranges, e.g. "project:3=20000-20999,default=30000-39999"
+	WebhookToken    string                      `json:"webhook_token"`    // Bearer token for /api/v1/webhooks; empty disables them
+	LocalHooks      bool                        `json:"local_hooks"`      // Let port hooks run commands on the server itself; off by default
+	MetadataSchemas string                      `json:"metadata_schemas"` // JSON or YAML file with the metadata schema of each entity type
+	TerminalPolicy  models.TerminalPolicy       `json:"terminal_policy"`  // Idle timeout and max duration of web terminals, overridden per host and group
+	Diagnostics     diagnostics.Config          `json:"diagnostics"`      // Support bundles written on request and when the server panics
+	CaptureDir      string                      `json:"capture_dir"`      // Directory debug capture files of ports are written to; empty uses ./data/captures
+	Setup           setup.Config                `json:"setup"`            // First-run setup state; its storage and master key apply from the next start
+	AuthFailures    authfailures.Config         `json:"auth_failures"`    // Consecutive SSH authentication failures before a host raises an event
+	HostStatus      hoststatus.Config           `json:"host_status"`      // Probes of host SSH ports joined into host lists as live state
+	Exec            manager.ExecSchedulerConfig `json:"exec"`             // SSH connections shared by commands run on hosts
+	Security        middleware.SecurityConfig   `json:"security"`         // Security headers and CSRF protection for cookie-authenticated requests
 }
 
 // NewServer creates a new server instance
 func NewServer(config *Config) (*Server, error) {
-	// Initialize logger
+	// Initialize logger, capturing session/port scoped logs for retrieval
+	logStore := utils.NewLogStore(config.SessionLogs)
 	loggerConfig := utils.LoggerConfig{
 		Level:  "info",
 		Format: "text",
 		Output: "stdout",
+		Store:  logStore,
 	}
 	logger, err := utils.NewLogger(loggerConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize logger: %w", err)
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := telemetry.Setup(context.Background(), config.Tracing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
 	// Initialize storage
-	store, err := storage.NewStorage(config.StorageConfig)
+	storageConfig := config.StorageConfig
+	if storageConfig.Logger == nil {
+		storageConfig.Logger = logger.With("component", "storage")
+	}
+	store, err := storage.NewStorage(storageConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
@@ -67,8 +153,45 @@ func NewServer(config *Config) (*Server, error) {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	// Initialize session manager - we'll create a simple version for now
-	sessionManager := &manager.SessionManager{}
+	// Cache hot dashboard reads; writes through this server invalidate entries
+	if config.StorageCache.Enabled {
+		store = storage.NewCachedStorage(store, config.StorageCache, nil)
+	}
+
+	// Initialize secrets providers
+	secretsRegistry, err := secrets.NewRegistryFromConfig(config.Secrets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize secrets providers: %w", err)
+	}
+
+	// Take over the listeners of the process this one replaces, if any
+	upgrader, err := upgrade.New(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inherited listeners: %w", err)
+	}
+
+	// Initialize local DNS names for active ports
+	var localDNS *localdns.Manager
+	if config.LocalDNS.Enabled {
+		listenDNS := func(network, address string) (net.PacketConn, error) {
+			return upgrader.ListenPacket("dns", func() (net.PacketConn, error) {
+				return net.ListenPacket(network, address)
+			})
+		}
+		localDNS, err = localdns.NewManagerWithListener(config.LocalDNS, listenDNS, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize local DNS: %w", err)
+		}
+	}
+
+	// Session manager running the tunnels started through the API
+	sessionManager := manager.NewSessionManager(models.DefaultConfig().SSH, logger)
+	// Ports name capture files; they are only ever written in the capture directory
+	captureDir := config.CaptureDir
+	if captureDir == "" {
+		captureDir = models.DefaultCaptureDir
+	}
+	sessionManager.SetCaptureDir(captureDir)
 
 	// Configure Gin mode
 	if config.Mode != "" {
@@ -77,10 +200,13 @@ func NewServer(config *Config) (*Server, error) {
 
 	// Create server
 	server := &Server{
-		config:         config,
-		storage:        store,
-		sessionManager: sessionManager,
-		logger:         logger,
+		config:          config,
+		storage:         store,
+		sessionManager:  sessionManager,
+		logger:          logger,
+		localDNS:        localDNS,
+		shutdownTracing: shutdownTracing,
+		upgrades:        upgrader,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				// Allow all origins for WebSocket connections
@@ -91,10 +217,171 @@ func NewServer(config *Config) (*Server, error) {
 	}
 
 	// Initialize handlers
-	h := handlers.NewHandlers(server.storage, server.sessionManager, server.logger)
+	server.handlers = handlers.NewHandlers(server.storage, server.sessionManager, server.logger)
+	server.handlers.SetDefaultSSHProxy(config.SSHProxy)
+	server.handlers.SetProxyCommands(config.ProxyCommands)
+	server.handlers.SetSecretsRegistry(secretsRegistry)
+	server.handlers.SetLogStore(logStore)
+	server.handlers.SetLocalDNS(localDNS)
+	server.handlers.SetWebhookToken(config.WebhookToken)
+	server.handlers.SetLocalHooks(config.LocalHooks)
+	server.handlers.SetBuildInfo(buildInfo)
+
+	// Guide the first start through creating an admin, choosing storage and
+	// importing hosts
+	setupManager, err := setup.NewManager(config.Setup, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load setup state: %w", err)
+	}
+	server.handlers.SetSetup(setupManager)
+	if !setupManager.State().Completed() {
+		logger.Info("Setup steps need the setup token in the X-PortFly-Setup-Token header", "token_file", setupManager.TokenFile())
+	}
+
+	// Coordinate tunnel ownership with other replicas sharing the database
+	if config.Cluster.Enabled {
+		server.coordinator = cluster.NewCoordinator(store, config.Cluster, logger)
+		server.coordinator.OnAcquired(func(ctx context.Context, resource string) {
+			logger.Info("This instance now owns the tunnel", "resource", resource)
+		})
+		server.coordinator.OnLost(func(ctx context.Context, resource string) {
+			logger.Info("This instance no longer owns the tunnel", "resource", resource)
+		})
+		server.handlers.SetCoordinator(server.coordinator)
+	}
+
+	// Accept remote agents that run tunnels on their own machines
+	var agentHub *agents.Hub
+	if config.Agents.Enabled() {
+		agentHub = agents.NewHub(config.Agents, logger)
+		agentHub.SetServerVersion(buildInfo.Version)
+		server.handlers.SetAgentHub(agentHub)
+	}
+
+	// Snapshot the database when the backend supports online backups
+	if backupper, ok := server.storage.(storage.Backupper); ok && config.Backup.Passphrase != "" {
+		server.backups, err = backup.NewManager(backupper, config.Backup, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize backups: %w", err)
+		}
+		server.handlers.SetBackupManager(server.backups)
+	} else if config.Backup.Enabled && config.Backup.Passphrase == "" {
+		logger.Warn("Backups are enabled but no passphrase is set; set PORTFLY_BACKUP_PASSPHRASE")
+	}
+
+	// Prune old session history; the admin endpoint works even when the
+	// background job is disabled
+	server.pruner = retention.NewPruner(server.storage, logStore, config.Retention, logger)
+	server.handlers.SetPruner(server.pruner)
+
+	// Record which process runs each forward and reset forwards whose
+	// process is gone, e.g. after a crash
+	instanceID := ""
+	if server.coordinator != nil {
+		instanceID = server.coordinator.InstanceID()
+	}
+	server.reconciler = reconcile.NewReconciler(server.storage, instanceID, logger)
+	server.handlers.SetReconciler(server.reconciler)
+
+	// Replace this process with a new binary on request; the new process
+	// adopts the forwards claimed under this instance
+	upgrader.Pass(upgradeOwner, server.reconciler.InstanceID())
+	server.handlers.SetUpgrader(upgrader)
+
+	// Refuse tunnel starts during host and group maintenance windows
+	server.maintenance = maintenance.NewChecker(server.storage, logger)
+	server.maintenance.OnPortStopped(server.handlers.PortStoppedForMaintenance)
+	server.handlers.SetMaintenance(server.maintenance)
+
+	// Stop ports when the temporary grant they were started with ends
+	server.grants = grants.NewManager(server.storage, logger)
+	server.grants.OnPortStopped(server.handlers.PortStoppedForGrant)
+	server.handlers.SetGrants(server.grants)
+
+	// Start ports with redundant hosts on the fastest one and fail over
+	server.failover = failover.NewMonitor(server.storage, logger)
+	server.handlers.SetFailover(server.failover)
+
+	// Probe host SSH ports so host lists show live reachability
+	server.hostStatus = hoststatus.NewMonitor(server.storage, config.HostStatus, logger)
+	server.handlers.SetHostStatus(server.hostStatus)
+
+	// Commands run on a host share connections, within the server's session limit
+	server.handlers.SetExecScheduler(config.Exec)
+
+	// Check published ports for the public status page
+	if config.StatusPage.Enabled {
+		server.statusPage = statuspage.NewMonitor(server.storage, config.StatusPage, logger)
+		server.handlers.SetStatusPage(server.statusPage)
+	}
+
+	// Keep hosts in step with cloud provider instances
+	cloudSources := config.CloudSync.Sources
+	if config.CloudSync.File != "" {
+		fileSources, err := cloudsync.LoadFile(config.CloudSync.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cloud sync sources: %w", err)
+		}
+		cloudSources = append(slices.Clone(cloudSources), fileSources...)
+	}
+	if len(cloudSources) > 0 {
+		server.cloudSync, err = cloudsync.NewSyncer(server.storage, cloudSources, server.reconciler.InstanceID(), logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize cloud sync: %w", err)
+		}
+		server.handlers.SetCloudSync(server.cloudSync)
+	}
+
+	// Hand out local ports from per-project and per-user ranges
+	portPools, err := models.ParsePortPools(config.PortPools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse port pools: %w", err)
+	}
+	server.handlers.SetPortPools(portPools)
+
+	// Validate entity metadata against per-entity schemas
+	if config.MetadataSchemas != "" {
+		schemas, err := models.LoadMetadataSchemas(config.MetadataSchemas)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load metadata schemas: %w", err)
+		}
+		server.handlers.SetMetadataSchemas(schemas)
+	}
+
+	// Close idle and long-running web terminals
+	if err := config.TerminalPolicy.Validate(); err != nil {
+		return nil, err
+	}
+	server.handlers.SetTerminalPolicy(config.TerminalPolicy)
+
+	// Count SSH authentication failures per host and warn about hosts that
+	// keep failing
+	server.handlers.SetAuthFailures(authfailures.NewTracker(server.storage, config.AuthFailures, logger))
+
+	// Track asynchronous port commands for polling and the event WebSocket
+	server.handlers.SetOperations(operations.NewTracker(operations.DefaultRetention, logger))
+
+	// Serve the gRPC API next to the REST API
+	if config.GRPC.Enabled {
+		server.grpc = grpcapi.NewServer(server.storage, sessionManager, config.GRPC, logger)
+	}
+
+	// Report subsystem states on /health and /metrics
+	server.health = health.NewRegistry("portfly-api")
+	server.registerHealthProbes(agentHub)
+	server.handlers.SetHealth(server.health)
 
 	// Initialize terminal manager
-	server.terminalManager = handlers.NewTerminalManager(h)
+	server.terminalManager = handlers.NewTerminalManager(server.handlers)
+
+	// Report goroutines and heap on the admin stats endpoint
+	selfStats := selfstats.NewCollector(server.selfStatsSubsystems()...)
+	server.handlers.SetSelfStats(selfStats)
+
+	// Write diagnostic bundles on request and when the server panics
+	if err := server.setupDiagnostics(logStore, selfStats); err != nil {
+		return nil, fmt.Errorf("failed to initialize diagnostics: %w", err)
+	}
 
 	// Setup routes
 	server.setupRoutes()
@@ -108,9 +395,18 @@ func (s *Server) setupRoutes() {
 
 	// Middleware
 	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	router.Use(middleware.Recovery())
 	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
 	router.Use(middleware.Logger(s.logger))
+	router.Use(middleware.Version(buildInfo))
+
+	// Security headers; origins allowed by CORS may also change state with cookies
+	security := s.config.Security
+	if s.config.EnableCORS {
+		security.TrustedOrigins = append(slices.Clone(security.TrustedOrigins), s.config.CORSOrigins...)
+	}
+	router.Use(middleware.Security(security))
 
 	// CORS middleware
 	if s.config.EnableCORS {
@@ -120,20 +416,39 @@ func (s *Server) setupRoutes() {
 		} else {
 			corsConfig.AllowAllOrigins = true
 		}
-		corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+		corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", version.HeaderVersion, version.HeaderAPIRevision}
+		corsConfig.ExposeHeaders = []string{version.HeaderVersion, version.HeaderAPIRevision}
 		corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
 		router.Use(cors.New(corsConfig))
 	}
 
-	// Initialize handlers
-	h := handlers.NewHandlers(s.storage, s.sessionManager, s.logger)
+	h := s.handlers
 
 	// Health check
 	router.GET("/health", h.Health)
+	router.GET("/metrics", h.Metrics)
+
+	// Public status page, served without authentication
+	router.GET("/status", h.StatusPage)
+	router.GET("/status.json", h.StatusPageJSON)
 
 	// API routes
 	api := router.Group("/api/v1")
 	{
+		// Server version and API revision for compatibility checks
+		api.GET("/version", h.GetVersion)
+
+		// First-run setup, locked once completed
+		setupRoutes := api.Group("/setup")
+		{
+			setupRoutes.GET("", h.GetSetup)
+			setupRoutes.POST("/admin", h.SetupAdmin)
+			setupRoutes.POST("/storage", h.SetupStorage)
+			setupRoutes.POST("/master-key", h.SetupMasterKey)
+			setupRoutes.POST("/import-ssh-config", h.SetupImportSSHConfig)
+			setupRoutes.POST("/complete", h.CompleteSetup)
+		}
+
 		// Projects
 		projects := api.Group("/projects")
 		{
@@ -143,6 +458,8 @@ func (s *Server) setupRoutes() {
 			projects.PUT("/:id", h.UpdateProject)
 			projects.DELETE("/:id", h.DeleteProject)
 			projects.GET("/:id/stats", h.GetProjectStats)
+			projects.GET("/:id/dashboard", h.GetProjectDashboard)
+			projects.GET("/:id/quota", h.GetProjectQuota)
 			projects.GET("/:id/children", h.GetProjectChildren)
 			projects.POST("/move", h.MoveProject)
 		}
@@ -156,6 +473,17 @@ func (s *Server) setupRoutes() {
 			groups.PUT("/:id", h.UpdateGroup)
 			groups.DELETE("/:id", h.DeleteGroup)
 			groups.GET("/:id/stats", h.GetGroupStats)
+			groups.GET("/:id/children", h.GetGroupChildren)
+			groups.GET("/:id/effective", h.GetEffectiveGroup)
+			groups.POST("/move", h.MoveGroup)
+			groups.GET("/:id/maintenance", h.GetGroupMaintenance)
+			groups.POST("/:id/execute", h.ExecuteGroupCommand)
+			groups.POST("/:id/files", h.PushGroupFiles)
+			groups.GET("/:id/reachability", h.GetReachabilityMatrix)
+			groups.POST("/:id/reachability", h.BuildReachabilityMatrix)
+			groups.GET("/:id/history", h.GetGroupHistory)
+			groups.GET("/:id/history/:version", h.GetGroupVersion)
+			groups.POST("/:id/history/:version/restore", h.RestoreGroupVersion)
 		}
 
 		// Hosts
@@ -167,13 +495,24 @@ func (s *Server) setupRoutes() {
 			hosts.PUT("/:id", h.UpdateHost)
 			hosts.DELETE("/:id", h.DeleteHost)
 			hosts.GET("/:id/stats", h.GetHostStats)
+			hosts.GET("/:id/maintenance", h.GetHostMaintenance)
+			hosts.GET("/:id/history", h.GetHostHistory)
+			hosts.GET("/:id/history/:version", h.GetHostVersion)
+			hosts.POST("/:id/history/:version/restore", h.RestoreHostVersion)
 			hosts.GET("/search", h.SearchHosts)
+			hosts.GET("/export/ssh-config", h.ExportSSHConfig)
+			hosts.POST("/discover", h.DiscoverHosts)
+			hosts.POST("/validate", h.ValidateHost)
+			hosts.POST("/import", h.ImportHosts)
+			hosts.GET("/auth-failures", h.GetAuthFailures)
+			hosts.GET("/exec-queue", h.GetExecQueue)
 
 			// Host connection endpoints
 			hosts.POST("/:id/connect", h.ConnectHost)
 			hosts.POST("/:id/disconnect", h.DisconnectHost)
 			hosts.POST("/:id/test", h.TestHostConnection)
 			hosts.POST("/:id/execute", h.ExecuteSSHCommand)
+			hosts.POST("/:id/reachability", h.ProbeHostReachability)
 		}
 
 		// Ports
@@ -185,11 +524,68 @@ func (s *Server) setupRoutes() {
 			ports.PUT("/:id", h.UpdatePort)
 			ports.DELETE("/:id", h.DeletePort)
 			ports.GET("/:id/stats", h.GetPortStats)
+			ports.GET("/:id/logs", h.GetPortLogs)
+			ports.GET("/:id/share", h.GetPortShare)
+			ports.GET("/:id/favicon", h.GetPortFavicon)
+			ports.POST("/:id/detect", h.DetectPortWebInfo)
+			ports.GET("/:id/resolved", h.GetResolvedPort)
+			ports.GET("/:id/history", h.GetPortHistory)
+			ports.GET("/:id/history/:version", h.GetPortVersion)
+			ports.POST("/:id/history/:version/restore", h.RestorePortVersion)
 			ports.GET("/search", h.SearchPorts)
 
 			// Port control endpoints
 			ports.POST("/:id/test", h.TestPortConnection)
 			ports.PUT("/:id/status", h.UpdatePortStatus)
+			ports.POST("/:id/control", h.ControlPort)
+			ports.POST("/:id/benchmark", h.BenchmarkPort)
+
+			ports.GET("/:id/hosts", h.GetPortHosts)
+
+			// Temporary access grants
+			ports.GET("/:id/grants", h.GetPortGrants)
+			ports.POST("/:id/grants", h.CreatePortGrant)
+			ports.DELETE("/:id/grants/:grantId", h.RevokePortGrant)
+		}
+
+		// Local port reservations, so auto-assigned ports do not collide across projects and users
+		portReservations := api.Group("/port-reservations")
+		{
+			portReservations.GET("", h.GetPortReservations)
+			portReservations.POST("", h.CreatePortReservation)
+			portReservations.GET("/plan", h.PlanPortReservations)
+			portReservations.DELETE("/:id", h.DeletePortReservation)
+		}
+
+		// Named commands per OS family, run on hosts and groups
+		snippets := api.Group("/snippets")
+		{
+			snippets.GET("", h.GetSnippets)
+			snippets.POST("", h.CreateSnippet)
+			snippets.GET("/:id", h.GetSnippet)
+			snippets.PUT("/:id", h.UpdateSnippet)
+			snippets.DELETE("/:id", h.DeleteSnippet)
+			snippets.POST("/:id/run", h.RunSnippet)
+		}
+
+		// Reusable port templates, instantiated onto hosts
+		portTemplates := api.Group("/port-templates")
+		{
+			portTemplates.GET("", h.GetPortTemplates)
+			portTemplates.POST("", h.CreatePortTemplate)
+			portTemplates.GET("/:id", h.GetPortTemplate)
+			portTemplates.PUT("/:id", h.UpdatePortTemplate)
+			portTemplates.DELETE("/:id", h.DeletePortTemplate)
+			portTemplates.POST("/:id/instantiate", h.InstantiatePortTemplate)
+		}
+
+		// Favorite ports of the requesting user, for the quick-access panel
+		favorites := api.Group("/favorites")
+		{
+			favorites.GET("", h.GetFavorites)
+			favorites.POST("", h.AddFavorite)
+			favorites.PUT("", h.SetFavorites)
+			favorites.DELETE("/:id", h.DeleteFavorite)
 		}
 
 		// Port Connections (Forward management)
@@ -208,19 +604,110 @@ func (s *Server) setupRoutes() {
 			sessions.PUT("/:id", h.UpdateTunnelSession)
 			sessions.DELETE("/:id", h.DeleteTunnelSession)
 			sessions.GET("/active", h.GetActiveTunnelSessions)
+			sessions.GET("/runtime", h.GetRuntimeSessions)
+			sessions.GET("/:id/logs", h.GetSessionLogs)
+			sessions.GET("/:id/http", h.ListHTTPRequests)
+			sessions.GET("/:id/http/:requestId", h.GetHTTPRequest)
+			sessions.POST("/:id/http/:requestId/replay", h.ReplayHTTPRequest)
 			sessions.POST("/:id/start", h.StartTunnel)
 			sessions.POST("/:id/stop", h.StopTunnel)
 		}
+
+		// Cluster coordination
+		api.GET("/cluster", h.GetClusterStatus)
+
+		// Storage cache metrics
+		api.GET("/storage/cache", h.GetStorageCacheStats)
+		api.GET("/storage/queries", h.GetStorageQueryStats)
+
+		// Local DNS
+		api.GET("/dns/records", h.GetDNSRecords)
+
+		// Maintenance windows
+		api.GET("/maintenance", h.GetMaintenance)
+
+		// Temporary port access grants
+		api.GET("/grants", h.GetGrants)
+
+		// Idle-resource report
+		api.GET("/reports/unused", h.GetUnusedReport)
+
+		// Inventory exports for other tools
+		api.GET("/export/ansible", h.ExportAnsibleInventory)
+
+		// Cloud host sync
+		api.GET("/cloud-sync", h.GetCloudSyncStatus)
+		api.POST("/cloud-sync/:name/run", h.RunCloudSync)
+
+		// Several operations in one request, e.g. bulk actions of the dashboard
+		api.POST("/batch", h.ExecuteBatch)
+
+		// Metadata schemas the entity metadata is validated against
+		api.GET("/metadata-schemas", h.GetMetadataSchemas)
+
+		// Asynchronous commands, e.g. starting a port's tunnel
+		api.GET("/operations", h.GetOperations)
+		api.GET("/operations/:id", h.GetOperation)
+
+		// Host authentication challenges (e.g. OTP) relayed to the requesting client
+		api.GET("/auth/challenges", h.GetAuthChallenges)
+		api.POST("/auth/challenges/:id", h.AnswerAuthChallenge)
+
+		// Web terminals interrupted by a server restart
+		api.POST("/terminals/restore", h.RestoreTerminals)
+		// Web terminals closed by the idle timeout or max duration
+		api.GET("/terminals/closures", h.GetTerminalClosures)
+		// Files transferred over the SSH connection of an open web terminal
+		api.POST("/terminals/:sessionId/files", h.UploadTerminalFile(s.terminalManager))
+		api.GET("/terminals/:sessionId/files", h.DownloadTerminalFile(s.terminalManager))
+
+		// Administration stays off the network listener unless enabled
+		// there, where it needs the setup admin's credentials
+		if s.config.AdminHTTP {
+			s.setupAdminRoutes(api.Group("/admin", h.AdminAuth))
+		}
+
+		// Host registration and port targets for external systems,
+		// authenticated by the webhook token
+		webhooks := api.Group("/webhooks", h.WebhookAuth)
+		{
+			webhooks.POST("/hosts", h.RegisterWebhookHost)
+			webhooks.DELETE("/hosts/:name", h.DeregisterWebhookHost)
+			webhooks.PUT("/ports/:id/target", h.UpdateWebhookPortTarget)
+		}
+
+		// Remote agents
+		agentsGroup := api.Group("/agents")
+		{
+			agentsGroup.GET("", h.GetAgents)
+			agentsGroup.GET("/:id", h.GetAgent)
+			agentsGroup.POST("/:id/forwards", h.CreateAgentForward)
+			agentsGroup.DELETE("/:id/forwards/:forwardId", h.DeleteAgentForward)
+		}
 	}
 
 	// WebSocket endpoint
 	if s.config.EnableWebSocket {
 		router.GET("/ws", h.WebSocketHandler(s.upgrader))
+		// Live debug capture of a session's forwarded bytes
+		router.GET("/ws/sessions/:id/capture", h.CaptureWebSocketHandler(s.upgrader))
 		// Terminal WebSocket endpoint
 		router.GET("/ws/terminal/:hostId", h.TerminalWebSocketHandler(s.terminalManager))
 	}
 
+	// Remote agent endpoint, authenticated by the agent token
+	router.GET(agent.WebSocketPath, h.AgentWebSocketHandler)
+	router.GET(agent.StreamPath+":streamId", h.AgentStreamHandler)
+
+	// Batch operations are dispatched to the routes above
+	h.SetBatchRouter(router)
 	s.router = router
+
+	if s.config.AdminSocket != "" {
+		s.setupAdminRouter()
+	} else if !s.config.AdminHTTP {
+		s.logger.Info("Admin API is disabled; set PORTFLY_ADMIN_SOCKET or PORTFLY_ADMIN_HTTP to serve it")
+	}
 }
 
 // Start starts the HTTP server
@@ -236,27 +723,167 @@ func (s *Server) Start() error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Terminals still open when the server last stopped become restorable
+	s.terminalManager.Recover(context.Background())
+
+	// Bind the HTTP listener, or take it over from the process this one replaces
+	listener, err := s.upgrades.Listen("http", func() (net.Listener, error) {
+		return net.Listen("tcp", addr)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
 	// Start server in a goroutine
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("Failed to start server: %v", err)
 		}
 	}()
 
 	s.logger.Info("Server started successfully on %s", addr)
 
-	// Wait for interrupt signal to gracefully shutdown the server
+	// Serve the admin API to local users only
+	var adminServer *http.Server
+	if s.adminRouter != nil {
+		listener, err := s.upgrades.Listen("admin", func() (net.Listener, error) {
+			return listenAdminSocket(s.config.AdminSocket)
+		})
+		if err != nil {
+			server.Close()
+			return err
+		}
+		adminServer = &http.Server{Handler: s.adminRouter}
+		go func() {
+			if err := adminServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+				s.logger.Error("Admin socket server failed", "error", err)
+			}
+		}()
+		s.logger.Info("Admin API listening", "socket", s.config.AdminSocket)
+	}
+
+	// Serve the gRPC API
+	if s.grpc != nil {
+		listener, err := s.upgrades.Listen("grpc", s.grpc.Listen)
+		if err != nil {
+			server.Close()
+			if adminServer != nil {
+				adminServer.Close()
+			}
+			return err
+		}
+		go func() {
+			if err := s.grpc.Serve(listener); err != nil {
+				s.logger.Error("gRPC server failed", "error", err)
+			}
+		}()
+		s.logger.Info("gRPC API listening", "address", listener.Addr().String())
+	}
+
+	// Take over from the process this one replaced, then let it exit
+	if parent := s.upgrades.Parent(); parent != 0 {
+		s.adoptFromParent(parent)
+	}
+	if err := s.upgrades.Ready(); err != nil {
+		s.logger.Warn("Failed to report ready to the previous process", "error", err)
+	}
+
+	// Compete for auto-start tunnels; leases are released on shutdown
+	coordinationDone := make(chan struct{})
+	coordinationCtx, stopCoordination := context.WithCancel(context.Background())
+	if s.coordinator != nil {
+		s.watchAutoStartPorts(coordinationCtx)
+		s.health.Go(coordinationCtx, "cluster", func() {
+			defer close(coordinationDone)
+			s.coordinator.Run(coordinationCtx)
+		})
+	} else {
+		close(coordinationDone)
+	}
+
+	// Run scheduled backups and pruning until shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if s.backups != nil && s.config.Backup.Enabled {
+		s.health.Go(jobsCtx, "backups", func() { s.backups.Run(jobsCtx) })
+	}
+	if s.config.Retention.Enabled {
+		s.health.Go(jobsCtx, "retention", func() { s.pruner.Run(jobsCtx) })
+	}
+	s.health.Go(jobsCtx, "maintenance", func() { s.maintenance.Run(jobsCtx, maintenance.DefaultInterval) })
+	s.health.Go(jobsCtx, "reconcile", func() { s.reconciler.Run(jobsCtx, reconcile.DefaultInterval) })
+	s.health.Go(jobsCtx, "grants", func() { s.grants.Run(jobsCtx, grants.DefaultInterval) })
+	s.health.Go(jobsCtx, "failover", func() { s.failover.Run(jobsCtx, failover.DefaultInterval) })
+	s.health.Go(jobsCtx, "host_status", func() { s.hostStatus.Run(jobsCtx) })
+	s.health.Go(jobsCtx, "port_sessions", func() { s.handlers.WatchPortSessions(jobsCtx) })
+	if s.statusPage != nil {
+		s.health.Go(jobsCtx, "status_page", func() { s.statusPage.Run(jobsCtx) })
+	}
+	if s.cloudSync != nil {
+		s.health.Go(jobsCtx, "cloud_sync", func() { s.cloudSync.Run(jobsCtx) })
+	}
+
+	// Wait for interrupt signal to gracefully shutdown the server, or for a
+	// new binary to take over
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	upgradeRequests := make(chan os.Signal, 1)
+	if len(upgrade.Signals) > 0 {
+		signal.Notify(upgradeRequests, upgrade.Signals...)
+	}
 
-	s.logger.Info("Shutting down server...")
+	upgraded := false
+	for waiting := true; waiting; {
+		select {
+		case <-quit:
+			waiting = false
+		case <-upgradeRequests:
+			go s.upgrade()
+		case <-s.upgrades.Done():
+			upgraded = true
+			waiting = false
+		}
+	}
+
+	if upgraded {
+		s.logger.Info("Handing over to the upgraded server, draining requests...")
+	} else {
+		s.logger.Info("Shutting down server...")
+	}
 
 	// Give outstanding requests 30 seconds to complete
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
+	// Keep open terminals restorable; hijacked WebSockets outlive server.Shutdown
+	s.terminalManager.Shutdown(ctx)
+
+	err = server.Shutdown(ctx)
+	if adminServer != nil {
+		adminServer.Shutdown(ctx) // closing the unix listener removes the socket
+	}
+	if s.grpc != nil {
+		s.grpc.Shutdown(ctx)
+	}
+	stopJobs()
+
+	// Hand auto-start tunnels over to the remaining replicas
+	stopCoordination()
+	<-coordinationDone
+
+	// Remove published names, unless the upgraded server now publishes them
+	if !upgraded {
+		if dnsErr := s.localDNS.Close(); dnsErr != nil {
+			s.logger.Warn("Failed to remove local DNS names", "error", dnsErr)
+		}
+	}
+
+	// Flush buffered spans
+	if tracingErr := s.shutdownTracing(ctx); tracingErr != nil {
+		s.logger.Warn("Failed to flush traces", "error", tracingErr)
+	}
+
+	if err != nil {
 		s.logger.Error("Server forced to shutdown: %v", err)
 		return err
 	}
@@ -265,6 +892,42 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// watchAutoStartPorts registers every auto-start port with the coordinator,
+// leaving out local ports reserved by another project or user
+func (s *Server) watchAutoStartPorts(ctx context.Context) {
+	ports, err := s.storage.GetPorts(ctx)
+	if err != nil {
+		s.logger.Error("Failed to load auto-start ports", "error", err)
+		return
+	}
+	reservations, err := s.storage.GetPortReservations(ctx, 0)
+	if err != nil {
+		s.logger.Error("Failed to load port reservations", "error", err)
+		return
+	}
+	groups, err := s.storage.GetGroups(ctx)
+	if err != nil {
+		s.logger.Error("Failed to load groups", "error", err)
+		return
+	}
+	projects := make(map[uint]uint, len(groups))
+	for _, group := range groups {
+		projects[group.ID] = group.ProjectID
+	}
+
+	for _, port := range ports {
+		if !port.AutoStart {
+			continue
+		}
+		if holder := models.ReservationConflict(reservations, &port, projects[port.GroupID]); holder != nil {
+			s.logger.Warn("Not auto-starting port reserved elsewhere", "port_id", port.ID, "port", port.Port,
+				"project_id", holder.ProjectID, "owner", holder.Owner)
+			continue
+		}
+		s.coordinator.Watch(cluster.PortResource(port.ID))
+	}
+}
+
 // Stop stops the server and cleans up resources
 func (s *Server) Stop() error {
 	// TODO: Stop all active sessions when SessionManager is properly implemented
@@ -279,6 +942,53 @@ func (s *Server) Stop() error {
 
 // DefaultConfig returns a default server configuration
 func DefaultConfig() *Config {
+	// PORTFLY_RETENTION_DAYS=0 disables background pruning
+	retentionDays := int(retention.DefaultMaxAge / (24 * time.Hour))
+	if days, err := strconv.Atoi(os.Getenv("PORTFLY_RETENTION_DAYS")); err == nil {
+		retentionDays = days
+	}
+	// PORTFLY_ADMIN_HTTP=true serves the admin API on the network listener
+	adminHTTP, _ := strconv.ParseBool(os.Getenv("PORTFLY_ADMIN_HTTP"))
+	// PORTFLY_PROXY_COMMANDS=true lets hosts connect through their proxy_command
+	proxyCommands, _ := strconv.ParseBool(os.Getenv("PORTFLY_PROXY_COMMANDS"))
+	// PORTFLY_LOCAL_HOOKS=true lets port hooks run commands on the server
+	localHooks, _ := strconv.ParseBool(os.Getenv("PORTFLY_LOCAL_HOOKS"))
+	// PORTFLY_STATUS_PAGE=true serves the public status page
+	statusPage, _ := strconv.ParseBool(os.Getenv("PORTFLY_STATUS_PAGE"))
+	// PORTFLY_AUTH_FAILURE_THRESHOLD sets the failures raising host.auth_failures
+	authFailureThreshold, _ := strconv.Atoi(os.Getenv("PORTFLY_AUTH_FAILURE_THRESHOLD"))
+	// PORTFLY_HOST_PROBE_INTERVAL sets how often host SSH ports are probed, e.g. "30s"
+	hostProbeInterval, _ := time.ParseDuration(os.Getenv("PORTFLY_HOST_PROBE_INTERVAL"))
+	// PORTFLY_EXEC_SESSIONS_PER_CONNECTION should match the MaxSessions of the hosts' sshd
+	execSessions, _ := strconv.Atoi(os.Getenv("PORTFLY_EXEC_SESSIONS_PER_CONNECTION"))
+	// PORTFLY_EXEC_CONNECTIONS_PER_HOST caps the connections commands open to one host
+	execConnections, _ := strconv.Atoi(os.Getenv("PORTFLY_EXEC_CONNECTIONS_PER_HOST"))
+	// PORTFLY_SECURITY_HEADERS=false stops sending the security headers
+	security := middleware.DefaultSecurityConfig()
+	if enabled, err := strconv.ParseBool(os.Getenv("PORTFLY_SECURITY_HEADERS")); err == nil {
+		security.Enabled = enabled
+	}
+	// PORTFLY_CONTENT_SECURITY_POLICY replaces the default policy
+	if policy, ok := os.LookupEnv("PORTFLY_CONTENT_SECURITY_POLICY"); ok {
+		security.ContentSecurityPolicy = policy
+	}
+	// PORTFLY_CSRF_TRUSTED_ORIGINS lists further origins allowed to change state with cookies
+	if origins := os.Getenv("PORTFLY_CSRF_TRUSTED_ORIGINS"); origins != "" {
+		security.TrustedOrigins = strings.FieldsFunc(origins, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+	// PORTFLY_GRPC_ADDRESS serves the gRPC API on that address
+	grpcAddress := os.Getenv("PORTFLY_GRPC_ADDRESS")
+	// Storage chosen during setup replaces the default database
+	storageConfig := storage.DefaultSQLiteConfig()
+	if chosen, err := setup.LoadStorage(setup.DefaultPath); err == nil && chosen != nil {
+		storageConfig = *chosen
+	}
+	// PORTFLY_BACKUP_PASSPHRASE takes precedence over the setup master key
+	backupPassphrase := os.Getenv("PORTFLY_BACKUP_PASSPHRASE")
+	if backupPassphrase == "" {
+		backupPassphrase, _ = setup.LoadMasterKey(setup.DefaultKeyFile)
+	}
+
 	return &Config{
 		Host:       "localhost",
 		Port:       8080,
@@ -291,6 +1001,85 @@ func DefaultConfig() *Config {
 		},
 		EnableWebSocket: true,
 		JWTSecret:       "your-secret-key-change-in-production",
-		StorageConfig:   storage.DefaultSQLiteConfig(),
+		ProxyCommands:   proxyCommands,
+		StorageConfig:   storageConfig,
+		StorageCache: storage.CacheConfig{
+			Enabled: true,
+			TTL:     storage.DefaultCacheTTL,
+		},
+		SessionLogs: utils.LogStoreConfig{
+			BufferSize: utils.DefaultLogBufferSize,
+		},
+		Backup: backup.Config{
+			Enabled:    true,
+			Interval:   backup.DefaultInterval,
+			Path:       backup.DefaultPath,
+			MaxFiles:   backup.DefaultMaxFiles,
+			Passphrase: backupPassphrase,
+		},
+		Retention: retention.Config{
+			Enabled:   retentionDays > 0,
+			MaxAge:    time.Duration(retentionDays) * 24 * time.Hour,
+			Interval:  retention.DefaultInterval,
+			BatchSize: retention.DefaultBatchSize,
+		},
+		Agents: agents.Config{
+			Token:  os.Getenv("PORTFLY_AGENT_TOKEN"),
+			Tokens: agents.ParseTokens(os.Getenv("PORTFLY_AGENT_TOKENS")),
+		},
+		AdminSocket:  os.Getenv("PORTFLY_ADMIN_SOCKET"),
+		AdminHTTP:    adminHTTP,
+		WebhookToken: os.Getenv("PORTFLY_WEBHOOK_TOKEN"),
+		LocalHooks:   localHooks,
+		StatusPage: statuspage.Config{
+			Enabled:  statusPage,
+			Title:    os.Getenv("PORTFLY_STATUS_PAGE_TITLE"),
+			Interval: statuspage.DefaultInterval,
+		},
+		GRPC: grpcapi.Config{
+			Enabled: grpcAddress != "",
+			Address: grpcAddress,
+		},
+		CloudSync: cloudsync.Config{
+			File: os.Getenv("PORTFLY_CLOUD_SYNC_FILE"),
+		},
+		PortPools:       os.Getenv("PORTFLY_PORT_POOLS"),
+		MetadataSchemas: os.Getenv("PORTFLY_METADATA_SCHEMAS"),
+		TerminalPolicy: models.TerminalPolicy{
+			IdleTimeout: envSeconds("PORTFLY_TERMINAL_IDLE_TIMEOUT"),
+			MaxDuration: envSeconds("PORTFLY_TERMINAL_MAX_DURATION"),
+			WarnBefore:  envSeconds("PORTFLY_TERMINAL_WARN_BEFORE"),
+		},
+		CaptureDir: os.Getenv("PORTFLY_CAPTURE_DIR"),
+		Diagnostics: diagnostics.Config{
+			Path:     diagnostics.DefaultPath,
+			MaxFiles: diagnostics.DefaultMaxFiles,
+		},
+		Setup: setup.Config{
+			Path:      setup.DefaultPath,
+			KeyFile:   setup.DefaultKeyFile,
+			TokenFile: setup.DefaultTokenFile,
+		},
+		AuthFailures: authfailures.Config{
+			Threshold: authFailureThreshold,
+		},
+		HostStatus: hoststatus.Config{
+			Interval: hostProbeInterval,
+		},
+		Exec: manager.ExecSchedulerConfig{
+			SessionsPerConnection: execSessions,
+			ConnectionsPerHost:    execConnections,
+		},
+		Security: security,
+	}
+}
+
+// envSeconds reads a duration such as "30m" from an environment variable as
+// whole seconds; unset or invalid values are 0
+func envSeconds(name string) int {
+	duration, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return 0
 	}
+	return int(duration / time.Second)
 }
@@ -0,0 +1,380 @@
+// Package setup keeps the state of the first-run setup of a server: the
+// admin account, the storage chosen for the next start and the master key
+// encrypting backups. Setup is done once; afterwards every step is refused.
+// Until then every step needs the setup token, written at start to a file
+// only the server's user can read.
+package setup
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// Defaults applied to zero config fields
+const (
+	DefaultPath      = "./data/setup.json"
+	DefaultKeyFile   = "./data/master.key"
+	DefaultTokenFile = "./data/setup.token"
+)
+
+// Limits of the admin account and master key
+const (
+	MinPasswordLength = 8
+	MinKeyLength      = 16
+	generatedKeySize  = 32
+	tokenSize         = 24
+)
+
+var (
+	// ErrCompleted is returned for setup steps after setup was completed
+	ErrCompleted = errors.New("setup is already completed")
+	// ErrAdminRequired is returned when completing setup without an admin
+	ErrAdminRequired = errors.New("create the admin user before completing setup")
+	// ErrKeyExists is returned when a master key is already in place;
+	// replacing it would leave existing backups unreadable
+	ErrKeyExists = errors.New("a master key is already set")
+	// ErrInvalidInput is returned for a rejected username, password or key
+	ErrInvalidInput = errors.New("invalid setup input")
+)
+
+// usernamePattern is the form of admin usernames
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]{0,63}$`)
+
+// Config is the setup section of the configuration
+type Config struct {
+	Path      string `json:"path"`       // setup state, including the chosen storage
+	KeyFile   string `json:"key_file"`   // master key, used as the backup passphrase
+	TokenFile string `json:"token_file"` // setup token, replaced at every start until setup is completed
+}
+
+// Admin is the admin account created during setup
+type Admin struct {
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"` // bcrypt
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MasterKey describes the master key without revealing it
+type MasterKey struct {
+	Fingerprint string    `json:"fingerprint"` // first bytes of the key's SHA-256, hex
+	Generated   bool      `json:"generated"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// State is the progress of setup, persisted to Config.Path
+type State struct {
+	Admin         *Admin                 `json:"admin,omitempty"`
+	Storage       *storage.StorageConfig `json:"storage,omitempty"` // used from the next start on
+	MasterKey     *MasterKey             `json:"master_key,omitempty"`
+	ImportedHosts int                    `json:"imported_hosts"`
+	CompletedAt   *time.Time             `json:"completed_at,omitempty"`
+}
+
+// Started reports whether any setup step was taken
+func (s State) Started() bool {
+	return s.Admin != nil || s.Storage != nil || s.MasterKey != nil || s.ImportedHosts > 0
+}
+
+// Completed reports whether setup is done
+func (s State) Completed() bool {
+	return s.CompletedAt != nil
+}
+
+// Manager records setup steps
+type Manager struct {
+	config Config
+	logger utils.Logger
+
+	mu      sync.Mutex
+	state   State
+	token   string // empty once setup is completed
+	restart bool   // storage or master key changed since the server started
+}
+
+// NewManager loads the setup state from config.Path, if there is any
+func NewManager(config Config, logger utils.Logger) (*Manager, error) {
+	if config.Path == "" {
+		config.Path = DefaultPath
+	}
+	if config.KeyFile == "" {
+		config.KeyFile = DefaultKeyFile
+	}
+	if config.TokenFile == "" {
+		config.TokenFile = DefaultTokenFile
+	}
+	state, err := load(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{config: config, logger: logger, state: state}
+	if state.Completed() {
+		os.Remove(config.TokenFile)
+		return m, nil
+	}
+	if m.token, err = writeToken(config.TokenFile); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TokenFile returns the file holding the setup token
+func (m *Manager) TokenFile() string {
+	return m.config.TokenFile
+}
+
+// CheckToken reports whether token is the setup token; none is once setup
+// is completed
+func (m *Manager) CheckToken(token string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(m.token)) == 1
+}
+
+// State returns the setup progress
+func (m *Manager) State() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// RestartRequired reports whether a step only takes effect after a restart
+func (m *Manager) RestartRequired() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.restart
+}
+
+// SetAdmin creates the admin account, replacing one created earlier in the
+// same setup
+func (m *Manager) SetAdmin(username, password string) error {
+	username = strings.TrimSpace(username)
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("%w: username must be 1-64 letters, digits or . _ @ -", ErrInvalidInput)
+	}
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("%w: password must have at least %d characters", ErrInvalidInput, MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+
+	return m.update(func(state *State) {
+		state.Admin = &Admin{Username: username, PasswordHash: string(hash), CreatedAt: time.Now()}
+	})
+}
+
+// CheckAdmin reports whether username and password are the admin's
+func (m *Manager) CheckAdmin(username, password string) bool {
+	admin := m.State().Admin
+	return admin != nil && admin.Username == username &&
+		bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(password)) == nil
+}
+
+// SetStorage checks that config connects and saves it for the next start
+func (m *Manager) SetStorage(config storage.StorageConfig) error {
+	if m.State().Completed() {
+		return ErrCompleted
+	}
+	if err := storage.ValidateConfig(config); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	config.Logger = utils.DiscardLogger()
+	store, err := storage.NewStorage(config)
+	if err != nil {
+		return fmt.Errorf("%w: failed to connect: %v", ErrInvalidInput, err)
+	}
+	store.Close()
+	config.Logger = nil
+
+	return m.update(func(state *State) {
+		state.Storage = &config
+		m.restart = true
+	})
+}
+
+// SetMasterKey writes the master key to the key file. An empty key generates
+// one; the key is returned so it can be shown once and kept offline.
+func (m *Manager) SetMasterKey(key string) (string, error) {
+	if m.State().Completed() {
+		return "", ErrCompleted
+	}
+	if _, err := os.Stat(m.config.KeyFile); err == nil {
+		return "", ErrKeyExists
+	}
+	generated := key == ""
+	if generated {
+		raw := make([]byte, generatedKeySize)
+		if _, err := rand.Read(raw); err != nil {
+			return "", err
+		}
+		key = base64.StdEncoding.EncodeToString(raw)
+	} else if len(key) < MinKeyLength {
+		return "", fmt.Errorf("%w: master key must have at least %d characters", ErrInvalidInput, MinKeyLength)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.config.KeyFile), 0700); err != nil {
+		return "", fmt.Errorf("failed to create key directory: %w", err)
+	}
+	file, err := os.OpenFile(m.config.KeyFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return "", ErrKeyExists
+		}
+		return "", fmt.Errorf("failed to write master key: %w", err)
+	}
+	_, err = file.WriteString(key + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(m.config.KeyFile)
+		return "", fmt.Errorf("failed to write master key: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	err = m.update(func(state *State) {
+		state.MasterKey = &MasterKey{Fingerprint: hex.EncodeToString(sum[:8]), Generated: generated, CreatedAt: time.Now()}
+		m.restart = true
+	})
+	return key, err
+}
+
+// AddImportedHosts counts hosts created by setup imports
+func (m *Manager) AddImportedHosts(n int) error {
+	return m.update(func(state *State) {
+		state.ImportedHosts += n
+	})
+}
+
+// Complete ends setup; all steps are refused from then on
+func (m *Manager) Complete() error {
+	if state := m.State(); state.Admin == nil && !state.Completed() {
+		return ErrAdminRequired
+	}
+	err := m.update(func(state *State) {
+		now := time.Now()
+		state.CompletedAt = &now
+		m.token = ""
+	})
+	if err == nil {
+		os.Remove(m.config.TokenFile)
+		m.logger.Info("Setup completed")
+	}
+	return err
+}
+
+// update changes and saves the state unless setup is completed
+func (m *Manager) update(change func(*State)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state.Completed() {
+		return ErrCompleted
+	}
+
+	state := m.state
+	change(&state)
+	if err := save(m.config.Path, state); err != nil {
+		return err
+	}
+	m.state = state
+	return nil
+}
+
+// LoadStorage returns the storage chosen during setup, or nil if none was
+func LoadStorage(path string) (*storage.StorageConfig, error) {
+	state, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	return state.Storage, nil
+}
+
+// LoadMasterKey returns the master key written during setup, or "" if none was
+func LoadMasterKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read master key: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// writeToken generates a setup token and writes it to path, replacing the
+// token of an earlier start
+func writeToken(path string) (string, error) {
+	raw := make([]byte, tokenSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("failed to create setup directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write setup token: %w", err)
+	}
+	// WriteFile keeps the mode of an existing file
+	if err := os.Chmod(path, 0600); err != nil {
+		return "", fmt.Errorf("failed to restrict setup token: %w", err)
+	}
+	return token, nil
+}
+
+// load reads the state file; a missing file is a setup not yet started
+func load(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read setup state: %w", err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("invalid setup state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// save writes the state file atomically; it holds the storage password and
+// admin hash, so only the owner may read it
+func save(path string, state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create setup directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write setup state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write setup state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package statuspage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+// WriteHTML renders the page as a self-contained HTML document that reloads
+// itself after every check
+func (p *Page) WriteHTML(w io.Writer) error {
+	return pageTemplate.Execute(w, p)
+}
+
+var pageTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"percent": func(value *float64) string {
+		if value == nil {
+			return "–"
+		}
+		return fmt.Sprintf("%.2f%%", *value)
+	},
+	"latency": func(value *float64) string {
+		if value == nil {
+			return "–"
+		}
+		if *value < 1 {
+			return "<1 ms"
+		}
+		return fmt.Sprintf("%.0f ms", *value)
+	},
+	"timestamp": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+	"summary": func(state string) string {
+		switch state {
+		case OverallOperational:
+			return "All services operational"
+		case OverallDegraded:
+			return "Some services are down"
+		case OverallOutage:
+			return "All services are down"
+		}
+		return "No checks yet"
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="{{.Interval}}">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; margin: 0; background: #f6f7f9; color: #1f2933; }
+main { max-width: 760px; margin: 0 auto; padding: 32px 16px; }
+h1 { font-size: 24px; margin: 0 0 16px; }
+.banner { padding: 14px 18px; border-radius: 8px; color: #fff; font-weight: 600; margin-bottom: 24px; }
+.operational { background: #16a34a; } .degraded { background: #d97706; } .outage { background: #dc2626; } .unknown { background: #6b7280; }
+.service { background: #fff; border-radius: 8px; padding: 16px 18px; margin-bottom: 12px; box-shadow: 0 1px 2px rgba(0,0,0,.06); }
+.head { display: flex; justify-content: space-between; align-items: center; }
+.name { font-weight: 600; }
+.state { font-size: 13px; font-weight: 600; text-transform: uppercase; }
+.state.up { color: #16a34a; } .state.down { color: #dc2626; } .state.unknown { color: #6b7280; }
+.bar { display: flex; gap: 2px; margin: 12px 0 8px; height: 24px; }
+.bar span { flex: 1; border-radius: 2px; }
+.bar .up { background: #22c55e; } .bar .down { background: #ef4444; }
+.stats { display: flex; gap: 20px; font-size: 13px; color: #52606d; }
+footer { font-size: 12px; color: #7b8794; margin-top: 24px; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+<div class="banner {{.State}}">{{summary .State}}</div>
+{{range .Services}}
+<div class="service">
+  <div class="head"><span class="name">{{.Name}}</span><span class="state {{.State}}">{{.State}}</span></div>
+  {{if .History}}<div class="bar">{{range .History}}<span class="{{.}}"></span>{{end}}</div>{{end}}
+  <div class="stats">
+    <span>Latency {{latency .LatencyMs}}</span>
+    <span>24h {{percent .Uptime.Day}}</span>
+    <span>7d {{percent .Uptime.Week}}</span>
+    <span>30d {{percent .Uptime.Month}}</span>
+  </div>
+</div>
+{{else}}
+<p>No services are published.</p>
+{{end}}
+<footer>Updated {{timestamp .GeneratedAt}}</footer>
+</main>
+</body>
+</html>
+`))
@@ -0,0 +1,133 @@
+package statuspage
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+const (
+	// probeTimeout bounds one check, including the service's answer
+	probeTimeout = 10 * time.Second
+
+	// greetingWait is how long a TCP check waits for the tunnel to close
+	// the connection, which it does when the service cannot be reached
+	greetingWait = time.Second
+)
+
+// probe checks a port through its tunnel. A port is up while its tunnel runs
+// and the service behind it answers: HTTP services with a response below 500,
+// other services by accepting the connection. Remote ports listen on the SSH
+// server, out of reach from here, and count as up while their tunnel runs.
+func (m *Monitor) probe(ctx context.Context, port *models.Port) *models.StatusCheck {
+	check := &models.StatusCheck{PortID: port.ID, CheckedAt: m.now()}
+	if !port.IsActive() {
+		check.Error = "tunnel is not running"
+		return check
+	}
+	if port.IsRemotePort() {
+		check.Up = true
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	address := net.JoinHostPort(port.GetShareHost(), strconv.Itoa(port.Port))
+	var latency time.Duration
+	var err error
+	switch service := port.GetServiceType(); service {
+	case models.ServiceHTTP, models.ServiceHTTPS:
+		latency, err = probeHTTP(ctx, string(service)+"://"+address+"/")
+	default:
+		latency, err = probeTCP(ctx, address)
+	}
+	if err != nil {
+		check.Error = err.Error()
+		if len(check.Error) > 255 {
+			check.Error = check.Error[:255]
+		}
+		return check
+	}
+
+	check.Up = true
+	ms := float64(latency) / float64(time.Millisecond)
+	check.LatencyMs = &ms
+	return check
+}
+
+// probeHTTP requests url and returns the time until the response headers
+func probeHTTP(ctx context.Context, url string) (time.Duration, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			// The certificate names the internal service, not the tunnel
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "portfly-status")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	latency := time.Since(start)
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return latency, nil
+}
+
+// probeTCP connects to address. The local listener accepts even when the
+// service is unreachable, so the check then waits briefly: the tunnel closes
+// connections it cannot forward, while services keep them open or greet.
+// The latency is the time to the greeting, or to the connection otherwise.
+func probeTCP(ctx context.Context, address string) (time.Duration, error) {
+	var dialer net.Dialer
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	connected := time.Since(start)
+
+	deadline := time.Now().Add(greetingWait)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetReadDeadline(deadline)
+
+	buf := make([]byte, 1)
+	_, err = conn.Read(buf)
+	switch {
+	case err == nil:
+		return time.Since(start), nil
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return connected, nil
+	case errors.Is(err, io.EOF):
+		return 0, errors.New("connection closed by the tunnel")
+	default:
+		return 0, err
+	}
+}
@@ -0,0 +1,290 @@
+// Package statuspage serves a public view of selected tunnels. Ports marked
+// published are checked periodically through their tunnel; the results are
+// kept for 30 days and summarized as each service's state, latency and
+// uptime. The page shows the public names only, never hosts, addresses or
+// errors, since it is served without authentication.
+package statuspage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// Defaults applied to zero config fields
+const (
+	DefaultInterval = time.Minute
+	DefaultTitle    = "Service Status"
+)
+
+const (
+	// historyRetention is the longest uptime window; older checks are pruned
+	historyRetention = 30 * 24 * time.Hour
+
+	// recentChecks is the number of checks shown as a service's history bar
+	recentChecks = 60
+
+	// pageTTL bounds how often the page is rebuilt from the database, as
+	// anyone may request it
+	pageTTL = 10 * time.Second
+)
+
+// Config configures the status page
+type Config struct {
+	Enabled  bool          `json:"enabled"`  // serve /status and check published ports
+	Title    string        `json:"title"`    // page heading
+	Interval time.Duration `json:"interval"` // time between checks
+}
+
+// Service states
+const (
+	StateUp      = "up"
+	StateDown    = "down"
+	StateUnknown = "unknown" // not checked yet
+)
+
+// Overall states of the page
+const (
+	OverallOperational = "operational"
+	OverallDegraded    = "degraded"
+	OverallOutage      = "outage"
+	OverallUnknown     = "unknown"
+)
+
+// Uptime is the percentage of successful checks per window, nil without checks
+type Uptime struct {
+	Day   *float64 `json:"24h"`
+	Week  *float64 `json:"7d"`
+	Month *float64 `json:"30d"`
+}
+
+// Service is one published port as shown on the page
+type Service struct {
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	LatencyMs *float64   `json:"latency_ms,omitempty"` // of the latest check
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	Uptime    Uptime     `json:"uptime"`
+	History   []string   `json:"history"` // states of the latest checks, oldest first
+}
+
+// Page is the public status of all published ports
+type Page struct {
+	Title       string    `json:"title"`
+	State       string    `json:"state"`
+	Services    []Service `json:"services"`
+	Interval    int64     `json:"interval"` // seconds between checks
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Monitor checks published ports and builds the status page
+type Monitor struct {
+	store  storage.StorageInterface
+	config Config
+	logger utils.Logger
+	now    func() time.Time
+
+	mu     sync.Mutex
+	cached *Page
+}
+
+// NewMonitor creates a status page monitor for store
+func NewMonitor(store storage.StorageInterface, config Config, logger utils.Logger) *Monitor {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.Title == "" {
+		config.Title = DefaultTitle
+	}
+	return &Monitor{store: store, config: config, logger: logger, now: time.Now}
+}
+
+// Run checks the published ports every interval until ctx is done
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Check(ctx); err != nil && ctx.Err() == nil {
+			m.logger.Warn("Failed to check published ports", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check probes every published port once, records the results and prunes
+// checks older than the longest uptime window
+func (m *Monitor) Check(ctx context.Context) error {
+	ports, err := m.publishedPorts(ctx)
+	if err != nil {
+		return err
+	}
+
+	checks := make([]*models.StatusCheck, len(ports))
+	var wg sync.WaitGroup
+	for i := range ports {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks[i] = m.probe(ctx, &ports[i])
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range checks {
+		if err := m.store.CreateStatusCheck(ctx, check); err != nil {
+			m.logger.Warn("Failed to record status check", "port_id", check.PortID, "error", err)
+		}
+	}
+
+	if _, err := m.store.DeleteStatusChecksBefore(ctx, m.now().Add(-historyRetention)); err != nil {
+		m.logger.Warn("Failed to prune status checks", "error", err)
+	}
+
+	m.mu.Lock()
+	m.cached = nil
+	m.mu.Unlock()
+	return nil
+}
+
+// Page returns the current status of the published ports
+func (m *Monitor) Page(ctx context.Context) (*Page, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cached != nil && m.now().Sub(m.cached.GeneratedAt) < pageTTL {
+		return m.cached, nil
+	}
+
+	ports, err := m.publishedPorts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{
+		Title:       m.config.Title,
+		Services:    make([]Service, 0, len(ports)),
+		Interval:    int64(m.config.Interval / time.Second),
+		GeneratedAt: m.now(),
+	}
+	for i := range ports {
+		service, err := m.service(ctx, &ports[i])
+		if err != nil {
+			return nil, err
+		}
+		page.Services = append(page.Services, *service)
+	}
+	page.State = overallState(page.Services)
+
+	m.cached = page
+	return page, nil
+}
+
+// service summarizes the checks of one port
+func (m *Monitor) service(ctx context.Context, port *models.Port) (*Service, error) {
+	service := &Service{
+		Name:    publicName(port),
+		State:   StateUnknown,
+		History: []string{},
+	}
+
+	recent, err := m.store.GetRecentStatusChecks(ctx, port.ID, recentChecks)
+	if err != nil {
+		return nil, err
+	}
+	if len(recent) > 0 {
+		latest := recent[0]
+		service.State = checkState(&latest)
+		service.LatencyMs = latest.LatencyMs
+		service.CheckedAt = &latest.CheckedAt
+	}
+	for i := len(recent) - 1; i >= 0; i-- {
+		service.History = append(service.History, checkState(&recent[i]))
+	}
+
+	now := m.now()
+	for _, window := range []struct {
+		since  time.Duration
+		target **float64
+	}{
+		{24 * time.Hour, &service.Uptime.Day},
+		{7 * 24 * time.Hour, &service.Uptime.Week},
+		{historyRetention, &service.Uptime.Month},
+	} {
+		uptime, err := m.store.GetStatusUptime(ctx, port.ID, now.Add(-window.since))
+		if err != nil {
+			return nil, err
+		}
+		*window.target = uptime.Percent()
+	}
+	return service, nil
+}
+
+// publishedPorts lists the published ports by public name
+func (m *Monitor) publishedPorts(ctx context.Context) ([]models.Port, error) {
+	ports, err := m.store.GetPorts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ports: %w", err)
+	}
+
+	published := ports[:0]
+	for _, port := range ports {
+		if port.Published {
+			published = append(published, port)
+		}
+	}
+	slices.SortFunc(published, func(a, b models.Port) int {
+		return cmp.Or(cmp.Compare(publicName(&a), publicName(&b)), cmp.Compare(a.ID, b.ID))
+	})
+	return published, nil
+}
+
+// publicName is the name a port is shown under
+func publicName(port *models.Port) string {
+	if port.PublicName != "" {
+		return port.PublicName
+	}
+	return port.GetDisplayName()
+}
+
+func checkState(check *models.StatusCheck) string {
+	if check.Up {
+		return StateUp
+	}
+	return StateDown
+}
+
+// overallState is operational when every checked service is up, an outage
+// when all are down and degraded in between
+func overallState(services []Service) string {
+	up, down := 0, 0
+	for _, service := range services {
+		switch service.State {
+		case StateUp:
+			up++
+		case StateDown:
+			down++
+		}
+	}
+	switch {
+	case up+down == 0:
+		return OverallUnknown
+	case down == 0:
+		return OverallOperational
+	case up == 0:
+		return OverallOutage
+	default:
+		return OverallDegraded
+	}
+}
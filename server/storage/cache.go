@@ -0,0 +1,390 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// Cache tags, one per family of cached queries. A write invalidates every tag
+// whose queries preload the written model.
+const (
+	CacheTagProjects = "projects" // project queries preload groups, parent and children
+	CacheTagGroups   = "groups"   // group queries preload project, hosts and port forwards
+)
+
+// DefaultCacheTTL is used when the cache is enabled without a TTL
+const DefaultCacheTTL = 30 * time.Second
+
+// CacheConfig configures the read-through cache for hot dashboard queries
+type CacheConfig struct {
+	Enabled bool                     `json:"enabled"`
+	TTL     time.Duration            `json:"ttl"`  // default entry lifetime
+	TTLs    map[string]time.Duration `json:"ttls"` // per-tag overrides keyed by CacheTag*
+}
+
+// CacheStats reports cache effectiveness
+type CacheStats struct {
+	Enabled       bool    `json:"enabled"`
+	Entries       int     `json:"entries"`
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Invalidations int64   `json:"invalidations"`
+	Expirations   int64   `json:"expirations"`
+}
+
+// InvalidationBus carries invalidations between server instances sharing a
+// database, so a write on one instance evicts stale entries on the others.
+type InvalidationBus interface {
+	Publish(tags []string)
+	Subscribe(handler func(tags []string)) (unsubscribe func())
+}
+
+// LocalInvalidationBus is an in-process bus for single-instance deployments
+// and for tests; multi-instance deployments plug in a shared transport
+type LocalInvalidationBus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]func(tags []string)
+}
+
+// NewLocalInvalidationBus creates an in-process invalidation bus
+func NewLocalInvalidationBus() *LocalInvalidationBus {
+	return &LocalInvalidationBus{handlers: make(map[int]func(tags []string))}
+}
+
+// Publish delivers tags to every subscriber
+func (b *LocalInvalidationBus) Publish(tags []string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(tags)
+	}
+}
+
+// Subscribe registers handler until the returned function is called
+func (b *LocalInvalidationBus) Subscribe(handler func(tags []string)) func() {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = handler
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}
+}
+
+// cacheEntry is a cached query result
+type cacheEntry struct {
+	value     any
+	tag       string
+	expiresAt time.Time
+}
+
+// CachedStorage wraps a backend with a read-through cache for project and
+// group listings. Writes made through it invalidate affected entries locally
+// and publish the invalidation on the bus. Cached slices are copied on read,
+// but nested associations are shared and must be treated as read-only.
+type CachedStorage struct {
+	StorageInterface
+
+	config      CacheConfig
+	bus         InvalidationBus
+	unsubscribe func()
+
+	mu         sync.RWMutex
+	entries    map[string]cacheEntry
+	generation uint64 // bumped on invalidation so in-flight loads are not stored stale
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+	expirations   atomic.Int64
+}
+
+// NewCachedStorage wraps backend with a cache; bus may be nil
+func NewCachedStorage(backend StorageInterface, config CacheConfig, bus InvalidationBus) *CachedStorage {
+	if config.TTL <= 0 {
+		config.TTL = DefaultCacheTTL
+	}
+
+	cs := &CachedStorage{
+		StorageInterface: backend,
+		config:           config,
+		bus:              bus,
+		entries:          make(map[string]cacheEntry),
+	}
+	if bus != nil {
+		cs.unsubscribe = bus.Subscribe(cs.invalidateLocal)
+	}
+	return cs
+}
+
+// Stats returns cache counters
+func (cs *CachedStorage) Stats() CacheStats {
+	cs.mu.RLock()
+	entries := len(cs.entries)
+	cs.mu.RUnlock()
+
+	stats := CacheStats{
+		Enabled:       true,
+		Entries:       entries,
+		Hits:          cs.hits.Load(),
+		Misses:        cs.misses.Load(),
+		Invalidations: cs.invalidations.Load(),
+		Expirations:   cs.expirations.Load(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// Close stops listening for invalidations and closes the backend
+func (cs *CachedStorage) Close() error {
+	if cs.unsubscribe != nil {
+		cs.unsubscribe()
+	}
+	return cs.StorageInterface.Close()
+}
+
+// ttl returns the entry lifetime for tag
+func (cs *CachedStorage) ttl(tag string) time.Duration {
+	if ttl, exists := cs.config.TTLs[tag]; exists && ttl > 0 {
+		return ttl
+	}
+	return cs.config.TTL
+}
+
+// get returns a live entry for key
+func (cs *CachedStorage) get(key string) (any, bool) {
+	cs.mu.RLock()
+	entry, exists := cs.entries[key]
+	cs.mu.RUnlock()
+
+	if !exists {
+		cs.misses.Add(1)
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		cs.mu.Lock()
+		if current, ok := cs.entries[key]; ok && current.expiresAt == entry.expiresAt {
+			delete(cs.entries, key)
+		}
+		cs.mu.Unlock()
+		cs.expirations.Add(1)
+		cs.misses.Add(1)
+		return nil, false
+	}
+
+	cs.hits.Add(1)
+	return entry.value, true
+}
+
+// currentGeneration returns the invalidation generation
+func (cs *CachedStorage) currentGeneration() uint64 {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.generation
+}
+
+// set stores value under key unless an invalidation happened since generation
+func (cs *CachedStorage) set(key, tag string, value any, generation uint64) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.generation != generation {
+		return
+	}
+	cs.entries[key] = cacheEntry{value: value, tag: tag, expiresAt: time.Now().Add(cs.ttl(tag))}
+}
+
+// invalidate evicts tags locally and tells the other instances
+func (cs *CachedStorage) invalidate(tags ...string) {
+	if cs.bus != nil {
+		// The local bus delivers to this instance as well
+		cs.bus.Publish(tags)
+		return
+	}
+	cs.invalidateLocal(tags)
+}
+
+// invalidateLocal evicts every entry with one of tags
+func (cs *CachedStorage) invalidateLocal(tags []string) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.generation++
+	for key, entry := range cs.entries {
+		for _, tag := range tags {
+			if entry.tag == tag {
+				delete(cs.entries, key)
+				cs.invalidations.Add(1)
+				break
+			}
+		}
+	}
+}
+
+// cachedSlice runs a read-through lookup for a slice result
+func cachedSlice[T any](cs *CachedStorage, key, tag string, load func() ([]T, error)) ([]T, error) {
+	if value, ok := cs.get(key); ok {
+		return append([]T(nil), value.([]T)...), nil
+	}
+
+	generation := cs.currentGeneration()
+	result, err := load()
+	if err != nil {
+		return nil, err
+	}
+	cs.set(key, tag, result, generation)
+	return append([]T(nil), result...), nil
+}
+
+// invalidateAfter runs a write and invalidates tags when it succeeds
+func (cs *CachedStorage) invalidateAfter(err error, tags ...string) error {
+	if err == nil {
+		cs.invalidate(tags...)
+	}
+	return err
+}
+
+// ===== Cached reads =====
+
+func (cs *CachedStorage) GetProjects(ctx context.Context) ([]models.Project, error) {
+	return cachedSlice(cs, "projects", CacheTagProjects, func() ([]models.Project, error) {
+		return cs.StorageInterface.GetProjects(ctx)
+	})
+}
+
+func (cs *CachedStorage) GetProjectsByParent(ctx context.Context, parentID *uint, includeChildren bool) ([]models.Project, error) {
+	key := fmt.Sprintf("projects:parent=%s:children=%t", optionalID(parentID), includeChildren)
+	return cachedSlice(cs, key, CacheTagProjects, func() ([]models.Project, error) {
+		return cs.StorageInterface.GetProjectsByParent(ctx, parentID, includeChildren)
+	})
+}
+
+func (cs *CachedStorage) GetProjectTree(ctx context.Context, rootID *uint) ([]*models.ProjectTreeNode, error) {
+	key := "projects:tree=" + optionalID(rootID)
+	return cachedSlice(cs, key, CacheTagProjects, func() ([]*models.ProjectTreeNode, error) {
+		return cs.StorageInterface.GetProjectTree(ctx, rootID)
+	})
+}
+
+func (cs *CachedStorage) GetGroups(ctx context.Context) ([]models.Group, error) {
+	return cachedSlice(cs, "groups", CacheTagGroups, func() ([]models.Group, error) {
+		return cs.StorageInterface.GetGroups(ctx)
+	})
+}
+
+func (cs *CachedStorage) GetGroupsByProject(ctx context.Context, projectID uint) ([]models.Group, error) {
+	key := fmt.Sprintf("groups:project=%d", projectID)
+	return cachedSlice(cs, key, CacheTagGroups, func() ([]models.Group, error) {
+		return cs.StorageInterface.GetGroupsByProject(ctx, projectID)
+	})
+}
+
+func (cs *CachedStorage) GetGroupTree(ctx context.Context, projectID uint, rootID *uint) ([]*models.GroupTreeNode, error) {
+	key := fmt.Sprintf("groups:tree:project=%d:root=%s", projectID, optionalID(rootID))
+	return cachedSlice(cs, key, CacheTagGroups, func() ([]*models.GroupTreeNode, error) {
+		return cs.StorageInterface.GetGroupTree(ctx, projectID, rootID)
+	})
+}
+
+// ===== Invalidating writes =====
+
+func (cs *CachedStorage) CreateProject(ctx context.Context, project *models.Project) error {
+	return cs.invalidateAfter(cs.StorageInterface.CreateProject(ctx, project), CacheTagProjects, CacheTagGroups)
+}
+
+func (cs *CachedStorage) MoveProject(ctx context.Context, params *models.MoveProjectParams) error {
+	return cs.invalidateAfter(cs.StorageInterface.MoveProject(ctx, params), CacheTagProjects, CacheTagGroups)
+}
+
+func (cs *CachedStorage) UpdateProject(ctx context.Context, project *models.Project) error {
+	return cs.invalidateAfter(cs.StorageInterface.UpdateProject(ctx, project), CacheTagProjects, CacheTagGroups)
+}
+
+func (cs *CachedStorage) DeleteProject(ctx context.Context, id uint) error {
+	return cs.invalidateAfter(cs.StorageInterface.DeleteProject(ctx, id), CacheTagProjects, CacheTagGroups)
+}
+
+func (cs *CachedStorage) CreateGroup(ctx context.Context, group *models.Group) error {
+	return cs.invalidateAfter(cs.StorageInterface.CreateGroup(ctx, group), CacheTagProjects, CacheTagGroups)
+}
+
+func (cs *CachedStorage) UpdateGroup(ctx context.Context, group *models.Group) error {
+	return cs.invalidateAfter(cs.StorageInterface.UpdateGroup(ctx, group), CacheTagProjects, CacheTagGroups)
+}
+
+func (cs *CachedStorage) DeleteGroup(ctx context.Context, id uint) error {
+	return cs.invalidateAfter(cs.StorageInterface.DeleteGroup(ctx, id), CacheTagProjects, CacheTagGroups)
+}
+
+func (cs *CachedStorage) MoveGroup(ctx context.Context, params *models.MoveGroupParams) error {
+	return cs.invalidateAfter(cs.StorageInterface.MoveGroup(ctx, params), CacheTagProjects, CacheTagGroups)
+}
+
+func (cs *CachedStorage) CreateHost(ctx context.Context, host *models.Host) error {
+	return cs.invalidateAfter(cs.StorageInterface.CreateHost(ctx, host), CacheTagGroups)
+}
+
+func (cs *CachedStorage) UpdateHost(ctx context.Context, host *models.Host) error {
+	return cs.invalidateAfter(cs.StorageInterface.UpdateHost(ctx, host), CacheTagGroups)
+}
+
+func (cs *CachedStorage) DeleteHost(ctx context.Context, id uint) error {
+	return cs.invalidateAfter(cs.StorageInterface.DeleteHost(ctx, id), CacheTagGroups)
+}
+
+// UndeleteEntity brings back a host or group, which the cached project and
+// group listings include
+func (cs *CachedStorage) UndeleteEntity(ctx context.Context, entityType string, entityID uint) error {
+	return cs.invalidateAfter(cs.StorageInterface.UndeleteEntity(ctx, entityType, entityID), CacheTagProjects, CacheTagGroups)
+}
+
+// ===== Backups =====
+
+// BackupTo snapshots the backend when it supports backups
+func (cs *CachedStorage) BackupTo(ctx context.Context, path string) error {
+	backupper, ok := cs.StorageInterface.(Backupper)
+	if !ok {
+		return ErrBackupNotSupported
+	}
+	return backupper.BackupTo(ctx, path)
+}
+
+// RestoreFrom restores the backend and drops every cached entry
+func (cs *CachedStorage) RestoreFrom(ctx context.Context, path string) error {
+	backupper, ok := cs.StorageInterface.(Backupper)
+	if !ok {
+		return ErrBackupNotSupported
+	}
+	return cs.invalidateAfter(backupper.RestoreFrom(ctx, path), CacheTagProjects, CacheTagGroups)
+}
+
+// ===== Query metrics =====
+
+// QueryStats reports the backend's query metrics when it records them
+func (cs *CachedStorage) QueryStats() QueryStats {
+	reporter, ok := cs.StorageInterface.(QueryStatsReporter)
+	if !ok {
+		return QueryStats{}
+	}
+	return reporter.QueryStats()
+}
+
+// optionalID formats an optional ID for cache keys
+func optionalID(id *uint) string {
+	if id == nil {
+		return "nil"
+	}
+	return fmt.Sprint(*id)
+}
@@ -2,7 +2,9 @@ package storage
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // StorageType represents the type of storage backend
@@ -25,13 +27,14 @@ func DefaultSQLiteConfig() StorageConfig {
 	}
 }
 
-// ValidateConfig validates the storage configuration
+// ValidateConfig validates the storage configuration. Types other than the
+// built-in ones are left to their driver's factory.
 func ValidateConfig(config StorageConfig) error {
 	if config.Type == "" {
 		return fmt.Errorf("storage type is required")
 	}
 
-	switch strings.ToLower(config.Type) {
+	switch DriverName(config.Type) {
 	case string(StorageTypeSQLite):
 		if config.Database == "" {
 			return fmt.Errorf("database file path is required for SQLite")
@@ -46,8 +49,6 @@ func ValidateConfig(config StorageConfig) error {
 		if config.Username == "" {
 			return fmt.Errorf("username is required for %s", config.Type)
 		}
-	default:
-		return fmt.Errorf("unsupported storage type: %s", config.Type)
 	}
 
 	return nil
@@ -56,12 +57,65 @@ func ValidateConfig(config StorageConfig) error {
 // StorageFactory is a function type for creating storage instances
 type StorageFactory func(config StorageConfig) (StorageInterface, error)
 
-// storage factories registry
-var storageFactories = make(map[string]StorageFactory)
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]StorageFactory)
+)
+
+// driverAliases maps alternative type names to registered drivers
+var driverAliases = map[string]string{
+	"sqlite3":    string(StorageTypeSQLite),
+	"postgresql": string(StorageTypePostgres),
+}
+
+// DriverName normalizes a storage type to the name its driver registers
+func DriverName(storageType string) string {
+	name := strings.ToLower(strings.TrimSpace(storageType))
+	if alias, ok := driverAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// Register makes a storage driver available under name, usually from the
+// init function of the package implementing it, so that a blank import is
+// enough to select it with the storage type. It panics if factory is nil or
+// the name is already registered.
+func Register(name string, factory StorageFactory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+	name = DriverName(name)
+	if name == "" {
+		panic("storage: Register name is empty")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("storage: Register called twice for driver " + name)
+	}
+	drivers[name] = factory
+}
 
-// RegisterStorageFactory registers a storage factory for a given type
+// RegisterStorageFactory registers a storage factory for a given type.
+//
+// Deprecated: use Register.
 func RegisterStorageFactory(storageType string, factory StorageFactory) {
-	storageFactories[strings.ToLower(storageType)] = factory
+	Register(storageType, factory)
+}
+
+// Drivers returns the sorted names of the registered storage drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 // NewStorage creates a new storage instance based on configuration
@@ -70,20 +124,15 @@ func NewStorage(config StorageConfig) (StorageInterface, error) {
 		return nil, err
 	}
 
-	storageType := strings.ToLower(config.Type)
-
-	// Try to find registered factory
-	if factory, exists := storageFactories[storageType]; exists {
-		return factory(config)
-	}
-
-	// Handle known aliases
-	switch storageType {
-	case "sqlite3":
-		if factory, exists := storageFactories["sqlite"]; exists {
-			return factory(config)
+	driversMu.RLock()
+	factory, exists := drivers[DriverName(config.Type)]
+	driversMu.RUnlock()
+	if !exists {
+		available := strings.Join(Drivers(), ", ")
+		if available == "" {
+			available = "none"
 		}
+		return nil, fmt.Errorf("unsupported storage type: %s (available drivers: %s)", config.Type, available)
 	}
-
-	return nil, fmt.Errorf("unsupported storage type: %s", config.Type)
+	return factory(config)
 }
@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// Defaults shared by the GORM-based drivers
+const (
+	DefaultMaxOpenConns       = 10
+	DefaultMaxIdleConns       = 5
+	DefaultConnMaxLifetime    = time.Hour
+	DefaultConnMaxIdleTime    = 10 * time.Minute
+	DefaultSlowQueryThreshold = 200 * time.Millisecond
+)
+
+// PoolConfig sizes the database connection pool. Zero fields take the
+// defaults; negative lifetimes keep connections open indefinitely.
+type PoolConfig struct {
+	MaxOpenConns    int           `json:"max_open_conns"`
+	MaxIdleConns    int           `json:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `json:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `json:"conn_max_idle_time"`
+}
+
+// withDefaults fills in the zero fields
+func (p PoolConfig) withDefaults() PoolConfig {
+	if p.MaxOpenConns <= 0 {
+		p.MaxOpenConns = DefaultMaxOpenConns
+	}
+	if p.MaxIdleConns <= 0 {
+		p.MaxIdleConns = DefaultMaxIdleConns
+	}
+	p.MaxIdleConns = min(p.MaxIdleConns, p.MaxOpenConns)
+	if p.ConnMaxLifetime == 0 {
+		p.ConnMaxLifetime = DefaultConnMaxLifetime
+	}
+	if p.ConnMaxIdleTime == 0 {
+		p.ConnMaxIdleTime = DefaultConnMaxIdleTime
+	}
+	return p
+}
+
+// ConfigurePool applies the pool settings to an open GORM connection
+func ConfigurePool(db *gorm.DB, pool PoolConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	pool = pool.withDefaults()
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	return nil
+}
+
+// OperationStats counts the queries of one operation on one table
+type OperationStats struct {
+	Operation    string  `json:"operation"`
+	Table        string  `json:"table,omitempty"`
+	Count        int64   `json:"count"`
+	Errors       int64   `json:"errors"`
+	Slow         int64   `json:"slow"`
+	TotalSeconds float64 `json:"total_seconds"`
+	MaxSeconds   float64 `json:"max_seconds"`
+}
+
+// QueryStats reports query counts and latencies since the storage opened
+type QueryStats struct {
+	Enabled            bool             `json:"enabled"`
+	SlowQueryThreshold time.Duration    `json:"slow_query_threshold"` // 0 when slow-query logging is off
+	Queries            int64            `json:"queries"`
+	Errors             int64            `json:"errors"`
+	Slow               int64            `json:"slow"`
+	TotalSeconds       float64          `json:"total_seconds"`
+	Operations         []OperationStats `json:"operations"` // busiest first
+	Pool               *PoolStats       `json:"pool,omitempty"`
+}
+
+// PoolStats is a snapshot of the connection pool
+type PoolStats struct {
+	MaxOpen       int     `json:"max_open"`
+	Open          int     `json:"open"`
+	InUse         int     `json:"in_use"`
+	Idle          int     `json:"idle"`
+	WaitCount     int64   `json:"wait_count"`
+	WaitSeconds   float64 `json:"wait_seconds"`
+	MaxIdleClosed int64   `json:"max_idle_closed"`
+}
+
+// QueryStatsReporter is implemented by backends that record query metrics
+type QueryStatsReporter interface {
+	QueryStats() QueryStats
+}
+
+// queryStartKey stores the start time on the GORM statement
+const queryStartKey = "portfly:query_start"
+
+// QueryMetrics is a GORM plugin counting queries per operation and table and
+// logging those slower than a threshold
+type QueryMetrics struct {
+	threshold time.Duration
+	logger    utils.Logger
+
+	mu         sync.Mutex
+	operations map[[2]string]*OperationStats
+}
+
+// NewQueryMetrics creates the plugin. A zero threshold means
+// DefaultSlowQueryThreshold; a negative one or a nil logger turns off
+// slow-query logging but keeps the counters.
+func NewQueryMetrics(threshold time.Duration, logger utils.Logger) *QueryMetrics {
+	switch {
+	case logger == nil || threshold < 0:
+		threshold = 0
+	case threshold == 0:
+		threshold = DefaultSlowQueryThreshold
+	}
+	return &QueryMetrics{
+		threshold:  threshold,
+		logger:     logger,
+		operations: make(map[[2]string]*OperationStats),
+	}
+}
+
+// Name returns the plugin name
+func (m *QueryMetrics) Name() string {
+	return "portfly:query_metrics"
+}
+
+// Initialize registers the before/after callbacks for each operation
+func (m *QueryMetrics) Initialize(db *gorm.DB) error {
+	// Method values let us register on GORM's unexported callback types
+	type register func(name string, fn func(*gorm.DB)) error
+	callbacks := db.Callback()
+	operations := []struct {
+		name   string
+		before register
+		after  register
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+
+	for _, op := range operations {
+		if err := op.before("metrics:before_"+op.name, m.before); err != nil {
+			return err
+		}
+		if err := op.after("metrics:after_"+op.name, m.after(op.name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// before records when the statement started
+func (m *QueryMetrics) before(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+// after counts the statement and logs it when slow
+func (m *QueryMetrics) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(start)
+		failed := db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound)
+		slow := m.threshold > 0 && elapsed >= m.threshold
+
+		table := db.Statement.Table
+		m.mu.Lock()
+		stats, exists := m.operations[[2]string{operation, table}]
+		if !exists {
+			stats = &OperationStats{Operation: operation, Table: table}
+			m.operations[[2]string{operation, table}] = stats
+		}
+		stats.Count++
+		stats.TotalSeconds += elapsed.Seconds()
+		stats.MaxSeconds = max(stats.MaxSeconds, elapsed.Seconds())
+		if failed {
+			stats.Errors++
+		}
+		if slow {
+			stats.Slow++
+		}
+		m.mu.Unlock()
+
+		if slow {
+			// The SQL keeps its placeholders, so no values reach the log
+			m.logger.Warn("Slow query",
+				"operation", operation,
+				"table", table,
+				"duration", elapsed.Round(time.Microsecond),
+				"threshold", m.threshold,
+				"rows", db.Statement.RowsAffected,
+				"sql", db.Statement.SQL.String())
+		}
+	}
+}
+
+// Stats returns the counters, busiest operations first
+func (m *QueryMetrics) Stats() QueryStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := QueryStats{
+		Enabled:            true,
+		SlowQueryThreshold: m.threshold,
+		Operations:         make([]OperationStats, 0, len(m.operations)),
+	}
+	for _, op := range m.operations {
+		stats.Queries += op.Count
+		stats.Errors += op.Errors
+		stats.Slow += op.Slow
+		stats.TotalSeconds += op.TotalSeconds
+		stats.Operations = append(stats.Operations, *op)
+	}
+	sortOperations(stats.Operations)
+	return stats
+}
+
+// sortOperations orders by total time spent, then by name
+func sortOperations(ops []OperationStats) {
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].TotalSeconds != ops[j].TotalSeconds {
+			return ops[i].TotalSeconds > ops[j].TotalSeconds
+		}
+		if ops[i].Operation != ops[j].Operation {
+			return ops[i].Operation < ops[j].Operation
+		}
+		return ops[i].Table < ops[j].Table
+	})
+}
+
+// PoolSnapshot returns the pool statistics of a GORM connection
+func PoolSnapshot(db *gorm.DB) *PoolStats {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil
+	}
+	s := sqlDB.Stats()
+	return &PoolStats{
+		MaxOpen:       s.MaxOpenConnections,
+		Open:          s.OpenConnections,
+		InUse:         s.InUse,
+		Idle:          s.Idle,
+		WaitCount:     s.WaitCount,
+		WaitSeconds:   s.WaitDuration.Seconds(),
+		MaxIdleClosed: s.MaxIdleClosed,
+	}
+}
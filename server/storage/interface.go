@@ -2,8 +2,18 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// Name lookup and uniqueness errors
+var (
+	ErrNameConflict  = errors.New("name already in use")
+	ErrNameNotFound  = errors.New("no resource with that name")
+	ErrAmbiguousName = errors.New("name matches more than one resource")
 )
 
 // StorageInterface defines the contract for data storage
@@ -25,15 +35,22 @@ type StorageInterface interface {
 	DeleteProject(ctx context.Context, id uint) error
 	GetProjectStats(ctx context.Context, projectID uint) (*models.ProjectStats, error)
 	GetProjectChildren(ctx context.Context, parentID uint) ([]models.Project, error)
+	GetProjectUsage(ctx context.Context, projectID uint) (*models.QuotaUsage, error) // quota limits and current usage
+	GetProjectDashboard(ctx context.Context, projectID uint) (*models.ProjectDashboard, error)
 
 	// ===== Group Operations =====
 	CreateGroup(ctx context.Context, group *models.Group) error
 	GetGroup(ctx context.Context, id uint) (*models.Group, error)
+	GetGroupByName(ctx context.Context, name string, projectID uint) (*models.Group, error) // projectID 0 searches all projects
 	GetGroups(ctx context.Context) ([]models.Group, error)
 	GetGroupsByProject(ctx context.Context, projectID uint) ([]models.Group, error)
 	UpdateGroup(ctx context.Context, group *models.Group) error
 	DeleteGroup(ctx context.Context, id uint) error
 	GetGroupStats(ctx context.Context, groupID uint) (*models.GroupStats, error)
+	GetGroupChildren(ctx context.Context, parentID uint) ([]models.Group, error)
+	GetGroupAncestors(ctx context.Context, groupID uint) ([]models.Group, error)                     // nearest first
+	GetGroupTree(ctx context.Context, projectID uint, rootID *uint) ([]*models.GroupTreeNode, error) // projectID 0 covers all projects
+	MoveGroup(ctx context.Context, params *models.MoveGroupParams) error
 
 	// ===== Host Operations =====
 	CreateHost(ctx context.Context, host *models.Host) error
@@ -43,11 +60,29 @@ type StorageInterface interface {
 	UpdateHost(ctx context.Context, host *models.Host) error
 	DeleteHost(ctx context.Context, id uint) error
 	GetHostStats(ctx context.Context, hostID uint) (*models.HostStats, error)
+	// GetActiveTunnelCounts returns the number of active tunnel sessions by
+	// host, leaving out hosts without any
+	GetActiveTunnelCounts(ctx context.Context) (map[uint]int, error)
 	SearchHosts(ctx context.Context, query string) ([]models.Host, error)
+	// GetHostsByMetadata returns the hosts whose metadata matches every filter
+	GetHostsByMetadata(ctx context.Context, filters []models.MetadataFilter) ([]models.Host, error)
+
+	// SetHostOSFamily saves the detected operating system family of a host
+	SetHostOSFamily(ctx context.Context, hostID uint, family string) error
+
+	// ===== Host Auth Status Operations (SSH authentication failures) =====
+	// GetHostAuthStatus returns a host's authentication record; a host
+	// without recorded attempts gets an empty one
+	GetHostAuthStatus(ctx context.Context, hostID uint) (*models.HostAuthStatus, error)
+	SaveHostAuthStatus(ctx context.Context, status *models.HostAuthStatus) error
+	// GetAuthFailures lists the hosts whose last attempts failed, most
+	// consecutive failures first; projectID and groupID 0 cover all
+	GetAuthFailures(ctx context.Context, projectID, groupID uint) ([]models.HostAuthStatus, error)
 
 	// ===== Port Operations =====
 	CreatePort(ctx context.Context, port *models.Port) error
 	GetPort(ctx context.Context, id uint) (*models.Port, error)
+	GetPortByName(ctx context.Context, name string, groupID uint) (*models.Port, error) // groupID 0 searches all groups
 	GetPorts(ctx context.Context) ([]models.Port, error)
 	GetPortsByGroup(ctx context.Context, groupID uint) ([]models.Port, error)
 	GetPortsByHost(ctx context.Context, hostID uint) ([]models.Port, error)
@@ -55,7 +90,64 @@ type StorageInterface interface {
 	DeletePort(ctx context.Context, id uint) error
 	GetPortStats(ctx context.Context, portID uint) (*models.PortStats, error)
 	SearchPorts(ctx context.Context, query string) ([]models.Port, error)
-	UpdatePortStatus(ctx context.Context, portID uint, status models.PortStatus) error
+	// GetPortsByMetadata returns the ports whose metadata matches every filter
+	GetPortsByMetadata(ctx context.Context, filters []models.MetadataFilter) ([]models.Port, error)
+	// UpdatePortStatus applies a status transition, recording cause as the
+	// port's last error when set; invalid transitions return ErrInvalidPortTransition
+	UpdatePortStatus(ctx context.Context, portID uint, status models.PortStatus, cause *models.PortError) error
+	// UpdatePortBackoff moves a port into backoff while its tunnel waits to
+	// reconnect, with the retry message and the failure as its last error
+	UpdatePortBackoff(ctx context.Context, portID uint, attempt int, delay time.Duration, cause *models.PortError) error
+	// UpdatePortWebInfo records what was detected behind an HTTP port
+	UpdatePortWebInfo(ctx context.Context, portID uint, info *models.WebInfo) error
+
+	// ===== Port Grant Operations =====
+	CreatePortGrant(ctx context.Context, grant *models.PortGrant) error
+	GetPortGrant(ctx context.Context, id uint) (*models.PortGrant, error)
+	GetPortGrantByToken(ctx context.Context, tokenHash string) (*models.PortGrant, error)
+	GetPortGrants(ctx context.Context, portID uint, includeArchived bool) ([]models.PortGrant, error) // portID 0 lists grants of all ports
+	UpdatePortGrant(ctx context.Context, grant *models.PortGrant) error
+
+	// ===== Port Reservation Operations =====
+	GetPortReservations(ctx context.Context, projectID uint) ([]models.PortReservation, error) // projectID 0 lists reservations of all projects
+	// ReservePort reserves the reservation's ports, replacing the earlier
+	// reservation of the same port; ports another project or user holds
+	// return ErrPortReserved
+	ReservePort(ctx context.Context, reservation *models.PortReservation) error
+	// AllocatePort reserves the first free block of size ports in pool and
+	// sets the reservation's ports; reserved ports and the ports of local
+	// ports count as taken. A full pool returns ErrNoFreePort
+	AllocatePort(ctx context.Context, pool models.PortPool, size int, reservation *models.PortReservation) error
+	// PlanPorts returns the first ports of the next count free blocks of size
+	// ports in pool, without reserving them
+	PlanPorts(ctx context.Context, pool models.PortPool, size, count int) ([]int, error)
+	DeletePortReservation(ctx context.Context, id uint) error
+	ReleasePortReservation(ctx context.Context, portID uint) error // drops the reservation a port holds, if any
+
+	// ===== Snippet Operations =====
+	CreateSnippet(ctx context.Context, snippet *models.Snippet) error
+	GetSnippet(ctx context.Context, id uint) (*models.Snippet, error)
+	GetSnippets(ctx context.Context) ([]models.Snippet, error)
+	UpdateSnippet(ctx context.Context, snippet *models.Snippet) error
+	DeleteSnippet(ctx context.Context, id uint) error
+
+	// ===== Port Template Operations =====
+	CreatePortTemplate(ctx context.Context, template *models.PortTemplate) error
+	GetPortTemplate(ctx context.Context, id uint) (*models.PortTemplate, error)
+	GetPortTemplates(ctx context.Context) ([]models.PortTemplate, error)
+	UpdatePortTemplate(ctx context.Context, template *models.PortTemplate) error
+	DeletePortTemplate(ctx context.Context, id uint) error
+
+	// ===== Port Favorite Operations (per-user quick access) =====
+	// GetPortFavorites lists an owner's favorites in panel order, with their
+	// ports; favorites of deleted ports are left out
+	GetPortFavorites(ctx context.Context, owner string) ([]models.PortFavorite, error)
+	// AddPortFavorite appends a port to the owner's favorites; a port
+	// already there keeps its position
+	AddPortFavorite(ctx context.Context, favorite *models.PortFavorite) error
+	DeletePortFavorite(ctx context.Context, owner string, portID uint) error
+	// SetPortFavorites replaces the owner's favorites with portIDs, in order
+	SetPortFavorites(ctx context.Context, owner string, portIDs []uint) error
 
 	// ===== Port Connection Operations =====
 	CreatePortConnection(ctx context.Context, connection *models.PortConnection) error
@@ -69,11 +161,78 @@ type StorageInterface interface {
 	// ===== Tunnel Session Operations =====
 	CreateTunnelSession(ctx context.Context, session *models.TunnelSession) error
 	GetTunnelSession(ctx context.Context, id uint) (*models.TunnelSession, error)
+	GetTunnelSessionByName(ctx context.Context, name string, hostID uint) (*models.TunnelSession, error) // hostID 0 searches all hosts
 	GetTunnelSessions(ctx context.Context) ([]models.TunnelSession, error)
 	GetActiveTunnelSessions(ctx context.Context) ([]models.TunnelSession, error)
 	UpdateTunnelSession(ctx context.Context, session *models.TunnelSession) error
 	DeleteTunnelSession(ctx context.Context, id uint) error
 	GetSessionStats(ctx context.Context) (*models.SessionStats, error)
+	// PruneTunnelSessions deletes stopped and failed sessions that ended
+	// before the cutoff, batchSize rows at a time, and returns the count
+	PruneTunnelSessions(ctx context.Context, before time.Time, batchSize int) (int64, error)
+
+	// ===== Terminal Record Operations (web terminal restore) =====
+	CreateTerminalRecord(ctx context.Context, record *models.TerminalRecord) error
+	GetTerminalRecordByToken(ctx context.Context, token string) (*models.TerminalRecord, error)
+	GetInterruptedTerminalRecords(ctx context.Context) ([]models.TerminalRecord, error)
+	UpdateTerminalRecord(ctx context.Context, record *models.TerminalRecord) error
+	DeleteTerminalRecord(ctx context.Context, id uint) error
+	// InterruptTerminalRecords marks open terminals as interrupted at the given
+	// time, deletes interrupted ones older than before, and returns the count marked
+	InterruptTerminalRecords(ctx context.Context, at, before time.Time) (int64, error)
+
+	// ===== Terminal Closure Operations (terminal policy audit) =====
+	CreateTerminalClosure(ctx context.Context, closure *models.TerminalClosure) error
+	// GetTerminalClosures lists enforced terminal closures, newest first;
+	// hostID 0 covers all hosts
+	GetTerminalClosures(ctx context.Context, hostID uint, limit int) ([]models.TerminalClosure, error)
+
+	// ===== Reachability Operations (host-to-host probes) =====
+	// SaveReachability stores a probe result, replacing the previous one for
+	// the same group, source host, target host and port
+	SaveReachability(ctx context.Context, result *models.Reachability) error
+	// GetReachability lists a group's latest probe results by source host
+	GetReachability(ctx context.Context, groupID uint) ([]models.Reachability, error)
+
+	// ===== Change History Operations (hosts, ports and groups) =====
+	// CreateChangeRecord stores a record as the entity's next version
+	CreateChangeRecord(ctx context.Context, record *models.ChangeRecord) error
+	// GetChangeHistory lists an entity's versions, newest first; limit 0 means all
+	GetChangeHistory(ctx context.Context, entityType string, entityID uint, limit int) ([]models.ChangeRecord, error)
+	GetChangeRecord(ctx context.Context, entityType string, entityID uint, version int) (*models.ChangeRecord, error)
+	// UndeleteEntity brings back a deleted host, port or group
+	UndeleteEntity(ctx context.Context, entityType string, entityID uint) error
+
+	// ===== Status Page Operations (checks of published ports) =====
+	CreateStatusCheck(ctx context.Context, check *models.StatusCheck) error
+	// GetStatusUptime counts a port's checks since the given time
+	GetStatusUptime(ctx context.Context, portID uint, since time.Time) (*models.StatusUptime, error)
+	// GetRecentStatusChecks returns a port's latest checks, newest first
+	GetRecentStatusChecks(ctx context.Context, portID uint, limit int) ([]models.StatusCheck, error)
+	// DeleteStatusChecksBefore prunes checks older than before
+	DeleteStatusChecksBefore(ctx context.Context, before time.Time) (int64, error)
+
+	// ===== Report Operations =====
+	// GetUsageReport lists the ports, hosts and groups unused since the given
+	// time; Days and GeneratedAt are left to the caller
+	GetUsageReport(ctx context.Context, since time.Time) (*models.UsageReport, error)
+
+	// ===== Lease Operations (multi-instance coordination) =====
+	// AcquireLease takes or renews the named lease for owner; it returns false
+	// when another owner holds an unexpired lease
+	AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, owner string) error
+	GetLeases(ctx context.Context) ([]models.Lease, error)
+}
+
+// ErrBackupNotSupported is returned by backends without online snapshots
+var ErrBackupNotSupported = errors.New("storage backend does not support backups")
+
+// Backupper is implemented by backends that can snapshot and restore the
+// live database without stopping the server
+type Backupper interface {
+	BackupTo(ctx context.Context, path string) error
+	RestoreFrom(ctx context.Context, path string) error
 }
 
 // StorageConfig contains storage configuration
@@ -86,4 +245,11 @@ type StorageConfig struct {
 	Password string            `json:"password"`
 	SSLMode  string            `json:"ssl_mode"`
 	Options  map[string]string `json:"options"`
+
+	Pool PoolConfig `json:"pool"`
+	// SlowQueryThreshold logs queries taking at least this long; 0 uses
+	// DefaultSlowQueryThreshold and a negative value turns logging off
+	SlowQueryThreshold time.Duration `json:"slow_query_threshold"`
+	// Logger receives slow-query logs
+	Logger utils.Logger `json:"-"`
 }
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// WhereMetadata narrows a query to rows whose metadata column matches every
+// filter. Values are compared in their JSON form, so each driver extracts the
+// value at the filter's path as JSON text: "prod" for strings, 3 for numbers.
+func WhereMetadata(db *gorm.DB, column string, filters []models.MetadataFilter) *gorm.DB {
+	for _, filter := range filters {
+		switch db.Dialector.Name() {
+		case "postgres":
+			path := "{" + strings.Join(filter.Path, ",") + "}"
+			db = db.Where("("+column+"::jsonb #> ?)::text IN ?", path, filter.JSONValues())
+		case "mysql":
+			db = db.Where("CAST(JSON_EXTRACT("+column+", ?) AS CHAR) IN ?", filter.JSONPath(), filter.JSONValues())
+		default:
+			// SQLite's -> returns the JSON representation of the value
+			db = db.Where(column+" -> ? IN ?", filter.JSONPath(), filter.JSONValues())
+		}
+	}
+	return db
+}
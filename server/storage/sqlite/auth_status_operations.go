@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ===== Host Auth Status Operations =====
+
+// GetHostAuthStatus returns a host's authentication record, or an empty one
+// for a host without recorded attempts
+func (s *SQLiteStorage) GetHostAuthStatus(ctx context.Context, hostID uint) (*models.HostAuthStatus, error) {
+	var status models.HostAuthStatus
+	err := s.db.WithContext(ctx).First(&status, "host_id = ?", hostID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.HostAuthStatus{HostID: hostID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host auth status: %w", err)
+	}
+	return &status, nil
+}
+
+// SaveHostAuthStatus inserts or replaces a host's authentication record
+func (s *SQLiteStorage) SaveHostAuthStatus(ctx context.Context, status *models.HostAuthStatus) error {
+	if err := s.db.WithContext(ctx).Save(status).Error; err != nil {
+		return fmt.Errorf("failed to save host auth status: %w", err)
+	}
+	return nil
+}
+
+// GetAuthFailures lists the hosts whose last attempts failed with their
+// names and groups, most consecutive failures first; projectID and groupID
+// 0 cover all projects and groups. Deleted hosts are left out.
+func (s *SQLiteStorage) GetAuthFailures(ctx context.Context, projectID, groupID uint) ([]models.HostAuthStatus, error) {
+	query := s.db.WithContext(ctx).
+		Select("host_auth_statuses.*, hosts.name AS host_name, hosts.group_id AS group_id").
+		Joins("JOIN hosts ON hosts.id = host_auth_statuses.host_id AND hosts.deleted_at IS NULL").
+		Where("host_auth_statuses.consecutive_failures > 0")
+	if groupID != 0 {
+		query = query.Where("hosts.group_id = ?", groupID)
+	}
+	if projectID != 0 {
+		query = query.Joins("JOIN groups ON groups.id = hosts.group_id").Where("groups.project_id = ?", projectID)
+	}
+
+	statuses := []models.HostAuthStatus{}
+	if err := query.Order("host_auth_statuses.consecutive_failures DESC, host_auth_statuses.last_failure_at DESC").
+		Find(&statuses).Error; err != nil {
+		return nil, fmt.Errorf("failed to get auth failures: %w", err)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// BackupTo writes a consistent snapshot of the live database to path using
+// SQLite's online backup API; writers are not blocked while it runs
+func (s *SQLiteStorage) BackupTo(ctx context.Context, path string) error {
+	dest, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("failed to create backup database: %w", err)
+	}
+	defer dest.Close()
+
+	src, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	if err := copyDatabase(ctx, dest, src); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// RestoreFrom replaces the contents of the live database with the snapshot
+// at path. The snapshot is checked for integrity before anything is copied.
+func (s *SQLiteStorage) RestoreFrom(ctx context.Context, path string) error {
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer src.Close()
+
+	var result string
+	if err := src.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("backup is not a valid database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup failed integrity check: %s", result)
+	}
+
+	dest, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	// Hold back other writers while the pages are replaced
+	if err := s.writes.acquire(ctx); err != nil {
+		return err
+	}
+	defer s.writes.release()
+	if err := copyDatabase(ctx, dest, src); err != nil {
+		return fmt.Errorf("failed to restore database: %w", err)
+	}
+	return nil
+}
+
+// copyDatabase copies the main database of src over the one of dest
+func copyDatabase(ctx context.Context, dest, src *sql.DB) error {
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriver any) error {
+		return srcConn.Raw(func(srcDriver any) error {
+			destSQLite, ok := destDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", destDriver)
+			}
+			srcSQLite, ok := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", srcDriver)
+			}
+
+			backup, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ===== Port Grant Operations =====
+
+func (s *SQLiteStorage) CreatePortGrant(ctx context.Context, grant *models.PortGrant) error {
+	if err := grant.Validate(); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Create(grant).Error; err != nil {
+		return fmt.Errorf("failed to create port grant: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) GetPortGrant(ctx context.Context, id uint) (*models.PortGrant, error) {
+	var grant models.PortGrant
+	if err := s.db.WithContext(ctx).First(&grant, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get port grant: %w", err)
+	}
+	return &grant, nil
+}
+
+// GetPortGrantByToken finds the grant issued with a token, by its hash
+func (s *SQLiteStorage) GetPortGrantByToken(ctx context.Context, tokenHash string) (*models.PortGrant, error) {
+	var grant models.PortGrant
+	if err := s.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&grant).Error; err != nil {
+		return nil, fmt.Errorf("failed to get port grant: %w", err)
+	}
+	return &grant, nil
+}
+
+// GetPortGrants lists grants, newest first; archived grants are the history
+func (s *SQLiteStorage) GetPortGrants(ctx context.Context, portID uint, includeArchived bool) ([]models.PortGrant, error) {
+	query := s.db.WithContext(ctx).Order("created_at DESC")
+	if portID != 0 {
+		query = query.Where("port_id = ?", portID)
+	}
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+
+	var grants []models.PortGrant
+	if err := query.Find(&grants).Error; err != nil {
+		return nil, fmt.Errorf("failed to get port grants: %w", err)
+	}
+	return grants, nil
+}
+
+func (s *SQLiteStorage) UpdatePortGrant(ctx context.Context, grant *models.PortGrant) error {
+	if err := s.db.WithContext(ctx).Save(grant).Error; err != nil {
+		return fmt.Errorf("failed to update port grant: %w", err)
+	}
+	return nil
+}
@@ -2,25 +2,76 @@ package sqlite
 
 import (
 	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
 
 	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/storage"
 )
 
 // ===== Group Operations =====
 
 func (s *SQLiteStorage) CreateGroup(ctx context.Context, group *models.Group) error {
-	return s.db.WithContext(ctx).Create(group).Error
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 如果设置了父分组，需要计算层级和路径
+		parentPath := ""
+		group.Level = 0
+		if group.ParentID != nil {
+			var parent models.Group
+			if err := tx.First(&parent, *group.ParentID).Error; err != nil {
+				return fmt.Errorf("parent group not found: %w", err)
+			}
+			if parent.ProjectID != group.ProjectID {
+				return models.ErrGroupParentProject
+			}
+			group.Level = parent.Level + 1
+			parentPath = parent.Path
+		}
+
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+
+		// 更新路径
+		group.Path = group.BuildPath(parentPath)
+		return tx.Model(group).Update("path", group.Path).Error
+	})
 }
 
 func (s *SQLiteStorage) GetGroup(ctx context.Context, id uint) (*models.Group, error) {
 	var group models.Group
-	err := s.db.WithContext(ctx).Preload("Project").Preload("Hosts").Preload("PortForwards").First(&group, id).Error
+	err := s.db.WithContext(ctx).Preload("Project").Preload("Hosts").Preload("PortForwards").
+		Preload("Parent").Preload("Children").First(&group, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &group, nil
 }
 
+// GetGroupByName retrieves a group by name; projectID 0 searches all projects
+func (s *SQLiteStorage) GetGroupByName(ctx context.Context, name string, projectID uint) (*models.Group, error) {
+	query := s.db.WithContext(ctx).Model(&models.Group{}).Where("name = ?", name)
+	if projectID != 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	var ids []uint
+	if err := query.Limit(2).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up group: %w", err)
+	}
+
+	switch len(ids) {
+	case 0:
+		return nil, fmt.Errorf("%w: group %q", storage.ErrNameNotFound, name)
+	case 1:
+		return s.GetGroup(ctx, ids[0])
+	default:
+		return nil, fmt.Errorf("%w: group %q exists in several projects, specify project_id", storage.ErrAmbiguousName, name)
+	}
+}
+
 func (s *SQLiteStorage) GetGroups(ctx context.Context) ([]models.Group, error) {
 	var groups []models.Group
 	err := s.db.WithContext(ctx).Preload("Project").Preload("Hosts").Preload("PortForwards").Find(&groups).Error
@@ -33,14 +84,170 @@ func (s *SQLiteStorage) GetGroupsByProject(ctx context.Context, projectID uint)
 	return groups, err
 }
 
+// UpdateGroup saves a group; its place in the tree only changes through MoveGroup
 func (s *SQLiteStorage) UpdateGroup(ctx context.Context, group *models.Group) error {
+	var existing models.Group
+	if err := s.db.WithContext(ctx).First(&existing, group.ID).Error; err != nil {
+		return err
+	}
+
+	if group.ProjectID != existing.ProjectID {
+		nested := existing.ParentID != nil
+		if !nested {
+			var children int64
+			if err := s.db.WithContext(ctx).Model(&models.Group{}).Where("parent_id = ?", group.ID).Count(&children).Error; err != nil {
+				return err
+			}
+			nested = children > 0
+		}
+		if nested {
+			return models.ErrNestedGroupProject
+		}
+	}
+
+	group.ParentID = existing.ParentID
+	group.Level = existing.Level
+	group.Path = existing.Path
+	group.Sort = existing.Sort
 	return s.db.WithContext(ctx).Save(group).Error
 }
 
+// DeleteGroup deletes a group without subgroups
 func (s *SQLiteStorage) DeleteGroup(ctx context.Context, id uint) error {
+	var children int64
+	if err := s.db.WithContext(ctx).Model(&models.Group{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+		return err
+	}
+	if children > 0 {
+		return models.ErrGroupHasChildren
+	}
 	return s.db.WithContext(ctx).Delete(&models.Group{}, id).Error
 }
 
+// GetGroupChildren lists the direct subgroups of a group
+func (s *SQLiteStorage) GetGroupChildren(ctx context.Context, parentID uint) ([]models.Group, error) {
+	var children []models.Group
+	err := s.db.WithContext(ctx).
+		Preload("Hosts").
+		Where("parent_id = ?", parentID).
+		Order("sort ASC, name ASC").
+		Find(&children).Error
+	return children, err
+}
+
+// GetGroupAncestors lists a group's ancestors, nearest first
+func (s *SQLiteStorage) GetGroupAncestors(ctx context.Context, groupID uint) ([]models.Group, error) {
+	var group models.Group
+	if err := s.db.WithContext(ctx).First(&group, groupID).Error; err != nil {
+		return nil, err
+	}
+
+	ids := group.AncestorIDs()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var ancestors []models.Group
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Order("level DESC").Find(&ancestors).Error; err != nil {
+		return nil, fmt.Errorf("failed to get group ancestors: %w", err)
+	}
+	return ancestors, nil
+}
+
+// GetGroupTree builds the group tree of a project, or of all projects when
+// projectID is 0; with rootID it returns the subgroups below that group
+func (s *SQLiteStorage) GetGroupTree(ctx context.Context, projectID uint, rootID *uint) ([]*models.GroupTreeNode, error) {
+	query := s.db.WithContext(ctx).Preload("Hosts").Order("sort ASC, name ASC")
+	if projectID != 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if rootID != nil {
+		var root models.Group
+		if err := s.db.WithContext(ctx).First(&root, *rootID).Error; err != nil {
+			return nil, fmt.Errorf("root group not found: %w", err)
+		}
+		query = query.Where("path LIKE ?", root.Path+"/%")
+	}
+
+	var groups []models.Group
+	if err := query.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return buildGroupTree(groups, rootID), nil
+}
+
+// buildGroupTree links groups to their parents, keeping the query's order
+// among siblings
+func buildGroupTree(groups []models.Group, rootID *uint) []*models.GroupTreeNode {
+	nodes := make(map[uint]*models.GroupTreeNode, len(groups))
+	for i := range groups {
+		nodes[groups[i].ID] = &models.GroupTreeNode{Group: &groups[i]}
+	}
+
+	roots := []*models.GroupTreeNode{}
+	for i := range groups {
+		node := nodes[groups[i].ID]
+		parentID := node.ParentID
+		if parentID == nil || (rootID != nil && *parentID == *rootID) {
+			roots = append(roots, node)
+		} else if parent, exists := nodes[*parentID]; exists {
+			parent.Children = append(parent.Children, node)
+			parent.HasChildren = true
+		}
+	}
+	return roots
+}
+
+// MoveGroup moves a group, with its subgroups, under another group of the
+// same project or to the top level
+func (s *SQLiteStorage) MoveGroup(ctx context.Context, params *models.MoveGroupParams) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var group models.Group
+		if err := tx.First(&group, params.GroupID).Error; err != nil {
+			return fmt.Errorf("group not found: %w", err)
+		}
+
+		parentPath := ""
+		group.Level = 0
+		if params.ParentID != nil {
+			var parent models.Group
+			if err := tx.First(&parent, *params.ParentID).Error; err != nil {
+				return fmt.Errorf("parent group not found: %w", err)
+			}
+			if parent.ProjectID != group.ProjectID {
+				return models.ErrGroupParentProject
+			}
+			// 检查是否形成循环
+			if parent.ID == group.ID || group.IsAncestorOf(&parent) {
+				return models.ErrGroupCycle
+			}
+			group.Level = parent.Level + 1
+			parentPath = parent.Path
+		}
+
+		oldPath := group.Path
+		group.ParentID = params.ParentID
+		group.Path = group.BuildPath(parentPath)
+		group.Sort = params.Position
+		if err := tx.Model(&group).Select("parent_id", "level", "path", "sort").Updates(&group).Error; err != nil {
+			return err
+		}
+
+		// 更新所有子分组的层级和路径
+		var descendants []models.Group
+		if err := tx.Where("path LIKE ?", oldPath+"/%").Find(&descendants).Error; err != nil {
+			return err
+		}
+		for _, descendant := range descendants {
+			path := group.Path + strings.TrimPrefix(descendant.Path, oldPath)
+			level := group.Level + strings.Count(path, "/") - strings.Count(group.Path, "/")
+			if err := tx.Model(&descendant).Updates(map[string]interface{}{"path": path, "level": level}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (s *SQLiteStorage) GetGroupStats(ctx context.Context, groupID uint) (*models.GroupStats, error) {
 	var stats models.GroupStats
 
@@ -67,5 +274,13 @@ func (s *SQLiteStorage) GetGroupStats(ctx context.Context, groupID uint) (*model
 		Count(&tunnelCount)
 	stats.ActiveTunnels = int(tunnelCount)
 
+	// Hosts failing authentication
+	authFailures, err := s.GetAuthFailures(ctx, 0, groupID)
+	if err != nil {
+		return nil, err
+	}
+	stats.AuthFailingHosts = len(authFailures)
+	stats.AuthFailures = authFailures
+
 	return &stats, nil
 }
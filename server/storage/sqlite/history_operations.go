@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ===== Change History Operations =====
+
+// CreateChangeRecord numbers the record after the entity's latest version
+// within the same transaction, so concurrent changes get distinct versions
+func (s *SQLiteStorage) CreateChangeRecord(ctx context.Context, record *models.ChangeRecord) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&models.ChangeRecord{}).
+			Where("entity_type = ? AND entity_id = ?", record.EntityType, record.EntityID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+		record.Version = latest + 1
+		return tx.Create(record).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create change record: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) GetChangeHistory(ctx context.Context, entityType string, entityID uint, limit int) ([]models.ChangeRecord, error) {
+	var records []models.ChangeRecord
+	query := s.db.WithContext(ctx).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("version DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to get change history: %w", err)
+	}
+	return records, nil
+}
+
+func (s *SQLiteStorage) GetChangeRecord(ctx context.Context, entityType string, entityID uint, version int) (*models.ChangeRecord, error) {
+	var record models.ChangeRecord
+	if err := s.db.WithContext(ctx).
+		Where("entity_type = ? AND entity_id = ? AND version = ?", entityType, entityID, version).
+		First(&record).Error; err != nil {
+		return nil, fmt.Errorf("failed to get change record: %w", err)
+	}
+	return &record, nil
+}
+
+// UndeleteEntity clears the soft-delete mark of a host, port or group
+func (s *SQLiteStorage) UndeleteEntity(ctx context.Context, entityType string, entityID uint) error {
+	var model any
+	switch entityType {
+	case models.EntityHost:
+		model = &models.Host{}
+	case models.EntityPort:
+		model = &models.Port{}
+	case models.EntityGroup:
+		model = &models.Group{}
+	default:
+		return fmt.Errorf("unknown entity type: %s", entityType)
+	}
+
+	result := s.db.WithContext(ctx).Unscoped().Model(model).
+		Where("id = ? AND deleted_at IS NOT NULL", entityID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return fmt.Errorf("failed to undelete %s: %w", entityType, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to undelete %s %d: %w", entityType, entityID, gorm.ErrRecordNotFound)
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package sqlite
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/storage"
 )
 
 // ===== Host Operations =====
@@ -60,12 +62,58 @@ func (s *SQLiteStorage) GetHostStats(ctx context.Context, hostID uint) (*models.
 	// Calculate uptime percentage (simplified)
 	stats.UptimePercentage = 95.0 // TODO: implement real calculation
 
+	// Authentication failures, once the host was connected to
+	authStatus, err := s.GetHostAuthStatus(ctx, hostID)
+	if err != nil {
+		return nil, err
+	}
+	if authStatus.LastAttemptAt != nil {
+		stats.AuthFailures = authStatus
+	}
+
 	return &stats, nil
 }
 
+// SetHostOSFamily updates only the OS family, leaving concurrent edits of the
+// host's other fields alone
+func (s *SQLiteStorage) SetHostOSFamily(ctx context.Context, hostID uint, family string) error {
+	if err := s.db.WithContext(ctx).Model(&models.Host{}).Where("id = ?", hostID).Update("os_family", family).Error; err != nil {
+		return fmt.Errorf("failed to set host os family: %w", err)
+	}
+	return nil
+}
+
+// GetActiveTunnelCounts counts the active tunnel sessions of all hosts in
+// one query, as GetHostStats does for a single host
+func (s *SQLiteStorage) GetActiveTunnelCounts(ctx context.Context) (map[uint]int, error) {
+	var rows []struct {
+		HostID uint
+		Count  int
+	}
+	if err := s.db.WithContext(ctx).Model(&models.TunnelSession{}).
+		Select("host_id, COUNT(*) AS count").
+		Where("status = ?", "active").
+		Group("host_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count active tunnels: %w", err)
+	}
+
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.HostID] = row.Count
+	}
+	return counts, nil
+}
+
 func (s *SQLiteStorage) SearchHosts(ctx context.Context, query string) ([]models.Host, error) {
 	var hosts []models.Host
 	searchPattern := "%" + query + "%"
 	err := s.db.WithContext(ctx).Preload("Group").Where("name LIKE ? OR hostname LIKE ? OR description LIKE ?", searchPattern, searchPattern, searchPattern).Find(&hosts).Error
 	return hosts, err
 }
+
+func (s *SQLiteStorage) GetHostsByMetadata(ctx context.Context, filters []models.MetadataFilter) ([]models.Host, error) {
+	var hosts []models.Host
+	err := storage.WhereMetadata(s.db.WithContext(ctx).Preload("Group"), "metadata", filters).Find(&hosts).Error
+	return hosts, err
+}
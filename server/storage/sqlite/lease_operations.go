@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ===== Lease Operations =====
+
+// AcquireLease inserts the lease, or takes it over when it is ours or expired.
+// The conditional upsert is a single statement, so two instances racing for
+// the same lease cannot both win.
+func (s *SQLiteStorage) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	lease := models.Lease{
+		Name:       name,
+		Owner:      owner,
+		ExpiresAt:  now.Add(ttl),
+		AcquiredAt: now,
+		UpdatedAt:  now,
+	}
+
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "name"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "owner"}, Value: owner},
+			{Column: clause.Column{Name: "expires_at"}, Value: lease.ExpiresAt},
+			{Column: clause.Column{Name: "updated_at"}, Value: now},
+			// Keep the original acquisition time on renewal
+			{Column: clause.Column{Name: "acquired_at"}, Value: clause.Expr{
+				SQL:  "CASE WHEN leases.owner = ? THEN leases.acquired_at ELSE ? END",
+				Vars: []interface{}{owner, now},
+			}},
+		},
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "leases.owner = ? OR leases.expires_at < ?", Vars: []interface{}{owner, now}},
+		}},
+	}).Create(&lease)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to acquire lease %s: %w", name, result.Error)
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseLease deletes the lease if owner still holds it
+func (s *SQLiteStorage) ReleaseLease(ctx context.Context, name, owner string) error {
+	err := s.db.WithContext(ctx).
+		Where("name = ? AND owner = ?", name, owner).
+		Delete(&models.Lease{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetLeases retrieves all leases, including expired ones
+func (s *SQLiteStorage) GetLeases(ctx context.Context) ([]models.Lease, error) {
+	var leases []models.Lease
+	if err := s.db.WithContext(ctx).Order("name").Find(&leases).Error; err != nil {
+		return nil, fmt.Errorf("failed to get leases: %w", err)
+	}
+	return leases, nil
+}
@@ -2,9 +2,12 @@ package sqlite
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/storage"
 	"gorm.io/gorm"
 )
 
@@ -16,6 +19,10 @@ func (s *SQLiteStorage) CreatePort(ctx context.Context, port *models.Port) error
 		return fmt.Errorf("invalid port data: %w", err)
 	}
 
+	if err := s.checkPortName(ctx, port); err != nil {
+		return err
+	}
+
 	if err := s.db.WithContext(ctx).Create(port).Error; err != nil {
 		return fmt.Errorf("failed to create port: %w", err)
 	}
@@ -44,6 +51,45 @@ func (s *SQLiteStorage) GetPort(ctx context.Context, id uint) (*models.Port, err
 	return &port, nil
 }
 
+// GetPortByName retrieves a port by name; groupID 0 searches all groups
+func (s *SQLiteStorage) GetPortByName(ctx context.Context, name string, groupID uint) (*models.Port, error) {
+	query := s.db.WithContext(ctx).Model(&models.Port{}).Where("name = ?", name)
+	if groupID != 0 {
+		query = query.Where("group_id = ?", groupID)
+	}
+
+	var ids []uint
+	if err := query.Limit(2).Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up port: %w", err)
+	}
+
+	switch len(ids) {
+	case 0:
+		return nil, fmt.Errorf("%w: port %q", storage.ErrNameNotFound, name)
+	case 1:
+		return s.GetPort(ctx, ids[0])
+	default:
+		return nil, fmt.Errorf("%w: port %q exists in several groups, specify group_id", storage.ErrAmbiguousName, name)
+	}
+}
+
+// checkPortName ensures no other port in the group uses the same name
+func (s *SQLiteStorage) checkPortName(ctx context.Context, port *models.Port) error {
+	var count int64
+	err := s.db.WithContext(ctx).
+		Model(&models.Port{}).
+		Where("group_id = ? AND name = ? AND id <> ?", port.GroupID, port.Name, port.ID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check port name: %w", err)
+	}
+
+	if count > 0 {
+		return fmt.Errorf("%w: port %q already exists in this group", storage.ErrNameConflict, port.Name)
+	}
+	return nil
+}
+
 // GetPorts retrieves all ports
 func (s *SQLiteStorage) GetPorts(ctx context.Context) ([]models.Port, error) {
 	var ports []models.Port
@@ -77,6 +123,23 @@ func (s *SQLiteStorage) GetPortsByGroup(ctx context.Context, groupID uint) ([]mo
 	return ports, nil
 }
 
+// GetPortsByMetadata retrieves the ports whose metadata matches every filter
+func (s *SQLiteStorage) GetPortsByMetadata(ctx context.Context, filters []models.MetadataFilter) ([]models.Port, error) {
+	var ports []models.Port
+	query := s.db.WithContext(ctx).
+		Preload("Group").
+		Preload("Host").
+		Preload("TargetPort")
+	err := storage.WhereMetadata(query, "metadata", filters).
+		Find(&ports).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ports by metadata: %w", err)
+	}
+
+	return ports, nil
+}
+
 // GetPortsByHost retrieves all ports associated with a host
 func (s *SQLiteStorage) GetPortsByHost(ctx context.Context, hostID uint) ([]models.Port, error) {
 	var ports []models.Port
@@ -100,6 +163,10 @@ func (s *SQLiteStorage) UpdatePort(ctx context.Context, port *models.Port) error
 		return fmt.Errorf("invalid port data: %w", err)
 	}
 
+	if err := s.checkPortName(ctx, port); err != nil {
+		return err
+	}
+
 	result := s.db.WithContext(ctx).Save(port)
 	if result.Error != nil {
 		return fmt.Errorf("failed to update port: %w", result.Error)
@@ -123,7 +190,7 @@ func (s *SQLiteStorage) DeletePort(ctx context.Context, id uint) error {
 		return fmt.Errorf("port not found: %d", id)
 	}
 
-	return nil
+	return s.ReleasePortReservation(ctx, id)
 }
 
 // GetPortStats retrieves statistics for a port
@@ -190,24 +257,89 @@ func (s *SQLiteStorage) SearchPorts(ctx context.Context, query string) ([]models
 	return ports, nil
 }
 
-// UpdatePortStatus updates the status of a port
-func (s *SQLiteStorage) UpdatePortStatus(ctx context.Context, portID uint, status models.PortStatus) error {
+// UpdatePortStatus moves a port to a new status if the transition is allowed.
+// The update is conditional on the status read, so a concurrent change makes
+// it fail with ErrPortStatusChanged instead of skipping a transition check.
+func (s *SQLiteStorage) UpdatePortStatus(ctx context.Context, portID uint, status models.PortStatus, cause *models.PortError) error {
+	var port models.Port
+	if err := s.db.WithContext(ctx).First(&port, portID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("port not found: %d", portID)
+		}
+		return fmt.Errorf("failed to get port: %w", err)
+	}
+
+	previous := port.Status
+	if err := port.TransitionTo(status, cause); err != nil {
+		return err
+	}
+
 	result := s.db.WithContext(ctx).
-		Model(&models.Port{}).
-		Where("id = ?", portID).
-		Update("status", status)
+		Model(&port).
+		Where("status = ?", previous).
+		Select("status", "status_message", "last_active", "last_tested", "last_error_code", "last_error_message", "last_error_timestamp", "last_error_retryable").
+		Updates(&port)
 
 	if result.Error != nil {
 		return fmt.Errorf("failed to update port status: %w", result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		return fmt.Errorf("port not found: %d", portID)
+		return fmt.Errorf("%w: port %d", models.ErrPortStatusChanged, portID)
 	}
 
 	return nil
 }
 
+// UpdatePortBackoff moves a port into backoff with its retry message
+func (s *SQLiteStorage) UpdatePortBackoff(ctx context.Context, portID uint, attempt int, delay time.Duration, cause *models.PortError) error {
+	var port models.Port
+	if err := s.db.WithContext(ctx).First(&port, portID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("port not found: %d", portID)
+		}
+		return fmt.Errorf("failed to get port: %w", err)
+	}
+
+	previous := port.Status
+	if err := port.TransitionTo(models.PortStatusBackoff, cause); err != nil {
+		return err
+	}
+	port.SetBackoffStatus(attempt, delay)
+
+	result := s.db.WithContext(ctx).
+		Model(&port).
+		Where("status = ?", previous).
+		Select("status", "status_message", "last_error_code", "last_error_message", "last_error_timestamp", "last_error_retryable").
+		Updates(&port)
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to update port status: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: port %d", models.ErrPortStatusChanged, portID)
+	}
+
+	return nil
+}
+
+// UpdatePortWebInfo writes only the web_* columns, leaving the port's
+// configuration and status to concurrent updates
+func (s *SQLiteStorage) UpdatePortWebInfo(ctx context.Context, portID uint, info *models.WebInfo) error {
+	result := s.db.WithContext(ctx).
+		Model(&models.Port{ID: portID}).
+		Select("web_app", "web_app_name", "web_title", "web_favicon_type", "web_favicon", "web_detected_at").
+		UpdateColumns(&models.Port{Web: *info})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update port web info: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("port not found: %d", portID)
+	}
+	return nil
+}
+
 // ===== Port Connection Operations =====
 
 // CreatePortConnection creates a new port connection
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -264,5 +265,147 @@ func (s *SQLiteStorage) GetProjectStats(ctx context.Context, projectID uint) (*m
 		Count(&tunnelCount)
 	stats.ActiveTunnels = int(tunnelCount)
 
+	usage, err := s.GetProjectUsage(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	stats.Quota = *usage
+
+	// Count hosts failing authentication
+	var authFailingCount int64
+	s.db.WithContext(ctx).Model(&models.HostAuthStatus{}).
+		Joins("JOIN hosts ON hosts.id = host_auth_statuses.host_id AND hosts.deleted_at IS NULL").
+		Joins("JOIN groups ON hosts.group_id = groups.id").
+		Where("groups.project_id = ? AND host_auth_statuses.consecutive_failures > 0", projectID).
+		Count(&authFailingCount)
+	stats.AuthFailingHosts = int(authFailingCount)
+
 	return &stats, nil
 }
+
+// GetProjectUsage returns the project's quota with its current host count
+// and active tunnels, counting both active sessions and active ports
+func (s *SQLiteStorage) GetProjectUsage(ctx context.Context, projectID uint) (*models.QuotaUsage, error) {
+	var project models.Project
+	if err := s.db.WithContext(ctx).First(&project, projectID).Error; err != nil {
+		return nil, err
+	}
+
+	var hosts, sessions, ports int64
+	if err := s.db.WithContext(ctx).Model(&models.Host{}).
+		Joins("JOIN groups ON hosts.group_id = groups.id").
+		Where("groups.project_id = ?", projectID).
+		Count(&hosts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count hosts: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&models.TunnelSession{}).
+		Joins("JOIN hosts ON tunnel_sessions.host_id = hosts.id").
+		Joins("JOIN groups ON hosts.group_id = groups.id").
+		Where("groups.project_id = ? AND tunnel_sessions.status = ?", projectID, models.StatusActive).
+		Count(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to count active sessions: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Port{}).
+		Joins("JOIN groups ON ports.group_id = groups.id").
+		Where("groups.project_id = ? AND ports.status = ?", projectID, models.PortStatusActive).
+		Count(&ports).Error; err != nil {
+		return nil, fmt.Errorf("failed to count active ports: %w", err)
+	}
+
+	return &models.QuotaUsage{
+		ProjectQuota:  project.Quota,
+		Hosts:         int(hosts),
+		ActiveTunnels: int(sessions + ports),
+	}, nil
+}
+
+// GetProjectDashboard gathers everything the project dashboard shows, with
+// the traffic history summed per hour by the database
+func (s *SQLiteStorage) GetProjectDashboard(ctx context.Context, projectID uint) (*models.ProjectDashboard, error) {
+	stats, err := s.GetProjectStats(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	dashboard := &models.ProjectDashboard{Stats: *stats}
+	db := s.db.WithContext(ctx)
+
+	if err := db.Select("ports.*").
+		Joins("JOIN groups ON ports.group_id = groups.id").
+		Where("groups.project_id = ? AND ports.status IN ?", projectID,
+			[]models.PortStatus{models.PortStatusActive, models.PortStatusConnecting, models.PortStatusBackoff}).
+		Order("ports.last_active DESC").
+		Find(&dashboard.ActivePorts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load active ports: %w", err)
+	}
+
+	if err := db.Select("tunnel_sessions.*").
+		Joins("JOIN hosts ON tunnel_sessions.host_id = hosts.id").
+		Joins("JOIN groups ON hosts.group_id = groups.id").
+		Where("groups.project_id = ?", projectID).
+		Order("tunnel_sessions.updated_at DESC").
+		Limit(models.DashboardRecentLimit).
+		Find(&dashboard.RecentActivity).Error; err != nil {
+		return nil, fmt.Errorf("failed to load recent sessions: %w", err)
+	}
+
+	if err := db.Select("hosts.*").
+		Joins("JOIN groups ON hosts.group_id = groups.id").
+		Where("groups.project_id = ? AND hosts.status = ?", projectID, "error").
+		Order("hosts.name").
+		Find(&dashboard.UnhealthyHosts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load unhealthy hosts: %w", err)
+	}
+
+	traffic, err := s.projectTraffic(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	dashboard.Traffic = traffic
+
+	authFailures, err := s.GetAuthFailures(ctx, projectID, 0)
+	if err != nil {
+		return nil, err
+	}
+	dashboard.AuthFailures = authFailures
+
+	return dashboard, nil
+}
+
+// projectTraffic sums the data transferred by the project's sessions per
+// hour, attributing each session to when it ended or was last updated
+func (s *SQLiteStorage) projectTraffic(ctx context.Context, projectID uint) ([]models.TrafficPoint, error) {
+	end := time.Now().UTC().Truncate(models.DashboardTrafficBucket).Add(models.DashboardTrafficBucket)
+	start := end.Add(-models.DashboardTrafficBuckets * models.DashboardTrafficBucket)
+
+	var rows []struct {
+		Bucket   string
+		Bytes    int64
+		Sessions int
+	}
+	if err := s.db.WithContext(ctx).Model(&models.TunnelSession{}).
+		Select("strftime('%Y-%m-%dT%H:00:00Z', COALESCE(tunnel_sessions.end_time, tunnel_sessions.updated_at)) AS bucket, "+
+			"SUM(tunnel_sessions.data_transferred) AS bytes, COUNT(*) AS sessions").
+		Joins("JOIN hosts ON tunnel_sessions.host_id = hosts.id").
+		Joins("JOIN groups ON hosts.group_id = groups.id").
+		Where("groups.project_id = ? AND COALESCE(tunnel_sessions.end_time, tunnel_sessions.updated_at) >= ?", projectID, start).
+		Group("bucket").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to sum traffic: %w", err)
+	}
+
+	points := make([]models.TrafficPoint, models.DashboardTrafficBuckets)
+	for i := range points {
+		points[i].Start = start.Add(time.Duration(i) * models.DashboardTrafficBucket)
+	}
+	for _, row := range rows {
+		bucket, err := time.Parse(time.RFC3339, row.Bucket)
+		if err != nil {
+			continue
+		}
+		if i := int(bucket.Sub(start) / models.DashboardTrafficBucket); i >= 0 && i < len(points) {
+			points[i].Bytes += row.Bytes
+			points[i].Sessions += row.Sessions
+		}
+	}
+	return points, nil
+}
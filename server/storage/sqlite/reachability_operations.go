@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ===== Reachability Operations =====
+
+// SaveReachability inserts the probe result, or overwrites the earlier result
+// for the same source and target so the group keeps one per pair
+func (s *SQLiteStorage) SaveReachability(ctx context.Context, result *models.Reachability) error {
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "group_id"}, {Name: "source_host_id"}, {Name: "target_host"}, {Name: "target_port"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"updated_at", "target_host_id", "reachable", "method", "latency_ms", "error", "checked_at",
+		}),
+	}).Create(result).Error
+	if err != nil {
+		return fmt.Errorf("failed to save reachability: %w", err)
+	}
+	return nil
+}
+
+// GetReachability lists a group's latest probe results by source host
+func (s *SQLiteStorage) GetReachability(ctx context.Context, groupID uint) ([]models.Reachability, error) {
+	var results []models.Reachability
+	err := s.db.WithContext(ctx).
+		Where("group_id = ?", groupID).
+		Order("source_host_id, target_host, target_port").
+		Find(&results).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reachability: %w", err)
+	}
+	return results, nil
+}
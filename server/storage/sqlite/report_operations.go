@@ -0,0 +1,208 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ===== Report Operations =====
+
+// GetUsageReport finds the ports, hosts and groups left unused since the
+// given time. Usage is gathered in Go from the tunnel sessions, port
+// connections and the resources' own timestamps, as it spans all of them.
+func (s *SQLiteStorage) GetUsageReport(ctx context.Context, since time.Time) (*models.UsageReport, error) {
+	db := s.db.WithContext(ctx)
+
+	var groups []models.Group
+	if err := db.Select("id, name, project_id, parent_id, path, created_at").
+		Order("id").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	var ports []models.Port
+	if err := db.Select("id, name, type, port, status, group_id, last_active, created_at").
+		Order("id").Find(&ports).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ports: %w", err)
+	}
+	var hosts []models.Host
+	if err := db.Select("id, name, hostname, group_id, last_connected, created_at").
+		Order("id").Find(&hosts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get hosts: %w", err)
+	}
+	var sessions []models.TunnelSession
+	if err := db.Select("id, status, start_time, end_time, data_transferred, created_at, host_id, port_id").
+		Where("deleted_at IS NULL").Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tunnel sessions: %w", err)
+	}
+	var connections []models.PortConnection
+	if err := db.Select("id, remote_port_id, local_port_id, status, stats_last_used").
+		Find(&connections).Error; err != nil {
+		return nil, fmt.Errorf("failed to get port connections: %w", err)
+	}
+
+	portLastUsed := make(map[uint]*time.Time)
+	hostLastUsed := make(map[uint]*time.Time)
+	inUse := make(map[uint]bool) // ports with an active session or connection
+	for _, port := range ports {
+		portLastUsed[port.ID] = port.LastActive
+		if port.IsActive() {
+			inUse[port.ID] = true
+		}
+	}
+	for _, host := range hosts {
+		hostLastUsed[host.ID] = host.LastConnected
+	}
+	for _, connection := range connections {
+		for _, id := range []uint{connection.RemotePortID, connection.LocalPortID} {
+			portLastUsed[id] = laterTime(portLastUsed[id], connection.Stats.LastUsed)
+			if connection.Status == models.PortStatusActive {
+				inUse[id] = true
+			}
+		}
+	}
+
+	portGroups := make(map[uint]uint, len(ports))
+	for _, port := range ports {
+		portGroups[port.ID] = port.GroupID
+	}
+	hostGroups := make(map[uint]uint, len(hosts))
+	for _, host := range hosts {
+		hostGroups[host.ID] = host.GroupID
+	}
+
+	// Traffic and last use of each group's own ports and hosts; sessions of a
+	// port count for the port's group, others for the host's
+	traffic := make(map[uint]int64)
+	groupLastUsed := make(map[uint]*time.Time)
+	for _, session := range sessions {
+		used := sessionLastUsed(session)
+		hostLastUsed[session.HostID] = laterTime(hostLastUsed[session.HostID], used)
+
+		groupID, ok := hostGroups[session.HostID]
+		if session.PortID != nil {
+			portLastUsed[*session.PortID] = laterTime(portLastUsed[*session.PortID], used)
+			if session.Status == models.StatusActive {
+				inUse[*session.PortID] = true
+			}
+			if portGroup, found := portGroups[*session.PortID]; found {
+				groupID, ok = portGroup, true
+			}
+		}
+		if !ok {
+			continue
+		}
+		groupLastUsed[groupID] = laterTime(groupLastUsed[groupID], used)
+		if session.Status == models.StatusActive || !used.Before(since) {
+			traffic[groupID] += session.DataTransferred
+		}
+	}
+	for _, port := range ports {
+		groupLastUsed[port.GroupID] = laterTime(groupLastUsed[port.GroupID], portLastUsed[port.ID])
+	}
+	for _, host := range hosts {
+		groupLastUsed[host.GroupID] = laterTime(groupLastUsed[host.GroupID], hostLastUsed[host.ID])
+	}
+
+	groupNames := make(map[uint]string, len(groups))
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+
+	report := &models.UsageReport{
+		Since:       since,
+		UnusedPorts: []models.UnusedPort{},
+		UnusedHosts: []models.UnusedHost{},
+		IdleGroups:  []models.IdleGroup{},
+	}
+
+	portCounts := make(map[uint]int)
+	for _, port := range ports {
+		portCounts[port.GroupID]++
+		lastUsed := portLastUsed[port.ID]
+		if inUse[port.ID] || port.CreatedAt.After(since) || (lastUsed != nil && !lastUsed.Before(since)) {
+			continue
+		}
+		report.UnusedPorts = append(report.UnusedPorts, models.UnusedPort{
+			ID:        port.ID,
+			Name:      port.Name,
+			Type:      port.Type,
+			Port:      port.Port,
+			GroupID:   port.GroupID,
+			GroupName: groupNames[port.GroupID],
+			CreatedAt: port.CreatedAt,
+			LastUsed:  lastUsed,
+		})
+	}
+
+	hostCounts := make(map[uint]int)
+	for _, host := range hosts {
+		hostCounts[host.GroupID]++
+		lastConnected := hostLastUsed[host.ID]
+		if host.CreatedAt.After(since) || (lastConnected != nil && !lastConnected.Before(since)) {
+			continue
+		}
+		report.UnusedHosts = append(report.UnusedHosts, models.UnusedHost{
+			ID:            host.ID,
+			Name:          host.Name,
+			Hostname:      host.Hostname,
+			GroupID:       host.GroupID,
+			GroupName:     groupNames[host.GroupID],
+			CreatedAt:     host.CreatedAt,
+			LastConnected: lastConnected,
+		})
+	}
+
+	// Roll traffic and last use up to the ancestors so a parent group with
+	// busy subgroups is not reported
+	groupTraffic := make(map[uint]int64, len(groups))
+	groupUsed := make(map[uint]*time.Time, len(groups))
+	for _, group := range groups {
+		groupTraffic[group.ID] += traffic[group.ID]
+		groupUsed[group.ID] = laterTime(groupUsed[group.ID], groupLastUsed[group.ID])
+		for _, ancestorID := range group.AncestorIDs() {
+			groupTraffic[ancestorID] += traffic[group.ID]
+			groupUsed[ancestorID] = laterTime(groupUsed[ancestorID], groupLastUsed[group.ID])
+		}
+	}
+
+	for _, group := range groups {
+		if groupTraffic[group.ID] > 0 || group.CreatedAt.After(since) {
+			continue
+		}
+		report.IdleGroups = append(report.IdleGroups, models.IdleGroup{
+			ID:        group.ID,
+			Name:      group.Name,
+			ProjectID: group.ProjectID,
+			ParentID:  group.ParentID,
+			Ports:     portCounts[group.ID],
+			Hosts:     hostCounts[group.ID],
+			CreatedAt: group.CreatedAt,
+			LastUsed:  groupUsed[group.ID],
+		})
+	}
+
+	return report, nil
+}
+
+// sessionLastUsed returns when a session was last in use: its end, or its
+// start while it has not ended
+func sessionLastUsed(session models.TunnelSession) *time.Time {
+	switch {
+	case session.EndTime != nil:
+		return session.EndTime
+	case session.StartTime != nil:
+		return session.StartTime
+	default:
+		return &session.CreatedAt
+	}
+}
+
+// laterTime returns the later of two optional times
+func laterTime(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,169 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ===== Port Reservation Operations =====
+
+// reservationEnd is the last port of a reservation in SQL
+const reservationEnd = "(CASE WHEN port_end = 0 THEN port ELSE port_end END)"
+
+// GetPortReservations lists reservations by port
+func (s *SQLiteStorage) GetPortReservations(ctx context.Context, projectID uint) ([]models.PortReservation, error) {
+	query := s.db.WithContext(ctx).Order("port")
+	if projectID != 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	var reservations []models.PortReservation
+	if err := query.Find(&reservations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get port reservations: %w", err)
+	}
+	return reservations, nil
+}
+
+// ReservePort reserves explicitly chosen ports. Reservations of the same
+// project and user may overlap: they run on the same machine.
+func (s *SQLiteStorage) ReservePort(ctx context.Context, reservation *models.PortReservation) error {
+	if err := reservation.Validate(); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var overlapping []models.PortReservation
+		query := tx.Where("port <= ? AND "+reservationEnd+" >= ?", reservation.LastPort(), reservation.Port)
+		if reservation.PortID != nil {
+			query = query.Where("port_id IS NULL OR port_id <> ?", *reservation.PortID)
+		}
+		if err := query.Find(&overlapping).Error; err != nil {
+			return fmt.Errorf("failed to check port reservations: %w", err)
+		}
+		for _, other := range overlapping {
+			if other.ConflictsWith(reservation.ProjectID, reservation.Owner) {
+				return reservedError(reservation, &other)
+			}
+		}
+		return replaceReservation(tx, reservation)
+	})
+}
+
+// AllocatePort reserves the first free ports of the pool
+func (s *SQLiteStorage) AllocatePort(ctx context.Context, pool models.PortPool, size int, reservation *models.PortReservation) error {
+	if err := pool.Validate(); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		used, err := usedLocalPorts(tx, reservation.PortID)
+		if err != nil {
+			return err
+		}
+		blocks := models.FreePortBlocks(pool, used, max(size, 1), 1)
+		if len(blocks) == 0 {
+			return fmt.Errorf("%w: %d-%d", models.ErrNoFreePort, pool.First, pool.Last)
+		}
+
+		reservation.Port, reservation.PortEnd = blocks[0], 0
+		if size > 1 {
+			reservation.PortEnd = blocks[0] + size - 1
+		}
+		if err := reservation.Validate(); err != nil {
+			return err
+		}
+		return replaceReservation(tx, reservation)
+	})
+}
+
+// PlanPorts returns the ports AllocatePort would hand out next
+func (s *SQLiteStorage) PlanPorts(ctx context.Context, pool models.PortPool, size, count int) ([]int, error) {
+	if err := pool.Validate(); err != nil {
+		return nil, err
+	}
+	used, err := usedLocalPorts(s.db.WithContext(ctx), nil)
+	if err != nil {
+		return nil, err
+	}
+	return models.FreePortBlocks(pool, used, max(size, 1), count), nil
+}
+
+// DeletePortReservation removes a reservation
+func (s *SQLiteStorage) DeletePortReservation(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.PortReservation{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete port reservation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("port reservation not found: %d", id)
+	}
+	return nil
+}
+
+// ReleasePortReservation removes the reservation a port holds
+func (s *SQLiteStorage) ReleasePortReservation(ctx context.Context, portID uint) error {
+	if err := s.db.WithContext(ctx).Where("port_id = ?", portID).Delete(&models.PortReservation{}).Error; err != nil {
+		return fmt.Errorf("failed to release port reservation: %w", err)
+	}
+	return nil
+}
+
+// replaceReservation stores the reservation in place of the one its port
+// held before
+func replaceReservation(tx *gorm.DB, reservation *models.PortReservation) error {
+	if reservation.PortID != nil {
+		if err := tx.Where("port_id = ? AND id <> ?", *reservation.PortID, reservation.ID).Delete(&models.PortReservation{}).Error; err != nil {
+			return fmt.Errorf("failed to replace port reservation: %w", err)
+		}
+	}
+	if err := tx.Save(reservation).Error; err != nil {
+		return fmt.Errorf("failed to reserve port: %w", err)
+	}
+	return nil
+}
+
+// usedLocalPorts returns the port spans held by reservations and local
+// ports, leaving out those of the port being allocated for
+func usedLocalPorts(db *gorm.DB, portID *uint) ([][2]int, error) {
+	type span struct {
+		Port    int
+		PortEnd int
+	}
+	var spans []span
+
+	reservations := db.Model(&models.PortReservation{}).Select("port, port_end")
+	ports := db.Model(&models.Port{}).Select("port, port_end").Where("type = ?", models.PortTypeLocal)
+	if portID != nil {
+		reservations = reservations.Where("port_id IS NULL OR port_id <> ?", *portID)
+		ports = ports.Where("id <> ?", *portID)
+	}
+	if err := reservations.Find(&spans).Error; err != nil {
+		return nil, fmt.Errorf("failed to load port reservations: %w", err)
+	}
+	var portSpans []span
+	if err := ports.Find(&portSpans).Error; err != nil {
+		return nil, fmt.Errorf("failed to load local ports: %w", err)
+	}
+
+	used := make([][2]int, 0, len(spans)+len(portSpans))
+	for _, s := range append(spans, portSpans...) {
+		used = append(used, [2]int{s.Port, max(s.Port, s.PortEnd)})
+	}
+	return used, nil
+}
+
+// reservedError describes the reservation that holds the wanted ports
+func reservedError(wanted, holder *models.PortReservation) error {
+	by := fmt.Sprintf("project %d", holder.ProjectID)
+	if holder.Owner != "" {
+		by += ", user " + holder.Owner
+	}
+	if holder.PortID != nil {
+		by += fmt.Sprintf(", port %d", *holder.PortID)
+	}
+	return fmt.Errorf("%w: %s overlaps %s held by %s", models.ErrPortReserved, wanted, holder, by)
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// ===== Snippet Operations =====
+
+// CreateSnippet creates a new command snippet
+func (s *SQLiteStorage) CreateSnippet(ctx context.Context, snippet *models.Snippet) error {
+	if err := snippet.Validate(); err != nil {
+		return err
+	}
+	if err := s.checkSnippetName(ctx, snippet); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Create(snippet).Error; err != nil {
+		return fmt.Errorf("failed to create snippet: %w", err)
+	}
+	return nil
+}
+
+// GetSnippet retrieves a command snippet by ID
+func (s *SQLiteStorage) GetSnippet(ctx context.Context, id uint) (*models.Snippet, error) {
+	var snippet models.Snippet
+	if err := s.db.WithContext(ctx).First(&snippet, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get snippet: %w", err)
+	}
+	return &snippet, nil
+}
+
+// GetSnippets retrieves all command snippets by name
+func (s *SQLiteStorage) GetSnippets(ctx context.Context) ([]models.Snippet, error) {
+	var snippets []models.Snippet
+	if err := s.db.WithContext(ctx).Order("name").Find(&snippets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get snippets: %w", err)
+	}
+	return snippets, nil
+}
+
+// UpdateSnippet updates an existing command snippet
+func (s *SQLiteStorage) UpdateSnippet(ctx context.Context, snippet *models.Snippet) error {
+	if err := snippet.Validate(); err != nil {
+		return err
+	}
+	if err := s.checkSnippetName(ctx, snippet); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Save(snippet).Error; err != nil {
+		return fmt.Errorf("failed to update snippet: %w", err)
+	}
+	return nil
+}
+
+// DeleteSnippet deletes a command snippet
+func (s *SQLiteStorage) DeleteSnippet(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.Snippet{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete snippet: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete snippet: %w", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// checkSnippetName ensures no other snippet uses the same name
+func (s *SQLiteStorage) checkSnippetName(ctx context.Context, snippet *models.Snippet) error {
+	var count int64
+	err := s.db.WithContext(ctx).
+		Model(&models.Snippet{}).
+		Where("name = ? AND id <> ?", snippet.Name, snippet.ID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check snippet name: %w", err)
+	}
+
+	if count > 0 {
+		return fmt.Errorf("%w: snippet %q already exists", storage.ErrNameConflict, snippet.Name)
+	}
+	return nil
+}
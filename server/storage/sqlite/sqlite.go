@@ -1,10 +1,15 @@
 package sqlite
 
 import (
+	"database/sql"
 	"fmt"
+	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -16,13 +21,15 @@ import (
 
 // SQLiteStorage implements StorageInterface using SQLite
 type SQLiteStorage struct {
-	db     *gorm.DB
-	config storage.StorageConfig
+	db      *gorm.DB
+	config  storage.StorageConfig
+	metrics *storage.QueryMetrics
+	writes  *writeLock
 }
 
 func init() {
-	// Register SQLite storage factory
-	storage.RegisterStorageFactory("sqlite", func(config storage.StorageConfig) (storage.StorageInterface, error) {
+	// Register SQLite storage driver
+	storage.Register(string(storage.StorageTypeSQLite), func(config storage.StorageConfig) (storage.StorageInterface, error) {
 		return NewSQLiteStorage(config)
 	})
 }
@@ -53,24 +60,117 @@ func (s *SQLiteStorage) Initialize() error {
 		return fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	// Configure GORM logger
-	gormLogger := logger.Default
+	// Configure GORM logger; slow queries are reported by the metrics plugin
+	logLevel := logger.Warn
 	if strings.ToLower(s.config.Options["log_level"]) == "silent" {
-		gormLogger = logger.Default.LogMode(logger.Silent)
+		logLevel = logger.Silent
+	}
+	gormLogger := logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		LogLevel: logLevel,
+		Colorful: true,
+	})
+
+	dsn, err := s.dsn(dbPath)
+	if err != nil {
+		return err
 	}
 
-	// Open database connection
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	// Open database connection, queueing writers instead of letting them
+	// contend for the database lock
+	sqlDB, err := sql.Open(sqlite.DriverName, dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SQLite database: %w", err)
+	}
+	s.writes = newWriteLock()
+	db, err := gorm.Open(sqlite.New(sqlite.Config{Conn: &serializedPool{DB: sqlDB, writes: s.writes}}), &gorm.Config{
 		Logger: gormLogger,
 	})
 	if err != nil {
+		sqlDB.Close()
 		return fmt.Errorf("failed to connect to SQLite database: %w", err)
 	}
 
+	// An in-memory database exists once per connection, so share one
+	pool := s.config.Pool
+	if isMemory(dbPath) {
+		pool.MaxOpenConns, pool.MaxIdleConns = 1, 1
+		pool.ConnMaxLifetime, pool.ConnMaxIdleTime = -1, -1
+	}
+	if err := storage.ConfigurePool(db, pool); err != nil {
+		return fmt.Errorf("failed to configure connection pool: %w", err)
+	}
+
+	// Trace every query under the caller's span
+	if err := db.Use(&tracingPlugin{}); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
+	// Count queries and log slow ones
+	s.metrics = storage.NewQueryMetrics(s.config.SlowQueryThreshold, s.config.Logger)
+	if err := db.Use(s.metrics); err != nil {
+		return fmt.Errorf("failed to register query metrics plugin: %w", err)
+	}
+
 	s.db = db
 	return nil
 }
 
+// Default pragmas, overridable with the journal_mode, synchronous and
+// busy_timeout options
+const (
+	defaultJournalMode = "wal"
+	defaultSynchronous = "normal"
+	defaultBusyTimeout = 5 * time.Second
+)
+
+// dsn adds the connection pragmas to the database path. They go in the DSN
+// so that every pooled connection gets them, not just the first one.
+func (s *SQLiteStorage) dsn(dbPath string) (string, error) {
+	option := func(key, fallback string) string {
+		if value := strings.TrimSpace(s.config.Options[key]); value != "" {
+			return value
+		}
+		return fallback
+	}
+
+	busyTimeout, err := time.ParseDuration(option("busy_timeout", defaultBusyTimeout.String()))
+	if err != nil || busyTimeout < 0 {
+		return "", fmt.Errorf("invalid busy_timeout option %q", s.config.Options["busy_timeout"])
+	}
+
+	params := url.Values{}
+	params.Set("_busy_timeout", strconv.FormatInt(busyTimeout.Milliseconds(), 10))
+	params.Set("_synchronous", strings.ToUpper(option("synchronous", defaultSynchronous)))
+	// Transactions take the write lock when they begin; a deferred
+	// transaction upgrading from read to write gets SQLITE_BUSY at once,
+	// without waiting for busy_timeout
+	params.Set("_txlock", "immediate")
+	if !isMemory(dbPath) {
+		params.Set("_journal_mode", strings.ToUpper(option("journal_mode", defaultJournalMode)))
+	}
+
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	return dbPath + separator + params.Encode(), nil
+}
+
+// isMemory reports whether the path names an in-memory database
+func isMemory(dbPath string) bool {
+	return strings.HasPrefix(dbPath, ":memory:") || strings.Contains(dbPath, "mode=memory")
+}
+
+// QueryStats returns query counts and latencies along with the pool usage
+func (s *SQLiteStorage) QueryStats() storage.QueryStats {
+	if s.metrics == nil {
+		return storage.QueryStats{}
+	}
+	stats := s.metrics.Stats()
+	stats.Pool = storage.PoolSnapshot(s.db)
+	return stats
+}
+
 // Close closes the database connection
 func (s *SQLiteStorage) Close() error {
 	if s.db != nil {
@@ -99,7 +199,7 @@ func (s *SQLiteStorage) Health() error {
 
 // Migrate runs database migrations
 func (s *SQLiteStorage) Migrate() error {
-	return s.db.AutoMigrate(
+	err := s.db.AutoMigrate(
 		&models.Project{},
 		&models.Group{},
 		&models.Host{},
@@ -107,5 +207,25 @@ func (s *SQLiteStorage) Migrate() error {
 		&models.PortConnection{},
 		&models.PortForward{},
 		&models.TunnelSession{},
+		&models.Lease{},
+		&models.PortGrant{},
+		&models.PortTemplate{},
+		&models.PortFavorite{},
+		&models.TerminalRecord{},
+		&models.TerminalClosure{},
+		&models.Reachability{},
+		&models.ChangeRecord{},
+		&models.StatusCheck{},
+		&models.PortReservation{},
+		&models.HostAuthStatus{},
+		&models.Snippet{},
 	)
+	if err != nil {
+		return err
+	}
+
+	// Groups created before nesting are top-level groups without a path
+	return s.db.Unscoped().Model(&models.Group{}).
+		Where("parent_id IS NULL AND (path IS NULL OR path = '')").
+		Update("path", gorm.Expr("'/' || id")).Error
 }
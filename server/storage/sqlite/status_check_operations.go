@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ===== Status Page Operations =====
+
+func (s *SQLiteStorage) CreateStatusCheck(ctx context.Context, check *models.StatusCheck) error {
+	if err := s.db.WithContext(ctx).Create(check).Error; err != nil {
+		return fmt.Errorf("failed to create status check: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) GetStatusUptime(ctx context.Context, portID uint, since time.Time) (*models.StatusUptime, error) {
+	var uptime models.StatusUptime
+	err := s.db.WithContext(ctx).Model(&models.StatusCheck{}).
+		Select("COUNT(*) AS checks, COALESCE(SUM(CASE WHEN up THEN 1 ELSE 0 END), 0) AS up, AVG(latency_ms) AS avg_latency_ms").
+		Where("port_id = ? AND checked_at >= ?", portID, since).
+		Scan(&uptime).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status uptime: %w", err)
+	}
+	return &uptime, nil
+}
+
+func (s *SQLiteStorage) GetRecentStatusChecks(ctx context.Context, portID uint, limit int) ([]models.StatusCheck, error) {
+	var checks []models.StatusCheck
+	err := s.db.WithContext(ctx).
+		Where("port_id = ?", portID).
+		Order("checked_at DESC").
+		Limit(limit).
+		Find(&checks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status checks: %w", err)
+	}
+	return checks, nil
+}
+
+func (s *SQLiteStorage) DeleteStatusChecksBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Where("checked_at < ?", before).Delete(&models.StatusCheck{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete status checks: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,214 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/storage"
+)
+
+// ===== Port Template Operations =====
+
+// CreatePortTemplate creates a new port template
+func (s *SQLiteStorage) CreatePortTemplate(ctx context.Context, template *models.PortTemplate) error {
+	if err := template.Validate(); err != nil {
+		return err
+	}
+	if err := s.checkPortTemplateName(ctx, template); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Create(template).Error; err != nil {
+		return fmt.Errorf("failed to create port template: %w", err)
+	}
+	return nil
+}
+
+// GetPortTemplate retrieves a port template by ID
+func (s *SQLiteStorage) GetPortTemplate(ctx context.Context, id uint) (*models.PortTemplate, error) {
+	var template models.PortTemplate
+	if err := s.db.WithContext(ctx).First(&template, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to get port template: %w", err)
+	}
+	return &template, nil
+}
+
+// GetPortTemplates retrieves all port templates by name
+func (s *SQLiteStorage) GetPortTemplates(ctx context.Context) ([]models.PortTemplate, error) {
+	var templates []models.PortTemplate
+	if err := s.db.WithContext(ctx).Order("name").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get port templates: %w", err)
+	}
+	return templates, nil
+}
+
+// UpdatePortTemplate updates an existing port template
+func (s *SQLiteStorage) UpdatePortTemplate(ctx context.Context, template *models.PortTemplate) error {
+	if err := template.Validate(); err != nil {
+		return err
+	}
+	if err := s.checkPortTemplateName(ctx, template); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(ctx).Save(template).Error; err != nil {
+		return fmt.Errorf("failed to update port template: %w", err)
+	}
+	return nil
+}
+
+// DeletePortTemplate deletes a port template; ports created from it stay
+func (s *SQLiteStorage) DeletePortTemplate(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.PortTemplate{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete port template: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete port template: %w", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// checkPortTemplateName ensures no other template uses the same name
+func (s *SQLiteStorage) checkPortTemplateName(ctx context.Context, template *models.PortTemplate) error {
+	var count int64
+	err := s.db.WithContext(ctx).
+		Model(&models.PortTemplate{}).
+		Where("name = ? AND id <> ?", template.Name, template.ID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check port template name: %w", err)
+	}
+
+	if count > 0 {
+		return fmt.Errorf("%w: port template %q already exists", storage.ErrNameConflict, template.Name)
+	}
+	return nil
+}
+
+// ===== Port Favorite Operations =====
+
+// GetPortFavorites lists an owner's favorites in panel order; the inner join
+// leaves out favorites of deleted ports
+func (s *SQLiteStorage) GetPortFavorites(ctx context.Context, owner string) ([]models.PortFavorite, error) {
+	var favorites []models.PortFavorite
+	err := s.db.WithContext(ctx).
+		InnerJoins("Port").
+		Where("port_favorites.owner = ?", owner).
+		Order("port_favorites.position, port_favorites.id").
+		Find(&favorites).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get port favorites: %w", err)
+	}
+	return favorites, nil
+}
+
+// AddPortFavorite appends a port to the owner's favorites
+func (s *SQLiteStorage) AddPortFavorite(ctx context.Context, favorite *models.PortFavorite) error {
+	if err := favorite.Validate(); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkFavoritePorts(tx, []uint{favorite.PortID}); err != nil {
+			return err
+		}
+
+		var existing models.PortFavorite
+		err := tx.Where("owner = ? AND port_id = ?", favorite.Owner, favorite.PortID).First(&existing).Error
+		if err == nil {
+			*favorite = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check port favorite: %w", err)
+		}
+
+		var last struct{ Position *int }
+		if err := tx.Model(&models.PortFavorite{}).
+			Select("MAX(position) AS position").
+			Where("owner = ?", favorite.Owner).
+			Scan(&last).Error; err != nil {
+			return fmt.Errorf("failed to add port favorite: %w", err)
+		}
+		favorite.Position = 0
+		if last.Position != nil {
+			favorite.Position = *last.Position + 1
+		}
+
+		if err := tx.Create(favorite).Error; err != nil {
+			return fmt.Errorf("failed to add port favorite: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeletePortFavorite removes a port from the owner's favorites
+func (s *SQLiteStorage) DeletePortFavorite(ctx context.Context, owner string, portID uint) error {
+	result := s.db.WithContext(ctx).
+		Where("owner = ? AND port_id = ?", owner, portID).
+		Delete(&models.PortFavorite{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete port favorite: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete port favorite: %w", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
+
+// SetPortFavorites replaces the owner's favorites, keeping the first
+// occurrence of a port listed twice
+func (s *SQLiteStorage) SetPortFavorites(ctx context.Context, owner string, portIDs []uint) error {
+	if owner == "" {
+		return models.ErrFavoriteOwnerRequired
+	}
+
+	favorites := make([]models.PortFavorite, 0, len(portIDs))
+	seen := make(map[uint]bool, len(portIDs))
+	for _, portID := range portIDs {
+		if seen[portID] {
+			continue
+		}
+		seen[portID] = true
+		favorites = append(favorites, models.PortFavorite{Owner: owner, PortID: portID, Position: len(favorites)})
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := make([]uint, len(favorites))
+		for i, favorite := range favorites {
+			ids[i] = favorite.PortID
+		}
+		if err := checkFavoritePorts(tx, ids); err != nil {
+			return err
+		}
+
+		if err := tx.Where("owner = ?", owner).Delete(&models.PortFavorite{}).Error; err != nil {
+			return fmt.Errorf("failed to replace port favorites: %w", err)
+		}
+		if len(favorites) == 0 {
+			return nil
+		}
+		if err := tx.Create(&favorites).Error; err != nil {
+			return fmt.Errorf("failed to replace port favorites: %w", err)
+		}
+		return nil
+	})
+}
+
+// checkFavoritePorts ensures the ports exist and are not deleted
+func checkFavoritePorts(tx *gorm.DB, portIDs []uint) error {
+	if len(portIDs) == 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.Port{}).Where("id IN ?", portIDs).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check favorite ports: %w", err)
+	}
+	if count != int64(len(portIDs)) {
+		return fmt.Errorf("failed to check favorite ports: port %w", gorm.ErrRecordNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aqz236/port-fly/core/models"
+)
+
+// ===== Terminal Record Operations =====
+
+func (s *SQLiteStorage) CreateTerminalRecord(ctx context.Context, record *models.TerminalRecord) error {
+	if err := s.db.WithContext(ctx).Create(record).Error; err != nil {
+		return fmt.Errorf("failed to create terminal record: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) GetTerminalRecordByToken(ctx context.Context, token string) (*models.TerminalRecord, error) {
+	var record models.TerminalRecord
+	if err := s.db.WithContext(ctx).Where("token = ?", token).First(&record).Error; err != nil {
+		return nil, fmt.Errorf("failed to get terminal record: %w", err)
+	}
+	return &record, nil
+}
+
+// GetInterruptedTerminalRecords lists the terminals left open when the server
+// stopped, newest first
+func (s *SQLiteStorage) GetInterruptedTerminalRecords(ctx context.Context) ([]models.TerminalRecord, error) {
+	var records []models.TerminalRecord
+	if err := s.db.WithContext(ctx).
+		Where("interrupted_at IS NOT NULL").
+		Order("interrupted_at DESC").
+		Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to get terminal records: %w", err)
+	}
+	return records, nil
+}
+
+func (s *SQLiteStorage) UpdateTerminalRecord(ctx context.Context, record *models.TerminalRecord) error {
+	if err := s.db.WithContext(ctx).Save(record).Error; err != nil {
+		return fmt.Errorf("failed to update terminal record: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) DeleteTerminalRecord(ctx context.Context, id uint) error {
+	if err := s.db.WithContext(ctx).Delete(&models.TerminalRecord{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete terminal record: %w", err)
+	}
+	return nil
+}
+
+// InterruptTerminalRecords marks every open terminal as interrupted at the
+// given time and deletes interrupted ones older than the cutoff
+func (s *SQLiteStorage) InterruptTerminalRecords(ctx context.Context, at, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&models.TerminalRecord{}).
+		Where("interrupted_at IS NULL").
+		Update("interrupted_at", at)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to interrupt terminal records: %w", result.Error)
+	}
+
+	if err := s.db.WithContext(ctx).
+		Where("interrupted_at < ?", before).
+		Delete(&models.TerminalRecord{}).Error; err != nil {
+		return result.RowsAffected, fmt.Errorf("failed to prune terminal records: %w", err)
+	}
+	return result.RowsAffected, nil
+}
+
+// ===== Terminal Closure Operations =====
+
+func (s *SQLiteStorage) CreateTerminalClosure(ctx context.Context, closure *models.TerminalClosure) error {
+	if err := s.db.WithContext(ctx).Create(closure).Error; err != nil {
+		return fmt.Errorf("failed to create terminal closure: %w", err)
+	}
+	return nil
+}
+
+// GetTerminalClosures lists enforced terminal closures, newest first
+func (s *SQLiteStorage) GetTerminalClosures(ctx context.Context, hostID uint, limit int) ([]models.TerminalClosure, error) {
+	query := s.db.WithContext(ctx).Order("created_at DESC, id DESC")
+	if hostID != 0 {
+		query = query.Where("host_id = ?", hostID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var closures []models.TerminalClosure
+	if err := query.Find(&closures).Error; err != nil {
+		return nil, fmt.Errorf("failed to get terminal closures: %w", err)
+	}
+	return closures, nil
+}
@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/aqz236/port-fly/core/telemetry"
+)
+
+// tracingSpanKey stores the active span on the GORM statement
+const tracingSpanKey = "portfly:tracing_span"
+
+// tracingPlugin creates an OpenTelemetry span for every GORM operation,
+// parented to the span carried by the statement context
+type tracingPlugin struct {
+	tracer trace.Tracer
+}
+
+// Name returns the plugin name
+func (p *tracingPlugin) Name() string {
+	return "portfly:tracing"
+}
+
+// Initialize registers the before/after callbacks for each operation
+func (p *tracingPlugin) Initialize(db *gorm.DB) error {
+	p.tracer = telemetry.Tracer("server/storage")
+
+	// Method values let us register on GORM's unexported callback types
+	type register func(name string, fn func(*gorm.DB)) error
+	callbacks := db.Callback()
+	operations := []struct {
+		name   string
+		before register
+		after  register
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+
+	for _, op := range operations {
+		if err := op.before("tracing:before_"+op.name, p.before(op.name)); err != nil {
+			return err
+		}
+		if err := op.after("tracing:after_"+op.name, p.after); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// before starts a span for the operation
+func (p *tracingPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+
+		ctx, span := p.tracer.Start(db.Statement.Context, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", "sqlite"),
+				attribute.String("db.operation.name", operation),
+			),
+		)
+		if db.Statement.Table != "" {
+			span.SetAttributes(attribute.String("db.collection.name", db.Statement.Table))
+		}
+
+		db.Statement.Context = ctx
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+// after records the statement and error, then ends the span
+func (p *tracingPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	if db.Statement.Table != "" {
+		span.SetAttributes(attribute.String("db.collection.name", db.Statement.Table))
+	}
+	if sql := db.Statement.SQL.String(); sql != "" {
+		span.SetAttributes(attribute.String("db.query.text", sql))
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", db.RowsAffected))
+
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
@@ -2,19 +2,29 @@ package sqlite
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/aqz236/port-fly/core/models"
+	"github.com/aqz236/port-fly/server/storage"
+	"gorm.io/gorm"
 )
 
+// terminatedSessionStatuses are statuses whose names may be reused
+var terminatedSessionStatuses = []models.SessionStatus{models.StatusStopped, models.StatusError}
+
 // ===== Tunnel Session Operations =====
 
 func (s *SQLiteStorage) CreateTunnelSession(ctx context.Context, session *models.TunnelSession) error {
+	if err := s.checkTunnelSessionName(ctx, session); err != nil {
+		return err
+	}
 	return s.db.WithContext(ctx).Create(session).Error
 }
 
 func (s *SQLiteStorage) GetTunnelSession(ctx context.Context, id uint) (*models.TunnelSession, error) {
 	var session models.TunnelSession
-	err := s.db.WithContext(ctx).Preload("Host").Preload("PortForward").First(&session, id).Error
+	err := s.db.WithContext(ctx).First(&session, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -23,20 +33,83 @@ func (s *SQLiteStorage) GetTunnelSession(ctx context.Context, id uint) (*models.
 
 func (s *SQLiteStorage) GetTunnelSessions(ctx context.Context) ([]models.TunnelSession, error) {
 	var sessions []models.TunnelSession
-	err := s.db.WithContext(ctx).Preload("Host").Preload("PortForward").Find(&sessions).Error
+	err := s.db.WithContext(ctx).Find(&sessions).Error
 	return sessions, err
 }
 
 func (s *SQLiteStorage) GetActiveTunnelSessions(ctx context.Context) ([]models.TunnelSession, error) {
 	var sessions []models.TunnelSession
-	err := s.db.WithContext(ctx).Preload("Host").Preload("PortForward").Where("status = ?", "active").Find(&sessions).Error
+	err := s.db.WithContext(ctx).Where("status = ?", "active").Find(&sessions).Error
 	return sessions, err
 }
 
 func (s *SQLiteStorage) UpdateTunnelSession(ctx context.Context, session *models.TunnelSession) error {
+	if err := s.checkTunnelSessionName(ctx, session); err != nil {
+		return err
+	}
 	return s.db.WithContext(ctx).Save(session).Error
 }
 
+// GetTunnelSessionByName retrieves a session by name, preferring the live one
+// over stopped sessions that used the name before; hostID 0 searches all hosts
+func (s *SQLiteStorage) GetTunnelSessionByName(ctx context.Context, name string, hostID uint) (*models.TunnelSession, error) {
+	query := s.db.WithContext(ctx).Where("name = ?", name)
+	if hostID != 0 {
+		query = query.Where("host_id = ?", hostID)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var live []models.TunnelSession
+	if err := query.Where("status NOT IN ?", terminatedSessionStatuses).Limit(2).Find(&live).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+	switch len(live) {
+	case 1:
+		return &live[0], nil
+	case 2:
+		return nil, fmt.Errorf("%w: session %q is running on several hosts, specify host_id", storage.ErrAmbiguousName, name)
+	}
+
+	var latest []models.TunnelSession
+	if err := query.Order("created_at DESC").Limit(1).Find(&latest).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+	if len(latest) == 0 {
+		return nil, fmt.Errorf("%w: session %q", storage.ErrNameNotFound, name)
+	}
+	return &latest[0], nil
+}
+
+// checkTunnelSessionName ensures a live session's name is unique on its host
+func (s *SQLiteStorage) checkTunnelSessionName(ctx context.Context, session *models.TunnelSession) error {
+	if session.Name == "" {
+		return nil
+	}
+	if models.IsNumericName(session.Name) {
+		return fmt.Errorf("invalid session name: %w", models.ErrNumericName)
+	}
+	for _, status := range terminatedSessionStatuses {
+		if session.Status == status {
+			return nil
+		}
+	}
+
+	var count int64
+	err := s.db.WithContext(ctx).
+		Model(&models.TunnelSession{}).
+		Where("host_id = ? AND name = ? AND id <> ?", session.HostID, session.Name, session.ID).
+		Where("status NOT IN ?", terminatedSessionStatuses).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check session name: %w", err)
+	}
+
+	if count > 0 {
+		return fmt.Errorf("%w: a live session named %q already exists on this host", storage.ErrNameConflict, session.Name)
+	}
+	return nil
+}
+
 func (s *SQLiteStorage) DeleteTunnelSession(ctx context.Context, id uint) error {
 	return s.db.WithContext(ctx).Delete(&models.TunnelSession{}, id).Error
 }
@@ -67,3 +140,49 @@ func (s *SQLiteStorage) GetSessionStats(ctx context.Context) (*models.SessionSta
 
 	return &stats, nil
 }
+
+// PruneTunnelSessions deletes terminated sessions that ended before the cutoff.
+// Each batch runs in its own transaction so a large backlog never holds the
+// write lock for long; port connections pointing at a pruned session are
+// detached first.
+func (s *SQLiteStorage) PruneTunnelSessions(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		var ids []uint
+		err := s.db.WithContext(ctx).Model(&models.TunnelSession{}).
+			Where("status IN ?", terminatedSessionStatuses).
+			Where("COALESCE(end_time, updated_at) < ?", before).
+			Limit(batchSize).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return total, fmt.Errorf("failed to select sessions to prune: %w", err)
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		var deleted int64
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.PortConnection{}).
+				Where("tunnel_session_id IN ?", ids).
+				Update("tunnel_session_id", nil).Error; err != nil {
+				return err
+			}
+			result := tx.Delete(&models.TunnelSession{}, ids)
+			deleted = result.RowsAffected
+			return result.Error
+		})
+		if err != nil {
+			return total, fmt.Errorf("failed to prune sessions: %w", err)
+		}
+		total += deleted
+
+		if len(ids) < batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// writeLock lets one writer at a time at the database. SQLite allows a
+// single writer; queueing writers here, in order and bounded by their
+// context, keeps them from piling up on the database lock and failing with
+// SQLITE_BUSY once busy_timeout runs out.
+type writeLock struct {
+	slot chan struct{}
+}
+
+func newWriteLock() *writeLock {
+	return &writeLock{slot: make(chan struct{}, 1)}
+}
+
+// acquire waits for the lock until ctx ends
+func (w *writeLock) acquire(ctx context.Context) error {
+	select {
+	case w.slot <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *writeLock) release() {
+	<-w.slot
+}
+
+// serializedPool is the connection pool GORM uses. Transactions, which GORM
+// also opens around every create, update and delete, and statements run with
+// Exec hold the write lock; queries run alongside them, as WAL mode allows.
+//
+// Writes inside a transaction must go through the transaction: one made on
+// the pool would wait for the lock its own transaction holds.
+type serializedPool struct {
+	*sql.DB
+	writes *writeLock
+}
+
+// GetDBConn exposes the underlying pool, for db.DB()
+func (p *serializedPool) GetDBConn() (*sql.DB, error) {
+	return p.DB, nil
+}
+
+// ExecContext runs a statement outside a transaction under the write lock
+func (p *serializedPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := p.writes.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer p.writes.release()
+	return p.DB.ExecContext(ctx, query, args...)
+}
+
+// BeginTx waits for the write lock and holds it until the transaction ends
+func (p *serializedPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	if err := p.writes.acquire(ctx); err != nil {
+		return nil, err
+	}
+	tx, err := p.DB.BeginTx(ctx, opts)
+	if err != nil {
+		p.writes.release()
+		return nil, err
+	}
+	return &serializedTx{Tx: tx, release: sync.OnceFunc(p.writes.release)}, nil
+}
+
+// serializedTx releases the write lock when the transaction ends
+type serializedTx struct {
+	*sql.Tx
+	release func()
+}
+
+func (t *serializedTx) Commit() error {
+	defer t.release()
+	return t.Tx.Commit()
+}
+
+func (t *serializedTx) Rollback() error {
+	defer t.release()
+	return t.Tx.Rollback()
+}
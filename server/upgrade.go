@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+// upgradeOwner is the value naming the instance the replaced process
+// claimed its forwards under
+const upgradeOwner = "owner"
+
+// upgrade replaces this process with the server binary on disk. On failure
+// this process keeps serving.
+func (s *Server) upgrade() {
+	s.logger.Info("Upgrade requested, starting the new server binary")
+	pid, err := s.upgrades.Upgrade(context.Background())
+	if err != nil {
+		s.logger.Error("Server upgrade failed", "error", err)
+		return
+	}
+	s.logger.Info("Server upgraded", "pid", pid)
+}
+
+// adoptFromParent takes over what the process this one replaced ran: its
+// forwards, so they are not reset as orphans once it exits, and the local
+// DNS names of the active ports
+func (s *Server) adoptFromParent(parent int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	owner := s.upgrades.ParentValue(upgradeOwner)
+	adopted, err := s.reconciler.Adopt(ctx, owner, parent)
+	if err != nil {
+		s.logger.Error("Failed to adopt forwards of the previous process", "pid", parent, "error", err)
+	} else {
+		s.logger.Info("Adopted forwards of the previous process", "pid", parent, "forwards", adopted)
+	}
+
+	s.handlers.RestorePortDNS(ctx)
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package upgrade
+
+import (
+	"os"
+	"syscall"
+)
+
+// supported reports whether sockets can be inherited by a new process
+const supported = true
+
+// Signals request an upgrade, like nginx's binary upgrade
+var Signals = []os.Signal{syscall.SIGUSR2}
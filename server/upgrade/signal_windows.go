@@ -0,0 +1,11 @@
+//go:build windows
+
+package upgrade
+
+import "os"
+
+// supported reports whether sockets can be inherited by a new process
+const supported = false
+
+// Signals request an upgrade; Windows has none
+var Signals []os.Signal
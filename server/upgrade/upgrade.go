@@ -0,0 +1,407 @@
+// Package upgrade replaces the running server with a new binary without
+// closing its listeners. The server starts the new executable with its
+// listening sockets as inherited file descriptors; the new process serves
+// them right away, takes over the state recorded for the old one and reports
+// ready, after which the old process drains its requests and exits.
+// Connections queued on the sockets during the handover are accepted by
+// whichever process gets to them first.
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aqz236/port-fly/core/utils"
+)
+
+// Environment of the new process, describing what it inherits
+const (
+	envFiles  = "PORTFLY_UPGRADE_FDS"    // name=fd,... of the inherited sockets
+	envReady  = "PORTFLY_UPGRADE_READY"  // fd of the pipe to report ready on
+	envParent = "PORTFLY_UPGRADE_PARENT" // pid of the process being replaced
+	envValues = "PORTFLY_UPGRADE_VALUE_" // prefix of the values passed with Pass
+)
+
+// DefaultReadyTimeout is how long the new process has to report ready
+// before it is killed and the old one keeps serving
+const DefaultReadyTimeout = 20 * time.Second
+
+// firstInheritedFD is where exec.Cmd.ExtraFiles start in the new process
+const firstInheritedFD = 3
+
+var (
+	// ErrUnsupported is returned where sockets cannot be passed to a new process
+	ErrUnsupported = errors.New("graceful upgrades are not supported on this platform")
+	// ErrInProgress is returned while an upgrade is running or after one succeeded
+	ErrInProgress = errors.New("an upgrade is already in progress or completed")
+)
+
+// socket is a listening socket the next process inherits
+type socket struct {
+	name     string
+	listener net.Listener   // nil for packet sockets
+	packet   net.PacketConn // nil for stream sockets
+}
+
+// Upgrader hands the server's sockets to a new process and, in that
+// process, returns the inherited sockets instead of binding new ones
+type Upgrader struct {
+	logger utils.Logger
+
+	mu        sync.Mutex
+	sockets   []socket
+	values    map[string]string
+	upgrading bool
+
+	// Set when this process replaced another
+	inherited map[string]*os.File
+	ready     *os.File
+	parentPID int
+	parentVal map[string]string
+
+	done chan struct{} // closed once a new process has taken over
+}
+
+// New creates an upgrader, picking up the sockets and values passed by the
+// process this one replaces. The variables describing them are unset so
+// child processes of the server do not see them.
+func New(logger utils.Logger) (*Upgrader, error) {
+	u := &Upgrader{
+		logger:    logger,
+		values:    make(map[string]string),
+		inherited: make(map[string]*os.File),
+		parentVal: make(map[string]string),
+		done:      make(chan struct{}),
+	}
+
+	parent := os.Getenv(envParent)
+	if parent == "" {
+		return u, nil
+	}
+	defer unsetUpgradeEnv()
+
+	pid, err := strconv.Atoi(parent)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %q", envParent, parent)
+	}
+	u.parentPID = pid
+
+	if fds := os.Getenv(envFiles); fds != "" {
+		for _, entry := range strings.Split(fds, ",") {
+			name, fdText, ok := strings.Cut(entry, "=")
+			fd, err := strconv.Atoi(fdText)
+			if !ok || err != nil {
+				return nil, fmt.Errorf("invalid %s entry: %q", envFiles, entry)
+			}
+			u.inherited[name] = os.NewFile(uintptr(fd), name)
+		}
+	}
+	if fd, err := strconv.Atoi(os.Getenv(envReady)); err == nil {
+		u.ready = os.NewFile(uintptr(fd), "upgrade-ready")
+	}
+	for _, variable := range os.Environ() {
+		if key, value, ok := strings.Cut(variable, "="); ok && strings.HasPrefix(key, envValues) {
+			u.parentVal[strings.ToLower(strings.TrimPrefix(key, envValues))] = value
+		}
+	}
+	return u, nil
+}
+
+func unsetUpgradeEnv() {
+	for _, variable := range os.Environ() {
+		key, _, _ := strings.Cut(variable, "=")
+		if strings.HasPrefix(key, "PORTFLY_UPGRADE_") {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// Parent returns the pid of the process this one replaced, or 0
+func (u *Upgrader) Parent() int {
+	return u.parentPID
+}
+
+// ParentValue returns a value the replaced process passed with Pass
+func (u *Upgrader) ParentValue(name string) string {
+	return u.parentVal[name]
+}
+
+// Pass makes value available to the next process through ParentValue
+func (u *Upgrader) Pass(name, value string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.values[name] = value
+}
+
+// Listen returns the stream socket called name inherited from the replaced
+// process, or else the one listen opens. Either way it is passed on to the
+// next process.
+func (u *Upgrader) Listen(name string, listen func() (net.Listener, error)) (net.Listener, error) {
+	var listener net.Listener
+	if file := u.takeInherited(name); file != nil {
+		var err error
+		listener, err = net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to use inherited %s socket: %w", name, err)
+		}
+		// Remove the socket file on close like a listener this process created
+		if unixListener, ok := listener.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(true)
+		}
+		u.logger.Info("Using socket inherited from the previous process", "socket", name, "address", listener.Addr().String())
+	} else {
+		var err error
+		if listener, err = listen(); err != nil {
+			return nil, err
+		}
+	}
+
+	u.mu.Lock()
+	u.sockets = append(u.sockets, socket{name: name, listener: listener})
+	u.mu.Unlock()
+	return listener, nil
+}
+
+// ListenPacket is Listen for packet sockets
+func (u *Upgrader) ListenPacket(name string, listen func() (net.PacketConn, error)) (net.PacketConn, error) {
+	var conn net.PacketConn
+	if file := u.takeInherited(name); file != nil {
+		var err error
+		conn, err = net.FilePacketConn(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to use inherited %s socket: %w", name, err)
+		}
+		u.logger.Info("Using socket inherited from the previous process", "socket", name, "address", conn.LocalAddr().String())
+	} else {
+		var err error
+		if conn, err = listen(); err != nil {
+			return nil, err
+		}
+	}
+
+	u.mu.Lock()
+	u.sockets = append(u.sockets, socket{name: name, packet: conn})
+	u.mu.Unlock()
+	return conn, nil
+}
+
+func (u *Upgrader) takeInherited(name string) *os.File {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	file := u.inherited[name]
+	delete(u.inherited, name)
+	return file
+}
+
+// Ready tells the replaced process that this one serves, so it can stop.
+// Inherited sockets nothing asked for, e.g. of a listener disabled since,
+// are closed. Under systemd the service's main PID moves to this process.
+func (u *Upgrader) Ready() error {
+	u.mu.Lock()
+	for name, file := range u.inherited {
+		u.logger.Info("Closing inherited socket no longer in use", "socket", name)
+		file.Close()
+	}
+	u.inherited = make(map[string]*os.File)
+	u.mu.Unlock()
+
+	if u.ready == nil {
+		return nil
+	}
+	defer func() {
+		u.ready.Close()
+		u.ready = nil
+	}()
+
+	if err := notifyMainPID(); err != nil {
+		u.logger.Warn("Failed to report the new main PID to systemd", "error", err)
+	}
+	if _, err := u.ready.Write([]byte{1}); err != nil {
+		return fmt.Errorf("failed to report ready to the previous process: %w", err)
+	}
+	return nil
+}
+
+// Done is closed once a new process has taken over; the server then drains
+// its requests and exits
+func (u *Upgrader) Done() <-chan struct{} {
+	return u.done
+}
+
+// Upgrade starts the server's executable, as it is on disk now, with the
+// same arguments and the sockets, and waits until it reports ready. On
+// error the new process is stopped and this one keeps serving.
+func (u *Upgrader) Upgrade(ctx context.Context) (int, error) {
+	if !supported {
+		return 0, ErrUnsupported
+	}
+
+	u.mu.Lock()
+	if u.upgrading {
+		u.mu.Unlock()
+		return 0, ErrInProgress
+	}
+	u.upgrading = true
+	sockets := append([]socket(nil), u.sockets...)
+	values := make(map[string]string, len(u.values))
+	for name, value := range u.values {
+		values[name] = value
+	}
+	u.mu.Unlock()
+
+	pid, err := u.start(ctx, sockets, values)
+	if err != nil {
+		u.mu.Lock()
+		u.upgrading = false
+		u.mu.Unlock()
+		return 0, err
+	}
+
+	// Closing the sockets in this process must not remove them
+	for _, s := range sockets {
+		if unixListener, ok := s.listener.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(false)
+		}
+	}
+	close(u.done)
+	return pid, nil
+}
+
+func (u *Upgrader) start(ctx context.Context, sockets []socket, values map[string]string) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find the server executable: %w", err)
+	}
+
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	var fds []string
+	for _, s := range sockets {
+		file, err := socketFile(s)
+		if err != nil {
+			return 0, fmt.Errorf("failed to pass %s socket: %w", s.name, err)
+		}
+		fds = append(fds, fmt.Sprintf("%s=%d", s.name, firstInheritedFD+len(files)))
+		files = append(files, file)
+	}
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create ready pipe: %w", err)
+	}
+	defer readyReader.Close()
+	readyFD := firstInheritedFD + len(files)
+	files = append(files, readyWriter)
+
+	env := os.Environ()
+	env = append(env,
+		envFiles+"="+strings.Join(fds, ","),
+		envReady+"="+strconv.Itoa(readyFD),
+		envParent+"="+strconv.Itoa(os.Getpid()))
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, envValues+strings.ToUpper(name)+"="+values[name])
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", executable, err)
+	}
+	u.logger.Info("Started new server process", "pid", cmd.Process.Pid, "executable", executable)
+
+	// Only the new process holds the write end now, so its exit ends the read
+	readyWriter.Close()
+	files = files[:len(files)-1]
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	readyResult := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		if _, err := readyReader.Read(buf); err != nil {
+			readyResult <- fmt.Errorf("new process did not report ready: %w", err)
+			return
+		}
+		readyResult <- nil
+	}()
+
+	timer := time.NewTimer(DefaultReadyTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-readyResult:
+		if err == nil {
+			return cmd.Process.Pid, nil
+		}
+		cmd.Process.Kill()
+		return 0, err
+	case err := <-exited:
+		return 0, fmt.Errorf("new process exited before it was ready: %v", err)
+	case <-timer.C:
+		cmd.Process.Kill()
+		return 0, fmt.Errorf("new process was not ready within %s", DefaultReadyTimeout)
+	case <-ctx.Done():
+		cmd.Process.Kill()
+		return 0, ctx.Err()
+	}
+}
+
+// socketFile duplicates the descriptor of a socket for the new process
+func socketFile(s socket) (*os.File, error) {
+	var source interface{ File() (*os.File, error) }
+	var ok bool
+	if s.listener != nil {
+		source, ok = s.listener.(interface{ File() (*os.File, error) })
+	} else {
+		source, ok = s.packet.(interface{ File() (*os.File, error) })
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s socket cannot be passed on", s.name)
+	}
+	return source.File()
+}
+
+// notifyMainPID tells systemd, when the service runs under it with
+// NotifyAccess=all, that this process is now the service's main process
+func notifyMainPID() error {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return nil
+	}
+	if strings.HasPrefix(path, "@") {
+		path = "\x00" + path[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte("MAINPID=" + strconv.Itoa(os.Getpid()) + "\n"))
+	return err
+}
@@ -0,0 +1,12 @@
+package server
+
+import "github.com/aqz236/port-fly/core/version"
+
+// buildInfo identifies the server binary on /api/v1/version, to agents and
+// in diagnostic bundles
+var buildInfo = version.Current(version.Dev, "")
+
+// SetBuildInfo sets the version and build time of the server binary
+func SetBuildInfo(v, buildTime string) {
+	buildInfo = version.Current(v, buildTime)
+}